@@ -0,0 +1,78 @@
+package api
+
+import "fmt"
+
+// MissingImportError is returned when instantiating a module whose import
+// isn't exported by any instantiated module.
+//
+// For example, to instantiate the modules a module depends on before
+// retrying:
+//
+//	var missing *api.MissingImportError
+//	if errors.As(err, &missing) && missing.ModuleMissing {
+//		// instantiate missing.Module
+//	}
+type MissingImportError struct {
+	// Module is the name of the imported module, after any renaming via
+	// wazero.ModuleConfig WithImportRename.
+	Module string
+	// Name is the name of the import.
+	Name string
+	// Type is the type of the import.
+	Type ExternType
+	// ModuleMissing is true when no module named Module is instantiated, as
+	// opposed to it not exporting Name.
+	ModuleMissing bool
+}
+
+// Error implements the error interface.
+func (e *MissingImportError) Error() string {
+	if e.ModuleMissing {
+		return fmt.Sprintf("module[%s] not instantiated", e.Module)
+	}
+	return fmt.Sprintf("%q is not exported in module %q", e.Name, e.Module)
+}
+
+// StartFunctionTrapError is returned when instantiating a module whose start
+// function failed, e.g. with a trap such as "wasm error: unreachable".
+//
+// This isn't returned when the start function exits the module, e.g. via
+// the WASI "proc_exit" function, which returns a sys.ExitError instead.
+type StartFunctionTrapError struct {
+	// Module is the name of the module being instantiated.
+	Module string
+	// Function describes the start function, e.g. "start function[1]" for
+	// the start section, or "function[_start]" for a function configured
+	// via wazero.ModuleConfig WithStartFunctions.
+	Function string
+	// Cause is the error returned by the start function.
+	Cause error
+}
+
+// Error implements the error interface.
+func (e *StartFunctionTrapError) Error() string {
+	return fmt.Sprintf("module[%s] %s failed: %v", e.Module, e.Function, e.Cause)
+}
+
+// Unwrap allows use of errors.Is and errors.As on the Cause.
+func (e *StartFunctionTrapError) Unwrap() error {
+	return e.Cause
+}
+
+// LimitExceededError is returned when a module requires more of a resource
+// than the limit of the runtime, e.g. memory pages beyond
+// wazero.RuntimeConfig WithMemoryLimitPages.
+type LimitExceededError struct {
+	// Resource is the limited resource, e.g. "memory min pages" or
+	// "function types".
+	Resource string
+	// Requested is the amount of the resource required.
+	Requested uint64
+	// Limit is the maximum amount of the resource.
+	Limit uint64
+}
+
+// Error implements the error interface.
+func (e *LimitExceededError) Error() string {
+	return fmt.Sprintf("%s: %d over limit of %d", e.Resource, e.Requested, e.Limit)
+}
@@ -207,6 +207,27 @@ func featureName(f CoreFeatures) string {
 	case CoreFeatureSIMD:
 		// match https://github.com/WebAssembly/spec/blob/wg-2.0.draft1/proposals/simd/SIMD.md
 		return "simd"
+	case CoreFeatureSIMD << 1: // experimental.CoreFeaturesTailCall, defined there as it isn't yet standard.
+		// match https://github.com/WebAssembly/tail-call/blob/main/proposals/tail-call/Overview.md
+		return "tail-call"
+	case CoreFeatureSIMD << 2: // experimental.CoreFeaturesMultiMemory, defined there as it isn't yet standard.
+		// match https://github.com/WebAssembly/multi-memory/blob/main/proposals/multi-memory/Overview.md
+		return "multi-memory"
+	case CoreFeatureSIMD << 3: // experimental.CoreFeaturesRelaxedSIMD, defined there as it isn't yet standard.
+		// match https://github.com/WebAssembly/relaxed-simd/blob/main/proposals/relaxed-simd/Overview.md
+		return "relaxed-simd"
+	case CoreFeatureSIMD << 4: // experimental.CoreFeaturesThreads, defined there as it isn't yet standard.
+		// match https://github.com/WebAssembly/threads/blob/main/proposals/threads/Overview.md
+		return "threads"
+	case CoreFeatureSIMD << 5: // experimental.CoreFeaturesMemory64, defined there as it isn't yet standard.
+		// match https://github.com/WebAssembly/memory64/blob/main/proposals/memory64/Overview.md
+		return "memory64"
+	case CoreFeatureSIMD << 6: // experimental.CoreFeaturesExtendedConst, defined there as it isn't yet standard.
+		// match https://github.com/WebAssembly/extended-const/blob/main/proposals/extended-const/Overview.md
+		return "extended-const"
+	case CoreFeatureSIMD << 7: // experimental.CoreFeaturesExceptionHandling, defined there as it isn't yet standard.
+		// match https://github.com/WebAssembly/exception-handling/blob/main/proposals/exception-handling/legacy/Exceptions.md
+		return "exception-handling"
 	}
 	return ""
 }
@@ -61,6 +61,13 @@ func TestCoreFeatures_String(t *testing.T) {
 		{name: "sign-extension-ops", feature: CoreFeatureSignExtensionOps, expected: "sign-extension-ops"},
 		{name: "multi-value", feature: CoreFeatureMultiValue, expected: "multi-value"},
 		{name: "simd", feature: CoreFeatureSIMD, expected: "simd"},
+		{name: "tail-call", feature: CoreFeatureSIMD << 1, expected: "tail-call"},
+		{name: "multi-memory", feature: CoreFeatureSIMD << 2, expected: "multi-memory"},
+		{name: "relaxed-simd", feature: CoreFeatureSIMD << 3, expected: "relaxed-simd"},
+		{name: "threads", feature: CoreFeatureSIMD << 4, expected: "threads"},
+		{name: "memory64", feature: CoreFeatureSIMD << 5, expected: "memory64"},
+		{name: "extended-const", feature: CoreFeatureSIMD << 6, expected: "extended-const"},
+		{name: "exception-handling", feature: CoreFeatureSIMD << 7, expected: "exception-handling"},
 		{name: "features", feature: CoreFeatureMutableGlobal | CoreFeatureMultiValue, expected: "multi-value|mutable-global"},
 		{name: "undefined", feature: 1 << 63, expected: ""},
 		{
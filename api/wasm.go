@@ -4,6 +4,7 @@ package api
 import (
 	"context"
 	"fmt"
+	"io"
 	"math"
 
 	"github.com/tetratelabs/wazero/internal/internalapi"
@@ -105,6 +106,15 @@ const (
 	//		WithFunc(func(context.Context, _ uintptr) (_ uintptr) { return }).
 	//		Export("f")
 	//
+	// Zero is the null reference (ref.null extern), so a null externref
+	// passed to a host function is a zero uintptr.
+	//
+	// Note: The Go garbage collector doesn't trace uintptr values, so wazero
+	// cannot keep the referenced object alive. The host must keep it reachable
+	// for as long as the guest can hold the reference, including in globals or
+	// tables, for example by storing it in a map or calling runtime.KeepAlive
+	// after the call that uses it returns.
+	//
 	// Note: The usage of this type is toggled with api.CoreFeatureBulkMemoryOperations.
 	ValueTypeExternref ValueType = 0x6f
 )
@@ -160,7 +170,33 @@ type Module interface {
 	// definitions in this module, keyed on export name.
 	ExportedFunctionDefinitions() map[string]FunctionDefinition
 
-	// TODO: Table
+	// RunStart calls the function in the start section of this module, if
+	// it has one that wasn't called yet, otherwise it returns nil.
+	//
+	// Instantiation calls the start function unless the module was
+	// configured with wazero.ModuleConfig WithStartSection(false), so this is
+	// only needed in that case.
+	RunStart(ctx context.Context) error
+
+	// ExportedTable returns a table exported from this module or nil if it wasn't.
+	ExportedTable(name string) Table
+
+	// CallIndirect calls the function at entryIdx of the table at tableIdx,
+	// like the call_indirect instruction with the function type at typeIdx
+	// in the type section of this module. For example, to call a callback
+	// registered in a dispatch table:
+	//
+	//	results, err := mod.CallIndirect(ctx, 0, callbackIdx, typeIdx, arg)
+	//
+	// # Notes
+	//
+	//   - A null entry, an entry out of range of the table or an entry of
+	//     another function type traps as call_indirect would, and the error
+	//     wraps the same cause as a trap in the guest.
+	//   - An error is returned if tableIdx doesn't refer to a table of
+	//     functions, or typeIdx to a function type, as the guest instruction
+	//     wouldn't validate.
+	CallIndirect(ctx context.Context, tableIdx, entryIdx, typeIdx uint32, params ...uint64) ([]uint64, error)
 
 	// ExportedMemory returns a memory exported from this module or nil if it wasn't.
 	//
@@ -180,6 +216,83 @@ type Module interface {
 	// ExportedGlobal a global exported from this module or nil if it wasn't.
 	ExportedGlobal(name string) Global
 
+	// GlobalValues returns the current values of all globals exported from
+	// this module, keyed on export name. For example, to dump them:
+	//
+	//	types := mod.GlobalTypes()
+	//	for name, v := range mod.GlobalValues() {
+	//		fmt.Printf("%s %s = %d\n", name, api.ValueTypeName(types[name]), v)
+	//	}
+	//
+	// # Notes
+	//
+	//   - Globals which aren't exported aren't included.
+	//   - Values are encoded as Global.Get, so a ValueTypeV128 global only has
+	//     its lower 64 bits.
+	//   - The result is a copy, so it isn't updated when the guest sets a
+	//     global later.
+	GlobalValues() map[string]uint64
+
+	// GlobalTypes returns the types of all globals exported from this module,
+	// keyed on export name, like GlobalValues.
+	GlobalTypes() map[string]ValueType
+
+	// SnapshotMemory returns a copy of the contents of Memory, or nil if
+	// there is none. Use RestoreMemory to reinstate it later, for example to
+	// rewind a deterministic simulation.
+	//
+	// Note: Only the memory is captured, not globals, tables or host state.
+	SnapshotMemory() []byte
+
+	// RestoreMemory replaces the contents of Memory with a snapshot returned
+	// by SnapshotMemory, growing it to the size of the snapshot. As memory
+	// never shrinks, a smaller snapshot leaves Memory at its current size,
+	// with the bytes beyond the snapshot zeroed.
+	//
+	// This returns an error if the module has no memory, or if the snapshot
+	// isn't a whole number of pages within the minimum and maximum pages of
+	// the memory. It must not be called while a function of the module is
+	// running.
+	RestoreMemory(snapshot []byte) error
+
+	// Clone instantiates a module named newName which shares the compiled
+	// code of this module, but has a copy of its current memory, globals and
+	// tables. Neither initialization nor start functions run again, so this
+	// can fork many instances from a warmed-up one.
+	//
+	// # Notes
+	//
+	//   - Imports are resolved again by module name, and imported memories,
+	//     tables and globals are shared rather than copied.
+	//   - The clone has a new system context from the same ModuleConfig, so
+	//     host state, such as open files, isn't copied.
+	//   - This returns an error if the module was instantiated from a binary
+	//     by Runtime.Instantiate, or a table defined by the module holds
+	//     external references.
+	//   - This must not be called while a function of the module is running.
+	Clone(ctx context.Context, newName string) (Module, error)
+
+	// StackRemaining returns approximately how many bytes of stack remain
+	// for the call in progress before it traps with a stack overflow, or -1
+	// if unknown.
+	//
+	// This is intended for host functions in a recursion with the guest, to
+	// fail with a domain error instead of trapping. For example:
+	//
+	//	if n := mod.StackRemaining(); n != -1 && n < 64*1024 {
+	//		return errTooDeep
+	//	}
+	//
+	// # Notes
+	//
+	//   - This is only known within a host function called by this module,
+	//     and is -1 otherwise.
+	//   - The interpreter limits the depth of calls rather than the size of
+	//     the stack, so this is always -1 with it.
+	//   - When functions of this module are called concurrently, this may be
+	//     the stack of another call.
+	StackRemaining() int
+
 	// CloseWithExitCode releases resources allocated for this Module. Use a non-zero exitCode parameter to indicate a
 	// failure to ExportedFunction callers.
 	//
@@ -530,6 +643,12 @@ type Global interface {
 
 // MutableGlobal is a Global whose value can be updated at runtime (variable).
 //
+// Updates are written directly to the module's global, so they are visible to
+// subsequent function calls. Here's an example that updates an "f64" global:
+//
+//	scale := module.ExportedGlobal("scale").(api.MutableGlobal)
+//	scale.Set(api.EncodeF64(1.5))
+//
 // # Notes
 //
 //   - This is an interface for decoupling, not third-party implementations.
@@ -539,12 +658,122 @@ type MutableGlobal interface {
 
 	// Set updates the value of this global.
 	//
-	// See Global.Type for how to encode this value from a Go type.
+	// See Global.Type for how to encode this value from a Go type, e.g. with
+	// EncodeI32 or EncodeF64.
 	Set(v uint64)
 
 	internalapi.WazeroOnly
 }
 
+// Table allows restricted access to a module's table, such as to install
+// function references used by call_indirect.
+//
+// # Notes
+//
+//   - This is an interface for decoupling, not third-party implementations.
+//     All implementations are in wazero.
+//   - This is not safe for concurrent use with calls to the module's
+//     functions, as they can also read or grow the table.
+//
+// See https://www.w3.org/TR/2022/WD-wasm-core-2-20220419/exec/runtime.html#table-instances
+type Table interface {
+	// Size returns the current count of elements in the table.
+	Size() uint32
+
+	// Grow increases the size of the table by delta elements, each set to
+	// init. On success, this returns the previous size and true.
+	//
+	// This returns false without changing the table when the new size would
+	// exceed the table's maximum, or init is not valid for the table's
+	// element type.
+	//
+	// See https://www.w3.org/TR/2022/WD-wasm-core-2-20220419/syntax/instructions.html#syntax-instr-table
+	Grow(delta uint32, init Reference) (uint32, bool)
+
+	// Set stores ref at the given index, returning false if the index is out
+	// of range or ref is not valid for the table's element type.
+	Set(idx uint32, ref Reference) bool
+
+	// Entries returns a snapshot of the elements of the table, in order, for
+	// inspection such as when debugging "call_indirect".
+	//
+	// Each function reference is resolved to the function which defines it,
+	// following any imports, so a host function imported by the module is
+	// reported as the function of its host module.
+	Entries() []TableEntry
+
+	internalapi.WazeroOnly
+}
+
+// TableEntryKind is the kind of TableEntry.
+type TableEntryKind byte
+
+const (
+	// TableEntryNull is a null reference.
+	TableEntryNull TableEntryKind = iota
+	// TableEntryFunction is a reference to a function, resolved to
+	// TableEntry.ModuleName and TableEntry.FunctionIndex.
+	TableEntryFunction
+	// TableEntryExternref is an opaque host value in TableEntry.Externref.
+	TableEntryExternref
+	// TableEntryUnresolved is a function reference whose function cannot be
+	// found, such as one of a module which was closed.
+	TableEntryUnresolved
+)
+
+// TableEntry is an element of a Table, as returned by Table.Entries.
+type TableEntry struct {
+	// Kind is the kind of this entry, which defines the other fields set.
+	Kind TableEntryKind
+
+	// ModuleName is the name of the module instance defining the function,
+	// when Kind is TableEntryFunction.
+	ModuleName string
+
+	// FunctionIndex is the index of the function in the module named
+	// ModuleName, when Kind is TableEntryFunction.
+	FunctionIndex uint32
+
+	// Host is true when the function is defined by a host module, when Kind
+	// is TableEntryFunction.
+	Host bool
+
+	// Externref is the value of the reference when Kind is
+	// TableEntryExternref.
+	Externref uintptr
+}
+
+// Reference is an element of a Table: either a function reference (funcref)
+// made with FuncRef, or an opaque host value (externref) made with ExternRef.
+//
+// The zero value is a null reference, which is valid for any table.
+type Reference struct {
+	fn        Function
+	externref uintptr
+}
+
+// FuncRef returns a reference to the function, valid for funcref tables. The
+// function must be from a module in the same wazero.Runtime as the table.
+func FuncRef(fn Function) Reference {
+	return Reference{fn: fn}
+}
+
+// ExternRef returns a reference to an opaque host value, valid for externref
+// tables. See ValueTypeExternref for how to encode a Go pointer.
+func ExternRef(v uintptr) Reference {
+	return Reference{externref: v}
+}
+
+// Function returns the function of a reference made by FuncRef, or nil.
+func (r Reference) Function() Function {
+	return r.fn
+}
+
+// Externref returns the value of a reference made by ExternRef, or zero.
+func (r Reference) Externref() uintptr {
+	return r.externref
+}
+
 // Memory allows restricted access to a module's memory. Notably, this does not allow growing.
 //
 // See https://www.w3.org/TR/2019/REC-wasm-core-1-20191205/#storage%E2%91%A0
@@ -577,6 +806,35 @@ type Memory interface {
 	// See MemorySizer Read and https://www.w3.org/TR/2019/REC-wasm-core-1-20191205/#grow-mem
 	Grow(deltaPages uint32) (previousPages uint32, ok bool)
 
+	// Pinned returns the whole memory as a slice which isn't moved until
+	// release is called, e.g. to pass guest buffers to native code without
+	// copying them:
+	//
+	//	buf, release, err := memory.Pinned()
+	//	if err != nil {
+	//		return err
+	//	}
+	//	defer release()
+	//	C.process((*C.char)(unsafe.Pointer(&buf[offset])), C.size_t(byteCount))
+	//
+	// # Notes
+	//
+	//   - While pinned, Grow and the "memory.grow" instruction fail when
+	//     they would reallocate the memory, so the latter returns -1.
+	//     Growing within the capacity reserved, e.g. via
+	//     wazero.RuntimeConfig WithMemoryCapacityFromMax, still succeeds, but
+	//     isn't visible in the returned slice.
+	//   - The memory can be pinned multiple times, and stays pinned until
+	//     each release function was called. Calling release again is a no-op.
+	//   - The slice is only valid until release is called, and must not be
+	//     used after the module is closed.
+	//   - The slice is allocated by Go: native code retaining it after a cgo
+	//     call returns must follow the cgo pointer passing rules, e.g. use
+	//     runtime.Pinner.
+	//   - This returns an error if the memory is empty, as there's no
+	//     pointer to pin.
+	Pinned() (buf []byte, release func(), err error)
+
 	// ReadByte reads a single byte from the underlying buffer at the offset or returns false if out of range.
 	ReadByte(offset uint32) (byte, bool)
 
@@ -669,6 +927,58 @@ type Memory interface {
 	// WriteString writes the string to the underlying buffer at the offset or returns false if out of range.
 	WriteString(offset uint32, v string) bool
 
+	// Reader returns a reader of byteCount bytes starting at the offset, which
+	// returns io.EOF at the end of the range.
+	//
+	// For example, to copy a guest buffer to a network connection:
+	//	_, err := io.Copy(conn, memory.Reader(offset, byteCount))
+	//
+	// # Notes
+	//
+	//   - The range is checked on each read, as memory can grow. A read
+	//     errs if the range is out of memory bounds.
+	//   - This reads directly from memory, so results of concurrent writes
+	//     by Wasm are undefined.
+	Reader(offset, byteCount uint32) io.Reader
+
+	// ReaderAt is like Reader, except offsets passed to io.ReaderAt are
+	// relative to the start of the range.
+	ReaderAt(offset, byteCount uint32) io.ReaderAt
+
+	// Writer returns a writer of at most byteCount bytes starting at the
+	// offset.
+	//
+	// For example, to copy a network stream to a guest buffer:
+	//	n, err := io.Copy(memory.Writer(offset, byteCount), conn)
+	//
+	// # Notes
+	//
+	//   - The range is checked on each write, as memory can grow. A write
+	//     errs if the range is out of memory bounds.
+	//   - A write past the end of the range only writes the bytes that fit and
+	//     returns io.ErrShortWrite. Memory after the range is never written.
+	Writer(offset, byteCount uint32) io.Writer
+
+	// WriterAt is like Writer, except offsets passed to io.WriterAt are
+	// relative to the start of the range.
+	WriterAt(offset, byteCount uint32) io.WriterAt
+
+	// ReadFrom reads from r directly into the memory starting at the offset,
+	// until io.EOF or limit bytes were read, and returns the count of bytes
+	// written.
+	//
+	// For example, to load a file into a guest buffer without an
+	// intermediate copy:
+	//	n, err := memory.ReadFrom(offset, f, byteCount)
+	//
+	// # Notes
+	//
+	//   - This errs without writing if the range of limit bytes at the offset
+	//     is out of memory bounds, even if r has fewer bytes.
+	//   - An error other than io.EOF from r is returned with the count of
+	//     bytes written before it.
+	ReadFrom(offset uint32, r io.Reader, limit uint32) (uint32, error)
+
 	internalapi.WazeroOnly
 }
 
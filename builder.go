@@ -317,12 +317,12 @@ func (b *hostModuleBuilder) Compile(ctx context.Context) (CompiledModule, error)
 	module, err := wasm.NewHostModule(b.moduleName, b.exportNames, b.nameToHostFunc, b.r.enabledFeatures)
 	if err != nil {
 		return nil, err
-	} else if err = module.Validate(b.r.enabledFeatures); err != nil {
+	} else if err = module.Validate(b.r.enabledFeatures, b.r.maxBlockNestingDepth); err != nil {
 		return nil, err
 	}
 
 	c := &compiledModule{module: module, compiledEngine: b.r.store.Engine}
-	listeners, err := buildFunctionListeners(ctx, module)
+	listeners, err := buildFunctionListeners(ctx, module, nil) // host functions aren't traced
 	if err != nil {
 		return nil, err
 	}
@@ -1,6 +1,7 @@
 package wazero
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
@@ -8,6 +9,7 @@ import (
 	"io/fs"
 	"math"
 	"net"
+	"sync"
 	"time"
 
 	"github.com/tetratelabs/wazero/api"
@@ -45,6 +47,11 @@ type RuntimeConfig interface {
 	//	features := api.CoreFeaturesV2.SetEnabled(api.CoreFeatureMutableGlobal, false)
 	//	rConfig = wazero.NewRuntimeConfig().WithCoreFeatures(features)
 	//
+	// Runtime.CompileModule rejects a module using a disabled feature, whether
+	// in an instruction, a section or a value type such as v128, with an
+	// error naming the feature. This allows forbidding proposals which wazero
+	// supports, such as api.CoreFeatureSIMD.
+	//
 	// # Why default to version 2.0?
 	//
 	// Many compilers that target WebAssembly require features after
@@ -79,6 +86,67 @@ type RuntimeConfig interface {
 	// results in allocating 4GB. See the doc on WithMemoryLimitPages for detail.
 	WithMemoryCapacityFromMax(memoryCapacityFromMax bool) RuntimeConfig
 
+	// WithMemoryZeroOnClose zeroes the memories defined by a module when it
+	// is closed, so that guest data doesn't linger in the released memory.
+	// Imported memories are left to the module which defines them. The
+	// default is false, as zeroing costs time in proportion to the size of
+	// the memories.
+	//
+	// This example ensures memory contents don't outlive the module:
+	//	rConfig = wazero.NewRuntimeConfig().WithMemoryZeroOnClose(true)
+	//
+	// Note: Memories are allocated by Go, so they are zeroed in place rather
+	// than released to the operating system with madvise.
+	WithMemoryZeroOnClose(memoryZeroOnClose bool) RuntimeConfig
+
+	// WithMemoryPrefault touches the initial pages of the memories defined by
+	// a module when it is instantiated, before any start function, so that
+	// the first accesses during calls don't page fault. The default is false.
+	//
+	// Where supported, the pages are also locked in RAM with mlock, so that
+	// they aren't paged out. This degrades to only pre-faulting on other
+	// platforms, or when the process may not lock that much memory, such as
+	// due to RLIMIT_MEMLOCK.
+	//
+	// This example reduces the tail latency of calls to a real-time guest:
+	//	rConfig = wazero.NewRuntimeConfig().WithMemoryPrefault(true)
+	//
+	// # Notes
+	//
+	//   - Pages added by growing memory aren't pre-faulted. Growing beyond
+	//     the capacity reallocates the memory, which also releases the lock.
+	//     Use WithMemoryCapacityFromMax to avoid this.
+	//   - Locks are released when the module is closed.
+	WithMemoryPrefault(memoryPrefault bool) RuntimeConfig
+
+	// WithMaxBlockNestingDepth overrides how deeply block, loop and if
+	// instructions can be nested in a function. The default is 65536, which
+	// a zero argument keeps.
+	//
+	// Modules exceeding this limit fail to compile with an error, before
+	// resources are spent on them. This hardens the host against modules
+	// crafted to exhaust memory or stack during decoding and compilation.
+	//
+	// This example rejects functions with more than 1024 nested blocks:
+	//	rConfig = wazero.NewRuntimeConfig().WithMaxBlockNestingDepth(1024)
+	WithMaxBlockNestingDepth(maxBlockNestingDepth uint32) RuntimeConfig
+
+	// WithDecodeLimits overrides the maximum count of functions, types,
+	// imports and exports a module can declare. A zero argument keeps the
+	// default of that limit, which are those of the WebAssembly JavaScript
+	// API: 1000000 functions and types, and 100000 imports and exports.
+	//
+	// Modules exceeding a limit fail to compile with an error as soon as the
+	// count is read, before memory is allocated for the declarations. This
+	// hardens hosts which compile untrusted modules, such as uploads.
+	//
+	// This example rejects modules with more than 10000 functions:
+	//	rConfig = wazero.NewRuntimeConfig().WithDecodeLimits(10000, 0, 0, 0)
+	//
+	// Note: The function limit counts imported functions, along with the
+	// entries of the function section.
+	WithDecodeLimits(maxFunctions, maxTypes, maxImports, maxExports uint32) RuntimeConfig
+
 	// WithDebugInfoEnabled toggles DWARF based stack traces in the face of
 	// runtime errors. Defaults to true.
 	//
@@ -168,6 +236,274 @@ type RuntimeConfig interface {
 	// When the invocations of api.Function are closed due to this, sys.ExitError is raised to the callers and
 	// the api.Module from which the functions are derived is made closed.
 	WithCloseOnContextDone(bool) RuntimeConfig
+
+	// WithSSADumper registers a function which receives the SSA (static
+	// single-assignment form) text of each function compiled by the
+	// optimizing compiler. Defaults to nil.
+	//
+	// For each function, the dumper is called twice: first with the stage
+	// "lowered", right after the function is lowered from WebAssembly, and
+	// then with the stage "optimized", after the optimization passes. This
+	// allows tests to assert that a particular optimization is applied:
+	//
+	//	config := wazero.NewRuntimeConfig().WithSSADumper(func(funcName, stage, ssaText string) {
+	//		if stage == "optimized" {
+	//			fmt.Println(funcName, ssaText)
+	//		}
+	//	})
+	//
+	// # Notes
+	//
+	//   - This is only supported by the optimizing compiler, and is ignored
+	//     by other engines.
+	//   - The dumper isn't called when the compilation result was cached.
+	//   - The SSA text format is for debugging, and may change at any time.
+	WithSSADumper(dumper func(funcName, stage, ssaText string)) RuntimeConfig
+
+	// WithRegAllocObserver registers a function which receives a summary of
+	// the register allocation of each function compiled by the optimizing
+	// compiler. Defaults to nil.
+	//
+	// This is useful to find functions with excessive spilling, e.g. when
+	// studying the quality of the generated code:
+	//
+	//	config := wazero.NewRuntimeConfig().WithRegAllocObserver(func(funcName string, info wazero.RegAllocInfo) {
+	//		if info.Spills > 100 {
+	//			fmt.Println(funcName, info.Spills, len(info.SpilledRanges))
+	//		}
+	//	})
+	//
+	// # Notes
+	//
+	//   - This is only supported by the optimizing compiler, and is ignored
+	//     by other engines.
+	//   - The observer isn't called when the compilation result was cached.
+	//   - This doesn't change how registers are allocated.
+	WithRegAllocObserver(observer func(funcName string, info RegAllocInfo)) RuntimeConfig
+
+	// WithInliningThreshold makes the optimizing compiler inline calls to
+	// small leaf functions, i.e. functions which don't call any function,
+	// whose body has at most n instructions. Defaults to zero, which
+	// disables inlining.
+	//
+	// Inlining removes the call overhead of small functions called in hot
+	// loops, at the cost of larger machine code:
+	//
+	//	config := wazero.NewRuntimeConfig().WithInliningThreshold(20)
+	//
+	// # Notes
+	//
+	//   - This is only supported by the optimizing compiler, and is ignored
+	//     by other engines.
+	//   - Calls aren't inlined when RuntimeConfig.WithCloseOnContextDone is
+	//     enabled, or with experimental.FunctionListener, as both observe
+	//     each function call.
+	//   - A trap in an inlined function is reported in the stack trace as
+	//     part of its caller.
+	//   - The threshold isn't part of the key of the CompilationCache, so a
+	//     module compiled with a different threshold may be reused.
+	WithInliningThreshold(n int) RuntimeConfig
+
+	// WithPerFunctionCompileTimeout bounds the time the optimizing compiler
+	// can take to compile a single function. Defaults to zero, which means
+	// no timeout.
+	//
+	// When a function takes longer, Runtime.CompileModule fails with an
+	// error including the index of the function. This protects services
+	// compiling untrusted modules from functions crafted to slow down the
+	// compiler, e.g. the register allocator:
+	//
+	//	config := wazero.NewRuntimeConfig().WithPerFunctionCompileTimeout(time.Second)
+	//
+	// # Notes
+	//
+	//   - This is only supported by the optimizing compiler, and is ignored
+	//     by other engines.
+	//   - The deadline is checked between compilation passes and while
+	//     allocating registers, so a pass can overrun the timeout before it
+	//     is noticed.
+	WithPerFunctionCompileTimeout(d time.Duration) RuntimeConfig
+
+	// WithFallbackInterpreter registers a predicate which selects modules
+	// to interpret instead of compile. Defaults to nil, which compiles all
+	// modules.
+	//
+	// This is useful to bisect a miscompilation against the interpreter,
+	// without creating another Runtime. The predicate is called with the
+	// binary passed to Runtime.CompileModule, before inflating it if it is
+	// compressed:
+	//
+	//	config := wazero.NewRuntimeConfigCompiler().WithFallbackInterpreter(func(binary []byte) bool {
+	//		return bytes.Equal(binary, flakyWasm)
+	//	})
+	//
+	// # Notes
+	//
+	//   - This is ignored by NewRuntimeConfigInterpreter, which interprets
+	//     all modules.
+	//   - Instances of an interpreted module run on the interpreter, even
+	//     when instantiated again.
+	//   - Interpreted modules can import functions and memories of compiled
+	//     modules, including host modules. Other imports between interpreted
+	//     and compiled modules fail instantiation, e.g. compiled modules can't
+	//     import from an interpreted one, and tables can't be shared.
+	WithFallbackInterpreter(predicate func(binary []byte) bool) RuntimeConfig
+
+	// WithDifferentialCheck enables instantiating each compiled module on the
+	// interpreter as well, so that every call to an exported function runs on
+	// both and returns an error if they diverge. Defaults to false.
+	//
+	// This is useful to find miscompilations when fuzzing or testing, as a
+	// call errs unless both engines return the same results, trap with the
+	// same error or exit code, and leave the memories of the module with the
+	// same contents.
+	//
+	// # Notes
+	//
+	//   - This is ignored by NewRuntimeConfigInterpreter, and doesn't apply
+	//     to modules selected by WithFallbackInterpreter.
+	//   - Each call runs twice, so host functions imported by the module are
+	//     called twice, including those writing to stdout.
+	//   - Imported memories are shared, so they aren't compared. Instantiation
+	//     fails if the module imports a table, as these can't be shared.
+	//   - This is for debugging: it is slower than the interpreter alone.
+	WithDifferentialCheck(enabled bool) RuntimeConfig
+
+	// WithCallTracer registers functions called on entry to and exit from
+	// each function defined in a guest module, e.g. to build a flame graph.
+	// Defaults to nil, which doesn't trace.
+	//
+	// The parameter of each function is the index of the called function in
+	// the module, including imported functions, so it can be symbolized with
+	// the "name" custom section:
+	//
+	//	var depth int
+	//	config := wazero.NewRuntimeConfig().WithCallTracer(
+	//		func(funcIdx uint32) { depth++ },
+	//		func(funcIdx uint32) { depth-- },
+	//	)
+	//
+	// # Notes
+	//
+	//   - The calls are compiled into modules, so this slows down traced
+	//     modules, but not those of a runtime without a tracer.
+	//   - exit is called even when the function traps or the module exits.
+	//   - Host functions aren't traced.
+	//   - This is implemented with an experimental.FunctionListener, so the
+	//     tracer is called in addition to any listener in the context.
+	WithCallTracer(enter, exit func(funcIdx uint32)) RuntimeConfig
+
+	// WithStrictFloat ensures denormal floating-point values are handled as
+	// IEEE 754 requires, rather than flushed to zero, while guest code runs.
+	// This makes float results reproducible across hosts and architectures
+	// even if the host process, e.g. in C code, enables flush-to-zero modes.
+	//
+	// # Notes
+	//
+	//   - Each call into a guest locks its goroutine to the OS thread and
+	//     sets the floating-point control of the thread, which costs a little
+	//     time per call. The previous control is restored after the call.
+	//   - This is supported on amd64 and arm64. Otherwise, the
+	//     Runtime.CompileModule errs.
+	WithStrictFloat() RuntimeConfig
+
+	// WithPprofLabels runs calls of exported functions in pprof.Do with the
+	// label "wasm_function" set to the debug name of the function, e.g.
+	// "env.fib" or "env.$2", so that CPU profiles of the host can be filtered
+	// or tagged by guest function. Defaults to false.
+	//
+	// # Notes
+	//
+	//   - Only calls made via api.Function are labelled. Calls between guest
+	//     functions don't change the label, so time in a callee is attributed
+	//     to the exported function called from Go.
+	//   - Host functions called by the guest see the label in their context,
+	//     e.g. via pprof.Label.
+	//   - This allocates per call, so leave it disabled unless profiling.
+	WithPprofLabels(enabled bool) RuntimeConfig
+
+	// WithCanonicalNaN makes the results of floating-point arithmetic which
+	// are NaN the canonical NaN of their type, i.e. 0x7fc00000 for f32 and
+	// 0x7ff8000000000000 for f64, including each lane of vectors. Defaults to
+	// false, so that the NaN bit patterns, e.g. their sign or payload,
+	// depend on the host architecture as the WebAssembly specification
+	// allows.
+	//
+	// This makes float results bit-for-bit reproducible across hosts, e.g.
+	// for golden tests:
+	//
+	//	config := wazero.NewRuntimeConfig().WithCanonicalNaN(true)
+	//
+	// # Notes
+	//
+	//   - This is only supported by the optimizing compiler, and is ignored
+	//     by other engines.
+	//   - Each arithmetic instruction, e.g. f32.add or f64x2.sqrt, is
+	//     followed by a check for NaN, which slows down float-heavy code.
+	//   - Instructions which only move bits, e.g. f32.neg, f64.copysign or
+	//     loads and stores, keep the NaN bit pattern of their operand as the
+	//     specification requires.
+	WithCanonicalNaN(enabled bool) RuntimeConfig
+
+	// WithMemorySanitizer makes each memory track which of its bytes were
+	// written, so that a load of any other byte traps with an error
+	// containing "uninitialized memory read". Defaults to false.
+	//
+	// This finds bugs such as a guest allocator returning memory which its
+	// callers read before writing:
+	//
+	//	config := wazero.NewRuntimeConfigInterpreter().WithMemorySanitizer(true)
+	//
+	// # Notes
+	//
+	//   - This is only supported by the interpreter, including for modules
+	//     selected by WithFallbackInterpreter. Runtime.CompileModule fails
+	//     for modules the compiler would compile.
+	//   - Bytes are initialized by stores, data segments, "memory.fill",
+	//     "memory.init" and writes by the host, including via the view
+	//     returned by api.Memory Read. "memory.copy" copies whether bytes
+	//     are initialized along with them.
+	//   - Memory below the value of the global "__heap_base", exported by
+	//     LLVM-based toolchains, is initialized, as it holds static data
+	//     and the stack.
+	//   - Each store is recorded in a bitmap of an eighth of the memory
+	//     size, which each load is checked against. This is for debugging:
+	//     it is much slower than the interpreter alone.
+	WithMemorySanitizer(enabled bool) RuntimeConfig
+}
+
+// RegAllocInfo is passed to the observer registered with
+// RuntimeConfig.WithRegAllocObserver.
+type RegAllocInfo struct {
+	// VirtualRegisters is the number of virtual registers the function used
+	// before allocation.
+	VirtualRegisters int
+
+	// Spills is the number of stores of registers to the stack, including
+	// those saving registers which are live across calls.
+	Spills int
+
+	// SpilledRanges are the live ranges of the virtual registers which
+	// weren't assigned a register, ordered by block and position.
+	SpilledRanges []LiveRange
+}
+
+// LiveRange is a range of machine instructions in a block in which a
+// virtual register is live.
+type LiveRange struct {
+	// VirtualRegister is the ID of the virtual register.
+	VirtualRegister uint32
+
+	// Block is the ID of the block.
+	Block int
+
+	// Begin and End are the indexes of the first and last instructions of the
+	// range in the block, not counting instructions added for spilling.
+	Begin, End int
+
+	// LiveOut is true when the register is still live at the end of the
+	// block, in which case End is -1.
+	LiveOut bool
 }
 
 // NewRuntimeConfig returns a RuntimeConfig using the compiler if it is supported in this environment,
@@ -182,12 +518,27 @@ type runtimeConfig struct {
 	enabledFeatures       api.CoreFeatures
 	memoryLimitPages      uint32
 	memoryCapacityFromMax bool
+	memoryZeroOnClose     bool
+	memoryPrefault        bool
+	strictFloat           bool
+	pprofLabels           bool
+	maxBlockNestingDepth  uint32
 	engineKind            engineKind
 	dwarfDisabled         bool // negative as defaults to enabled
 	newEngine             newEngine
 	cache                 CompilationCache
 	storeCustomSections   bool
 	ensureTermination     bool
+	ssaDumper             func(funcName, stage, ssaText string)
+	regAllocObserver      func(funcName string, info RegAllocInfo)
+	inliningThreshold     int
+	functionTimeout       time.Duration
+	fallbackInterpreter   func(binary []byte) bool
+	differentialCheck     bool
+	callTracer            *callTracer
+	canonicalNaN          bool
+	memorySanitizer       bool
+	decodeLimits          wasm.DecodeLimits
 }
 
 // engineLessConfig helps avoid copy/pasting the wrong defaults.
@@ -195,7 +546,9 @@ var engineLessConfig = &runtimeConfig{
 	enabledFeatures:       api.CoreFeaturesV2,
 	memoryLimitPages:      wasm.MemoryLimitPages,
 	memoryCapacityFromMax: false,
+	maxBlockNestingDepth:  wasm.MaximumBlockNestingDepth,
 	dwarfDisabled:         false,
+	decodeLimits:          wasm.DefaultDecodeLimits,
 }
 
 type engineKind int
@@ -235,6 +588,16 @@ func NewRuntimeConfigInterpreter() RuntimeConfig {
 	return ret
 }
 
+// CPUFeatures returns the names of optional CPU features detected on this
+// host which affect machine code generated by the compiler, e.g. "sse4.1" on
+// amd64. This returns nil if the compiler considers none, such as on arm64.
+//
+// This is useful when debugging why code compiled on another machine, e.g. in
+// a shared CompilationCache, behaves differently or is rejected.
+func CPUFeatures() []string {
+	return platform.CpuFeatureNames()
+}
+
 // clone makes a deep copy of this runtime config.
 func (c *runtimeConfig) clone() *runtimeConfig {
 	ret := *c // copy except maps which share a ref
@@ -255,6 +618,87 @@ func (c *runtimeConfig) WithCloseOnContextDone(ensure bool) RuntimeConfig {
 	return ret
 }
 
+// WithSSADumper implements RuntimeConfig.WithSSADumper
+func (c *runtimeConfig) WithSSADumper(dumper func(funcName, stage, ssaText string)) RuntimeConfig {
+	ret := c.clone()
+	ret.ssaDumper = dumper
+	return ret
+}
+
+// WithInliningThreshold implements RuntimeConfig.WithInliningThreshold
+func (c *runtimeConfig) WithInliningThreshold(n int) RuntimeConfig {
+	ret := c.clone()
+	ret.inliningThreshold = n
+	return ret
+}
+
+// WithPerFunctionCompileTimeout implements RuntimeConfig.WithPerFunctionCompileTimeout
+func (c *runtimeConfig) WithPerFunctionCompileTimeout(d time.Duration) RuntimeConfig {
+	ret := c.clone()
+	ret.functionTimeout = d
+	return ret
+}
+
+// WithRegAllocObserver implements RuntimeConfig.WithRegAllocObserver
+func (c *runtimeConfig) WithRegAllocObserver(observer func(funcName string, info RegAllocInfo)) RuntimeConfig {
+	ret := c.clone()
+	ret.regAllocObserver = observer
+	return ret
+}
+
+// WithFallbackInterpreter implements RuntimeConfig.WithFallbackInterpreter
+func (c *runtimeConfig) WithFallbackInterpreter(predicate func(binary []byte) bool) RuntimeConfig {
+	ret := c.clone()
+	ret.fallbackInterpreter = predicate
+	return ret
+}
+
+// WithDifferentialCheck implements RuntimeConfig.WithDifferentialCheck
+func (c *runtimeConfig) WithDifferentialCheck(enabled bool) RuntimeConfig {
+	ret := c.clone()
+	ret.differentialCheck = enabled
+	return ret
+}
+
+// WithCallTracer implements RuntimeConfig.WithCallTracer
+func (c *runtimeConfig) WithCallTracer(enter, exit func(funcIdx uint32)) RuntimeConfig {
+	ret := c.clone()
+	if enter == nil && exit == nil {
+		ret.callTracer = nil
+	} else {
+		ret.callTracer = &callTracer{enter: enter, exit: exit}
+	}
+	return ret
+}
+
+// WithStrictFloat implements RuntimeConfig.WithStrictFloat
+func (c *runtimeConfig) WithStrictFloat() RuntimeConfig {
+	ret := c.clone()
+	ret.strictFloat = true
+	return ret
+}
+
+// WithPprofLabels implements RuntimeConfig.WithPprofLabels
+func (c *runtimeConfig) WithPprofLabels(enabled bool) RuntimeConfig {
+	ret := c.clone()
+	ret.pprofLabels = enabled
+	return ret
+}
+
+// WithCanonicalNaN implements RuntimeConfig.WithCanonicalNaN
+func (c *runtimeConfig) WithCanonicalNaN(enabled bool) RuntimeConfig {
+	ret := c.clone()
+	ret.canonicalNaN = enabled
+	return ret
+}
+
+// WithMemorySanitizer implements RuntimeConfig.WithMemorySanitizer
+func (c *runtimeConfig) WithMemorySanitizer(enabled bool) RuntimeConfig {
+	ret := c.clone()
+	ret.memorySanitizer = enabled
+	return ret
+}
+
 // WithMemoryLimitPages implements RuntimeConfig.WithMemoryLimitPages
 func (c *runtimeConfig) WithMemoryLimitPages(memoryLimitPages uint32) RuntimeConfig {
 	ret := c.clone()
@@ -280,6 +724,47 @@ func (c *runtimeConfig) WithMemoryCapacityFromMax(memoryCapacityFromMax bool) Ru
 	return ret
 }
 
+// WithMemoryZeroOnClose implements RuntimeConfig.WithMemoryZeroOnClose
+func (c *runtimeConfig) WithMemoryZeroOnClose(memoryZeroOnClose bool) RuntimeConfig {
+	ret := c.clone()
+	ret.memoryZeroOnClose = memoryZeroOnClose
+	return ret
+}
+
+// WithMemoryPrefault implements RuntimeConfig.WithMemoryPrefault
+func (c *runtimeConfig) WithMemoryPrefault(memoryPrefault bool) RuntimeConfig {
+	ret := c.clone()
+	ret.memoryPrefault = memoryPrefault
+	return ret
+}
+
+// WithMaxBlockNestingDepth implements RuntimeConfig.WithMaxBlockNestingDepth
+func (c *runtimeConfig) WithMaxBlockNestingDepth(maxBlockNestingDepth uint32) RuntimeConfig {
+	ret := c.clone()
+	ret.maxBlockNestingDepth = orDefault(maxBlockNestingDepth, wasm.MaximumBlockNestingDepth)
+	return ret
+}
+
+// WithDecodeLimits implements RuntimeConfig.WithDecodeLimits
+func (c *runtimeConfig) WithDecodeLimits(maxFunctions, maxTypes, maxImports, maxExports uint32) RuntimeConfig {
+	ret := c.clone()
+	ret.decodeLimits = wasm.DecodeLimits{
+		MaxFunctions: orDefault(maxFunctions, wasm.DefaultDecodeLimits.MaxFunctions),
+		MaxTypes:     orDefault(maxTypes, wasm.DefaultDecodeLimits.MaxTypes),
+		MaxImports:   orDefault(maxImports, wasm.DefaultDecodeLimits.MaxImports),
+		MaxExports:   orDefault(maxExports, wasm.DefaultDecodeLimits.MaxExports),
+	}
+	return ret
+}
+
+// orDefault returns limit unless it is zero.
+func orDefault(limit, defaultLimit uint32) uint32 {
+	if limit == 0 {
+		return defaultLimit
+	}
+	return limit
+}
+
 // WithDebugInfoEnabled implements RuntimeConfig.WithDebugInfoEnabled
 func (c *runtimeConfig) WithDebugInfoEnabled(dwarfEnabled bool) RuntimeConfig {
 	ret := c.clone()
@@ -336,10 +821,107 @@ type CompiledModule interface {
 	// memory.
 	ExportedMemories() map[string]api.MemoryDefinition
 
-	// CustomSections returns all the custom sections
-	// (api.CustomSection) in this module keyed on the section name.
+	// CustomSections returns all the custom sections (api.CustomSection) in
+	// this module, in the order they were decoded, except the "name" section.
+	// Multiple sections can have the same name.
+	//
+	// Note: Custom sections are only guaranteed to be retained when
+	// RuntimeConfig.WithCustomSections is enabled.
 	CustomSections() []api.CustomSection
 
+	// SectionRanges returns the byte ranges of all the sections in the
+	// binary this module was compiled from, in the order they were decoded,
+	// including custom sections.
+	//
+	// This allows editing a section of the binary, e.g. replacing a custom
+	// section, without encoding the whole module again.
+	SectionRanges() []SectionRange
+
+	// CallGraph returns the calls made by functions defined in this module,
+	// ordered by caller. Each distinct callee is returned once per caller.
+	//
+	// Function indices include imported functions, so they correspond to
+	// api.FunctionDefinition Index. Imported functions have no outgoing edges.
+	//
+	// Note: This scans every function body on each call.
+	CallGraph() []CallEdge
+
+	// Disassemble returns the machine instructions compiled for the function
+	// at the given index, which includes imported functions as with
+	// api.FunctionDefinition Index.
+	//
+	// This is intended for tests which compare the instructions generated for
+	// a given module against expectations, e.g. golden files.
+	//
+	// # Notes
+	//
+	//   - This is only supported by the optimizing compiler (wazevo), which
+	//     currently targets arm64. Other engines return an error.
+	//   - An error is returned for imported functions and those of host
+	//     modules, as they have no machine code in this module.
+	//   - The function is compiled again on each call, so this is as
+	//     expensive as compiling it.
+	//   - Branch targets are formatted as labels, e.g. "L3", and calls as
+	//     function references, as the instructions are relative to the
+	//     function rather than an absolute address in the executable.
+	Disassemble(funcIdx uint32) ([]Instruction, error)
+
+	// BoundsCheckStats returns how many bounds checks were compiled for the
+	// loads and stores of the function at the given index, and how many were
+	// eliminated as the access was known to be in bounds. The index includes
+	// imported functions as with api.FunctionDefinition Index.
+	//
+	// This is intended for performance tuning, e.g. to confirm a change to a
+	// hot loop reduced its bounds checks.
+	//
+	// # Notes
+	//
+	//   - This is only supported by the optimizing compiler (wazevo). Other
+	//     engines return an error.
+	//   - An error is returned for imported functions and those of host
+	//     modules.
+	//   - Currently, a check is only eliminated when an earlier access in the
+	//     same basic block has the same address and an equal or higher end.
+	//   - The function is lowered again on each call.
+	BoundsCheckStats(funcIdx uint32) (BoundsCheckStats, error)
+
+	// TrapSites returns the instructions which can trap in the function at
+	// the given index, ordered by offset, e.g. each bounds check, division
+	// and unreachable. The index includes imported functions as with
+	// api.FunctionDefinition Index.
+	//
+	// This is intended for coverage analysis, e.g. of the traps a fuzzer
+	// hits compared to those which are possible.
+	//
+	// # Notes
+	//
+	//   - This is only supported by the optimizing compiler (wazevo). Other
+	//     engines return an error.
+	//   - An error is returned for imported functions and those of host
+	//     modules.
+	//   - Bounds checks eliminated by the compiler aren't trap sites.
+	//   - Calls aren't inlined, so the sites are only those of the function.
+	//     Checks on entry to the function, e.g. of the call depth, are at the
+	//     offset of its body.
+	//   - The function is lowered again on each call.
+	TrapSites(funcIdx uint32) ([]TrapSite, error)
+
+	// FunctionBody returns the wasm instructions decoded from the body of
+	// the function at the given index, which includes imported functions as
+	// with api.FunctionDefinition Index.
+	//
+	// This is intended for static analysis, e.g. finding loops or memory
+	// accesses, without instantiating the module.
+	//
+	// # Notes
+	//
+	//   - An error is returned for imported functions and those of host
+	//     modules, as their bodies are not known.
+	//   - Block nesting is represented by explicit "else" and "end"
+	//     instructions, and the last instruction is the "end" of the function.
+	//   - The body is decoded again on each call.
+	FunctionBody(funcIdx uint32) ([]BodyInstruction, error)
+
 	// Close releases all the allocated resources for this CompiledModule.
 	//
 	// Note: It is safe to call Close while having outstanding calls from an
@@ -347,6 +929,114 @@ type CompiledModule interface {
 	Close(context.Context) error
 }
 
+// CallEdge is an edge in the call graph returned by CompiledModule.CallGraph.
+type CallEdge struct {
+	// Caller is the index of the calling function.
+	Caller uint32
+
+	// Callee is the index of the function called, or zero if Indirect.
+	Callee uint32
+
+	// Indirect is true for a call_indirect instruction, which may call any
+	// function whose type is TypeIndex.
+	Indirect bool
+
+	// TypeIndex is the index of the callee's type in the module's type
+	// section. This is only set when Indirect.
+	TypeIndex uint32
+}
+
+// SectionRange is the range of bytes of a section in a binary, returned by
+// CompiledModule.SectionRanges.
+type SectionRange struct {
+	// ID is the section ID, e.g. zero for a custom section.
+	ID byte
+
+	// Start is the offset of the section ID in the binary.
+	Start int
+
+	// End is the offset following the last byte of the section contents,
+	// so binary[Start:End] includes the section ID and size.
+	End int
+}
+
+// Instruction is a machine instruction returned by
+// CompiledModule.Disassemble.
+type Instruction struct {
+	// Address is the offset of the instruction from the start of the
+	// function's machine code.
+	Address uint64
+
+	// Size is the length in bytes of the instruction. This is larger than a
+	// single instruction of the target architecture for sequences the
+	// compiler emits as a unit, e.g. "exit_sequence".
+	Size uint32
+
+	// Mnemonic is the name of the instruction, e.g. "add".
+	Mnemonic string
+
+	// Operands are the operands of the instruction in assembly order, e.g.
+	// []string{"x1", "[x2, #0x8]"} for "ldr x1, [x2, #0x8]".
+	Operands []string
+}
+
+// BoundsCheckStats is returned by CompiledModule.BoundsCheckStats.
+type BoundsCheckStats struct {
+	// Emitted is the number of bounds checks compiled.
+	Emitted int
+
+	// Eliminated is the number of loads and stores compiled without a
+	// bounds check.
+	Eliminated int
+}
+
+// TrapSite is an instruction which can trap, returned by
+// CompiledModule.TrapSites.
+type TrapSite struct {
+	// Offset is the offset of the instruction in the code section, as in the
+	// stack trace of the trap when the module has DWARF.
+	Offset uint64
+
+	// Kind is the cause of the trap, as in the message of its error, e.g.
+	// "integer divide by zero" for "wasm error: integer divide by zero".
+	// An instruction which can trap for several causes is a site per cause.
+	Kind string
+}
+
+// BodyInstruction is a wasm instruction returned by
+// CompiledModule.FunctionBody.
+type BodyInstruction struct {
+	// Offset is the offset of the instruction from the start of the
+	// function's body, after the declarations of its locals.
+	Offset uint32
+
+	// Opcode is the first byte of the instruction, e.g. 0x6a for "i32.add".
+	// This is 0xfc or 0xfd for instructions which have a Subopcode.
+	Opcode byte
+
+	// Subopcode is the opcode following the 0xfc or 0xfd prefix, e.g. 0x0a
+	// for "memory.copy", or zero.
+	Subopcode uint32
+
+	// Name is the name of the instruction in the text format, e.g.
+	// "i32.add".
+	Name string
+
+	// Immediates are the immediate arguments of the instruction in the
+	// order they are encoded. Indices are as encoded and constants are
+	// encoded as with api.EncodeI32 and similar. In detail:
+	//
+	//   - Block types are the signed 33-bit integer, e.g. -64 for a block
+	//     with no params or results, or a non-negative type index.
+	//   - "br_table" has the number of labels, each label, then the default.
+	//   - "select" with value types has their count, then each value type.
+	//   - Memory arguments are the alignment exponent, the offset and the
+	//     memory index. Vector lane loads and stores add the lane index.
+	//   - "v128.const" and "i8x16.shuffle" have the low then the high 64
+	//     bits of their little-endian 16 bytes.
+	Immediates []uint64
+}
+
 // compile-time check to ensure compiledModule implements CompiledModule
 var _ CompiledModule = &compiledModule{}
 
@@ -354,6 +1044,8 @@ type compiledModule struct {
 	module *wasm.Module
 	// compiledEngine holds an engine on which `module` is compiled.
 	compiledEngine wasm.Engine
+	// differentialEngine is non-nil when `module` is also compiled on it, for RuntimeConfig.WithDifferentialCheck.
+	differentialEngine wasm.Engine
 	// closeWithModule prevents leaking compiled code when a module is compiled implicitly.
 	closeWithModule bool
 	typeIDs         []wasm.FunctionTypeID
@@ -370,6 +1062,9 @@ func (c *compiledModule) Name() (moduleName string) {
 // Close implements CompiledModule.Close
 func (c *compiledModule) Close(context.Context) error {
 	c.compiledEngine.DeleteCompiledModule(c.module)
+	if c.differentialEngine != nil {
+		c.differentialEngine.DeleteCompiledModule(c.module)
+	}
 	// It is possible the underlying may need to return an error later, but in any case this matches api.Module.Close.
 	return nil
 }
@@ -403,6 +1098,86 @@ func (c *compiledModule) CustomSections() []api.CustomSection {
 	return ret
 }
 
+// SectionRanges implements CompiledModule.SectionRanges
+func (c *compiledModule) SectionRanges() []SectionRange {
+	ret := make([]SectionRange, len(c.module.SectionRanges))
+	for i, r := range c.module.SectionRanges {
+		ret[i] = SectionRange{ID: r.ID, Start: r.Start, End: r.End}
+	}
+	return ret
+}
+
+// CallGraph implements CompiledModule.CallGraph
+func (c *compiledModule) CallGraph() []CallEdge {
+	edges := c.module.CallGraph()
+	ret := make([]CallEdge, len(edges))
+	for i, e := range edges {
+		ret[i] = CallEdge{Caller: e.Caller, Callee: e.Callee, Indirect: e.Indirect, TypeIndex: e.TypeIndex}
+	}
+	return ret
+}
+
+// Disassemble implements CompiledModule.Disassemble
+func (c *compiledModule) Disassemble(funcIdx uint32) ([]Instruction, error) {
+	d, ok := c.compiledEngine.(wasm.Disassembler)
+	if !ok {
+		return nil, errors.New("disassembly is not supported by this engine")
+	}
+	instrs, err := d.Disassemble(c.module, funcIdx)
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]Instruction, len(instrs))
+	for i, instr := range instrs {
+		ret[i] = Instruction{Address: instr.Address, Size: instr.Size, Mnemonic: instr.Mnemonic, Operands: instr.Operands}
+	}
+	return ret, nil
+}
+
+// BoundsCheckStats implements CompiledModule.BoundsCheckStats
+func (c *compiledModule) BoundsCheckStats(funcIdx uint32) (BoundsCheckStats, error) {
+	b, ok := c.compiledEngine.(wasm.BoundsCheckCounter)
+	if !ok {
+		return BoundsCheckStats{}, errors.New("bounds check stats are not supported by this engine")
+	}
+	stats, err := b.BoundsCheckStats(c.module, funcIdx)
+	if err != nil {
+		return BoundsCheckStats{}, err
+	}
+	return BoundsCheckStats{Emitted: stats.Emitted, Eliminated: stats.Eliminated}, nil
+}
+
+// TrapSites implements CompiledModule.TrapSites
+func (c *compiledModule) TrapSites(funcIdx uint32) ([]TrapSite, error) {
+	l, ok := c.compiledEngine.(wasm.TrapSiteLister)
+	if !ok {
+		return nil, errors.New("trap sites are not supported by this engine")
+	}
+	sites, err := l.TrapSites(c.module, funcIdx)
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]TrapSite, len(sites))
+	for i, site := range sites {
+		ret[i] = TrapSite{Offset: site.Offset, Kind: site.Cause.Error()}
+	}
+	return ret, nil
+}
+
+// FunctionBody implements CompiledModule.FunctionBody
+func (c *compiledModule) FunctionBody(funcIdx uint32) ([]BodyInstruction, error) {
+	instrs, err := c.module.FunctionBody(funcIdx)
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]BodyInstruction, len(instrs))
+	for i := range instrs {
+		instr := &instrs[i]
+		ret[i] = BodyInstruction{Offset: instr.Offset, Opcode: instr.Opcode, Subopcode: instr.Subopcode, Name: instr.Name(), Immediates: instr.Immediates}
+	}
+	return ret, nil
+}
+
 // customSection implements wasm.CustomSection
 type customSection struct {
 	internalapi.WazeroOnlyType
@@ -457,6 +1232,29 @@ type ModuleConfig interface {
 	// See https://linux.die.net/man/3/argv and https://en.wikipedia.org/wiki/Null-terminated_string
 	WithArgs(...string) ModuleConfig
 
+	// WithBeforeStart registers a function called with the module while it is
+	// being instantiated: after its imports are bound and its data and element
+	// segments are applied, but before its start function, or any configured
+	// by WithStartFunctions, is called. Defaults to nil.
+	//
+	// This allows the host to prepare state the start function observes, e.g.
+	// by writing to memory, setting a mutable global or populating a table:
+	//
+	//	config := wazero.NewModuleConfig().WithBeforeStart(func(ctx context.Context, mod api.Module) error {
+	//		if !mod.Memory().Write(0, seed) {
+	//			return errors.New("seed out of range")
+	//		}
+	//		return nil
+	//	})
+	//
+	// # Notes
+	//
+	//   - An error fails the instantiation, and the start function isn't
+	//     called.
+	//   - The module can't yet be imported by other modules or looked up via
+	//     Runtime.Module, as it isn't registered until instantiation succeeds.
+	WithBeforeStart(func(ctx context.Context, mod api.Module) error) ModuleConfig
+
 	// WithEnv sets an environment variable visible to a Module that imports functions. Defaults to none.
 	// Runtime.InstantiateModule errs if the key is empty or contains a NULL(0) or equals("") character.
 	//
@@ -483,6 +1281,210 @@ type ModuleConfig interface {
 	// (e.g. syscall.ENOSYS).
 	WithFSConfig(FSConfig) ModuleConfig
 
+	// WithFuel limits how long a guest can run, by failing it with a
+	// sys.ExitError of sys.ExitCodeFuelExhausted once it has consumed the
+	// given amount of fuel. Defaults to unlimited.
+	//
+	// One unit of fuel is consumed each time execution enters a guest
+	// function or reaches the start of a loop, including in the start
+	// functions. Fuel is shared by all calls into the same module instance
+	// and is not replenished.
+	//
+	// # Notes
+	//
+	//   - This is only supported by the interpreter and the optimizing
+	//     compiler (wazevo). With the compiler, instantiation fails.
+	//   - The optimizing compiler compiles a variant of the module that
+	//     consumes fuel the first time it is instantiated with fuel.
+	//     Functions of modules instantiated without fuel don't consume any.
+	//   - The fuel of the module whose function is called from the host is
+	//     consumed by all guest functions nested in that call, including
+	//     those of other modules.
+	//   - Once fuel is exhausted, the module is closed and cannot be used
+	//     again.
+	//   - Host functions do not consume fuel.
+	WithFuel(fuel uint64) ModuleConfig
+
+	// WithMaxCallDepth limits the nesting of calls into Wasm functions, by
+	// trapping with a "call stack exhausted" error once a call would nest
+	// deeper than the given depth. Defaults to zero, which means unlimited.
+	//
+	// The depth counts the guest function called from the host as one, and is
+	// checked on entry of each guest function. Unlike the stack overflow
+	// detection, this gives a predictable, catchable error regardless of the
+	// size of the frames.
+	//
+	// # Notes
+	//
+	//   - This is only supported by the interpreter and the optimizing
+	//     compiler (wazevo). With the compiler, instantiation fails.
+	//   - The optimizing compiler compiles a variant of the module that
+	//     counts calls the first time it is instantiated with a limit.
+	//     Functions of modules instantiated without one aren't counted.
+	//   - The limit of the module whose function is called from the host
+	//     applies to all guest functions nested in that call, including
+	//     those of other modules. A call back into a guest from a host
+	//     function starts counting from zero again.
+	//   - Tail calls don't increase the depth.
+	WithMaxCallDepth(n uint32) ModuleConfig
+
+	// WithMemoryGrowHook registers a function called before a memory defined
+	// by the module grows, with its size in pages before and after growing.
+	// Returning false fails the growth, so "memory.grow" returns -1 and
+	// api.Memory Grow returns false. Defaults to nil.
+	//
+	// This allows dynamic policies a static maximum can't express, e.g. to
+	// apply backpressure when the host is low on memory:
+	//
+	//	config := wazero.NewModuleConfig().WithMemoryGrowHook(func(mod api.Module, previousPages, newPages uint32) bool {
+	//		return budget.Reserve(uint64(newPages-previousPages) * 65536)
+	//	})
+	//
+	// # Notes
+	//
+	//   - The hook is called before any allocation, and only for growth
+	//     within the maximum of the memory.
+	//   - The hook is called for the growth of a memory by any module which
+	//     imports it, as well as by the host.
+	//   - The hook must not grow the memory or call into the module.
+	WithMemoryGrowHook(hook func(mod api.Module, previousPages, newPages uint32) bool) ModuleConfig
+
+	// WithHostPanicRecovery converts a panic of a host function called by
+	// the module into the error returned by recoverFn, which traps the
+	// module, so the guest stack unwinds and the call into the module
+	// returns that error. Defaults to nil, which returns the panic value
+	// wrapped in an error, as is.
+	//
+	// For example, this distinguishes host bugs from other errors:
+	//
+	//	config := wazero.NewModuleConfig().WithHostPanicRecovery(func(recovered interface{}) error {
+	//		return fmt.Errorf("%w: %v", errHostBug, recovered)
+	//	})
+	//
+	// # Notes
+	//
+	//   - recoverFn is called on the goroutine of the panic. Returning nil
+	//     keeps the panic value, as if there was no recoverFn.
+	//   - A sys.ExitError, such as from the WASI "proc_exit" function, isn't
+	//     a failure, so it isn't passed to recoverFn.
+	//   - Like other traps, the returned error is wrapped with the wasm
+	//     stack trace, so use errors.Is or errors.As to match it.
+	//   - This applies to host functions called by this module, including
+	//     during its start function, but not to host functions called by
+	//     other modules it imports.
+	WithHostPanicRecovery(recoverFn func(recovered interface{}) error) ModuleConfig
+
+	// WithHostCallLogger calls logger after each call of a host function by
+	// the module, with the module and function name of its definition, and
+	// its params and results encoded as in api.Function Call. Defaults to
+	// nil, which doesn't log.
+	//
+	// For example, this records each call into the host for an audit log:
+	//
+	//	config := wazero.NewModuleConfig().WithHostCallLogger(func(module, name string, params, results []uint64) {
+	//		log.Printf("%s.%s%v -> %v", module, name, params, results)
+	//	})
+	//
+	// # Notes
+	//
+	//   - logger is called on the goroutine of the call, once the host
+	//     function returns. The results are nil if it didn't return, such
+	//     as when it panicked or exited the module, and the panic continues
+	//     after logger returns.
+	//   - results is only valid until logger returns, so copy it to retain.
+	//   - This doesn't change the calls, but copies the params of each, so
+	//     only set it when needed.
+	//   - This applies to host functions called by this module, including
+	//     during its start function, but not to host functions called by
+	//     other modules it imports.
+	WithHostCallLogger(logger func(module, name string, params, results []uint64)) ModuleConfig
+
+	// WithImportRename resolves the imports from the module named fromModule
+	// against the module named toModule instead. This allows satisfying
+	// guests which import the same functions under different module names,
+	// without instantiating the host module multiple times.
+	//
+	// For example, this resolves imports from "env" with the "host" module:
+	//
+	//	config := wazero.NewModuleConfig().WithImportRename("env", "host")
+	//
+	// # Notes
+	//
+	//   - Instantiation fails if a module named fromModule is instantiated,
+	//     as the rename would shadow it.
+	//   - Renames are not transitive: imports are resolved against toModule
+	//     even if it is also renamed.
+	WithImportRename(fromModule, toModule string) ModuleConfig
+
+	// WithImportStub resolves the function imported as name from the module
+	// named moduleName with a stub, which ignores its parameters and returns
+	// the results, encoded as for api.Function Call. This allows isolating
+	// the time spent in the guest when profiling, without editing the host
+	// module.
+	//
+	// For example, this makes the import "env" "expensive", returning an
+	// i32, return zero:
+	//
+	//	config := wazero.NewModuleConfig().
+	//		WithImportStub("env", "expensive", []uint64{api.EncodeI32(0)})
+	//
+	// # Notes
+	//
+	//   - Instantiation fails unless the module imports a function of that
+	//     name, with as many results.
+	//   - The stub applies to the module name as imported, before any rename
+	//     via WithImportRename.
+	//   - The module named moduleName doesn't need to be instantiated when
+	//     all the functions imported from it are stubbed.
+	//   - A module importing stubs can't be cloned via api.Module Clone.
+	WithImportStub(moduleName, name string, results []uint64) ModuleConfig
+
+	// WithMinMemoryPages allocates the memory defined by the module with at
+	// least minPages, instead of the minimum it declares. Defaults to zero,
+	// which allocates the declared minimum.
+	//
+	// This allows sizing the memory of each instance of the same compiled
+	// module differently, without the cost of "memory.grow" calls at startup.
+	//
+	// # Notes
+	//
+	//   - Instantiation fails if minPages is over the maximum of the memory,
+	//     which is limited by RuntimeConfig.WithMemoryLimitPages.
+	//   - This has no effect on a module which doesn't define a memory, or
+	//     imports it.
+	//   - The declared minimum is still the minimum of the memory, e.g. in
+	//     api.MemoryDefinition Min.
+	WithMinMemoryPages(minPages uint32) ModuleConfig
+
+	// WithMemoryBuffer backs the memory defined by the module with buf,
+	// instead of allocating it. Defaults to nil, which allocates the memory.
+	//
+	// The memory starts with its declared minimum, or the pages set by
+	// WithMinMemoryPages if more, and can grow up to the length of buf. This
+	// allows pooling buffers across instances, e.g. to avoid mmap churn:
+	//
+	//	buf := make([]byte, 16*65536)
+	//	mod, _ := r.InstantiateModule(ctx, compiled,
+	//		wazero.NewModuleConfig().WithMemoryBuffer(buf))
+	//	// ... use mod
+	//	_ = mod.Close(ctx) // buf can be reused now.
+	//
+	// # Notes
+	//
+	//   - Instantiation fails unless the length of buf is a multiple of the
+	//     page size (65536 bytes), and at least the initial pages of the
+	//     memory. A shared memory needs buf to hold its maximum pages.
+	//   - buf is zeroed during instantiation, and used until the module is
+	//     closed, which drops the reference to it. Instantiation fails while
+	//     another module of the Runtime uses buf, e.g. when reusing this
+	//     ModuleConfig before closing the module instantiated with it.
+	//   - "memory.grow" fails beyond the length of buf, instead of
+	//     reallocating the memory.
+	//   - This has no effect on a module which doesn't define a memory, or
+	//     imports it. A clone of the module via api.Module Clone allocates
+	//     its own memory.
+	WithMemoryBuffer(buf []byte) ModuleConfig
+
 	// WithName configures the module name. Defaults to what was decoded from
 	// the name section. Empty string ("") clears any name.
 	WithName(string) ModuleConfig
@@ -505,6 +1507,21 @@ type ModuleConfig interface {
 	//   - See /RATIONALE.md for motivation of this feature.
 	WithStartFunctions(...string) ModuleConfig
 
+	// WithStartSection configures whether the function in the start section
+	// of the module, if any, is called during instantiation. Defaults to true.
+	//
+	// When false, it isn't called until api.Module RunStart, if ever. This
+	// allows preparing the module beforehand, e.g. mocking the I/O of the
+	// start function, and controlling the order of initialization.
+	//
+	// # Notes
+	//
+	//   - This is unrelated to WithStartFunctions, which are still called.
+	//   - The start function is validated regardless, so a start section with
+	//     an invalid signature fails compilation.
+	//   - The start function is never called more than once.
+	WithStartSection(enabled bool) ModuleConfig
+
 	// WithStderr configures where standard error (file descriptor 2) is written. Defaults to io.Discard.
 	//
 	// This writer is most commonly used by the functions like "fd_write" in "wasi_snapshot_preview1" although it could
@@ -513,11 +1530,21 @@ type ModuleConfig interface {
 	// # Notes
 	//
 	//   - The caller is responsible to close any io.Writer they supply: It is not closed on api.Module Close.
+	//   - If the io.Writer has a method `Flush() error`, such as bufio.Writer, it is flushed on api.Module Close.
 	//   - This does not default to os.Stderr as that both violates sandboxing and prevents concurrent modules.
 	//
 	// See https://linux.die.net/man/3/stderr
 	WithStderr(io.Writer) ModuleConfig
 
+	// WithStderrBuffer is a convenience for WithStderr, which captures standard error (file descriptor 2) into the
+	// given buffer. This is typically used in tests, to assert on what the guest wrote:
+	//
+	//	var stderr bytes.Buffer
+	//	config := wazero.NewModuleConfig().WithStderrBuffer(&stderr)
+	//
+	// A nil buffer resets standard error to the default, io.Discard.
+	WithStderrBuffer(*bytes.Buffer) ModuleConfig
+
 	// WithStdin configures where standard input (file descriptor 0) is read. Defaults to return io.EOF.
 	//
 	// This reader is most commonly used by the functions like "fd_read" in "wasi_snapshot_preview1" although it could
@@ -527,6 +1554,9 @@ type ModuleConfig interface {
 	//
 	//   - The caller is responsible to close any io.Reader they supply: It is not closed on api.Module Close.
 	//   - This does not default to os.Stdin as that both violates sandboxing and prevents concurrent modules.
+	//   - If the io.Reader has a method `Poll(timeoutMillis int32) (ready bool)`, it is used to wait for data, e.g.
+	//     in "poll_oneoff". Moreover, a read that returns no data without an error waits on Poll instead of being
+	//     mistaken for EOF by the guest. A negative timeoutMillis means to wait indefinitely.
 	//
 	// See https://linux.die.net/man/3/stdin
 	WithStdin(io.Reader) ModuleConfig
@@ -539,11 +1569,40 @@ type ModuleConfig interface {
 	// # Notes
 	//
 	//   - The caller is responsible to close any io.Writer they supply: It is not closed on api.Module Close.
+	//   - If the io.Writer has a method `Flush() error`, such as bufio.Writer, it is flushed on api.Module Close.
 	//   - This does not default to os.Stdout as that both violates sandboxing and prevents concurrent modules.
 	//
 	// See https://linux.die.net/man/3/stdout
 	WithStdout(io.Writer) ModuleConfig
 
+	// WithStdoutBuffer is a convenience for WithStdout, which captures standard output (file descriptor 1) into the
+	// given buffer. This is typically used in tests, to assert on what the guest wrote:
+	//
+	//	var stdout bytes.Buffer
+	//	config := wazero.NewModuleConfig().WithStdoutBuffer(&stdout)
+	//
+	// A nil buffer resets standard output to the default, io.Discard.
+	WithStdoutBuffer(*bytes.Buffer) ModuleConfig
+
+	// WithCombinedOutput configures both standard output (file descriptor 1)
+	// and standard error (file descriptor 2) to be written to the same writer,
+	// in the order of the writes. This is typically used in tests, to assert
+	// on the output of a guest as a whole, such as with a golden file:
+	//
+	//	var out bytes.Buffer
+	//	config := wazero.NewModuleConfig().WithCombinedOutput(&out)
+	//
+	// # Notes
+	//
+	//   - Each write is serialized, so concurrent writes, such as by guest
+	//     threads, don't corrupt the writer. However, a write of multiple
+	//     buffers, such as "fd_write" with multiple iovecs, can interleave
+	//     with the writes of another thread.
+	//   - This replaces the writers of WithStdout and WithStderr, and a later
+	//     call to either replaces the writer of its file descriptor.
+	//   - A nil writer resets both to the default, io.Discard.
+	WithCombinedOutput(io.Writer) ModuleConfig
+
 	// WithWalltime configures the wall clock, sometimes referred to as the
 	// real time clock. sys.Walltime returns the current unix/epoch time,
 	// seconds since midnight UTC 1 January 1970, with a nanosecond fraction.
@@ -630,7 +1689,9 @@ type ModuleConfig interface {
 	//
 	// This reader is most commonly used by the functions like "random_get" in
 	// "wasi_snapshot_preview1", "seed" in AssemblyScript standard "env", and
-	// "getRandomData" when runtime.GOOS is "js".
+	// "getRandomData" when runtime.GOOS is "js". These read until the buffer
+	// is full, so a reader returning fewer bytes per read is fine, and
+	// "random_get" returns EIO when the reader errs.
 	//
 	// Note: The caller is responsible to close any io.Reader they supply: It
 	// is not closed on api.Module Close.
@@ -641,6 +1702,7 @@ type moduleConfig struct {
 	name               string
 	nameSet            bool
 	startFunctions     []string
+	startSection       bool
 	stdin              io.Reader
 	stdout             io.Writer
 	stderr             io.Writer
@@ -660,12 +1722,34 @@ type moduleConfig struct {
 	fsConfig FSConfig
 	// sockConfig is the network listener configuration for ABI like WASI.
 	sockConfig *internalsock.Config
+	// fuel is the fuel limit when fuelSet.
+	fuel    uint64
+	fuelSet bool
+	// maxCallDepth is the maximum call depth, or zero if unlimited.
+	maxCallDepth uint32
+	// importRenames maps imported module names to those resolving them.
+	importRenames map[string]string
+	// importStubs maps imported module names and function names to the results of their stubs.
+	importStubs map[string]map[string][]uint64
+	// minMemoryPages is the minimum pages to allocate the memory defined by the module with.
+	minMemoryPages uint32
+	// memoryBuffer backs the memory defined by the module when non-nil.
+	memoryBuffer []byte
+	// beforeStart is called before the start function when non-nil.
+	beforeStart func(ctx context.Context, mod api.Module) error
+	// memoryGrowHook is called before a memory defined by the module grows when non-nil.
+	memoryGrowHook func(mod api.Module, previousPages, newPages uint32) bool
+	// hostPanicRecovery converts a panic of a host function called by the module when non-nil.
+	hostPanicRecovery func(recovered interface{}) error
+	// hostCallLogger is called after each call of a host function by the module when non-nil.
+	hostCallLogger func(module, name string, params, results []uint64)
 }
 
 // NewModuleConfig returns a ModuleConfig that can be used for configuring module instantiation.
 func NewModuleConfig() ModuleConfig {
 	return &moduleConfig{
 		startFunctions: []string{"_start"},
+		startSection:   true,
 		environKeys:    map[string]int{},
 	}
 }
@@ -677,6 +1761,21 @@ func (c *moduleConfig) clone() *moduleConfig {
 	for key, value := range c.environKeys {
 		ret.environKeys[key] = value
 	}
+	if c.importRenames != nil {
+		ret.importRenames = make(map[string]string, len(c.importRenames))
+		for from, to := range c.importRenames {
+			ret.importRenames[from] = to
+		}
+	}
+	if c.importStubs != nil {
+		ret.importStubs = make(map[string]map[string][]uint64, len(c.importStubs))
+		for moduleName, stubs := range c.importStubs {
+			ret.importStubs[moduleName] = make(map[string][]uint64, len(stubs))
+			for name, results := range stubs {
+				ret.importStubs[moduleName][name] = results
+			}
+		}
+	}
 	return &ret
 }
 
@@ -698,6 +1797,13 @@ func toByteSlices(strings []string) (result [][]byte) {
 	return
 }
 
+// WithBeforeStart implements ModuleConfig.WithBeforeStart
+func (c *moduleConfig) WithBeforeStart(beforeStart func(ctx context.Context, mod api.Module) error) ModuleConfig {
+	ret := c.clone()
+	ret.beforeStart = beforeStart
+	return ret
+}
+
 // WithEnv implements ModuleConfig.WithEnv
 func (c *moduleConfig) WithEnv(key, value string) ModuleConfig {
 	ret := c.clone()
@@ -727,6 +1833,78 @@ func (c *moduleConfig) WithFSConfig(config FSConfig) ModuleConfig {
 	return ret
 }
 
+// WithFuel implements ModuleConfig.WithFuel
+func (c *moduleConfig) WithFuel(fuel uint64) ModuleConfig {
+	ret := c.clone()
+	ret.fuelSet = true
+	ret.fuel = fuel
+	return ret
+}
+
+// WithMaxCallDepth implements ModuleConfig.WithMaxCallDepth
+func (c *moduleConfig) WithMaxCallDepth(n uint32) ModuleConfig {
+	ret := c.clone()
+	ret.maxCallDepth = n
+	return ret
+}
+
+// WithImportRename implements ModuleConfig.WithImportRename
+func (c *moduleConfig) WithImportRename(fromModule, toModule string) ModuleConfig {
+	ret := c.clone()
+	if ret.importRenames == nil {
+		ret.importRenames = map[string]string{}
+	}
+	ret.importRenames[fromModule] = toModule
+	return ret
+}
+
+// WithImportStub implements ModuleConfig.WithImportStub
+func (c *moduleConfig) WithImportStub(moduleName, name string, results []uint64) ModuleConfig {
+	ret := c.clone()
+	if ret.importStubs == nil {
+		ret.importStubs = map[string]map[string][]uint64{}
+	}
+	if ret.importStubs[moduleName] == nil {
+		ret.importStubs[moduleName] = map[string][]uint64{}
+	}
+	ret.importStubs[moduleName][name] = append([]uint64{}, results...)
+	return ret
+}
+
+// WithMinMemoryPages implements ModuleConfig.WithMinMemoryPages
+func (c *moduleConfig) WithMinMemoryPages(minPages uint32) ModuleConfig {
+	ret := c.clone()
+	ret.minMemoryPages = minPages
+	return ret
+}
+
+// WithMemoryBuffer implements ModuleConfig.WithMemoryBuffer
+func (c *moduleConfig) WithMemoryBuffer(buf []byte) ModuleConfig {
+	ret := c.clone()
+	ret.memoryBuffer = buf
+	return ret
+}
+
+func (c *moduleConfig) WithMemoryGrowHook(hook func(mod api.Module, previousPages, newPages uint32) bool) ModuleConfig {
+	ret := c.clone()
+	ret.memoryGrowHook = hook
+	return ret
+}
+
+// WithHostPanicRecovery implements ModuleConfig.WithHostPanicRecovery
+func (c *moduleConfig) WithHostPanicRecovery(recoverFn func(recovered interface{}) error) ModuleConfig {
+	ret := c.clone()
+	ret.hostPanicRecovery = recoverFn
+	return ret
+}
+
+// WithHostCallLogger implements ModuleConfig.WithHostCallLogger
+func (c *moduleConfig) WithHostCallLogger(logger func(module, name string, params, results []uint64)) ModuleConfig {
+	ret := c.clone()
+	ret.hostCallLogger = logger
+	return ret
+}
+
 // WithName implements ModuleConfig.WithName
 func (c *moduleConfig) WithName(name string) ModuleConfig {
 	ret := c.clone()
@@ -742,6 +1920,13 @@ func (c *moduleConfig) WithStartFunctions(startFunctions ...string) ModuleConfig
 	return ret
 }
 
+// WithStartSection implements ModuleConfig.WithStartSection
+func (c *moduleConfig) WithStartSection(enabled bool) ModuleConfig {
+	ret := c.clone()
+	ret.startSection = enabled
+	return ret
+}
+
 // WithStderr implements ModuleConfig.WithStderr
 func (c *moduleConfig) WithStderr(stderr io.Writer) ModuleConfig {
 	ret := c.clone()
@@ -749,6 +1934,16 @@ func (c *moduleConfig) WithStderr(stderr io.Writer) ModuleConfig {
 	return ret
 }
 
+// WithStderrBuffer implements ModuleConfig.WithStderrBuffer
+func (c *moduleConfig) WithStderrBuffer(stderr *bytes.Buffer) ModuleConfig {
+	ret := c.clone()
+	ret.stderr = nil
+	if stderr != nil { // avoid a non-nil io.Writer wrapping a nil pointer
+		ret.stderr = stderr
+	}
+	return ret
+}
+
 // WithStdin implements ModuleConfig.WithStdin
 func (c *moduleConfig) WithStdin(stdin io.Reader) ModuleConfig {
 	ret := c.clone()
@@ -763,6 +1958,50 @@ func (c *moduleConfig) WithStdout(stdout io.Writer) ModuleConfig {
 	return ret
 }
 
+// WithStdoutBuffer implements ModuleConfig.WithStdoutBuffer
+func (c *moduleConfig) WithStdoutBuffer(stdout *bytes.Buffer) ModuleConfig {
+	ret := c.clone()
+	ret.stdout = nil
+	if stdout != nil { // avoid a non-nil io.Writer wrapping a nil pointer
+		ret.stdout = stdout
+	}
+	return ret
+}
+
+// WithCombinedOutput implements ModuleConfig.WithCombinedOutput
+func (c *moduleConfig) WithCombinedOutput(output io.Writer) ModuleConfig {
+	ret := c.clone()
+	ret.stdout, ret.stderr = nil, nil
+	if output != nil {
+		combined := &combinedWriter{w: output}
+		ret.stdout, ret.stderr = combined, combined
+	}
+	return ret
+}
+
+// combinedWriter serializes the writes to standard output and standard error. See ModuleConfig.WithCombinedOutput
+type combinedWriter struct {
+	mux sync.Mutex
+	w   io.Writer
+}
+
+// Write implements io.Writer
+func (c *combinedWriter) Write(p []byte) (int, error) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	return c.w.Write(p)
+}
+
+// Flush flushes the writer, if it has a method `Flush() error`, as done for writers passed to WithStdout.
+func (c *combinedWriter) Flush() error {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	if flusher, ok := c.w.(interface{ Flush() error }); ok {
+		return flusher.Flush()
+	}
+	return nil
+}
+
 // WithWalltime implements ModuleConfig.WithWalltime
 func (c *moduleConfig) WithWalltime(walltime sys.Walltime, resolution sys.ClockResolution) ModuleConfig {
 	ret := c.clone()
@@ -859,7 +2098,7 @@ func (c *moduleConfig) toSysContext() (sysCtx *internalsys.Context, err error) {
 		}
 	}
 
-	return internalsys.NewContext(
+	if sysCtx, err = internalsys.NewContext(
 		math.MaxUint32,
 		c.args,
 		environ,
@@ -872,5 +2111,12 @@ func (c *moduleConfig) toSysContext() (sysCtx *internalsys.Context, err error) {
 		c.nanosleep, c.osyield,
 		fs, guestPaths,
 		listeners,
-	)
+	); err != nil {
+		return
+	}
+	if c.fuelSet {
+		sysCtx.SetFuel(c.fuel)
+	}
+	sysCtx.SetMaxCallDepth(c.maxCallDepth)
+	return
 }
@@ -1,9 +1,12 @@
 package wazero
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	_ "embed"
+	goruntime "runtime"
+	"strings"
 	"testing"
 	"time"
 
@@ -13,6 +16,7 @@ import (
 	"github.com/tetratelabs/wazero/internal/platform"
 	internalsys "github.com/tetratelabs/wazero/internal/sys"
 	"github.com/tetratelabs/wazero/internal/sysfs"
+	"github.com/tetratelabs/wazero/internal/testing/binaryencoding"
 	testfs "github.com/tetratelabs/wazero/internal/testing/fs"
 	"github.com/tetratelabs/wazero/internal/testing/require"
 	"github.com/tetratelabs/wazero/internal/wasm"
@@ -52,6 +56,116 @@ func TestRuntimeConfig(t *testing.T) {
 				memoryCapacityFromMax: true,
 			},
 		},
+		{
+			name: "memoryPrefault",
+			with: func(c RuntimeConfig) RuntimeConfig {
+				return c.WithMemoryPrefault(true)
+			},
+			expected: &runtimeConfig{
+				memoryPrefault: true,
+			},
+		},
+		{
+			name: "memoryZeroOnClose",
+			with: func(c RuntimeConfig) RuntimeConfig {
+				return c.WithMemoryZeroOnClose(true)
+			},
+			expected: &runtimeConfig{
+				memoryZeroOnClose: true,
+			},
+		},
+		{
+			name: "strictFloat",
+			with: func(c RuntimeConfig) RuntimeConfig {
+				return c.WithStrictFloat()
+			},
+			expected: &runtimeConfig{
+				strictFloat: true,
+			},
+		},
+		{
+			name: "pprofLabels",
+			with: func(c RuntimeConfig) RuntimeConfig {
+				return c.WithPprofLabels(true)
+			},
+			expected: &runtimeConfig{
+				pprofLabels: true,
+			},
+		},
+		{
+			name: "differentialCheck",
+			with: func(c RuntimeConfig) RuntimeConfig {
+				return c.WithDifferentialCheck(true)
+			},
+			expected: &runtimeConfig{
+				differentialCheck: true,
+			},
+		},
+		{
+			name: "maxBlockNestingDepth",
+			with: func(c RuntimeConfig) RuntimeConfig {
+				return c.WithMaxBlockNestingDepth(1024)
+			},
+			expected: &runtimeConfig{
+				maxBlockNestingDepth: 1024,
+			},
+		},
+		{
+			name: "WithMaxBlockNestingDepth zero",
+			with: func(c RuntimeConfig) RuntimeConfig {
+				return c.WithMaxBlockNestingDepth(0)
+			},
+			expected: &runtimeConfig{
+				// Zero keeps the default.
+				maxBlockNestingDepth: wasm.MaximumBlockNestingDepth,
+			},
+		},
+		{
+			name: "WithDecodeLimits",
+			with: func(c RuntimeConfig) RuntimeConfig {
+				return c.WithDecodeLimits(10, 20, 0, 40)
+			},
+			expected: &runtimeConfig{
+				// Zero keeps the default.
+				decodeLimits: wasm.DecodeLimits{MaxFunctions: 10, MaxTypes: 20, MaxImports: wasm.MaximumImports, MaxExports: 40},
+			},
+		},
+		{
+			name: "inliningThreshold",
+			with: func(c RuntimeConfig) RuntimeConfig {
+				return c.WithInliningThreshold(20)
+			},
+			expected: &runtimeConfig{
+				inliningThreshold: 20,
+			},
+		},
+		{
+			name: "WithCanonicalNaN",
+			with: func(c RuntimeConfig) RuntimeConfig {
+				return c.WithCanonicalNaN(true)
+			},
+			expected: &runtimeConfig{
+				canonicalNaN: true,
+			},
+		},
+		{
+			name: "WithMemorySanitizer",
+			with: func(c RuntimeConfig) RuntimeConfig {
+				return c.WithMemorySanitizer(true)
+			},
+			expected: &runtimeConfig{
+				memorySanitizer: true,
+			},
+		},
+		{
+			name: "WithPerFunctionCompileTimeout",
+			with: func(c RuntimeConfig) RuntimeConfig {
+				return c.WithPerFunctionCompileTimeout(time.Second)
+			},
+			expected: &runtimeConfig{
+				functionTimeout: time.Second,
+			},
+		},
 		{
 			name: "WithDebugInfoEnabled",
 			with: func(c RuntimeConfig) RuntimeConfig {
@@ -89,6 +203,46 @@ func TestRuntimeConfig(t *testing.T) {
 		})
 	}
 
+	t.Run("WithSSADumper", func(t *testing.T) {
+		input := &runtimeConfig{}
+		var called bool
+		rc := input.WithSSADumper(func(funcName, stage, ssaText string) { called = true }).(*runtimeConfig)
+		rc.ssaDumper("", "", "")
+		require.True(t, called)
+		// The source wasn't modified
+		require.Nil(t, input.ssaDumper)
+	})
+
+	t.Run("WithRegAllocObserver", func(t *testing.T) {
+		input := &runtimeConfig{}
+		var called bool
+		rc := input.WithRegAllocObserver(func(funcName string, info RegAllocInfo) { called = true }).(*runtimeConfig)
+		rc.regAllocObserver("", RegAllocInfo{})
+		require.True(t, called)
+		// The source wasn't modified
+		require.Nil(t, input.regAllocObserver)
+	})
+
+	t.Run("WithFallbackInterpreter", func(t *testing.T) {
+		input := &runtimeConfig{}
+		rc := input.WithFallbackInterpreter(func(binary []byte) bool { return len(binary) > 0 }).(*runtimeConfig)
+		require.True(t, rc.fallbackInterpreter([]byte{0}))
+		// The source wasn't modified
+		require.Nil(t, input.fallbackInterpreter)
+	})
+
+	t.Run("WithCallTracer", func(t *testing.T) {
+		input := &runtimeConfig{}
+		var entered uint32
+		rc := input.WithCallTracer(func(funcIdx uint32) { entered = funcIdx }, nil).(*runtimeConfig)
+		rc.callTracer.enter(3)
+		require.Equal(t, uint32(3), entered)
+		// The source wasn't modified
+		require.Nil(t, input.callTracer)
+		// Passing nil functions disables tracing.
+		require.Nil(t, rc.WithCallTracer(nil, nil).(*runtimeConfig).callTracer)
+	})
+
 	t.Run("memoryLimitPages invalid panics", func(t *testing.T) {
 		err := require.CapturePanic(func() {
 			input := &runtimeConfig{}
@@ -160,6 +314,67 @@ func TestModuleConfig(t *testing.T) {
 	}
 }
 
+func TestModuleConfig_WithStdioBuffer(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	input := NewModuleConfig()
+	rc := input.WithStdoutBuffer(&stdout).WithStderrBuffer(&stderr).(*moduleConfig)
+	require.Equal(t, &stdout, rc.stdout)
+	require.Equal(t, &stderr, rc.stderr)
+	// The source wasn't modified
+	require.Equal(t, NewModuleConfig(), input)
+
+	t.Run("nil resets to default", func(t *testing.T) {
+		rc := rc.WithStdoutBuffer(nil).WithStderrBuffer(nil).(*moduleConfig)
+		require.Nil(t, rc.stdout)
+		require.Nil(t, rc.stderr)
+	})
+}
+
+func TestModuleConfig_WithCombinedOutput(t *testing.T) {
+	var out bytes.Buffer
+	input := NewModuleConfig()
+	rc := input.WithCombinedOutput(&out).(*moduleConfig)
+	// The source wasn't modified
+	require.Equal(t, NewModuleConfig(), input)
+
+	sysCtx, err := rc.toSysContext()
+	require.NoError(t, err)
+	stdout, ok := sysCtx.FS().LookupFile(internalsys.FdStdout)
+	require.True(t, ok)
+	stderr, ok := sysCtx.FS().LookupFile(internalsys.FdStderr)
+	require.True(t, ok)
+
+	// The writes are in order, regardless of the file descriptor.
+	for _, w := range []struct {
+		f    *internalsys.FileEntry
+		text string
+	}{{stdout, "out1 "}, {stderr, "err1 "}, {stdout, "out2 "}, {stderr, "err2"}} {
+		_, errno := w.f.File.Write([]byte(w.text))
+		require.EqualErrno(t, 0, errno)
+	}
+	require.Equal(t, "out1 err1 out2 err2", out.String())
+
+	t.Run("nil resets to default", func(t *testing.T) {
+		rc := rc.WithCombinedOutput(nil).(*moduleConfig)
+		require.Nil(t, rc.stdout)
+		require.Nil(t, rc.stderr)
+	})
+
+	t.Run("flushes", func(t *testing.T) {
+		var out bytes.Buffer
+		w := bufio.NewWriter(&out)
+		sysCtx, err := NewModuleConfig().WithCombinedOutput(w).(*moduleConfig).toSysContext()
+		require.NoError(t, err)
+		stderr, _ := sysCtx.FS().LookupFile(internalsys.FdStderr)
+		_, errno := stderr.File.Write([]byte("buffered"))
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, "", out.String())
+
+		require.NoError(t, sysCtx.FS().Close())
+		require.Equal(t, "buffered", out.String())
+	})
+}
+
 // TestModuleConfig_toSysContext only tests the cases that change the inputs to
 // sys.NewContext.
 func TestModuleConfig_toSysContext(t *testing.T) {
@@ -175,6 +390,37 @@ func TestModuleConfig_toSysContext(t *testing.T) {
 				return base, func(t *testing.T, sys *internalsys.Context) { require.NotNil(t, sys) }
 			},
 		},
+		{
+			name: "WithFuel",
+			input: func() (ModuleConfig, func(t *testing.T, sys *internalsys.Context)) {
+				config := base.WithFuel(42)
+				return config, func(t *testing.T, sys *internalsys.Context) {
+					fuel, limited := sys.Fuel()
+					require.True(t, limited)
+					require.Equal(t, uint64(42), fuel)
+				}
+			},
+		},
+		{
+			name: "WithFuel zero",
+			input: func() (ModuleConfig, func(t *testing.T, sys *internalsys.Context)) {
+				config := base.WithFuel(0)
+				return config, func(t *testing.T, sys *internalsys.Context) {
+					fuel, limited := sys.Fuel()
+					require.True(t, limited)
+					require.Zero(t, fuel)
+				}
+			},
+		},
+		{
+			name: "WithMaxCallDepth",
+			input: func() (ModuleConfig, func(t *testing.T, sys *internalsys.Context)) {
+				config := base.WithMaxCallDepth(42)
+				return config, func(t *testing.T, sys *internalsys.Context) {
+					require.Equal(t, uint32(42), sys.MaxCallDepth())
+				}
+			},
+		},
 		{
 			name: "WithNanotime",
 			input: func() (ModuleConfig, func(t *testing.T, sys *internalsys.Context)) {
@@ -591,12 +837,24 @@ func TestModuleConfig_clone(t *testing.T) {
 	// Make post-clone changes
 	mc.fsConfig = NewFSConfig().WithFSMount(fstest.FS, "/")
 	mc.environKeys["2"] = 2
+	mc.importRenames = map[string]string{"a": "b"}
 
 	cloned.environKeys["1"] = 1
 
 	// Ensure the maps are not shared
 	require.Equal(t, map[string]int{"2": 2}, mc.environKeys)
 	require.Equal(t, map[string]int{"1": 1}, cloned.environKeys)
+	require.Nil(t, cloned.importRenames)
+
+	renamed := mc.WithImportRename("c", "d").(*moduleConfig)
+	require.Equal(t, map[string]string{"a": "b"}, mc.importRenames)
+	require.Equal(t, map[string]string{"a": "b", "c": "d"}, renamed.importRenames)
+
+	stubbed := mc.WithImportStub("env", "f", []uint64{1}).(*moduleConfig)
+	stubbedAgain := stubbed.WithImportStub("env", "g", nil).(*moduleConfig)
+	require.Nil(t, mc.importStubs)
+	require.Equal(t, map[string]map[string][]uint64{"env": {"f": {1}}}, stubbed.importStubs)
+	require.Equal(t, map[string]map[string][]uint64{"env": {"f": {1}, "g": {}}}, stubbedAgain.importStubs)
 
 	// Ensure the fs is not shared
 	require.Nil(t, cloned.fsConfig)
@@ -675,6 +933,95 @@ func Test_compiledModule_CustomSections(t *testing.T) {
 	}
 }
 
+func Test_compiledModule_CallGraph(t *testing.T) {
+	m := &compiledModule{module: &wasm.Module{
+		TypeSection:         []wasm.FunctionType{{}, {Params: []wasm.ValueType{wasm.ValueTypeI32}}},
+		ImportSection:       []wasm.Import{{Type: wasm.ExternTypeFunc, DescFunc: 0}},
+		ImportFunctionCount: 1,
+		FunctionSection:     []wasm.Index{0, 1},
+		TableSection:        []wasm.Table{{Min: 1, Type: wasm.RefTypeFuncref}},
+		CodeSection: []wasm.Code{
+			{Body: []byte{wasm.OpcodeCall, 0, wasm.OpcodeI32Const, 1, wasm.OpcodeCall, 2, wasm.OpcodeEnd}},
+			{Body: []byte{wasm.OpcodeI32Const, 0, wasm.OpcodeCallIndirect, 0, 0, wasm.OpcodeEnd}},
+		},
+	}}
+
+	require.Equal(t, []CallEdge{
+		{Caller: 1, Callee: 0},
+		{Caller: 1, Callee: 2},
+		{Caller: 2, Indirect: true, TypeIndex: 0},
+	}, m.CallGraph())
+}
+
+func Test_compiledModule_SectionRanges(t *testing.T) {
+	m := &compiledModule{module: &wasm.Module{}}
+	require.Equal(t, []SectionRange{}, m.SectionRanges())
+
+	m = &compiledModule{module: &wasm.Module{SectionRanges: []wasm.SectionRange{
+		{ID: wasm.SectionIDType, Start: 8, End: 14},
+		{ID: wasm.SectionIDCustom, Start: 14, End: 219},
+	}}}
+	require.Equal(t, []SectionRange{
+		{ID: wasm.SectionIDType, Start: 8, End: 14},
+		{ID: wasm.SectionIDCustom, Start: 14, End: 219},
+	}, m.SectionRanges())
+}
+
+func Test_compiledModule_Disassemble(t *testing.T) {
+	m := &compiledModule{module: &wasm.Module{}, compiledEngine: &mockEngine{}}
+	_, err := m.Disassemble(0)
+	require.EqualError(t, err, "disassembly is not supported by this engine")
+}
+
+func Test_compiledModule_BoundsCheckStats(t *testing.T) {
+	m := &compiledModule{module: &wasm.Module{}, compiledEngine: &mockEngine{}}
+	_, err := m.BoundsCheckStats(0)
+	require.EqualError(t, err, "bounds check stats are not supported by this engine")
+}
+
+func Test_compiledModule_TrapSites(t *testing.T) {
+	m := &compiledModule{module: &wasm.Module{}, compiledEngine: &mockEngine{}}
+	_, err := m.TrapSites(0)
+	require.EqualError(t, err, "trap sites are not supported by this engine")
+}
+
+func Test_compiledModule_FunctionBody(t *testing.T) {
+	r := NewRuntime(testCtx)
+	defer r.Close(testCtx)
+
+	bin := binaryencoding.EncodeModule(&wasm.Module{
+		TypeSection:     []wasm.FunctionType{{Params: []wasm.ValueType{wasm.ValueTypeI32}, Results: []wasm.ValueType{wasm.ValueTypeI32}}},
+		ImportSection:   []wasm.Import{{Module: "env", Name: "f", Type: wasm.ExternTypeFunc, DescFunc: 0}},
+		FunctionSection: []wasm.Index{0},
+		CodeSection: []wasm.Code{{Body: []byte{
+			wasm.OpcodeLocalGet, 0,
+			wasm.OpcodeIf, 0x7f, // i32 result
+			wasm.OpcodeI32Const, 1,
+			wasm.OpcodeElse,
+			wasm.OpcodeI32Const, 0x7f, // -1
+			wasm.OpcodeEnd,
+			wasm.OpcodeEnd,
+		}}},
+	})
+	compiled, err := r.CompileModule(testCtx, bin)
+	require.NoError(t, err)
+
+	body, err := compiled.FunctionBody(1)
+	require.NoError(t, err)
+	require.Equal(t, []BodyInstruction{
+		{Offset: 0, Opcode: wasm.OpcodeLocalGet, Name: "local.get", Immediates: []uint64{0}},
+		{Offset: 2, Opcode: wasm.OpcodeIf, Name: "if", Immediates: []uint64{api.EncodeI64(-1)}},
+		{Offset: 4, Opcode: wasm.OpcodeI32Const, Name: "i32.const", Immediates: []uint64{1}},
+		{Offset: 6, Opcode: wasm.OpcodeElse, Name: "else"},
+		{Offset: 7, Opcode: wasm.OpcodeI32Const, Name: "i32.const", Immediates: []uint64{api.EncodeI32(-1)}},
+		{Offset: 9, Opcode: wasm.OpcodeEnd, Name: "end"},
+		{Offset: 10, Opcode: wasm.OpcodeEnd, Name: "end"},
+	}, body)
+
+	_, err = compiled.FunctionBody(0)
+	require.EqualError(t, err, "function[0] is imported")
+}
+
 func Test_compiledModule_Close(t *testing.T) {
 	for _, ctx := range []context.Context{nil, testCtx} { // Ensure it doesn't crash on nil!
 		e := &mockEngine{name: "1", cachedModules: map[*wasm.Module]struct{}{}}
@@ -711,3 +1058,15 @@ func TestNewRuntimeConfig(t *testing.T) {
 		require.Equal(t, engineKindInterpreter, c.engineKind)
 	}
 }
+
+func TestCPUFeatures(t *testing.T) {
+	features := CPUFeatures()
+	if goruntime.GOARCH != "amd64" {
+		require.Nil(t, features)
+		return
+	}
+	// The compiler requires SSE4.1 on amd64.
+	if platform.CompilerSupported() {
+		require.Contains(t, strings.Join(features, ","), "sse4.1")
+	}
+}
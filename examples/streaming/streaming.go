@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	_ "embed"
+	"encoding/binary"
+	"fmt"
+	"log"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+)
+
+// streamingWasm was generated by the following:
+//
+//	cd testdata; wat2wasm --debug-names streaming.wat
+//
+//go:embed testdata/streaming.wasm
+var streamingWasm []byte
+
+const (
+	// recordCount is the number of records the guest produces.
+	recordCount = 10
+	// batchSize is the number of records the guest buffers before flushing.
+	batchSize = 3
+	// channelSize bounds how many records the host buffers before the guest
+	// is blocked.
+	channelSize = 4
+)
+
+// main shows how a guest can stream records to a host consumer, with
+// backpressure when the consumer falls behind.
+func main() {
+	// Choose the context to use for function calls.
+	ctx := context.Background()
+
+	// Create a new WebAssembly Runtime.
+	r := wazero.NewRuntime(ctx)
+	defer r.Close(ctx) // This closes everything this Runtime created.
+
+	// records is a bounded channel: once it holds channelSize records, the
+	// guest blocks inside "env.flush" until the consumer catches up.
+	records := make(chan uint32, channelSize)
+
+	// Instantiate a Go-defined module named "env" that exports a function
+	// which copies each record out of the guest's batch buffer.
+	_, err := r.NewHostModuleBuilder("env").
+		NewFunctionBuilder().
+		WithFunc(func(ctx context.Context, m api.Module, ptr, count uint32) {
+			buf, ok := m.Memory().Read(ptr, count*4)
+			if !ok {
+				log.Panicf("Memory.Read(%d, %d) out of range", ptr, count*4)
+			}
+			// The guest reuses its buffer after this returns, so records must
+			// be copied out before then. Sending blocks while the channel is
+			// full, which is what applies backpressure to the guest.
+			for i := uint32(0); i < count; i++ {
+				records <- binary.LittleEndian.Uint32(buf[i*4:])
+			}
+		}).
+		Export("flush").
+		Instantiate(ctx)
+	if err != nil {
+		log.Panicln(err)
+	}
+
+	// Instantiate a WebAssembly module that imports the "flush" function
+	// defined in "env".
+	mod, err := r.Instantiate(ctx, streamingWasm)
+	if err != nil {
+		log.Panicln(err)
+	}
+
+	// Run the guest in its own goroutine, so that it can block on the
+	// channel while this goroutine consumes records.
+	produced := make(chan error, 1)
+	go func() {
+		defer close(records)
+		_, err := mod.ExportedFunction("produce").Call(ctx, recordCount, batchSize)
+		produced <- err
+	}()
+
+	var sum uint32
+	for record := range records {
+		fmt.Printf("record=%d\n", record)
+		sum += record
+	}
+	if err = <-produced; err != nil {
+		log.Panicln(err)
+	}
+	fmt.Printf("sum=%d\n", sum)
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/testing/maintester"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+// Test_main ensures the following will work:
+//
+//	go run streaming.go
+func Test_main(t *testing.T) {
+	stdout, _ := maintester.TestMain(t, main, "streaming")
+	require.Equal(t, `record=0
+record=1
+record=2
+record=3
+record=4
+record=5
+record=6
+record=7
+record=8
+record=9
+sum=45
+`, stdout)
+}
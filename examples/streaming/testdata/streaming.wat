@@ -0,0 +1,30 @@
+(module $streaming
+  ;; flush is implemented by the host, which consumes `count` i32 records
+  ;; starting at `ptr`. It blocks while the host is behind.
+  (import "env" "flush" (func $flush (param $ptr i32) (param $count i32)))
+
+  (memory (export "memory") 1)
+
+  ;; produce streams the records 0 to n-1 to the host, in batches of `batch`.
+  ;; The batch buffer starts at offset zero in memory.
+  (func (export "produce") (param $n i32) (param $batch i32)
+    (local $i i32)
+    (local $pending i32)
+    (block $done
+      (loop $next
+        (br_if $done (i32.ge_u (local.get $i) (local.get $n)))
+        ;; Write the record into the batch buffer.
+        (i32.store (i32.shl (local.get $pending) (i32.const 2)) (local.get $i))
+        (local.set $pending (i32.add (local.get $pending) (i32.const 1)))
+        (local.set $i (i32.add (local.get $i) (i32.const 1)))
+        ;; Flush the batch to the host once it is full.
+        (if (i32.eq (local.get $pending) (local.get $batch))
+          (then
+            (call $flush (i32.const 0) (local.get $pending))
+            (local.set $pending (i32.const 0))))
+        (br $next)))
+    ;; Flush any remaining records.
+    (if (local.get $pending)
+      (then (call $flush (i32.const 0) (local.get $pending))))
+  )
+)
@@ -0,0 +1,81 @@
+package experimental
+
+import (
+	"context"
+
+	"github.com/tetratelabs/wazero/internal/compilation"
+)
+
+// CompilationMemoryObserver is notified of the peak scratch memory in bytes
+// used while compiling a module.
+//
+// Note: This is experimental progress towards bounding the resources used
+// by compilation, and likely to change.
+type CompilationMemoryObserver func(peakBytes uint64)
+
+// WithCompilationMemoryLimit registers into the given context.Context a
+// limit in bytes on the scratch memory used to compile a single function, and
+// an optional observer of the peak usage. A zero limitBytes only observes.
+//
+// When the limit is exceeded, wazero.Runtime CompileModule fails with an
+// error naming the function being compiled.
+//
+// # Notes
+//
+//   - This is only supported by the optimizing compiler (wazevo), and is
+//     ignored by other engines.
+//   - The scratch memory is the pooled intermediate representation, e.g. SSA
+//     instructions and register allocation state, not the resulting code.
+//   - The observer is not called when the compilation result was cached.
+func WithCompilationMemoryLimit(ctx context.Context, limitBytes uint64, observer CompilationMemoryObserver) context.Context {
+	return context.WithValue(ctx, compilation.MemoryConfigKey{},
+		&compilation.MemoryConfig{Limit: limitBytes, Observer: observer})
+}
+
+// Symbolizer is notified of the machine code of each function compiled, so
+// that external disassembly or annotation tools can be integrated.
+//
+// Note: This is experimental progress towards debugging the optimizing
+// compiler (wazevo), and likely to change.
+type Symbolizer interface {
+	// Symbolize is called once per function with its name, machine code, and
+	// the relocations within that code.
+	//
+	// The machine code is that of the function alone, before relocations are
+	// resolved: call instructions in it don't yet encode their targets. Use
+	// relocations to map them to the functions they call.
+	//
+	// Note: Neither code nor relocations may be modified.
+	Symbolize(funcName string, code []byte, relocations []Relocation)
+}
+
+// Relocation is a call site in the machine code passed to Symbolizer.
+type Relocation struct {
+	// Offset is the offset of the call instruction from the start of the
+	// function's machine code.
+	Offset uint64
+
+	// FunctionIndex is the index of the called function in the function index
+	// namespace, which includes imported functions.
+	FunctionIndex uint32
+
+	// FunctionName is the name of the called function, in the same format as
+	// the name passed to Symbolizer.Symbolize.
+	FunctionName string
+}
+
+// WithSymbolizer registers the given Symbolizer into the context.Context,
+// which is then notified of the machine code of each function compiled by
+// wazero.Runtime CompileModule.
+//
+// # Notes
+//
+//   - This is only supported by the optimizing compiler (wazevo), and is
+//     ignored by other engines.
+//   - The symbolizer is not called when the compilation result was cached.
+func WithSymbolizer(ctx context.Context, symbolizer Symbolizer) context.Context {
+	if symbolizer != nil {
+		return context.WithValue(ctx, compilation.SymbolizerKey{}, symbolizer)
+	}
+	return ctx
+}
@@ -0,0 +1,177 @@
+// Package debug steps through the calls of guest functions, pausing each one
+// at its entry until resumed, for debuggers with call granularity.
+//
+// For example, the below pauses at each function called by "run" and prints
+// it with its locals, while the module runs in another goroutine:
+//
+//	ctx, session := debug.NewSession(ctx)
+//	compiled, _ := r.CompileModule(ctx, wasm) // Compile with the session.
+//	mod, _ := r.InstantiateModule(ctx, compiled, wazero.NewModuleConfig())
+//	go func() {
+//		_, _ = mod.ExportedFunction("run").Call(ctx)
+//		session.Detach()
+//	}()
+//	for {
+//		stop, err := session.Next(ctx)
+//		if err != nil {
+//			break // Detached.
+//		}
+//		fmt.Println(stop.Definition.DebugName(), stop.Locals)
+//		stop.Resume()
+//	}
+package debug
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+// ErrDetached is returned by Session.Next once the session is detached.
+var ErrDetached = errors.New("debug session detached")
+
+// Session pauses the guest functions of the modules compiled with its context
+// at their entry, until the Stop returned by Next is resumed.
+//
+// A session is safe for concurrent use. Functions called concurrently pause
+// independently, each with their own Stop.
+type Session struct {
+	stops    chan *Stop
+	detached chan struct{}
+	detach   sync.Once
+}
+
+// NewSession returns a session, and a context derived from ctx to pass to
+// wazero.Runtime CompileModule, so that the functions of the module pause.
+//
+// # Notes
+//
+//   - Only functions defined by the compiled module pause. Host functions,
+//     including those imported by the module, don't.
+//   - This composes with a FunctionListenerFactory already in ctx, via
+//     experimental.MultiFunctionListenerFactory.
+//   - The compiled module calls the session's listener until closed, though it
+//     doesn't pause once the session is detached.
+func NewSession(ctx context.Context) (context.Context, *Session) {
+	s := &Session{stops: make(chan *Stop), detached: make(chan struct{})}
+	var factory experimental.FunctionListenerFactory = s
+	if existing, ok := ctx.Value(experimental.FunctionListenerFactoryKey{}).(experimental.FunctionListenerFactory); ok {
+		factory = experimental.MultiFunctionListenerFactory(existing, s)
+	}
+	return context.WithValue(ctx, experimental.FunctionListenerFactoryKey{}, factory), s
+}
+
+// Next waits for a function to pause at its entry, and returns where. The
+// function stays paused until Stop.Resume is called.
+//
+// This returns ErrDetached once Detach is called, or the error of ctx if it is
+// done first.
+func (s *Session) Next(ctx context.Context) (*Stop, error) {
+	select {
+	case stop := <-s.stops:
+		return stop, nil
+	case <-s.detached:
+		return nil, ErrDetached
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Detach resumes any paused function, and lets all functions run normally
+// afterwards. Calling Detach more than once has no effect.
+func (s *Session) Detach() {
+	s.detach.Do(func() { close(s.detached) })
+}
+
+// NewFunctionListener implements experimental.FunctionListenerFactory
+func (s *Session) NewFunctionListener(api.FunctionDefinition) experimental.FunctionListener {
+	return s
+}
+
+// Before implements experimental.FunctionListener Before by pausing until the
+// stop is resumed or the session is detached.
+func (s *Session) Before(ctx context.Context, mod api.Module, def api.FunctionDefinition, params []uint64, si experimental.StackIterator) {
+	select {
+	case <-s.detached:
+		return
+	default:
+	}
+
+	stop := &Stop{
+		Module:     mod,
+		Definition: def,
+		Locals:     append([]uint64(nil), params...),
+		LocalTypes: append([]api.ValueType(nil), def.ParamTypes()...),
+		resumed:    make(chan struct{}),
+	}
+	if m, ok := mod.(*wasm.ModuleInstance); ok && def.Index() >= m.Source.ImportFunctionCount {
+		// Declared locals are zero at the entry of the function.
+		localTypes := m.Source.CodeSection[def.Index()-m.Source.ImportFunctionCount].LocalTypes
+		stop.Locals = append(stop.Locals, make([]uint64, len(localTypes))...)
+		stop.LocalTypes = append(stop.LocalTypes, localTypes...)
+	}
+	for si.Next() {
+		fn := si.Function()
+		stop.Stack = append(stop.Stack, Frame{
+			Definition:   fn.Definition(),
+			SourceOffset: fn.SourceOffsetForPC(si.ProgramCounter()),
+		})
+	}
+
+	select {
+	case s.stops <- stop:
+	case <-s.detached:
+		return
+	}
+	select {
+	case <-stop.resumed:
+	case <-s.detached:
+	}
+}
+
+// After implements experimental.FunctionListener After
+func (s *Session) After(context.Context, api.Module, api.FunctionDefinition, []uint64) {}
+
+// Abort implements experimental.FunctionListener Abort
+func (s *Session) Abort(context.Context, api.Module, api.FunctionDefinition, error) {}
+
+// Stop is a function paused at its entry.
+//
+// Note: The fields are valid until Resume is called, after which the function
+// may change the memory and globals of Module.
+type Stop struct {
+	// Module is the module defining the function, to inspect its memory and
+	// globals. It is meant for inspection, not modification.
+	Module api.Module
+	// Definition describes the paused function.
+	Definition api.FunctionDefinition
+	// Locals are the values of the locals of the function, starting with its
+	// parameters, encoded as documented on api.ValueType.
+	Locals []uint64
+	// LocalTypes are the types of Locals.
+	LocalTypes []api.ValueType
+	// Stack is the call stack, starting with the paused function.
+	Stack []Frame
+
+	resumed chan struct{}
+	resume  sync.Once
+}
+
+// Resume lets the function run until the next function called pauses.
+// Calling Resume more than once has no effect.
+func (s *Stop) Resume() {
+	s.resume.Do(func() { close(s.resumed) })
+}
+
+// Frame is a function on the call stack of a Stop.
+type Frame struct {
+	// Definition describes the function.
+	Definition api.FunctionDefinition
+	// SourceOffset is the offset in the code section of the call the function
+	// is executing, or zero if unknown.
+	SourceOffset uint64
+}
@@ -0,0 +1,126 @@
+package debug_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental/debug"
+	"github.com/tetratelabs/wazero/internal/testing/binaryencoding"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasm/text"
+)
+
+// testCtx is an arbitrary, non-default context. Non-nil also prevents linter errors.
+var testCtx = context.WithValue(context.Background(), struct{}{}, "arbitrary")
+
+// guestWasm exports "run", which stores its parameter in memory and calls "add" twice, which calls the host function
+// "env.print".
+func guestWasm(t *testing.T) []byte {
+	m, err := text.DecodeModule([]byte(`(module
+  (import "env" "print" (func $print (param i32)))
+  (memory (export "memory") 1)
+  (func $add (param i32 i32) (result i32) (local i64)
+    local.get 0
+    call $print
+    local.get 0
+    local.get 1
+    i32.add)
+  (func (export "run") (param i32) (result i32)
+    i32.const 0
+    local.get 0
+    i32.store
+    local.get 0
+    i32.const 1
+    call $add
+    i32.const 2
+    call $add)
+)`))
+	require.NoError(t, err)
+	return binaryencoding.EncodeModule(m)
+}
+
+func TestSession(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		config wazero.RuntimeConfig
+	}{
+		{name: "interpreter", config: wazero.NewRuntimeConfigInterpreter()},
+		{name: "compiler", config: wazero.NewRuntimeConfig()},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r := wazero.NewRuntimeWithConfig(testCtx, tc.config)
+			defer r.Close(testCtx)
+
+			var printed []uint32
+			_, err := r.NewHostModuleBuilder("env").NewFunctionBuilder().
+				WithFunc(func(v uint32) { printed = append(printed, v) }).Export("print").
+				Instantiate(testCtx)
+			require.NoError(t, err)
+
+			ctx, session := debug.NewSession(testCtx)
+			compiled, err := r.CompileModule(ctx, guestWasm(t))
+			require.NoError(t, err)
+			mod, err := r.InstantiateModule(testCtx, compiled, wazero.NewModuleConfig())
+			require.NoError(t, err)
+
+			run := func() <-chan []uint64 {
+				done := make(chan []uint64, 1)
+				go func() {
+					results, _ := mod.ExportedFunction("run").Call(testCtx, 5)
+					done <- results // nil on error
+				}()
+				return done
+			}
+			done := run()
+
+			stop, err := session.Next(testCtx)
+			require.NoError(t, err)
+			require.Equal(t, "run", stop.Definition.ExportNames()[0])
+			require.Equal(t, []uint64{5}, stop.Locals)
+			require.Equal(t, []api.ValueType{api.ValueTypeI32}, stop.LocalTypes)
+			require.Equal(t, 1, len(stop.Stack))
+			v, ok := stop.Module.Memory().ReadUint32Le(0)
+			require.True(t, ok)
+			require.Equal(t, uint32(0), v) // "run" hasn't stored yet.
+			stop.Resume()
+
+			stop, err = session.Next(testCtx)
+			require.NoError(t, err)
+			require.Equal(t, uint32(1), stop.Definition.Index())
+			require.Equal(t, []uint64{5, 1, 0}, stop.Locals)
+			require.Equal(t, []api.ValueType{api.ValueTypeI32, api.ValueTypeI32, api.ValueTypeI64}, stop.LocalTypes)
+			require.Equal(t, 2, len(stop.Stack))
+			require.Equal(t, uint32(1), stop.Stack[0].Definition.Index())
+			require.Equal(t, uint32(2), stop.Stack[1].Definition.Index())
+			v, ok = stop.Module.Memory().ReadUint32Le(0)
+			require.True(t, ok)
+			require.Equal(t, uint32(5), v)
+			require.Nil(t, printed) // Host functions don't pause, but "add" hasn't called "print" yet.
+			stop.Resume()
+			stop.Resume() // No effect.
+
+			stop, err = session.Next(testCtx)
+			require.NoError(t, err)
+			require.Equal(t, []uint64{6, 2, 0}, stop.Locals)
+			require.Equal(t, []uint32{5}, printed)
+
+			// Detaching resumes the paused function, and the module runs normally afterwards.
+			session.Detach()
+			require.Equal(t, []uint64{8}, <-done)
+			require.Equal(t, []uint64{8}, <-run())
+			_, err = session.Next(testCtx)
+			require.Equal(t, debug.ErrDetached, err)
+			session.Detach() // No effect.
+		})
+	}
+}
+
+func TestSession_Next(t *testing.T) {
+	_, session := debug.NewSession(testCtx)
+	ctx, cancel := context.WithCancel(testCtx)
+	cancel()
+	_, err := session.Next(ctx)
+	require.Equal(t, context.Canceled, err)
+}
@@ -0,0 +1,64 @@
+// Package dispatch exports many host functions which share one Go function,
+// for host APIs too large to register each function individually.
+//
+// For example, the below exports "open", "read" and "close" from the module
+// "env", each calling handle with the ID of the function's name:
+//
+//	handle := func(ctx context.Context, mod api.Module, id uint32, stack []uint64) {
+//		switch id {
+//		case openID:
+//			// ...
+//		}
+//	}
+//	builder := r.NewHostModuleBuilder("env")
+//	dispatch.Export(builder, handle, []api.ValueType{api.ValueTypeI32}, []api.ValueType{api.ValueTypeI32},
+//		map[string]uint32{"open": openID, "read": readID, "close": closeID})
+//	_, err := builder.Instantiate(ctx)
+package dispatch
+
+import (
+	"context"
+	"sort"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+)
+
+// Func handles a call to any function exported by Export, where id is the
+// value of the function's name in the map given to Export.
+//
+// The stack is the same as documented on api.GoModuleFunction.
+type Func func(ctx context.Context, mod api.Module, id uint32, stack []uint64)
+
+// Export exports from the builder a function named by each key of nameToID,
+// which has the given signature and calls fn with the value of the key.
+// Call this once per signature when functions have different ones.
+//
+// # Notes
+//
+//   - The functions are exported in the order of their names, so that the
+//     resulting module doesn't depend on the iteration order of nameToID.
+//   - A name already exported by the builder is replaced, as with
+//     wazero.HostFunctionBuilder Export.
+func Export(
+	builder wazero.HostModuleBuilder,
+	fn Func,
+	params, results []api.ValueType,
+	nameToID map[string]uint32,
+) wazero.HostModuleBuilder {
+	names := make([]string, 0, len(nameToID))
+	for name := range nameToID {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		id := nameToID[name]
+		builder.NewFunctionBuilder().
+			WithGoModuleFunction(api.GoModuleFunc(func(ctx context.Context, mod api.Module, stack []uint64) {
+				fn(ctx, mod, id, stack)
+			}), params, results).
+			Export(name)
+	}
+	return builder
+}
@@ -0,0 +1,143 @@
+package dispatch_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental/dispatch"
+	"github.com/tetratelabs/wazero/internal/leb128"
+	"github.com/tetratelabs/wazero/internal/testing/binaryencoding"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+// testCtx is an arbitrary, non-default context. Non-nil also prevents linter errors.
+var testCtx = context.WithValue(context.Background(), struct{}{}, "arbitrary")
+
+const (
+	moduleName = "env"
+	i32        = wasm.ValueTypeI32
+	// funcCount is the number of host functions, large enough that a scan per
+	// function would be noticeable when instantiating.
+	funcCount = 1000
+)
+
+var i32s = []api.ValueType{i32}
+
+// nameToID maps "f<n>" to 1000+n.
+func nameToID(n int) map[string]uint32 {
+	ret := make(map[string]uint32, n)
+	for i := 0; i < n; i++ {
+		ret[fmt.Sprintf("f%d", i)] = uint32(1000 + i)
+	}
+	return ret
+}
+
+// guestWasm returns a guest which imports "f<n>" from the host module for
+// each name, and exports a function of the same name which calls it.
+func guestWasm(n int) []byte {
+	m := &wasm.Module{
+		TypeSection:     []wasm.FunctionType{{Params: []wasm.ValueType{i32}, Results: []wasm.ValueType{i32}}},
+		ImportSection:   make([]wasm.Import, n),
+		FunctionSection: make([]wasm.Index, n),
+		CodeSection:     make([]wasm.Code, n),
+		ExportSection:   make([]wasm.Export, n),
+	}
+	for i := 0; i < n; i++ {
+		name := fmt.Sprintf("f%d", i)
+		m.ImportSection[i] = wasm.Import{Module: moduleName, Name: name, Type: wasm.ExternTypeFunc, DescFunc: 0}
+		body := []byte{wasm.OpcodeLocalGet, 0, wasm.OpcodeCall}
+		body = append(body, leb128.EncodeUint32(uint32(i))...)
+		m.CodeSection[i] = wasm.Code{Body: append(body, wasm.OpcodeEnd)}
+		m.ExportSection[i] = wasm.Export{Name: name, Type: wasm.ExternTypeFunc, Index: wasm.Index(n + i)}
+	}
+	return binaryencoding.EncodeModule(m)
+}
+
+// addID returns the parameter plus the id of the called function.
+func addID(_ context.Context, _ api.Module, id uint32, stack []uint64) {
+	stack[0] = uint64(uint32(stack[0]) + id)
+}
+
+func TestExport(t *testing.T) {
+	r := wazero.NewRuntime(testCtx)
+	defer r.Close(testCtx)
+
+	builder := r.NewHostModuleBuilder(moduleName)
+	dispatch.Export(builder, addID, i32s, i32s, nameToID(funcCount))
+	host, err := builder.Compile(testCtx)
+	require.NoError(t, err)
+
+	// The functions are exported in the order of their names.
+	defs := host.ExportedFunctions()
+	require.Equal(t, funcCount, len(defs))
+	require.Equal(t, uint32(0), defs["f0"].Index())
+	require.Equal(t, uint32(1), defs["f1"].Index())
+	require.Equal(t, uint32(2), defs["f10"].Index())
+	require.Equal(t, []string{"f10"}, defs["f10"].ExportNames())
+
+	_, err = r.InstantiateModule(testCtx, host, wazero.NewModuleConfig())
+	require.NoError(t, err)
+	guest, err := r.Instantiate(testCtx, guestWasm(funcCount))
+	require.NoError(t, err)
+
+	// Each import calls the dispatcher with the id of its name.
+	for name, id := range nameToID(funcCount) {
+		res, err := guest.ExportedFunction(name).Call(testCtx, 1)
+		require.NoError(t, err)
+		require.Equal(t, uint64(id+1), res[0], name)
+	}
+}
+
+func TestExport_signatures(t *testing.T) {
+	r := wazero.NewRuntime(testCtx)
+	defer r.Close(testCtx)
+
+	builder := r.NewHostModuleBuilder(moduleName)
+	dispatch.Export(builder, addID, i32s, i32s, map[string]uint32{"f0": 1})
+	dispatch.Export(builder, func(_ context.Context, _ api.Module, id uint32, stack []uint64) {
+		stack[0] = uint64(id)
+	}, i32s, i32s, map[string]uint32{"f1": 2})
+	_, err := builder.Instantiate(testCtx)
+	require.NoError(t, err)
+	guest, err := r.Instantiate(testCtx, guestWasm(2))
+	require.NoError(t, err)
+
+	res, err := guest.ExportedFunction("f0").Call(testCtx, 2)
+	require.NoError(t, err)
+	require.Equal(t, uint64(3), res[0])
+
+	res, err = guest.ExportedFunction("f1").Call(testCtx, 2)
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), res[0])
+}
+
+// BenchmarkInstantiate measures the cost of compiling a host module with many
+// functions and resolving the imports of a guest which uses all of them.
+func BenchmarkInstantiate(b *testing.B) {
+	r := wazero.NewRuntime(testCtx)
+	defer r.Close(testCtx)
+
+	guest, err := r.CompileModule(testCtx, guestWasm(funcCount))
+	require.NoError(b, err)
+	names := nameToID(funcCount)
+
+	b.ResetTimer()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		builder := r.NewHostModuleBuilder(moduleName)
+		host, err := dispatch.Export(builder, addID, i32s, i32s, names).Instantiate(testCtx)
+		if err != nil {
+			b.Fatal(err)
+		}
+		mod, err := r.InstantiateModule(testCtx, guest, wazero.NewModuleConfig().WithName(""))
+		if err != nil {
+			b.Fatal(err)
+		}
+		_ = mod.Close(testCtx)
+		_ = host.Close(testCtx)
+	}
+}
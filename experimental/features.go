@@ -0,0 +1,188 @@
+package experimental
+
+import "github.com/tetratelabs/wazero/api"
+
+// CoreFeaturesTailCall enables the "return_call" and "return_call_indirect"
+// instructions of the tail-call proposal. The callee replaces the frame of the
+// caller, so that deep, mutually recursive tail calls run in constant stack
+// space.
+//
+// This is enabled with wazero.RuntimeConfig WithCoreFeatures, for example:
+//
+//	cfg := wazero.NewRuntimeConfig().
+//		WithCoreFeatures(api.CoreFeaturesV2 | experimental.CoreFeaturesTailCall)
+//
+// # Notes
+//
+//   - The interpreter and the optimizing compiler reuse the frame of the
+//     caller. The default compiler implements these as a call followed by a
+//     return, so the stack grows with each call, as it would without this
+//     feature.
+//   - The optimizing compiler falls back to a call followed by a return when
+//     the callee takes a different size of parameters on the stack than the
+//     caller, or when a function listener is configured.
+//
+// See https://github.com/WebAssembly/tail-call/blob/main/proposals/tail-call/Overview.md
+const CoreFeaturesTailCall = api.CoreFeatureSIMD << 1
+
+// CoreFeaturesMultiMemory enables the multi-memory proposal, which allows a
+// module to define more than one memory and adds a memory index immediate to
+// the load, store, memory.size, memory.grow, memory.copy, memory.fill and
+// memory.init instructions.
+//
+// This is enabled with wazero.RuntimeConfig WithCoreFeatures, for example:
+//
+//	cfg := wazero.NewRuntimeConfig().
+//		WithCoreFeatures(api.CoreFeaturesV2 | experimental.CoreFeaturesMultiMemory)
+//
+// # Notes
+//
+//   - Only the interpreter supports more than one memory. With the compiler,
+//     modules defining more than one memory are interpreted, like those
+//     selected by wazero.RuntimeConfig WithFallbackInterpreter, so they run
+//     much slower and compiled modules can't import from them. Modules with
+//     a single memory are compiled as usual.
+//   - At most one memory can be imported, and it is the memory at index zero.
+//
+// See https://github.com/WebAssembly/multi-memory/blob/main/proposals/multi-memory/Overview.md
+const CoreFeaturesMultiMemory = api.CoreFeatureSIMD << 2
+
+// CoreFeaturesRelaxedSIMD enables the relaxed-SIMD proposal, which adds vector
+// instructions whose results may differ between hosts, in exchange for mapping
+// to faster native instructions.
+//
+// This is enabled with wazero.RuntimeConfig WithCoreFeatures, for example:
+//
+//	cfg := wazero.NewRuntimeConfig().
+//		WithCoreFeatures(api.CoreFeaturesV2 | experimental.CoreFeaturesRelaxedSIMD)
+//
+// # Notes
+//
+// wazero picks the same results on every host and in every engine, so a module
+// computes the same values wherever it runs, and none of these instructions
+// trap:
+//
+//   - i8x16.relaxed_swizzle is i8x16.swizzle, so out of range indices select 0.
+//   - i32x4.relaxed_trunc_* are i32x4.trunc_sat_*, so NaN is 0 and out of
+//     range values saturate.
+//   - *.relaxed_madd is a multiply then an add, rounding twice as if not fused,
+//     and *.relaxed_nmadd negates the product before the add.
+//   - *.relaxed_laneselect is v128.bitselect, regardless of the lane shape.
+//   - *.relaxed_min and *.relaxed_max are *.min and *.max, so NaN propagates
+//     and -0 is less than 0.
+//   - i16x8.relaxed_q15mulr_s is i16x8.q15mulr_sat_s.
+//   - i16x8.relaxed_dot_i8x16_i7x16_s treats both operands as signed, and
+//     saturates the sum of each pair of products to 16 bits.
+//     i32x4.relaxed_dot_i8x16_i7x16_add_s adds pairs of those sums then the
+//     accumulator, wrapping on overflow.
+//
+// See https://github.com/WebAssembly/relaxed-simd/blob/main/proposals/relaxed-simd/Overview.md
+const CoreFeaturesRelaxedSIMD = api.CoreFeatureSIMD << 3
+
+// CoreFeaturesThreads enables the atomic instructions of the threads proposal,
+// and memories declared as shared, so that a module compiled for threads can
+// run on a single thread.
+//
+// This is enabled with wazero.RuntimeConfig WithCoreFeatures, for example:
+//
+//	cfg := wazero.NewRuntimeConfig().
+//		WithCoreFeatures(api.CoreFeaturesV2 | experimental.CoreFeaturesThreads)
+//
+// # Notes
+//
+//   - wazero doesn't yet run functions concurrently on the same memory, so the
+//     atomic instructions behave as their non-atomic equivalents, except that
+//     they trap when the effective address isn't a multiple of the size of the
+//     access.
+//   - memory.atomic.notify returns zero, as there can't be any waiters.
+//   - memory.atomic.wait32 and memory.atomic.wait64 return immediately: one
+//     when the loaded value differs from the expected one, otherwise two
+//     (timed out), as no other thread could notify them.
+//   - A shared memory must declare a max, and is allocated with the capacity
+//     of it, so that growing it never moves the memory. It can only be
+//     imported as a shared memory.
+//   - As the whole max is allocated on instantiation, it counts toward the Go
+//     heap, including GOMEMLIMIT, even if the guest never grows the memory.
+//     For example, a module declaring (memory 1 65536 shared) allocates
+//     4 GiB per instance, and fails to instantiate on a 32-bit host. Lower
+//     the max with wazero.RuntimeConfig WithMemoryLimitPages to bound this,
+//     especially when running untrusted modules.
+//
+// See https://github.com/WebAssembly/threads/blob/main/proposals/threads/Overview.md
+const CoreFeaturesThreads = api.CoreFeatureSIMD << 4
+
+// CoreFeaturesMemory64 enables the memory64 proposal, which allows memories
+// indexed with i64 addresses, declared with 64-bit limits.
+//
+// This is enabled with wazero.RuntimeConfig WithCoreFeatures, for example:
+//
+//	cfg := wazero.NewRuntimeConfig().
+//		WithCoreFeatures(api.CoreFeaturesV2 | experimental.CoreFeaturesMemory64)
+//
+// # Notes
+//
+//   - The size of a 64-bit memory is still bounded by the memory limit of the
+//     runtime (wazero.RuntimeConfig WithMemoryLimitPages), which is at most
+//     4GiB. A larger max is accepted, but memory.grow returns -1 instead of
+//     growing beyond the limit.
+//   - Accessing an address beyond the memory traps as usual, including
+//     addresses which don't fit in 32 bits.
+//
+// See https://github.com/WebAssembly/memory64/blob/main/proposals/memory64/Overview.md
+const CoreFeaturesMemory64 = api.CoreFeatureSIMD << 5
+
+// CoreFeaturesExtendedConst enables the extended-const proposal, which allows
+// the i32.add, i32.sub, i32.mul, i64.add, i64.sub and i64.mul instructions in
+// constant expressions, such as global initializers and data segment offsets.
+//
+// This is enabled with wazero.RuntimeConfig WithCoreFeatures, for example:
+//
+//	cfg := wazero.NewRuntimeConfig().
+//		WithCoreFeatures(api.CoreFeaturesV2 | experimental.CoreFeaturesExtendedConst)
+//
+// # Notes
+//
+//   - A global initializer can also use global.get on an immutable global
+//     defined before it, in addition to imported globals. Globals are
+//     initialized in order, so referencing the global itself or a later one
+//     is a validation error.
+//   - Expressions which don't use global.get are folded into a constant when
+//     the module is decoded. The others are evaluated once, when the module
+//     is instantiated.
+//   - Element segment offsets must still be a single i32.const or global.get
+//     instruction, after folding.
+//
+// See https://github.com/WebAssembly/extended-const/blob/main/proposals/extended-const/Overview.md
+const CoreFeaturesExtendedConst = api.CoreFeatureSIMD << 6
+
+// CoreFeaturesExceptionHandling enables decoding and validating the tag
+// section and the try, catch, catch_all, throw, rethrow and delegate
+// instructions of the legacy exception-handling proposal, as emitted by
+// toolchains for C++ exceptions.
+//
+// This is enabled with wazero.RuntimeConfig WithCoreFeatures, for example:
+//
+//	cfg := wazero.NewRuntimeConfig().
+//		WithCoreFeatures(api.CoreFeaturesV2 | experimental.CoreFeaturesExceptionHandling)
+//
+// # Notes
+//
+//   - Only the optimizing compiler, which is still experimental, runs these.
+//     The interpreter and the default compiler fail CompileModule with an
+//     error saying exception handling is not supported, rather than a
+//     decoding error. A module which doesn't use tags or these instructions
+//     compiles as usual.
+//   - A tag may carry at most 8 values, counting v128 as two. CompileModule
+//     fails on a module defining or importing a tag with more.
+//   - An exception which isn't caught by the guest fails the api.Function
+//     call with an "uncaught exception" error. Exceptions don't cross host
+//     functions: one thrown by a guest function called from a host function
+//     fails that call, the same as if it were called from Go.
+//   - A module which uses exception handling can import functions from one
+//     which doesn't, but not the other way around. Funcref tables and
+//     globals can't be shared between such modules at all.
+//   - wazero.ValidateModule accepts a valid module using them, so tools
+//     can check such a module without compiling it.
+//
+// See https://github.com/WebAssembly/exception-handling/blob/main/proposals/exception-handling/legacy/Exceptions.md
+const CoreFeaturesExceptionHandling = api.CoreFeatureSIMD << 7
@@ -0,0 +1,135 @@
+// Package kvstore contains a host module which gives guests access to a
+// key-value store supplied by the embedder.
+//
+// # Functions
+//
+// The module named "kvstore" exports the below functions, where keys and
+// values are byte ranges in the calling module's memory.
+//
+//   - "get" - (param $key i32 $key_len i32) (result $value i32 $value_len i32)
+//     copies the value of the key into memory allocated by the guest's
+//     AllocatorName export, and returns its range. When the key is absent,
+//     $value_len is -1 and $value is zero. Nothing is allocated for an empty
+//     value.
+//   - "set" - (param $key i32 $key_len i32 $value i32 $value_len i32) copies
+//     the value into the store under the key, replacing any existing value.
+//
+// An out-of-range key or value traps, as would a guest memory access.
+//
+// Note: "get" returns multiple values, so wazero.RuntimeConfig must enable
+// api.CoreFeatureMultiValue, as it does by default.
+package kvstore
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/internal/wasmruntime"
+)
+
+const (
+	// ModuleName is the module name guests import the functions from.
+	ModuleName = "kvstore"
+
+	// AllocatorName is the function the guest exports to allocate memory for
+	// values returned by "get". Its signature is
+	// (param $size i32) (result $offset i32).
+	AllocatorName = "malloc"
+
+	i32 = api.ValueTypeI32
+)
+
+// MustInstantiate calls Instantiate or panics on error.
+func MustInstantiate(ctx context.Context, r wazero.Runtime, store map[string][]byte) {
+	if _, err := Instantiate(ctx, r, store); err != nil {
+		panic(err)
+	}
+}
+
+// Instantiate instantiates the "kvstore" module into the runtime, backed by
+// the given store.
+//
+// # Notes
+//
+//   - Failure cases are documented on wazero.Runtime InstantiateModule.
+//   - Closing the wazero.Runtime has the same effect as closing the result.
+//   - Guests may call the functions concurrently, so the embedder must not
+//     access store while any module importing "kvstore" is in use.
+func Instantiate(ctx context.Context, r wazero.Runtime, store map[string][]byte) (api.Closer, error) {
+	s := &kvstore{store: store}
+	return r.NewHostModuleBuilder(ModuleName).
+		NewFunctionBuilder().
+		WithGoModuleFunction(api.GoModuleFunc(s.get), []api.ValueType{i32, i32}, []api.ValueType{i32, i32}).
+		WithParameterNames("key", "key_len").
+		WithResultNames("value", "value_len").
+		Export("get").
+		NewFunctionBuilder().
+		WithGoModuleFunction(api.GoModuleFunc(s.set), []api.ValueType{i32, i32, i32, i32}, nil).
+		WithParameterNames("key", "key_len", "value", "value_len").
+		Export("set").
+		Instantiate(ctx)
+}
+
+type kvstore struct {
+	mux   sync.Mutex
+	store map[string][]byte
+}
+
+// get implements the "get" function documented on the package.
+func (s *kvstore) get(ctx context.Context, mod api.Module, stack []uint64) {
+	key := mustRead(mod.Memory(), stack[0], stack[1])
+
+	s.mux.Lock()
+	value, ok := s.store[string(key)]
+	s.mux.Unlock()
+
+	switch {
+	case !ok:
+		stack[0], stack[1] = 0, api.EncodeI32(-1)
+		return
+	case len(value) == 0:
+		stack[0], stack[1] = 0, 0
+		return
+	}
+
+	malloc := mod.ExportedFunction(AllocatorName)
+	if malloc == nil {
+		panic(fmt.Errorf("%s.get requires the guest to export %q", ModuleName, AllocatorName))
+	}
+	results, err := malloc.Call(ctx, uint64(len(value)))
+	if err != nil {
+		panic(err)
+	}
+	offset := uint32(results[0])
+	if !mod.Memory().Write(offset, value) {
+		panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
+	}
+	stack[0], stack[1] = uint64(offset), uint64(len(value))
+}
+
+// set implements the "set" function documented on the package.
+func (s *kvstore) set(_ context.Context, mod api.Module, stack []uint64) {
+	mem := mod.Memory()
+	key := mustRead(mem, stack[0], stack[1])
+	// Copy the value, as the guest memory can change after this returns.
+	value := append([]byte{}, mustRead(mem, stack[2], stack[3])...)
+
+	s.mux.Lock()
+	s.store[string(key)] = value
+	s.mux.Unlock()
+}
+
+// mustRead returns a view of the memory range or traps if it is out of range.
+func mustRead(mem api.Memory, offset, byteCount uint64) []byte {
+	if mem == nil {
+		panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
+	}
+	buf, ok := mem.Read(uint32(offset), uint32(byteCount))
+	if !ok {
+		panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
+	}
+	return buf
+}
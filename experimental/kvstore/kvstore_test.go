@@ -0,0 +1,157 @@
+package kvstore_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental/kvstore"
+	"github.com/tetratelabs/wazero/internal/testing/binaryencoding"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasm"
+	"github.com/tetratelabs/wazero/internal/wasmruntime"
+)
+
+// testCtx is an arbitrary, non-default context. Non-nil also prevents linter errors.
+var testCtx = context.WithValue(context.Background(), struct{}{}, "arbitrary")
+
+const i32 = wasm.ValueTypeI32
+
+// guestWasm returns a guest which re-exports "get" and "set" from the
+// "kvstore" module, and optionally a bump allocator as "malloc".
+func guestWasm(withMalloc bool) []byte {
+	getType := wasm.FunctionType{Params: []wasm.ValueType{i32, i32}, Results: []wasm.ValueType{i32, i32}}
+	setType := wasm.FunctionType{Params: []wasm.ValueType{i32, i32, i32, i32}}
+	m := &wasm.Module{
+		TypeSection: []wasm.FunctionType{getType, setType, {Params: []wasm.ValueType{i32}, Results: []wasm.ValueType{i32}}},
+		ImportSection: []wasm.Import{
+			{Module: kvstore.ModuleName, Name: "get", Type: wasm.ExternTypeFunc, DescFunc: 0},
+			{Module: kvstore.ModuleName, Name: "set", Type: wasm.ExternTypeFunc, DescFunc: 1},
+		},
+		FunctionSection: []wasm.Index{0, 1},
+		CodeSection: []wasm.Code{
+			{Body: []byte{wasm.OpcodeLocalGet, 0, wasm.OpcodeLocalGet, 1, wasm.OpcodeCall, 0, wasm.OpcodeEnd}},
+			{Body: []byte{
+				wasm.OpcodeLocalGet, 0, wasm.OpcodeLocalGet, 1, wasm.OpcodeLocalGet, 2, wasm.OpcodeLocalGet, 3,
+				wasm.OpcodeCall, 1, wasm.OpcodeEnd,
+			}},
+		},
+		MemorySection: &wasm.Memory{Min: 1, Cap: 1, Max: 1},
+		ExportSection: []wasm.Export{
+			{Name: "memory", Type: wasm.ExternTypeMemory, Index: 0},
+			{Name: "get", Type: wasm.ExternTypeFunc, Index: 2},
+			{Name: "set", Type: wasm.ExternTypeFunc, Index: 3},
+		},
+	}
+	if withMalloc {
+		// The heap starts at offset 1024 and grows by the requested size.
+		m.GlobalSection = []wasm.Global{{
+			Type: wasm.GlobalType{ValType: i32, Mutable: true},
+			Init: wasm.ConstantExpression{Opcode: wasm.OpcodeI32Const, Data: []byte{0x80, 0x08}},
+		}}
+		m.FunctionSection = append(m.FunctionSection, 2)
+		m.CodeSection = append(m.CodeSection, wasm.Code{Body: []byte{
+			wasm.OpcodeGlobalGet, 0,
+			wasm.OpcodeGlobalGet, 0, wasm.OpcodeLocalGet, 0, wasm.OpcodeI32Add, wasm.OpcodeGlobalSet, 0,
+			wasm.OpcodeEnd,
+		}})
+		m.ExportSection = append(m.ExportSection, wasm.Export{Name: kvstore.AllocatorName, Type: wasm.ExternTypeFunc, Index: 4})
+	}
+	return binaryencoding.EncodeModule(m)
+}
+
+func TestInstantiate(t *testing.T) {
+	r := wazero.NewRuntime(testCtx)
+	defer r.Close(testCtx)
+
+	store := map[string][]byte{"seeded": []byte("hi"), "empty": {}}
+	kvstore.MustInstantiate(testCtx, r, store)
+
+	mod, err := r.Instantiate(testCtx, guestWasm(true))
+	require.NoError(t, err)
+	mem := mod.Memory()
+
+	// put writes the key and value into guest memory, then calls "set".
+	put := func(key, value string) {
+		require.True(t, mem.WriteString(0, key))
+		require.True(t, mem.WriteString(512, value))
+		_, err := mod.ExportedFunction("set").Call(testCtx, 0, uint64(len(key)), 512, uint64(len(value)))
+		require.NoError(t, err)
+	}
+	// get writes the key into guest memory, then calls "get".
+	get := func(key string) (value string, ok bool) {
+		require.True(t, mem.WriteString(0, key))
+		res, err := mod.ExportedFunction("get").Call(testCtx, 0, uint64(len(key)))
+		require.NoError(t, err)
+		if int32(res[1]) == -1 {
+			require.Zero(t, res[0])
+			return "", false
+		}
+		buf, ok := mem.Read(uint32(res[0]), uint32(res[1]))
+		require.True(t, ok)
+		return string(buf), true
+	}
+
+	t.Run("set then get", func(t *testing.T) {
+		put("key", "value")
+		require.Equal(t, []byte("value"), store["key"])
+
+		// The store isn't affected by later writes to the guest memory.
+		require.True(t, mem.WriteString(512, "VALUE"))
+		require.Equal(t, []byte("value"), store["key"])
+
+		value, ok := get("key")
+		require.True(t, ok)
+		require.Equal(t, "value", value)
+
+		put("key", "replaced")
+		value, ok = get("key")
+		require.True(t, ok)
+		require.Equal(t, "replaced", value)
+	})
+
+	t.Run("get seeded by the embedder", func(t *testing.T) {
+		value, ok := get("seeded")
+		require.True(t, ok)
+		require.Equal(t, "hi", value)
+	})
+
+	t.Run("get empty", func(t *testing.T) {
+		res, err := mod.ExportedFunction("get").Call(testCtx, 0, 0)
+		require.NoError(t, err)
+		require.Equal(t, []uint64{0, api.EncodeI32(-1)}, res) // the empty key is absent
+
+		value, ok := get("empty")
+		require.True(t, ok)
+		require.Equal(t, "", value)
+	})
+
+	t.Run("get absent", func(t *testing.T) {
+		_, ok := get("absent")
+		require.False(t, ok)
+	})
+
+	t.Run("out of range", func(t *testing.T) {
+		_, err := mod.ExportedFunction("set").Call(testCtx, 0, 3, 65535, 2)
+		require.ErrorIs(t, err, wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
+
+		_, err = mod.ExportedFunction("get").Call(testCtx, 65536, 1)
+		require.ErrorIs(t, err, wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
+	})
+}
+
+func TestInstantiate_noAllocator(t *testing.T) {
+	r := wazero.NewRuntime(testCtx)
+	defer r.Close(testCtx)
+
+	kvstore.MustInstantiate(testCtx, r, map[string][]byte{"k": []byte("v")})
+
+	mod, err := r.Instantiate(testCtx, guestWasm(false))
+	require.NoError(t, err)
+	require.True(t, mod.Memory().WriteString(0, "k"))
+
+	_, err = mod.ExportedFunction("get").Call(testCtx, 0, 1)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `kvstore.get requires the guest to export "malloc"`)
+}
@@ -2,6 +2,7 @@ package sock
 
 import (
 	"context"
+	"net"
 
 	"github.com/tetratelabs/wazero/internal/sock"
 )
@@ -14,6 +15,14 @@ import (
 type Config interface {
 	// WithTCPListener configures the host to set up the given host:port listener.
 	WithTCPListener(host string, port int) Config
+
+	// WithListener pre-opens the given listener, e.g. one on a port chosen by
+	// the host. Listeners added with this are numerically after those added
+	// with WithTCPListener.
+	//
+	// Note: Closing the module may close the listener, so it shouldn't be
+	// shared between module instances.
+	WithListener(ln *net.TCPListener) Config
 }
 
 // NewConfig returns a Config for module instantiation.
@@ -33,9 +42,15 @@ func (c *internalSockConfig) WithTCPListener(host string, port int) Config {
 	return &internalSockConfig{cNew}
 }
 
+// WithListener implements Config.WithListener
+func (c *internalSockConfig) WithListener(ln *net.TCPListener) Config {
+	cNew := c.c.WithListener(ln)
+	return &internalSockConfig{cNew}
+}
+
 // WithConfig registers the given Config into the given context.Context.
 func WithConfig(ctx context.Context, config Config) context.Context {
-	if config, ok := config.(*internalSockConfig); ok && len(config.c.TCPAddresses) > 0 {
+	if config, ok := config.(*internalSockConfig); ok && (len(config.c.TCPAddresses) > 0 || len(config.c.TCPListeners) > 0) {
 		return context.WithValue(ctx, sock.ConfigKey{}, config.c)
 	}
 	return ctx
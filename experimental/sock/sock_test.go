@@ -2,6 +2,7 @@ package sock_test
 
 import (
 	"context"
+	"net"
 	"testing"
 
 	"github.com/tetratelabs/wazero/experimental/sock"
@@ -27,6 +28,11 @@ func TestWithSockConfig(t *testing.T) {
 			sockCfg:  sock.NewConfig(),
 			expected: false,
 		},
+		{
+			name:     "decorates with listener",
+			sockCfg:  sock.NewConfig().WithListener(&net.TCPListener{}),
+			expected: true,
+		},
 		{
 			name:     "decorates with sockCfg",
 			sockCfg:  sock.NewConfig().WithTCPListener("", 0),
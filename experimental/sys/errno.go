@@ -41,6 +41,8 @@ const (
 	ENOTSUP
 	EPERM
 	EROFS
+	ESPIPE
+	EFBIG
 
 	// NOTE ENOTCAPABLE is defined in wasip1, but not in POSIX. wasi-libc
 	// converts it to EBADF, ESPIPE or EINVAL depending on the call site.
@@ -62,6 +64,8 @@ func (e Errno) Error() string {
 		return "file exists"
 	case EFAULT:
 		return "bad address"
+	case EFBIG:
+		return "file too large"
 	case EINTR:
 		return "interrupted function"
 	case EINVAL:
@@ -92,6 +96,8 @@ func (e Errno) Error() string {
 		return "operation not permitted"
 	case EROFS:
 		return "read-only file system"
+	case ESPIPE:
+		return "invalid seek"
 	default:
 		return "Errno(" + strconv.Itoa(int(e)) + ")"
 	}
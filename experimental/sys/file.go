@@ -136,6 +136,7 @@ type File interface {
 	//   - EBADF: the file or directory was closed or not readable.
 	//   - EINVAL: the offset was negative.
 	//   - EISDIR: the file was a directory.
+	//   - ESPIPE: the file doesn't support positioned reads, e.g. a pipe.
 	//
 	// # Notes
 	//
@@ -226,6 +227,7 @@ type File interface {
 	//   - EBADF: the file or directory was closed or not writeable.
 	//   - EINVAL: the offset was negative.
 	//   - EISDIR: the file was a directory.
+	//   - ESPIPE: the file doesn't support positioned writes, e.g. a pipe.
 	//
 	// # Notes
 	//
@@ -22,6 +22,8 @@ func syscallToErrno(err error) (Errno, bool) {
 		return EEXIST, true
 	case syscall.EFAULT:
 		return EFAULT, true
+	case syscall.EFBIG:
+		return EFBIG, true
 	case syscall.EINTR:
 		return EINTR, true
 	case syscall.EINVAL:
@@ -52,6 +54,8 @@ func syscallToErrno(err error) (Errno, bool) {
 		return EPERM, true
 	case syscall.EROFS:
 		return EROFS, true
+	case syscall.ESPIPE:
+		return ESPIPE, true
 	default:
 		return EIO, true
 	}
@@ -72,6 +76,8 @@ func (e Errno) Unwrap() error {
 		return syscall.EEXIST
 	case EFAULT:
 		return syscall.EFAULT
+	case EFBIG:
+		return syscall.EFBIG
 	case EINTR:
 		return syscall.EINTR
 	case EINVAL:
@@ -100,6 +106,8 @@ func (e Errno) Unwrap() error {
 		return syscall.EPERM
 	case EROFS:
 		return syscall.EROFS
+	case ESPIPE:
+		return syscall.ESPIPE
 	default:
 		return syscall.EIO
 	}
@@ -30,6 +30,14 @@ func ExampleDirFS() {
 		WithFSConfig(wazero.NewFSConfig().(sysfs.FSConfig).WithSysFSMount(root, "/"))
 }
 
+// This example shows how to configure a sysfs.NewMemFS
+func ExampleNewMemFS() {
+	root := sysfs.NewMemFS()
+
+	moduleConfig = wazero.NewModuleConfig().
+		WithFSConfig(wazero.NewFSConfig().(sysfs.FSConfig).WithSysFSMount(root, "/"))
+}
+
 // This example shows how to configure a sysfs.ReadFS
 func ExampleReadFS() {
 	root := sysfs.DirFS(".")
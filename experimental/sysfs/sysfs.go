@@ -26,6 +26,15 @@ func DirFS(dir string) experimentalsys.FS {
 	return sysfs.DirFS(dir)
 }
 
+// NewMemFS returns an empty, writable sys.FS backed by memory. This is useful
+// for tests that assert on files a guest wrote, without touching the host
+// filesystem.
+//
+// Note: This is not safe for concurrent use.
+func NewMemFS() experimentalsys.FS {
+	return sysfs.NewMemFS()
+}
+
 // ReadFS is used to mask an existing sys.FS for reads. Notably, this allows
 // the CLI to do read-only mounts of directories the host user can write, but
 // doesn't want the guest wasm to. For example, Python libraries shouldn't be
@@ -5,6 +5,7 @@ import (
 	"encoding/binary"
 	"errors"
 	"fmt"
+	"io"
 	"math"
 	"reflect"
 	"strconv"
@@ -13,6 +14,7 @@ import (
 
 	"github.com/tetratelabs/wazero/api"
 	"github.com/tetratelabs/wazero/internal/internalapi"
+	"github.com/tetratelabs/wazero/internal/memio"
 	"github.com/tetratelabs/wazero/sys"
 )
 
@@ -72,6 +74,73 @@ func (m *Module) Memory() api.Memory {
 	return nil
 }
 
+// RunStart implements the same method as documented on api.Module.
+func (m *Module) RunStart(context.Context) error {
+	return nil
+}
+
+// SnapshotMemory implements the same method as documented on api.Module.
+func (m *Module) SnapshotMemory() []byte {
+	if m.ExportMemory == nil {
+		return nil
+	}
+	return append([]byte{}, m.ExportMemory.Bytes...)
+}
+
+// RestoreMemory implements the same method as documented on api.Module.
+func (m *Module) RestoreMemory(snapshot []byte) error {
+	mem := m.ExportMemory
+	switch pages := uint32(len(snapshot) / PageSize); {
+	case mem == nil:
+		return fmt.Errorf("%s has no memory", m)
+	case len(snapshot)%PageSize != 0:
+		return fmt.Errorf("snapshot size %d isn't a multiple of the page size", len(snapshot))
+	case pages < mem.Min:
+		return fmt.Errorf("snapshot of %d pages is less than the minimum of %d pages", pages, mem.Min)
+	case mem.Max != 0 && pages > mem.Max:
+		return fmt.Errorf("snapshot of %d pages exceeds the maximum of %d pages", pages, mem.Max)
+	}
+	if len(snapshot) > len(mem.Bytes) {
+		mem.Bytes = append(mem.Bytes, make([]byte, len(snapshot)-len(mem.Bytes))...)
+	}
+	tail := mem.Bytes[copy(mem.Bytes, snapshot):]
+	for i := range tail {
+		tail[i] = 0
+	}
+	return nil
+}
+
+// Clone implements the same method as documented on api.Module.
+func (m *Module) Clone(ctx context.Context, newName string) (api.Module, error) {
+	c := &Module{ModuleName: newName}
+	for _, f := range m.Functions {
+		f := *f
+		c.Functions = append(c.Functions, &f)
+	}
+	for _, g := range m.Globals {
+		g := *g
+		c.Globals = append(c.Globals, &g)
+	}
+	if mem := m.ExportMemory; mem != nil {
+		c.ExportMemory = &Memory{Bytes: append([]byte{}, mem.Bytes...), Min: mem.Min, Max: mem.Max}
+	}
+	return c, nil
+}
+
+// CallIndirect implements the same method as documented on api.Module.
+//
+// Tables aren't supported, so this always returns an error.
+func (m *Module) CallIndirect(ctx context.Context, tableIdx, entryIdx, typeIdx uint32, params ...uint64) ([]uint64, error) {
+	return nil, fmt.Errorf("%s has no table %d", m, tableIdx)
+}
+
+// StackRemaining implements the same method as documented on api.Module.
+//
+// The stack of host function calls isn't tracked, so this always returns -1.
+func (m *Module) StackRemaining() int {
+	return -1
+}
+
 // ExportedFunction implements the same method as documented on api.Module.
 func (m *Module) ExportedFunction(name string) api.Function {
 	m.once.Do(m.initialize)
@@ -99,12 +168,37 @@ func (m *Module) ExportedMemoryDefinitions() map[string]api.MemoryDefinition {
 	return m.exportedMemoryDefinitions
 }
 
+// ExportedTable implements the same method as documented on api.Module.
+func (m *Module) ExportedTable(name string) api.Table {
+	return nil
+}
+
 // ExportedGlobal implements the same method as documented on api.Module.
 func (m *Module) ExportedGlobal(name string) api.Global {
 	m.once.Do(m.initialize)
 	return m.exportedGlobals[name]
 }
 
+// GlobalValues implements the same method as documented on api.Module.
+func (m *Module) GlobalValues() map[string]uint64 {
+	m.once.Do(m.initialize)
+	ret := make(map[string]uint64, len(m.exportedGlobals))
+	for name, g := range m.exportedGlobals {
+		ret[name] = g.Get()
+	}
+	return ret
+}
+
+// GlobalTypes implements the same method as documented on api.Module.
+func (m *Module) GlobalTypes() map[string]api.ValueType {
+	m.once.Do(m.initialize)
+	ret := make(map[string]api.ValueType, len(m.exportedGlobals))
+	for name, g := range m.exportedGlobals {
+		ret[name] = g.Type()
+	}
+	return ret
+}
+
 // Close implements the same method as documented on api.Closer.
 func (m *Module) Close(ctx context.Context) error {
 	return m.CloseWithExitCode(ctx, 0)
@@ -477,6 +571,9 @@ type Memory struct {
 
 	// Lazily initialized when accessed through the module.
 	module *Module
+
+	// Count of Pinned calls not yet released, which prevents Grow.
+	pins atomic.Int32
 }
 
 // NewMemory constructs a Memory object with a buffer of the given size, aligned
@@ -517,12 +614,29 @@ func (m *Memory) Grow(deltaPages uint32) (previousPages uint32, ok bool) {
 	if m.Max != 0 && numPages > m.Max {
 		return previousPages, false
 	}
+	if m.pins.Load() > 0 {
+		return previousPages, false
+	}
 	bytes := make([]byte, PageSize*numPages)
 	copy(bytes, m.Bytes)
 	m.Bytes = bytes
 	return previousPages, true
 }
 
+func (m *Memory) Pinned() ([]byte, func(), error) {
+	if len(m.Bytes) == 0 {
+		return nil, nil, errors.New("cannot pin an empty memory")
+	}
+	m.pins.Add(1)
+	var released atomic.Bool
+	release := func() {
+		if released.CompareAndSwap(false, true) {
+			m.pins.Add(-1)
+		}
+	}
+	return m.Bytes, release, nil
+}
+
 func (m *Memory) ReadByte(offset uint32) (byte, bool) {
 	if m.isOutOfRange(offset, 1) {
 		return 0, false
@@ -624,6 +738,26 @@ func (m *Memory) WriteString(offset uint32, value string) bool {
 	return true
 }
 
+func (m *Memory) Reader(offset, length uint32) io.Reader {
+	return memio.NewReader(m, offset, length)
+}
+
+func (m *Memory) ReaderAt(offset, length uint32) io.ReaderAt {
+	return memio.NewReaderAt(m, offset, length)
+}
+
+func (m *Memory) Writer(offset, length uint32) io.Writer {
+	return memio.NewWriter(m, offset, length)
+}
+
+func (m *Memory) WriterAt(offset, length uint32) io.WriterAt {
+	return memio.NewWriterAt(m, offset, length)
+}
+
+func (m *Memory) ReadFrom(offset uint32, r io.Reader, limit uint32) (uint32, error) {
+	return memio.ReadFrom(m, offset, r, limit)
+}
+
 func (m *Memory) isOutOfRange(offset, length uint32) bool {
 	size := m.Size()
 	return offset >= size || length > size || offset > (size-length)
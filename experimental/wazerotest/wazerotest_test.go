@@ -165,3 +165,55 @@ func TestNewFixedMemory(t *testing.T) {
 		t.Error("invalid max memory size:", memory.Max)
 	}
 }
+
+func TestModule_RestoreMemory(t *testing.T) {
+	m := NewModule(NewFixedMemory(PageSize))
+	m.ExportMemory.Bytes[0] = 1
+	snapshot := m.SnapshotMemory()
+
+	m.ExportMemory.Bytes[0] = 2
+	if err := m.RestoreMemory(snapshot); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(snapshot, m.ExportMemory.Bytes) {
+		t.Error("memory not restored")
+	}
+	if err := m.RestoreMemory(make([]byte, 2*PageSize)); err == nil {
+		t.Error("restored a snapshot over the maximum pages")
+	}
+
+	m = NewModule(&Memory{Bytes: make([]byte, 2*PageSize), Max: 2})
+	m.ExportMemory.Bytes[PageSize] = 2
+	if err := m.RestoreMemory(snapshot); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(snapshot, m.ExportMemory.Bytes[:PageSize]) || !bytes.Equal(make([]byte, PageSize), m.ExportMemory.Bytes[PageSize:]) {
+		t.Error("memory not restored to its current size with the bytes beyond the snapshot zeroed")
+	}
+	if err := NewModule(nil).RestoreMemory(snapshot); err == nil {
+		t.Error("restored a module without memory")
+	}
+}
+
+func TestModule_Clone(t *testing.T) {
+	m := NewModule(NewFixedMemory(PageSize))
+	m.Globals = []*Global{GlobalI32(1)}
+	m.ExportMemory.Bytes[0] = 1
+
+	clone, err := m.Clone(context.Background(), "clone")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.ExportMemory.Bytes[0] = 2
+	m.Globals[0].Value = 2
+
+	if clone.Name() != "clone" {
+		t.Error("invalid clone name:", clone.Name())
+	}
+	if b, _ := clone.Memory().ReadByte(0); b != 1 {
+		t.Error("memory not copied:", b)
+	}
+	if v := clone.(*Module).Globals[0].Value; v != 1 {
+		t.Error("global not copied:", v)
+	}
+}
@@ -555,6 +555,8 @@ func toTimes(walltime func() int64, atim, mtim int64, fstFlags uint16) (int64, i
 // descriptor, without using and updating the file descriptor's offset.
 //
 // Except for handling offset, this implementation is identical to fdRead.
+// Files that can't be positioned, such as pipes or stdio, result in
+// sys.ESPIPE.
 //
 // See https://github.com/WebAssembly/WASI/blob/snapshot-01/phases/snapshot/docs.md#-fd_preadfd-fd-iovs-iovec_array-offset-filesize---errno-size
 var fdPread = newHostFunc(
@@ -678,6 +680,8 @@ func fdPrestatDirNameFn(_ context.Context, mod api.Module, params []uint64) expe
 // descriptor, without using and updating the file descriptor's offset.
 //
 // Except for handling offset, this implementation is identical to fdWrite.
+// Files that can't be positioned, such as pipes or stdio, result in
+// sys.ESPIPE.
 //
 // See https://github.com/WebAssembly/WASI/blob/snapshot-01/phases/snapshot/docs.md#-fd_pwritefd-fd-iovs-ciovec_array-offset-filesize---errno-size
 var fdPwrite = newHostFunc(
@@ -17,6 +17,7 @@ import (
 	"github.com/tetratelabs/wazero"
 	"github.com/tetratelabs/wazero/api"
 	experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
+	experimentalsysfs "github.com/tetratelabs/wazero/experimental/sysfs"
 	"github.com/tetratelabs/wazero/internal/fsapi"
 	"github.com/tetratelabs/wazero/internal/fstest"
 	"github.com/tetratelabs/wazero/internal/platform"
@@ -1282,6 +1283,24 @@ func Test_fdPread_Errors(t *testing.T) {
 			expectedLog: `
 ==> wasi_snapshot_preview1.fd_pread(fd=4,iovs=65523,iovs_len=1,offset=-1)
 <== (nread=,errno=EIO)
+`,
+		},
+		{
+			name: "not seekable",
+			fd:   sys.FdStdin,
+			iovs: 1, iovsCount: 1,
+			resultNread: 10,
+			memory: []byte{
+				'?',        // `iovs` is after this
+				9, 0, 0, 0, // = iovs[0].offset
+				1, 0, 0, 0, // = iovs[0].length
+				'?',
+				'?', '?', '?', '?',
+			},
+			expectedErrno: wasip1.ErrnoSpipe,
+			expectedLog: `
+==> wasi_snapshot_preview1.fd_pread(fd=0,iovs=65523,iovs_len=1,offset=0)
+<== (nread=,errno=ESPIPE)
 `,
 		},
 	}
@@ -1774,6 +1793,24 @@ func Test_fdPwrite_Errors(t *testing.T) {
 			expectedLog: `
 ==> wasi_snapshot_preview1.fd_pwrite(fd=4,iovs=65523,iovs_len=1,offset=-1)
 <== (nwritten=,errno=EIO)
+`,
+		},
+		{
+			name: "not seekable",
+			fd:   sys.FdStdout,
+			iovs: 1, iovsCount: 1,
+			resultNwritten: 10,
+			memory: []byte{
+				'?',        // `iovs` is after this
+				9, 0, 0, 0, // = iovs[0].offset
+				1, 0, 0, 0, // = iovs[0].length
+				'?',
+				'?', '?', '?', '?',
+			},
+			expectedErrno: wasip1.ErrnoSpipe,
+			expectedLog: `
+==> wasi_snapshot_preview1.fd_pwrite(fd=1,iovs=65523,iovs_len=1,offset=0)
+<== (nwritten=,errno=ESPIPE)
 `,
 		},
 	}
@@ -2817,6 +2854,35 @@ func Test_fdWrite(t *testing.T) {
 	require.Equal(t, []byte("wazero"), buf) // verify the file was actually written
 }
 
+func Test_fdWrite_stdioBuffer(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	mod, r, _ := requireProxyModule(t, wazero.NewModuleConfig().
+		WithStdoutBuffer(&stdout).
+		WithStderrBuffer(&stderr))
+	defer r.Close(testCtx)
+
+	iovs := uint32(1) // arbitrary offset
+	initialMemory := []byte{
+		'?',         // `iovs` is after this
+		10, 0, 0, 0, // = iovs[0].offset
+		6, 0, 0, 0, // = iovs[0].length
+		'?',                          // iovs[0].offset is after this
+		'w', 'a', 'z', 'e', 'r', 'o', // iovs[0].length bytes
+	}
+	iovsCount := uint32(1)       // The count of iovs
+	resultNwritten := uint32(16) // arbitrary offset
+
+	ok := mod.Memory().Write(0, initialMemory)
+	require.True(t, ok)
+
+	requireErrnoResult(t, wasip1.ErrnoSuccess, mod, wasip1.FdWriteName, uint64(sys.FdStdout), uint64(iovs), uint64(iovsCount), uint64(resultNwritten))
+	require.Equal(t, "wazero", stdout.String())
+	require.Zero(t, stderr.Len())
+
+	requireErrnoResult(t, wasip1.ErrnoSuccess, mod, wasip1.FdWriteName, uint64(sys.FdStderr), uint64(iovs), uint64(iovsCount), uint64(resultNwritten))
+	require.Equal(t, "wazero", stderr.String())
+}
+
 func Test_fdWrite_Errors(t *testing.T) {
 	tmpDir := t.TempDir() // open before loop to ensure no locking problems.
 	pathName := "test_path"
@@ -3911,6 +3977,98 @@ func Test_pathOpen(t *testing.T) {
 	}
 }
 
+// Test_pathOpen_multipleMounts ensures each pre-opened directory resolves
+// paths relative to its own host directory, and doesn't allow escaping it.
+func Test_pathOpen_multipleMounts(t *testing.T) {
+	etcDir, dataDir := t.TempDir(), t.TempDir()
+	writeFile(t, etcDir, "app.conf", []byte("conf"))
+
+	fsConfig := wazero.NewFSConfig().
+		WithReadOnlyDirMount(etcDir, "/etc").
+		WithDirMount(dataDir, "/data")
+	mod, r, log := requireProxyModule(t, wazero.NewModuleConfig().WithFSConfig(fsConfig))
+	defer r.Close(testCtx)
+
+	fsc := mod.(*wasm.ModuleInstance).Sys.FS()
+	etcFd, dataFd := sys.FdPreopen, sys.FdPreopen+1
+	for fd, guestPath := range map[int32]string{etcFd: "/etc", dataFd: "/data"} {
+		f, ok := fsc.LookupFile(fd)
+		require.True(t, ok)
+		require.True(t, f.IsPreopen)
+		require.Equal(t, guestPath, f.Name)
+	}
+
+	pathOpen := func(fd int32, pathName string, oflags uint16) wasip1.Errno {
+		defer log.Reset()
+		mod.Memory().Write(0, []byte(pathName))
+		pathLen := uint32(len(pathName))
+		rights := wasip1.RIGHT_FD_READ
+		if oflags&wasip1.O_CREAT != 0 {
+			rights |= wasip1.RIGHT_FD_WRITE
+		}
+		results, err := mod.ExportedFunction(wasip1.PathOpenName).Call(testCtx, uint64(fd), 0, 0,
+			uint64(pathLen), uint64(oflags), uint64(rights), 0, 0, uint64(pathLen))
+		require.NoError(t, err)
+		return wasip1.Errno(results[0])
+	}
+
+	t.Run("opens from its own mount", func(t *testing.T) {
+		require.Equal(t, wasip1.ErrnoSuccess, pathOpen(etcFd, "app.conf", 0))
+		require.Equal(t, wasip1.ErrnoNoent, pathOpen(dataFd, "app.conf", 0))
+	})
+
+	t.Run("creates in its own mount", func(t *testing.T) {
+		require.Equal(t, wasip1.ErrnoSuccess, pathOpen(dataFd, "out.txt", wasip1.O_CREAT))
+		_, err := os.Stat(joinPath(dataDir, "out.txt"))
+		require.NoError(t, err)
+		_, err = os.Stat(joinPath(etcDir, "out.txt"))
+		require.True(t, os.IsNotExist(err))
+	})
+
+	t.Run("rejects escaping the mount", func(t *testing.T) {
+		require.Equal(t, wasip1.ErrnoPerm, pathOpen(dataFd, "../etc/app.conf", 0))
+		require.Equal(t, wasip1.ErrnoPerm, pathOpen(etcFd, "/etc/app.conf", 0))
+	})
+}
+
+// Test_memFS ensures files written by the guest into sysfs.NewMemFS can be
+// read back by the host.
+func Test_memFS(t *testing.T) {
+	memFS := sysfs.NewMemFS()
+
+	fsConfig := wazero.NewFSConfig().(experimentalsysfs.FSConfig).WithSysFSMount(memFS, "/")
+	mod, r, _ := requireProxyModule(t, wazero.NewModuleConfig().WithFSConfig(fsConfig))
+	defer r.Close(testCtx)
+
+	dirName, fileName, contents := "dir", "dir/out.txt", "hello"
+	mod.Memory().Write(0, []byte(dirName))
+	requireErrnoResult(t, wasip1.ErrnoSuccess, mod, wasip1.PathCreateDirectoryName,
+		uint64(sys.FdPreopen), 0, uint64(len(dirName)))
+
+	resultOpenedFd := uint32(64)
+	mod.Memory().Write(0, []byte(fileName))
+	requireErrnoResult(t, wasip1.ErrnoSuccess, mod, wasip1.PathOpenName, uint64(sys.FdPreopen), 0, 0,
+		uint64(len(fileName)), uint64(wasip1.O_CREAT|wasip1.O_TRUNC), uint64(wasip1.RIGHT_FD_WRITE), 0, 0, uint64(resultOpenedFd))
+	fd, ok := mod.Memory().ReadUint32Le(resultOpenedFd)
+	require.True(t, ok)
+
+	// Write the contents via a single iovec.
+	iovs, resultNwritten := uint32(128), uint32(136)
+	mod.Memory().Write(0, []byte(contents))
+	mod.Memory().WriteUint32Le(iovs, 0)
+	mod.Memory().WriteUint32Le(iovs+4, uint32(len(contents)))
+	requireErrnoResult(t, wasip1.ErrnoSuccess, mod, wasip1.FdWriteName, uint64(fd), uint64(iovs), 1, uint64(resultNwritten))
+	requireErrnoResult(t, wasip1.ErrnoSuccess, mod, wasip1.FdCloseName, uint64(fd))
+
+	f, errno := memFS.OpenFile(fileName, experimentalsys.O_RDONLY, 0)
+	require.EqualErrno(t, 0, errno)
+	defer f.Close()
+	buf := make([]byte, 16)
+	n, errno := f.Read(buf)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, contents, string(buf[:n]))
+}
+
 func writeAndCloseFile(t *testing.T, fsc *sys.FSContext, fd int32) []byte {
 	contents := []byte("hello")
 	f, ok := fsc.LookupFile(fd)
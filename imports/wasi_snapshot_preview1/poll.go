@@ -2,6 +2,7 @@ package wasi_snapshot_preview1
 
 import (
 	"context"
+	"math"
 	"time"
 
 	"github.com/tetratelabs/wazero/api"
@@ -34,6 +35,8 @@ import (
 //
 //   - Since the `out` pointer nests Errno, the result is always 0.
 //   - This is similar to `poll` in POSIX.
+//   - Absolute clock subscriptions are relative to the current time of the
+//     "realtime" or "monotonic" clock configured in wazero.ModuleConfig.
 //
 // See https://github.com/WebAssembly/WASI/blob/snapshot-01/phases/snapshot/docs.md#poll_oneoff
 // See https://linux.die.net/man/3/poll
@@ -43,6 +46,9 @@ var pollOneoff = newHostFunc(
 	"in", "out", "nsubscriptions", "result.nevents",
 )
 
+// noTimeout is the timeout when there are no clock subscriptions.
+const noTimeout time.Duration = 1<<63 - 1
+
 type event struct {
 	eventType byte
 	userData  []byte
@@ -85,11 +91,12 @@ func pollOneoffFn(_ context.Context, mod api.Module, params []uint64) sys.Errno
 	// Loop through all subscriptions and write their output.
 
 	// Extract FS context, used in the body of the for loop for FS access.
-	fsc := mod.(*wasm.ModuleInstance).Sys.FS()
+	sysCtx := mod.(*wasm.ModuleInstance).Sys
+	fsc := sysCtx.FS()
 	// Slice of events that are processed out of the loop (blocking stdin subscribers).
 	var blockingStdinSubs []*event
 	// The timeout is initialized at max Duration, the loop will find the minimum.
-	var timeout time.Duration = 1<<63 - 1
+	timeout := noTimeout
 	// Count of all the subscriptions that have been already written back to outBuf.
 	// nevents*32 returns at all times the offset where the next event should be written:
 	// this way we ensure that there are no gaps between records.
@@ -114,7 +121,7 @@ func pollOneoffFn(_ context.Context, mod api.Module, params []uint64) sys.Errno
 
 		switch eventType {
 		case wasip1.EventTypeClock: // handle later
-			newTimeout, err := processClockEvent(argBuf)
+			newTimeout, err := processClockEvent(sysCtx, argBuf)
 			if err != 0 {
 				return err
 			}
@@ -159,13 +166,12 @@ func pollOneoffFn(_ context.Context, mod api.Module, params []uint64) sys.Errno
 		}
 	}
 
-	sysCtx := mod.(*wasm.ModuleInstance).Sys
 	if nevents == nsubscriptions {
 		// We already wrote back all the results. We already wrote this number
 		// earlier to offset `resultNevents`.
 		// We only need to observe the timeout (nonzero if there are clock subscriptions)
 		// and return.
-		if timeout > 0 {
+		if timeout > 0 && timeout != noTimeout {
 			sysCtx.Nanosleep(int64(timeout))
 		}
 		return 0
@@ -178,7 +184,7 @@ func pollOneoffFn(_ context.Context, mod api.Module, params []uint64) sys.Errno
 	}
 	// Wait for the timeout to expire, or for some data to become available on Stdin.
 
-	if stdinReady, errno := stdin.File.Poll(fsapi.POLLIN, int32(timeout.Milliseconds())); errno != 0 {
+	if stdinReady, errno := stdin.File.Poll(fsapi.POLLIN, pollTimeoutMillis(timeout)); errno != 0 {
 		return errno
 	} else if stdinReady {
 		// stdin has data ready to for reading, write back all the events
@@ -199,32 +205,53 @@ func pollOneoffFn(_ context.Context, mod api.Module, params []uint64) sys.Errno
 	return 0
 }
 
-// processClockEvent supports only relative name events, as that's what's used
-// to implement sleep in various compilers including Rust, Zig and TinyGo.
-func processClockEvent(inBuf []byte) (time.Duration, sys.Errno) {
-	_ /* ID */ = le.Uint32(inBuf[0:8])          // See below
+// processClockEvent returns the timeout of a clock subscription. Relative
+// timeouts are used to implement sleep in various compilers including Rust,
+// Zig and TinyGo. Absolute ones are relative to the clock of the subscription,
+// which honors the clocks configured in wazero.ModuleConfig.
+func processClockEvent(sysCtx *internalsys.Context, inBuf []byte) (time.Duration, sys.Errno) {
+	id := le.Uint32(inBuf[0:8])                 // See below
 	timeout := le.Uint64(inBuf[8:16])           // nanos if relative
 	_ /* precision */ = le.Uint64(inBuf[16:24]) // Unused
 	flags := le.Uint16(inBuf[24:32])
 
-	var err sys.Errno
 	// subclockflags has only one flag defined:  subscription_clock_abstime
 	switch flags {
 	case 0: // relative time
+		// https://linux.die.net/man/3/clock_settime says relative timers are
+		// unaffected by the clock, so we can skip name ID validation and use a
+		// single sleep function.
+		return time.Duration(timeout), 0
 	case 1: // subscription_clock_abstime
-		err = sys.ENOTSUP
+		var now int64
+		switch id {
+		case wasip1.ClockIDRealtime:
+			now = sysCtx.WalltimeNanos()
+		case wasip1.ClockIDMonotonic:
+			now = sysCtx.Nanotime()
+		default:
+			return 0, sys.EINVAL
+		}
+		// A deadline in the past expires immediately.
+		if deadline := int64(timeout); deadline > now {
+			return time.Duration(deadline - now), 0
+		}
+		return 0, 0
 	default: // subclockflags has only one flag defined.
-		err = sys.EINVAL
+		return 0, sys.EINVAL
 	}
+}
 
-	if err != 0 {
-		return 0, err
-	} else {
-		// https://linux.die.net/man/3/clock_settime says relative timers are
-		// unaffected. Since this function only supports relative timeout, we can
-		// skip name ID validation and use a single sleep function.
-
-		return time.Duration(timeout), 0
+// pollTimeoutMillis converts the minimum timeout of the clock subscriptions
+// to that of fsapi.File Poll, where a negative value blocks indefinitely.
+func pollTimeoutMillis(timeout time.Duration) int32 {
+	switch millis := timeout.Milliseconds(); {
+	case timeout == noTimeout:
+		return -1
+	case millis > math.MaxInt32:
+		return math.MaxInt32
+	default:
+		return int32(millis)
 	}
 }
 
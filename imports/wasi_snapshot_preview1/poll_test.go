@@ -64,6 +64,61 @@ func Test_pollOneoff(t *testing.T) {
 	require.Equal(t, nsubscriptions, nevents)
 }
 
+func Test_pollOneoff_AbsoluteClock(t *testing.T) {
+	tests := []struct {
+		name          string
+		clockID       byte
+		deadline      uint64
+		expectedErrno wasip1.Errno
+		expectedSleep int64
+	}{
+		{
+			name:          "monotonic",
+			clockID:       wasip1.ClockIDMonotonic,
+			deadline:      150,
+			expectedSleep: 50,
+		},
+		{
+			name:     "monotonic deadline passed",
+			clockID:  wasip1.ClockIDMonotonic,
+			deadline: 50,
+		},
+		{
+			name:          "realtime",
+			clockID:       wasip1.ClockIDRealtime,
+			deadline:      2*1e9 + 20,
+			expectedSleep: 10,
+		},
+		{
+			name:          "invalid clock",
+			clockID:       2,
+			deadline:      150,
+			expectedErrno: wasip1.ErrnoInval,
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			var slept int64
+			mod, r, _ := requireProxyModule(t, wazero.NewModuleConfig().
+				WithNanotime(func() int64 { return 100 }, sysapi.ClockResolution(1)).
+				WithWalltime(func() (int64, int32) { return 2, 10 }, sysapi.ClockResolution(1)).
+				WithNanosleep(func(ns int64) { slept += ns }))
+			defer r.Close(testCtx)
+
+			maskMemory(t, mod, 1024)
+			sub := clockNsSub(tc.deadline)
+			sub[16] = tc.clockID
+			sub[40] = 1 // subscription_clock_abstime
+			mod.Memory().Write(0, sub)
+
+			requireErrnoResult(t, tc.expectedErrno, mod, wasip1.PollOneoffName, 0, 128, 1, 512)
+			require.Equal(t, tc.expectedSleep, slept)
+		})
+	}
+}
+
 func Test_pollOneoff_Errors(t *testing.T) {
 	mod, r, log := requireProxyModule(t, wazero.NewModuleConfig())
 	defer r.Close(testCtx)
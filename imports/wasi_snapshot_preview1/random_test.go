@@ -39,6 +39,26 @@ func Test_randomGet(t *testing.T) {
 	require.Equal(t, expectedMemory, actual)
 }
 
+// Test_randomGet_ShortReads ensures the buffer is filled even when the source
+// returns fewer bytes than requested per read.
+func Test_randomGet_ShortReads(t *testing.T) {
+	mod, r, log := requireProxyModule(t, wazero.NewModuleConfig().
+		WithRandSource(iotest.OneByteReader(bytes.NewReader([]byte{1, 2, 3, 4, 5}))))
+	defer r.Close(testCtx)
+
+	maskMemory(t, mod, 7)
+
+	requireErrnoResult(t, wasip1.ErrnoSuccess, mod, wasip1.RandomGetName, uint64(1), uint64(5))
+	require.Equal(t, `
+==> wasi_snapshot_preview1.random_get(buf=1,buf_len=5)
+<== errno=ESUCCESS
+`, "\n"+log.String())
+
+	actual, ok := mod.Memory().Read(0, 7)
+	require.True(t, ok)
+	require.Equal(t, []byte{'?', 1, 2, 3, 4, 5, '?'}, actual)
+}
+
 func Test_randomGet_Errors(t *testing.T) {
 	mod, r, log := requireProxyModule(t, wazero.NewModuleConfig())
 	defer r.Close(testCtx)
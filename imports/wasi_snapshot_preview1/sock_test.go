@@ -65,6 +65,49 @@ func Test_sockAccept(t *testing.T) {
 	}
 }
 
+func Test_sockAccept_Listener(t *testing.T) {
+	ln, err := net.ListenTCP("tcp", &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	require.NoError(t, err)
+	ctx := experimentalsock.WithConfig(testCtx, experimentalsock.NewConfig().WithListener(ln))
+
+	mod, r, log := requireProxyModuleWithContext(ctx, t, wazero.NewModuleConfig())
+	defer r.Close(testCtx)
+	require.Equal(t, ln.Addr(), requireTCPListenerAddr(t, mod))
+
+	// Nothing is pending, so accept fails once the listener is non-blocking.
+	requireErrnoResult(t, wasip1.ErrnoSuccess, mod, wasip1.FdFdstatSetFlagsName, uint64(sys.FdPreopen), uint64(wasip1.FD_NONBLOCK))
+	requireErrnoResult(t, wasip1.ErrnoAgain, mod, wasip1.SockAcceptName, uint64(sys.FdPreopen), 0, 128)
+
+	tcp, err := net.DialTCP("tcp", nil, ln.Addr().(*net.TCPAddr))
+	require.NoError(t, err)
+	defer tcp.Close() //nolint
+
+	requireErrnoResult(t, wasip1.ErrnoSuccess, mod, wasip1.SockAcceptName, uint64(sys.FdPreopen), 0, 128)
+	connFd, _ := mod.Memory().ReadUint32Le(128)
+	require.Equal(t, uint32(4), connFd)
+
+	// Writes to the connection are routed to its fd.
+	_, err = tcp.Write([]byte("wazero"))
+	require.NoError(t, err)
+	iovs := uint32(0)
+	require.True(t, mod.Memory().WriteUint32Le(iovs, 32))  // iovs[0].offset
+	require.True(t, mod.Memory().WriteUint32Le(iovs+4, 6)) // iovs[0].length
+	requireErrnoResult(t, wasip1.ErrnoSuccess, mod, wasip1.FdReadName, uint64(connFd), uint64(iovs), 1, 64)
+	buf, _ := mod.Memory().Read(32, 6)
+	require.Equal(t, "wazero", string(buf))
+
+	require.Equal(t, `
+==> wasi_snapshot_preview1.fd_fdstat_set_flags(fd=3,flags=NONBLOCK)
+<== errno=ESUCCESS
+==> wasi_snapshot_preview1.sock_accept(fd=3,flags=)
+<== (fd=,errno=EAGAIN)
+==> wasi_snapshot_preview1.sock_accept(fd=3,flags=)
+<== (fd=4,errno=ESUCCESS)
+==> wasi_snapshot_preview1.fd_read(fd=4,iovs=0,iovs_len=1)
+<== (nread=6,errno=ESUCCESS)
+`, "\n"+log.String())
+}
+
 func Test_sockShutdown(t *testing.T) {
 	tests := []struct {
 		name          string
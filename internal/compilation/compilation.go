@@ -0,0 +1,83 @@
+// Package compilation allows experimental compilation hooks without
+// introducing a package cycle.
+package compilation
+
+// MemoryConfigKey is a context.Context Value key. Its associated value should
+// be a *MemoryConfig.
+type MemoryConfigKey struct{}
+
+// MemoryConfig bounds and reports the pooled scratch memory, e.g. SSA
+// instructions or register allocation nodes, used while compiling a single
+// function.
+type MemoryConfig struct {
+	// Limit is the maximum scratch memory in bytes a single function can use.
+	// Zero means unbounded.
+	Limit uint64
+	// Observer, when non-nil, is called once per compiled module with the
+	// peak scratch memory in bytes.
+	Observer func(peakBytes uint64)
+}
+
+// SSADumperKey is a context.Context Value key. Its associated value should be
+// a func(funcName, stage, ssaText string).
+type SSADumperKey struct{}
+
+// The stages at which the SSA is passed to the function registered with
+// SSADumperKey.
+const (
+	// SSAStageLowered is the SSA right after lowering from Wasm, before any
+	// optimization passes.
+	SSAStageLowered = "lowered"
+	// SSAStageOptimized is the SSA after optimization passes.
+	SSAStageOptimized = "optimized"
+)
+
+// InliningThresholdKey is a context.Context Value key. Its associated value
+// should be an int, which is the maximum number of instructions of a function
+// whose calls are inlined.
+type InliningThresholdKey struct{}
+
+// CanonicalNaNKey is a context.Context Value key. Its associated value should
+// be a bool, which is true if NaN results of floating-point arithmetic are
+// replaced with the canonical NaN.
+type CanonicalNaNKey struct{}
+
+// FunctionCompileTimeoutKey is a context.Context Value key. Its associated
+// value should be a time.Duration, which is the maximum time compiling a
+// single function can take.
+type FunctionCompileTimeoutKey struct{}
+
+// SymbolizerKey is a context.Context Value key. Its associated value should be
+// an experimental.Symbolizer.
+type SymbolizerKey struct{}
+
+// RegAllocObserverKey is a context.Context Value key. Its associated value
+// should be a func(funcName string, info *RegAllocInfo).
+type RegAllocObserverKey struct{}
+
+// RegAllocInfo summarizes the register allocation of a single function.
+type RegAllocInfo struct {
+	// VirtualRegisters is the number of virtual registers allocated.
+	VirtualRegisters int
+	// Spills is the number of stores of registers to the stack inserted by
+	// the allocator, including those saving registers around calls.
+	Spills int
+	// SpilledRanges are the live ranges of the virtual registers which were
+	// not assigned a real register, ordered by block and then by position.
+	SpilledRanges []LiveRange
+}
+
+// LiveRange is a range of instructions in a block where a virtual register
+// is live.
+type LiveRange struct {
+	// VirtualRegister is the ID of the virtual register.
+	VirtualRegister uint32
+	// Block is the ID of the block.
+	Block int
+	// Begin and End are the indexes of the first and last instructions of the
+	// range in the block, before any spill instructions are inserted.
+	Begin, End int
+	// LiveOut is true when the register is live at the end of the block, in
+	// which case End is -1.
+	LiveOut bool
+}
@@ -301,6 +301,11 @@ type compiler interface {
 	// compileV128ITruncSatFromF adds instructions to perform wazeroir.NewOperationV128ITruncSatFromF.
 	compileV128ITruncSatFromF(o *wazeroir.UnionOperation) error
 
+	// compileAtomicCheckAlignment adds instructions to perform wazeroir.NewOperationAtomicCheckAlignment.
+	compileAtomicCheckAlignment(o *wazeroir.UnionOperation) error
+	// compileNarrowAddress adds instructions to perform wazeroir.NewOperationNarrowAddress.
+	compileNarrowAddress() error
+
 	// compileBuiltinFunctionCheckExitCode adds instructions to perform wazeroir.OperationBuiltinFunctionCheckExitCode.
 	compileBuiltinFunctionCheckExitCode() error
 
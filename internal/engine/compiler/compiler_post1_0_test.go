@@ -255,7 +255,7 @@ func TestCompiler_compileMemoryCopy(t *testing.T) {
 }
 
 func TestCompiler_compileMemoryFill(t *testing.T) {
-	const checkCeil = 50
+	const memSize = defaultMemoryPageNumInTest * wasm.MemoryPageSize
 
 	tests := []struct {
 		v, destOffset           uint32
@@ -279,10 +279,16 @@ func TestCompiler_compileMemoryFill(t *testing.T) {
 		{v: 10, destOffset: 0, size: 5},
 		{v: 10, destOffset: 0, size: 1},
 		{v: 10, destOffset: 0, size: 0},
-		{v: 10, destOffset: defaultMemoryPageNumInTest*wasm.MemoryPageSize - 99, size: 100, requireOutOfBoundsError: true},
-		{v: 10, destOffset: defaultMemoryPageNumInTest * wasm.MemoryPageSize, size: 5, requireOutOfBoundsError: true},
-		{v: 10, destOffset: defaultMemoryPageNumInTest * wasm.MemoryPageSize, size: 1, requireOutOfBoundsError: true},
-		{v: 10, destOffset: defaultMemoryPageNumInTest*wasm.MemoryPageSize + 1, size: 0, requireOutOfBoundsError: true},
+		// Only the lowest byte of the value is used.
+		{v: 0x1ff, destOffset: 3, size: 21},
+		{v: 7, destOffset: 3, size: 60000},
+		{v: 10, destOffset: memSize - 21, size: 21},
+		{v: 10, destOffset: memSize - 16, size: 16},
+		{v: 10, destOffset: memSize, size: 0},
+		{v: 10, destOffset: memSize - 99, size: 100, requireOutOfBoundsError: true},
+		{v: 10, destOffset: memSize, size: 5, requireOutOfBoundsError: true},
+		{v: 10, destOffset: memSize, size: 1, requireOutOfBoundsError: true},
+		{v: 10, destOffset: memSize + 1, size: 0, requireOutOfBoundsError: true},
 	}
 
 	for i, tt := range tests {
@@ -316,29 +322,25 @@ func TestCompiler_compileMemoryFill(t *testing.T) {
 
 			// Setup the memory region.
 			mem := env.memory()
-			for i := 0; i < checkCeil; i++ {
+			exp := make([]byte, len(mem))
+			for i := range mem {
 				mem[i] = byte(i)
+				exp[i] = byte(i)
 			}
 
 			// Run code.
 			env.exec(code.Bytes())
 
 			if !tc.requireOutOfBoundsError {
-				exp := make([]byte, checkCeil)
-				for i := 0; i < checkCeil; i++ {
-					if i >= int(tc.destOffset) && i < int(tc.destOffset+tc.size) {
-						exp[i] = byte(tc.v)
-					} else {
-						exp[i] = byte(i)
-					}
+				for i := tc.destOffset; i < tc.destOffset+tc.size; i++ {
+					exp[i] = byte(tc.v)
 				}
-
-				// Check the status code and the destination memory region.
 				require.Equal(t, nativeCallStatusCodeReturned, env.compilerStatus())
-				require.Equal(t, exp, mem[:checkCeil])
 			} else {
 				require.Equal(t, nativeCallStatusCodeMemoryOutOfBounds, env.compilerStatus())
 			}
+			// Nothing else is written, nor anything at all when out of bounds.
+			require.Equal(t, exp, mem)
 		})
 	}
 }
@@ -446,7 +448,7 @@ func TestCompiler_compileMemoryInit(t *testing.T) {
 			err = compiler.compileConstI32(operationPtr(wazeroir.NewOperationConstI32(tc.copySize)))
 			require.NoError(t, err)
 
-			err = compiler.compileMemoryInit(operationPtr(wazeroir.NewOperationMemoryInit(tc.dataIndex)))
+			err = compiler.compileMemoryInit(operationPtr(wazeroir.NewOperationMemoryInit(tc.dataIndex, 0)))
 			require.NoError(t, err)
 
 			code := asm.CodeSegment{}
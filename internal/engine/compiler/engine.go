@@ -437,6 +437,7 @@ const (
 	nativeCallStatusCodeTypeMismatchOnIndirectCall
 	nativeCallStatusIntegerOverflow
 	nativeCallStatusIntegerDivisionByZero
+	nativeCallStatusUnalignedAtomic
 	nativeCallStatusModuleClosed
 )
 
@@ -458,6 +459,8 @@ func (s nativeCallStatusCode) causePanic() {
 		err = wasmruntime.ErrRuntimeInvalidTableAccess
 	case nativeCallStatusCodeTypeMismatchOnIndirectCall:
 		err = wasmruntime.ErrRuntimeIndirectCallTypeMismatch
+	case nativeCallStatusUnalignedAtomic:
+		err = wasmruntime.ErrRuntimeUnalignedAtomic
 	}
 	panic(err)
 }
@@ -486,6 +489,8 @@ func (s nativeCallStatusCode) String() (ret string) {
 		ret = "integer division by zero"
 	case nativeCallStatusModuleClosed:
 		ret = "module closed"
+	case nativeCallStatusUnalignedAtomic:
+		ret = "unaligned atomic"
 	default:
 		panic("BUG")
 	}
@@ -529,6 +534,13 @@ func (e *engine) CompileModule(_ context.Context, module *wasm.Module, listeners
 		return err
 	}
 
+	if len(module.AdditionalMemorySection) > 0 {
+		return errors.New("multiple memories require the interpreter: use wazero.NewRuntimeConfigInterpreter")
+	}
+	if module.UsesExceptionHandling {
+		return errors.New("exception handling is not supported by the compiler")
+	}
+
 	irCompiler, err := wazeroir.NewCompiler(e.enabledFeatures, callFrameDataSizeInUint64, module, ensureTermination)
 	if err != nil {
 		return err
@@ -616,6 +628,13 @@ func (e *engine) CompileModule(_ context.Context, module *wasm.Module, listeners
 
 // NewModuleEngine implements the same method as documented on wasm.Engine.
 func (e *engine) NewModuleEngine(module *wasm.Module, instance *wasm.ModuleInstance) (wasm.ModuleEngine, error) {
+	if instance.MaxCallDepth() != 0 {
+		return nil, errors.New("WithMaxCallDepth is not supported by the compiler: use wazero.NewRuntimeConfigInterpreter")
+	}
+	if _, limited := instance.Fuel(); limited {
+		return nil, errors.New("WithFuel is not supported by the compiler: use wazero.NewRuntimeConfigInterpreter")
+	}
+
 	me := &moduleEngine{
 		functions: make([]function, len(module.FunctionSection)+int(module.ImportFunctionCount)),
 	}
@@ -664,6 +683,19 @@ func (e *moduleEngine) FunctionInstanceReference(funcIndex wasm.Index) wasm.Refe
 	return uintptr(unsafe.Pointer(&e.functions[funcIndex]))
 }
 
+// ReferencedFunctionIndex implements the same method as documented on wasm.ModuleEngine.
+func (e *moduleEngine) ReferencedFunctionIndex(ref wasm.Reference) (wasm.Index, bool) {
+	if len(e.functions) == 0 {
+		return 0, false
+	}
+	// FunctionInstanceReference points into e.functions, so the index follows from the offset.
+	begin, size := uintptr(unsafe.Pointer(&e.functions[0])), unsafe.Sizeof(e.functions[0])
+	if ref < begin || ref >= begin+size*uintptr(len(e.functions)) || (ref-begin)%size != 0 {
+		return 0, false
+	}
+	return wasm.Index((ref - begin) / size), true
+}
+
 // DoneInstantiation implements wasm.ModuleEngine.
 func (e *moduleEngine) DoneInstantiation() {}
 
@@ -714,7 +746,12 @@ func (ce *callEngine) Definition() api.FunctionDefinition {
 	return ce.initialFn.definition()
 }
 
-func (f *function) definition() api.FunctionDefinition {
+// FunctionInstanceReference implements wasm.FunctionReferencer.
+func (ce *callEngine) FunctionInstanceReference() wasm.Reference {
+	return uintptr(unsafe.Pointer(ce.initialFn))
+}
+
+func (f *function) definition() *wasm.FunctionDefinition {
 	compiled := f.parent
 	return compiled.parent.source.FunctionDefinition(compiled.index)
 }
@@ -751,6 +788,10 @@ func (ce *callEngine) call(ctx context.Context, params, results []uint64) (_ []u
 		}
 	}
 
+	if m.StrictFloat {
+		defer platform.ExitStrictFloat(platform.EnterStrictFloat())
+	}
+
 	// We ensure that this Call method never panics as
 	// this Call method is indirectly invoked by embedders via store.CallFunction,
 	// and we have to make sure that all the runtime errors, including the one happening inside
@@ -782,6 +823,7 @@ func (ce *callEngine) call(ctx context.Context, params, results []uint64) (_ []u
 		results = make([]uint64, ft.ResultNumInUint64)
 	}
 	copy(results, ce.stack)
+	wasm.ZeroExtend32BitResults(ft, results)
 	return results, nil
 }
 
@@ -854,14 +896,15 @@ func (ce *callEngine) deferredOnCall(ctx context.Context, m *wasm.ModuleInstance
 
 			// sourceInfo holds the source code information corresponding to the frame.
 			// It is not empty only when the DWARF is enabled.
+			wasmFrame := wasmdebug.Frame{ModuleName: def.ModuleName(), FunctionIndex: def.Index(), FunctionName: def.Name()}
 			var sources []string
 			if p := fn.parent; p.parent.executable.Bytes() != nil {
 				if fn.parent.sourceOffsetMap.irOperationSourceOffsetsInWasmBinary != nil {
-					offset := fn.getSourceOffsetInWasmBinary(pc)
-					sources = p.parent.source.DWARFLines.Line(offset)
+					wasmFrame.Offset = fn.getSourceOffsetInWasmBinary(pc)
+					sources = p.parent.source.DWARFLines.Annotate(&wasmFrame)
 				}
 			}
-			builder.AddFrame(def.DebugName(), def.ParamTypes(), def.ResultTypes(), sources)
+			builder.AddFrame(wasmFrame, def.ParamTypes(), def.ResultTypes(), sources)
 
 			if fn.parent.listener != nil {
 				functionListeners = append(functionListeners, functionListenerInvocation{
@@ -1047,13 +1090,7 @@ entry:
 			}
 			stack := ce.stack[base : base+stackLen]
 
-			fn := calleeHostFunction.parent.goFunc
-			switch fn := fn.(type) {
-			case api.GoModuleFunction:
-				fn.Call(ctx, ce.callerModuleInstance, stack)
-			case api.GoFunction:
-				fn.Call(ctx, stack)
-			}
+			wasm.CallHostFunction(ctx, ce.callerModuleInstance, calleeHostFunction.definition(), calleeHostFunction.parent.goFunc, stack, ce.stackRemaining())
 
 			codeAddr, modAddr = ce.returnAddress, ce.moduleInstance
 			goto entry
@@ -1100,6 +1137,16 @@ entry:
 // TODO: allows to configure this via context?
 var callStackCeiling = uint64(5000000) // in uint64 (8 bytes) == 40000000 bytes in total == 40mb.
 
+// stackRemaining returns approximately how many bytes the stack can grow from the base pointer of the current frame,
+// before it exceeds callStackCeiling.
+func (ce *callEngine) stackRemaining() int {
+	used := ce.stackBasePointerInBytes >> 3
+	if used >= callStackCeiling {
+		return 0
+	}
+	return int(callStackCeiling-used) << 3
+}
+
 func (ce *callEngine) builtinFunctionGrowStack(stackPointerCeil uint64) {
 	oldLen := uint64(len(ce.stack))
 	if callStackCeiling < oldLen {
@@ -1557,6 +1604,10 @@ func compileWasmFunction(buf asm.Buffer, cmp compiler, ir *wazeroir.CompilationR
 			err = cmp.compileV128Narrow(op)
 		case wazeroir.OperationKindV128ITruncSatFromF:
 			err = cmp.compileV128ITruncSatFromF(op)
+		case wazeroir.OperationKindAtomicCheckAlignment:
+			err = cmp.compileAtomicCheckAlignment(op)
+		case wazeroir.OperationKindNarrowAddress:
+			err = cmp.compileNarrowAddress()
 		case wazeroir.OperationKindBuiltinFunctionCheckExitCode:
 			err = cmp.compileBuiltinFunctionCheckExitCode()
 		default:
@@ -268,6 +268,43 @@ func (c *amd64Compiler) label(label wazeroir.Label) *amd64LabelInfo {
 	return &frames[frameID]
 }
 
+// compileAtomicCheckAlignment implements compiler.compileAtomicCheckAlignment for the amd64 architecture.
+func (c *amd64Compiler) compileAtomicCheckAlignment(o *wazeroir.UnionOperation) error {
+	addr := c.locationStack.pop()
+	if err := c.compileEnsureOnRegister(addr); err != nil {
+		return err
+	}
+
+	// Only the low bits are tested, so the upper 32 bits of the register don't matter.
+	c.assembler.CompileConstToRegister(amd64.TESTQ, int64(o.U1), addr.register)
+	// Skipped if none of the bits of the mask are set.
+	c.compileMaybeExitFromNativeCode(amd64.JEQ, nativeCallStatusUnalignedAtomic)
+
+	c.locationStack.markRegisterUnused(addr.register)
+	return nil
+}
+
+// compileNarrowAddress implements compiler.compileNarrowAddress for the amd64 architecture.
+func (c *amd64Compiler) compileNarrowAddress() error {
+	addr := c.locationStack.peek() // Note this is peek!
+	if err := c.compileEnsureOnRegister(addr); err != nil {
+		return err
+	}
+
+	tmp, err := c.allocateRegister(registerTypeGeneralPurpose)
+	if err != nil {
+		return err
+	}
+	c.assembler.CompileRegisterToRegister(amd64.MOVQ, addr.register, tmp)
+	c.assembler.CompileConstToRegister(amd64.SHRQ, 32, tmp)
+	c.assembler.CompileRegisterToRegister(amd64.TESTQ, tmp, tmp)
+	// Skipped if the upper 32 bits are zero, which means the register already holds the i32 address.
+	c.compileMaybeExitFromNativeCode(amd64.JEQ, nativeCallStatusCodeMemoryOutOfBounds)
+
+	addr.valueType = runtimeValueTypeI32
+	return nil
+}
+
 // compileBuiltinFunctionCheckExitCode implements compiler.compileBuiltinFunctionCheckExitCode for the amd64 architecture.
 func (c *amd64Compiler) compileBuiltinFunctionCheckExitCode() error {
 	if err := c.compileCallBuiltinFunction(builtinFunctionIndexCheckExitCode); err != nil {
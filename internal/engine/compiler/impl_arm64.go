@@ -504,6 +504,45 @@ func (c *arm64Compiler) setLocationStack(newStack *runtimeValueLocationStack) {
 	c.locationStack = newStack
 }
 
+// compileAtomicCheckAlignment implements compiler.compileAtomicCheckAlignment for the arm64 architecture.
+func (c *arm64Compiler) compileAtomicCheckAlignment(o *wazeroir.UnionOperation) error {
+	addr, err := c.popValueOnRegister()
+	if err != nil {
+		return err
+	}
+
+	// The address is popped, so its register can hold the masked bits.
+	c.assembler.CompileConstToRegister(arm64.ANDIMM32, int64(o.U1), addr.register)
+	c.assembler.CompileTwoRegistersToNone(arm64.CMPW, arm64.RegRZR, addr.register)
+	// Skipped if none of the bits of the mask are set.
+	c.compileMaybeExitFromNativeCode(arm64.BCONDEQ, nativeCallStatusUnalignedAtomic)
+
+	c.markRegisterUnused(addr.register)
+	return nil
+}
+
+// compileNarrowAddress implements compiler.compileNarrowAddress for the arm64 architecture.
+func (c *arm64Compiler) compileNarrowAddress() error {
+	addr, err := c.popValueOnRegister()
+	if err != nil {
+		return err
+	}
+
+	tmp, err := c.allocateRegister(registerTypeGeneralPurpose)
+	if err != nil {
+		return err
+	}
+	// "tmp = addr >> 32"
+	c.assembler.CompileRegisterToRegister(arm64.MOVD, addr.register, tmp)
+	c.assembler.CompileConstToRegister(arm64.LSR, 32, tmp)
+	c.assembler.CompileTwoRegistersToNone(arm64.CMP, arm64.RegRZR, tmp)
+	// Skipped if the upper 32 bits are zero, which means the register already holds the i32 address.
+	c.compileMaybeExitFromNativeCode(arm64.BCONDEQ, nativeCallStatusCodeMemoryOutOfBounds)
+
+	c.pushRuntimeValueLocationOnRegister(addr.register, runtimeValueTypeI32)
+	return nil
+}
+
 // compileBuiltinFunctionCheckExitCode implements compiler.compileBuiltinFunctionCheckExitCode for the arm64 architecture.
 func (c *arm64Compiler) compileBuiltinFunctionCheckExitCode() error {
 	if err := c.compileCallGoFunction(nativeCallStatusCodeCallBuiltInFunction, builtinFunctionIndexCheckExitCode); err != nil {
@@ -3598,6 +3637,7 @@ func (c *arm64Compiler) compileFillImpl(isTable bool, tableIndex uint32) error {
 
 	var str asm.Instruction
 	var movSize int64
+	var skipGroupsJump asm.Node
 	if isTable {
 		str = arm64.STRD
 		movSize = 8
@@ -3619,20 +3659,49 @@ func (c *arm64Compiler) compileFillImpl(isTable bool, tableIndex uint32) error {
 		// copySize = copySize << pointerSizeLog2 as each element has 8 bytes and we copy one by one.
 		c.assembler.CompileConstToRegister(arm64.LSL, pointerSizeLog2, fillSize.register)
 	} else {
-		str = arm64.STRB
-		movSize = 1
+		str = arm64.STRD
+		movSize = 8
 
 		// destinationOffset += memory buffer's absolute address.
 		c.assembler.CompileRegisterToRegister(arm64.ADD, arm64ReservedRegisterForMemory, destinationOffset.register)
+
+		// Fill the last size%8 bytes one by one, so that the rest can be filled in groups of 8 bytes.
+		beginTailLoop := c.assembler.CompileStandAlone(arm64.NOP)
+		c.assembler.CompileRegisterToRegister(arm64.MOVD, fillSize.register, arm64ReservedRegisterForTemporary)
+		c.assembler.CompileConstToRegister(arm64.ANDIMM64, 7, arm64ReservedRegisterForTemporary)
+		c.assembler.CompileTwoRegistersToNone(arm64.CMP, arm64.RegRZR, arm64ReservedRegisterForTemporary)
+		breakTailLoop := c.assembler.CompileJump(arm64.BCONDEQ)
+
+		// size -= 1
+		c.assembler.CompileConstToRegister(arm64.SUBS, 1, fillSize.register)
+		// [destinationOffset + (size.register)] = value.
+		c.assembler.CompileRegisterToMemoryWithRegisterOffset(arm64.STRB,
+			value.register,
+			destinationOffset.register, fillSize.register,
+		)
+		c.assembler.CompileJump(arm64.B).AssignJumpTarget(beginTailLoop)
+
+		// If nothing is left, we can skip the loop below.
+		c.assembler.SetJumpTargetOnNext(breakTailLoop)
+		c.assembler.CompileTwoRegistersToNone(arm64.CMP, arm64.RegRZR, fillSize.register)
+		skipGroupsJump = c.assembler.CompileJump(arm64.BCONDEQ)
+
+		// Otherwise, repeat the value in each byte of the register: value = (value & 0xff) * 0x0101010101010101.
+		// The zero register already is, and can't be written.
+		if !isZeroRegister(value.register) {
+			c.assembler.CompileConstToRegister(arm64.ANDIMM64, 0xff, value.register)
+			c.assembler.CompileConstToRegister(arm64.MOVD, 0x0101010101010101, arm64ReservedRegisterForTemporary)
+			c.assembler.CompileRegisterToRegister(arm64.MUL, arm64ReservedRegisterForTemporary, value.register)
+		}
 	}
 
-	// Naively implement the copy with "for loop" by copying byte one by one.
+	// Implement the copy with "for loop" by copying movSize bytes at once.
 	beginCopyLoop := c.assembler.CompileStandAlone(arm64.NOP)
 
-	// size -= 1
+	// size -= movSize
 	c.assembler.CompileConstToRegister(arm64.SUBS, movSize, fillSize.register)
 
-	// [destinationOffset + (size.register)] = arm64ReservedRegisterForTemporary.
+	// [destinationOffset + (size.register)] = value.
 	c.assembler.CompileRegisterToMemoryWithRegisterOffset(str,
 		value.register,
 		destinationOffset.register, fillSize.register,
@@ -3646,6 +3715,9 @@ func (c *arm64Compiler) compileFillImpl(isTable bool, tableIndex uint32) error {
 	c.markRegisterUnused(fillSize.register, value.register, destinationOffset.register)
 
 	c.assembler.SetJumpTargetOnNext(skipCopyJump)
+	if skipGroupsJump != nil {
+		c.assembler.SetJumpTargetOnNext(skipGroupsJump)
+	}
 	return nil
 }
 
@@ -15,6 +15,7 @@ import (
 	"github.com/tetratelabs/wazero/internal/filecache"
 	"github.com/tetratelabs/wazero/internal/internalapi"
 	"github.com/tetratelabs/wazero/internal/moremath"
+	"github.com/tetratelabs/wazero/internal/platform"
 	"github.com/tetratelabs/wazero/internal/wasm"
 	"github.com/tetratelabs/wazero/internal/wasmdebug"
 	"github.com/tetratelabs/wazero/internal/wasmruntime"
@@ -43,6 +44,9 @@ func NewEngine(_ context.Context, enabledFeatures api.CoreFeatures, _ filecache.
 	}
 }
 
+// SanitizesMemory implements wasm.MemorySanitizer.
+func (e *engine) SanitizesMemory() {}
+
 // Close implements the same method as documented on wasm.Engine.
 func (e *engine) Close() (err error) {
 	return
@@ -106,6 +110,13 @@ type callEngine struct {
 
 	// stackiterator for Listeners to walk frames and stack.
 	stackIterator stackIterator
+
+	// maxCallDepth is the maximum nesting of calls into Wasm functions, or zero if unlimited.
+	maxCallDepth int
+	// callDepth is the current nesting of calls into Wasm functions.
+	callDepth int
+	// fuelLimited is true if the module of f consumes fuel on each function entry and loop header.
+	fuelLimited bool
 }
 
 func (e *moduleEngine) newCallEngine(compiled *function) *callEngine {
@@ -299,8 +310,13 @@ func (e *engine) CompileModule(_ context.Context, module *wasm.Module, listeners
 		return nil
 	}
 
+	if module.UsesExceptionHandling {
+		return errors.New("exception handling is not supported by the interpreter")
+	}
+
 	funcs := make([]compiledFunction, len(module.FunctionSection))
-	irCompiler, err := wazeroir.NewCompiler(e.enabledFeatures, callFrameStackSize, module, ensureTermination)
+	// The exit code is always checked at loop headers, as that's also where fuel is consumed.
+	irCompiler, err := wazeroir.NewCompiler(e.enabledFeatures, callFrameStackSize, module, true)
 	if err != nil {
 		return err
 	}
@@ -434,6 +450,18 @@ func (e *moduleEngine) ResolveImportedFunction(index, indexInImportedModule wasm
 	e.functions[index] = imported.functions[indexInImportedModule]
 }
 
+// ResolveForeignFunction implements wasm.ForeignFunctionResolver.
+func (e *moduleEngine) ResolveForeignFunction(index wasm.Index, goFunc interface{}, importedModule *wasm.ModuleInstance, indexInImportedModule wasm.Index) {
+	source := importedModule.Source
+	funcType := source.FunctionDefinition(indexInImportedModule).Functype
+	e.functions[index] = function{
+		moduleInstance: importedModule,
+		typeID:         importedModule.GetFunctionTypeID(funcType),
+		funcType:       funcType,
+		parent:         &compiledFunction{source: source, hostFn: goFunc, index: indexInImportedModule},
+	}
+}
+
 // ResolveImportedMemory implements wasm.ModuleEngine.
 func (e *moduleEngine) ResolveImportedMemory(wasm.ModuleEngine) {}
 
@@ -445,6 +473,19 @@ func (e *moduleEngine) FunctionInstanceReference(funcIndex wasm.Index) wasm.Refe
 	return uintptr(unsafe.Pointer(&e.functions[funcIndex]))
 }
 
+// ReferencedFunctionIndex implements the same method as documented on wasm.ModuleEngine.
+func (e *moduleEngine) ReferencedFunctionIndex(ref wasm.Reference) (wasm.Index, bool) {
+	if len(e.functions) == 0 {
+		return 0, false
+	}
+	// FunctionInstanceReference points into e.functions, so the index follows from the offset.
+	begin, size := uintptr(unsafe.Pointer(&e.functions[0])), unsafe.Sizeof(e.functions[0])
+	if ref < begin || ref >= begin+size*uintptr(len(e.functions)) || (ref-begin)%size != 0 {
+		return 0, false
+	}
+	return wasm.Index((ref - begin) / size), true
+}
+
 // NewFunction implements the same method as documented on wasm.ModuleEngine.
 func (e *moduleEngine) NewFunction(index wasm.Index) (ce api.Function) {
 	// Note: The input parameters are pre-validated, so a compiled function is only absent on close. Updates to
@@ -475,7 +516,12 @@ func (ce *callEngine) Definition() api.FunctionDefinition {
 	return ce.f.definition()
 }
 
-func (f *function) definition() api.FunctionDefinition {
+// FunctionInstanceReference implements wasm.FunctionReferencer.
+func (ce *callEngine) FunctionInstanceReference() wasm.Reference {
+	return uintptr(unsafe.Pointer(ce.f))
+}
+
+func (f *function) definition() *wasm.FunctionDefinition {
 	compiled := f.parent
 	return compiled.source.FunctionDefinition(compiled.index)
 }
@@ -512,6 +558,10 @@ func (ce *callEngine) call(ctx context.Context, params, results []uint64) (_ []u
 		}
 	}
 
+	if m.StrictFloat {
+		defer platform.ExitStrictFloat(platform.EnterStrictFloat())
+	}
+
 	defer func() {
 		// If the module closed during the call, and the call didn't err for another reason, set an ExitError.
 		if err == nil {
@@ -530,6 +580,8 @@ func (ce *callEngine) call(ctx context.Context, params, results []uint64) (_ []u
 		done := m.CloseModuleOnCanceledOrTimeout(ctx)
 		defer done()
 	}
+	ce.maxCallDepth, ce.callDepth = int(m.MaxCallDepth()), 0
+	_, ce.fuelLimited = m.Fuel()
 
 	ce.callFunction(ctx, m, ce.f)
 
@@ -563,11 +615,13 @@ func (ce *callEngine) recoverOnCall(ctx context.Context, m *wasm.ModuleInstance,
 		frame := ce.popFrame()
 		f := frame.f
 		def := f.definition()
+		wasmFrame := wasmdebug.Frame{ModuleName: def.ModuleName(), FunctionIndex: def.Index(), FunctionName: def.Name()}
 		var sources []string
 		if parent := frame.f.parent; parent.body != nil && len(parent.offsetsInWasmBinary) > 0 {
-			sources = parent.source.DWARFLines.Line(parent.offsetsInWasmBinary[frame.pc])
+			wasmFrame.Offset = parent.offsetsInWasmBinary[frame.pc]
+			sources = parent.source.DWARFLines.Annotate(&wasmFrame)
 		}
-		builder.AddFrame(def.DebugName(), def.ParamTypes(), def.ResultTypes(), sources)
+		builder.AddFrame(wasmFrame, def.ParamTypes(), def.ResultTypes(), sources)
 		if f.parent.listener != nil {
 			functionListeners = append(functionListeners, functionListenerInvocation{
 				FunctionListener: f.parent.listener,
@@ -608,13 +662,7 @@ func (ce *callEngine) callGoFunc(ctx context.Context, m *wasm.ModuleInstance, f
 	frame := &callFrame{f: f, base: len(ce.stack)}
 	ce.pushFrame(frame)
 
-	fn := f.parent.hostFn
-	switch fn := fn.(type) {
-	case api.GoModuleFunction:
-		fn.Call(ctx, m, stack)
-	case api.GoFunction:
-		fn.Call(ctx, stack)
-	}
+	wasm.CallHostFunction(ctx, m, f.definition(), f.parent.hostFn, stack, -1) // the call depth is limited instead of the stack.
 
 	ce.popFrame()
 	if lsn != nil {
@@ -625,31 +673,57 @@ func (ce *callEngine) callGoFunc(ctx context.Context, m *wasm.ModuleInstance, f
 }
 
 func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance, f *function) {
+	if ce.maxCallDepth != 0 && ce.callDepth >= ce.maxCallDepth {
+		panic(wasmruntime.ErrRuntimeCallStackExhausted)
+	}
+	ce.callDepth++
+	if ce.fuelLimited {
+		if err := m.FailIfClosedOrOutOfFuel(1); err != nil {
+			panic(err)
+		}
+	}
+entry:
 	frame := &callFrame{f: f, base: len(ce.stack)}
 	moduleInst := f.moduleInstance
 	functions := moduleInst.Engine.(*moduleEngine).functions
-	memoryInst := moduleInst.MemoryInstance
 	globals := moduleInst.Globals
 	tables := moduleInst.Tables
 	typeIDs := moduleInst.TypeIDs
 	dataInstances := moduleInst.DataInstances
 	elementInstances := moduleInst.ElementInstances
+	sanitize := moduleInst.MemorySanitized()
 	ce.pushFrame(frame)
 	body := frame.f.parent.body
 	bodyLen := uint64(len(body))
 	for frame.pc < bodyLen {
 		op := &body[frame.pc]
+		if sanitize {
+			ce.sanitizeMemoryAccess(moduleInst, op)
+		}
 		// TODO: add description of each operation/case
 		// on, for example, how many args are used,
 		// how the stack is modified, etc.
 		switch op.Kind {
 		case wazeroir.OperationKindBuiltinFunctionCheckExitCode:
-			if err := m.FailIfClosed(); err != nil {
-				panic(err)
+			if ce.fuelLimited || frame.f.parent.ensureTermination {
+				if err := m.FailIfClosedOrOutOfFuel(1); err != nil {
+					panic(err)
+				}
 			}
 			frame.pc++
 		case wazeroir.OperationKindUnreachable:
 			panic(wasmruntime.ErrRuntimeUnreachable)
+		case wazeroir.OperationKindAtomicCheckAlignment:
+			if uint32(ce.popValue())&uint32(op.U1) != 0 {
+				panic(wasmruntime.ErrRuntimeUnalignedAtomic)
+			}
+			frame.pc++
+		case wazeroir.OperationKindNarrowAddress:
+			// The value is kept as is, as an i32 is stored as the zero-extended uint64.
+			if ce.stack[len(ce.stack)-1]>>32 != 0 {
+				panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
+			}
+			frame.pc++
 		case wazeroir.OperationKindBr:
 			frame.pc = op.U1
 		case wazeroir.OperationKindBrIf:
@@ -669,7 +743,14 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 			ce.drop(op.Us[v+1])
 			frame.pc = op.Us[v]
 		case wazeroir.OperationKindCall:
-			ce.callFunction(ctx, f.moduleInstance, &functions[op.U1])
+			tf := &functions[op.U1]
+			if op.B3 && tf.parent.hostFn == nil && tf.parent.listener == nil {
+				// return_call: replace the current frame with the callee's.
+				ce.popFrame()
+				m, f = f.moduleInstance, tf
+				goto entry
+			}
+			ce.callFunction(ctx, f.moduleInstance, tf)
 			frame.pc++
 		case wazeroir.OperationKindCallIndirect:
 			offset := ce.popValue()
@@ -687,6 +768,12 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 				panic(wasmruntime.ErrRuntimeIndirectCallTypeMismatch)
 			}
 
+			if op.B3 && tf.parent.hostFn == nil && tf.parent.listener == nil {
+				// return_call_indirect: replace the current frame with the callee's.
+				ce.popFrame()
+				m, f = f.moduleInstance, tf
+				goto entry
+			}
 			ce.callFunction(ctx, f.moduleInstance, tf)
 			frame.pc++
 		case wazeroir.OperationKindDrop:
@@ -742,6 +829,7 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 			g.Val = ce.popValue()
 			frame.pc++
 		case wazeroir.OperationKindLoad:
+			memoryInst := moduleInst.MemoryAt(wasm.Index(op.U3))
 			offset := ce.popMemoryOffset(op)
 			switch wazeroir.UnsignedType(op.B1) {
 			case wazeroir.UnsignedTypeI32, wazeroir.UnsignedTypeF32:
@@ -759,6 +847,7 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 			}
 			frame.pc++
 		case wazeroir.OperationKindLoad8:
+			memoryInst := moduleInst.MemoryAt(wasm.Index(op.U3))
 			val, ok := memoryInst.ReadByte(ce.popMemoryOffset(op))
 			if !ok {
 				panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
@@ -774,6 +863,7 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 			}
 			frame.pc++
 		case wazeroir.OperationKindLoad16:
+			memoryInst := moduleInst.MemoryAt(wasm.Index(op.U3))
 
 			val, ok := memoryInst.ReadUint16Le(ce.popMemoryOffset(op))
 			if !ok {
@@ -790,6 +880,7 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 			}
 			frame.pc++
 		case wazeroir.OperationKindLoad32:
+			memoryInst := moduleInst.MemoryAt(wasm.Index(op.U3))
 			val, ok := memoryInst.ReadUint32Le(ce.popMemoryOffset(op))
 			if !ok {
 				panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
@@ -802,6 +893,7 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 			}
 			frame.pc++
 		case wazeroir.OperationKindStore:
+			memoryInst := moduleInst.MemoryAt(wasm.Index(op.U3))
 			val := ce.popValue()
 			offset := ce.popMemoryOffset(op)
 			switch wazeroir.UnsignedType(op.B1) {
@@ -816,6 +908,7 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 			}
 			frame.pc++
 		case wazeroir.OperationKindStore8:
+			memoryInst := moduleInst.MemoryAt(wasm.Index(op.U3))
 			val := byte(ce.popValue())
 			offset := ce.popMemoryOffset(op)
 			if !memoryInst.WriteByte(offset, val) {
@@ -823,6 +916,7 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 			}
 			frame.pc++
 		case wazeroir.OperationKindStore16:
+			memoryInst := moduleInst.MemoryAt(wasm.Index(op.U3))
 			val := uint16(ce.popValue())
 			offset := ce.popMemoryOffset(op)
 			if !memoryInst.WriteUint16Le(offset, val) {
@@ -830,6 +924,7 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 			}
 			frame.pc++
 		case wazeroir.OperationKindStore32:
+			memoryInst := moduleInst.MemoryAt(wasm.Index(op.U3))
 			val := uint32(ce.popValue())
 			offset := ce.popMemoryOffset(op)
 			if !memoryInst.WriteUint32Le(offset, val) {
@@ -837,9 +932,11 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 			}
 			frame.pc++
 		case wazeroir.OperationKindMemorySize:
+			memoryInst := moduleInst.MemoryAt(wasm.Index(op.U1))
 			ce.pushValue(uint64(memoryInst.PageSize()))
 			frame.pc++
 		case wazeroir.OperationKindMemoryGrow:
+			memoryInst := moduleInst.MemoryAt(wasm.Index(op.U1))
 			n := ce.popValue()
 			if res, ok := memoryInst.Grow(uint32(n)); !ok {
 				ce.pushValue(uint64(0xffffffff)) // = -1 in signed 32-bit integer.
@@ -1605,6 +1702,7 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 			ce.pushValue(uint64(v))
 			frame.pc++
 		case wazeroir.OperationKindMemoryInit:
+			memoryInst := moduleInst.MemoryAt(wasm.Index(op.U2))
 			dataInstance := dataInstances[op.U1]
 			copySize := ce.popValue()
 			inDataOffset := ce.popValue()
@@ -1620,18 +1718,19 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 			dataInstances[op.U1] = nil
 			frame.pc++
 		case wazeroir.OperationKindMemoryCopy:
-			memLen := uint64(len(memoryInst.Buffer))
+			dst, src := moduleInst.MemoryAt(wasm.Index(op.U1)), moduleInst.MemoryAt(wasm.Index(op.U2))
 			copySize := ce.popValue()
 			sourceOffset := ce.popValue()
 			destinationOffset := ce.popValue()
-			if sourceOffset+copySize > memLen || destinationOffset+copySize > memLen {
+			if sourceOffset+copySize > uint64(len(src.Buffer)) || destinationOffset+copySize > uint64(len(dst.Buffer)) {
 				panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
 			} else if copySize != 0 {
-				copy(memoryInst.Buffer[destinationOffset:],
-					memoryInst.Buffer[sourceOffset:sourceOffset+copySize])
+				copy(dst.Buffer[destinationOffset:],
+					src.Buffer[sourceOffset:sourceOffset+copySize])
 			}
 			frame.pc++
 		case wazeroir.OperationKindMemoryFill:
+			memoryInst := moduleInst.MemoryAt(wasm.Index(op.U1))
 			fillSize := ce.popValue()
 			value := byte(ce.popValue())
 			offset := ce.popValue()
@@ -1819,6 +1918,7 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 			}
 			frame.pc++
 		case wazeroir.OperationKindV128Load:
+			memoryInst := moduleInst.MemoryAt(wasm.Index(op.U3))
 			offset := ce.popMemoryOffset(op)
 			switch op.B1 {
 			case wazeroir.V128LoadType128:
@@ -1941,6 +2041,7 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 			}
 			frame.pc++
 		case wazeroir.OperationKindV128LoadLane:
+			memoryInst := moduleInst.MemoryAt(wasm.Index(op.U3))
 			hi, lo := ce.popValue(), ce.popValue()
 			offset := ce.popMemoryOffset(op)
 			switch op.B1 {
@@ -1995,6 +2096,7 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 			ce.pushValue(hi)
 			frame.pc++
 		case wazeroir.OperationKindV128Store:
+			memoryInst := moduleInst.MemoryAt(wasm.Index(op.U3))
 			hi, lo := ce.popValue(), ce.popValue()
 			offset := ce.popMemoryOffset(op)
 			if ok := memoryInst.WriteUint64Le(offset, lo); !ok {
@@ -2005,6 +2107,7 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 			}
 			frame.pc++
 		case wazeroir.OperationKindV128StoreLane:
+			memoryInst := moduleInst.MemoryAt(wasm.Index(op.U3))
 			hi, lo := ce.popValue(), ce.popValue()
 			offset := ce.popMemoryOffset(op)
 			var ok bool
@@ -3894,6 +3997,7 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 		}
 	}
 	ce.popFrame()
+	ce.callDepth--
 }
 
 func WasmCompatMax32bits(v1, v2 uint32) uint64 {
@@ -4113,6 +4217,71 @@ func (ce *callEngine) popMemoryOffset(op *wazeroir.UnionOperation) uint32 {
 	return uint32(offset)
 }
 
+// sanitizeMemoryAccess panics with wasmruntime.ErrRuntimeUninitializedMemoryRead if op loads bytes of a Sanitized
+// memory which aren't initialized, and updates which are for the bulk memory operations. Stores mark the bytes via
+// the write methods of wasm.MemoryInstance. An access out of bounds is left to op to trap.
+func (ce *callEngine) sanitizeMemoryAccess(moduleInst *wasm.ModuleInstance, op *wazeroir.UnionOperation) {
+	// peek returns the value at the given depth from the top of the stack.
+	peek := func(depth int) uint64 { return ce.stack[len(ce.stack)-1-depth] }
+	var byteCount, addrDepth uint64
+	switch op.Kind {
+	case wazeroir.OperationKindLoad:
+		switch wazeroir.UnsignedType(op.B1) {
+		case wazeroir.UnsignedTypeI32, wazeroir.UnsignedTypeF32:
+			byteCount = 4
+		default:
+			byteCount = 8
+		}
+	case wazeroir.OperationKindLoad8:
+		byteCount = 1
+	case wazeroir.OperationKindLoad16:
+		byteCount = 2
+	case wazeroir.OperationKindLoad32:
+		byteCount = 4
+	case wazeroir.OperationKindV128Load:
+		switch op.B1 {
+		case wazeroir.V128LoadType128:
+			byteCount = 16
+		case wazeroir.V128LoadType8Splat:
+			byteCount = 1
+		case wazeroir.V128LoadType16Splat:
+			byteCount = 2
+		case wazeroir.V128LoadType32Splat, wazeroir.V128LoadType32zero:
+			byteCount = 4
+		default:
+			byteCount = 8
+		}
+	case wazeroir.OperationKindV128LoadLane:
+		byteCount, addrDepth = uint64(op.B1)/8, 2 // The address is below the vector.
+	case wazeroir.OperationKindMemoryInit:
+		mem, n, src, dst := moduleInst.MemoryAt(wasm.Index(op.U2)), peek(0), peek(1), peek(2)
+		if src+n <= uint64(len(moduleInst.DataInstances[op.U1])) && dst+n <= uint64(len(mem.Buffer)) {
+			mem.MarkInitialized(dst, n)
+		}
+		return
+	case wazeroir.OperationKindMemoryFill:
+		mem, n, dst := moduleInst.MemoryAt(wasm.Index(op.U1)), peek(0), peek(2)
+		if dst+n <= uint64(len(mem.Buffer)) {
+			mem.MarkInitialized(dst, n)
+		}
+		return
+	case wazeroir.OperationKindMemoryCopy:
+		dstMem, srcMem := moduleInst.MemoryAt(wasm.Index(op.U1)), moduleInst.MemoryAt(wasm.Index(op.U2))
+		n, src, dst := peek(0), peek(1), peek(2)
+		if src+n <= uint64(len(srcMem.Buffer)) && dst+n <= uint64(len(dstMem.Buffer)) {
+			dstMem.CopyInitialized(dst, srcMem, src, n)
+		}
+		return
+	default:
+		return
+	}
+	mem := moduleInst.MemoryAt(wasm.Index(op.U3))
+	addr := op.U2 + peek(int(addrDepth))
+	if addr+byteCount <= uint64(len(mem.Buffer)) && !mem.IsInitialized(addr, byteCount) {
+		panic(wasmruntime.ErrRuntimeUninitializedMemoryRead)
+	}
+}
+
 func (ce *callEngine) callGoFuncWithStack(ctx context.Context, m *wasm.ModuleInstance, f *function) {
 	typ := f.funcType
 	paramLen := typ.ParamNumInUint64
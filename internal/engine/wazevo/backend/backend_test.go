@@ -40,6 +40,7 @@ func TestE2E(t *testing.T) {
 		name                                   string
 		m                                      *wasm.Module
 		targetIndex                            uint32
+		ensureTermination                      bool
 		afterLoweringARM64, afterFinalizeARM64 string
 		// TODO: amd64.
 	}
@@ -311,6 +312,51 @@ L1 (SSA Block: blk0):
 	str xzr, [sp, #-0x10]!
 L2 (SSA Block: blk1):
 	b #0x0 (L2)
+`,
+		},
+		{
+			name: "loop_br / ensure termination", m: testcases.LoopBr.Module,
+			ensureTermination: true,
+			afterLoweringARM64: `
+L1 (SSA Block: blk0):
+	mov x128?, x0
+	mov x129?, x1
+L2 (SSA Block: blk1):
+	ldr x130?, [x129?, #0x18]
+	ldr w131?, [x130?]
+	cbnz w131?, L3
+L4 (SSA Block: blk5):
+L5 (SSA Block: blk4):
+	b L2
+L3 (SSA Block: blk3):
+	ldr x132?, [x128?, #0x58]
+	mov x0, x128?
+	bl x132?
+	b L5
+`,
+			afterFinalizeARM64: `
+L1 (SSA Block: blk0):
+	stp x30, xzr, [sp, #-0x10]!
+	sub sp, sp, #0x10
+	orr x27, xzr, #0x10
+	str x27, [sp, #-0x10]!
+	mov x8, x0
+L2 (SSA Block: blk1):
+	ldr x9, [x1, #0x18]
+	ldr w9, [x9]
+	cbnz w9, #0x8 (L3)
+L4 (SSA Block: blk5):
+L5 (SSA Block: blk4):
+	b #-0xc (L2)
+L3 (SSA Block: blk3):
+	ldr x9, [x8, #0x58]
+	mov x0, x8
+	str x8, [sp, #0x10]
+	str x1, [sp, #0x18]
+	bl x9
+	ldr x1, [sp, #0x18]
+	ldr x8, [sp, #0x10]
+	b #-0x20 (L5)
 `,
 		},
 		{
@@ -668,6 +714,204 @@ L1 (SSA Block: blk0):
 	add sp, sp, #0x10
 	ldr x30, [sp], #0x10
 	ret
+`,
+		},
+		{
+			name: "return_call", m: testcases.ReturnCall.Module,
+			afterLoweringARM64: `
+L1 (SSA Block: blk0):
+	mov x128?, x0
+	mov x129?, x1
+	mov x130?, x2
+	mov x131?, x3
+	subs wzr, w130?, #0x0
+	b.eq L2
+L3 (SSA Block: blk2):
+L4 (SSA Block: blk3):
+	sub w135?, w130?, #0x1
+	add w136?, w131?, w130?
+	str x129?, [x128?, #0x8]
+	mov x0, x128?
+	mov x1, x129?
+	mov x2, x135?
+	mov x3, x136?
+	b f0
+L2 (SSA Block: blk1):
+	mov x0, x131?
+	ret
+`,
+			afterFinalizeARM64: `
+L1 (SSA Block: blk0):
+	stp x30, xzr, [sp, #-0x10]!
+	str xzr, [sp, #-0x10]!
+	mov x8, x2
+	subs wzr, w8, #0x0
+	b.eq #0x1c, (L2)
+L3 (SSA Block: blk2):
+L4 (SSA Block: blk3):
+	sub w2, w8, #0x1
+	add w3, w3, w8
+	str x1, [x0, #0x8]
+	add sp, sp, #0x10
+	ldr x30, [sp], #0x10
+	b f0
+L2 (SSA Block: blk1):
+	mov x0, x3
+	add sp, sp, #0x10
+	ldr x30, [sp], #0x10
+	ret
+`,
+		},
+		{
+			name: "return_call_indirect", m: testcases.ReturnCallIndirect.Module,
+			afterLoweringARM64: `
+L1 (SSA Block: blk0):
+	mov x128?, x0
+	mov x129?, x1
+	mov x130?, x2
+	ldr x131?, [x129?, #0x10]
+	ldr w132?, [x131?, #0x8]
+	subs wzr, w130?, w132?
+	b.lo L4
+	movz x155?, #0x7, lsl 0
+	str w155?, [x128?]
+	mov x156?, sp
+	str x156?, [x128?, #0x38]
+	adr x157?, #0x0
+	str x157?, [x128?, #0x30]
+	exit_sequence x128?
+L4:
+	ldr x134?, [x131?]
+	lsl w136?, w130?, 0x3
+	uxtw x153?, w136?
+	add x154?, x134?, x153?
+	ldr x138?, [x154?]
+	subs xzr, x138?, #0x0
+	b.ne L3
+	movz x150?, #0x8, lsl 0
+	str w150?, [x128?]
+	mov x151?, sp
+	str x151?, [x128?, #0x38]
+	adr x152?, #0x0
+	str x152?, [x128?, #0x30]
+	exit_sequence x128?
+L3:
+	ldr w141?, [x138?, #0x10]
+	ldr x142?, [x129?, #0x8]
+	ldr w143?, [x142?]
+	subs wzr, w141?, w143?
+	b.eq L2
+	movz x147?, #0x9, lsl 0
+	str w147?, [x128?]
+	mov x148?, sp
+	str x148?, [x128?, #0x38]
+	adr x149?, #0x0
+	str x149?, [x128?, #0x30]
+	exit_sequence x128?
+L2:
+	ldr x145?, [x138?]
+	ldr x146?, [x138?, #0x8]
+	str x129?, [x128?, #0x8]
+	mov x0, x128?
+	mov x1, x146?
+	mov x2, x130?
+	mov x16, x145?
+	br x16
+`,
+			afterFinalizeARM64: `
+L1 (SSA Block: blk0):
+	stp x30, xzr, [sp, #-0x10]!
+	str xzr, [sp, #-0x10]!
+	ldr x8, [x1, #0x10]
+	ldr w9, [x8, #0x8]
+	subs wzr, w2, w9
+	b.lo #0x34, (L4)
+	movz x9, #0x7, lsl 0
+	str w9, [x0]
+	mov x9, sp
+	str x9, [x0, #0x38]
+	adr x9, #0x0
+	str x9, [x0, #0x30]
+	exit_sequence x0
+L4:
+	ldr x8, [x8]
+	lsl w9, w2, 0x3
+	uxtw x9, w9
+	add x8, x8, x9
+	ldr x8, [x8]
+	subs xzr, x8, #0x0
+	b.ne #0x34, (L3)
+	movz x9, #0x8, lsl 0
+	str w9, [x0]
+	mov x9, sp
+	str x9, [x0, #0x38]
+	adr x9, #0x0
+	str x9, [x0, #0x30]
+	exit_sequence x0
+L3:
+	ldr w9, [x8, #0x10]
+	ldr x10, [x1, #0x8]
+	ldr w10, [x10]
+	subs wzr, w9, w10
+	b.eq #0x34, (L2)
+	movz x9, #0x9, lsl 0
+	str w9, [x0]
+	mov x9, sp
+	str x9, [x0, #0x38]
+	adr x9, #0x0
+	str x9, [x0, #0x30]
+	exit_sequence x0
+L2:
+	ldr x16, [x8]
+	ldr x8, [x8, #0x8]
+	str x1, [x0, #0x8]
+	mov x1, x8
+	add sp, sp, #0x10
+	ldr x30, [sp], #0x10
+	br x16
+`,
+		},
+		{
+			name: "return_call_many_params", m: testcases.ReturnCallManyParams.Module,
+			afterLoweringARM64: `
+L1 (SSA Block: blk0):
+	mov x128?, x0
+	mov x129?, x1
+	mov x130?, x2
+	str x129?, [x128?, #0x8]
+	mov x0, x128?
+	mov x1, x129?
+	mov x2, x130?
+	mov x3, x130?
+	mov x4, x130?
+	mov x5, x130?
+	mov x6, x130?
+	mov x7, x130?
+	str x130?, [sp, #-0x10]
+	str x130?, [sp, #-0x8]
+	bl f1
+	mov x131?, x0
+	mov x0, x131?
+	ret
+`,
+			afterFinalizeARM64: `
+L1 (SSA Block: blk0):
+	stp x30, xzr, [sp, #-0x10]!
+	str xzr, [sp, #-0x10]!
+	mov x8, x2
+	str x1, [x0, #0x8]
+	mov x2, x8
+	mov x3, x8
+	mov x4, x8
+	mov x5, x8
+	mov x6, x8
+	mov x7, x8
+	str x8, [sp, #-0x10]
+	str x8, [sp, #-0x8]
+	bl f1
+	add sp, sp, #0x10
+	ldr x30, [sp], #0x10
+	ret
 `,
 		},
 		{
@@ -2068,7 +2312,7 @@ L1 (SSA Block: blk0):
 		t.Run(tc.name, func(t *testing.T) {
 			ssab := ssa.NewBuilder()
 			offset := wazevoapi.NewModuleContextOffsetData(tc.m, false)
-			fc := frontend.NewFrontendCompiler(tc.m, ssab, &offset, false, false, false)
+			fc := frontend.NewFrontendCompiler(tc.m, ssab, &offset, tc.ensureTermination, false, false)
 			machine := newMachine()
 			machine.DisableStackCheck()
 			be := backend.NewCompiler(context.Background(), machine, ssab)
@@ -4,7 +4,9 @@ import (
 	"context"
 	"encoding/hex"
 	"fmt"
+	"time"
 
+	"github.com/tetratelabs/wazero/internal/compilation"
 	"github.com/tetratelabs/wazero/internal/engine/wazevo/backend/regalloc"
 	"github.com/tetratelabs/wazero/internal/engine/wazevo/ssa"
 	"github.com/tetratelabs/wazero/internal/engine/wazevo/wazevoapi"
@@ -49,7 +51,12 @@ type Compiler interface {
 	Lower()
 
 	// RegAlloc performs the register allocation after Lower is called.
-	RegAlloc()
+	// This returns wazevoapi.ErrDeadlineExceeded if the deadline set by SetDeadline passes during the allocation.
+	RegAlloc() error
+
+	// SetDeadline sets the time by which the register allocation must finish for the current function.
+	// The zero time means no deadline.
+	SetDeadline(deadline time.Time)
 
 	// Finalize performs the finalization of the compilation. This must be called after RegAlloc.
 	Finalize()
@@ -102,6 +109,14 @@ type Compiler interface {
 
 	// Emit4Bytes appends 4 bytes to the buffer. Used during the code emission.
 	Emit4Bytes(b uint32)
+
+	// ScratchBytes returns the size in bytes of the pooled scratch memory used by the machine and the register
+	// allocator for the current compilation.
+	ScratchBytes() int
+
+	// RegAllocInfo returns the summary of the register allocation of the current compilation.
+	// This is only for debugging purpose, and must be called after RegAlloc.
+	RegAllocInfo() *compilation.RegAllocInfo
 }
 
 // RelocationInfo represents the relocation information for a call instruction.
@@ -152,6 +167,19 @@ type SourceOffsetInfo struct {
 	ExecutableOffset int64
 }
 
+// DisassembledInstruction is an instruction returned by Machine.Disassemble.
+type DisassembledInstruction struct {
+	// Offset is the offset of the instruction from the beginning of the function's machine code.
+	Offset int64
+	// Size is the size in bytes of the instruction. This is larger than a single machine instruction when it is
+	// a pseudo instruction encoded as a sequence, e.g. the exit sequence.
+	Size int64
+	// Mnemonic is the name of the instruction, e.g. "add".
+	Mnemonic string
+	// Operands are the formatted operands of the instruction in order, e.g. ["x0", "x1", "#0x8"].
+	Operands []string
+}
+
 // Compile implements Compiler.Compile.
 func (c *compiler) Compile(ctx context.Context) ([]byte, []RelocationInfo, error) {
 	c.Lower()
@@ -161,7 +189,9 @@ func (c *compiler) Compile(ctx context.Context) ([]byte, []RelocationInfo, error
 	if wazevoapi.DeterministicCompilationVerifierEnabled {
 		wazevoapi.VerifyOrSetDeterministicCompilationContextValue(ctx, "After lowering to ISA specific IR", c.Format())
 	}
-	c.RegAlloc()
+	if err := c.RegAlloc(); err != nil {
+		return nil, nil, err
+	}
 	if wazevoapi.PrintRegisterAllocated {
 		fmt.Printf("[[[after regalloc for %s]]]%s\n", wazevoapi.GetCurrentFunctionName(ctx), c.Format())
 	}
@@ -183,9 +213,24 @@ func (c *compiler) Compile(ctx context.Context) ([]byte, []RelocationInfo, error
 }
 
 // RegAlloc implements Compiler.RegAlloc.
-func (c *compiler) RegAlloc() {
+func (c *compiler) RegAlloc() error {
 	regAllocFn := c.mach.Function()
-	c.regAlloc.DoAllocation(regAllocFn)
+	return c.regAlloc.DoAllocation(regAllocFn)
+}
+
+// SetDeadline implements Compiler.SetDeadline.
+func (c *compiler) SetDeadline(deadline time.Time) {
+	c.regAlloc.SetDeadline(deadline)
+}
+
+// ScratchBytes implements Compiler.ScratchBytes.
+func (c *compiler) ScratchBytes() int {
+	return c.mach.ScratchBytes() + c.regAlloc.ScratchBytes()
+}
+
+// RegAllocInfo implements Compiler.RegAllocInfo.
+func (c *compiler) RegAllocInfo() *compilation.RegAllocInfo {
+	return c.regAlloc.Info()
 }
 
 // Finalize implements Compiler.Finalize.
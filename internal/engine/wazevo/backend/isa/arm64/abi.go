@@ -222,45 +222,50 @@ func (a *abiImpl) CalleeGenVRegsToFunctionReturns(rets []ssa.Value) {
 				a.m.InsertLoadConstant(inst, reg)
 			}
 		}
-		if r.Kind == backend.ABIArgKindReg {
-			a.m.InsertMove(r.Reg, reg, ret.Type())
-		} else {
-			// TODO: we could use pair store if there's consecutive stores for the same type.
-			//
-			//            (high address)
-			//          +-----------------+
-			//          |     .......     |
-			//          |      ret Y      |
-			//          |     .......     |
-			//          |      ret 0      |    <-+
-			//          |      arg X      |      |
-			//          |     .......     |      |
-			//          |      arg 1      |      |
-			//          |      arg 0      |      |
-			//          |   ReturnAddress |      |
-			//          +-----------------+      |
-			//          |   ...........   |      |
-			//          |   spill slot M  |      |   retStackOffset: is unknown at this point of compilation.
-			//          |   ............  |      |
-			//          |   spill slot 2  |      |
-			//          |   spill slot 1  |      |
-			//          |   clobbered 0   |      |
-			//          |   clobbered 1   |      |
-			//          |   ...........   |      |
-			//          |   clobbered N   |      |
-			//   SP---> +-----------------+    <-+
-			//             (low address)
+		a.calleeGenVRegToFunctionReturn(r, reg)
+	}
+}
 
-			bits := r.Type.Bits()
+// calleeGenVRegToFunctionReturn moves the value of reg into the location of the return value r.
+func (a *abiImpl) calleeGenVRegToFunctionReturn(r *backend.ABIArg, reg regalloc.VReg) {
+	if r.Kind == backend.ABIArgKindReg {
+		a.m.InsertMove(r.Reg, reg, r.Type)
+	} else {
+		// TODO: we could use pair store if there's consecutive stores for the same type.
+		//
+		//            (high address)
+		//          +-----------------+
+		//          |     .......     |
+		//          |      ret Y      |
+		//          |     .......     |
+		//          |      ret 0      |    <-+
+		//          |      arg X      |      |
+		//          |     .......     |      |
+		//          |      arg 1      |      |
+		//          |      arg 0      |      |
+		//          |   ReturnAddress |      |
+		//          +-----------------+      |
+		//          |   ...........   |      |
+		//          |   spill slot M  |      |   retStackOffset: is unknown at this point of compilation.
+		//          |   ............  |      |
+		//          |   spill slot 2  |      |
+		//          |   spill slot 1  |      |
+		//          |   clobbered 0   |      |
+		//          |   clobbered 1   |      |
+		//          |   ...........   |      |
+		//          |   clobbered N   |      |
+		//   SP---> +-----------------+    <-+
+		//             (low address)
 
-			// At this point of compilation, we don't yet know how much space exist below the return address.
-			// So we instruct the address mode to add the `retStackOffset` to the offset at the later phase of compilation.
-			amode := addressMode{imm: r.Offset, rn: spVReg, kind: addressModeKindResultStackSpace}
-			store := a.m.allocateInstr()
-			store.asStore(operandNR(reg), amode, bits)
-			a.m.insert(store)
-			a.m.unresolvedAddressModes = append(a.m.unresolvedAddressModes, store)
-		}
+		bits := r.Type.Bits()
+
+		// At this point of compilation, we don't yet know how much space exist below the return address.
+		// So we instruct the address mode to add the `retStackOffset` to the offset at the later phase of compilation.
+		amode := addressMode{imm: r.Offset, rn: spVReg, kind: addressModeKindResultStackSpace}
+		store := a.m.allocateInstr()
+		store.asStore(operandNR(reg), amode, bits)
+		a.m.insert(store)
+		a.m.unresolvedAddressModes = append(a.m.unresolvedAddressModes, store)
 	}
 }
 
@@ -348,19 +353,28 @@ func (a *abiImpl) alignedArgResultStackSlotSize() int64 {
 }
 
 func (m *machine) lowerCall(si *ssa.Instruction) {
-	isDirectCall := si.Opcode() == ssa.OpcodeCall
-	var indirectCalleePtr ssa.Value
-	var directCallee ssa.FuncRef
-	var sigID ssa.SignatureID
-	var args []ssa.Value
-	if isDirectCall {
-		directCallee, sigID, args = si.CallData()
-	} else {
-		indirectCalleePtr, sigID, args = si.CallIndirectData()
+	calleeABI, stackSlotSize := m.insertCall(si)
+
+	var index int
+	r1, rs := si.Returns()
+	if r1.Valid() {
+		calleeABI.callerGenFunctionReturnVReg(0, m.compiler.VRegOf(r1), stackSlotSize)
+		index++
 	}
-	calleeABI := m.getOrCreateABIImpl(m.compiler.ResolveSignature(sigID))
 
-	stackSlotSize := calleeABI.alignedArgResultStackSlotSize()
+	for _, r := range rs {
+		calleeABI.callerGenFunctionReturnVReg(index, m.compiler.VRegOf(r), stackSlotSize)
+		index++
+	}
+}
+
+// insertCall inserts the call instruction for si, which is either a direct or indirect (return) call, after placing the
+// arguments. This returns the ABI of the callee, and the size of the stack slot for its arguments and results.
+func (m *machine) insertCall(si *ssa.Instruction) (calleeABI *abiImpl, stackSlotSize int64) {
+	isDirectCall, indirectCalleePtr, directCallee, sigID, args := callData(si)
+	calleeABI = m.getOrCreateABIImpl(m.compiler.ResolveSignature(sigID))
+
+	stackSlotSize = calleeABI.alignedArgResultStackSlotSize()
 	if m.maxRequiredStackSizeForCalls < stackSlotSize+16 {
 		m.maxRequiredStackSizeForCalls = stackSlotSize + 16 // return address frame.
 	}
@@ -381,18 +395,90 @@ func (m *machine) lowerCall(si *ssa.Instruction) {
 		callInd.asCallIndirect(ptr, calleeABI)
 		m.insert(callInd)
 	}
+	return
+}
 
-	var index int
-	r1, rs := si.Returns()
-	if r1.Valid() {
-		calleeABI.callerGenFunctionReturnVReg(0, m.compiler.VRegOf(r1), stackSlotSize)
-		index++
+// lowerReturnCall lowers OpcodeReturnCall and OpcodeReturnCallIndirect.
+//
+// When the callee takes the same size of arguments on the stack as the current function, its arguments and results are
+// placed exactly where ours are. In that case, the arguments are placed as if they were ours, and the callee is
+// branched to after the epilogue, so that it returns directly to our caller. Otherwise, this falls back to a normal
+// call followed by returning its results.
+func (m *machine) lowerReturnCall(si *ssa.Instruction) {
+	isDirectCall, indirectCalleePtr, directCallee, sigID, args := callData(si)
+	calleeABI := m.getOrCreateABIImpl(m.compiler.ResolveSignature(sigID))
+
+	if calleeABI.argStackSize != m.currentABI.argStackSize {
+		calleeABI, stackSlotSize := m.insertCall(si)
+		// Wasm validation ensures that the callee has the same results as the current function.
+		for i := range calleeABI.rets {
+			r := &calleeABI.rets[i]
+			reg := m.compiler.AllocateVReg(r.Type)
+			calleeABI.callerGenFunctionReturnVReg(i, reg, stackSlotSize)
+			m.currentABI.calleeGenVRegToFunctionReturn(&m.currentABI.rets[i], reg)
+		}
+		m.InsertReturn()
+		return
 	}
 
-	for _, r := range rs {
-		calleeABI.callerGenFunctionReturnVReg(index, m.compiler.VRegOf(r), stackSlotSize)
-		index++
+	// Stack arguments are stored first, so that the argument registers are live for as short as possible.
+	for i, arg := range args {
+		a := &calleeABI.args[i]
+		if a.Kind != backend.ABIArgKindStack {
+			continue
+		}
+		reg := m.compiler.VRegOf(arg)
+		if def := m.compiler.ValueDefinition(arg); def.IsFromInstr() {
+			// Constant instructions are inlined.
+			if inst := def.Instr; inst.Constant() {
+				m.InsertLoadConstant(inst, reg)
+			}
+		}
+		// Our own arguments are at the same offsets, so use the same address mode as loading them.
+		amode := addressMode{imm: a.Offset, rn: spVReg, kind: addressModeKindArgStackSpace}
+		store := m.allocateInstr()
+		store.asStore(operandNR(reg), amode, a.Type.Bits())
+		m.insert(store)
+		m.unresolvedAddressModes = append(m.unresolvedAddressModes, store)
+	}
+
+	for i, arg := range args {
+		a := &calleeABI.args[i]
+		if a.Kind != backend.ABIArgKindReg {
+			continue
+		}
+		reg := m.compiler.VRegOf(arg)
+		if def := m.compiler.ValueDefinition(arg); def.IsFromInstr() {
+			// Constant instructions are inlined.
+			if inst := def.Instr; inst.Constant() {
+				m.InsertLoadConstant(inst, reg)
+			}
+		}
+		m.InsertMove(a.Reg, reg, a.Type)
+	}
+
+	tail := m.allocateInstr()
+	if isDirectCall {
+		tail.asTailCall(directCallee, calleeABI)
+	} else {
+		// The pointer must be in a caller-saved register which is not used for arguments, as the epilogue
+		// is inserted before the branch and restores the callee-saved ones.
+		m.InsertMove(x16VReg, m.compiler.VRegOf(indirectCalleePtr), ssa.TypeI64)
+		tail.asTailCallIndirect(x16VReg, calleeABI)
+	}
+	m.insert(tail)
+}
+
+// callData returns the operands of a (return) call instruction, which is either direct or indirect.
+func callData(si *ssa.Instruction) (isDirectCall bool, indirectCalleePtr ssa.Value, directCallee ssa.FuncRef, sigID ssa.SignatureID, args []ssa.Value) {
+	switch si.Opcode() {
+	case ssa.OpcodeCall, ssa.OpcodeReturnCall:
+		isDirectCall = true
+		directCallee, sigID, args = si.CallData()
+	default:
+		indirectCalleePtr, sigID, args = si.CallIndirectData()
 	}
+	return
 }
 
 func (m *machine) insertAddOrSubStackPointer(rd regalloc.VReg, diff int64, add bool) {
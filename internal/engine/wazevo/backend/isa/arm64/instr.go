@@ -66,6 +66,8 @@ var defKinds = [numInstructionKinds]defKind{
 	nop0:                 defKindNone,
 	call:                 defKindCall,
 	callInd:              defKindCall,
+	tailCall:             defKindNone,
+	tailCallInd:          defKindNone,
 	ret:                  defKindNone,
 	store8:               defKindNone,
 	store16:              defKindNone,
@@ -185,6 +187,8 @@ var useKinds = [numInstructionKinds]useKind{
 	nop0:                 useKindNone,
 	call:                 useKindCall,
 	callInd:              useKindCallInd,
+	tailCall:             useKindCall,
+	tailCallInd:          useKindCallInd,
 	ret:                  useKindRet,
 	store8:               useKindRNAMode,
 	store16:              useKindRNAMode,
@@ -407,6 +411,18 @@ func (i *instruction) asCallIndirect(ptr regalloc.VReg, abi *abiImpl) {
 	i.abi = abi
 }
 
+func (i *instruction) asTailCall(ref ssa.FuncRef, abi *abiImpl) {
+	i.kind = tailCall
+	i.u1 = uint64(ref)
+	i.abi = abi
+}
+
+func (i *instruction) asTailCallIndirect(ptr regalloc.VReg, abi *abiImpl) {
+	i.kind = tailCallInd
+	i.rn = operandNR(ptr)
+	i.abi = abi
+}
+
 func (i *instruction) callFuncRef() ssa.FuncRef {
 	return ssa.FuncRef(i.u1)
 }
@@ -1307,6 +1323,10 @@ func (i *instruction) String() (str string) {
 		}
 	case callInd:
 		str = fmt.Sprintf("bl %s", formatVRegSized(i.rn.nr(), 64))
+	case tailCall:
+		str = fmt.Sprintf("b %s", ssa.FuncRef(i.u1))
+	case tailCallInd:
+		str = fmt.Sprintf("br %s", formatVRegSized(i.rn.nr(), 64))
 	case ret:
 		str = "ret"
 	case br:
@@ -1537,6 +1557,10 @@ const (
 	call
 	// callInd represents a machine indirect-call instruction.
 	callInd
+	// tailCall represents a machine tail-call instruction, which branches to the callee after the epilogue.
+	tailCall
+	// tailCallInd represents a machine indirect tail-call instruction.
+	tailCallInd
 	// ret represents a machine return instruction.
 	ret
 	// br represents an unconditional branch.
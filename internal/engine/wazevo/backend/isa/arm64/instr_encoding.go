@@ -42,6 +42,12 @@ func (i *instruction) encode(c backend.Compiler) {
 		}
 	case callInd:
 		c.Emit4Bytes(encodeUnconditionalBranchReg(regNumberInEncoding[i.rn.realReg()], true))
+	case tailCall:
+		// Same as call, but without the link so that the callee returns to our caller.
+		c.AddRelocationInfo(i.callFuncRef())
+		c.Emit4Bytes(encodeUnconditionalBranch(false, 0)) // 0 = placeholder
+	case tailCallInd:
+		c.Emit4Bytes(encodeUnconditionalBranchReg(regNumberInEncoding[i.rn.realReg()], false))
 	case store8, store16, store32, store64, fpuStore32, fpuStore64, fpuStore128:
 		c.Emit4Bytes(encodeLoadOrStore(i.kind, regNumberInEncoding[i.rn.realReg()], i.amode))
 	case uLoad8, uLoad16, uLoad32, uLoad64, sLoad8, sLoad16, sLoad32, fpuLoad32, fpuLoad64, fpuLoad128:
@@ -1017,6 +1017,9 @@ func TestInstruction_encode(t *testing.T) {
 		{want: "60033fd6", setup: func(i *instruction) {
 			i.asCallIndirect(tmpRegVReg, nil)
 		}},
+		{want: "60031fd6", setup: func(i *instruction) {
+			i.asTailCallIndirect(tmpRegVReg, nil)
+		}},
 		{want: "fb633bcb", setup: func(i *instruction) {
 			i.asALU(aluOpSub, operandNR(tmpRegVReg), operandNR(spVReg), operandNR(tmpRegVReg), true)
 		}},
@@ -1672,6 +1675,18 @@ func TestInstruction_encode_call(t *testing.T) {
 	require.Equal(t, int64(128), m.relocs[0].Offset)
 }
 
+func TestInstruction_encode_tailCall(t *testing.T) {
+	m := &mockCompiler{buf: make([]byte, 128)}
+	i := &instruction{}
+	i.asTailCall(ssa.FuncRef(555), nil)
+	i.encode(m)
+	buf := m.buf[128:]
+	require.Equal(t, "00000014", hex.EncodeToString(buf))
+	require.Equal(t, 1, len(m.relocs))
+	require.Equal(t, ssa.FuncRef(555), m.relocs[0].FuncRef)
+	require.Equal(t, int64(128), m.relocs[0].Offset)
+}
+
 func TestInstruction_encode_br_condflag(t *testing.T) {
 	for _, tc := range []struct {
 		c    condFlag
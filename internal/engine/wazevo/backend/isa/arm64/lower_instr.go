@@ -163,6 +163,8 @@ func (m *machine) LowerInstr(instr *ssa.Instruction) {
 		m.lowerExtLoad(op, ptr, offset, ret)
 	case ssa.OpcodeCall, ssa.OpcodeCallIndirect:
 		m.lowerCall(instr)
+	case ssa.OpcodeReturnCall, ssa.OpcodeReturnCallIndirect:
+		m.lowerReturnCall(instr)
 	case ssa.OpcodeIcmp:
 		m.lowerIcmp(instr)
 	case ssa.OpcodeVIcmp:
@@ -152,6 +152,11 @@ func (m *machine) Reset() {
 	m.nextLabel = invalidLabel
 }
 
+// ScratchBytes implements backend.Machine.
+func (m *machine) ScratchBytes() int {
+	return m.instrPool.Bytes() + m.labelPositionPool.Bytes()
+}
+
 // InitializeABI implements backend.Machine InitializeABI.
 func (m *machine) InitializeABI(sig *ssa.Signature) {
 	m.currentABI = m.getOrCreateABIImpl(sig)
@@ -470,6 +475,53 @@ func (m *machine) Format() string {
 	return "\n" + strings.Join(lines, "\n") + "\n"
 }
 
+// Disassemble implements backend.Machine.
+func (m *machine) Disassemble() (ret []backend.DisassembledInstruction) {
+	var offset int64
+	for cur := m.rootInstr; cur != nil; cur = cur.next {
+		size := cur.size()
+		if size == 0 { // Labels and other markers which are not encoded.
+			continue
+		}
+		mnemonic, operands := splitInstructionString(cur.String())
+		ret = append(ret, backend.DisassembledInstruction{
+			Offset:   offset,
+			Size:     size,
+			Mnemonic: mnemonic,
+			Operands: operands,
+		})
+		offset += size
+	}
+	return
+}
+
+// splitInstructionString splits the result of instruction.String into the mnemonic and the operands, which are
+// separated by commas except within brackets, e.g. "ldr x1, [x2, #0x8]" into "ldr" and ["x1", "[x2, #0x8]"].
+func splitInstructionString(str string) (mnemonic string, operands []string) {
+	space := strings.IndexByte(str, ' ')
+	if space < 0 {
+		return str, nil
+	}
+	mnemonic, str = str[:space], str[space+1:]
+
+	depth, start := 0, 0
+	for i := 0; i < len(str); i++ {
+		switch str[i] {
+		case '[', '{', '(':
+			depth++
+		case ']', '}', ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				operands = append(operands, strings.TrimSpace(str[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	operands = append(operands, strings.TrimSpace(str[start:]))
+	return
+}
+
 // InsertReturn implements backend.Machine.
 func (m *machine) InsertReturn() {
 	i := m.allocateInstr()
@@ -191,7 +191,7 @@ func (m *machine) createFrameSizeSlot(cur *instruction, s int64) *instruction {
 // SetupEpilogue implements backend.Machine.
 func (m *machine) SetupEpilogue() {
 	for cur := m.rootInstr; cur != nil; cur = cur.next {
-		if cur.kind == ret {
+		if cur.kind == ret || cur.kind == tailCall || cur.kind == tailCallInd {
 			m.setupEpilogueAfter(cur.prev)
 			continue
 		}
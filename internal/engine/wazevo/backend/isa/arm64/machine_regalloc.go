@@ -260,7 +260,8 @@ func (r *regAllocInstrImpl) IsIndirectCall() bool {
 
 // IsReturn implements regalloc.Instr IsReturn.
 func (r *regAllocInstrImpl) IsReturn() bool {
-	return r.i.kind == ret
+	// Tail calls are lowered after the epilogue, and all their uses are already real registers.
+	return r.i.kind == ret || r.i.kind == tailCall || r.i.kind == tailCallInd
 }
 
 // AssignUse implements regalloc.Instr AssignUse.
@@ -15,14 +15,13 @@ func (m *machine) ResolveRelocations(refToBinaryOffset map[ssa.FuncRef]int, bina
 		brInstr := binary[instrOffset : instrOffset+4]
 		diff := int64(calleeFnOffset) - (instrOffset)
 		// https://developer.arm.com/documentation/ddi0596/2020-12/Base-Instructions/BL--Branch-with-Link-
+		// https://developer.arm.com/documentation/ddi0596/2020-12/Base-Instructions/B--Branch-
 		imm26 := diff / 4
 		brInstr[0] = byte(imm26)
 		brInstr[1] = byte(imm26 >> 8)
 		brInstr[2] = byte(imm26 >> 16)
-		if diff < 0 {
-			brInstr[3] = (byte(imm26 >> 24 & 0b000000_01)) | 0b100101_10 // Set sign bit.
-		} else {
-			brInstr[3] = (byte(imm26 >> 24 & 0b000000_01)) | 0b100101_00 // No sign bit.
-		}
+		// Keep the opcode bits which differ between BL (calls) and B (tail calls), and set the rest of imm26
+		// including the sign bit.
+		brInstr[3] = brInstr[3]&0b111111_00 | byte(imm26>>24)&0b000000_11
 	}
 }
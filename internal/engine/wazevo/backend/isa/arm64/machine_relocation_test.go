@@ -0,0 +1,24 @@
+package arm64
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/engine/wazevo/backend"
+	"github.com/tetratelabs/wazero/internal/engine/wazevo/ssa"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestMachine_ResolveRelocations(t *testing.T) {
+	m := &machine{}
+	binary := make([]byte, 16)
+	// bl and b placeholders at 0x4 and 0x8.
+	copy(binary[4:], []byte{0x00, 0x00, 0x00, 0x94, 0x00, 0x00, 0x00, 0x14})
+	refToBinaryOffset := map[ssa.FuncRef]int{0: 0x0, 1: 0xc}
+	m.ResolveRelocations(refToBinaryOffset, binary, []backend.RelocationInfo{
+		{Offset: 0x4, FuncRef: 1},
+		{Offset: 0x8, FuncRef: 0},
+	})
+	require.Equal(t, "02000094", hex.EncodeToString(binary[4:8]))  // bl #0x8
+	require.Equal(t, "feffff17", hex.EncodeToString(binary[8:12])) // b #-0x8
+}
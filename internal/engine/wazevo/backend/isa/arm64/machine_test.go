@@ -3,6 +3,7 @@ package arm64
 import (
 	"testing"
 
+	"github.com/tetratelabs/wazero/internal/engine/wazevo/backend"
 	"github.com/tetratelabs/wazero/internal/engine/wazevo/backend/regalloc"
 	"github.com/tetratelabs/wazero/internal/engine/wazevo/wazevoapi"
 	"github.com/tetratelabs/wazero/internal/testing/require"
@@ -123,3 +124,42 @@ func TestMachine_getVRegSpillSlotOffsetFromSP(t *testing.T) {
 	_, ok = m.spillSlots[id]
 	require.True(t, ok)
 }
+
+func TestMachine_Disassemble(t *testing.T) {
+	m := &machine{}
+	add, label, load, exit, ret := &instruction{}, &instruction{}, &instruction{}, &instruction{}, &instruction{}
+	add.asALU(aluOpAdd, operandNR(x0VReg), operandNR(x1VReg), operandNR(x2VReg), true)
+	label.asNop0WithLabel(1)
+	load.asULoad(operandNR(x3VReg), addressMode{kind: addressModeKindRegUnsignedImm12, rn: x4VReg, imm: 8}, 64)
+	exit.asExitSequence(x5VReg)
+	ret.asRet(nil)
+	m.rootInstr = add
+	linkInstr(linkInstr(linkInstr(linkInstr(add, label), load), exit), ret)
+
+	require.Equal(t, []backend.DisassembledInstruction{
+		{Offset: 0, Size: 4, Mnemonic: "add", Operands: []string{"x0", "x1", "x2"}},
+		{Offset: 4, Size: 4, Mnemonic: "ldr", Operands: []string{"x3", "[x4, #0x8]"}},
+		{Offset: 8, Size: exitSequenceSize, Mnemonic: "exit_sequence", Operands: []string{"x5"}},
+		{Offset: 8 + exitSequenceSize, Size: 4, Mnemonic: "ret"},
+	}, m.Disassemble())
+}
+
+func Test_splitInstructionString(t *testing.T) {
+	for _, tc := range []struct {
+		str      string
+		mnemonic string
+		operands []string
+	}{
+		{str: "ret", mnemonic: "ret"},
+		{str: "b L3", mnemonic: "b", operands: []string{"L3"}},
+		{str: "add w0, w1, w2", mnemonic: "add", operands: []string{"w0", "w1", "w2"}},
+		{str: "ldr x1, [x2, #0x8]", mnemonic: "ldr", operands: []string{"x1", "[x2, #0x8]"}},
+		{str: "ld1r {v0.4s}, [x1]", mnemonic: "ld1r", operands: []string{"{v0.4s}", "[x1]"}},
+	} {
+		t.Run(tc.str, func(t *testing.T) {
+			mnemonic, operands := splitInstructionString(tc.str)
+			require.Equal(t, tc.mnemonic, mnemonic)
+			require.Equal(t, tc.operands, operands)
+		})
+	}
+}
@@ -3,7 +3,9 @@ package arm64
 import (
 	"context"
 	"strings"
+	"time"
 
+	"github.com/tetratelabs/wazero/internal/compilation"
 	"github.com/tetratelabs/wazero/internal/engine/wazevo/backend"
 	"github.com/tetratelabs/wazero/internal/engine/wazevo/backend/regalloc"
 	"github.com/tetratelabs/wazero/internal/engine/wazevo/ssa"
@@ -78,11 +80,16 @@ func (m *mockCompiler) Buf() []byte { return m.buf }
 func (m *mockCompiler) TypeOf(v regalloc.VReg) (ret ssa.Type) {
 	return m.typeOf[v]
 }
-func (m *mockCompiler) Finalize()      {}
-func (m *mockCompiler) RegAlloc()      {}
-func (m *mockCompiler) Lower()         {}
-func (m *mockCompiler) Format() string { return "" }
-func (m *mockCompiler) Init()          {}
+func (m *mockCompiler) Finalize()         {}
+func (m *mockCompiler) RegAlloc() error   { return nil }
+func (m *mockCompiler) Lower()            {}
+func (m *mockCompiler) Format() string    { return "" }
+func (m *mockCompiler) Init()             {}
+func (m *mockCompiler) ScratchBytes() int { return 0 }
+
+func (m *mockCompiler) SetDeadline(time.Time) {}
+
+func (m *mockCompiler) RegAllocInfo() *compilation.RegAllocInfo { return nil }
 
 func newMockCompilationContext() *mockCompiler {
 	return &mockCompiler{
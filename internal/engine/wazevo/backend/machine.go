@@ -62,6 +62,9 @@ type (
 		// Reset resets the machine state for the next compilation.
 		Reset()
 
+		// ScratchBytes returns the size in bytes of the pooled scratch memory used by the current compilation.
+		ScratchBytes() int
+
 		// FlushPendingInstructions flushes the pending instructions to the buffer.
 		// This will be called after the lowering of each SSA Instruction.
 		FlushPendingInstructions()
@@ -99,6 +102,10 @@ type (
 		// Encode encodes the machine instructions to the Compiler.
 		Encode()
 
+		// Disassemble returns the instructions of the currently compiled function, which must have been encoded.
+		// This is only for debugging purpose, and the result is not reused by the machine.
+		Disassemble() []DisassembledInstruction
+
 		// CompileGoFunctionTrampoline compiles the trampoline function  to call a Go function of the given exit code and signature.
 		CompileGoFunctionTrampoline(exitCode wazevoapi.ExitCode, sig *ssa.Signature, needModuleContextPtr bool) []byte
 
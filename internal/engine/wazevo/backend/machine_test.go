@@ -118,9 +118,15 @@ func (m mockMachine) Reset() {
 	m.reset()
 }
 
+// ScratchBytes implements Machine.ScratchBytes.
+func (m mockMachine) ScratchBytes() int { return 0 }
+
 // FlushPendingInstructions implements Machine.FlushPendingInstructions.
 func (m mockMachine) FlushPendingInstructions() {}
 
+// Disassemble implements Machine.Disassemble.
+func (m mockMachine) Disassemble() []DisassembledInstruction { return nil }
+
 // InsertMove implements Machine.InsertMove.
 func (m mockMachine) InsertMove(dst, src regalloc.VReg, typ ssa.Type) {
 	m.insertMove(dst, src)
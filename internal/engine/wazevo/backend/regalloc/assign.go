@@ -43,6 +43,7 @@ func (a *Allocator) assignRegistersPerInstr(f Function, pc programCounter, instr
 			if r := active.r; a.regInfo.isCallerSaved(r) {
 				v := active.v.SetRealReg(r)
 				f.StoreRegisterBefore(v, instr)
+				a.spills++
 				f.ReloadRegisterAfter(v, instr)
 			}
 		}
@@ -121,6 +122,7 @@ func (a *Allocator) handleSpills(
 		if evictedNode != nil {
 			evictedNodeV := evictedNode.v.SetRealReg(evictedNode.assignedRealReg())
 			f.StoreRegisterBefore(evictedNodeV, instr)
+			a.spills++
 			f.ReloadRegisterAfter(evictedNodeV, instr)
 		}
 
@@ -128,6 +130,7 @@ func (a *Allocator) handleSpills(
 		instr.AssignDef(defSpill)
 
 		f.StoreRegisterAfter(defSpill, instr)
+		a.spills++
 
 	case _usesSpills:
 		intervalMng.collectActiveNodes(pc, &a.nodes1, true)
@@ -148,6 +151,7 @@ func (a *Allocator) handleSpills(
 			evictedNode := evicted[i]
 			evictedNodeV := evictedNode.v.SetRealReg(evictedNode.assignedRealReg())
 			f.StoreRegisterBefore(evictedNodeV, instr)
+			a.spills++
 			f.ReloadRegisterAfter(evictedNodeV, instr)
 		}
 
@@ -180,6 +184,7 @@ func (a *Allocator) handleSpills(
 				if evictedNode != nil {
 					evictedNodeV := evictedNode.v.SetRealReg(evictedNode.assignedRealReg())
 					f.StoreRegisterBefore(evictedNodeV, instr)
+					a.spills++
 					f.ReloadRegisterAfter(evictedNodeV, instr)
 				}
 				defSpill = defSpill.SetRealReg(r)
@@ -187,6 +192,7 @@ func (a *Allocator) handleSpills(
 
 			instr.AssignDef(defSpill)
 			f.StoreRegisterAfter(defSpill, instr)
+			a.spills++
 		}
 	}
 }
@@ -55,11 +55,13 @@ func (a *Allocator) buildNeighborsFor(n *node) {
 
 // coloring does the graph coloring for both RegType(s).
 // Since the graphs are disjoint per RegType, we do it by RegType separately.
-func (a *Allocator) coloring() {
+func (a *Allocator) coloring() error {
 	a.collectNodesByRegType(RegTypeInt)
-	a.coloringFor(a.regInfo.AllocatableRegisters[RegTypeInt])
+	if err := a.coloringFor(a.regInfo.AllocatableRegisters[RegTypeInt]); err != nil {
+		return err
+	}
 	a.collectNodesByRegType(RegTypeFloat)
-	a.coloringFor(a.regInfo.AllocatableRegisters[RegTypeFloat])
+	return a.coloringFor(a.regInfo.AllocatableRegisters[RegTypeFloat])
 }
 
 // collectNodesByRegType collects all the nodes that are of the given register type.
@@ -81,7 +83,7 @@ func (a *Allocator) collectNodesByRegType(regType RegType) {
 // This assumes that the coloring target nodes are stored at Allocator.nodes1.
 //
 // TODO: the implementation here is not optimized at all. Come back later.
-func (a *Allocator) coloringFor(allocatable []RealReg) {
+func (a *Allocator) coloringFor(allocatable []RealReg) error {
 	degreeSortedNodes := a.nodes1 // We assume nodes1 holds all the nodes of the given register type.
 	// Reuses the nodes2 slice and the degrees map from the previous iteration.
 	coloringStack := a.nodes2[:0]
@@ -114,6 +116,9 @@ func (a *Allocator) coloringFor(allocatable []RealReg) {
 	}
 	total := len(degreeSortedNodes)
 	for len(coloringStack) != total {
+		if err := wazevoapi.CheckDeadline(a.deadline); err != nil {
+			return err
+		}
 		if len(popTargetQueue) == 0 {
 			// If no node can be popped, it means that the graph is not colorable. We need to forcibly choose one node to pop.
 			// TODO: currently we just choose the last node. We could do this more wisely. e.g. choose the one without pre-colored neighbors etc.
@@ -196,6 +201,7 @@ func (a *Allocator) coloringFor(allocatable []RealReg) {
 	a.nodes1 = degreeSortedNodes[:0]
 	a.nodes2 = coloringStack[:0]
 	a.nodes3 = popTargetQueue[:0]
+	return nil
 }
 
 func (a *Allocator) assignColor(n *node, neighborColorsSet *[128]bool, allocatable []RealReg) {
@@ -140,7 +140,8 @@ func TestAllocator_coloringFor(t *testing.T) {
 					addEdge(n1, n2)
 				}
 			}
-			a.coloringFor(tc.allocatable)
+			err := a.coloringFor(tc.allocatable)
+			require.NoError(t, err)
 			var actual []string
 			for _, n := range testNodes {
 				actual = append(actual, n.r.String())
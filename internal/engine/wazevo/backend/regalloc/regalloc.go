@@ -13,7 +13,9 @@ import (
 	"math"
 	"sort"
 	"strings"
+	"time"
 
+	"github.com/tetratelabs/wazero/internal/compilation"
 	"github.com/tetratelabs/wazero/internal/engine/wazevo/wazevoapi"
 )
 
@@ -73,6 +75,12 @@ type (
 		nodes2     []*node
 		nodes3     []*node
 		dedup      []bool
+
+		// spills is the number of stores inserted to the stack for the current function.
+		spills int
+
+		// deadline is set by SetDeadline, and checked between the phases of DoAllocation and while coloring.
+		deadline time.Time
 	}
 
 	// blockInfo is a per-block information used during the register allocation.
@@ -109,15 +117,34 @@ type (
 	programCounter int32
 )
 
+// SetDeadline sets the time by which DoAllocation must finish for the current function.
+// The zero time means no deadline.
+func (a *Allocator) SetDeadline(deadline time.Time) {
+	a.deadline = deadline
+}
+
 // DoAllocation performs register allocation on the given Function.
-func (a *Allocator) DoAllocation(f Function) {
+// This returns wazevoapi.ErrDeadlineExceeded if the deadline set by SetDeadline passes before the allocation is done.
+func (a *Allocator) DoAllocation(f Function) error {
 	a.livenessAnalysis(f)
+	if err := wazevoapi.CheckDeadline(a.deadline); err != nil {
+		return err
+	}
 	a.buildLiveRanges(f)
+	if err := wazevoapi.CheckDeadline(a.deadline); err != nil {
+		return err
+	}
 	a.buildNeighbors()
-	a.coloring()
+	if err := wazevoapi.CheckDeadline(a.deadline); err != nil {
+		return err
+	}
+	if err := a.coloring(); err != nil {
+		return err
+	}
 	a.determineCalleeSavedRealRegs(f)
 	a.assignRegisters(f)
 	f.Done()
+	return nil
 }
 
 func (a *Allocator) determineCalleeSavedRealRegs(f Function) {
@@ -431,6 +458,52 @@ func (a *Allocator) Reset() {
 	}
 	a.phis = a.phis[:0]
 	a.vs = a.vs[:0]
+	a.spills = 0
+}
+
+// ScratchBytes returns the size in bytes of the pooled scratch memory used by the current allocation.
+func (a *Allocator) ScratchBytes() int {
+	ret := a.nodePool.Bytes() + a.blockInfoPool.Bytes()
+	for i := 0; i < a.blockInfoPool.Allocated(); i++ {
+		if mng := a.blockInfoPool.View(i).intervalMng; mng != nil {
+			ret += mng.allocator.Bytes()
+		}
+	}
+	return ret
+}
+
+// Info returns the summary of the last DoAllocation. This is only for debugging purpose, and is not used by the
+// allocation itself.
+func (a *Allocator) Info() *compilation.RegAllocInfo {
+	info := &compilation.RegAllocInfo{Spills: a.spills}
+	for _, n := range a.vRegIDToNode {
+		if n != nil {
+			info.VirtualRegisters++
+		}
+	}
+	for blkID, blkInfo := range a.blockInfos {
+		if blkInfo == nil {
+			continue
+		}
+		for _, i := range blkInfo.intervalMng.sortedIntervals {
+			for _, n := range i.nodes {
+				if n.v.IsRealReg() || !n.spill() {
+					continue
+				}
+				r := compilation.LiveRange{
+					VirtualRegister: uint32(n.v.ID()),
+					Block:           blkID,
+					Begin:           int(i.begin / pcStride),
+					End:             int(i.end / pcStride),
+				}
+				if i.end == math.MaxInt32 {
+					r.End, r.LiveOut = -1, true
+				}
+				info.SpilledRanges = append(info.SpilledRanges, r)
+			}
+		}
+	}
+	return info
 }
 
 func (a *Allocator) allocateBlockInfo(blockID int) *blockInfo {
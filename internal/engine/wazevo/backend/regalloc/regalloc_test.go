@@ -2,7 +2,9 @@ package regalloc
 
 import (
 	"testing"
+	"time"
 
+	"github.com/tetratelabs/wazero/internal/engine/wazevo/wazevoapi"
 	"github.com/tetratelabs/wazero/internal/testing/require"
 )
 
@@ -496,6 +498,19 @@ func TestAllocator_livenessAnalysis_copy(t *testing.T) {
 	require.Nil(t, n2.copyToVReg)
 }
 
+func TestAllocator_DoAllocation_deadline(t *testing.T) {
+	f := newMockFunction(
+		newMockBlock(0,
+			newMockInstr().def(1),
+			newMockInstr().use(1),
+		).entry(),
+	)
+	a := NewAllocator(&RegisterInfo{})
+	a.SetDeadline(time.Now().Add(-time.Second))
+	err := a.DoAllocation(f)
+	require.Equal(t, wazevoapi.ErrDeadlineExceeded, err)
+}
+
 func TestAllocator_recordCopyRelation(t *testing.T) {
 	t.Run("real/real", func(t *testing.T) {
 		// Just ensure that it doesn't panic.
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/binary"
 	"fmt"
+	"math"
 	"reflect"
 	"unsafe"
 
@@ -11,6 +12,7 @@ import (
 	"github.com/tetratelabs/wazero/experimental"
 	"github.com/tetratelabs/wazero/internal/engine/wazevo/wazevoapi"
 	"github.com/tetratelabs/wazero/internal/internalapi"
+	"github.com/tetratelabs/wazero/internal/platform"
 	"github.com/tetratelabs/wazero/internal/wasm"
 	"github.com/tetratelabs/wazero/internal/wasmdebug"
 	"github.com/tetratelabs/wazero/internal/wasmruntime"
@@ -41,6 +43,10 @@ type (
 		execCtxPtr        uintptr
 		numberOfResults   int
 		stackIteratorImpl stackIterator
+		// funcType is the type of the function, used to zero-extend 32-bit results.
+		funcType *wasm.FunctionType
+		// fuelLoaded is the value of execCtx.fuelRemaining when it was last loaded from, or charged to, the module.
+		fuelLoaded uint64
 	}
 
 	// executionContext is the struct to be read/written by assembly functions.
@@ -80,6 +86,17 @@ type (
 		refFuncTrampolineAddress *byte
 		// memmoveAddress holds the address of memmove function implemented by Go runtime. See memmove.go.
 		memmoveAddress uintptr
+		// callDepthRemaining holds the number of nested calls into Wasm functions which are still allowed.
+		// This is only checked and updated by functions of the compiledModule metered variant.
+		callDepthRemaining uint64
+		// fuelRemaining holds the fuel of the module called from the host which wasn't consumed yet. Like
+		// callDepthRemaining, this is only checked and updated by functions of the compiledModule metered variant.
+		fuelRemaining uint64
+		// exceptionTag holds the *wasm.TagInstance of the exception being thrown, or zero if there is none. While this is
+		// set, functions branch to the innermost handler after each call, or return to their caller if there's none.
+		exceptionTag uintptr
+		// exceptionPayload holds the values carried by the exception being thrown.
+		exceptionPayload [wazevoapi.ExceptionPayloadSlots]uint64
 	}
 )
 
@@ -121,6 +138,11 @@ func (c *callEngine) Definition() api.FunctionDefinition {
 	return c.parent.module.Source.FunctionDefinition(c.indexInModule)
 }
 
+// FunctionInstanceReference implements wasm.FunctionReferencer.
+func (c *callEngine) FunctionInstanceReference() wasm.Reference {
+	return c.parent.FunctionInstanceReference(c.indexInModule)
+}
+
 // Call implements api.Function.
 func (c *callEngine) Call(ctx context.Context, params ...uint64) ([]uint64, error) {
 	if c.requiredParams != len(params) {
@@ -140,12 +162,13 @@ func (c *callEngine) addFrame(builder wasmdebug.ErrorBuilder, addr uintptr) (def
 	if cm != nil {
 		index := cm.functionIndexOf(addr)
 		def = cm.module.FunctionDefinition(cm.module.ImportFunctionCount + index)
+		frame := wasmdebug.Frame{ModuleName: def.ModuleName(), FunctionIndex: def.Index(), FunctionName: def.Name()}
 		var sources []string
 		if dw := cm.module.DWARFLines; dw != nil {
-			sourceOffset := cm.getSourceOffset(addr)
-			sources = dw.Line(sourceOffset)
+			frame.Offset = cm.getSourceOffset(addr)
+			sources = dw.Annotate(&frame)
 		}
-		builder.AddFrame(def.DebugName(), def.ParamTypes(), def.ResultTypes(), sources)
+		builder.AddFrame(frame, def.ParamTypes(), def.ResultTypes(), sources)
 		if len(cm.listeners) > 0 {
 			listener = cm.listeners[index]
 		}
@@ -153,6 +176,16 @@ func (c *callEngine) addFrame(builder wasmdebug.ErrorBuilder, addr uintptr) (def
 	return
 }
 
+// uncaughtException returns the error of an exception which wasn't caught. The frames it propagated through have
+// already returned, so only the called function is in the stack trace.
+func (c *callEngine) uncaughtException() error {
+	def := c.Definition()
+	builder := wasmdebug.NewErrorBuilder()
+	builder.AddFrame(wasmdebug.Frame{ModuleName: def.ModuleName(), FunctionIndex: def.Index(), FunctionName: def.Name()},
+		def.ParamTypes(), def.ResultTypes(), nil)
+	return builder.FromRecovered(wasmruntime.ErrRuntimeUncaughtException)
+}
+
 // CallWithStack implements api.Function.
 func (c *callEngine) CallWithStack(ctx context.Context, paramResultStack []uint64) (err error) {
 	if c.sizeOfParamResultSlice > len(paramResultStack) {
@@ -182,11 +215,16 @@ func (c *callEngine) callWithStack(ctx context.Context, paramResultStack []uint6
 		}
 	}
 
+	if m.StrictFloat {
+		defer platform.ExitStrictFloat(platform.EnterStrictFloat())
+	}
+
 	var paramResultPtr *uint64
 	if len(paramResultStack) > 0 {
 		paramResultPtr = &paramResultStack[0]
 	}
 	defer func() {
+		c.chargeFuel(m)
 		if r := recover(); r != nil {
 			type listenerForAbort struct {
 				def api.FunctionDefinition
@@ -211,10 +249,8 @@ func (c *callEngine) callWithStack(ctx context.Context, paramResultStack []uint6
 			for _, lsn := range listeners {
 				lsn.lsn.Abort(ctx, m, lsn.def, err)
 			}
-		} else {
-			if err != wasmruntime.ErrRuntimeStackOverflow { // Stackoverflow case shouldn't be panic (to avoid extreme stack unwinding).
-				err = c.parent.module.FailIfClosed()
-			}
+		} else if err == nil { // Stackoverflow case shouldn't be panic (to avoid extreme stack unwinding), so it's returned as is.
+			err = c.parent.module.FailIfClosed()
 		}
 
 		if err != nil {
@@ -227,11 +263,24 @@ func (c *callEngine) callWithStack(ctx context.Context, paramResultStack []uint6
 		done := m.CloseModuleOnCanceledOrTimeout(ctx)
 		defer done()
 	}
+	// This is set even if this module doesn't limit its call depth, as it may call metered functions of another module.
+	if n := m.MaxCallDepth(); n != 0 {
+		c.execCtx.callDepthRemaining = uint64(n)
+	} else {
+		c.execCtx.callDepthRemaining = math.MaxUint64
+	}
+	c.loadFuel(m)
 
 	entrypoint(c.preambleExecutable, c.executable, c.execCtxPtr, c.parent.opaquePtr, paramResultPtr, c.stackTop)
 	for {
 		switch ec := c.execCtx.exitCode; ec & wazevoapi.ExitCodeMask {
 		case wazevoapi.ExitCodeOK:
+			if c.execCtx.exceptionTag != 0 {
+				// The exception propagated through all the frames without being caught.
+				c.execCtx.exceptionTag = 0
+				return c.uncaughtException()
+			}
+			wasm.ZeroExtend32BitResults(c.funcType, paramResultStack)
 			return nil
 		case wazevoapi.ExitCodeGrowStack:
 			var newsp uintptr
@@ -277,7 +326,8 @@ func (c *callEngine) callWithStack(ctx context.Context, paramResultStack []uint6
 		case wazevoapi.ExitCodeCallGoFunction:
 			index := wazevoapi.GoFunctionIndexFromExitCode(ec)
 			f := hostModuleGoFuncFromOpaque[api.GoFunction](index, c.execCtx.goFunctionCallCalleeModuleContextOpaque)
-			f.Call(ctx, goCallStackView(c.execCtx.stackPointerBeforeGoCall))
+			def := hostModuleFromOpaque(c.execCtx.goFunctionCallCalleeModuleContextOpaque).FunctionDefinition(wasm.Index(index))
+			c.callHostFunction(ctx, m, c.callerModuleInstance(), def, f, goCallStackView(c.execCtx.stackPointerBeforeGoCall))
 			// Back to the native code.
 			c.execCtx.exitCode = wazevoapi.ExitCodeOK
 			afterGoFunctionCallEntrypoint(c.execCtx.goCallReturnAddress, c.execCtxPtr, uintptr(unsafe.Pointer(c.execCtx.stackPointerBeforeGoCall)))
@@ -293,7 +343,7 @@ func (c *callEngine) callWithStack(ctx context.Context, paramResultStack []uint6
 			def := hostModule.FunctionDefinition(wasm.Index(index))
 			listener.Before(ctx, callerModule, def, s, c.stackIterator(true))
 			// Call into the Go function.
-			f.Call(ctx, s)
+			c.callHostFunction(ctx, m, callerModule, def, f, s)
 			// Call Listener.After.
 			listener.After(ctx, callerModule, def, s)
 			// Back to the native code.
@@ -302,8 +352,9 @@ func (c *callEngine) callWithStack(ctx context.Context, paramResultStack []uint6
 		case wazevoapi.ExitCodeCallGoModuleFunction:
 			index := wazevoapi.GoFunctionIndexFromExitCode(ec)
 			f := hostModuleGoFuncFromOpaque[api.GoModuleFunction](index, c.execCtx.goFunctionCallCalleeModuleContextOpaque)
+			def := hostModuleFromOpaque(c.execCtx.goFunctionCallCalleeModuleContextOpaque).FunctionDefinition(wasm.Index(index))
 			mod := c.callerModuleInstance()
-			f.Call(ctx, mod, goCallStackView(c.execCtx.stackPointerBeforeGoCall))
+			c.callHostFunction(ctx, m, mod, def, f, goCallStackView(c.execCtx.stackPointerBeforeGoCall))
 			// Back to the native code.
 			c.execCtx.exitCode = wazevoapi.ExitCodeOK
 			afterGoFunctionCallEntrypoint(c.execCtx.goCallReturnAddress, c.execCtxPtr, uintptr(unsafe.Pointer(c.execCtx.stackPointerBeforeGoCall)))
@@ -319,7 +370,7 @@ func (c *callEngine) callWithStack(ctx context.Context, paramResultStack []uint6
 			def := hostModule.FunctionDefinition(wasm.Index(index))
 			listener.Before(ctx, callerModule, def, s, c.stackIterator(true))
 			// Call into the Go function.
-			f.Call(ctx, callerModule, s)
+			c.callHostFunction(ctx, m, callerModule, def, f, s)
 			// Call Listener.After.
 			listener.After(ctx, callerModule, def, s)
 			// Back to the native code.
@@ -376,12 +427,47 @@ func (c *callEngine) callWithStack(ctx context.Context, paramResultStack []uint6
 			panic(wasmruntime.ErrRuntimeIntegerDivideByZero)
 		case wazevoapi.ExitCodeInvalidConversionToInteger:
 			panic(wasmruntime.ErrRuntimeInvalidConversionToInteger)
+		case wazevoapi.ExitCodeUnalignedAtomic:
+			panic(wasmruntime.ErrRuntimeUnalignedAtomic)
+		case wazevoapi.ExitCodeCallStackExhausted:
+			panic(wasmruntime.ErrRuntimeCallStackExhausted)
+		case wazevoapi.ExitCodeFuelExhausted:
+			// All the fuel loaded was consumed, so consuming it and the unit needed next fails.
+			n := c.fuelLoaded + 1
+			c.fuelLoaded = 0
+			panic(m.FailIfClosedOrOutOfFuel(n))
 		default:
 			panic("BUG")
 		}
 	}
 }
 
+// loadFuel loads the remaining fuel of the module called from the host into execCtx.
+func (c *callEngine) loadFuel(m *wasm.ModuleInstance) {
+	if fuel, limited := m.Fuel(); limited {
+		c.execCtx.fuelRemaining = fuel
+	} else {
+		c.execCtx.fuelRemaining = math.MaxUint64
+	}
+	c.fuelLoaded = c.execCtx.fuelRemaining
+}
+
+// chargeFuel consumes the fuel of the module called from the host which was consumed in execCtx since it was loaded.
+func (c *callEngine) chargeFuel(m *wasm.ModuleInstance) {
+	if consumed := c.fuelLoaded - c.execCtx.fuelRemaining; consumed != 0 && m.Sys != nil {
+		m.Sys.ConsumeFuel(consumed)
+	}
+	c.fuelLoaded = c.execCtx.fuelRemaining
+}
+
+// callHostFunction calls the host function with wasm.CallHostFunction. The fuel is charged before and loaded after,
+// as the host function may call back into the module called from the host.
+func (c *callEngine) callHostFunction(ctx context.Context, m, caller *wasm.ModuleInstance, def *wasm.FunctionDefinition, fn interface{}, stack []uint64) {
+	c.chargeFuel(m)
+	wasm.CallHostFunction(ctx, caller, def, fn, stack, c.stackRemaining())
+	c.loadFuel(m)
+}
+
 func (c *callEngine) callerModuleInstance() *wasm.ModuleInstance {
 	return moduleInstanceFromOpaquePtr(c.execCtx.callerModuleContextPtr)
 }
@@ -397,6 +483,16 @@ func opaqueViewFromPtr(ptr uintptr) []byte {
 
 const callStackCeiling = uintptr(5000000) // in uint64 (8 bytes) == 40000000 bytes in total == 40mb.
 
+// stackRemaining returns approximately how many bytes the stack can grow from the stack pointer before the Go call,
+// before it exceeds callStackCeiling.
+func (c *callEngine) stackRemaining() int {
+	used := c.stackTop - uintptr(unsafe.Pointer(c.execCtx.stackPointerBeforeGoCall))
+	if used >= callStackCeiling {
+		return 0
+	}
+	return int(callStackCeiling - used)
+}
+
 func (c *callEngine) growStackWithGuarded() (newSP uintptr, err error) {
 	if wazevoapi.StackGuardCheckEnabled {
 		wazevoapi.CheckStackGuardPage(c.stack)
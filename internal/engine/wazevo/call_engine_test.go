@@ -1,11 +1,14 @@
 package wazevo
 
 import (
+	"math"
 	"reflect"
 	"testing"
 	"unsafe"
 
+	"github.com/tetratelabs/wazero/internal/sys"
 	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasm"
 )
 
 func TestCallEngine_init(t *testing.T) {
@@ -63,3 +66,40 @@ func TestCallEngine_requiredInitialStackSize(t *testing.T) {
 	c.sizeOfParamResultSlice = 120
 	require.Equal(t, 120*16+32+16, c.requiredInitialStackSize())
 }
+
+func TestCallEngine_fuel(t *testing.T) {
+	t.Run("unlimited", func(t *testing.T) {
+		m := &wasm.ModuleInstance{Sys: sys.DefaultContext(nil)}
+		c := &callEngine{}
+		c.loadFuel(m)
+		require.Equal(t, uint64(math.MaxUint64), c.execCtx.fuelRemaining)
+
+		c.execCtx.fuelRemaining -= 10
+		c.chargeFuel(m)
+		_, limited := m.Fuel()
+		require.False(t, limited)
+	})
+
+	t.Run("limited", func(t *testing.T) {
+		m := &wasm.ModuleInstance{Sys: sys.DefaultContext(nil)}
+		m.Sys.SetFuel(100)
+		c := &callEngine{}
+		c.loadFuel(m)
+		require.Equal(t, uint64(100), c.execCtx.fuelRemaining)
+
+		c.execCtx.fuelRemaining -= 10
+		c.chargeFuel(m)
+		fuel, _ := m.Fuel()
+		require.Equal(t, uint64(90), fuel)
+
+		// Charging again doesn't consume the same fuel twice.
+		c.chargeFuel(m)
+		fuel, _ = m.Fuel()
+		require.Equal(t, uint64(90), fuel)
+
+		// Fuel consumed elsewhere, e.g. by a host function calling back into the module, is seen once reloaded.
+		require.True(t, m.Sys.ConsumeFuel(30))
+		c.loadFuel(m)
+		require.Equal(t, uint64(60), c.execCtx.fuelRemaining)
+	})
+}
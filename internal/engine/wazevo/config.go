@@ -10,6 +10,26 @@ import (
 	"github.com/tetratelabs/wazero/internal/wasm"
 )
 
+type newEngine func(context.Context, api.CoreFeatures, filecache.Cache) wasm.Engine
+
+// runtimeConfig corresponds to the unexported wazero.runtimeConfig up to the field newEngine,
+// which exists in the middle of the implementation. Its layout must match, which is checked by
+// Test_runtimeConfigOffsets.
+type runtimeConfig struct {
+	enabledFeatures       api.CoreFeatures
+	memoryLimitPages      uint32
+	memoryCapacityFromMax bool
+	memoryZeroOnClose     bool
+	memoryPrefault        bool
+	strictFloat           bool
+	pprofLabels           bool
+	maxBlockNestingDepth  uint32
+	engineKind            int
+	dwarfDisabled         bool
+	newEngine
+	// Other fields follow, but we don't care.
+}
+
 // ConfigureWazevo modifies wazero.RuntimeConfig and sets the wazevo implementation.
 // This is a hack to avoid modifying outside the wazevo package while testing it end-to-end.
 //
@@ -24,18 +44,6 @@ func ConfigureWazevo(config wazero.RuntimeConfig) {
 
 	configInterface := (*iface)(unsafe.Pointer(&config))
 
-	// This corresponds to the unexported wazero.runtimeConfig, and the target field newEngine exists
-	// in the middle of the implementation.
-	type newEngine func(context.Context, api.CoreFeatures, filecache.Cache) wasm.Engine
-	type runtimeConfig struct {
-		enabledFeatures       api.CoreFeatures
-		memoryLimitPages      uint32
-		memoryCapacityFromMax bool
-		engineKind            int
-		dwarfDisabled         bool
-		newEngine
-		// Other fields follow, but we don't care.
-	}
 	cm := (*runtimeConfig)(configInterface.data)
 	// Insert the wazevo implementation.
 	cm.newEngine = NewEngine
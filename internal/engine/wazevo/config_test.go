@@ -0,0 +1,32 @@
+package wazevo
+
+import (
+	"reflect"
+	"testing"
+	"unsafe"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func Test_runtimeConfigOffsets(t *testing.T) {
+	var cm runtimeConfig
+	actual := reflect.TypeOf(wazero.NewRuntimeConfigInterpreter()).Elem()
+	for name, offset := range map[string]uintptr{
+		"enabledFeatures":       unsafe.Offsetof(cm.enabledFeatures),
+		"memoryLimitPages":      unsafe.Offsetof(cm.memoryLimitPages),
+		"memoryCapacityFromMax": unsafe.Offsetof(cm.memoryCapacityFromMax),
+		"memoryZeroOnClose":     unsafe.Offsetof(cm.memoryZeroOnClose),
+		"memoryPrefault":        unsafe.Offsetof(cm.memoryPrefault),
+		"pprofLabels":           unsafe.Offsetof(cm.pprofLabels),
+		"strictFloat":           unsafe.Offsetof(cm.strictFloat),
+		"maxBlockNestingDepth":  unsafe.Offsetof(cm.maxBlockNestingDepth),
+		"engineKind":            unsafe.Offsetof(cm.engineKind),
+		"dwarfDisabled":         unsafe.Offsetof(cm.dwarfDisabled),
+		"newEngine":             unsafe.Offsetof(cm.newEngine),
+	} {
+		f, ok := actual.FieldByName(name)
+		require.True(t, ok, name)
+		require.Equal(t, f.Offset, offset, name)
+	}
+}
@@ -232,6 +232,14 @@ func TestE2E(t *testing.T) {
 				{params: []uint64{1000, 300}, expResults: []uint64{11 + 300}},
 			},
 		},
+		{
+			name: "block_with_param_results",
+			m:    testcases.BlockWithParamResults.Module,
+			calls: []callCase{
+				{params: []uint64{10, 3}, expResults: []uint64{7}},
+				{params: []uint64{100, 1}, expResults: []uint64{99}},
+			},
+		},
 		{
 			name: "multi_predecessor_local_ref",
 			m:    testcases.MultiPredecessorLocalRef.Module,
@@ -582,6 +590,211 @@ func TestE2E_reexported_memory(t *testing.T) {
 	require.Equal(t, uint32(11), mem.Size()/65536)
 }
 
+func TestE2E_exceptionHandling(t *testing.T) {
+	config := wazero.NewRuntimeConfigCompiler().
+		WithCoreFeatures(api.CoreFeaturesV2 | experimental.CoreFeaturesExceptionHandling)
+
+	// Configure the new optimizing backend!
+	wazevo.ConfigureWazevo(config)
+
+	type callCase struct {
+		funcName           string // defaults to testcases.ExportedFunctionName
+		params, expResults []uint64
+		expErr             string
+	}
+
+	for _, tc := range []struct {
+		name  string
+		m     *wasm.Module
+		calls []callCase
+	}{
+		{
+			name: "throw catch", m: testcases.ExceptionThrowCatch.Module,
+			calls: []callCase{
+				{params: []uint64{0}, expResults: []uint64{1}},
+				{params: []uint64{5}, expResults: []uint64{6}},
+			},
+		},
+		{
+			name: "propagation", m: testcases.ExceptionPropagation.Module,
+			calls: []callCase{
+				{params: []uint64{0}, expResults: []uint64{1007}},
+				{params: []uint64{5}, expResults: []uint64{105}},
+				{funcName: "throw", params: []uint64{0}, expResults: []uint64{7}},
+				{funcName: "throw", params: []uint64{5}, expErr: "uncaught exception"},
+				// The pending exception must not leak into the next call.
+				{params: []uint64{0}, expResults: []uint64{1007}},
+			},
+		},
+		{
+			name: "rethrow delegate", m: testcases.ExceptionRethrowDelegate.Module,
+			calls: []callCase{
+				{params: []uint64{0}, expResults: []uint64{7}},
+				{params: []uint64{5}, expResults: []uint64{105}},
+				{funcName: "delegate", params: []uint64{0}, expResults: []uint64{7}},
+				{funcName: "delegate", params: []uint64{5}, expResults: []uint64{105}},
+			},
+		},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			r := wazero.NewRuntimeWithConfig(ctx, config)
+			defer func() {
+				require.NoError(t, r.Close(ctx))
+			}()
+
+			inst, err := r.Instantiate(ctx, binaryencoding.EncodeModule(tc.m))
+			require.NoError(t, err)
+
+			for _, cc := range tc.calls {
+				name := cc.funcName
+				if name == "" {
+					name = testcases.ExportedFunctionName
+				}
+				f := inst.ExportedFunction(name)
+				require.NotNil(t, f)
+				result, err := f.Call(ctx, cc.params...)
+				if cc.expErr != "" {
+					require.Contains(t, err.Error(), cc.expErr)
+				} else {
+					require.NoError(t, err)
+					require.Equal(t, cc.expResults, result)
+				}
+			}
+		})
+	}
+
+	t.Run("host frame", func(t *testing.T) {
+		ctx := context.Background()
+		r := wazero.NewRuntimeWithConfig(ctx, config)
+		defer func() {
+			require.NoError(t, r.Close(ctx))
+		}()
+
+		// call_throw calls back into the "throw" function of the caller, so an exception thrown there would have
+		// to cross the host frame to reach the try block in "f".
+		_, err := r.NewHostModuleBuilder("env").
+			NewFunctionBuilder().WithFunc(func(ctx context.Context, m api.Module, x uint32) uint32 {
+			res, err := m.ExportedFunction("throw").Call(ctx, uint64(x))
+			if err != nil {
+				panic(err)
+			}
+			return uint32(res[0])
+		}).Export("call_throw").
+			Instantiate(ctx)
+		require.NoError(t, err)
+
+		i32_i32 := wasm.FunctionType{Params: []wasm.ValueType{i32}, Results: []wasm.ValueType{i32}}
+		m := &wasm.Module{
+			ImportFunctionCount: 1,
+			ImportSection:       []wasm.Import{{Module: "env", Name: "call_throw", Type: wasm.ExternTypeFunc, DescFunc: 0}},
+			TypeSection:         []wasm.FunctionType{i32_i32, {Params: []wasm.ValueType{i32}}},
+			FunctionSection:     []wasm.Index{0, 0},
+			TagSection:          []wasm.Index{1},
+			CodeSection: []wasm.Code{
+				{Body: []byte{
+					wasm.OpcodeTry, i32,
+					wasm.OpcodeLocalGet, 0,
+					wasm.OpcodeCall, 0,
+					wasm.OpcodeCatch, 0,
+					wasm.OpcodeI32Const, 0xe4, 0x00, // 100.
+					wasm.OpcodeI32Add,
+					wasm.OpcodeEnd,
+					wasm.OpcodeEnd,
+				}},
+				{Body: []byte{
+					wasm.OpcodeLocalGet, 0,
+					wasm.OpcodeI32Eqz,
+					wasm.OpcodeIf, i32,
+					wasm.OpcodeI32Const, 7,
+					wasm.OpcodeElse,
+					wasm.OpcodeLocalGet, 0,
+					wasm.OpcodeThrow, 0,
+					wasm.OpcodeEnd,
+					wasm.OpcodeEnd,
+				}},
+			},
+			ExportSection: []wasm.Export{
+				{Name: testcases.ExportedFunctionName, Type: wasm.ExternTypeFunc, Index: 1},
+				{Name: "throw", Type: wasm.ExternTypeFunc, Index: 2},
+			},
+		}
+
+		inst, err := r.Instantiate(ctx, binaryencoding.EncodeModule(m))
+		require.NoError(t, err)
+
+		f := inst.ExportedFunction(testcases.ExportedFunctionName)
+		result, err := f.Call(ctx, 0)
+		require.NoError(t, err)
+		require.Equal(t, []uint64{7}, result)
+
+		// The exception traps at the host frame, so the catch clause in "f" never sees it.
+		_, err = f.Call(ctx, 5)
+		require.Contains(t, err.Error(), "uncaught exception")
+	})
+}
+
+func TestE2E_canonicalNaN(t *testing.T) {
+	const (
+		// signalingNaNF32 is turned into a quiet NaN with the same payload by arithmetic.
+		signalingNaNF32 = 0x7f800001
+		negativeNaNF64  = 0xfff8000000000001
+	)
+
+	for _, tc := range []struct {
+		name         string
+		canonicalNaN bool
+		exp          []uint64
+	}{
+		{
+			name:         "enabled",
+			canonicalNaN: true,
+			exp: []uint64{
+				0x7fc00000,
+				// Negation only flips the sign bit, so isn't canonicalized.
+				0x7ff8000000000001,
+				// The lanes which aren't NaN are left as is.
+				uint64(math.Float32bits(4.0))<<32 | 0x7fc00000, 0x7fc00000<<32 | uint64(math.Float32bits(9.0)),
+			},
+		},
+		{
+			name: "disabled",
+			exp: []uint64{
+				0x7fc00001,
+				0x7ff8000000000001,
+				uint64(math.Float32bits(4.0))<<32 | 0x7fc00001, 0x7fc00001<<32 | uint64(math.Float32bits(9.0)),
+			},
+		},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			config := wazero.NewRuntimeConfigCompiler().WithCanonicalNaN(tc.canonicalNaN)
+
+			// Configure the new optimizing backend!
+			wazevo.ConfigureWazevo(config)
+
+			ctx := context.Background()
+			r := wazero.NewRuntimeWithConfig(ctx, config)
+			defer func() {
+				require.NoError(t, r.Close(ctx))
+			}()
+
+			inst, err := r.Instantiate(ctx, binaryencoding.EncodeModule(testcases.FloatCanonicalNaN.Module))
+			require.NoError(t, err)
+
+			f := inst.ExportedFunction(testcases.ExportedFunctionName)
+			result, err := f.Call(ctx,
+				signalingNaNF32,
+				negativeNaNF64,
+				uint64(math.Float32bits(2.0))<<32|signalingNaNF32, signalingNaNF32<<32|uint64(math.Float32bits(3.0)),
+			)
+			require.NoError(t, err)
+			require.Equal(t, tc.exp, result)
+		})
+	}
+}
+
 func TestStackUnwind_panic_in_host(t *testing.T) {
 	unreachable := &wasm.Module{
 		ImportFunctionCount: 1,
@@ -8,10 +8,12 @@ import (
 	"runtime"
 	"sort"
 	"sync"
+	"time"
 	"unsafe"
 
 	"github.com/tetratelabs/wazero/api"
 	"github.com/tetratelabs/wazero/experimental"
+	"github.com/tetratelabs/wazero/internal/compilation"
 	"github.com/tetratelabs/wazero/internal/engine/wazevo/backend"
 	"github.com/tetratelabs/wazero/internal/engine/wazevo/frontend"
 	"github.com/tetratelabs/wazero/internal/engine/wazevo/ssa"
@@ -20,6 +22,7 @@ import (
 	"github.com/tetratelabs/wazero/internal/platform"
 	"github.com/tetratelabs/wazero/internal/version"
 	"github.com/tetratelabs/wazero/internal/wasm"
+	"github.com/tetratelabs/wazero/internal/wasmruntime"
 )
 
 type (
@@ -65,10 +68,21 @@ type (
 	compiledModule struct {
 		*executables
 		// functionOffsets maps a local function index to the offset in the executable.
-		functionOffsets           []int
-		parent                    *engine
-		module                    *wasm.Module
-		ensureTermination         bool
+		functionOffsets   []int
+		parent            *engine
+		module            *wasm.Module
+		ensureTermination bool
+		// inliningThreshold is configured via compilation.InliningThresholdKey. See frontend.Compiler SetInliningThreshold.
+		inliningThreshold int
+		// canonicalNaN is configured via compilation.CanonicalNaNKey. See frontend.Compiler SetCanonicalNaN.
+		canonicalNaN bool
+		// metering is true if this is the variant compiled with frontend.Compiler SetMetering. See meteredVariant.
+		metering bool
+		// metered is the variant of this compiled module for instances which limit their call depth or fuel, or nil
+		// until one is instantiated. meteredErr is the error compiling it, and meteredOnce guards both.
+		metered                   *compiledModule
+		meteredErr                error
+		meteredOnce               sync.Once
 		listeners                 []experimental.FunctionListener
 		listenerBeforeTrampolines []*byte
 		listenerAfterTrampolines  []*byte
@@ -86,6 +100,87 @@ type (
 	}
 )
 
+// compilationHooks holds the experimental hooks configured via context.Context for compiling a module.
+// This is nil when none are configured, so that the default compilation doesn't have any overhead.
+type compilationHooks struct {
+	// scratch is non-nil when compilation.MemoryConfigKey is configured.
+	scratch *scratchTracker
+	// scratchObserver is compilation.MemoryConfig Observer, called with the peak usage after compilation.
+	scratchObserver func(peakBytes uint64)
+	// ssaDumper is non-nil when compilation.SSADumperKey is configured.
+	ssaDumper func(funcName, stage, ssaText string)
+	// symbolizer is non-nil when compilation.SymbolizerKey is configured.
+	symbolizer experimental.Symbolizer
+	// regAllocObserver is non-nil when compilation.RegAllocObserverKey is configured.
+	regAllocObserver func(funcName string, info *compilation.RegAllocInfo)
+	// timeout is non-zero when compilation.FunctionCompileTimeoutKey is configured.
+	timeout time.Duration
+	// funcName is the name of the currently compiled function, only resolved when a hook needs it.
+	funcName string
+}
+
+// newCompilationHooks returns the compilationHooks configured in ctx, or nil if there are none.
+func newCompilationHooks(ctx context.Context) *compilationHooks {
+	var hooks compilationHooks
+	if memConfig, ok := ctx.Value(compilation.MemoryConfigKey{}).(*compilation.MemoryConfig); ok {
+		hooks.scratch = &scratchTracker{limit: memConfig.Limit}
+		hooks.scratchObserver = memConfig.Observer
+	}
+	if dumper, ok := ctx.Value(compilation.SSADumperKey{}).(func(funcName, stage, ssaText string)); ok {
+		hooks.ssaDumper = dumper
+	}
+	if symbolizer, ok := ctx.Value(compilation.SymbolizerKey{}).(experimental.Symbolizer); ok {
+		hooks.symbolizer = symbolizer
+	}
+	if observer, ok := ctx.Value(compilation.RegAllocObserverKey{}).(func(funcName string, info *compilation.RegAllocInfo)); ok {
+		hooks.regAllocObserver = observer
+	}
+	if timeout, ok := ctx.Value(compilation.FunctionCompileTimeoutKey{}).(time.Duration); ok {
+		hooks.timeout = timeout
+	}
+	if hooks.scratch == nil && hooks.ssaDumper == nil && hooks.symbolizer == nil && hooks.regAllocObserver == nil && hooks.timeout == 0 {
+		return nil
+	}
+	return &hooks
+}
+
+// needFunctionName returns true if any of the hooks requires compilationHooks.funcName.
+func (h *compilationHooks) needFunctionName() bool {
+	return h.ssaDumper != nil || h.symbolizer != nil || h.regAllocObserver != nil
+}
+
+// symbolize passes the machine code of the current function to the symbolizer, with relocations whose offsets are
+// relative to the beginning of body.
+func (h *compilationHooks) symbolize(module *wasm.Module, body []byte, rels []backend.RelocationInfo) {
+	relocations := make([]experimental.Relocation, len(rels))
+	for i, r := range rels {
+		idx := wasm.Index(r.FuncRef)
+		relocations[i] = experimental.Relocation{
+			Offset:        uint64(r.Offset),
+			FunctionIndex: idx,
+			FunctionName:  functionName(module, idx),
+		}
+	}
+	h.symbolizer.Symbolize(h.funcName, body, relocations)
+}
+
+// scratchTracker tracks the scratch memory used while compiling functions against compilation.MemoryConfig.
+type scratchTracker struct {
+	limit, peak uint64
+}
+
+// check records usedBytes as the current scratch memory usage, and returns an error if it exceeds the limit.
+func (s *scratchTracker) check(usedBytes int) error {
+	used := uint64(usedBytes)
+	if used > s.peak {
+		s.peak = used
+	}
+	if s.limit > 0 && used > s.limit {
+		return fmt.Errorf("scratch memory %d bytes exceeds the limit of %d bytes", used, s.limit)
+	}
+	return nil
+}
+
 // sourceMap is a mapping from the offset of the executable to the offset of the original wasm binary.
 type sourceMap struct {
 	// executableOffsets is a sorted list of offsets of the executable. This is index-correlated with wasmBinaryOffsets,
@@ -96,7 +191,12 @@ type sourceMap struct {
 	wasmBinaryOffsets []uint64
 }
 
-var _ wasm.Engine = (*engine)(nil)
+var (
+	_ wasm.Engine             = (*engine)(nil)
+	_ wasm.Disassembler       = (*engine)(nil)
+	_ wasm.BoundsCheckCounter = (*engine)(nil)
+	_ wasm.TrapSiteLister     = (*engine)(nil)
+)
 
 // NewEngine returns the implementation of wasm.Engine.
 func NewEngine(ctx context.Context, _ api.CoreFeatures, fc filecache.Cache) wasm.Engine {
@@ -122,10 +222,19 @@ func (e *engine) CompileModule(ctx context.Context, module *wasm.Module, listene
 		return err
 	}
 
+	if len(module.AdditionalMemorySection) > 0 {
+		return errors.New("multiple memories require the interpreter: use wazero.NewRuntimeConfigInterpreter")
+	}
+	for i := wasm.Index(0); i < module.ImportTagCount+wasm.Index(len(module.TagSection)); i++ {
+		if frontend.TagPayloadSlots(module.TagType(i)) > wazevoapi.ExceptionPayloadSlots {
+			return fmt.Errorf("tag[%d] carries more values than supported by the compiler", i)
+		}
+	}
+
 	if wazevoapi.DeterministicCompilationVerifierEnabled {
 		ctx = wazevoapi.NewDeterministicCompilationVerifierContext(ctx, len(module.CodeSection))
 	}
-	cm, err := e.compileModule(ctx, module, listeners, ensureTermination)
+	cm, err := e.compileModule(ctx, module, listeners, ensureTermination, false)
 	if err != nil {
 		return err
 	}
@@ -135,7 +244,7 @@ func (e *engine) CompileModule(ctx context.Context, module *wasm.Module, listene
 
 	if wazevoapi.DeterministicCompilationVerifierEnabled {
 		for i := 0; i < wazevoapi.DeterministicCompilationVerifyingIter; i++ {
-			_, err := e.compileModule(ctx, module, listeners, ensureTermination)
+			_, err := e.compileModule(ctx, module, listeners, ensureTermination, false)
 			if err != nil {
 				return err
 			}
@@ -168,14 +277,21 @@ func (exec *executables) compileEntryPreambles(m *wasm.Module, machine backend.M
 	}
 }
 
-func (e *engine) compileModule(ctx context.Context, module *wasm.Module, listeners []experimental.FunctionListener, ensureTermination bool) (*compiledModule, error) {
+func (e *engine) compileModule(ctx context.Context, module *wasm.Module, listeners []experimental.FunctionListener, ensureTermination, metering bool) (*compiledModule, error) {
 	withListener := len(listeners) > 0
 	e.rels = e.rels[:0]
 	cm := &compiledModule{
 		offsets: wazevoapi.NewModuleContextOffsetData(module, withListener), parent: e, module: module,
 		ensureTermination: ensureTermination,
+		metering:          metering,
 		executables:       &executables{},
 	}
+	if threshold, ok := ctx.Value(compilation.InliningThresholdKey{}).(int); ok {
+		cm.inliningThreshold = threshold
+	}
+	if canonicalNaN, ok := ctx.Value(compilation.CanonicalNaNKey{}).(bool); ok {
+		cm.canonicalNaN = canonicalNaN
+	}
 
 	if module.IsHostModule {
 		return e.compileHostModule(ctx, module, listeners)
@@ -193,9 +309,17 @@ func (e *engine) compileModule(ctx context.Context, module *wasm.Module, listene
 
 	needSourceInfo := module.DWARFLines != nil
 
+	hooks := newCompilationHooks(ctx)
+	if hooks != nil && hooks.scratchObserver != nil {
+		defer func() { hooks.scratchObserver(hooks.scratch.peak) }()
+	}
+
 	// Creates new compiler instances which are reused for each function.
 	ssaBuilder := ssa.NewBuilder()
 	fe := frontend.NewFrontendCompiler(module, ssaBuilder, &cm.offsets, ensureTermination, withListener, needSourceInfo)
+	fe.SetMetering(metering)
+	fe.SetInliningThreshold(cm.inliningThreshold)
+	fe.SetCanonicalNaN(cm.canonicalNaN)
 	machine := newMachine()
 	be := backend.NewCompiler(ctx, machine, ssaBuilder)
 
@@ -212,19 +336,21 @@ func (e *engine) compileModule(ctx context.Context, module *wasm.Module, listene
 		fidx := wasm.Index(i + importedFns)
 
 		if wazevoapi.NeedFunctionNameInContext {
-			def := module.FunctionDefinition(fidx)
-			name := def.DebugName()
-			if len(def.ExportNames()) > 0 {
-				name = def.ExportNames()[0]
-			}
+			name := functionName(module, fidx)
 			ctx = wazevoapi.SetCurrentFunctionName(ctx, fmt.Sprintf("[%d/%d] \"%s\"", i, len(module.CodeSection)-1, name))
 		}
+		if hooks != nil && hooks.needFunctionName() {
+			hooks.funcName = functionName(module, fidx)
+		}
 
 		needListener := len(listeners) > 0 && listeners[i] != nil
-		body, rels, err := e.compileLocalWasmFunction(ctx, module, wasm.Index(i), fe, ssaBuilder, be, needListener)
+		body, rels, err := e.compileLocalWasmFunction(ctx, module, wasm.Index(i), fe, ssaBuilder, be, needListener, hooks)
 		if err != nil {
 			return nil, fmt.Errorf("compile function %d/%d: %v", i, len(module.CodeSection)-1, err)
 		}
+		if hooks != nil && hooks.symbolizer != nil {
+			hooks.symbolize(module, body, rels)
+		}
 
 		// Align 16-bytes boundary.
 		totalSize = (totalSize + 15) &^ 15
@@ -291,6 +417,41 @@ func (e *engine) compileModule(ctx context.Context, module *wasm.Module, listene
 	return cm, nil
 }
 
+// meteredVariant returns the variant of cm whose functions count their nesting and consume fuel, compiling it on first
+// use. Otherwise, instances which limit neither their call depth nor their fuel would pay for the checks.
+func (e *engine) meteredVariant(cm *compiledModule) (*compiledModule, error) {
+	cm.meteredOnce.Do(func() {
+		ctx := context.Background()
+		if cm.canonicalNaN {
+			ctx = context.WithValue(ctx, compilation.CanonicalNaNKey{}, true)
+		}
+		metered, err := e.compileModule(ctx, cm.module, cm.listeners, cm.ensureTermination, true)
+		if err != nil {
+			cm.meteredErr = err
+			return
+		}
+		metered.listeners = cm.listeners
+		metered.listenerBeforeTrampolines = cm.listenerBeforeTrampolines
+		metered.listenerAfterTrampolines = cm.listenerAfterTrampolines
+		if len(metered.executable) > 0 {
+			e.mux.Lock()
+			e.addCompiledModuleToSortedList(metered)
+			e.mux.Unlock()
+		}
+		cm.metered = metered
+	})
+	return cm.metered, cm.meteredErr
+}
+
+// functionName returns the name of the function used for debugging, preferring the first export name if any.
+func functionName(module *wasm.Module, fidx wasm.Index) string {
+	def := module.FunctionDefinition(fidx)
+	if names := def.ExportNames(); len(names) > 0 {
+		return names[0]
+	}
+	return def.DebugName()
+}
+
 func (e *engine) compileLocalWasmFunction(
 	ctx context.Context,
 	module *wasm.Module,
@@ -299,6 +460,7 @@ func (e *engine) compileLocalWasmFunction(
 	ssaBuilder ssa.Builder,
 	be backend.Compiler,
 	needListener bool,
+	hooks *compilationHooks,
 ) (body []byte, rels []backend.RelocationInfo, err error) {
 	typIndex := module.FunctionSection[localFunctionIndex]
 	typ := &module.TypeSection[typIndex]
@@ -307,9 +469,29 @@ func (e *engine) compileLocalWasmFunction(
 	// Initializes both frontend and backend compilers.
 	fe.Init(localFunctionIndex, typIndex, typ, codeSeg.LocalTypes, codeSeg.Body, needListener, codeSeg.BodyOffsetInCodeSection)
 	be.Init()
+	if hooks != nil && hooks.timeout > 0 {
+		deadline := time.Now().Add(hooks.timeout)
+		ssaBuilder.SetDeadline(deadline)
+		be.SetDeadline(deadline)
+		defer func() {
+			if errors.Is(err, wazevoapi.ErrDeadlineExceeded) {
+				err = fmt.Errorf("compilation exceeded the timeout of %s", hooks.timeout)
+			}
+		}()
+	}
 
 	// Lower Wasm to SSA.
 	fe.LowerToSSA()
+	if hooks != nil {
+		if hooks.scratch != nil {
+			if err = hooks.scratch.check(ssaBuilder.ScratchBytes()); err != nil {
+				return nil, nil, err
+			}
+		}
+		if hooks.ssaDumper != nil {
+			hooks.ssaDumper(hooks.funcName, compilation.SSAStageLowered, ssaBuilder.Format())
+		}
+	}
 	if wazevoapi.PrintSSA {
 		fmt.Printf("[[[SSA for %s]]]%s\n", wazevoapi.GetCurrentFunctionName(ctx), ssaBuilder.Format())
 	}
@@ -319,8 +501,13 @@ func (e *engine) compileLocalWasmFunction(
 	}
 
 	// Run SSA-level optimization passes.
-	ssaBuilder.RunPasses()
+	if err = ssaBuilder.RunPasses(); err != nil {
+		return nil, nil, err
+	}
 
+	if hooks != nil && hooks.ssaDumper != nil {
+		hooks.ssaDumper(hooks.funcName, compilation.SSAStageOptimized, ssaBuilder.Format())
+	}
 	if wazevoapi.PrintOptimizedSSA {
 		fmt.Printf("[[[Optimized SSA for %s]]]%s\n", wazevoapi.GetCurrentFunctionName(ctx), ssaBuilder.Format())
 	}
@@ -343,9 +530,21 @@ func (e *engine) compileLocalWasmFunction(
 	// Now our ssaBuilder contains the necessary information to further lower them to
 	// machine code.
 	original, rels, err := be.Compile(ctx)
-	if err != nil {
+	if errors.Is(err, wazevoapi.ErrDeadlineExceeded) {
+		return nil, nil, err
+	} else if err != nil {
 		return nil, nil, fmt.Errorf("ssa->machine code: %v", err)
 	}
+	if hooks != nil {
+		if hooks.scratch != nil {
+			if err = hooks.scratch.check(ssaBuilder.ScratchBytes() + be.ScratchBytes()); err != nil {
+				return nil, nil, err
+			}
+		}
+		if hooks.regAllocObserver != nil {
+			hooks.regAllocObserver(hooks.funcName, be.RegAllocInfo())
+		}
+	}
 
 	// TODO: optimize as zero copy.
 	copied := make([]byte, len(original))
@@ -353,6 +552,184 @@ func (e *engine) compileLocalWasmFunction(
 	return copied, rels, nil
 }
 
+// Disassemble implements wasm.Disassembler.
+//
+// The compiled module only retains the machine code, so this compiles the function again with the same options.
+func (e *engine) Disassemble(module *wasm.Module, funcIdx wasm.Index) ([]wasm.MachineInstruction, error) {
+	if module.IsHostModule {
+		return nil, errors.New("host functions cannot be disassembled")
+	}
+	cm, localIdx, err := e.compiledLocalFunction(module, funcIdx)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	withListener := len(cm.listeners) > 0
+	ssaBuilder := ssa.NewBuilder()
+	fe := frontend.NewFrontendCompiler(module, ssaBuilder, &cm.offsets, cm.ensureTermination, withListener, module.DWARFLines != nil)
+	fe.SetInliningThreshold(cm.inliningThreshold)
+	fe.SetCanonicalNaN(cm.canonicalNaN)
+	machine := newMachine()
+	be := backend.NewCompiler(ctx, machine, ssaBuilder)
+	needListener := withListener && cm.listeners[localIdx] != nil
+	if _, _, err := e.compileLocalWasmFunction(ctx, module, localIdx, fe, ssaBuilder, be, needListener, nil); err != nil {
+		return nil, fmt.Errorf("compile function[%d]: %v", funcIdx, err)
+	}
+
+	instrs := machine.Disassemble()
+	ret := make([]wasm.MachineInstruction, len(instrs))
+	for i, instr := range instrs {
+		ret[i] = wasm.MachineInstruction{
+			Address:  uint64(instr.Offset),
+			Size:     uint32(instr.Size),
+			Mnemonic: instr.Mnemonic,
+			Operands: instr.Operands,
+		}
+	}
+	return ret, nil
+}
+
+// BoundsCheckStats implements wasm.BoundsCheckCounter.
+//
+// Bounds checks are decided when lowering to SSA, so this only lowers the function again, without compiling it.
+func (e *engine) BoundsCheckStats(module *wasm.Module, funcIdx wasm.Index) (wasm.BoundsCheckStats, error) {
+	if module.IsHostModule {
+		return wasm.BoundsCheckStats{}, errors.New("host functions have no bounds checks")
+	}
+	cm, localIdx, err := e.compiledLocalFunction(module, funcIdx)
+	if err != nil {
+		return wasm.BoundsCheckStats{}, err
+	}
+
+	withListener := len(cm.listeners) > 0
+	fe := frontend.NewFrontendCompiler(module, ssa.NewBuilder(), &cm.offsets, cm.ensureTermination, withListener, module.DWARFLines != nil)
+	fe.SetInliningThreshold(cm.inliningThreshold)
+	fe.SetCanonicalNaN(cm.canonicalNaN)
+	typIndex := module.FunctionSection[localIdx]
+	codeSeg := &module.CodeSection[localIdx]
+	needListener := withListener && cm.listeners[localIdx] != nil
+	fe.Init(localIdx, typIndex, &module.TypeSection[typIndex], codeSeg.LocalTypes, codeSeg.Body, needListener, codeSeg.BodyOffsetInCodeSection)
+	fe.LowerToSSA()
+
+	emitted, eliminated := fe.BoundsCheckStats()
+	return wasm.BoundsCheckStats{Emitted: emitted, Eliminated: eliminated}, nil
+}
+
+// TrapSites implements wasm.TrapSiteLister.
+//
+// Traps are decided when lowering to SSA, except the checks of integer division and conversion the backend emits for
+// their instructions, so this only lowers the function again, without inlining calls. Instructions inserted at the
+// entry of the function have no offset, so they are attributed to the beginning of its body.
+func (e *engine) TrapSites(module *wasm.Module, funcIdx wasm.Index) ([]wasm.TrapSite, error) {
+	if module.IsHostModule {
+		return nil, errors.New("host functions have no trap sites")
+	}
+	cm, localIdx, err := e.compiledLocalFunction(module, funcIdx)
+	if err != nil {
+		return nil, err
+	}
+
+	withListener := len(cm.listeners) > 0
+	ssaBuilder := ssa.NewBuilder()
+	fe := frontend.NewFrontendCompiler(module, ssaBuilder, &cm.offsets, cm.ensureTermination, withListener, true)
+	fe.SetCanonicalNaN(cm.canonicalNaN)
+	typIndex := module.FunctionSection[localIdx]
+	codeSeg := &module.CodeSection[localIdx]
+	needListener := withListener && cm.listeners[localIdx] != nil
+	fe.Init(localIdx, typIndex, &module.TypeSection[typIndex], codeSeg.LocalTypes, codeSeg.Body, needListener, codeSeg.BodyOffsetInCodeSection)
+	fe.LowerToSSA()
+
+	var ret []wasm.TrapSite
+	for blk := ssaBuilder.BlockIteratorBegin(); blk != nil; blk = ssaBuilder.BlockIteratorNext() {
+		for instr := blk.Root(); instr != nil; instr = instr.Next() {
+			offset := codeSeg.BodyOffsetInCodeSection
+			if o := instr.SourceOffset(); o.Valid() {
+				offset = uint64(o)
+			}
+			for _, cause := range instructionTraps(instr) {
+				ret = append(ret, wasm.TrapSite{Offset: offset, Cause: cause})
+			}
+		}
+	}
+
+	// Blocks aren't in the order of the body, and an instruction can check the same condition more than once, e.g.
+	// memory.copy checks the bounds of both its source and destination.
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].Offset != ret[j].Offset {
+			return ret[i].Offset < ret[j].Offset
+		}
+		return ret[i].Cause.Error() < ret[j].Cause.Error()
+	})
+	deduped := ret[:0]
+	for i, site := range ret {
+		if i == 0 || site != ret[i-1] {
+			deduped = append(deduped, site)
+		}
+	}
+	return deduped, nil
+}
+
+// instructionTraps returns the errors of the traps instr can raise.
+func instructionTraps(instr *ssa.Instruction) []*wasmruntime.Error {
+	var code wazevoapi.ExitCode
+	switch instr.Opcode() {
+	case ssa.OpcodeExitWithCode:
+		_, code = instr.ExitWithCodeData()
+	case ssa.OpcodeExitIfTrueWithCode:
+		_, _, code = instr.ExitIfTrueWithCodeData()
+	case ssa.OpcodeUdiv, ssa.OpcodeUrem, ssa.OpcodeSrem:
+		return []*wasmruntime.Error{wasmruntime.ErrRuntimeIntegerDivideByZero}
+	case ssa.OpcodeSdiv:
+		return []*wasmruntime.Error{wasmruntime.ErrRuntimeIntegerDivideByZero, wasmruntime.ErrRuntimeIntegerOverflow}
+	case ssa.OpcodeFcvtToUint, ssa.OpcodeFcvtToSint:
+		return []*wasmruntime.Error{wasmruntime.ErrRuntimeInvalidConversionToInteger, wasmruntime.ErrRuntimeIntegerOverflow}
+	default:
+		return nil
+	}
+
+	// These correspond to the errors raised by callEngine.callWithStack.
+	switch code {
+	case wazevoapi.ExitCodeUnreachable:
+		return []*wasmruntime.Error{wasmruntime.ErrRuntimeUnreachable}
+	case wazevoapi.ExitCodeMemoryOutOfBounds:
+		return []*wasmruntime.Error{wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess}
+	case wazevoapi.ExitCodeTableOutOfBounds, wazevoapi.ExitCodeIndirectCallNullPointer:
+		return []*wasmruntime.Error{wasmruntime.ErrRuntimeInvalidTableAccess}
+	case wazevoapi.ExitCodeIndirectCallTypeMismatch:
+		return []*wasmruntime.Error{wasmruntime.ErrRuntimeIndirectCallTypeMismatch}
+	case wazevoapi.ExitCodeIntegerOverflow:
+		return []*wasmruntime.Error{wasmruntime.ErrRuntimeIntegerOverflow}
+	case wazevoapi.ExitCodeIntegerDivisionByZero:
+		return []*wasmruntime.Error{wasmruntime.ErrRuntimeIntegerDivideByZero}
+	case wazevoapi.ExitCodeInvalidConversionToInteger:
+		return []*wasmruntime.Error{wasmruntime.ErrRuntimeInvalidConversionToInteger}
+	case wazevoapi.ExitCodeUnalignedAtomic:
+		return []*wasmruntime.Error{wasmruntime.ErrRuntimeUnalignedAtomic}
+	case wazevoapi.ExitCodeCallStackExhausted:
+		return []*wasmruntime.Error{wasmruntime.ErrRuntimeCallStackExhausted}
+	default: // Not a trap, e.g. a call to a host function.
+		return nil
+	}
+}
+
+// compiledLocalFunction returns the compiled module and the local index of the function at funcIdx, which must be
+// defined in the module.
+func (e *engine) compiledLocalFunction(module *wasm.Module, funcIdx wasm.Index) (*compiledModule, wasm.Index, error) {
+	cm, ok := e.getCompiledModuleFromMemory(module)
+	if !ok {
+		return nil, 0, errors.New("module is not compiled")
+	}
+	if funcIdx < module.ImportFunctionCount {
+		return nil, 0, fmt.Errorf("function[%d] is imported", funcIdx)
+	}
+	localIdx := funcIdx - module.ImportFunctionCount
+	if int(localIdx) >= len(module.CodeSection) {
+		return nil, 0, fmt.Errorf("function[%d] does not exist", funcIdx)
+	}
+	return cm, localIdx, nil
+}
+
 func (e *engine) compileHostModule(ctx context.Context, module *wasm.Module, listeners []experimental.FunctionListener) (*compiledModule, error) {
 	machine := newMachine()
 	be := backend.NewCompiler(ctx, machine, ssa.NewBuilder())
@@ -473,6 +850,9 @@ func (e *engine) DeleteCompiledModule(m *wasm.Module) {
 		if len(cm.executable) > 0 {
 			e.deleteCompiledModuleFromSortedList(cm)
 		}
+		if metered := cm.metered; metered != nil && len(metered.executable) > 0 {
+			e.deleteCompiledModuleFromSortedList(metered)
+		}
 		delete(e.compiledModules, m.ID)
 	}
 }
@@ -526,6 +906,12 @@ func (e *engine) NewModuleEngine(m *wasm.Module, mi *wasm.ModuleInstance) (wasm.
 	if !ok {
 		return nil, errors.New("source module must be compiled before instantiation")
 	}
+	if _, fuelLimited := mi.Fuel(); !m.IsHostModule && (mi.MaxCallDepth() != 0 || fuelLimited) {
+		var err error
+		if compiled, err = e.meteredVariant(compiled); err != nil {
+			return nil, err
+		}
+	}
 	me.parent = compiled
 	me.module = mi
 	me.listeners = compiled.listeners
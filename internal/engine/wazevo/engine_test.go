@@ -4,12 +4,18 @@ import (
 	"context"
 	"fmt"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 	"unsafe"
 
+	"github.com/tetratelabs/wazero/experimental"
+	"github.com/tetratelabs/wazero/internal/compilation"
 	"github.com/tetratelabs/wazero/internal/platform"
+	"github.com/tetratelabs/wazero/internal/sys"
 	"github.com/tetratelabs/wazero/internal/testing/require"
 	"github.com/tetratelabs/wazero/internal/wasm"
+	"github.com/tetratelabs/wazero/internal/wasmruntime"
 )
 
 func Test_sharedFunctionsFinalizer(t *testing.T) {
@@ -91,6 +97,380 @@ func TestEngine_CompileModule(t *testing.T) {
 	}
 }
 
+func TestEngine_NewModuleEngine_metered(t *testing.T) {
+	ctx := context.Background()
+	e := NewEngine(ctx, 0, nil).(*engine)
+
+	m := &wasm.Module{
+		TypeSection:     []wasm.FunctionType{{}},
+		FunctionSection: []wasm.Index{0, 0},
+		CodeSection: []wasm.Code{
+			{Body: []byte{wasm.OpcodeEnd}},
+			{Body: []byte{wasm.OpcodeCall, 0, wasm.OpcodeEnd}},
+		},
+		ID: wasm.ModuleID{8},
+	}
+	err := e.CompileModule(ctx, m, nil, false)
+	require.NoError(t, err)
+	cm := e.compiledModules[m.ID]
+	require.Equal(t, 1, len(e.sortedCompiledModules))
+
+	// Without a call depth limit, the module is instantiated without counting calls.
+	me, err := e.NewModuleEngine(m, &wasm.ModuleInstance{Sys: sys.DefaultContext(nil)})
+	require.NoError(t, err)
+	require.Equal(t, cm, me.(*moduleEngine).parent)
+	require.Nil(t, cm.metered)
+
+	// With one, the metered variant is compiled once and shared between instances.
+	limited := sys.DefaultContext(nil)
+	limited.SetMaxCallDepth(10)
+	for i := 0; i < 2; i++ {
+		me, err = e.NewModuleEngine(m, &wasm.ModuleInstance{Sys: limited})
+		require.NoError(t, err)
+		require.NotNil(t, cm.metered)
+		require.Equal(t, cm.metered, me.(*moduleEngine).parent)
+		require.True(t, cm.metered.metering)
+		require.Equal(t, 2, len(e.sortedCompiledModules))
+	}
+
+	// Limiting the fuel also uses the metered variant.
+	fuelLimited := sys.DefaultContext(nil)
+	fuelLimited.SetFuel(10)
+	me, err = e.NewModuleEngine(m, &wasm.ModuleInstance{Sys: fuelLimited})
+	require.NoError(t, err)
+	require.Equal(t, cm.metered, me.(*moduleEngine).parent)
+
+	e.DeleteCompiledModule(m)
+	require.Equal(t, 0, len(e.sortedCompiledModules))
+}
+
+func TestEngine_CompileModule_memoryLimit(t *testing.T) {
+	// Build a module whose function body is large enough to require multiple pages of pooled instructions.
+	var body []byte
+	for i := 0; i < 1000; i++ {
+		body = append(body, wasm.OpcodeI32Const, 1, wasm.OpcodeDrop)
+	}
+	body = append(body, wasm.OpcodeEnd)
+	newModule := func(id byte) *wasm.Module {
+		return &wasm.Module{
+			TypeSection:     []wasm.FunctionType{{}},
+			FunctionSection: []wasm.Index{0},
+			CodeSection:     []wasm.Code{{Body: body}},
+			ID:              wasm.ModuleID{id},
+		}
+	}
+
+	t.Run("observed", func(t *testing.T) {
+		e := NewEngine(ctx, 0, nil).(*engine)
+		var peak uint64
+		ctx := experimental.WithCompilationMemoryLimit(context.Background(), 0, func(peakBytes uint64) {
+			peak = peakBytes
+		})
+		err := e.CompileModule(ctx, newModule(1), nil, false)
+		require.NoError(t, err)
+		require.NotEqual(t, uint64(0), peak)
+	})
+
+	t.Run("exceeded", func(t *testing.T) {
+		e := NewEngine(ctx, 0, nil).(*engine)
+		var peak uint64
+		ctx := experimental.WithCompilationMemoryLimit(context.Background(), 1024, func(peakBytes uint64) {
+			peak = peakBytes
+		})
+		err := e.CompileModule(ctx, newModule(2), nil, false)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "compile function 0/0: scratch memory")
+		require.Contains(t, err.Error(), "exceeds the limit of 1024 bytes")
+		require.True(t, peak > 1024)
+		require.Equal(t, uint32(0), e.CompiledModuleCount())
+	})
+}
+
+func TestEngine_CompileModule_ssaDumper(t *testing.T) {
+	m := &wasm.Module{
+		TypeSection:     []wasm.FunctionType{{}},
+		FunctionSection: []wasm.Index{0},
+		CodeSection:     []wasm.Code{{Body: []byte{wasm.OpcodeEnd}}},
+		ExportSection:   []wasm.Export{{Name: "empty", Type: wasm.ExternTypeFunc, Index: 0}},
+		Exports:         map[string]*wasm.Export{"empty": {Name: "empty", Type: wasm.ExternTypeFunc, Index: 0}},
+		ID:              wasm.ModuleID{3},
+	}
+
+	e := NewEngine(ctx, 0, nil).(*engine)
+	var dumped []string
+	ctx := context.WithValue(context.Background(), compilation.SSADumperKey{},
+		func(funcName, stage, ssaText string) {
+			require.NotEqual(t, "", ssaText)
+			dumped = append(dumped, funcName+":"+stage)
+		})
+	err := e.CompileModule(ctx, m, nil, false)
+	require.NoError(t, err)
+	require.Equal(t, []string{"empty:lowered", "empty:optimized"}, dumped)
+}
+
+func TestEngine_CompileModule_regAllocObserver(t *testing.T) {
+	// Keeps more values live at once than there are registers, by pushing them all before adding them up.
+	const values = 40
+	var body []byte
+	for i := 0; i < values; i++ {
+		body = append(body, wasm.OpcodeLocalGet, 0, wasm.OpcodeI32Const, byte(i+1), wasm.OpcodeI32Add)
+	}
+	for i := 1; i < values; i++ {
+		body = append(body, wasm.OpcodeI32Add)
+	}
+	i32 := wasm.ValueTypeI32
+	m := &wasm.Module{
+		TypeSection:     []wasm.FunctionType{{}, {Params: []wasm.ValueType{i32}, Results: []wasm.ValueType{i32}}},
+		FunctionSection: []wasm.Index{0, 1},
+		CodeSection:     []wasm.Code{{Body: []byte{wasm.OpcodeEnd}}, {Body: append(body, wasm.OpcodeEnd)}},
+		ExportSection: []wasm.Export{
+			{Name: "empty", Type: wasm.ExternTypeFunc, Index: 0},
+			{Name: "pressure", Type: wasm.ExternTypeFunc, Index: 1},
+		},
+		Exports: map[string]*wasm.Export{
+			"empty":    {Name: "empty", Type: wasm.ExternTypeFunc, Index: 0},
+			"pressure": {Name: "pressure", Type: wasm.ExternTypeFunc, Index: 1},
+		},
+		ID: wasm.ModuleID{6},
+	}
+
+	e := NewEngine(ctx, 0, nil).(*engine)
+	infos := map[string]*compilation.RegAllocInfo{}
+	ctx := context.WithValue(context.Background(), compilation.RegAllocObserverKey{},
+		func(funcName string, info *compilation.RegAllocInfo) {
+			infos[funcName] = info
+		})
+	err := e.CompileModule(ctx, m, nil, false)
+	require.NoError(t, err)
+	require.Equal(t, 2, len(infos))
+
+	empty := infos["empty"]
+	require.Equal(t, 0, empty.Spills)
+	require.Equal(t, 0, len(empty.SpilledRanges))
+
+	pressure := infos["pressure"]
+	require.True(t, pressure.VirtualRegisters > values)
+	require.True(t, pressure.Spills > 0)
+	require.True(t, len(pressure.SpilledRanges) > 0)
+	for _, r := range pressure.SpilledRanges {
+		require.True(t, r.Begin <= r.End || r.LiveOut)
+	}
+}
+
+func TestEngine_CompileModule_timeout(t *testing.T) {
+	m := &wasm.Module{
+		TypeSection:     []wasm.FunctionType{{}},
+		FunctionSection: []wasm.Index{0, 0},
+		CodeSection: []wasm.Code{
+			{Body: []byte{wasm.OpcodeEnd}},
+			{Body: []byte{wasm.OpcodeCall, 0, wasm.OpcodeEnd}},
+		},
+		ID: wasm.ModuleID{7},
+	}
+
+	t.Run("exceeded", func(t *testing.T) {
+		e := NewEngine(ctx, 0, nil).(*engine)
+		ctx := context.WithValue(context.Background(), compilation.FunctionCompileTimeoutKey{}, time.Nanosecond)
+		err := e.CompileModule(ctx, m, nil, false)
+		require.EqualError(t, err, "compile function 0/1: compilation exceeded the timeout of 1ns")
+	})
+
+	t.Run("within", func(t *testing.T) {
+		e := NewEngine(ctx, 0, nil).(*engine)
+		ctx := context.WithValue(context.Background(), compilation.FunctionCompileTimeoutKey{}, time.Hour)
+		err := e.CompileModule(ctx, m, nil, false)
+		require.NoError(t, err)
+	})
+}
+
+type symbolized struct {
+	funcName    string
+	code        []byte
+	relocations []experimental.Relocation
+}
+
+// fakeSymbolizer implements experimental.Symbolizer.
+type fakeSymbolizer struct {
+	symbolized []symbolized
+}
+
+// Symbolize implements the same method as documented on experimental.Symbolizer.
+func (s *fakeSymbolizer) Symbolize(funcName string, code []byte, relocations []experimental.Relocation) {
+	s.symbolized = append(s.symbolized, symbolized{funcName, code, relocations})
+}
+
+func TestEngine_CompileModule_symbolizer(t *testing.T) {
+	m := &wasm.Module{
+		TypeSection:     []wasm.FunctionType{{}},
+		FunctionSection: []wasm.Index{0, 0},
+		CodeSection: []wasm.Code{
+			{Body: []byte{wasm.OpcodeEnd}},
+			{Body: []byte{wasm.OpcodeCall, 0, wasm.OpcodeEnd}},
+		},
+		ExportSection: []wasm.Export{
+			{Name: "callee", Type: wasm.ExternTypeFunc, Index: 0},
+			{Name: "caller", Type: wasm.ExternTypeFunc, Index: 1},
+		},
+		Exports: map[string]*wasm.Export{
+			"callee": {Name: "callee", Type: wasm.ExternTypeFunc, Index: 0},
+			"caller": {Name: "caller", Type: wasm.ExternTypeFunc, Index: 1},
+		},
+		ID: wasm.ModuleID{4},
+	}
+
+	e := NewEngine(ctx, 0, nil).(*engine)
+	s := &fakeSymbolizer{}
+	err := e.CompileModule(experimental.WithSymbolizer(context.Background(), s), m, nil, false)
+	require.NoError(t, err)
+
+	cm, ok := e.compiledModules[m.ID]
+	require.True(t, ok)
+
+	require.Equal(t, 2, len(s.symbolized))
+	for i, expectedName := range []string{"callee", "caller"} {
+		sym := s.symbolized[i]
+		require.Equal(t, expectedName, sym.funcName)
+		// The code is the region of the function within the executable.
+		require.NotEqual(t, 0, len(sym.code))
+		require.True(t, len(sym.code) <= len(cm.executable)-cm.functionOffsets[i])
+	}
+	require.Equal(t, 0, len(s.symbolized[0].relocations))
+	relocations := s.symbolized[1].relocations
+	require.Equal(t, 1, len(relocations))
+	require.Equal(t, uint32(0), relocations[0].FunctionIndex)
+	require.Equal(t, "callee", relocations[0].FunctionName)
+	require.True(t, relocations[0].Offset < uint64(len(s.symbolized[1].code)))
+}
+
+func TestEngine_Disassemble(t *testing.T) {
+	i32 := wasm.ValueTypeI32
+	m := &wasm.Module{
+		TypeSection:         []wasm.FunctionType{{Params: []wasm.ValueType{i32, i32}, Results: []wasm.ValueType{i32}}},
+		ImportSection:       []wasm.Import{{Type: wasm.ExternTypeFunc, DescFunc: 0}},
+		ImportFunctionCount: 1,
+		FunctionSection:     []wasm.Index{0},
+		CodeSection: []wasm.Code{
+			{Body: []byte{wasm.OpcodeLocalGet, 0, wasm.OpcodeLocalGet, 1, wasm.OpcodeI32Add, wasm.OpcodeEnd}},
+		},
+		ID: wasm.ModuleID{5},
+	}
+
+	e := NewEngine(ctx, 0, nil).(*engine)
+	_, err := e.Disassemble(m, 1)
+	require.EqualError(t, err, "module is not compiled")
+
+	s := &fakeSymbolizer{}
+	err = e.CompileModule(experimental.WithSymbolizer(context.Background(), s), m, nil, false)
+	require.NoError(t, err)
+
+	_, err = e.Disassemble(m, 0)
+	require.EqualError(t, err, "function[0] is imported")
+	_, err = e.Disassemble(m, 2)
+	require.EqualError(t, err, "function[2] does not exist")
+
+	instrs, err := e.Disassemble(m, 1)
+	require.NoError(t, err)
+
+	// The instructions cover the machine code of the function without gaps.
+	var address uint64
+	var mnemonics []string
+	for _, instr := range instrs {
+		require.Equal(t, address, instr.Address)
+		address += uint64(instr.Size)
+		mnemonics = append(mnemonics, instr.Mnemonic)
+	}
+	require.Equal(t, uint64(len(s.symbolized[0].code)), address)
+	require.Contains(t, strings.Join(mnemonics, " "), "add")
+	require.Equal(t, "ret", mnemonics[len(mnemonics)-1])
+}
+
+func TestEngine_BoundsCheckStats(t *testing.T) {
+	i32 := wasm.ValueTypeI32
+	m := &wasm.Module{
+		TypeSection:         []wasm.FunctionType{{Params: []wasm.ValueType{i32}, Results: []wasm.ValueType{i32}}},
+		ImportSection:       []wasm.Import{{Type: wasm.ExternTypeFunc, DescFunc: 0}},
+		ImportFunctionCount: 1,
+		FunctionSection:     []wasm.Index{0},
+		MemorySection:       &wasm.Memory{Min: 1},
+		CodeSection: []wasm.Code{
+			{Body: []byte{
+				wasm.OpcodeLocalGet, 0, wasm.OpcodeI32Load, 0x2, 4,
+				wasm.OpcodeLocalGet, 0, wasm.OpcodeI32Load, 0x2, 0, // covered by the check of the first load.
+				wasm.OpcodeI32Add,
+				wasm.OpcodeEnd,
+			}},
+		},
+		ID: wasm.ModuleID{6},
+	}
+
+	e := NewEngine(ctx, 0, nil).(*engine)
+	_, err := e.BoundsCheckStats(m, 1)
+	require.EqualError(t, err, "module is not compiled")
+
+	err = e.CompileModule(ctx, m, nil, false)
+	require.NoError(t, err)
+
+	_, err = e.BoundsCheckStats(m, 0)
+	require.EqualError(t, err, "function[0] is imported")
+
+	stats, err := e.BoundsCheckStats(m, 1)
+	require.NoError(t, err)
+	require.Equal(t, wasm.BoundsCheckStats{Emitted: 1, Eliminated: 1}, stats)
+}
+
+func TestEngine_TrapSites(t *testing.T) {
+	i32 := wasm.ValueTypeI32
+	m := &wasm.Module{
+		TypeSection:         []wasm.FunctionType{{Params: []wasm.ValueType{i32}, Results: []wasm.ValueType{i32}}},
+		ImportSection:       []wasm.Import{{Type: wasm.ExternTypeFunc, DescFunc: 0}},
+		ImportFunctionCount: 1,
+		FunctionSection:     []wasm.Index{0},
+		MemorySection:       &wasm.Memory{Min: 1},
+		CodeSection: []wasm.Code{
+			{Body: []byte{
+				wasm.OpcodeLocalGet, 0, wasm.OpcodeI32Load, 0x2, 4, // offset 0x2
+				wasm.OpcodeLocalGet, 0, wasm.OpcodeI32Load, 0x2, 0, // covered by the check of the first load.
+				wasm.OpcodeI32DivS, // offset 0xa
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeIf, 0x40,
+				wasm.OpcodeUnreachable, // offset 0xf
+				wasm.OpcodeEnd,
+				wasm.OpcodeEnd,
+			}},
+		},
+		ID: wasm.ModuleID{8},
+	}
+
+	e := NewEngine(ctx, 0, nil).(*engine)
+	_, err := e.TrapSites(m, 1)
+	require.EqualError(t, err, "module is not compiled")
+
+	err = e.CompileModule(ctx, m, nil, false)
+	require.NoError(t, err)
+
+	_, err = e.TrapSites(m, 0)
+	require.EqualError(t, err, "function[0] is imported")
+
+	sites, err := e.TrapSites(m, 1)
+	require.NoError(t, err)
+	require.Equal(t, []wasm.TrapSite{
+		{Offset: 0x2, Cause: wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess},
+		{Offset: 0xa, Cause: wasmruntime.ErrRuntimeIntegerDivideByZero},
+		{Offset: 0xa, Cause: wasmruntime.ErrRuntimeIntegerOverflow},
+		{Offset: 0xf, Cause: wasmruntime.ErrRuntimeUnreachable},
+	}, sites)
+}
+
+func Test_scratchTracker_check(t *testing.T) {
+	s := &scratchTracker{limit: 100}
+	require.NoError(t, s.check(50))
+	require.NoError(t, s.check(100))
+	require.Equal(t, uint64(100), s.peak)
+	require.EqualError(t, s.check(101), "scratch memory 101 bytes exceeds the limit of 100 bytes")
+	require.NoError(t, s.check(10))
+	require.Equal(t, uint64(101), s.peak)
+}
+
 func TestEngine_sortedCompiledModules(t *testing.T) {
 	getCM := func(addr uintptr) *compiledModule {
 		var buf []byte
@@ -27,6 +27,17 @@ type Compiler struct {
 	memmoveSig             ssa.Signature
 	checkModuleExitCodeArg [1]ssa.Value
 	ensureTermination      bool
+	// metering is true if functions count their nesting against executionContext.callDepthRemaining, and consume
+	// executionContext.fuelRemaining. See SetMetering.
+	metering bool
+	// memory64 is true if the memory of the module is 64-bit, whose i64 operands are narrowed into i32.
+	memory64 bool
+	// inlinable is indexed by the local function index, and is true if calls to the function are inlined.
+	// See SetInliningThreshold.
+	inlinable []bool
+	// canonicalNaN is true if NaN results of floating-point arithmetic are replaced with the canonical NaN.
+	// See SetCanonicalNaN.
+	canonicalNaN bool
 
 	// Followings are reset by per function.
 
@@ -51,6 +62,16 @@ type Compiler struct {
 	loweringState loweringState
 
 	execCtxPtrValue, moduleCtxPtrValue ssa.Value
+
+	// knownSafeBounds maps the address of a memory access to the highest end of it already checked against the
+	// memory length in knownSafeBoundsBlock, so that accesses below it don't need their own check.
+	knownSafeBounds      map[ssa.Value]uint64
+	knownSafeBoundsBlock ssa.BasicBlock
+	// boundsChecksEmitted and boundsChecksEliminated count the bounds checks of memory accesses. See BoundsCheckStats.
+	boundsChecksEmitted, boundsChecksEliminated int
+	// exceptionPropagationBlk returns to the caller with the exception being thrown, or is nil until needed.
+	// See exceptionHandler.
+	exceptionPropagationBlk ssa.BasicBlock
 }
 
 // NewFrontendCompiler returns a frontend Compiler.
@@ -64,6 +85,14 @@ func NewFrontendCompiler(m *wasm.Module, ssaBuilder ssa.Builder, offset *wazevoa
 		ensureTermination:    ensureTermination,
 		needSourceOffsetInfo: sourceInfo,
 	}
+	if m.MemorySection != nil {
+		c.memory64 = m.MemorySection.Is64
+	}
+	for i := range m.ImportSection {
+		if imp := &m.ImportSection[i]; imp.Type == wasm.ExternTypeMemory {
+			c.memory64 = imp.DescMem.Is64
+		}
+	}
 	c.declareSignatures(listenerOn)
 	return c
 }
@@ -153,6 +182,38 @@ func SignatureForWasmFunctionType(typ *wasm.FunctionType) ssa.Signature {
 	return sig
 }
 
+// SetInliningThreshold makes calls to the leaf functions defined in this module, whose body has at most threshold
+// instructions, inlined into the caller. A threshold of zero or less disables inlining.
+//
+// Calls aren't inlined when ensureTermination, metering or listeners are enabled, as these observe each function call.
+// Neither are they in modules using exception handling, as exceptions thrown by the callee must reach the handlers of
+// the caller. SetMetering must be called before this.
+func (c *Compiler) SetInliningThreshold(threshold int) {
+	c.inlinable = c.inlinable[:0]
+	if threshold <= 0 || c.ensureTermination || c.metering || c.listenerSignatures != nil || c.m.UsesExceptionHandling {
+		return
+	}
+	for i := range c.m.CodeSection {
+		count, leaf := c.m.LeafInstructionCount(wasm.Index(i))
+		c.inlinable = append(c.inlinable, leaf && count <= threshold)
+	}
+}
+
+// SetMetering makes each function count its nesting against executionContext.callDepthRemaining, exiting with
+// wazevoapi.ExitCodeCallStackExhausted when no more nested calls are allowed. It also makes the entry of each function
+// and each loop header consume one unit of executionContext.fuelRemaining, exiting with
+// wazevoapi.ExitCodeFuelExhausted when none remains. This is independent of ensureTermination, so that modules
+// instantiated with limits don't need to check the exit code.
+func (c *Compiler) SetMetering(enabled bool) {
+	c.metering = enabled
+}
+
+// SetCanonicalNaN makes the NaN results of floating-point arithmetic the canonical NaN of their type, so that their bit
+// patterns don't depend on the architecture. Instructions which only move bits, e.g. fneg or copysign, are unaffected.
+func (c *Compiler) SetCanonicalNaN(enabled bool) {
+	c.canonicalNaN = enabled
+}
+
 // Init initializes the state of frontendCompiler and make it ready for a next function.
 func (c *Compiler) Init(idx, typIndex wasm.Index, typ *wasm.FunctionType, localTypes []wasm.ValueType, body []byte, needListener bool, bodyOffsetInCodeSection uint64) {
 	c.ssaBuilder.Init(c.signatures[typ])
@@ -165,6 +226,15 @@ func (c *Compiler) Init(idx, typIndex wasm.Index, typ *wasm.FunctionType, localT
 	c.wasmFunctionBody = body
 	c.wasmFunctionBodyOffsetInCodeSection = bodyOffsetInCodeSection
 	c.needListener = needListener
+	c.knownSafeBoundsBlock = nil
+	c.boundsChecksEmitted, c.boundsChecksEliminated = 0, 0
+	c.exceptionPropagationBlk = nil
+}
+
+// BoundsCheckStats returns the number of bounds checks emitted for the loads and stores of the function lowered by
+// the latest LowerToSSA, and the number eliminated as an earlier check in the same block already covers the access.
+func (c *Compiler) BoundsCheckStats() (emitted, eliminated int) {
+	return c.boundsChecksEmitted, c.boundsChecksEliminated
 }
 
 // Note: this assumes 64-bit platform (I believe we won't have 32-bit backend ;)).
@@ -229,27 +299,29 @@ func (c *Compiler) declareWasmLocals(entry ssa.BasicBlock) {
 		st := WasmTypeToSSAType(typ)
 		variable := c.ssaBuilder.DeclareVariable(st)
 		c.wasmLocalToVariable[wasm.Index(i)+localCount] = variable
+		c.ssaBuilder.DefineVariable(variable, c.insertZeroValue(st), entry)
+	}
+}
 
-		zeroInst := c.ssaBuilder.AllocateInstruction()
-		switch st {
-		case ssa.TypeI32:
-			zeroInst.AsIconst32(0)
-		case ssa.TypeI64:
-			zeroInst.AsIconst64(0)
-		case ssa.TypeF32:
-			zeroInst.AsF32const(0)
-		case ssa.TypeF64:
-			zeroInst.AsF64const(0)
-		case ssa.TypeV128:
-			zeroInst.AsVconst(0, 0)
-		default:
-			panic("TODO: " + wasm.ValueTypeName(typ))
-		}
-
-		c.ssaBuilder.InsertInstruction(zeroInst)
-		value := zeroInst.Return()
-		c.ssaBuilder.DefineVariable(variable, value, entry)
+// insertZeroValue inserts the constant zero of the given type.
+func (c *Compiler) insertZeroValue(st ssa.Type) ssa.Value {
+	zeroInst := c.ssaBuilder.AllocateInstruction()
+	switch st {
+	case ssa.TypeI32:
+		zeroInst.AsIconst32(0)
+	case ssa.TypeI64:
+		zeroInst.AsIconst64(0)
+	case ssa.TypeF32:
+		zeroInst.AsF32const(0)
+	case ssa.TypeF64:
+		zeroInst.AsF64const(0)
+	case ssa.TypeV128:
+		zeroInst.AsVconst(0, 0)
+	default:
+		panic("TODO: " + st.String())
 	}
+	c.ssaBuilder.InsertInstruction(zeroInst)
+	return zeroInst.Return()
 }
 
 func (c *Compiler) declareNecessaryVariables() {
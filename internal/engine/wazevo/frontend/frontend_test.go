@@ -1,9 +1,11 @@
 package frontend
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
 	"github.com/tetratelabs/wazero/internal/engine/wazevo/ssa"
 	"github.com/tetratelabs/wazero/internal/engine/wazevo/testcases"
 	"github.com/tetratelabs/wazero/internal/engine/wazevo/wazevoapi"
@@ -18,7 +20,9 @@ func TestCompiler_LowerToSSA(t *testing.T) {
 	for _, tc := range []struct {
 		name              string
 		ensureTermination bool
+		metering          bool
 		needListener      bool
+		canonicalNaN      bool
 		// m is the *wasm.Module to be compiled in this test.
 		m *wasm.Module
 		// targetIndex is the index of a local function to be compiled in this test.
@@ -256,12 +260,21 @@ signatures:
 blk0: (exec_ctx:i64, module_ctx:i64)
 	Jump blk1
 
-blk1: () <-- (blk0,blk1)
-	v2:i64 = Load exec_ctx, 0x58
-	CallIndirect v2:sig2, exec_ctx
-	Jump blk1
+blk1: () <-- (blk0,blk4)
+	v2:i64 = Load module_ctx, 0x18
+	v3:i32 = Load v2, 0x0
+	Brz v3, blk4
+	Jump blk3
 
 blk2: ()
+
+blk3: () <-- (blk1)
+	v4:i64 = Load exec_ctx, 0x58
+	CallIndirect v4:sig2, exec_ctx
+	Jump blk4
+
+blk4: () <-- (blk1,blk3)
+	Jump blk1
 `,
 			expAfterOpt: `
 signatures:
@@ -270,10 +283,53 @@ signatures:
 blk0: (exec_ctx:i64, module_ctx:i64)
 	Jump blk1
 
+blk1: () <-- (blk0,blk4)
+	v2:i64 = Load module_ctx, 0x18
+	v3:i32 = Load v2, 0x0
+	Brz v3, blk4
+	Jump blk3
+
+blk3: () <-- (blk1)
+	v4:i64 = Load exec_ctx, 0x58
+	CallIndirect v4:sig2, exec_ctx
+	Jump blk4
+
+blk4: () <-- (blk1,blk3)
+	Jump blk1
+`,
+		},
+		{
+			name: "loop - br / metering", m: testcases.LoopBr.Module,
+			metering: true,
+			exp: `
+blk0: (exec_ctx:i64, module_ctx:i64)
+	v2:i64 = Load exec_ctx, 0x480
+	v3:i64 = Iconst_64 0x0
+	v4:i32 = Icmp eq, v2, v3
+	ExitIfTrue v4, exec_ctx, call_stack_exhausted
+	v5:i64 = Iconst_64 0x1
+	v6:i64 = Isub v2, v5
+	Store v6, exec_ctx, 0x480
+	v7:i64 = Load exec_ctx, 0x488
+	v8:i64 = Iconst_64 0x0
+	v9:i32 = Icmp eq, v7, v8
+	ExitIfTrue v9, exec_ctx, fuel_exhausted
+	v10:i64 = Iconst_64 0x1
+	v11:i64 = Isub v7, v10
+	Store v11, exec_ctx, 0x488
+	Jump blk1
+
 blk1: () <-- (blk0,blk1)
-	v2:i64 = Load exec_ctx, 0x58
-	CallIndirect v2:sig2, exec_ctx
+	v12:i64 = Load exec_ctx, 0x488
+	v13:i64 = Iconst_64 0x0
+	v14:i32 = Icmp eq, v12, v13
+	ExitIfTrue v14, exec_ctx, fuel_exhausted
+	v15:i64 = Iconst_64 0x1
+	v16:i64 = Isub v12, v15
+	Store v16, exec_ctx, 0x488
 	Jump blk1
+
+blk2: ()
 `,
 		},
 		{
@@ -561,6 +617,173 @@ blk0: (exec_ctx:i64, module_ctx:i64, v2:i32, v3:i64, v4:f32, v5:f64)
 	Store module_ctx, exec_ctx, 0x8
 	Call f1:sig1, exec_ctx, module_ctx, v2, v3, v4, v5, v2, v3, v4, v5, v2, v3, v4, v5, v2, v3, v4, v5, v2, v3, v4, v5, v2, v3, v4, v5, v2, v3, v4, v5, v2, v3, v4, v5, v2, v3, v4, v5, v2, v3, v4, v5
 	Jump blk_ret
+`,
+		},
+		{
+			name: "return_call",
+			m:    testcases.ReturnCall.Module,
+			exp: `
+signatures:
+	sig0: i64i64i32i32_i32
+
+blk0: (exec_ctx:i64, module_ctx:i64, v2:i32, v3:i32)
+	v4:i32 = Iconst_32 0x0
+	v5:i32 = Icmp eq, v2, v4
+	Brz v5, blk2
+	Jump blk1
+
+blk1: () <-- (blk0)
+	Return v3
+
+blk2: () <-- (blk0)
+	Jump blk3
+
+blk3: () <-- (blk2)
+	v6:i32 = Iconst_32 0x1
+	v7:i32 = Isub v2, v6
+	v8:i32 = Iadd v3, v2
+	Store module_ctx, exec_ctx, 0x8
+	ReturnCall f0:sig0, exec_ctx, module_ctx, v7, v8
+`,
+		},
+		{
+			name:         "return_call / listener",
+			m:            testcases.ReturnCall.Module,
+			needListener: true,
+			exp: `
+signatures:
+	sig0: i64i64i32i32_i32
+	sig1: i64i32i32i32_v
+	sig2: i64i32i32_v
+
+blk0: (exec_ctx:i64, module_ctx:i64, v2:i32, v3:i32)
+	Store module_ctx, exec_ctx, 0x8
+	v4:i64 = Load module_ctx, 0x8
+	v5:i64 = Load v4, 0x0
+	v6:i32 = Iconst_32 0x0
+	CallIndirect v5:sig1, exec_ctx, v6, v2, v3
+	v7:i32 = Iconst_32 0x0
+	v8:i32 = Icmp eq, v2, v7
+	Brz v8, blk2
+	Jump blk1
+
+blk1: () <-- (blk0)
+	Store module_ctx, exec_ctx, 0x8
+	v9:i64 = Load module_ctx, 0x10
+	v10:i64 = Load v9, 0x0
+	v11:i32 = Iconst_32 0x0
+	CallIndirect v10:sig2, exec_ctx, v11, v3
+	Return v3
+
+blk2: () <-- (blk0)
+	Jump blk3
+
+blk3: () <-- (blk2)
+	v12:i32 = Iconst_32 0x1
+	v13:i32 = Isub v2, v12
+	v14:i32 = Iadd v3, v2
+	Store module_ctx, exec_ctx, 0x8
+	v15:i32 = Call f0:sig0, exec_ctx, module_ctx, v13, v14
+	Store module_ctx, exec_ctx, 0x8
+	v16:i64 = Load module_ctx, 0x10
+	v17:i64 = Load v16, 0x0
+	v18:i32 = Iconst_32 0x0
+	CallIndirect v17:sig2, exec_ctx, v18, v15
+	Return v15
+`,
+		},
+		{
+			name:              "return_call / ensure termination / metering",
+			m:                 testcases.ReturnCall.Module,
+			ensureTermination: true,
+			metering:          true,
+			exp: `
+signatures:
+	sig0: i64i64i32i32_i32
+	sig2: i64_v
+
+blk0: (exec_ctx:i64, module_ctx:i64, v2:i32, v3:i32)
+	v4:i64 = Load exec_ctx, 0x480
+	v5:i64 = Iconst_64 0x0
+	v6:i32 = Icmp eq, v4, v5
+	ExitIfTrue v6, exec_ctx, call_stack_exhausted
+	v7:i64 = Iconst_64 0x1
+	v8:i64 = Isub v4, v7
+	Store v8, exec_ctx, 0x480
+	v9:i64 = Load exec_ctx, 0x488
+	v10:i64 = Iconst_64 0x0
+	v11:i32 = Icmp eq, v9, v10
+	ExitIfTrue v11, exec_ctx, fuel_exhausted
+	v12:i64 = Iconst_64 0x1
+	v13:i64 = Isub v9, v12
+	Store v13, exec_ctx, 0x488
+	v14:i32 = Iconst_32 0x0
+	v15:i32 = Icmp eq, v2, v14
+	Brz v15, blk2
+	Jump blk1
+
+blk1: () <-- (blk0)
+	v16:i64 = Load exec_ctx, 0x480
+	v17:i64 = Iconst_64 0x1
+	v18:i64 = Iadd v16, v17
+	Store v18, exec_ctx, 0x480
+	Return v3
+
+blk2: () <-- (blk0)
+	Jump blk3
+
+blk3: () <-- (blk2)
+	v19:i32 = Iconst_32 0x1
+	v20:i32 = Isub v2, v19
+	v21:i32 = Iadd v3, v2
+	v22:i64 = Load module_ctx, 0x18
+	v23:i32 = Load v22, 0x0
+	Brz v23, blk5
+	Jump blk4
+
+blk4: () <-- (blk3)
+	v24:i64 = Load exec_ctx, 0x58
+	CallIndirect v24:sig2, exec_ctx
+	Jump blk5
+
+blk5: () <-- (blk3,blk4)
+	v25:i64 = Load exec_ctx, 0x480
+	v26:i64 = Iconst_64 0x1
+	v27:i64 = Iadd v25, v26
+	Store v27, exec_ctx, 0x480
+	Store module_ctx, exec_ctx, 0x8
+	ReturnCall f0:sig0, exec_ctx, module_ctx, v20, v21
+`,
+		},
+		{
+			name: "return_call_indirect",
+			m:    testcases.ReturnCallIndirect.Module,
+			exp: `
+signatures:
+	sig0: i64i64i32_i32
+
+blk0: (exec_ctx:i64, module_ctx:i64, v2:i32)
+	v3:i64 = Load module_ctx, 0x10
+	v4:i32 = Load v3, 0x8
+	v5:i32 = Icmp ge_u, v2, v4
+	ExitIfTrue v5, exec_ctx, table_out_of_bounds
+	v6:i64 = Load v3, 0x0
+	v7:i64 = Iconst_64 0x3
+	v8:i32 = Ishl v2, v7
+	v9:i64 = Iadd v6, v8
+	v10:i64 = Load v9, 0x0
+	v11:i64 = Iconst_64 0x0
+	v12:i32 = Icmp eq, v10, v11
+	ExitIfTrue v12, exec_ctx, indirect_call_null_pointer
+	v13:i32 = Load v10, 0x10
+	v14:i64 = Load module_ctx, 0x8
+	v15:i32 = Load v14, 0x0
+	v16:i32 = Icmp neq, v13, v15
+	ExitIfTrue v16, exec_ctx, indirect_call_type_mismatch
+	v17:i64 = Load v10, 0x0
+	v18:i64 = Load v10, 0x8
+	Store module_ctx, exec_ctx, 0x8
+	ReturnCallIndirect v17:sig0, exec_ctx, v18, v2
 `,
 		},
 		{
@@ -780,6 +1003,17 @@ blk2: () <-- (blk3)
 
 blk3: () <-- (blk1)
 	Jump blk2
+`,
+		},
+		{
+			name: "block with param and results", m: testcases.BlockWithParamResults.Module,
+			exp: `
+blk0: (exec_ctx:i64, module_ctx:i64, v2:i32, v3:i32)
+	v5:i32 = Isub v2, v3
+	Jump blk1, v5
+
+blk1: (v4:i32) <-- (blk0)
+	Jump blk_ret, v4
 `,
 		},
 		{
@@ -1019,14 +1253,10 @@ blk0: (exec_ctx:i64, module_ctx:i64, v2:i32, v3:i32)
 	v9:i64 = Load module_ctx, 0x8
 	v10:i64 = Iadd v9, v5
 	Store v3, v10, 0x0
-	v11:i64 = Iconst_64 0x4
-	v12:i64 = UExtend v2, 32->64
-	v13:i64 = Iadd v12, v11
-	v14:i32 = Icmp lt_u, v6, v13
-	ExitIfTrue v14, exec_ctx, memory_out_of_bounds
-	v15:i64 = Iadd v9, v12
-	v16:i32 = Load v15, 0x0
-	Jump blk_ret, v16
+	v11:i64 = UExtend v2, 32->64
+	v12:i64 = Iadd v9, v11
+	v13:i32 = Load v12, 0x0
+	Jump blk_ret, v13
 `,
 		},
 		{
@@ -1144,189 +1374,93 @@ blk0: (exec_ctx:i64, module_ctx:i64, v2:i32)
 	ExitIfTrue v14, exec_ctx, memory_out_of_bounds
 	v15:i64 = Iadd v8, v12
 	v16:i64 = Load v15, 0x0
-	v17:i64 = Iconst_64 0x4
-	v18:i64 = UExtend v2, 32->64
-	v19:i64 = Iadd v18, v17
-	v20:i32 = Icmp lt_u, v5, v19
-	ExitIfTrue v20, exec_ctx, memory_out_of_bounds
-	v21:i64 = Iadd v8, v18
-	v22:f32 = Load v21, 0x0
-	v23:i64 = Iconst_64 0x8
+	v17:i64 = UExtend v2, 32->64
+	v18:i64 = Iadd v8, v17
+	v19:f32 = Load v18, 0x0
+	v20:i64 = UExtend v2, 32->64
+	v21:i64 = Iadd v8, v20
+	v22:f64 = Load v21, 0x0
+	v23:i64 = Iconst_64 0x13
 	v24:i64 = UExtend v2, 32->64
 	v25:i64 = Iadd v24, v23
 	v26:i32 = Icmp lt_u, v5, v25
 	ExitIfTrue v26, exec_ctx, memory_out_of_bounds
 	v27:i64 = Iadd v8, v24
-	v28:f64 = Load v27, 0x0
-	v29:i64 = Iconst_64 0x13
+	v28:i32 = Load v27, 0xf
+	v29:i64 = Iconst_64 0x17
 	v30:i64 = UExtend v2, 32->64
 	v31:i64 = Iadd v30, v29
 	v32:i32 = Icmp lt_u, v5, v31
 	ExitIfTrue v32, exec_ctx, memory_out_of_bounds
 	v33:i64 = Iadd v8, v30
-	v34:i32 = Load v33, 0xf
-	v35:i64 = Iconst_64 0x17
-	v36:i64 = UExtend v2, 32->64
-	v37:i64 = Iadd v36, v35
-	v38:i32 = Icmp lt_u, v5, v37
-	ExitIfTrue v38, exec_ctx, memory_out_of_bounds
-	v39:i64 = Iadd v8, v36
-	v40:i64 = Load v39, 0xf
-	v41:i64 = Iconst_64 0x13
-	v42:i64 = UExtend v2, 32->64
-	v43:i64 = Iadd v42, v41
-	v44:i32 = Icmp lt_u, v5, v43
-	ExitIfTrue v44, exec_ctx, memory_out_of_bounds
-	v45:i64 = Iadd v8, v42
-	v46:f32 = Load v45, 0xf
-	v47:i64 = Iconst_64 0x17
-	v48:i64 = UExtend v2, 32->64
-	v49:i64 = Iadd v48, v47
-	v50:i32 = Icmp lt_u, v5, v49
-	ExitIfTrue v50, exec_ctx, memory_out_of_bounds
-	v51:i64 = Iadd v8, v48
-	v52:f64 = Load v51, 0xf
-	v53:i64 = Iconst_64 0x1
-	v54:i64 = UExtend v2, 32->64
-	v55:i64 = Iadd v54, v53
-	v56:i32 = Icmp lt_u, v5, v55
-	ExitIfTrue v56, exec_ctx, memory_out_of_bounds
-	v57:i64 = Iadd v8, v54
-	v58:i32 = Sload8 v57, 0x0
-	v59:i64 = Iconst_64 0x10
-	v60:i64 = UExtend v2, 32->64
-	v61:i64 = Iadd v60, v59
-	v62:i32 = Icmp lt_u, v5, v61
-	ExitIfTrue v62, exec_ctx, memory_out_of_bounds
-	v63:i64 = Iadd v8, v60
-	v64:i32 = Sload8 v63, 0xf
-	v65:i64 = Iconst_64 0x1
-	v66:i64 = UExtend v2, 32->64
-	v67:i64 = Iadd v66, v65
-	v68:i32 = Icmp lt_u, v5, v67
-	ExitIfTrue v68, exec_ctx, memory_out_of_bounds
-	v69:i64 = Iadd v8, v66
-	v70:i32 = Uload8 v69, 0x0
-	v71:i64 = Iconst_64 0x10
-	v72:i64 = UExtend v2, 32->64
-	v73:i64 = Iadd v72, v71
-	v74:i32 = Icmp lt_u, v5, v73
-	ExitIfTrue v74, exec_ctx, memory_out_of_bounds
-	v75:i64 = Iadd v8, v72
-	v76:i32 = Uload8 v75, 0xf
-	v77:i64 = Iconst_64 0x2
-	v78:i64 = UExtend v2, 32->64
-	v79:i64 = Iadd v78, v77
-	v80:i32 = Icmp lt_u, v5, v79
-	ExitIfTrue v80, exec_ctx, memory_out_of_bounds
-	v81:i64 = Iadd v8, v78
-	v82:i32 = Sload16 v81, 0x0
-	v83:i64 = Iconst_64 0x11
-	v84:i64 = UExtend v2, 32->64
-	v85:i64 = Iadd v84, v83
-	v86:i32 = Icmp lt_u, v5, v85
-	ExitIfTrue v86, exec_ctx, memory_out_of_bounds
-	v87:i64 = Iadd v8, v84
-	v88:i32 = Sload16 v87, 0xf
-	v89:i64 = Iconst_64 0x2
-	v90:i64 = UExtend v2, 32->64
-	v91:i64 = Iadd v90, v89
-	v92:i32 = Icmp lt_u, v5, v91
-	ExitIfTrue v92, exec_ctx, memory_out_of_bounds
-	v93:i64 = Iadd v8, v90
-	v94:i32 = Uload16 v93, 0x0
-	v95:i64 = Iconst_64 0x11
-	v96:i64 = UExtend v2, 32->64
-	v97:i64 = Iadd v96, v95
-	v98:i32 = Icmp lt_u, v5, v97
-	ExitIfTrue v98, exec_ctx, memory_out_of_bounds
-	v99:i64 = Iadd v8, v96
-	v100:i32 = Uload16 v99, 0xf
-	v101:i64 = Iconst_64 0x1
-	v102:i64 = UExtend v2, 32->64
-	v103:i64 = Iadd v102, v101
-	v104:i32 = Icmp lt_u, v5, v103
-	ExitIfTrue v104, exec_ctx, memory_out_of_bounds
-	v105:i64 = Iadd v8, v102
-	v106:i64 = Sload8 v105, 0x0
-	v107:i64 = Iconst_64 0x10
-	v108:i64 = UExtend v2, 32->64
-	v109:i64 = Iadd v108, v107
-	v110:i32 = Icmp lt_u, v5, v109
-	ExitIfTrue v110, exec_ctx, memory_out_of_bounds
-	v111:i64 = Iadd v8, v108
-	v112:i64 = Sload8 v111, 0xf
-	v113:i64 = Iconst_64 0x1
-	v114:i64 = UExtend v2, 32->64
-	v115:i64 = Iadd v114, v113
-	v116:i32 = Icmp lt_u, v5, v115
-	ExitIfTrue v116, exec_ctx, memory_out_of_bounds
-	v117:i64 = Iadd v8, v114
-	v118:i64 = Uload8 v117, 0x0
-	v119:i64 = Iconst_64 0x10
-	v120:i64 = UExtend v2, 32->64
-	v121:i64 = Iadd v120, v119
-	v122:i32 = Icmp lt_u, v5, v121
-	ExitIfTrue v122, exec_ctx, memory_out_of_bounds
-	v123:i64 = Iadd v8, v120
-	v124:i64 = Uload8 v123, 0xf
-	v125:i64 = Iconst_64 0x2
-	v126:i64 = UExtend v2, 32->64
-	v127:i64 = Iadd v126, v125
-	v128:i32 = Icmp lt_u, v5, v127
-	ExitIfTrue v128, exec_ctx, memory_out_of_bounds
-	v129:i64 = Iadd v8, v126
-	v130:i64 = Sload16 v129, 0x0
-	v131:i64 = Iconst_64 0x11
-	v132:i64 = UExtend v2, 32->64
-	v133:i64 = Iadd v132, v131
-	v134:i32 = Icmp lt_u, v5, v133
-	ExitIfTrue v134, exec_ctx, memory_out_of_bounds
-	v135:i64 = Iadd v8, v132
-	v136:i64 = Sload16 v135, 0xf
-	v137:i64 = Iconst_64 0x2
-	v138:i64 = UExtend v2, 32->64
-	v139:i64 = Iadd v138, v137
-	v140:i32 = Icmp lt_u, v5, v139
-	ExitIfTrue v140, exec_ctx, memory_out_of_bounds
-	v141:i64 = Iadd v8, v138
-	v142:i64 = Uload16 v141, 0x0
-	v143:i64 = Iconst_64 0x11
-	v144:i64 = UExtend v2, 32->64
-	v145:i64 = Iadd v144, v143
-	v146:i32 = Icmp lt_u, v5, v145
-	ExitIfTrue v146, exec_ctx, memory_out_of_bounds
-	v147:i64 = Iadd v8, v144
-	v148:i64 = Uload16 v147, 0xf
-	v149:i64 = Iconst_64 0x4
-	v150:i64 = UExtend v2, 32->64
-	v151:i64 = Iadd v150, v149
-	v152:i32 = Icmp lt_u, v5, v151
-	ExitIfTrue v152, exec_ctx, memory_out_of_bounds
-	v153:i64 = Iadd v8, v150
-	v154:i64 = Sload32 v153, 0x0
-	v155:i64 = Iconst_64 0x13
-	v156:i64 = UExtend v2, 32->64
-	v157:i64 = Iadd v156, v155
-	v158:i32 = Icmp lt_u, v5, v157
-	ExitIfTrue v158, exec_ctx, memory_out_of_bounds
-	v159:i64 = Iadd v8, v156
-	v160:i64 = Sload32 v159, 0xf
-	v161:i64 = Iconst_64 0x4
-	v162:i64 = UExtend v2, 32->64
-	v163:i64 = Iadd v162, v161
-	v164:i32 = Icmp lt_u, v5, v163
-	ExitIfTrue v164, exec_ctx, memory_out_of_bounds
-	v165:i64 = Iadd v8, v162
-	v166:i64 = Uload32 v165, 0x0
-	v167:i64 = Iconst_64 0x13
-	v168:i64 = UExtend v2, 32->64
-	v169:i64 = Iadd v168, v167
-	v170:i32 = Icmp lt_u, v5, v169
-	ExitIfTrue v170, exec_ctx, memory_out_of_bounds
-	v171:i64 = Iadd v8, v168
-	v172:i64 = Uload32 v171, 0xf
-	Jump blk_ret, v10, v16, v22, v28, v34, v40, v46, v52, v58, v64, v70, v76, v82, v88, v94, v100, v106, v112, v118, v124, v130, v136, v142, v148, v154, v160, v166, v172
+	v34:i64 = Load v33, 0xf
+	v35:i64 = UExtend v2, 32->64
+	v36:i64 = Iadd v8, v35
+	v37:f32 = Load v36, 0xf
+	v38:i64 = UExtend v2, 32->64
+	v39:i64 = Iadd v8, v38
+	v40:f64 = Load v39, 0xf
+	v41:i64 = UExtend v2, 32->64
+	v42:i64 = Iadd v8, v41
+	v43:i32 = Sload8 v42, 0x0
+	v44:i64 = UExtend v2, 32->64
+	v45:i64 = Iadd v8, v44
+	v46:i32 = Sload8 v45, 0xf
+	v47:i64 = UExtend v2, 32->64
+	v48:i64 = Iadd v8, v47
+	v49:i32 = Uload8 v48, 0x0
+	v50:i64 = UExtend v2, 32->64
+	v51:i64 = Iadd v8, v50
+	v52:i32 = Uload8 v51, 0xf
+	v53:i64 = UExtend v2, 32->64
+	v54:i64 = Iadd v8, v53
+	v55:i32 = Sload16 v54, 0x0
+	v56:i64 = UExtend v2, 32->64
+	v57:i64 = Iadd v8, v56
+	v58:i32 = Sload16 v57, 0xf
+	v59:i64 = UExtend v2, 32->64
+	v60:i64 = Iadd v8, v59
+	v61:i32 = Uload16 v60, 0x0
+	v62:i64 = UExtend v2, 32->64
+	v63:i64 = Iadd v8, v62
+	v64:i32 = Uload16 v63, 0xf
+	v65:i64 = UExtend v2, 32->64
+	v66:i64 = Iadd v8, v65
+	v67:i64 = Sload8 v66, 0x0
+	v68:i64 = UExtend v2, 32->64
+	v69:i64 = Iadd v8, v68
+	v70:i64 = Sload8 v69, 0xf
+	v71:i64 = UExtend v2, 32->64
+	v72:i64 = Iadd v8, v71
+	v73:i64 = Uload8 v72, 0x0
+	v74:i64 = UExtend v2, 32->64
+	v75:i64 = Iadd v8, v74
+	v76:i64 = Uload8 v75, 0xf
+	v77:i64 = UExtend v2, 32->64
+	v78:i64 = Iadd v8, v77
+	v79:i64 = Sload16 v78, 0x0
+	v80:i64 = UExtend v2, 32->64
+	v81:i64 = Iadd v8, v80
+	v82:i64 = Sload16 v81, 0xf
+	v83:i64 = UExtend v2, 32->64
+	v84:i64 = Iadd v8, v83
+	v85:i64 = Uload16 v84, 0x0
+	v86:i64 = UExtend v2, 32->64
+	v87:i64 = Iadd v8, v86
+	v88:i64 = Uload16 v87, 0xf
+	v89:i64 = UExtend v2, 32->64
+	v90:i64 = Iadd v8, v89
+	v91:i64 = Sload32 v90, 0x0
+	v92:i64 = UExtend v2, 32->64
+	v93:i64 = Iadd v8, v92
+	v94:i64 = Sload32 v93, 0xf
+	v95:i64 = UExtend v2, 32->64
+	v96:i64 = Iadd v8, v95
+	v97:i64 = Uload32 v96, 0x0
+	v98:i64 = UExtend v2, 32->64
+	v99:i64 = Iadd v8, v98
+	v100:i64 = Uload32 v99, 0xf
+	Jump blk_ret, v10, v16, v19, v22, v28, v34, v37, v40, v43, v46, v49, v52, v55, v58, v61, v64, v67, v70, v73, v76, v79, v82, v85, v88, v91, v94, v97, v100
 `,
 		},
 		{
@@ -1787,6 +1921,371 @@ blk4: () <-- (blk2,blk3)
 blk0: (exec_ctx:i64, module_ctx:i64, v2:v128, v3:v128)
 	v4:v128 = Shuffle.[0 1 2 3 4 5 6 7 24 25 26 27 28 29 30 31] v2, v3
 	Jump blk_ret, v4
+`,
+		},
+		{
+			name: "VecRelaxedMadd",
+			m:    testcases.VecRelaxedMadd.Module,
+			exp: `
+blk0: (exec_ctx:i64, module_ctx:i64, v2:v128, v3:v128, v4:v128)
+	v5:v128 = VFmul.f32x4 v2, v3
+	v6:v128 = VFadd.f32x4 v5, v4
+	Jump blk_ret, v6
+`,
+		},
+		{
+			name: "VecRelaxedDotAdd",
+			m:    testcases.VecRelaxedDotAdd.Module,
+			exp: `
+blk0: (exec_ctx:i64, module_ctx:i64, v2:v128, v3:v128, v4:v128)
+	v5:v128 = SwidenLow.i8x16 v2
+	v6:v128 = SwidenLow.i8x16 v3
+	v7:v128 = VImul.i16x8 v5, v6
+	v8:v128 = SwidenLow.i16x8 v7
+	v9:v128 = SwidenHigh.i16x8 v7
+	v10:v128 = IaddPairwise.i32x4 v8, v9
+	v11:v128 = SwidenHigh.i8x16 v2
+	v12:v128 = SwidenHigh.i8x16 v3
+	v13:v128 = VImul.i16x8 v11, v12
+	v14:v128 = SwidenLow.i16x8 v13
+	v15:v128 = SwidenHigh.i16x8 v13
+	v16:v128 = IaddPairwise.i32x4 v14, v15
+	v17:v128 = Snarrow.i32x4 v10, v16
+	v18:v128 = SwidenLow.i16x8 v17
+	v19:v128 = SwidenHigh.i16x8 v17
+	v20:v128 = IaddPairwise.i32x4 v18, v19
+	v21:v128 = VIadd.i32x4 v4, v20
+	Jump blk_ret, v21
+`,
+		},
+		{
+			name: "AtomicRmwCmpxchg",
+			m:    testcases.AtomicRmwCmpxchg.Module,
+			exp: `
+blk0: (exec_ctx:i64, module_ctx:i64, v2:i32, v3:i32, v4:i32)
+	v5:i32 = Iconst_32 0x8
+	v6:i32 = Iadd v2, v5
+	v7:i32 = Iconst_32 0x1
+	v8:i32 = Band v6, v7
+	v9:i32 = Iconst_32 0x0
+	v10:i32 = Icmp neq, v8, v9
+	ExitIfTrue v10, exec_ctx, unaligned_atomic
+	v11:i64 = Iconst_64 0xa
+	v12:i64 = UExtend v2, 32->64
+	v13:i64 = Uload32 module_ctx, 0x10
+	v14:i64 = Iadd v12, v11
+	v15:i32 = Icmp lt_u, v13, v14
+	ExitIfTrue v15, exec_ctx, memory_out_of_bounds
+	v16:i64 = Load module_ctx, 0x8
+	v17:i64 = Iadd v16, v12
+	v18:i32 = Uload16 v17, 0x8
+	v19:i32 = Iconst_32 0xffff
+	v20:i32 = Band v3, v19
+	v21:i32 = Icmp eq, v18, v20
+	v22:i32 = Select v21, v4, v18
+	Istore16 v22, v17, 0x8
+	Jump blk_ret, v18
+`,
+		},
+		{
+			name: "exception throw catch", m: testcases.ExceptionThrowCatch.Module,
+			exp: `
+blk0: (exec_ctx:i64, module_ctx:i64, v2:i32)
+	v4:i64 = Load module_ctx, 0x18
+	Store v2, exec_ctx, 0x498
+	Store v4, exec_ctx, 0x490
+	Jump blk2
+
+blk1: (v3:i32) <-- (blk3)
+	Jump blk_ret, v3
+
+blk2: () <-- (blk0)
+	v5:i64 = Load exec_ctx, 0x490
+	v6:i64 = Load module_ctx, 0x18
+	v7:i32 = Icmp eq, v5, v6
+	Brnz v7, blk3
+	Jump blk4
+
+blk3: () <-- (blk2)
+	v8:i32 = Load exec_ctx, 0x498
+	v9:i64 = Iconst_64 0x0
+	Store v9, exec_ctx, 0x490
+	v10:i32 = Iconst_32 0x1
+	v11:i32 = Iadd v8, v10
+	Jump blk1, v11
+
+blk4: () <-- (blk2)
+	Jump blk5
+
+blk5: () <-- (blk4)
+	v12:i32 = Iconst_32 0x0
+	Jump blk_ret, v12
+`,
+		},
+		{
+			name: "exception propagation - catch", m: testcases.ExceptionPropagation.Module,
+			exp: `
+signatures:
+	sig0: i64i64i32_i32
+
+blk0: (exec_ctx:i64, module_ctx:i64, v2:i32)
+	Store module_ctx, exec_ctx, 0x8
+	v4:i32 = Call f1:sig0, exec_ctx, module_ctx, v2
+	v5:i64 = Load exec_ctx, 0x490
+	v6:i64 = Iconst_64 0x0
+	v7:i32 = Icmp neq, v5, v6
+	Brnz v7, blk2
+	Jump blk3
+
+blk1: (v3:i32) <-- (blk3,blk4)
+	Jump blk_ret, v3
+
+blk2: () <-- (blk0)
+	v8:i64 = Load exec_ctx, 0x490
+	v9:i64 = Load module_ctx, 0x18
+	v10:i32 = Icmp eq, v8, v9
+	Brnz v10, blk4
+	Jump blk5
+
+blk3: () <-- (blk0)
+	Jump blk1, v4
+
+blk4: () <-- (blk2)
+	v11:i32 = Load exec_ctx, 0x498
+	v12:i64 = Iconst_64 0x0
+	Store v12, exec_ctx, 0x490
+	v13:i32 = Iconst_32 0x64
+	v14:i32 = Iadd v11, v13
+	Jump blk1, v14
+
+blk5: () <-- (blk2)
+	Jump blk6
+
+blk6: () <-- (blk5)
+	v15:i32 = Iconst_32 0x0
+	Jump blk_ret, v15
+`,
+		},
+		{
+			name: "exception propagation - no try", m: testcases.ExceptionPropagation.Module,
+			targetIndex: 1,
+			exp: `
+signatures:
+	sig0: i64i64i32_i32
+
+blk0: (exec_ctx:i64, module_ctx:i64, v2:i32)
+	Store module_ctx, exec_ctx, 0x8
+	v3:i32 = Call f2:sig0, exec_ctx, module_ctx, v2
+	v4:i64 = Load exec_ctx, 0x490
+	v5:i64 = Iconst_64 0x0
+	v6:i32 = Icmp neq, v4, v5
+	Brnz v6, blk1
+	Jump blk2
+
+blk1: () <-- (blk0)
+	v9:i32 = Iconst_32 0x0
+	Jump blk_ret, v9
+
+blk2: () <-- (blk0)
+	v7:i32 = Iconst_32 0x3e8
+	v8:i32 = Iadd v3, v7
+	Jump blk_ret, v8
+`,
+		},
+		{
+			name: "exception propagation - no try / metering", m: testcases.ExceptionPropagation.Module,
+			targetIndex: 1, metering: true,
+			exp: `
+signatures:
+	sig0: i64i64i32_i32
+
+blk0: (exec_ctx:i64, module_ctx:i64, v2:i32)
+	v3:i64 = Load exec_ctx, 0x480
+	v4:i64 = Iconst_64 0x0
+	v5:i32 = Icmp eq, v3, v4
+	ExitIfTrue v5, exec_ctx, call_stack_exhausted
+	v6:i64 = Iconst_64 0x1
+	v7:i64 = Isub v3, v6
+	Store v7, exec_ctx, 0x480
+	v8:i64 = Load exec_ctx, 0x488
+	v9:i64 = Iconst_64 0x0
+	v10:i32 = Icmp eq, v8, v9
+	ExitIfTrue v10, exec_ctx, fuel_exhausted
+	v11:i64 = Iconst_64 0x1
+	v12:i64 = Isub v8, v11
+	Store v12, exec_ctx, 0x488
+	Store module_ctx, exec_ctx, 0x8
+	v13:i32 = Call f2:sig0, exec_ctx, module_ctx, v2
+	v14:i64 = Load exec_ctx, 0x490
+	v15:i64 = Iconst_64 0x0
+	v16:i32 = Icmp neq, v14, v15
+	Brnz v16, blk1
+	Jump blk2
+
+blk1: () <-- (blk0)
+	v22:i32 = Iconst_32 0x0
+	v23:i64 = Load exec_ctx, 0x480
+	v24:i64 = Iconst_64 0x1
+	v25:i64 = Iadd v23, v24
+	Store v25, exec_ctx, 0x480
+	Jump blk_ret, v22
+
+blk2: () <-- (blk0)
+	v17:i32 = Iconst_32 0x3e8
+	v18:i32 = Iadd v13, v17
+	v19:i64 = Load exec_ctx, 0x480
+	v20:i64 = Iconst_64 0x1
+	v21:i64 = Iadd v19, v20
+	Store v21, exec_ctx, 0x480
+	Jump blk_ret, v18
+`,
+		},
+		{
+			name: "exception rethrow", m: testcases.ExceptionRethrowDelegate.Module,
+			exp: `
+signatures:
+	sig0: i64i64i32_i32
+
+blk0: (exec_ctx:i64, module_ctx:i64, v2:i32)
+	Store module_ctx, exec_ctx, 0x8
+	v5:i32 = Call f2:sig0, exec_ctx, module_ctx, v2
+	v6:i64 = Load exec_ctx, 0x490
+	v7:i64 = Iconst_64 0x0
+	v8:i32 = Icmp neq, v6, v7
+	Brnz v8, blk4
+	Jump blk5
+
+blk1: (v3:i32) <-- (blk3,blk6)
+	Jump blk_ret, v3
+
+blk2: () <-- (blk4)
+	v19:i64 = Load exec_ctx, 0x490
+	v20:i64 = Load module_ctx, 0x18
+	v21:i32 = Icmp eq, v19, v20
+	Brnz v21, blk6
+	Jump blk7
+
+blk3: (v4:i32) <-- (blk5)
+	Jump blk1, v4
+
+blk4: () <-- (blk0)
+	v9:i64 = Load exec_ctx, 0x490
+	v10:i64 = Load exec_ctx, 0x498
+	v11:i64 = Load exec_ctx, 0x4a0
+	v12:i64 = Load exec_ctx, 0x4a8
+	v13:i64 = Load exec_ctx, 0x4b0
+	v14:i64 = Load exec_ctx, 0x4b8
+	v15:i64 = Load exec_ctx, 0x4c0
+	v16:i64 = Load exec_ctx, 0x4c8
+	v17:i64 = Load exec_ctx, 0x4d0
+	v18:i64 = Iconst_64 0x0
+	Store v18, exec_ctx, 0x490
+	Store v10, exec_ctx, 0x498
+	Store v11, exec_ctx, 0x4a0
+	Store v12, exec_ctx, 0x4a8
+	Store v13, exec_ctx, 0x4b0
+	Store v14, exec_ctx, 0x4b8
+	Store v15, exec_ctx, 0x4c0
+	Store v16, exec_ctx, 0x4c8
+	Store v17, exec_ctx, 0x4d0
+	Store v9, exec_ctx, 0x490
+	Jump blk2
+
+blk5: () <-- (blk0)
+	Jump blk3, v5
+
+blk6: () <-- (blk2)
+	v22:i32 = Load exec_ctx, 0x498
+	v23:i64 = Iconst_64 0x0
+	Store v23, exec_ctx, 0x490
+	v24:i32 = Iconst_32 0x64
+	v25:i32 = Iadd v22, v24
+	Jump blk1, v25
+
+blk7: () <-- (blk2)
+	Jump blk8
+
+blk8: () <-- (blk7)
+	v26:i32 = Iconst_32 0x0
+	Jump blk_ret, v26
+`,
+		},
+		{
+			name: "exception delegate", m: testcases.ExceptionRethrowDelegate.Module,
+			targetIndex: 1,
+			exp: `
+signatures:
+	sig0: i64i64i32_i32
+
+blk0: (exec_ctx:i64, module_ctx:i64, v2:i32)
+	Store module_ctx, exec_ctx, 0x8
+	v5:i32 = Call f2:sig0, exec_ctx, module_ctx, v2
+	v6:i64 = Load exec_ctx, 0x490
+	v7:i64 = Iconst_64 0x0
+	v8:i32 = Icmp neq, v6, v7
+	Brnz v8, blk4
+	Jump blk5
+
+blk1: (v3:i32) <-- (blk3,blk6)
+	Jump blk_ret, v3
+
+blk2: () <-- (blk4)
+	v9:i64 = Load exec_ctx, 0x490
+	v10:i64 = Load module_ctx, 0x18
+	v11:i32 = Icmp eq, v9, v10
+	Brnz v11, blk6
+	Jump blk7
+
+blk3: (v4:i32) <-- (blk5)
+	Jump blk1, v4
+
+blk4: () <-- (blk0)
+	Jump blk2
+
+blk5: () <-- (blk0)
+	Jump blk3, v5
+
+blk6: () <-- (blk2)
+	v12:i32 = Load exec_ctx, 0x498
+	v13:i64 = Iconst_64 0x0
+	Store v13, exec_ctx, 0x490
+	v14:i32 = Iconst_32 0x64
+	v15:i32 = Iadd v12, v14
+	Jump blk1, v15
+
+blk7: () <-- (blk2)
+	Jump blk8
+
+blk8: () <-- (blk7)
+	v16:i32 = Iconst_32 0x0
+	Jump blk_ret, v16
+`,
+		},
+		{
+			name: "float canonical nan - disabled", m: testcases.FloatCanonicalNaN.Module,
+			exp: `
+blk0: (exec_ctx:i64, module_ctx:i64, v2:f32, v3:f64, v4:v128)
+	v5:f32 = Fadd v2, v2
+	v6:f64 = Fneg v3
+	v7:v128 = VFmul.f32x4 v4, v4
+	Jump blk_ret, v5, v6, v7
+`,
+		},
+		{
+			name: "float canonical nan - enabled", m: testcases.FloatCanonicalNaN.Module,
+			canonicalNaN: true,
+			exp: `
+blk0: (exec_ctx:i64, module_ctx:i64, v2:f32, v3:f64, v4:v128)
+	v5:f32 = Fadd v2, v2
+	v6:i32 = Fcmp neq, v5, v5
+	v7:f32 = F32const NaN
+	v8:f32 = Select v6, v7, v5
+	v9:f64 = Fneg v3
+	v10:v128 = VFmul.f32x4 v4, v4
+	v11:v128 = VFcmp v10, v10
+	v12:v128 = Vconst 7fc000007fc00000 7fc000007fc00000
+	v13:v128 = Vbitselect v11, v12, v10
+	Jump blk_ret, v8, v9, v13
 `,
 		},
 	} {
@@ -1794,13 +2293,16 @@ blk0: (exec_ctx:i64, module_ctx:i64, v2:v128, v3:v128)
 		tc := tc
 		t.Run(tc.name, func(t *testing.T) {
 			// Just in case let's check the test module is valid.
-			err := tc.m.Validate(api.CoreFeaturesV2)
+			err := tc.m.Validate(api.CoreFeaturesV2|experimental.CoreFeaturesTailCall|experimental.CoreFeaturesRelaxedSIMD|
+				experimental.CoreFeaturesThreads|experimental.CoreFeaturesExceptionHandling, wasm.MaximumBlockNestingDepth)
 			require.NoError(t, err, "invalid test case module!")
 
 			b := ssa.NewBuilder()
 
 			offset := wazevoapi.NewModuleContextOffsetData(tc.m, tc.needListener)
 			fc := NewFrontendCompiler(tc.m, b, &offset, tc.ensureTermination, tc.needListener, false)
+			fc.SetMetering(tc.metering)
+			fc.SetCanonicalNaN(tc.canonicalNaN)
 			typeIndex := tc.m.FunctionSection[tc.targetIndex]
 			code := &tc.m.CodeSection[tc.targetIndex]
 			fc.Init(tc.targetIndex, typeIndex, &tc.m.TypeSection[typeIndex], code.LocalTypes, code.Body, tc.needListener, 0)
@@ -1934,3 +2436,140 @@ func TestCompiler_declareSignatures(t *testing.T) {
 		}
 	})
 }
+
+func TestCompiler_BoundsCheckStats(t *testing.T) {
+	load := func(offset byte) []byte {
+		return []byte{wasm.OpcodeLocalGet, 0, wasm.OpcodeI32Load, 0x2, offset, wasm.OpcodeDrop}
+	}
+	body := func(instrs ...[]byte) (ret []byte) {
+		for _, i := range instrs {
+			ret = append(ret, i...)
+		}
+		return append(ret, wasm.OpcodeEnd)
+	}
+
+	for _, tc := range []struct {
+		name                      string
+		body                      []byte
+		expEmitted, expEliminated int
+	}{
+		{name: "single", body: body(load(0)), expEmitted: 1},
+		{name: "covered by an earlier check", body: body(load(4), load(0), load(4)), expEmitted: 1, expEliminated: 2},
+		{name: "larger than an earlier check", body: body(load(0), load(4)), expEmitted: 2},
+		{
+			name:       "different block",
+			body:       body(load(0), []byte{wasm.OpcodeLoop, 0x40}, load(0), []byte{wasm.OpcodeEnd}),
+			expEmitted: 2,
+		},
+		{
+			name: "different address",
+			body: body(load(0), []byte{
+				wasm.OpcodeLocalGet, 0, wasm.OpcodeI32Const, 1, wasm.OpcodeI32Add, wasm.OpcodeI32Load, 0x2, 0, wasm.OpcodeDrop,
+			}),
+			expEmitted: 2,
+		},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			m := &wasm.Module{
+				TypeSection:     []wasm.FunctionType{{Params: []wasm.ValueType{wasm.ValueTypeI32}}},
+				MemorySection:   &wasm.Memory{Min: 1},
+				FunctionSection: []wasm.Index{0},
+				CodeSection:     []wasm.Code{{Body: tc.body}},
+			}
+			err := m.Validate(api.CoreFeaturesV2, wasm.MaximumBlockNestingDepth)
+			require.NoError(t, err, "invalid test case module!")
+
+			offset := wazevoapi.NewModuleContextOffsetData(m, false)
+			fc := NewFrontendCompiler(m, ssa.NewBuilder(), &offset, false, false, false)
+			fc.Init(0, 0, &m.TypeSection[0], nil, tc.body, false, 0)
+			fc.LowerToSSA()
+
+			emitted, eliminated := fc.BoundsCheckStats()
+			require.Equal(t, tc.expEmitted, emitted)
+			require.Equal(t, tc.expEliminated, eliminated)
+		})
+	}
+}
+
+func TestCompiler_Inlining(t *testing.T) {
+	i32_i32 := wasm.FunctionType{Params: []wasm.ValueType{wasm.ValueTypeI32}, Results: []wasm.ValueType{wasm.ValueTypeI32}}
+	caller := []byte{
+		wasm.OpcodeLocalGet, 0, wasm.OpcodeCall, 1,
+		wasm.OpcodeLocalGet, 0, wasm.OpcodeI32Add, wasm.OpcodeEnd,
+	}
+
+	for _, tc := range []struct {
+		name       string
+		callee     []byte
+		localTypes []wasm.ValueType
+		threshold  int
+		expInlined bool
+	}{
+		{
+			name:       "add",
+			callee:     []byte{wasm.OpcodeLocalGet, 0, wasm.OpcodeI32Const, 1, wasm.OpcodeI32Add, wasm.OpcodeEnd},
+			threshold:  10,
+			expInlined: true,
+		},
+		{
+			name: "return in block",
+			callee: []byte{
+				wasm.OpcodeBlock, 0x40,
+				wasm.OpcodeLocalGet, 0, wasm.OpcodeIf, 0x40, wasm.OpcodeLocalGet, 0, wasm.OpcodeReturn, wasm.OpcodeEnd,
+				wasm.OpcodeEnd,
+				wasm.OpcodeI32Const, 1, wasm.OpcodeEnd,
+			},
+			threshold:  10,
+			expInlined: true,
+		},
+		{
+			name: "locals",
+			callee: []byte{
+				wasm.OpcodeLocalGet, 0, wasm.OpcodeLocalSet, 1,
+				wasm.OpcodeLocalGet, 1, wasm.OpcodeEnd,
+			},
+			localTypes: []wasm.ValueType{wasm.ValueTypeI32},
+			threshold:  10,
+			expInlined: true,
+		},
+		{
+			name:       "unreachable",
+			callee:     []byte{wasm.OpcodeUnreachable, wasm.OpcodeEnd},
+			threshold:  10,
+			expInlined: true,
+		},
+		{
+			name:      "above threshold",
+			callee:    []byte{wasm.OpcodeLocalGet, 0, wasm.OpcodeI32Const, 1, wasm.OpcodeI32Add, wasm.OpcodeEnd},
+			threshold: 2,
+		},
+		{
+			name:   "disabled",
+			callee: []byte{wasm.OpcodeLocalGet, 0, wasm.OpcodeEnd},
+		},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			m := &wasm.Module{
+				TypeSection:     []wasm.FunctionType{i32_i32},
+				FunctionSection: []wasm.Index{0, 0},
+				CodeSection:     []wasm.Code{{Body: caller}, {Body: tc.callee, LocalTypes: tc.localTypes}},
+			}
+			err := m.Validate(api.CoreFeaturesV2, wasm.MaximumBlockNestingDepth)
+			require.NoError(t, err, "invalid test case module!")
+
+			b := ssa.NewBuilder()
+			offset := wazevoapi.NewModuleContextOffsetData(m, false)
+			fc := NewFrontendCompiler(m, b, &offset, false, false, false)
+			fc.SetInliningThreshold(tc.threshold)
+			fc.Init(0, 0, &m.TypeSection[0], nil, caller, false, 0)
+			fc.LowerToSSA()
+
+			require.Equal(t, !tc.expInlined, strings.Contains(fc.formatBuilder(), "Call "))
+
+			b.RunPasses()
+			b.LayoutBlocks()
+		})
+	}
+}
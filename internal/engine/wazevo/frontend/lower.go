@@ -29,13 +29,18 @@ type (
 		// originalStackLen holds the number of values on the Wasm stack
 		// when start executing this control frame minus params for the block.
 		originalStackLenWithoutParam int
-		// blk is the loop header if this is loop, and is the else-block if this is an if frame.
+		// blk is the loop header if this is loop, and is the else-block if this is an if frame. For a try frame, this
+		// is the handler which the exceptions thrown in its body branch to, and for a catch frame, this dispatches the
+		// exception to the next catch clause, or is nil if there's none or the exception is never thrown.
 		blk,
 		// followingBlock is the basic block we enter if we reach "end" of block.
 		followingBlock ssa.BasicBlock
 		blockType *wasm.FunctionType
 		// clonedArgs hold the arguments to Else block.
 		clonedArgs []ssa.Value
+		// exception holds the tag of the exception handled by a catch frame, followed by the values it carries in
+		// the body of a catch clause, which are stored again by rethrow.
+		exception []ssa.Value
 	}
 
 	controlFrameKind byte
@@ -64,6 +69,10 @@ const (
 	controlFrameKindIfWithElse
 	controlFrameKindIfWithoutElse
 	controlFrameKindBlock
+	// controlFrameKindTry is a try block whose body is being lowered.
+	controlFrameKindTry
+	// controlFrameKindCatch is a try block whose catch clauses are being lowered.
+	controlFrameKindCatch
 )
 
 // String implements fmt.Stringer for debugging.
@@ -79,6 +88,10 @@ func (k controlFrameKind) String() string {
 		return "if_without_else"
 	case controlFrameKindBlock:
 		return "block"
+	case controlFrameKindTry:
+		return "try"
+	case controlFrameKindCatch:
+		return "catch"
 	default:
 		panic(k)
 	}
@@ -154,6 +167,11 @@ func (l *loweringState) ctrlPeekAt(n int) (ret *controlFrame) {
 func (c *Compiler) lowerBody(entryBlk ssa.BasicBlock) {
 	c.ssaBuilder.Seal(entryBlk)
 
+	if c.metering {
+		c.insertEnterCallDepth()
+		c.insertConsumeFuel()
+	}
+
 	if c.needListener {
 		c.callListenerBefore()
 	}
@@ -185,6 +203,9 @@ func (c *Compiler) lowerCurrentOpcode() {
 
 	builder := c.ssaBuilder
 	state := c.state()
+	if c.memory64 && !state.unreachable {
+		c.narrowMemory64Operands(op)
+	}
 	switch op {
 	case wasm.OpcodeI32Const:
 		c := c.readI32s()
@@ -252,7 +273,7 @@ func (c *Compiler) lowerCurrentOpcode() {
 		iadd.AsFadd(x, y)
 		builder.InsertInstruction(iadd)
 		value := iadd.Return()
-		state.push(value)
+		state.push(c.canonicalizeNaN(value))
 	case wasm.OpcodeI32Mul, wasm.OpcodeI64Mul:
 		if state.unreachable {
 			break
@@ -272,7 +293,7 @@ func (c *Compiler) lowerCurrentOpcode() {
 		isub.AsFsub(x, y)
 		builder.InsertInstruction(isub)
 		value := isub.Return()
-		state.push(value)
+		state.push(c.canonicalizeNaN(value))
 	case wasm.OpcodeF32Mul, wasm.OpcodeF64Mul:
 		if state.unreachable {
 			break
@@ -282,7 +303,7 @@ func (c *Compiler) lowerCurrentOpcode() {
 		isub.AsFmul(x, y)
 		builder.InsertInstruction(isub)
 		value := isub.Return()
-		state.push(value)
+		state.push(c.canonicalizeNaN(value))
 	case wasm.OpcodeF32Div, wasm.OpcodeF64Div:
 		if state.unreachable {
 			break
@@ -292,7 +313,7 @@ func (c *Compiler) lowerCurrentOpcode() {
 		isub.AsFdiv(x, y)
 		builder.InsertInstruction(isub)
 		value := isub.Return()
-		state.push(value)
+		state.push(c.canonicalizeNaN(value))
 	case wasm.OpcodeF32Max, wasm.OpcodeF64Max:
 		if state.unreachable {
 			break
@@ -302,7 +323,7 @@ func (c *Compiler) lowerCurrentOpcode() {
 		isub.AsFmax(x, y)
 		builder.InsertInstruction(isub)
 		value := isub.Return()
-		state.push(value)
+		state.push(c.canonicalizeNaN(value))
 	case wasm.OpcodeF32Min, wasm.OpcodeF64Min:
 		if state.unreachable {
 			break
@@ -312,7 +333,7 @@ func (c *Compiler) lowerCurrentOpcode() {
 		isub.AsFmin(x, y)
 		builder.InsertInstruction(isub)
 		value := isub.Return()
-		state.push(value)
+		state.push(c.canonicalizeNaN(value))
 	case wasm.OpcodeI64Extend8S:
 		if state.unreachable {
 			break
@@ -454,7 +475,7 @@ func (c *Compiler) lowerCurrentOpcode() {
 		}
 		x := state.pop()
 		v := builder.AllocateInstruction().AsSqrt(x).Insert(builder).Return()
-		state.push(v)
+		state.push(c.canonicalizeNaN(v))
 	case wasm.OpcodeF32Abs, wasm.OpcodeF64Abs:
 		if state.unreachable {
 			break
@@ -476,28 +497,28 @@ func (c *Compiler) lowerCurrentOpcode() {
 		}
 		x := state.pop()
 		v := builder.AllocateInstruction().AsCeil(x).Insert(builder).Return()
-		state.push(v)
+		state.push(c.canonicalizeNaN(v))
 	case wasm.OpcodeF32Floor, wasm.OpcodeF64Floor:
 		if state.unreachable {
 			break
 		}
 		x := state.pop()
 		v := builder.AllocateInstruction().AsFloor(x).Insert(builder).Return()
-		state.push(v)
+		state.push(c.canonicalizeNaN(v))
 	case wasm.OpcodeF32Trunc, wasm.OpcodeF64Trunc:
 		if state.unreachable {
 			break
 		}
 		x := state.pop()
 		v := builder.AllocateInstruction().AsTrunc(x).Insert(builder).Return()
-		state.push(v)
+		state.push(c.canonicalizeNaN(v))
 	case wasm.OpcodeF32Nearest, wasm.OpcodeF64Nearest:
 		if state.unreachable {
 			break
 		}
 		x := state.pop()
 		v := builder.AllocateInstruction().AsNearest(x).Insert(builder).Return()
-		state.push(v)
+		state.push(c.canonicalizeNaN(v))
 	case wasm.OpcodeI64TruncF64S, wasm.OpcodeI64TruncF32S,
 		wasm.OpcodeI32TruncF64S, wasm.OpcodeI32TruncF32S,
 		wasm.OpcodeI64TruncF64U, wasm.OpcodeI64TruncF32U,
@@ -617,7 +638,8 @@ func (c *Compiler) lowerCurrentOpcode() {
 			c.callMemmove(dstAddr, srcAddr, copySizeInBytes)
 
 		case wasm.OpcodeMiscMemoryCopy:
-			state.pc += 2 // +2 to skip two memory indexes which are fixed to zero.
+			c.readI32u() // Skip the memory indexes which are zero as there is one memory.
+			c.readI32u()
 			if state.unreachable {
 				break
 			}
@@ -722,7 +744,7 @@ func (c *Compiler) lowerCurrentOpcode() {
 			builder.Seal(followingBlk)
 
 		case wasm.OpcodeMiscMemoryFill:
-			state.pc++ // Skip the memory index which is fixed to zero.
+			c.readI32u() // Skip the memory index which is zero as there is one memory.
 			if state.unreachable {
 				break
 			}
@@ -799,7 +821,7 @@ func (c *Compiler) lowerCurrentOpcode() {
 
 		case wasm.OpcodeMiscMemoryInit:
 			index := c.readI32u()
-			state.pc++ // Skip the memory index which is fixed to zero.
+			c.readI32u() // Skip the memory index which is zero as there is one memory.
 			if state.unreachable {
 				break
 			}
@@ -1124,7 +1146,7 @@ func (c *Compiler) lowerCurrentOpcode() {
 		state.push(sl)
 
 	case wasm.OpcodeMemorySize:
-		state.pc++ // skips the memory index.
+		c.readI32u() // skips the memory index.
 		if state.unreachable {
 			break
 		}
@@ -1154,10 +1176,13 @@ func (c *Compiler) lowerCurrentOpcode() {
 			AsUshr(memSizeInBytes, amount.Return()).
 			Insert(builder).
 			Return()
+		if c.memory64 {
+			memSize = builder.AllocateInstruction().AsUExtend(memSize, 32, 64).Insert(builder).Return()
+		}
 		state.push(memSize)
 
 	case wasm.OpcodeMemoryGrow:
-		state.pc++ // skips the memory index.
+		c.readI32u() // skips the memory index.
 		if state.unreachable {
 			break
 		}
@@ -1178,6 +1203,10 @@ func (c *Compiler) lowerCurrentOpcode() {
 			AllocateInstruction().
 			AsCallIndirect(memoryGrowPtr, &c.memoryGrowSig, args).
 			Insert(builder).Return()
+		if c.memory64 {
+			// Sign-extended so that the failure is still -1.
+			callGrowRet = builder.AllocateInstruction().AsSExtend(callGrowRet, 32, 64).Insert(builder).Return()
+		}
 		state.push(callGrowRet)
 
 		// After the memory grow, reload the cached memory base and len.
@@ -1322,6 +1351,25 @@ func (c *Compiler) lowerCurrentOpcode() {
 			followingBlock:               followingBlk,
 			blockType:                    bt,
 		})
+	case wasm.OpcodeTry:
+		bt := c.readBlockType()
+
+		if state.unreachable {
+			state.unreachableDepth++
+			break
+		}
+
+		// Like a block, the body is lowered in the current BB. Any exception thrown in it branches to the handler.
+		followingBlk, handlerBlk := builder.AllocateBasicBlock(), builder.AllocateBasicBlock()
+		c.addBlockParamsFromWasmTypes(bt.Results, followingBlk)
+
+		state.ctrlPush(controlFrame{
+			kind:                         controlFrameKindTry,
+			originalStackLenWithoutParam: len(state.values) - len(bt.Params),
+			blk:                          handlerBlk,
+			followingBlock:               followingBlk,
+			blockType:                    bt,
+		})
 	case wasm.OpcodeLoop:
 		bt := c.readBlockType()
 
@@ -1356,17 +1404,10 @@ func (c *Compiler) lowerCurrentOpcode() {
 		c.switchTo(originalLen, loopHeader)
 
 		if c.ensureTermination {
-			checkModuleExitCodePtr := builder.AllocateInstruction().
-				AsLoad(c.execCtxPtrValue,
-					wazevoapi.ExecutionContextOffsetCheckModuleExitCodeTrampolineAddress.U32(),
-					ssa.TypeI64,
-				).Insert(builder).Return()
-
-			c.checkModuleExitCodeArg[0] = c.execCtxPtrValue
-
-			builder.AllocateInstruction().
-				AsCallIndirect(checkModuleExitCodePtr, &c.checkModuleExitCodeSig, c.checkModuleExitCodeArg[:]).
-				Insert(builder)
+			c.insertCheckModuleExitCode()
+		}
+		if c.metering {
+			c.insertConsumeFuel()
 		}
 	case wasm.OpcodeIf:
 		bt := c.readBlockType()
@@ -1442,6 +1483,101 @@ func (c *Compiler) lowerCurrentOpcode() {
 
 		builder.SetCurrentBlock(elseBlk)
 
+	case wasm.OpcodeCatch, wasm.OpcodeCatchAll:
+		var tagIndex uint32
+		if op == wasm.OpcodeCatch {
+			tagIndex = c.readI32u()
+		}
+		if state.unreachableDepth > 0 {
+			// The entire try block is unreachable.
+			break
+		}
+
+		ctrl := state.ctrlPeekAt(0)
+		if !state.unreachable {
+			// The try body or the previous catch clause ends with the branch to the following BB.
+			args := c.loweringState.nPeekDup(len(ctrl.blockType.Results))
+			c.insertJumpToBlock(args, ctrl.followingBlock)
+		}
+		state.values = state.values[:ctrl.originalStackLenWithoutParam]
+
+		if ctrl.kind == controlFrameKindTry {
+			// All the exceptions thrown in the try body have branched to the handler by now.
+			handlerBlk := ctrl.blk
+			builder.Seal(handlerBlk)
+			ctrl.kind = controlFrameKindCatch
+			if handlerBlk.Preds() == 0 {
+				ctrl.blk = nil
+			} else {
+				builder.SetCurrentBlock(handlerBlk)
+				thrownTag := builder.AllocateInstruction().
+					AsLoad(c.execCtxPtrValue, wazevoapi.ExecutionContextOffsetExceptionTag.U32(), ssa.TypeI64).
+					Insert(builder).Return()
+				ctrl.exception = []ssa.Value{thrownTag}
+			}
+		}
+
+		dispatchBlk := ctrl.blk
+		if dispatchBlk == nil {
+			// No exception reaches this clause.
+			state.unreachable = true
+			break
+		}
+		state.unreachable = false
+		builder.SetCurrentBlock(dispatchBlk)
+
+		thrownTag := ctrl.exception[0]
+		if op == wasm.OpcodeCatch {
+			tag := c.loadTag(tagIndex)
+			caught := builder.AllocateInstruction().
+				AsIcmp(thrownTag, tag, ssa.IntegerCmpCondEqual).
+				Insert(builder).Return()
+			caughtBlk, nextBlk := builder.AllocateBasicBlock(), builder.AllocateBasicBlock()
+			brnz := builder.AllocateInstruction()
+			brnz.AsBrnz(caught, nil, caughtBlk)
+			builder.InsertInstruction(brnz)
+			c.insertJumpToBlock(nil, nextBlk)
+			builder.Seal(caughtBlk)
+			builder.Seal(nextBlk)
+
+			ctrl.blk = nextBlk
+			builder.SetCurrentBlock(caughtBlk)
+			ctrl.exception = c.catchException(thrownTag, c.m.TagType(tagIndex))
+			for _, v := range ctrl.exception[1:] {
+				state.push(v)
+			}
+		} else {
+			ctrl.blk = nil
+			ctrl.exception = c.catchException(thrownTag, nil)
+		}
+
+	case wasm.OpcodeDelegate:
+		labelIndex := c.readI32u()
+		if state.unreachableDepth > 0 {
+			// Like end, this closes an unreachable try block.
+			state.unreachableDepth--
+			break
+		}
+
+		ctrl := state.ctrlPop()
+		if !state.unreachable {
+			args := c.loweringState.nPeekDup(len(ctrl.blockType.Results))
+			c.insertJumpToBlock(args, ctrl.followingBlock)
+		} else {
+			state.unreachable = false
+		}
+
+		// The exceptions thrown in the try body are delegated to the handler of the label.
+		handlerBlk := ctrl.blk
+		builder.Seal(handlerBlk)
+		if handlerBlk.Preds() > 0 {
+			builder.SetCurrentBlock(handlerBlk)
+			c.insertJumpToBlock(nil, c.exceptionHandler(int(labelIndex)))
+		}
+
+		builder.Seal(ctrl.followingBlock)
+		c.switchTo(ctrl.originalStackLenWithoutParam, ctrl.followingBlock)
+
 	case wasm.OpcodeEnd:
 		if state.unreachableDepth > 0 {
 			state.unreachableDepth--
@@ -1464,7 +1600,10 @@ func (c *Compiler) lowerCurrentOpcode() {
 
 		switch ctrl.kind {
 		case controlFrameKindFunction:
-			break // This is the very end of function.
+			// This is the very end of function, so all the branches to the exception propagation are lowered.
+			if c.exceptionPropagationBlk != nil {
+				c.lowerExceptionPropagation()
+			}
 		case controlFrameKindLoop:
 			// Loop header block can be reached from any br/br_table contained in the loop,
 			// so now that we've reached End of it, we can seal it.
@@ -1474,6 +1613,18 @@ func (c *Compiler) lowerCurrentOpcode() {
 			elseBlk := ctrl.blk
 			builder.SetCurrentBlock(elseBlk)
 			c.insertJumpToBlock(ctrl.clonedArgs, followingBlk)
+		case controlFrameKindTry, controlFrameKindCatch:
+			// The exceptions which aren't caught by any catch clause propagate to the enclosing handler.
+			if ctrl.kind == controlFrameKindTry {
+				builder.Seal(ctrl.blk)
+				if ctrl.blk.Preds() == 0 {
+					ctrl.blk = nil
+				}
+			}
+			if blk := ctrl.blk; blk != nil {
+				builder.SetCurrentBlock(blk)
+				c.insertJumpToBlock(nil, c.exceptionHandler(0))
+			}
 		}
 
 		builder.Seal(ctrl.followingBlock)
@@ -1504,10 +1655,23 @@ func (c *Compiler) lowerCurrentOpcode() {
 		targetBlk, argNum := state.brTargetArgNumFor(labelIndex)
 		args := c.loweringState.nPeekDup(argNum)
 
-		// Insert the conditional jump to the target block.
-		brnz := builder.AllocateInstruction()
-		brnz.AsBrnz(v, args, targetBlk)
-		builder.InsertInstruction(brnz)
+		if c.metering && targetBlk.ReturnBlock() {
+			// The call depth has to be restored before returning, so branch via a trampoline block.
+			trampoline := builder.AllocateBasicBlock()
+			brnz := builder.AllocateInstruction()
+			brnz.AsBrnz(v, nil, trampoline)
+			builder.InsertInstruction(brnz)
+			currentBlk := builder.CurrentBlock()
+			builder.SetCurrentBlock(trampoline)
+			c.insertJumpToBlock(args, targetBlk)
+			builder.Seal(trampoline)
+			builder.SetCurrentBlock(currentBlk)
+		} else {
+			// Insert the conditional jump to the target block.
+			brnz := builder.AllocateInstruction()
+			brnz.AsBrnz(v, args, targetBlk)
+			builder.InsertInstruction(brnz)
+		}
 
 		// Insert the unconditional jump to the Else block which corresponds to after br_if.
 		elseBlk := builder.AllocateBasicBlock()
@@ -1544,16 +1708,7 @@ func (c *Compiler) lowerCurrentOpcode() {
 		if state.unreachable {
 			break
 		}
-		if c.needListener {
-			c.callListenerAfter()
-		}
-
-		results := c.loweringState.nPeekDup(c.results())
-		instr := builder.AllocateInstruction()
-
-		instr.AsReturn(results)
-		builder.InsertInstruction(instr)
-		state.unreachable = true
+		c.lowerReturn()
 
 	case wasm.OpcodeUnreachable:
 		if state.unreachable {
@@ -1564,19 +1719,45 @@ func (c *Compiler) lowerCurrentOpcode() {
 		builder.InsertInstruction(exit)
 		state.unreachable = true
 
-	case wasm.OpcodeCallIndirect:
+	case wasm.OpcodeThrow:
+		tagIndex := c.readI32u()
+		if state.unreachable {
+			break
+		}
+
+		payload := make([]ssa.Value, len(c.m.TagType(tagIndex).Params))
+		state.nPopInto(len(payload), payload)
+		c.throwException(c.loadTag(tagIndex), payload)
+		state.unreachable = true
+
+	case wasm.OpcodeRethrow:
+		labelIndex := c.readI32u()
+		if state.unreachable {
+			break
+		}
+
+		exception := state.ctrlPeekAt(int(labelIndex)).exception
+		c.throwException(exception[0], exception[1:])
+		state.unreachable = true
+
+	case wasm.OpcodeCallIndirect, wasm.OpcodeReturnCallIndirect:
 		typeIndex := c.readI32u()
 		tableIndex := c.readI32u()
 		if state.unreachable {
 			break
 		}
-		c.lowerCallIndirect(typeIndex, tableIndex)
+		c.lowerCallIndirect(typeIndex, tableIndex, op == wasm.OpcodeReturnCallIndirect)
 
-	case wasm.OpcodeCall:
+	case wasm.OpcodeCall, wasm.OpcodeReturnCall:
 		fnIndex := c.readI32u()
 		if state.unreachable {
 			break
 		}
+		if op == wasm.OpcodeCall && c.isInlinable(fnIndex) {
+			c.lowerInlinedCall(fnIndex)
+			break
+		}
+		tail := c.prepareReturnCall(op == wasm.OpcodeReturnCall)
 
 		// Before transfer the control to the callee, we have to store the current module's moduleContextPtr
 		// into execContext.callerModuleContextPtr in case when the callee is a Go function.
@@ -1612,7 +1793,11 @@ func (c *Compiler) lowerCurrentOpcode() {
 		call := builder.AllocateInstruction()
 		if fnIndex >= c.m.ImportFunctionCount {
 			args[1] = c.moduleCtxPtrValue // This case the callee module is itself.
-			call.AsCall(FunctionIndexToFuncRef(fnIndex), sig, args)
+			if tail {
+				call.AsReturnCall(FunctionIndexToFuncRef(fnIndex), sig, args)
+			} else {
+				call.AsCall(FunctionIndexToFuncRef(fnIndex), sig, args)
+			}
 			builder.InsertInstruction(call)
 		} else {
 			// This case we have to read the address of the imported function from the module context.
@@ -1626,19 +1811,14 @@ func (c *Compiler) lowerCurrentOpcode() {
 
 			args[1] = loadModuleCtxPtr.Return() // This case the callee module is itself.
 
-			call.AsCallIndirect(loadFuncPtr.Return(), sig, args)
+			if tail {
+				call.AsReturnCallIndirect(loadFuncPtr.Return(), sig, args)
+			} else {
+				call.AsCallIndirect(loadFuncPtr.Return(), sig, args)
+			}
 			builder.InsertInstruction(call)
 		}
-
-		first, rest := call.Returns()
-		if first.Valid() {
-			state.push(first)
-		}
-		for _, v := range rest {
-			state.push(v)
-		}
-
-		c.reloadAfterCall()
+		c.finishCall(call, op == wasm.OpcodeReturnCall, tail)
 
 	case wasm.OpcodeDrop:
 		if state.unreachable {
@@ -1672,7 +1852,7 @@ func (c *Compiler) lowerCurrentOpcode() {
 		cvt := builder.AllocateInstruction()
 		cvt.AsFdemote(state.pop())
 		builder.InsertInstruction(cvt)
-		state.push(cvt.Return())
+		state.push(c.canonicalizeNaN(cvt.Return()))
 	case wasm.OpcodeF64PromoteF32:
 		if state.unreachable {
 			break
@@ -1680,10 +1860,28 @@ func (c *Compiler) lowerCurrentOpcode() {
 		cvt := builder.AllocateInstruction()
 		cvt.AsFpromote(state.pop())
 		builder.InsertInstruction(cvt)
-		state.push(cvt.Return())
+		state.push(c.canonicalizeNaN(cvt.Return()))
 
+	case wasm.OpcodeAtomicPrefix:
+		state.pc++
+		atomicOp := c.wasmFunctionBody[state.pc]
+		if atomicOp == wasm.OpcodeAtomicFence {
+			state.pc++ // reserved byte
+			break
+		}
+		_, offset := c.readMemArg()
+		if !state.unreachable {
+			c.lowerAtomic(atomicOp, offset)
+		}
 	case wasm.OpcodeVecPrefix:
 		state.pc++
+		if relaxedOp, ok := wasm.VecRelaxedOpcode(c.wasmFunctionBody[state.pc:]); ok {
+			state.pc++ // relaxed-SIMD opcodes take two bytes.
+			if !state.unreachable {
+				c.lowerVecRelaxed(relaxedOp)
+			}
+			break
+		}
 		vecOp := c.wasmFunctionBody[state.pc]
 		switch vecOp {
 		case wasm.OpcodeVecV128Const:
@@ -2425,7 +2623,7 @@ func (c *Compiler) lowerCurrentOpcode() {
 			v2 := state.pop()
 			v1 := state.pop()
 			ret := builder.AllocateInstruction().AsVFmax(v1, v2, lane).Insert(builder).Return()
-			state.push(ret)
+			state.push(c.canonicalizeVecNaN(ret, lane))
 		case wasm.OpcodeVecF32x4Abs, wasm.OpcodeVecF64x2Abs:
 			if state.unreachable {
 				break
@@ -2454,7 +2652,7 @@ func (c *Compiler) lowerCurrentOpcode() {
 			v2 := state.pop()
 			v1 := state.pop()
 			ret := builder.AllocateInstruction().AsVFmin(v1, v2, lane).Insert(builder).Return()
-			state.push(ret)
+			state.push(c.canonicalizeVecNaN(ret, lane))
 		case wasm.OpcodeVecF32x4Neg, wasm.OpcodeVecF64x2Neg:
 			if state.unreachable {
 				break
@@ -2482,7 +2680,7 @@ func (c *Compiler) lowerCurrentOpcode() {
 			}
 			v1 := state.pop()
 			ret := builder.AllocateInstruction().AsVSqrt(v1, lane).Insert(builder).Return()
-			state.push(ret)
+			state.push(c.canonicalizeVecNaN(ret, lane))
 
 		case wasm.OpcodeVecF32x4Add, wasm.OpcodeVecF64x2Add:
 			if state.unreachable {
@@ -2498,7 +2696,7 @@ func (c *Compiler) lowerCurrentOpcode() {
 			v2 := state.pop()
 			v1 := state.pop()
 			ret := builder.AllocateInstruction().AsVFadd(v1, v2, lane).Insert(builder).Return()
-			state.push(ret)
+			state.push(c.canonicalizeVecNaN(ret, lane))
 		case wasm.OpcodeVecF32x4Sub, wasm.OpcodeVecF64x2Sub:
 			if state.unreachable {
 				break
@@ -2513,7 +2711,7 @@ func (c *Compiler) lowerCurrentOpcode() {
 			v2 := state.pop()
 			v1 := state.pop()
 			ret := builder.AllocateInstruction().AsVFsub(v1, v2, lane).Insert(builder).Return()
-			state.push(ret)
+			state.push(c.canonicalizeVecNaN(ret, lane))
 		case wasm.OpcodeVecF32x4Mul, wasm.OpcodeVecF64x2Mul:
 			if state.unreachable {
 				break
@@ -2528,7 +2726,7 @@ func (c *Compiler) lowerCurrentOpcode() {
 			v2 := state.pop()
 			v1 := state.pop()
 			ret := builder.AllocateInstruction().AsVFmul(v1, v2, lane).Insert(builder).Return()
-			state.push(ret)
+			state.push(c.canonicalizeVecNaN(ret, lane))
 		case wasm.OpcodeVecF32x4Div, wasm.OpcodeVecF64x2Div:
 			if state.unreachable {
 				break
@@ -2543,7 +2741,7 @@ func (c *Compiler) lowerCurrentOpcode() {
 			v2 := state.pop()
 			v1 := state.pop()
 			ret := builder.AllocateInstruction().AsVFdiv(v1, v2, lane).Insert(builder).Return()
-			state.push(ret)
+			state.push(c.canonicalizeVecNaN(ret, lane))
 
 		case wasm.OpcodeVecI16x8ExtaddPairwiseI8x16S, wasm.OpcodeVecI16x8ExtaddPairwiseI8x16U:
 			if state.unreachable {
@@ -2768,7 +2966,7 @@ func (c *Compiler) lowerCurrentOpcode() {
 			}
 			v1 := state.pop()
 			ret := builder.AllocateInstruction().AsVCeil(v1, lane).Insert(builder).Return()
-			state.push(ret)
+			state.push(c.canonicalizeVecNaN(ret, lane))
 		case wasm.OpcodeVecF32x4Floor, wasm.OpcodeVecF64x2Floor:
 			if state.unreachable {
 				break
@@ -2782,7 +2980,7 @@ func (c *Compiler) lowerCurrentOpcode() {
 			}
 			v1 := state.pop()
 			ret := builder.AllocateInstruction().AsVFloor(v1, lane).Insert(builder).Return()
-			state.push(ret)
+			state.push(c.canonicalizeVecNaN(ret, lane))
 		case wasm.OpcodeVecF32x4Trunc, wasm.OpcodeVecF64x2Trunc:
 			if state.unreachable {
 				break
@@ -2796,7 +2994,7 @@ func (c *Compiler) lowerCurrentOpcode() {
 			}
 			v1 := state.pop()
 			ret := builder.AllocateInstruction().AsVTrunc(v1, lane).Insert(builder).Return()
-			state.push(ret)
+			state.push(c.canonicalizeVecNaN(ret, lane))
 		case wasm.OpcodeVecF32x4Nearest, wasm.OpcodeVecF64x2Nearest:
 			if state.unreachable {
 				break
@@ -2810,7 +3008,7 @@ func (c *Compiler) lowerCurrentOpcode() {
 			}
 			v1 := state.pop()
 			ret := builder.AllocateInstruction().AsVNearest(v1, lane).Insert(builder).Return()
-			state.push(ret)
+			state.push(c.canonicalizeVecNaN(ret, lane))
 		case wasm.OpcodeVecF32x4Pmin, wasm.OpcodeVecF64x2Pmin:
 			if state.unreachable {
 				break
@@ -2959,7 +3157,7 @@ func (c *Compiler) lowerCurrentOpcode() {
 			ret := builder.AllocateInstruction().
 				AsFvpromoteLow(v1, ssa.VecLaneF32x4).
 				Insert(builder).Return()
-			state.push(ret)
+			state.push(c.canonicalizeVecNaN(ret, ssa.VecLaneF64x2))
 		case wasm.OpcodeVecF32x4DemoteF64x2Zero:
 			if state.unreachable {
 				break
@@ -2968,7 +3166,7 @@ func (c *Compiler) lowerCurrentOpcode() {
 			ret := builder.AllocateInstruction().
 				AsFvdemote(v1, ssa.VecLaneF64x2).
 				Insert(builder).Return()
-			state.push(ret)
+			state.push(c.canonicalizeVecNaN(ret, ssa.VecLaneF32x4))
 		case wasm.OpcodeVecI8x16Shl, wasm.OpcodeVecI16x8Shl, wasm.OpcodeVecI32x4Shl, wasm.OpcodeVecI64x2Shl:
 			if state.unreachable {
 				break
@@ -3222,6 +3420,245 @@ func (c *Compiler) lowerCurrentOpcode() {
 	c.loweringState.pc++
 }
 
+// lowerAtomic lowers the atomic instruction which accesses memory as its non-atomic equivalents, with the
+// single-threaded semantics documented on experimental.CoreFeaturesThreads.
+func (c *Compiler) lowerAtomic(op wasm.OpcodeAtomic, offset uint32) {
+	builder := c.ssaBuilder
+	state := c.state()
+	t, size := wasm.AtomicMemoryAccess(op)
+	is64 := t == wasm.ValueTypeI64
+
+	var operands [3]ssa.Value
+	var operandCount int
+	switch {
+	case op == wasm.OpcodeAtomicMemoryWait32 || op == wasm.OpcodeAtomicMemoryWait64 || op >= wasm.OpcodeAtomicI32RmwCmpxchg:
+		operandCount = 3
+	case op == wasm.OpcodeAtomicMemoryNotify || op >= wasm.OpcodeAtomicI32Store:
+		operandCount = 2
+	default:
+		operandCount = 1
+	}
+	for i := operandCount - 1; i >= 0; i-- {
+		operands[i] = state.pop()
+	}
+	baseAddr := operands[0]
+
+	if size > 1 {
+		// The low bits of the effective address are the same when the addition wraps.
+		ea := baseAddr
+		if offset != 0 {
+			offsetConst := builder.AllocateInstruction().AsIconst32(offset).Insert(builder).Return()
+			ea = builder.AllocateInstruction().AsIadd(baseAddr, offsetConst).Insert(builder).Return()
+		}
+		mask := builder.AllocateInstruction().AsIconst32(size - 1).Insert(builder).Return()
+		band := builder.AllocateInstruction()
+		band.AsBand(ea, mask)
+		builder.InsertInstruction(band)
+		zero := builder.AllocateInstruction().AsIconst32(0).Insert(builder).Return()
+		unaligned := builder.AllocateInstruction().
+			AsIcmp(band.Return(), zero, ssa.IntegerCmpCondNotEqual).
+			Insert(builder).Return()
+		builder.AllocateInstruction().
+			AsExitIfTrueWithCode(c.execCtxPtrValue, unaligned, wazevoapi.ExitCodeUnalignedAtomic).
+			Insert(builder)
+	}
+
+	addr := c.memOpSetup(baseAddr, uint64(offset), uint64(size))
+	load := func() ssa.Value {
+		load := builder.AllocateInstruction()
+		switch size {
+		case 1:
+			load.AsExtLoad(ssa.OpcodeUload8, addr, offset, is64)
+		case 2:
+			load.AsExtLoad(ssa.OpcodeUload16, addr, offset, is64)
+		case 4:
+			if is64 {
+				load.AsExtLoad(ssa.OpcodeUload32, addr, offset, true)
+			} else {
+				load.AsLoad(addr, offset, ssa.TypeI32)
+			}
+		default:
+			load.AsLoad(addr, offset, ssa.TypeI64)
+		}
+		builder.InsertInstruction(load)
+		return load.Return()
+	}
+	store := func(v ssa.Value) {
+		storeOp := ssa.OpcodeStore
+		switch {
+		case size == 1:
+			storeOp = ssa.OpcodeIstore8
+		case size == 2:
+			storeOp = ssa.OpcodeIstore16
+		case size == 4 && is64:
+			storeOp = ssa.OpcodeIstore32
+		}
+		builder.AllocateInstruction().AsStore(storeOp, v, addr, offset).Insert(builder)
+	}
+
+	switch {
+	case op == wasm.OpcodeAtomicMemoryNotify:
+		// There are no waiters, but the address is still checked.
+		load()
+		state.push(builder.AllocateInstruction().AsIconst32(0).Insert(builder).Return())
+	case op == wasm.OpcodeAtomicMemoryWait32 || op == wasm.OpcodeAtomicMemoryWait64:
+		// One ("not-equal") or two ("timed-out") as there is no other thread to notify.
+		ne := builder.AllocateInstruction().
+			AsIcmp(load(), operands[1], ssa.IntegerCmpCondNotEqual).
+			Insert(builder).Return()
+		two := builder.AllocateInstruction().AsIconst32(2).Insert(builder).Return()
+		state.push(builder.AllocateInstruction().AsIsub(two, ne).Insert(builder).Return())
+	case op < wasm.OpcodeAtomicI32Store:
+		state.push(load())
+	case op < wasm.OpcodeAtomicI32RmwAdd:
+		store(operands[1])
+	case op < wasm.OpcodeAtomicI32RmwCmpxchg:
+		old, v := load(), operands[1]
+		rmw := builder.AllocateInstruction()
+		switch (op - wasm.OpcodeAtomicI32RmwAdd) / 7 {
+		case 0:
+			rmw.AsIadd(old, v)
+		case 1:
+			rmw.AsIsub(old, v)
+		case 2:
+			rmw.AsBand(old, v)
+		case 3:
+			rmw.AsBor(old, v)
+		case 4:
+			rmw.AsBxor(old, v)
+		default: // xchg
+			rmw = nil
+		}
+		if rmw != nil {
+			builder.InsertInstruction(rmw)
+			v = rmw.Return()
+		}
+		store(v)
+		state.push(old)
+	default:
+		// The expected value is compared with the loaded one, which is zero-extended.
+		old, expected := load(), operands[1]
+		if bits := uint64(size) * 8; is64 && bits < 64 {
+			mask := builder.AllocateInstruction().AsIconst64(1<<bits - 1).Insert(builder).Return()
+			band := builder.AllocateInstruction()
+			band.AsBand(expected, mask)
+			builder.InsertInstruction(band)
+			expected = band.Return()
+		} else if !is64 && bits < 32 {
+			mask := builder.AllocateInstruction().AsIconst32(1<<bits - 1).Insert(builder).Return()
+			band := builder.AllocateInstruction()
+			band.AsBand(expected, mask)
+			builder.InsertInstruction(band)
+			expected = band.Return()
+		}
+		eq := builder.AllocateInstruction().
+			AsIcmp(old, expected, ssa.IntegerCmpCondEqual).
+			Insert(builder).Return()
+		store(builder.AllocateInstruction().AsSelect(eq, operands[2], old).Insert(builder).Return())
+		state.push(old)
+	}
+}
+
+// lowerVecRelaxed lowers the relaxed-SIMD instruction as the deterministic instructions documented on
+// experimental.CoreFeaturesRelaxedSIMD, so that the results match the other engines on every ISA.
+func (c *Compiler) lowerVecRelaxed(op wasm.OpcodeVecRelaxed) {
+	builder := c.ssaBuilder
+	state := c.state()
+	switch op {
+	case wasm.OpcodeVecRelaxedI8x16Swizzle:
+		v2 := state.pop()
+		v1 := state.pop()
+		ret := builder.AllocateInstruction().AsSwizzle(v1, v2, ssa.VecLaneI8x16).Insert(builder).Return()
+		state.push(ret)
+	case wasm.OpcodeVecRelaxedI32x4TruncF32x4S, wasm.OpcodeVecRelaxedI32x4TruncF32x4U:
+		v1 := state.pop()
+		ret := builder.AllocateInstruction().
+			AsVFcvtToIntSat(v1, ssa.VecLaneF32x4, op == wasm.OpcodeVecRelaxedI32x4TruncF32x4S).Insert(builder).Return()
+		state.push(ret)
+	case wasm.OpcodeVecRelaxedI32x4TruncF64x2SZero, wasm.OpcodeVecRelaxedI32x4TruncF64x2UZero:
+		v1 := state.pop()
+		ret := builder.AllocateInstruction().
+			AsVFcvtToIntSat(v1, ssa.VecLaneF64x2, op == wasm.OpcodeVecRelaxedI32x4TruncF64x2SZero).Insert(builder).Return()
+		state.push(ret)
+	case wasm.OpcodeVecRelaxedF32x4Madd, wasm.OpcodeVecRelaxedF32x4Nmadd,
+		wasm.OpcodeVecRelaxedF64x2Madd, wasm.OpcodeVecRelaxedF64x2Nmadd:
+		lane := ssa.VecLaneF32x4
+		if op == wasm.OpcodeVecRelaxedF64x2Madd || op == wasm.OpcodeVecRelaxedF64x2Nmadd {
+			lane = ssa.VecLaneF64x2
+		}
+		v3 := state.pop()
+		v2 := state.pop()
+		v1 := state.pop()
+		// The multiplication is not fused with the addition, so that the product is rounded on every ISA.
+		mul := builder.AllocateInstruction().AsVFmul(v1, v2, lane).Insert(builder).Return()
+		if op == wasm.OpcodeVecRelaxedF32x4Nmadd || op == wasm.OpcodeVecRelaxedF64x2Nmadd {
+			mul = builder.AllocateInstruction().AsVFneg(mul, lane).Insert(builder).Return()
+		}
+		ret := builder.AllocateInstruction().AsVFadd(mul, v3, lane).Insert(builder).Return()
+		state.push(ret)
+	case wasm.OpcodeVecRelaxedI8x16Laneselect, wasm.OpcodeVecRelaxedI16x8Laneselect,
+		wasm.OpcodeVecRelaxedI32x4Laneselect, wasm.OpcodeVecRelaxedI64x2Laneselect:
+		v3 := state.pop()
+		v2 := state.pop()
+		v1 := state.pop()
+		ret := builder.AllocateInstruction().AsVbitselect(v3, v1, v2).Insert(builder).Return()
+		state.push(ret)
+	case wasm.OpcodeVecRelaxedF32x4Min, wasm.OpcodeVecRelaxedF64x2Min:
+		lane := ssa.VecLaneF32x4
+		if op == wasm.OpcodeVecRelaxedF64x2Min {
+			lane = ssa.VecLaneF64x2
+		}
+		v2 := state.pop()
+		v1 := state.pop()
+		ret := builder.AllocateInstruction().AsVFmin(v1, v2, lane).Insert(builder).Return()
+		state.push(ret)
+	case wasm.OpcodeVecRelaxedF32x4Max, wasm.OpcodeVecRelaxedF64x2Max:
+		lane := ssa.VecLaneF32x4
+		if op == wasm.OpcodeVecRelaxedF64x2Max {
+			lane = ssa.VecLaneF64x2
+		}
+		v2 := state.pop()
+		v1 := state.pop()
+		ret := builder.AllocateInstruction().AsVFmax(v1, v2, lane).Insert(builder).Return()
+		state.push(ret)
+	case wasm.OpcodeVecRelaxedI16x8Q15mulrS:
+		v2 := state.pop()
+		v1 := state.pop()
+		ret := builder.AllocateInstruction().AsSqmulRoundSat(v1, v2, ssa.VecLaneI16x8).Insert(builder).Return()
+		state.push(ret)
+	case wasm.OpcodeVecRelaxedI16x8DotI8x16I7x16S:
+		v2 := state.pop()
+		v1 := state.pop()
+		state.push(c.lowerVecRelaxedDot(v1, v2))
+	case wasm.OpcodeVecRelaxedI32x4DotI8x16I7x16AddS:
+		v3 := state.pop()
+		v2 := state.pop()
+		v1 := state.pop()
+		dot := c.lowerVecRelaxedDot(v1, v2)
+		lo := builder.AllocateInstruction().AsWiden(dot, ssa.VecLaneI16x8, true, true).Insert(builder).Return()
+		hi := builder.AllocateInstruction().AsWiden(dot, ssa.VecLaneI16x8, true, false).Insert(builder).Return()
+		sum := builder.AllocateInstruction().AsIaddPairwise(lo, hi, ssa.VecLaneI32x4).Insert(builder).Return()
+		ret := builder.AllocateInstruction().AsVIadd(v3, sum, ssa.VecLaneI32x4).Insert(builder).Return()
+		state.push(ret)
+	default:
+		panic("TODO: unsupported relaxed-SIMD instruction: " + wasm.VecRelaxedInstructionName(op))
+	}
+}
+
+// lowerVecRelaxedDot lowers i16x8.relaxed_dot_i8x16_i7x16_s as the narrowing with signed saturation of the pairwise
+// sums of the products of the signed lanes of v1 and v2.
+func (c *Compiler) lowerVecRelaxedDot(v1, v2 ssa.Value) ssa.Value {
+	builder := c.ssaBuilder
+	sums := [2]ssa.Value{}
+	for i, low := range [2]bool{true, false} {
+		mul := c.lowerExtMul(v1, v2, ssa.VecLaneI8x16, ssa.VecLaneI16x8, true, low)
+		lo := builder.AllocateInstruction().AsWiden(mul, ssa.VecLaneI16x8, true, true).Insert(builder).Return()
+		hi := builder.AllocateInstruction().AsWiden(mul, ssa.VecLaneI16x8, true, false).Insert(builder).Return()
+		sums[i] = builder.AllocateInstruction().AsIaddPairwise(lo, hi, ssa.VecLaneI32x4).Insert(builder).Return()
+	}
+	return builder.AllocateInstruction().AsNarrow(sums[0], sums[1], ssa.VecLaneI32x4, true).Insert(builder).Return()
+}
+
 func (c *Compiler) lowerExtMul(v1, v2 ssa.Value, from, to ssa.VecLane, signed, low bool) ssa.Value {
 	// TODO: The sequence `Widen; Widen; VIMul` can be substituted for a single instruction on some ISAs.
 	builder := c.ssaBuilder
@@ -3282,9 +3719,11 @@ func (c *Compiler) lowerAccessTableWithBoundsCheck(tableIndex uint32, elementOff
 	return calcElementAddressInTable.Return()
 }
 
-func (c *Compiler) lowerCallIndirect(typeIndex, tableIndex uint32) {
+// lowerCallIndirect lowers call_indirect, or return_call_indirect if isReturnCall is true.
+func (c *Compiler) lowerCallIndirect(typeIndex, tableIndex uint32, isReturnCall bool) {
 	builder := c.ssaBuilder
 	state := c.state()
+	tail := c.prepareReturnCall(isReturnCall)
 
 	elementOffsetInTable := state.pop()
 	functionInstancePtrAddress := c.lowerAccessTableWithBoundsCheck(tableIndex, elementOffsetInTable)
@@ -3352,8 +3791,42 @@ func (c *Compiler) lowerCallIndirect(typeIndex, tableIndex uint32) {
 	c.storeCallerModuleContext()
 
 	call := builder.AllocateInstruction()
-	call.AsCallIndirect(executablePtr, c.signatures[typ], args)
+	if tail {
+		call.AsReturnCallIndirect(executablePtr, c.signatures[typ], args)
+	} else {
+		call.AsCallIndirect(executablePtr, c.signatures[typ], args)
+	}
 	builder.InsertInstruction(call)
+	c.finishCall(call, isReturnCall, tail)
+}
+
+// prepareReturnCall returns true if a return_call(_indirect) can be lowered as a tail call which replaces the frame
+// of the current function. Otherwise, it is lowered as a call followed by a return, which is required when the
+// function listener must observe the return of the current function.
+func (c *Compiler) prepareReturnCall(isReturnCall bool) (tail bool) {
+	if !isReturnCall {
+		return false
+	}
+	tail = !c.needListener
+	if c.ensureTermination {
+		// Tail calls can loop forever without growing the stack, so check the exit code as loop headers do.
+		c.insertCheckModuleExitCode()
+	}
+	if c.metering && tail {
+		// The callee replaces the frame of the current function, so it must not be counted twice.
+		c.insertLeaveCallDepth()
+	}
+	return
+}
+
+// finishCall pushes the results of the call instruction, unless it is a tail call. If isReturnCall is true, the
+// current function returns the results.
+func (c *Compiler) finishCall(call *ssa.Instruction, isReturnCall, tail bool) {
+	state := c.state()
+	if tail {
+		state.unreachable = true
+		return
+	}
 
 	first, rest := call.Returns()
 	if first.Valid() {
@@ -3364,6 +3837,223 @@ func (c *Compiler) lowerCallIndirect(typeIndex, tableIndex uint32) {
 	}
 
 	c.reloadAfterCall()
+
+	if c.m.UsesExceptionHandling {
+		if isReturnCall {
+			// The exceptions thrown by the callee aren't caught by this function, which it replaces.
+			c.insertExceptionCheck(c.exceptionPropagationBlock())
+		} else {
+			c.insertExceptionCheck(c.exceptionHandler(0))
+		}
+	}
+
+	if isReturnCall {
+		c.lowerReturn()
+	}
+}
+
+// exceptionHandler returns the block which an exception thrown at the label index depth branches to: the handler of
+// the innermost try block whose body encloses the label, or the block propagating it to the caller if there's none.
+func (c *Compiler) exceptionHandler(depth int) ssa.BasicBlock {
+	frames := c.loweringState.controlFrames
+	for i := len(frames) - 1 - depth; i >= 0; i-- {
+		if frames[i].kind == controlFrameKindTry {
+			return frames[i].blk
+		}
+	}
+	return c.exceptionPropagationBlock()
+}
+
+// exceptionPropagationBlock returns the block which returns to the caller with the exception being thrown.
+func (c *Compiler) exceptionPropagationBlock() ssa.BasicBlock {
+	if c.exceptionPropagationBlk == nil {
+		c.exceptionPropagationBlk = c.ssaBuilder.AllocateBasicBlock()
+	}
+	return c.exceptionPropagationBlk
+}
+
+// lowerExceptionPropagation lowers the block returned by exceptionPropagationBlock, once all the branches to it are
+// lowered. The results are zeros, as the caller branches to its own handler instead of using them.
+func (c *Compiler) lowerExceptionPropagation() {
+	builder := c.ssaBuilder
+	blk := c.exceptionPropagationBlk
+	builder.SetCurrentBlock(blk)
+	builder.Seal(blk)
+
+	state := c.state()
+	state.values = state.values[:0]
+	for _, typ := range c.wasmFunctionTyp.Results {
+		state.push(c.insertZeroValue(WasmTypeToSSAType(typ)))
+	}
+	c.insertJumpToBlock(state.nPeekDup(c.results()), builder.ReturnBlock())
+}
+
+// insertExceptionCheck inserts the branch to the handler, taken when the callee returned with an exception being
+// thrown.
+func (c *Compiler) insertExceptionCheck(handler ssa.BasicBlock) {
+	builder := c.ssaBuilder
+	thrownTag := builder.AllocateInstruction().
+		AsLoad(c.execCtxPtrValue, wazevoapi.ExecutionContextOffsetExceptionTag.U32(), ssa.TypeI64).
+		Insert(builder).Return()
+	zero := builder.AllocateInstruction().AsIconst64(0).Insert(builder).Return()
+	thrown := builder.AllocateInstruction().
+		AsIcmp(thrownTag, zero, ssa.IntegerCmpCondNotEqual).
+		Insert(builder).Return()
+	brnz := builder.AllocateInstruction()
+	brnz.AsBrnz(thrown, nil, handler)
+	builder.InsertInstruction(brnz)
+
+	continueBlk := builder.AllocateBasicBlock()
+	c.insertJumpToBlock(nil, continueBlk)
+	builder.SetCurrentBlock(continueBlk)
+	builder.Seal(continueBlk)
+}
+
+// loadTag loads the *wasm.TagInstance of the tag at the index.
+func (c *Compiler) loadTag(index uint32) ssa.Value {
+	builder := c.ssaBuilder
+	return builder.AllocateInstruction().
+		AsLoad(c.moduleCtxPtrValue, c.offset.TagOffset(index).U32(), ssa.TypeI64).
+		Insert(builder).Return()
+}
+
+// throwException stores the tag and the payload of the exception into the execution context, and branches to the
+// handler.
+func (c *Compiler) throwException(tag ssa.Value, payload []ssa.Value) {
+	builder := c.ssaBuilder
+	offset := wazevoapi.ExecutionContextOffsetExceptionPayloadBegin
+	for _, v := range payload {
+		builder.AllocateInstruction().AsStore(ssa.OpcodeStore, v, c.execCtxPtrValue, offset.U32()).Insert(builder)
+		if v.Type() == ssa.TypeV128 {
+			offset += 16
+		} else {
+			offset += 8
+		}
+	}
+	builder.AllocateInstruction().
+		AsStore(ssa.OpcodeStore, tag, c.execCtxPtrValue, wazevoapi.ExecutionContextOffsetExceptionTag.U32()).
+		Insert(builder)
+	c.insertJumpToBlock(nil, c.exceptionHandler(0))
+}
+
+// catchException clears the exception being thrown, and returns its tag followed by its payload, which is read as the
+// params of the tag type. If the tag type is nil, as for catch_all, the payload is read as all the slots, which are
+// only used if the exception is rethrown.
+func (c *Compiler) catchException(tag ssa.Value, tagType *wasm.FunctionType) []ssa.Value {
+	builder := c.ssaBuilder
+	exception := []ssa.Value{tag}
+	offset := wazevoapi.ExecutionContextOffsetExceptionPayloadBegin
+	if tagType != nil {
+		for _, p := range tagType.Params {
+			st := WasmTypeToSSAType(p)
+			v := builder.AllocateInstruction().AsLoad(c.execCtxPtrValue, offset.U32(), st).Insert(builder).Return()
+			exception = append(exception, v)
+			if st == ssa.TypeV128 {
+				offset += 16
+			} else {
+				offset += 8
+			}
+		}
+	} else {
+		for i := 0; i < wazevoapi.ExceptionPayloadSlots; i++ {
+			v := builder.AllocateInstruction().AsLoad(c.execCtxPtrValue, offset.U32(), ssa.TypeI64).Insert(builder).Return()
+			exception = append(exception, v)
+			offset += 8
+		}
+	}
+
+	zero := builder.AllocateInstruction().AsIconst64(0).Insert(builder).Return()
+	builder.AllocateInstruction().
+		AsStore(ssa.OpcodeStore, zero, c.execCtxPtrValue, wazevoapi.ExecutionContextOffsetExceptionTag.U32()).
+		Insert(builder)
+	return exception
+}
+
+// lowerReturn returns the results of the current function, which are on top of the stack.
+func (c *Compiler) lowerReturn() {
+	if fn := &c.loweringState.controlFrames[0]; !fn.followingBlock.ReturnBlock() {
+		// This is an inlined callee, which returns by branching to the block following the call.
+		results := c.loweringState.nPeekDup(c.results())
+		c.insertJumpToBlock(results, fn.followingBlock)
+		c.state().unreachable = true
+		return
+	}
+	if c.needListener {
+		c.callListenerAfter()
+	}
+	if c.metering {
+		c.insertLeaveCallDepth()
+	}
+
+	results := c.loweringState.nPeekDup(c.results())
+	instr := c.ssaBuilder.AllocateInstruction()
+
+	instr.AsReturn(results)
+	c.ssaBuilder.InsertInstruction(instr)
+	c.state().unreachable = true
+}
+
+// isInlinable returns true if calls to the function at fnIndex are inlined. See SetInliningThreshold.
+func (c *Compiler) isInlinable(fnIndex wasm.Index) bool {
+	if fnIndex < c.m.ImportFunctionCount || len(c.inlinable) == 0 {
+		return false
+	}
+	return c.inlinable[fnIndex-c.m.ImportFunctionCount]
+}
+
+// lowerInlinedCall lowers the body of the function at fnIndex in place of a call to it. The state of the caller is
+// saved while lowering the callee, whose returns branch to the block following the call.
+//
+// Note: the callee is a leaf function, so this never recurses.
+func (c *Compiler) lowerInlinedCall(fnIndex wasm.Index) {
+	builder := c.ssaBuilder
+	localIdx := fnIndex - c.m.ImportFunctionCount
+	typ := &c.m.TypeSection[c.m.FunctionSection[localIdx]]
+	code := &c.m.CodeSection[localIdx]
+
+	args := make([]ssa.Value, len(typ.Params))
+	c.loweringState.nPopInto(len(args), args)
+
+	callerState, callerLocals := c.loweringState, c.wasmLocalToVariable
+	callerTyp, callerLocalTypes := c.wasmFunctionTyp, c.wasmFunctionLocalTypes
+	callerBody, callerBodyOffset := c.wasmFunctionBody, c.wasmFunctionBodyOffsetInCodeSection
+
+	c.loweringState = loweringState{}
+	c.wasmLocalToVariable = make(map[wasm.Index]ssa.Variable, len(typ.Params)+len(code.LocalTypes))
+	c.wasmFunctionTyp, c.wasmFunctionLocalTypes = typ, code.LocalTypes
+	c.wasmFunctionBody, c.wasmFunctionBodyOffsetInCodeSection = code.Body, code.BodyOffsetInCodeSection
+
+	// The parameters of the callee are defined as the arguments of the call.
+	current := builder.CurrentBlock()
+	for i, arg := range args {
+		variable := builder.DeclareVariable(WasmTypeToSSAType(typ.Params[i]))
+		builder.DefineVariable(variable, arg, current)
+		c.wasmLocalToVariable[wasm.Index(i)] = variable
+	}
+	c.declareWasmLocals(current)
+
+	followingBlk := builder.AllocateBasicBlock()
+	c.addBlockParamsFromWasmTypes(typ.Results, followingBlk)
+	c.loweringState.ctrlPush(controlFrame{
+		kind:           controlFrameKindFunction,
+		blockType:      typ,
+		followingBlock: followingBlk,
+	})
+	for c.loweringState.pc < len(c.wasmFunctionBody) {
+		c.lowerCurrentOpcode()
+	}
+
+	// The end of the callee switched to the following block, whose params are the results of the call.
+	results, unreachable := c.loweringState.values, c.loweringState.unreachable
+
+	c.loweringState, c.wasmLocalToVariable = callerState, callerLocals
+	c.wasmFunctionTyp, c.wasmFunctionLocalTypes = callerTyp, callerLocalTypes
+	c.wasmFunctionBody, c.wasmFunctionBodyOffsetInCodeSection = callerBody, callerBodyOffset
+
+	for _, v := range results {
+		c.loweringState.push(v)
+	}
+	c.loweringState.unreachable = unreachable
 }
 
 // memOpSetup inserts the bounds check and calculates the address of the memory operation (loads/stores).
@@ -3371,30 +4061,36 @@ func (c *Compiler) memOpSetup(baseAddr ssa.Value, constOffset, operationSizeInBy
 	builder := c.ssaBuilder
 
 	ceil := constOffset + operationSizeInBytes
-	ceilConst := builder.AllocateInstruction()
-	ceilConst.AsIconst64(ceil)
-	builder.InsertInstruction(ceilConst)
+	needBoundsCheck := c.needBoundsCheck(baseAddr, ceil)
+	var ceilConst *ssa.Instruction
+	if needBoundsCheck {
+		ceilConst = builder.AllocateInstruction()
+		ceilConst.AsIconst64(ceil)
+		builder.InsertInstruction(ceilConst)
+	}
 
 	// We calculate the offset in 64-bit space.
 	extBaseAddr := builder.AllocateInstruction()
 	extBaseAddr.AsUExtend(baseAddr, 32, 64)
 	builder.InsertInstruction(extBaseAddr)
 
-	// Note: memLen is already zero extended to 64-bit space at the load time.
-	memLen := c.getMemoryLenValue(false)
-
-	// baseAddrPlusCeil = baseAddr + ceil
-	baseAddrPlusCeil := builder.AllocateInstruction()
-	baseAddrPlusCeil.AsIadd(extBaseAddr.Return(), ceilConst.Return())
-	builder.InsertInstruction(baseAddrPlusCeil)
-
-	// Check for out of bounds memory access: `memLen >= baseAddrPlusCeil`.
-	cmp := builder.AllocateInstruction()
-	cmp.AsIcmp(memLen, baseAddrPlusCeil.Return(), ssa.IntegerCmpCondUnsignedLessThan)
-	builder.InsertInstruction(cmp)
-	exitIfNZ := builder.AllocateInstruction()
-	exitIfNZ.AsExitIfTrueWithCode(c.execCtxPtrValue, cmp.Return(), wazevoapi.ExitCodeMemoryOutOfBounds)
-	builder.InsertInstruction(exitIfNZ)
+	if needBoundsCheck {
+		// Note: memLen is already zero extended to 64-bit space at the load time.
+		memLen := c.getMemoryLenValue(false)
+
+		// baseAddrPlusCeil = baseAddr + ceil
+		baseAddrPlusCeil := builder.AllocateInstruction()
+		baseAddrPlusCeil.AsIadd(extBaseAddr.Return(), ceilConst.Return())
+		builder.InsertInstruction(baseAddrPlusCeil)
+
+		// Check for out of bounds memory access: `memLen >= baseAddrPlusCeil`.
+		cmp := builder.AllocateInstruction()
+		cmp.AsIcmp(memLen, baseAddrPlusCeil.Return(), ssa.IntegerCmpCondUnsignedLessThan)
+		builder.InsertInstruction(cmp)
+		exitIfNZ := builder.AllocateInstruction()
+		exitIfNZ.AsExitIfTrueWithCode(c.execCtxPtrValue, cmp.Return(), wazevoapi.ExitCodeMemoryOutOfBounds)
+		builder.InsertInstruction(exitIfNZ)
+	}
 
 	// Load the value from memBase + extBaseAddr.
 	memBase := c.getMemoryBaseValue(false)
@@ -3404,6 +4100,29 @@ func (c *Compiler) memOpSetup(baseAddr ssa.Value, constOffset, operationSizeInBy
 	return addrCalc.Return()
 }
 
+// needBoundsCheck returns false if an earlier check in the current block already ensured that baseAddr+ceil is within
+// the memory, which is still the case as the memory never shrinks. Otherwise, it records that baseAddr+ceil is checked.
+func (c *Compiler) needBoundsCheck(baseAddr ssa.Value, ceil uint64) bool {
+	if blk := c.ssaBuilder.CurrentBlock(); blk != c.knownSafeBoundsBlock {
+		c.knownSafeBoundsBlock = blk
+		if c.knownSafeBounds == nil {
+			c.knownSafeBounds = map[ssa.Value]uint64{}
+		} else {
+			for k := range c.knownSafeBounds {
+				delete(c.knownSafeBounds, k)
+			}
+		}
+	}
+
+	if known, ok := c.knownSafeBounds[baseAddr]; ok && ceil <= known {
+		c.boundsChecksEliminated++
+		return false
+	}
+	c.knownSafeBounds[baseAddr] = ceil
+	c.boundsChecksEmitted++
+	return true
+}
+
 func (c *Compiler) callMemmove(dst, src, size ssa.Value) {
 	args := []ssa.Value{dst, src, size} // TODO: reuse the slice.
 
@@ -3640,13 +4359,215 @@ func (c *Compiler) readMemArg() (align, offset uint32) {
 	}
 
 	state.pc += int(num)
-	offset, num, err = leb128.LoadUint32(c.wasmFunctionBody[state.pc+1:])
+	if align&wasm.MemArgMemoryIndexFlag != 0 {
+		// Validation only allows the flag with multi-memory, and the engine rejects modules with more than one memory,
+		// so the memory index which follows is always zero.
+		align &^= wasm.MemArgMemoryIndexFlag
+		c.readI32u()
+	}
+	// The offset is 64-bit for a 64-bit memory, but narrowMemory64Operands makes the access trap if it doesn't fit
+	// in 32 bits.
+	offset64, num, err := leb128.LoadUint64(c.wasmFunctionBody[state.pc+1:])
 	if err != nil {
 		panic(fmt.Errorf("read memory offset: %v", err))
 	}
 
 	state.pc += int(num)
-	return align, offset
+	return align, uint32(offset64)
+}
+
+// narrowMemory64Operands narrows the i64 operands of the current instruction into i32 in place if it accesses
+// memory, which must be 64-bit, so that the rest of its lowering is the same as for a 32-bit memory.
+//
+// Memories are never larger than 4GiB, so an address which doesn't fit in 32 bits is out of bounds, as is any
+// access with an offset which doesn't. The delta of memory.grow is clamped instead, as the instruction fails with -1.
+func (c *Compiler) narrowMemory64Operands(op wasm.Opcode) {
+	state := c.state()
+	// depth is the depth of the address operand on the stack, and memArg is the position of its memory argument.
+	depth, memArg := -1, 0
+	switch {
+	case op >= wasm.OpcodeI32Load && op <= wasm.OpcodeI64Load32U:
+		depth, memArg = 0, state.pc+1
+	case op >= wasm.OpcodeI32Store && op <= wasm.OpcodeI64Store32:
+		depth, memArg = 1, state.pc+1
+	case op == wasm.OpcodeMemoryGrow:
+		builder := c.ssaBuilder
+		delta := state.values[len(state.values)-1]
+		maxDelta := builder.AllocateInstruction().AsIconst64(math.MaxUint32).Insert(builder).Return()
+		inRange := builder.AllocateInstruction().
+			AsIcmp(delta, maxDelta, ssa.IntegerCmpCondUnsignedLessThanOrEqual).Insert(builder).Return()
+		delta = builder.AllocateInstruction().AsSelect(inRange, delta, maxDelta).Insert(builder).Return()
+		state.values[len(state.values)-1] = builder.AllocateInstruction().
+			AsIreduce(delta, ssa.TypeI32).Insert(builder).Return()
+	case op == wasm.OpcodeMiscPrefix:
+		switch miscOp, _, _ := leb128.LoadUint32(c.wasmFunctionBody[state.pc+1:]); wasm.OpcodeMisc(miscOp) {
+		case wasm.OpcodeMiscMemoryInit:
+			// [dst, offset, n], where only dst is an address.
+			depth = 2
+		case wasm.OpcodeMiscMemoryCopy, wasm.OpcodeMiscMemoryFill:
+			// [dst, src, n] or [dst, value, n], where n is also i64 as there is one memory.
+			c.narrowAddress(0)
+			if wasm.OpcodeMisc(miscOp) == wasm.OpcodeMiscMemoryCopy {
+				c.narrowAddress(1)
+			}
+			depth = 2
+		}
+	case op == wasm.OpcodeAtomicPrefix:
+		switch atomicOp := c.wasmFunctionBody[state.pc+1]; {
+		case atomicOp == wasm.OpcodeAtomicFence:
+		case atomicOp == wasm.OpcodeAtomicMemoryWait32 || atomicOp == wasm.OpcodeAtomicMemoryWait64 ||
+			atomicOp >= wasm.OpcodeAtomicI32RmwCmpxchg:
+			depth, memArg = 2, state.pc+2
+		case atomicOp == wasm.OpcodeAtomicMemoryNotify || atomicOp >= wasm.OpcodeAtomicI32Store:
+			depth, memArg = 1, state.pc+2
+		default:
+			depth, memArg = 0, state.pc+2
+		}
+	case op == wasm.OpcodeVecPrefix:
+		switch vecOp := c.wasmFunctionBody[state.pc+1]; {
+		case vecOp <= wasm.OpcodeVecV128Load64Splat, vecOp == wasm.OpcodeVecV128Load32zero,
+			vecOp == wasm.OpcodeVecV128Load64zero:
+			depth, memArg = 0, state.pc+2
+		case vecOp == wasm.OpcodeVecV128Store,
+			vecOp >= wasm.OpcodeVecV128Load8Lane && vecOp <= wasm.OpcodeVecV128Store64Lane:
+			depth, memArg = 1, state.pc+2 // the address is followed by a v128.
+		}
+	}
+	if depth < 0 {
+		return
+	}
+	if memArg > 0 && c.memArgOffsetOverflows(memArg) {
+		builder := c.ssaBuilder
+		state.values[len(state.values)-1-depth] = builder.AllocateInstruction().
+			AsIconst64(math.MaxUint64).Insert(builder).Return()
+	}
+	c.narrowAddress(depth)
+}
+
+// narrowAddress replaces the i64 at the given depth of the stack with the i32 of the same value, after the check
+// that it fits in 32 bits.
+func (c *Compiler) narrowAddress(depth int) {
+	builder := c.ssaBuilder
+	state := c.state()
+	i := len(state.values) - 1 - depth
+	addr := state.values[i]
+
+	amount := builder.AllocateInstruction().AsIconst64(32).Insert(builder).Return()
+	high := builder.AllocateInstruction().AsUshr(addr, amount).Insert(builder).Return()
+	zero := builder.AllocateInstruction().AsIconst64(0).Insert(builder).Return()
+	overflows := builder.AllocateInstruction().
+		AsIcmp(high, zero, ssa.IntegerCmpCondNotEqual).Insert(builder).Return()
+	builder.AllocateInstruction().
+		AsExitIfTrueWithCode(c.execCtxPtrValue, overflows, wazevoapi.ExitCodeMemoryOutOfBounds).
+		Insert(builder)
+	state.values[i] = builder.AllocateInstruction().AsIreduce(addr, ssa.TypeI32).Insert(builder).Return()
+}
+
+// memArgOffsetOverflows returns true if the offset of the memory argument at the given position of the function body
+// doesn't fit in 32 bits.
+func (c *Compiler) memArgOffsetOverflows(pos int) bool {
+	align, num, _ := leb128.LoadUint32(c.wasmFunctionBody[pos:])
+	pos += int(num)
+	if align&wasm.MemArgMemoryIndexFlag != 0 {
+		_, num, _ = leb128.LoadUint32(c.wasmFunctionBody[pos:])
+		pos += int(num)
+	}
+	offset, _, _ := leb128.LoadUint64(c.wasmFunctionBody[pos:])
+	return offset > math.MaxUint32
+}
+
+// insertCheckModuleExitCode inserts the check of the exit code flag, which is set when the module is closed
+// (e.g. on context cancellation), and only exits to Go to check the exit code when the flag is non-zero.
+func (c *Compiler) insertCheckModuleExitCode() {
+	builder := c.ssaBuilder
+	checkBlk, continueBlk := builder.AllocateBasicBlock(), builder.AllocateBasicBlock()
+
+	flagAddr := builder.AllocateInstruction().
+		AsLoad(c.moduleCtxPtrValue, c.offset.ExitCodeCheckFlagAddress.U32(), ssa.TypeI64).
+		Insert(builder).Return()
+	// Only the lower 32 bits are loaded, as branches take i32. That's enough, since ModuleInstance.Closed
+	// always has a flag in its lower bits once closed.
+	flag := builder.AllocateInstruction().
+		AsLoad(flagAddr, 0, ssa.TypeI32).
+		Insert(builder).Return()
+	brz := builder.AllocateInstruction()
+	brz.AsBrz(flag, nil, continueBlk)
+	builder.InsertInstruction(brz)
+	c.insertJumpToBlock(nil, checkBlk)
+
+	builder.SetCurrentBlock(checkBlk)
+	checkModuleExitCodePtr := builder.AllocateInstruction().
+		AsLoad(c.execCtxPtrValue,
+			wazevoapi.ExecutionContextOffsetCheckModuleExitCodeTrampolineAddress.U32(),
+			ssa.TypeI64,
+		).Insert(builder).Return()
+
+	c.checkModuleExitCodeArg[0] = c.execCtxPtrValue
+
+	builder.AllocateInstruction().
+		AsCallIndirect(checkModuleExitCodePtr, &c.checkModuleExitCodeSig, c.checkModuleExitCodeArg[:]).
+		Insert(builder)
+	c.insertJumpToBlock(nil, continueBlk)
+
+	builder.SetCurrentBlock(continueBlk)
+	builder.Seal(checkBlk)
+	builder.Seal(continueBlk)
+}
+
+// insertEnterCallDepth inserts the check of executionContext.callDepthRemaining at the entry of the function,
+// which exits with ExitCodeCallStackExhausted when no more nested calls are allowed, and otherwise decrements it.
+func (c *Compiler) insertEnterCallDepth() {
+	builder := c.ssaBuilder
+	remaining := builder.AllocateInstruction().
+		AsLoad(c.execCtxPtrValue, wazevoapi.ExecutionContextOffsetCallDepthRemaining.U32(), ssa.TypeI64).
+		Insert(builder).Return()
+	zero := builder.AllocateInstruction().AsIconst64(0).Insert(builder).Return()
+	exhausted := builder.AllocateInstruction().
+		AsIcmp(remaining, zero, ssa.IntegerCmpCondEqual).
+		Insert(builder).Return()
+	builder.AllocateInstruction().
+		AsExitIfTrueWithCode(c.execCtxPtrValue, exhausted, wazevoapi.ExitCodeCallStackExhausted).
+		Insert(builder)
+	one := builder.AllocateInstruction().AsIconst64(1).Insert(builder).Return()
+	decremented := builder.AllocateInstruction().AsIsub(remaining, one).Insert(builder).Return()
+	builder.AllocateInstruction().
+		AsStore(ssa.OpcodeStore, decremented, c.execCtxPtrValue, wazevoapi.ExecutionContextOffsetCallDepthRemaining.U32()).
+		Insert(builder)
+}
+
+// insertLeaveCallDepth increments executionContext.callDepthRemaining, which is decremented by insertEnterCallDepth,
+// before the function returns.
+func (c *Compiler) insertLeaveCallDepth() {
+	builder := c.ssaBuilder
+	remaining := builder.AllocateInstruction().
+		AsLoad(c.execCtxPtrValue, wazevoapi.ExecutionContextOffsetCallDepthRemaining.U32(), ssa.TypeI64).
+		Insert(builder).Return()
+	one := builder.AllocateInstruction().AsIconst64(1).Insert(builder).Return()
+	incremented := builder.AllocateInstruction().AsIadd(remaining, one).Insert(builder).Return()
+	builder.AllocateInstruction().
+		AsStore(ssa.OpcodeStore, incremented, c.execCtxPtrValue, wazevoapi.ExecutionContextOffsetCallDepthRemaining.U32()).
+		Insert(builder)
+}
+
+// insertConsumeFuel inserts the check of executionContext.fuelRemaining, which exits with ExitCodeFuelExhausted when
+// none remains, and otherwise decrements it.
+func (c *Compiler) insertConsumeFuel() {
+	builder := c.ssaBuilder
+	remaining := builder.AllocateInstruction().
+		AsLoad(c.execCtxPtrValue, wazevoapi.ExecutionContextOffsetFuelRemaining.U32(), ssa.TypeI64).
+		Insert(builder).Return()
+	zero := builder.AllocateInstruction().AsIconst64(0).Insert(builder).Return()
+	exhausted := builder.AllocateInstruction().
+		AsIcmp(remaining, zero, ssa.IntegerCmpCondEqual).
+		Insert(builder).Return()
+	builder.AllocateInstruction().
+		AsExitIfTrueWithCode(c.execCtxPtrValue, exhausted, wazevoapi.ExitCodeFuelExhausted).
+		Insert(builder)
+	one := builder.AllocateInstruction().AsIconst64(1).Insert(builder).Return()
+	decremented := builder.AllocateInstruction().AsIsub(remaining, one).Insert(builder).Return()
+	builder.AllocateInstruction().
+		AsStore(ssa.OpcodeStore, decremented, c.execCtxPtrValue, wazevoapi.ExecutionContextOffsetFuelRemaining.U32()).
+		Insert(builder)
 }
 
 // insertJumpToBlock inserts a jump instruction to the given block in the current block.
@@ -3655,6 +4576,9 @@ func (c *Compiler) insertJumpToBlock(args []ssa.Value, targetBlk ssa.BasicBlock)
 		if c.needListener {
 			c.callListenerAfter()
 		}
+		if c.metering {
+			c.insertLeaveCallDepth()
+		}
 	}
 
 	builder := c.ssaBuilder
@@ -3678,6 +4602,53 @@ func (c *Compiler) insertIntegerExtend(signed bool, from, to byte) {
 	state.push(value)
 }
 
+// canonicalizeNaN returns v, the result of floating-point arithmetic, or the canonical NaN of its type if v is a NaN
+// when canonicalNaN is enabled. See SetCanonicalNaN.
+func (c *Compiler) canonicalizeNaN(v ssa.Value) ssa.Value {
+	if !c.canonicalNaN {
+		return v
+	}
+	builder := c.ssaBuilder
+	// Only NaN isn't equal to itself.
+	isNaN := builder.AllocateInstruction()
+	isNaN.AsFcmp(v, v, ssa.FloatCmpCondNotEqual)
+	builder.InsertInstruction(isNaN)
+
+	canonical := builder.AllocateInstruction()
+	if v.Type() == ssa.TypeF32 {
+		canonical.AsF32const(math.Float32frombits(canonicalNaNF32))
+	} else {
+		canonical.AsF64const(math.Float64frombits(canonicalNaNF64))
+	}
+	builder.InsertInstruction(canonical)
+	return builder.AllocateInstruction().AsSelect(isNaN.Return(), canonical.Return(), v).Insert(builder).Return()
+}
+
+// canonicalizeVecNaN is like canonicalizeNaN, but replaces each NaN lane of the vector v.
+func (c *Compiler) canonicalizeVecNaN(v ssa.Value, lane ssa.VecLane) ssa.Value {
+	if !c.canonicalNaN {
+		return v
+	}
+	builder := c.ssaBuilder
+	isNaN := builder.AllocateInstruction().AsVFcmp(v, v, ssa.FloatCmpCondNotEqual, lane).Insert(builder).Return()
+
+	var canonical uint64
+	if lane == ssa.VecLaneF32x4 {
+		canonical = canonicalNaNF32<<32 | canonicalNaNF32
+	} else {
+		canonical = canonicalNaNF64
+	}
+	nans := builder.AllocateInstruction().AsVconst(canonical, canonical).Insert(builder).Return()
+	return builder.AllocateInstruction().AsVbitselect(isNaN, nans, v).Insert(builder).Return()
+}
+
+const (
+	// canonicalNaNF32 and canonicalNaNF64 are the bit patterns of the canonical NaN, which is positive and whose
+	// payload only has the most significant bit set.
+	canonicalNaNF32 = 0x7fc00000
+	canonicalNaNF64 = 0x7ff8000000000000
+)
+
 func (c *Compiler) switchTo(originalStackLen int, targetBlk ssa.BasicBlock) {
 	if targetBlk.Preds() == 0 {
 		c.loweringState.unreachable = true
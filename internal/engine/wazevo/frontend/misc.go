@@ -8,3 +8,16 @@ import (
 func FunctionIndexToFuncRef(idx wasm.Index) ssa.FuncRef {
 	return ssa.FuncRef(idx)
 }
+
+// TagPayloadSlots returns the number of 64-bit slots taken by the values carried by an exception of the tag type,
+// where a v128 value takes two slots.
+func TagPayloadSlots(typ *wasm.FunctionType) (slots int) {
+	for _, p := range typ.Params {
+		if p == wasm.ValueTypeV128 {
+			slots += 2
+		} else {
+			slots++
+		}
+	}
+	return
+}
@@ -56,6 +56,7 @@ type (
 	// 	    afterListenerTrampolines1stElement        **byte                 (optional)
 	//      dataInstances1stElement                   []wasm.DataInstance    (optional)
 	//      elementInstances1stElement                []wasm.ElementInstance (optional)
+	//      tags                                      []*wasm.TagInstance    (optional)
 	// 	}
 	//
 	// See wazevoapi.NewModuleContextOffsetData for the details of the offsets.
@@ -123,6 +124,15 @@ func (m *moduleEngine) setupOpaque() {
 	if len(inst.ElementInstances) > 0 {
 		binary.LittleEndian.PutUint64(opaque[offsets.ElementInstances1stElement:], uint64(uintptr(unsafe.Pointer(&inst.ElementInstances[0]))))
 	}
+	if tagOffset := offsets.TagsBegin; tagOffset >= 0 {
+		for _, tag := range inst.Tags {
+			binary.LittleEndian.PutUint64(opaque[tagOffset:], uint64(uintptr(unsafe.Pointer(tag))))
+			tagOffset += 8
+		}
+	}
+
+	// The exit code is only checked in Go when the module is closed.
+	binary.LittleEndian.PutUint64(opaque[offsets.ExitCodeCheckFlagAddress:], uint64(uintptr(unsafe.Pointer(&inst.Closed))))
 }
 
 // NewFunction implements wasm.ModuleEngine.
@@ -157,6 +167,7 @@ func (m *moduleEngine) NewFunction(index wasm.Index) api.Function {
 		sizeOfParamResultSlice: sizeOfParamResultSlice,
 		requiredParams:         typ.ParamNumInUint64,
 		numberOfResults:        typ.ResultNumInUint64,
+		funcType:               &src.TypeSection[typIndex],
 	}
 
 	ce.execCtx.memoryGrowTrampolineAddress = &m.parent.sharedFunctions.memoryGrowExecutable[0]
@@ -264,6 +275,37 @@ func (m *moduleEngine) FunctionInstanceReference(funcIndex wasm.Index) wasm.Refe
 	return uintptr(unsafe.Pointer(lf))
 }
 
+// ReferencedFunctionIndex implements wasm.ModuleEngine.
+func (m *moduleEngine) ReferencedFunctionIndex(ref wasm.Reference) (wasm.Index, bool) {
+	if ref == 0 {
+		return 0, false
+	}
+	source := m.module.Source
+	// An imported function is referenced by its functionInstance in the opaque.
+	if n := source.ImportFunctionCount; n > 0 {
+		begin, _, _ := m.parent.offsets.ImportedFunctionOffset(0)
+		base := uintptr(unsafe.Pointer(&m.opaque[begin]))
+		if ref >= base && ref < base+uintptr(n)*wazevoapi.FunctionInstanceSize {
+			if (ref-base)%wazevoapi.FunctionInstanceSize != 0 {
+				return 0, false
+			}
+			return wasm.Index((ref - base) / wazevoapi.FunctionInstanceSize), true
+		}
+	}
+
+	tf := functionFromUintptr(ref)
+	if tf.moduleContextOpaquePtr != m.opaquePtr || tf.indexInModule < source.ImportFunctionCount {
+		return 0, false
+	}
+	// Another module's opaque may point to this module, so ensure the index matches the code.
+	p := m.parent
+	localIndex := tf.indexInModule - source.ImportFunctionCount
+	if int(localIndex) >= len(p.functionOffsets) || tf.executable != &p.executable[p.functionOffsets[localIndex]] {
+		return 0, false
+	}
+	return tf.indexInModule, true
+}
+
 // LookupFunction implements wasm.ModuleEngine.
 func (m *moduleEngine) LookupFunction(t *wasm.TableInstance, typeId wasm.FunctionTypeID, tableOffset wasm.Index) (*wasm.ModuleInstance, wasm.Index) {
 	if tableOffset >= uint32(len(t.References)) || t.Type != wasm.RefTypeFuncref {
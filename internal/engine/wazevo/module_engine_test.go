@@ -8,6 +8,7 @@ import (
 	"unsafe"
 
 	"github.com/tetratelabs/wazero/internal/engine/wazevo/wazevoapi"
+	"github.com/tetratelabs/wazero/internal/sys"
 	"github.com/tetratelabs/wazero/internal/testing/require"
 	"github.com/tetratelabs/wazero/internal/wasm"
 )
@@ -64,6 +65,8 @@ func TestModuleEngine_setupOpaque(t *testing.T) {
 	} {
 		t.Run(strconv.Itoa(i), func(t *testing.T) {
 			tc.offset.TotalSize = 1000 // arbitrary large number to ensure we don't panic.
+			// Past the other fields, so that it doesn't overwrite them.
+			tc.offset.ExitCodeCheckFlagAddress = 992
 			m := &moduleEngine{
 				parent: &compiledModule{
 					offsets:                   tc.offset,
@@ -131,6 +134,37 @@ func TestModuleEngine_setupOpaque(t *testing.T) {
 	}
 }
 
+func TestModuleEngine_setupOpaque_exitCodeCheckFlag(t *testing.T) {
+	offset := wazevoapi.NewModuleContextOffsetData(&wasm.Module{}, false)
+	fuelLimited := sys.DefaultContext(nil)
+	fuelLimited.SetFuel(10)
+
+	for _, tc := range []struct {
+		name string
+		sys  *sys.Context
+	}{
+		{name: "nil sys"},
+		{name: "unlimited fuel", sys: sys.DefaultContext(nil)},
+		// Fuel is consumed by the compiled code, so it doesn't need to exit on every check.
+		{name: "limited fuel", sys: fuelLimited},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			inst := &wasm.ModuleInstance{Sys: tc.sys}
+			m := &moduleEngine{
+				parent: &compiledModule{offsets: offset},
+				module: inst,
+				opaque: make([]byte, offset.TotalSize),
+			}
+			m.setupOpaque()
+
+			// The flag becomes non-zero once the module is closed, e.g. on context cancellation.
+			flagPtr := uintptr(binary.LittleEndian.Uint64(m.opaque[offset.ExitCodeCheckFlagAddress:]))
+			require.Equal(t, uintptr(unsafe.Pointer(&inst.Closed)), flagPtr)
+		})
+	}
+}
+
 func TestModuleEngine_ResolveImportedFunction(t *testing.T) {
 	const begin = 5000
 	m := &moduleEngine{
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/tetratelabs/wazero/internal/engine/wazevo/wazevoapi"
 )
@@ -74,8 +75,13 @@ type Builder interface {
 	// ResolveSignature returns the Signature which corresponds to SignatureID.
 	ResolveSignature(id SignatureID) *Signature
 
+	// SetDeadline sets the time by which RunPasses must finish for the currently-compiled function.
+	// The zero time means no deadline.
+	SetDeadline(deadline time.Time)
+
 	// RunPasses runs various passes on the constructed SSA function.
-	RunPasses()
+	// This returns wazevoapi.ErrDeadlineExceeded if the deadline set by SetDeadline passes between two passes.
+	RunPasses() error
 
 	// Format returns the debugging string of the SSA function.
 	Format() string
@@ -121,6 +127,9 @@ type Builder interface {
 
 	// SetCurrentSourceOffset sets the current source offset. The incoming instruction will be annotated with this offset.
 	SetCurrentSourceOffset(line SourceOffset)
+
+	// ScratchBytes returns the size in bytes of the pooled scratch memory used by the currently-compiled function.
+	ScratchBytes() int
 }
 
 // NewBuilder returns a new Builder implementation.
@@ -184,6 +193,8 @@ type builder struct {
 	donePasses bool
 	// doneBlockLayout is true if LayoutBlocks is called.
 	doneBlockLayout bool
+	// deadline is set by SetDeadline, and checked between passes.
+	deadline time.Time
 
 	currentSourceOffset SourceOffset
 }
@@ -193,6 +204,11 @@ func (b *builder) ReturnBlock() BasicBlock {
 	return b.returnBlk
 }
 
+// SetDeadline implements Builder.SetDeadline.
+func (b *builder) SetDeadline(deadline time.Time) {
+	b.deadline = deadline
+}
+
 // Init implements Builder.Reset.
 func (b *builder) Init(s *Signature) {
 	b.currentSignature = s
@@ -236,6 +252,11 @@ func (b *builder) Init(s *Signature) {
 	b.currentSourceOffset = sourceOffsetUnknown
 }
 
+// ScratchBytes implements Builder.ScratchBytes.
+func (b *builder) ScratchBytes() int {
+	return b.instructionsPool.Bytes() + b.basicBlocksPool.Bytes()
+}
+
 // Signature implements Builder.Signature.
 func (b *builder) Signature() *Signature {
 	return b.currentSignature
@@ -210,6 +210,14 @@ const (
 	// Note that this is different from call_indirect in Wasm, which also does type checking, etc.
 	OpcodeCallIndirect
 
+	// OpcodeReturnCall is like OpcodeCall, but replaces the frame of the current function with the callee's, so the
+	// callee returns its results to the caller of the current function: `return_call FN, args...`.
+	// This terminates the block, and the signature of FN must have the same results as the current function.
+	OpcodeReturnCall
+
+	// OpcodeReturnCallIndirect is the OpcodeCallIndirect variant of OpcodeReturnCall: `return_call_indirect SIG, callee, args`.
+	OpcodeReturnCallIndirect
+
 	// OpcodeSplat performs a vector splat operation: `v = Splat.lane x`.
 	OpcodeSplat
 
@@ -685,6 +693,8 @@ var instructionSideEffects = [opcodeEnd]sideEffect{
 	OpcodeIconst:             sideEffectNone,
 	OpcodeCall:               sideEffectStrict,
 	OpcodeCallIndirect:       sideEffectStrict,
+	OpcodeReturnCall:         sideEffectStrict,
+	OpcodeReturnCallIndirect: sideEffectStrict,
 	OpcodeIadd:               sideEffectNone,
 	OpcodeImul:               sideEffectNone,
 	OpcodeIsub:               sideEffectNone,
@@ -956,6 +966,8 @@ var instructionReturnTypes = [opcodeEnd]returnTypesFn{
 	OpcodeExitWithCode:       returnTypesFnNoReturns,
 	OpcodeExitIfTrueWithCode: returnTypesFnNoReturns,
 	OpcodeReturn:             returnTypesFnNoReturns,
+	OpcodeReturnCall:         returnTypesFnNoReturns,
+	OpcodeReturnCallIndirect: returnTypesFnNoReturns,
 	OpcodeBrz:                returnTypesFnNoReturns,
 	OpcodeBrnz:               returnTypesFnNoReturns,
 	OpcodeBrTable:            returnTypesFnNoReturns,
@@ -2044,10 +2056,16 @@ func (i *Instruction) AsCall(ref FuncRef, sig *Signature, args []Value) {
 	sig.used = true
 }
 
+// AsReturnCall initializes this instruction as a tail call instruction with OpcodeReturnCall.
+func (i *Instruction) AsReturnCall(ref FuncRef, sig *Signature, args []Value) {
+	i.AsCall(ref, sig, args)
+	i.opcode = OpcodeReturnCall
+}
+
 // CallData returns the call data for this instruction necessary for backends.
 func (i *Instruction) CallData() (ref FuncRef, sigID SignatureID, args []Value) {
-	if i.opcode != OpcodeCall {
-		panic("BUG: CallData only available for OpcodeCall")
+	if i.opcode != OpcodeCall && i.opcode != OpcodeReturnCall {
+		panic("BUG: CallData only available for OpcodeCall and OpcodeReturnCall")
 	}
 	ref = FuncRef(i.u1)
 	sigID = SignatureID(i.u2)
@@ -2066,10 +2084,17 @@ func (i *Instruction) AsCallIndirect(funcPtr Value, sig *Signature, args []Value
 	return i
 }
 
+// AsReturnCallIndirect initializes this instruction as a tail call instruction with OpcodeReturnCallIndirect.
+func (i *Instruction) AsReturnCallIndirect(funcPtr Value, sig *Signature, args []Value) *Instruction {
+	i.AsCallIndirect(funcPtr, sig, args)
+	i.opcode = OpcodeReturnCallIndirect
+	return i
+}
+
 // CallIndirectData returns the call indirect data for this instruction necessary for backends.
 func (i *Instruction) CallIndirectData() (funcPtr Value, sigID SignatureID, args []Value) {
-	if i.opcode != OpcodeCallIndirect {
-		panic("BUG: CallIndirectData only available for OpcodeCallIndirect")
+	if i.opcode != OpcodeCallIndirect && i.opcode != OpcodeReturnCallIndirect {
+		panic("BUG: CallIndirectData only available for OpcodeCallIndirect and OpcodeReturnCallIndirect")
 	}
 	funcPtr = i.v
 	sigID = SignatureID(i.u1)
@@ -2359,12 +2384,12 @@ func (i *Instruction) Format(b Builder) string {
 		instSuffix = fmt.Sprintf(" %s, %s, %s", FloatCmpCond(i.u1), i.v.Format(b), i.v2.Format(b))
 	case OpcodeSExtend, OpcodeUExtend:
 		instSuffix = fmt.Sprintf(" %s, %d->%d", i.v.Format(b), i.u1>>8, i.u1&0xff)
-	case OpcodeCall, OpcodeCallIndirect:
+	case OpcodeCall, OpcodeCallIndirect, OpcodeReturnCall, OpcodeReturnCallIndirect:
 		vs := make([]string, len(i.vs))
 		for idx := range vs {
 			vs[idx] = i.vs[idx].Format(b)
 		}
-		if i.opcode == OpcodeCallIndirect {
+		if i.opcode == OpcodeCallIndirect || i.opcode == OpcodeReturnCallIndirect {
 			instSuffix = fmt.Sprintf(" %s:%s, %s", i.v.Format(b), SignatureID(i.u1), strings.Join(vs, ", "))
 		} else {
 			instSuffix = fmt.Sprintf(" %s:%s, %s", FuncRef(i.u1), SignatureID(i.u2), strings.Join(vs, ", "))
@@ -2555,6 +2580,10 @@ func (o Opcode) String() (ret string) {
 		return "Call"
 	case OpcodeCallIndirect:
 		return "CallIndirect"
+	case OpcodeReturnCall:
+		return "ReturnCall"
+	case OpcodeReturnCallIndirect:
+		return "ReturnCallIndirect"
 	case OpcodeSplat:
 		return "Splat"
 	case OpcodeSwizzle:
@@ -12,28 +12,37 @@ import (
 //
 // Note that passes suffixed with "Opt" are the optimization passes, meaning that they edit the instructions and blocks
 // while the other passes are not, like passEstimateBranchProbabilities does not edit them, but only calculates the additional information.
-func (b *builder) RunPasses() {
-	passDeadBlockEliminationOpt(b)
-	passRedundantPhiEliminationOpt(b)
-	// The result of passCalculateImmediateDominators will be used by various passes below.
-	passCalculateImmediateDominators(b)
-	passNopInstElimination(b)
-
-	// TODO: implement either conversion of irreducible CFG into reducible one, or irreducible CFG detection where we panic.
-	// 	WebAssembly program shouldn't result in irreducible CFG, but we should handle it properly in just in case.
-	// 	See FixIrreducible pass in LLVM: https://llvm.org/doxygen/FixIrreducible_8cpp_source.html
-
-	// TODO: implement more optimization passes like:
-	// 	block coalescing.
-	// 	Copy-propagation.
-	// 	Constant folding.
-	// 	Common subexpression elimination.
-	// 	Arithmetic simplifications.
-	// 	and more!
-
-	// passDeadCodeEliminationOpt could be more accurate if we do this after other optimizations.
-	passDeadCodeEliminationOpt(b)
+func (b *builder) RunPasses() error {
+	passes := [...]func(b *builder){
+		passDeadBlockEliminationOpt,
+		passRedundantPhiEliminationOpt,
+		// The result of passCalculateImmediateDominators will be used by various passes below.
+		passCalculateImmediateDominators,
+		passNopInstElimination,
+
+		// TODO: implement either conversion of irreducible CFG into reducible one, or irreducible CFG detection where we panic.
+		// 	WebAssembly program shouldn't result in irreducible CFG, but we should handle it properly in just in case.
+		// 	See FixIrreducible pass in LLVM: https://llvm.org/doxygen/FixIrreducible_8cpp_source.html
+
+		// TODO: implement more optimization passes like:
+		// 	block coalescing.
+		// 	Copy-propagation.
+		// 	Constant folding.
+		// 	Common subexpression elimination.
+		// 	Arithmetic simplifications.
+		// 	and more!
+
+		// passDeadCodeEliminationOpt could be more accurate if we do this after other optimizations.
+		passDeadCodeEliminationOpt,
+	}
+	for _, pass := range passes {
+		if err := wazevoapi.CheckDeadline(b.deadline); err != nil {
+			return err
+		}
+		pass(b)
+	}
 	b.donePasses = true
+	return nil
 }
 
 // passDeadBlockEliminationOpt searches the unreachable blocks, and sets the basicBlock.invalid flag true if so.
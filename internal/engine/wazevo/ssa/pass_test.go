@@ -2,7 +2,9 @@ package ssa
 
 import (
 	"testing"
+	"time"
 
+	"github.com/tetratelabs/wazero/internal/engine/wazevo/wazevoapi"
 	"github.com/tetratelabs/wazero/internal/testing/require"
 )
 
@@ -353,3 +355,23 @@ blk0: (v0:i32, v1:i64)
 		})
 	}
 }
+
+func TestBuilder_RunPasses_deadline(t *testing.T) {
+	b := NewBuilder().(*builder)
+	entry := b.AllocateBasicBlock()
+	b.SetCurrentBlock(entry)
+	ret := b.AllocateInstruction()
+	ret.AsReturn(nil)
+	b.InsertInstruction(ret)
+	b.Seal(entry)
+
+	b.SetDeadline(time.Now().Add(-time.Second))
+	err := b.RunPasses()
+	require.Equal(t, wazevoapi.ErrDeadlineExceeded, err)
+	require.False(t, b.donePasses)
+
+	b.SetDeadline(time.Time{})
+	err = b.RunPasses()
+	require.NoError(t, err)
+	require.True(t, b.donePasses)
+}
@@ -210,6 +210,18 @@ var (
 			wasm.OpcodeEnd,
 		}, []wasm.ValueType{}),
 	}
+	BlockWithParamResults = TestCase{
+		Name: "block_with_param_results",
+		Module: SingleFunctionModule(i32i32_i32, []byte{
+			wasm.OpcodeLocalGet, 0,
+			wasm.OpcodeLocalGet, 1,
+			// The block type is the type index 0 (i32i32_i32): consumes the two params and produces one result.
+			wasm.OpcodeBlock, 0,
+			wasm.OpcodeI32Sub,
+			wasm.OpcodeEnd,
+			wasm.OpcodeEnd,
+		}, []wasm.ValueType{}),
+	}
 	LoopBrIf = TestCase{
 		Name: "loop_br_if",
 		Module: SingleFunctionModule(vv, []byte{
@@ -502,6 +514,59 @@ var (
 			},
 		},
 	}
+	ReturnCall = TestCase{
+		Name: "return_call",
+		Module: &wasm.Module{
+			TypeSection:     []wasm.FunctionType{i32i32_i32},
+			FunctionSection: []wasm.Index{0},
+			CodeSection: []wasm.Code{{Body: []byte{
+				// Returns the second param if the first one is zero.
+				wasm.OpcodeLocalGet, 0, wasm.OpcodeI32Eqz,
+				wasm.OpcodeIf, blockSignature_vv,
+				wasm.OpcodeLocalGet, 1, wasm.OpcodeReturn,
+				wasm.OpcodeEnd,
+				// Otherwise, tail calls itself with (param[0] - 1, param[1] + param[0]).
+				wasm.OpcodeLocalGet, 0, wasm.OpcodeI32Const, 1, wasm.OpcodeI32Sub,
+				wasm.OpcodeLocalGet, 1, wasm.OpcodeLocalGet, 0, wasm.OpcodeI32Add,
+				wasm.OpcodeReturnCall, 0,
+				wasm.OpcodeEnd,
+			}}},
+		},
+	}
+	ReturnCallIndirect = TestCase{
+		Name: "return_call_indirect",
+		Module: &wasm.Module{
+			TypeSection:     []wasm.FunctionType{i32_i32},
+			FunctionSection: []wasm.Index{0},
+			TableSection:    []wasm.Table{{Type: wasm.RefTypeFuncref, Min: 1}},
+			CodeSection: []wasm.Code{{Body: []byte{
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeReturnCallIndirect, 0, 0, // Expecting type 0 (i32_i32), in tables[0]
+				wasm.OpcodeEnd,
+			}}},
+		},
+	}
+	// ReturnCallManyParams tail calls a function which takes more params on the stack than the caller.
+	ReturnCallManyParams = TestCase{
+		Name: "return_call_many_params",
+		Module: &wasm.Module{
+			TypeSection: []wasm.FunctionType{
+				{Params: []wasm.ValueType{i64}, Results: []wasm.ValueType{i64}},
+				{Params: []wasm.ValueType{i64, i64, i64, i64, i64, i64, i64, i64}, Results: []wasm.ValueType{i64}},
+			},
+			FunctionSection: []wasm.Index{0, 1},
+			CodeSection: []wasm.Code{
+				{Body: []byte{
+					wasm.OpcodeLocalGet, 0, wasm.OpcodeLocalGet, 0, wasm.OpcodeLocalGet, 0, wasm.OpcodeLocalGet, 0,
+					wasm.OpcodeLocalGet, 0, wasm.OpcodeLocalGet, 0, wasm.OpcodeLocalGet, 0, wasm.OpcodeLocalGet, 0,
+					wasm.OpcodeReturnCall, 1,
+					wasm.OpcodeEnd,
+				}},
+				{Body: []byte{wasm.OpcodeLocalGet, 7, wasm.OpcodeEnd}},
+			},
+		},
+	}
 	ManyParamsSmallResults = TestCase{
 		Name: "many_params_small_results",
 		Module: SingleFunctionModule(wasm.FunctionType{
@@ -895,6 +960,28 @@ var (
 			wasm.OpcodeEnd,
 		}, []wasm.ValueType{}),
 	}
+	// FloatCanonicalNaN returns the results of arithmetic, which are canonicalized with RuntimeConfig.WithCanonicalNaN,
+	// and of a negation, which never is.
+	FloatCanonicalNaN = TestCase{
+		Name: "float_canonical_nan",
+		Module: SingleFunctionModule(wasm.FunctionType{
+			Params:  []wasm.ValueType{f32, f64, v128},
+			Results: []wasm.ValueType{f32, f64, v128},
+		}, []byte{
+			wasm.OpcodeLocalGet, 0,
+			wasm.OpcodeLocalGet, 0,
+			wasm.OpcodeF32Add,
+
+			wasm.OpcodeLocalGet, 1,
+			wasm.OpcodeF64Neg,
+
+			wasm.OpcodeLocalGet, 2,
+			wasm.OpcodeLocalGet, 2,
+			wasm.OpcodeVecPrefix, wasm.OpcodeVecF32x4Mul, 0x01,
+
+			wasm.OpcodeEnd,
+		}, []wasm.ValueType{}),
+	}
 	NonTrappingFloatConversions = TestCase{
 		Name: "float_conversions",
 		Module: SingleFunctionModule(wasm.FunctionType{
@@ -1533,12 +1620,171 @@ var (
 		},
 	}
 
+	VecRelaxedMadd = TestCase{
+		Name: "relaxed_madd",
+		Module: SingleFunctionModule(
+			wasm.FunctionType{Params: []wasm.ValueType{v128, v128, v128}, Results: []wasm.ValueType{v128}},
+			[]byte{
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeLocalGet, 1,
+				wasm.OpcodeLocalGet, 2,
+				wasm.OpcodeVecPrefix, 0x80 | wasm.OpcodeVecRelaxedF32x4Madd, 0x02,
+				wasm.OpcodeEnd,
+			}, nil,
+		),
+	}
+	VecRelaxedDotAdd = TestCase{
+		Name: "relaxed_dot_add",
+		Module: SingleFunctionModule(
+			wasm.FunctionType{Params: []wasm.ValueType{v128, v128, v128}, Results: []wasm.ValueType{v128}},
+			[]byte{
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeLocalGet, 1,
+				wasm.OpcodeLocalGet, 2,
+				wasm.OpcodeVecPrefix, 0x80 | wasm.OpcodeVecRelaxedI32x4DotI8x16I7x16AddS, 0x02,
+				wasm.OpcodeEnd,
+			}, nil,
+		),
+	}
+
+	AtomicRmwCmpxchg = TestCase{
+		Name: "atomic_rmw_cmpxchg",
+		Module: &wasm.Module{
+			TypeSection:     []wasm.FunctionType{{Params: []wasm.ValueType{i32, i32, i32}, Results: []wasm.ValueType{i32}}},
+			ExportSection:   []wasm.Export{{Name: ExportedFunctionName, Type: wasm.ExternTypeFunc, Index: 0}},
+			FunctionSection: []wasm.Index{0},
+			MemorySection:   &wasm.Memory{Min: 1, Max: 1, IsMaxEncoded: true, IsShared: true},
+			CodeSection: []wasm.Code{{Body: []byte{
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeLocalGet, 1,
+				wasm.OpcodeLocalGet, 2,
+				wasm.OpcodeAtomicPrefix, wasm.OpcodeAtomicI32Rmw16CmpxchgU, 1, 8,
+				wasm.OpcodeEnd,
+			}}},
+		},
+	}
+
 	VecShuffle = TestCase{
 		Name:   "shuffle",
 		Module: VecShuffleWithLane(0, 1, 2, 3, 4, 5, 6, 7, 24, 25, 26, 27, 28, 29, 30, 31),
 	}
+
+	ExceptionThrowCatch = TestCase{
+		Name: "exception_throw_catch",
+		Module: &wasm.Module{
+			TypeSection:     []wasm.FunctionType{i32_i32, i32_v},
+			FunctionSection: []wasm.Index{0},
+			TagSection:      []wasm.Index{1},
+			CodeSection: []wasm.Code{{Body: []byte{
+				wasm.OpcodeTry, i32,
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeThrow, 0,
+				wasm.OpcodeCatch, 0,
+				wasm.OpcodeI32Const, 1,
+				wasm.OpcodeI32Add,
+				wasm.OpcodeEnd,
+				wasm.OpcodeEnd,
+			}}},
+			ExportSection:         []wasm.Export{{Name: ExportedFunctionName, Index: 0, Type: wasm.ExternTypeFunc}},
+			UsesExceptionHandling: true,
+		},
+	}
+
+	// ExceptionPropagation throws from the function "throw" unless its param is zero, which is called through
+	// another function without any try block.
+	ExceptionPropagation = TestCase{
+		Name: "exception_propagation",
+		Module: &wasm.Module{
+			TypeSection:     []wasm.FunctionType{i32_i32, i32_v},
+			FunctionSection: []wasm.Index{0, 0, 0},
+			TagSection:      []wasm.Index{1},
+			CodeSection: []wasm.Code{
+				{Body: []byte{
+					wasm.OpcodeTry, i32,
+					wasm.OpcodeLocalGet, 0,
+					wasm.OpcodeCall, 1,
+					wasm.OpcodeCatch, 0,
+					wasm.OpcodeI32Const, 0xe4, 0x00, // 100.
+					wasm.OpcodeI32Add,
+					wasm.OpcodeEnd,
+					wasm.OpcodeEnd,
+				}},
+				{Body: []byte{
+					wasm.OpcodeLocalGet, 0,
+					wasm.OpcodeCall, 2,
+					wasm.OpcodeI32Const, 0xe8, 0x07, // 1000.
+					wasm.OpcodeI32Add,
+					wasm.OpcodeEnd,
+				}},
+				exceptionThrowUnlessZero,
+			},
+			ExportSection: []wasm.Export{
+				{Name: ExportedFunctionName, Index: 0, Type: wasm.ExternTypeFunc},
+				{Name: "throw", Index: 2, Type: wasm.ExternTypeFunc},
+			},
+			UsesExceptionHandling: true,
+		},
+	}
+
+	// ExceptionRethrowDelegate catches the exception thrown by "throw" in an inner try block, which rethrows it from
+	// catch_all in "f", and delegates it in "delegate", to the catch clause of the outer try block.
+	ExceptionRethrowDelegate = TestCase{
+		Name: "exception_rethrow_delegate",
+		Module: &wasm.Module{
+			TypeSection:     []wasm.FunctionType{i32_i32, i32_v},
+			FunctionSection: []wasm.Index{0, 0, 0},
+			TagSection:      []wasm.Index{1},
+			CodeSection: []wasm.Code{
+				{Body: []byte{
+					wasm.OpcodeTry, i32,
+					wasm.OpcodeTry, i32,
+					wasm.OpcodeLocalGet, 0,
+					wasm.OpcodeCall, 2,
+					wasm.OpcodeCatchAll,
+					wasm.OpcodeRethrow, 0,
+					wasm.OpcodeEnd,
+					wasm.OpcodeCatch, 0,
+					wasm.OpcodeI32Const, 0xe4, 0x00, // 100.
+					wasm.OpcodeI32Add,
+					wasm.OpcodeEnd,
+					wasm.OpcodeEnd,
+				}},
+				{Body: []byte{
+					wasm.OpcodeTry, i32,
+					wasm.OpcodeTry, i32,
+					wasm.OpcodeLocalGet, 0,
+					wasm.OpcodeCall, 2,
+					wasm.OpcodeDelegate, 0,
+					wasm.OpcodeCatch, 0,
+					wasm.OpcodeI32Const, 0xe4, 0x00, // 100.
+					wasm.OpcodeI32Add,
+					wasm.OpcodeEnd,
+					wasm.OpcodeEnd,
+				}},
+				exceptionThrowUnlessZero,
+			},
+			ExportSection: []wasm.Export{
+				{Name: ExportedFunctionName, Index: 0, Type: wasm.ExternTypeFunc},
+				{Name: "delegate", Index: 1, Type: wasm.ExternTypeFunc},
+			},
+			UsesExceptionHandling: true,
+		},
+	}
 )
 
+// exceptionThrowUnlessZero returns 7 if the param is zero, and otherwise throws it with the tag 0.
+var exceptionThrowUnlessZero = wasm.Code{Body: []byte{
+	wasm.OpcodeLocalGet, 0,
+	wasm.OpcodeI32Eqz,
+	wasm.OpcodeIf, i32,
+	wasm.OpcodeI32Const, 7,
+	wasm.OpcodeElse,
+	wasm.OpcodeLocalGet, 0,
+	wasm.OpcodeThrow, 0,
+	wasm.OpcodeEnd,
+	wasm.OpcodeEnd,
+}}
+
 // VecShuffleWithLane returns a VecShuffle test with a custom 16-bytes immediate (lane indexes).
 func VecShuffleWithLane(lane ...byte) *wasm.Module {
 	return &wasm.Module{
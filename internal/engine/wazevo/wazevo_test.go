@@ -68,4 +68,8 @@ func Test_ExecutionContextOffsets(t *testing.T) {
 	require.Equal(t, wazevoapi.Offset(unsafe.Offsetof(execCtx.tableGrowTrampolineAddress)), wazevoapi.ExecutionContextOffsetTableGrowTrampolineAddress)
 	require.Equal(t, wazevoapi.Offset(unsafe.Offsetof(execCtx.refFuncTrampolineAddress)), wazevoapi.ExecutionContextOffsetRefFuncTrampolineAddress)
 	require.Equal(t, wazevoapi.Offset(unsafe.Offsetof(execCtx.memmoveAddress)), wazevoapi.ExecutionContextOffsetMemmoveAddress)
+	require.Equal(t, wazevoapi.Offset(unsafe.Offsetof(execCtx.callDepthRemaining)), wazevoapi.ExecutionContextOffsetCallDepthRemaining)
+	require.Equal(t, wazevoapi.Offset(unsafe.Offsetof(execCtx.fuelRemaining)), wazevoapi.ExecutionContextOffsetFuelRemaining)
+	require.Equal(t, wazevoapi.Offset(unsafe.Offsetof(execCtx.exceptionTag)), wazevoapi.ExecutionContextOffsetExceptionTag)
+	require.Equal(t, wazevoapi.Offset(unsafe.Offsetof(execCtx.exceptionPayload)), wazevoapi.ExecutionContextOffsetExceptionPayloadBegin)
 }
@@ -0,0 +1,17 @@
+package wazevoapi
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrDeadlineExceeded is returned when compiling a function doesn't finish by its deadline.
+var ErrDeadlineExceeded = errors.New("compilation deadline exceeded")
+
+// CheckDeadline returns ErrDeadlineExceeded if the given deadline has passed. The zero time means no deadline.
+func CheckDeadline(deadline time.Time) error {
+	if !deadline.IsZero() && time.Now().After(deadline) {
+		return ErrDeadlineExceeded
+	}
+	return nil
+}
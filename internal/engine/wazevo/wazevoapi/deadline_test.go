@@ -0,0 +1,14 @@
+package wazevoapi
+
+import (
+	"testing"
+	"time"
+
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestCheckDeadline(t *testing.T) {
+	require.NoError(t, CheckDeadline(time.Time{}))
+	require.NoError(t, CheckDeadline(time.Now().Add(time.Hour)))
+	require.Equal(t, ErrDeadlineExceeded, CheckDeadline(time.Now().Add(-time.Nanosecond)))
+}
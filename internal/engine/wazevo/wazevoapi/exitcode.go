@@ -26,6 +26,9 @@ const (
 	ExitCodeCallGoFunctionWithListener
 	ExitCodeTableGrow
 	ExitCodeRefFunc
+	ExitCodeUnalignedAtomic
+	ExitCodeCallStackExhausted
+	ExitCodeFuelExhausted
 	exitCodeMax
 )
 
@@ -74,6 +77,12 @@ func (e ExitCode) String() string {
 		return "table_grow"
 	case ExitCodeRefFunc:
 		return "ref_func"
+	case ExitCodeUnalignedAtomic:
+		return "unaligned_atomic"
+	case ExitCodeCallStackExhausted:
+		return "call_stack_exhausted"
+	case ExitCodeFuelExhausted:
+		return "fuel_exhausted"
 	}
 	panic("TODO")
 }
@@ -49,8 +49,21 @@ const (
 	// ExecutionContextOffsetRefFuncTrampolineAddress is an offset of `refFuncTrampolineAddress` field in wazevo.executionContext
 	ExecutionContextOffsetRefFuncTrampolineAddress Offset = 1136
 	ExecutionContextOffsetMemmoveAddress           Offset = 1144
+	// ExecutionContextOffsetCallDepthRemaining is an offset of `callDepthRemaining` field in wazevo.executionContext
+	ExecutionContextOffsetCallDepthRemaining Offset = 1152
+	// ExecutionContextOffsetFuelRemaining is an offset of `fuelRemaining` field in wazevo.executionContext
+	ExecutionContextOffsetFuelRemaining Offset = 1160
+	// ExecutionContextOffsetExceptionTag is an offset of `exceptionTag` field in wazevo.executionContext
+	ExecutionContextOffsetExceptionTag Offset = 1168
+	// ExecutionContextOffsetExceptionPayloadBegin is an offset of the first element of `exceptionPayload` field in
+	// wazevo.executionContext
+	ExecutionContextOffsetExceptionPayloadBegin Offset = 1176
 )
 
+// ExceptionPayloadSlots is the number of 64-bit slots holding the values carried by an exception, where a v128 value
+// takes two slots. Tags whose params don't fit aren't supported.
+const ExceptionPayloadSlots = 8
+
 // ModuleContextOffsetData allows the compilers to get the information about offsets to the fields of wazevo.moduleContextOpaque,
 // This is unique per module.
 type ModuleContextOffsetData struct {
@@ -65,7 +78,12 @@ type ModuleContextOffsetData struct {
 	BeforeListenerTrampolines1stElement,
 	AfterListenerTrampolines1stElement,
 	DataInstances1stElement,
-	ElementInstances1stElement Offset
+	ElementInstances1stElement,
+	// TagsBegin is the offset of the pointers to *wasm.TagInstance, or -1 if the module has no tags.
+	TagsBegin,
+	// ExitCodeCheckFlagAddress holds the address of a 64-bit flag which is non-zero when the module needs to
+	// exit to Go to check the module exit code. This is only read when the module is compiled with ensureTermination.
+	ExitCodeCheckFlagAddress Offset
 }
 
 // ImportedFunctionOffset returns an offset of the i-th imported function.
@@ -113,6 +131,11 @@ func (m *ModuleContextOffsetData) LocalMemoryLen() Offset {
 	return -1
 }
 
+// TagOffset returns an offset of the i-th tag instance.
+func (m *ModuleContextOffsetData) TagOffset(tagIndex wasm.Index) Offset {
+	return m.TagsBegin + Offset(tagIndex)*8
+}
+
 // TableOffset returns an offset of the i-th table instance.
 func (m *ModuleContextOffsetData) TableOffset(tableIndex int) Offset {
 	return m.TablesBegin + Offset(tableIndex)*8
@@ -193,6 +216,17 @@ func NewModuleContextOffsetData(m *wasm.Module, withListener bool) ModuleContext
 	ret.ElementInstances1stElement = offset
 	offset += 8 // First element of ElementInstances.
 
+	if tags := int(m.ImportTagCount) + len(m.TagSection); tags > 0 {
+		ret.TagsBegin = offset
+		// Pointers to *wasm.TagInstance.
+		offset += Offset(tags) * 8
+	} else {
+		ret.TagsBegin = -1
+	}
+
+	ret.ExitCodeCheckFlagAddress = offset
+	offset += 8 // Address of the exit code check flag.
+
 	ret.TotalSize = int(offset)
 	return ret
 }
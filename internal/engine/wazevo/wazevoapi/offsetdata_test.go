@@ -28,7 +28,9 @@ func TestNewModuleContextOffsetData(t *testing.T) {
 				AfterListenerTrampolines1stElement:  -1,
 				DataInstances1stElement:             8,
 				ElementInstances1stElement:          16,
-				TotalSize:                           24,
+				TagsBegin:                           -1,
+				ExitCodeCheckFlagAddress:            24,
+				TotalSize:                           32,
 			},
 		},
 		{
@@ -45,7 +47,9 @@ func TestNewModuleContextOffsetData(t *testing.T) {
 				AfterListenerTrampolines1stElement:  -1,
 				DataInstances1stElement:             24,
 				ElementInstances1stElement:          32,
-				TotalSize:                           40,
+				TagsBegin:                           -1,
+				ExitCodeCheckFlagAddress:            40,
+				TotalSize:                           48,
 			},
 		},
 		{
@@ -62,7 +66,9 @@ func TestNewModuleContextOffsetData(t *testing.T) {
 				AfterListenerTrampolines1stElement:  -1,
 				DataInstances1stElement:             24,
 				ElementInstances1stElement:          32,
-				TotalSize:                           40,
+				TagsBegin:                           -1,
+				ExitCodeCheckFlagAddress:            40,
+				TotalSize:                           48,
 			},
 		},
 		{
@@ -79,7 +85,9 @@ func TestNewModuleContextOffsetData(t *testing.T) {
 				AfterListenerTrampolines1stElement:  -1,
 				DataInstances1stElement:             10*FunctionInstanceSize + 8,
 				ElementInstances1stElement:          10*FunctionInstanceSize + 16,
-				TotalSize:                           10*FunctionInstanceSize + 24,
+				TagsBegin:                           -1,
+				ExitCodeCheckFlagAddress:            10*FunctionInstanceSize + 24,
+				TotalSize:                           10*FunctionInstanceSize + 32,
 			},
 		},
 		{
@@ -96,7 +104,9 @@ func TestNewModuleContextOffsetData(t *testing.T) {
 				AfterListenerTrampolines1stElement:  -1,
 				DataInstances1stElement:             10*FunctionInstanceSize + 24,
 				ElementInstances1stElement:          10*FunctionInstanceSize + 32,
-				TotalSize:                           10*FunctionInstanceSize + 40,
+				TagsBegin:                           -1,
+				ExitCodeCheckFlagAddress:            10*FunctionInstanceSize + 40,
+				TotalSize:                           10*FunctionInstanceSize + 48,
 			},
 		},
 		{
@@ -120,7 +130,9 @@ func TestNewModuleContextOffsetData(t *testing.T) {
 				AfterListenerTrampolines1stElement:  -1,
 				DataInstances1stElement:             24 + 10*FunctionInstanceSize + 8*30 + 8 + 8*15,
 				ElementInstances1stElement:          24 + 10*FunctionInstanceSize + 8*30 + 8 + 8*15 + 8,
-				TotalSize:                           24 + 10*FunctionInstanceSize + 8*30 + 8 + 8*15 + 16,
+				TagsBegin:                           -1,
+				ExitCodeCheckFlagAddress:            24 + 10*FunctionInstanceSize + 8*30 + 8 + 8*15 + 16,
+				TotalSize:                           24 + 10*FunctionInstanceSize + 8*30 + 8 + 8*15 + 24,
 			},
 		},
 		{
@@ -145,7 +157,28 @@ func TestNewModuleContextOffsetData(t *testing.T) {
 				AfterListenerTrampolines1stElement:  24 + 10*FunctionInstanceSize + 8*30 + 8 + 8*15 + 8,
 				DataInstances1stElement:             24 + 10*FunctionInstanceSize + 8*30 + 8 + 8*15 + 16,
 				ElementInstances1stElement:          24 + 10*FunctionInstanceSize + 8*30 + 8 + 8*15 + 24,
-				TotalSize:                           24 + 10*FunctionInstanceSize + 8*30 + 8 + 8*15 + 32,
+				TagsBegin:                           -1,
+				ExitCodeCheckFlagAddress:            24 + 10*FunctionInstanceSize + 8*30 + 8 + 8*15 + 32,
+				TotalSize:                           24 + 10*FunctionInstanceSize + 8*30 + 8 + 8*15 + 40,
+			},
+		},
+		{
+			name: "tags",
+			m:    &wasm.Module{ImportTagCount: 1, TagSection: []wasm.Index{0, 0}},
+			exp: ModuleContextOffsetData{
+				LocalMemoryBegin:                    -1,
+				ImportedMemoryBegin:                 -1,
+				ImportedFunctionsBegin:              -1,
+				GlobalsBegin:                        -1,
+				TypeIDs1stElement:                   -1,
+				TablesBegin:                         -1,
+				BeforeListenerTrampolines1stElement: -1,
+				AfterListenerTrampolines1stElement:  -1,
+				DataInstances1stElement:             8,
+				ElementInstances1stElement:          16,
+				TagsBegin:                           24,
+				ExitCodeCheckFlagAddress:            24 + 8*3,
+				TotalSize:                           32 + 8*3,
 			},
 		},
 	} {
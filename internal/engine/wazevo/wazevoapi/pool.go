@@ -1,5 +1,7 @@
 package wazevoapi
 
+import "unsafe"
+
 const poolPageSize = 128
 
 // Pool is a pool of T that can be allocated and reset.
@@ -45,6 +47,13 @@ func (p *Pool[T]) Allocate() *T {
 	return ret
 }
 
+// Bytes returns the size in bytes of the pages currently used by the pool.
+// This is used to observe the scratch memory used during compilation.
+func (p *Pool[T]) Bytes() int {
+	var zero T
+	return len(p.pages) * poolPageSize * int(unsafe.Sizeof(zero))
+}
+
 // View returns the pointer to i-th item from the pool.
 func (p *Pool[T]) View(i int) *T {
 	page, index := i/poolPageSize, i%poolPageSize
@@ -0,0 +1,26 @@
+package wazevoapi
+
+import (
+	"testing"
+	"unsafe"
+
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestPool_Bytes(t *testing.T) {
+	p := NewPool[uint64](func(*uint64) {})
+	require.Equal(t, 0, p.Bytes())
+
+	pageBytes := poolPageSize * int(unsafe.Sizeof(uint64(0)))
+	p.Allocate()
+	require.Equal(t, pageBytes, p.Bytes())
+
+	for i := 0; i < poolPageSize; i++ {
+		p.Allocate()
+	}
+	require.Equal(t, 2*pageBytes, p.Bytes())
+
+	// Reset retains the pages for reuse, but they are no longer in use.
+	p.Reset()
+	require.Equal(t, 0, p.Bytes())
+}
@@ -31,6 +31,8 @@ var (
 	ErrnoExist = &Errno{"EEXIST"}
 	// ErrnoFault Bad address.
 	ErrnoFault = &Errno{"EFAULT"}
+	// ErrnoFbig File too large.
+	ErrnoFbig = &Errno{"EFBIG"}
 	// ErrnoIntr Interrupted function.
 	ErrnoIntr = &Errno{"EINTR"}
 	// ErrnoInval Invalid argument.
@@ -80,6 +82,8 @@ func ToErrno(err error) *Errno {
 		return ErrnoExist
 	case sys.EFAULT:
 		return ErrnoFault
+	case sys.EFBIG:
+		return ErrnoFbig
 	case sys.EINTR:
 		return ErrnoIntr
 	case sys.EINVAL:
@@ -45,6 +45,11 @@ func TestToErrno(t *testing.T) {
 			input:    sys.EFAULT,
 			expected: ErrnoFault,
 		},
+		{
+			name:     "sys.EFBIG",
+			input:    sys.EFBIG,
+			expected: ErrnoFbig,
+		},
 		{
 			name:     "sys.EINTR",
 			input:    sys.EINTR,
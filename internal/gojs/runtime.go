@@ -3,6 +3,7 @@ package gojs
 import (
 	"context"
 	"fmt"
+	"io"
 	"time"
 
 	"github.com/tetratelabs/wazero/api"
@@ -156,10 +157,8 @@ func getRandomData(_ context.Context, mod api.Module, stack goarch.Stack) {
 
 	randSource := mod.(*wasm.ModuleInstance).Sys.RandSource()
 
-	bufLen := len(r)
-	if n, err := randSource.Read(r); err != nil {
-		panic(fmt.Errorf("RandSource.Read(r /* len=%d */) failed: %w", bufLen, err))
-	} else if n != bufLen {
-		panic(fmt.Errorf("RandSource.Read(r /* len=%d */) read %d bytes", bufLen, n))
+	// Loop until r is filled, as the source may return fewer bytes per read.
+	if n, err := io.ReadFull(randSource, r); err != nil {
+		panic(fmt.Errorf("RandSource.Read(r /* len=%d */) read %d bytes: %w", len(r), n, err))
 	}
 }
@@ -7,9 +7,11 @@ import (
 	"math"
 	"testing"
 
+	"github.com/tetratelabs/wazero"
 	"github.com/tetratelabs/wazero/api"
 	"github.com/tetratelabs/wazero/internal/engine/compiler"
 	"github.com/tetratelabs/wazero/internal/platform"
+	"github.com/tetratelabs/wazero/internal/testing/binaryencoding"
 	"github.com/tetratelabs/wazero/internal/testing/require"
 	"github.com/tetratelabs/wazero/internal/wasm"
 )
@@ -21,6 +23,12 @@ const (
 	// callGoReflectHostName is the name of exported function which calls the
 	// Go-implemented host function defined in reflection.
 	callGoReflectHostName = "call_go_reflect_host"
+	// callAddReflectName is the name of exported function which calls the
+	// "add" host function defined via HostFunctionBuilder.WithFunc.
+	callAddReflectName = "call_add_reflect"
+	// callAddGoName is the name of exported function which calls the "add"
+	// host function defined via HostFunctionBuilder.WithGoModuleFunction.
+	callAddGoName = "call_add_go"
 )
 
 // BenchmarkHostFunctionCall measures the cost of host function calls whose target functions are either
@@ -123,6 +131,104 @@ func TestBenchmarkFunctionCall(t *testing.T) {
 	}
 }
 
+// BenchmarkHostFunctionBuilder compares the cost of calling a trivial "add" host function defined via
+// HostFunctionBuilder.WithFunc, which uses reflection, to one defined via HostFunctionBuilder.WithGoModuleFunction,
+// which reads and writes the stack directly.
+func BenchmarkHostFunctionBuilder(b *testing.B) {
+	b.Run("interpreter", func(b *testing.B) {
+		benchmarkHostFunctionBuilder(b, wazero.NewRuntimeConfigInterpreter())
+	})
+	if platform.CompilerSupported() {
+		b.Run("compiler", func(b *testing.B) {
+			benchmarkHostFunctionBuilder(b, wazero.NewRuntimeConfigCompiler())
+		})
+	}
+}
+
+func benchmarkHostFunctionBuilder(b *testing.B, config wazero.RuntimeConfig) {
+	r, m := instantiateAddHostFunctionModule(testCtx, config, func(err error) {
+		if err != nil {
+			b.Fatal(err)
+		}
+	})
+	defer r.Close(testCtx)
+
+	for _, fn := range []string{callAddReflectName, callAddGoName} {
+		f := m.ExportedFunction(fn)
+		b.Run(fn, func(b *testing.B) {
+			stack := make([]uint64, 2)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				stack[0], stack[1] = 1, 2
+				if err := f.CallWithStack(testCtx, stack); err != nil {
+					b.Fatal(err)
+				}
+				if stack[0] != 3 {
+					b.Fail()
+				}
+			}
+		})
+	}
+}
+
+func TestHostFunctionBuilder_add(t *testing.T) {
+	r, m := instantiateAddHostFunctionModule(testCtx, wazero.NewRuntimeConfig(), func(err error) {
+		require.NoError(t, err)
+	})
+	defer r.Close(testCtx)
+
+	for _, fn := range []string{callAddReflectName, callAddGoName} {
+		res, err := m.ExportedFunction(fn).Call(testCtx, 1, math.MaxUint32)
+		require.NoError(t, err)
+		require.Equal(t, []uint64{0}, res) // i32 overflow
+	}
+}
+
+// instantiateAddHostFunctionModule instantiates a host module "env" exporting the same "add" host function defined
+// via reflection and via the stack, as well as a guest module calling each of them.
+func instantiateAddHostFunctionModule(
+	ctx context.Context,
+	config wazero.RuntimeConfig,
+	requireNoError func(error),
+) (wazero.Runtime, api.Module) {
+	r := wazero.NewRuntimeWithConfig(ctx, config)
+
+	i32 := api.ValueTypeI32
+	_, err := r.NewHostModuleBuilder("env").
+		NewFunctionBuilder().
+		WithFunc(func(x, y uint32) uint32 { return x + y }).
+		Export("add_reflect").
+		NewFunctionBuilder().
+		WithGoModuleFunction(api.GoModuleFunc(func(_ context.Context, _ api.Module, stack []uint64) {
+			x, y := api.DecodeU32(stack[0]), api.DecodeU32(stack[1])
+			stack[0] = api.EncodeU32(x + y)
+		}), []api.ValueType{i32, i32}, []api.ValueType{i32}).
+		Export("add_go").
+		Instantiate(ctx)
+	requireNoError(err)
+
+	callBody := func(idx byte) []byte {
+		return []byte{wasm.OpcodeLocalGet, 0, wasm.OpcodeLocalGet, 1, wasm.OpcodeCall, idx, wasm.OpcodeEnd}
+	}
+	guest := binaryencoding.EncodeModule(&wasm.Module{
+		TypeSection: []wasm.FunctionType{{Params: []wasm.ValueType{i32, i32}, Results: []wasm.ValueType{i32}}},
+		ImportSection: []wasm.Import{
+			{Module: "env", Name: "add_reflect", Type: wasm.ExternTypeFunc, DescFunc: 0},
+			{Module: "env", Name: "add_go", Type: wasm.ExternTypeFunc, DescFunc: 0},
+		},
+		FunctionSection: []wasm.Index{0, 0},
+		CodeSection:     []wasm.Code{{Body: callBody(0)}, {Body: callBody(1)}},
+		ExportSection: []wasm.Export{
+			{Name: callAddReflectName, Type: wasm.ExternTypeFunc, Index: 2},
+			{Name: callAddGoName, Type: wasm.ExternTypeFunc, Index: 3},
+		},
+	})
+	m, err := r.Instantiate(ctx, guest)
+	requireNoError(err)
+	return r, m
+}
+
 func getCallEngine(m *wasm.ModuleInstance, name string) (ce api.Function) {
 	exp := m.Exports[name]
 	ce = m.Engine.NewFunction(exp.Index)
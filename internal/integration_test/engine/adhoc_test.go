@@ -7,9 +7,11 @@ import (
 	"errors"
 	"fmt"
 	"math"
+	"math/bits"
 	"runtime"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 	"unsafe"
@@ -33,8 +35,12 @@ import (
 )
 
 type testCase struct {
-	f          func(t *testing.T, r wazero.Runtime)
-	wazevoSkip bool
+	f            func(t *testing.T, r wazero.Runtime)
+	wazevoSkip   bool
+	compilerSkip bool
+	// features are enabled in addition to api.CoreFeaturesV2, for tests of
+	// experimental features which are otherwise rejected by validation.
+	features api.CoreFeatures
 }
 
 var tests = map[string]testCase{
@@ -49,13 +55,31 @@ var tests = map[string]testCase{
 	"multiple instantiation from same source":                          {f: testMultipleInstantiation},
 	"exported function that grows memory":                              {f: testMemOps},
 	"import functions with reference type in signature":                {f: testReftypeImports},
+	"externref round-trip through table":                               {f: testExternrefTable},
 	"overflow integer addition":                                        {f: testOverflow},
 	"un-signed extend global":                                          {f: testGlobalExtend},
+	"signed and unsigned comparison and extension":                     {f: testSignedUnsigned},
 	"user-defined primitive in host func":                              {f: testUserDefinedPrimitiveHostFunc},
 	"ensures invocations terminate on module close":                    {f: testEnsureTerminationOnClose},
+	"ensures invocations terminate on fuel exhausted":                  {f: testFuelExhausted, compilerSkip: true},
+	"call stack exhausted at max call depth":                           {f: testMaxCallDepth, compilerSkip: true, features: experimental.CoreFeaturesTailCall},
 	"call host function indirectly":                                    {f: callHostFunctionIndirect},
 	"lookup function":                                                  {f: testLookupFunction},
 	"memory grow in recursive call":                                    {f: testMemoryGrowInRecursiveCall},
+	"memory.init from passive data segment":                            {f: testMemoryInit},
+	"mutable global set from host":                                     {f: testMutableGlobalSet},
+	"table grow and set from host":                                     {f: testTableGrowSet},
+	"table entries":                                                    {f: testTableEntries},
+	"call indirect from host":                                          {f: testCallIndirect},
+	"tail calls":                                                       {f: testTailCall, features: experimental.CoreFeaturesTailCall},
+	"multiple memories":                                                {f: testMultiMemory, wazevoSkip: true, features: experimental.CoreFeaturesMultiMemory},
+	"relaxed SIMD":                                                     {f: testRelaxedSIMD, features: experimental.CoreFeaturesRelaxedSIMD},
+	"atomic memory instructions":                                       {f: testAtomic, features: experimental.CoreFeaturesThreads},
+	"shared memory grows in place":                                     {f: testSharedMemoryGrow, features: experimental.CoreFeaturesThreads},
+	"64-bit memory":                                                    {f: testMemory64, features: experimental.CoreFeaturesMemory64},
+	"float load and store preserve bits":                               {f: testFloatLoadStoreBits},
+	"table slots are initially null":                                   {f: testTableInitiallyNull},
+	"table bulk operations":                                            {f: testTableBulkOps},
 	"call":                                                             {f: testCall},
 	"module memory":                                                    {f: testModuleMemory},
 	"two indirection to host":                                          {f: testTwoIndirection},
@@ -78,7 +102,13 @@ func TestEngineCompiler(t *testing.T) {
 	if !platform.CompilerSupported() {
 		t.Skip()
 	}
-	runAllTests(t, tests, wazero.NewRuntimeConfigCompiler().WithCloseOnContextDone(true), false)
+	compilerTests := make(map[string]testCase, len(tests))
+	for name, tc := range tests {
+		if !tc.compilerSkip {
+			compilerTests[name] = tc
+		}
+	}
+	runAllTests(t, compilerTests, wazero.NewRuntimeConfigCompiler().WithCloseOnContextDone(true), false)
 }
 
 func TestEngineInterpreter(t *testing.T) {
@@ -111,6 +141,10 @@ func runAllTests(t *testing.T, tests map[string]testCase, config wazero.RuntimeC
 		}
 		t.Run(name, func(t *testing.T) {
 			t.Parallel()
+			config := config
+			if tc.features != 0 {
+				config = config.WithCoreFeatures(api.CoreFeaturesV2 | tc.features)
+			}
 			tc.f(t, wazero.NewRuntimeWithConfig(testCtx, config))
 		})
 	}
@@ -180,6 +214,19 @@ func testEnsureTerminationOnClose(t *testing.T, r wazero.Runtime) {
 		require.Contains(t, err.Error(), "module closed with context deadline exceeded")
 	})
 
+	t.Run("context timeout observed promptly", func(t *testing.T) {
+		_, infinite := newInfiniteLoopFn(t)
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		start := time.Now()
+		_, err = infinite.Call(ctx)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "module closed with context deadline exceeded")
+		// The loop checks for the closure on each iteration, so this returns
+		// shortly after the deadline. The bound is generous to avoid flakes.
+		require.True(t, time.Since(start) < time.Second, "took %v", time.Since(start))
+	})
+
 	t.Run("explicit close of module", func(t *testing.T) {
 		module, infinite := newInfiniteLoopFn(t)
 		go func() {
@@ -254,6 +301,99 @@ func testReftypeImports(t *testing.T, r wazero.Runtime) {
 	require.Equal(t, uintptr(unsafe.Pointer(hostObj)), uintptr(actual[0]))
 }
 
+// testExternrefTable ensures a host pointer survives a round-trip through a
+// guest table, including a garbage collection after the caller dropped its
+// reference. As documented on api.ValueTypeExternref, the table doesn't keep
+// the object alive, so the host keeps it reachable in a registry, which is
+// the only place it remains.
+func testExternrefTable(t *testing.T, r wazero.Runtime) {
+	host, err := r.NewHostModuleBuilder("host").
+		NewFunctionBuilder().
+		WithFunc(func(ctx context.Context, ref uintptr) uintptr { return ref }).
+		Export("identity").
+		Instantiate(testCtx)
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, host.Close(testCtx))
+	}()
+
+	externref := wasm.ValueTypeExternref
+	bin := binaryencoding.EncodeModule(&wasm.Module{
+		TypeSection: []wasm.FunctionType{
+			{Params: []wasm.ValueType{externref}, Results: []wasm.ValueType{externref}},
+			{Params: []wasm.ValueType{externref}},
+			{Results: []wasm.ValueType{externref}},
+			{Results: []wasm.ValueType{i32}},
+		},
+		ImportSection:   []wasm.Import{{Module: "host", Name: "identity", Type: wasm.ExternTypeFunc, DescFunc: 0}},
+		FunctionSection: []wasm.Index{1, 2, 2, 3},
+		CodeSection: []wasm.Code{
+			{Body: []byte{wasm.OpcodeI32Const, 0, wasm.OpcodeLocalGet, 0, wasm.OpcodeTableSet, 0, wasm.OpcodeEnd}},
+			{Body: []byte{wasm.OpcodeI32Const, 0, wasm.OpcodeTableGet, 0, wasm.OpcodeEnd}},
+			{Body: []byte{wasm.OpcodeI32Const, 0, wasm.OpcodeTableGet, 0, wasm.OpcodeCall, 0, wasm.OpcodeEnd}},
+			{Body: []byte{wasm.OpcodeI32Const, 0, wasm.OpcodeTableGet, 0, wasm.OpcodeRefIsNull, wasm.OpcodeEnd}},
+		},
+		TableSection: []wasm.Table{{Min: 1, Type: wasm.RefTypeExternref}},
+		ExportSection: []wasm.Export{
+			{Name: "store", Type: wasm.ExternTypeFunc, Index: 1},
+			{Name: "load", Type: wasm.ExternTypeFunc, Index: 2},
+			{Name: "load_via_host", Type: wasm.ExternTypeFunc, Index: 3},
+			{Name: "is_null", Type: wasm.ExternTypeFunc, Index: 4},
+		},
+	})
+	mod, err := r.Instantiate(testCtx, bin)
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, mod.Close(testCtx))
+	}()
+
+	call := func(name string, params ...uint64) uint64 {
+		res, err := mod.ExportedFunction(name).Call(testCtx, params...)
+		require.NoError(t, err)
+		if len(res) == 0 {
+			return 0
+		}
+		return res[0]
+	}
+
+	// The table is initialized with null.
+	require.Equal(t, uint64(1), call("is_null"))
+	require.Equal(t, uint64(0), call("load"))
+
+	type hostObject struct{ name string }
+	registry := map[uint64]*hostObject{}
+	var collected atomic.Bool
+	ref := func() uint64 { // obj is not reachable once this returns.
+		obj := &hostObject{name: "hello"}
+		runtime.SetFinalizer(obj, func(*hostObject) { collected.Store(true) })
+		ref := api.EncodeExternref(uintptr(unsafe.Pointer(obj)))
+		registry[ref] = obj
+		return ref
+	}()
+	call("store", ref)
+	runtime.GC()
+	runtime.GC() // The second cycle runs finalizers queued by the first.
+	require.False(t, collected.Load())
+
+	require.Equal(t, uint64(0), call("is_null"))
+	require.Equal(t, ref, call("load"))
+	require.Equal(t, ref, call("load_via_host"))
+	require.Equal(t, "hello", registry[call("load")].name)
+
+	call("store", 0)
+	require.Equal(t, uint64(1), call("is_null"))
+	require.Equal(t, uint64(0), call("load_via_host"))
+
+	// Once the host drops it too, the object is collected, which shows the
+	// finalizer above would have noticed a collection.
+	delete(registry, ref)
+	for i := 0; i < 100 && !collected.Load(); i++ {
+		runtime.GC()
+		time.Sleep(time.Millisecond)
+	}
+	require.True(t, collected.Load())
+}
+
 func testHugeStack(t *testing.T, r wazero.Runtime) {
 	module, err := r.Instantiate(testCtx, hugestackWasm)
 	require.NoError(t, err)
@@ -311,6 +451,258 @@ func testGlobalExtend(t *testing.T, r wazero.Runtime) {
 	require.Equal(t, uint64(0xffff_ffff), res[0])
 }
 
+// testFuelExhausted ensures a guest stuck in a loop, or recursing without
+// looping, is closed once its fuel runs out.
+func testFuelExhausted(t *testing.T, r wazero.Runtime) {
+	compiled, err := r.CompileModule(testCtx, infiniteLoopWasm)
+	require.NoError(t, err)
+
+	for _, fuel := range []uint64{0, 1, 1000} {
+		fuel := fuel
+		t.Run(fmt.Sprintf("fuel=%d", fuel), func(t *testing.T) {
+			m, err := r.InstantiateModule(testCtx, compiled, wazero.NewModuleConfig().WithName(t.Name()).WithFuel(fuel))
+			require.NoError(t, err)
+
+			_, err = m.ExportedFunction("infinite_loop").Call(testCtx)
+			require.Equal(t, sys.NewExitError(sys.ExitCodeFuelExhausted), err)
+			require.True(t, m.IsClosed())
+		})
+	}
+
+	t.Run("recursion", func(t *testing.T) {
+		// fib calls itself twice for each n > 1, so it takes exponential time without looping.
+		i32_i32 := wasm.FunctionType{Params: []wasm.ValueType{i32}, Results: []wasm.ValueType{i32}}
+		compiled, err := r.CompileModule(testCtx, binaryencoding.EncodeModule(&wasm.Module{
+			TypeSection:     []wasm.FunctionType{i32_i32},
+			FunctionSection: []wasm.Index{0},
+			CodeSection: []wasm.Code{{Body: []byte{
+				wasm.OpcodeLocalGet, 0, wasm.OpcodeI32Const, 2, wasm.OpcodeI32LtU,
+				wasm.OpcodeIf, byte(wasm.ValueTypeI32),
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeElse,
+				wasm.OpcodeLocalGet, 0, wasm.OpcodeI32Const, 1, wasm.OpcodeI32Sub, wasm.OpcodeCall, 0,
+				wasm.OpcodeLocalGet, 0, wasm.OpcodeI32Const, 2, wasm.OpcodeI32Sub, wasm.OpcodeCall, 0,
+				wasm.OpcodeI32Add,
+				wasm.OpcodeEnd,
+				wasm.OpcodeEnd,
+			}}},
+			ExportSection: []wasm.Export{{Name: "fib", Type: wasm.ExternTypeFunc, Index: 0}},
+		}))
+		require.NoError(t, err)
+
+		m, err := r.InstantiateModule(testCtx, compiled, wazero.NewModuleConfig().WithName(t.Name()).WithFuel(1000))
+		require.NoError(t, err)
+
+		// fib(10) makes 177 calls, so fits within the fuel.
+		res, err := m.ExportedFunction("fib").Call(testCtx, 10)
+		require.NoError(t, err)
+		require.Equal(t, uint64(55), res[0])
+
+		_, err = m.ExportedFunction("fib").Call(testCtx, 64)
+		require.Equal(t, sys.NewExitError(sys.ExitCodeFuelExhausted), err)
+		require.True(t, m.IsClosed())
+	})
+}
+
+func testMaxCallDepth(t *testing.T, r wazero.Runtime) {
+	i32_i32 := wasm.FunctionType{Params: []wasm.ValueType{i32}, Results: []wasm.ValueType{i32}}
+	compiled, err := r.CompileModule(testCtx, binaryencoding.EncodeModule(&wasm.Module{
+		TypeSection:     []wasm.FunctionType{{}, i32_i32},
+		FunctionSection: []wasm.Index{0, 1, 1, 1},
+		CodeSection: []wasm.Code{
+			// recurse calls itself forever.
+			{Body: []byte{wasm.OpcodeCall, 0, wasm.OpcodeEnd}},
+			// depth returns n after nesting n calls to itself, returning from the innermost one with br_if.
+			{Body: []byte{
+				wasm.OpcodeI32Const, 0,
+				wasm.OpcodeLocalGet, 0, wasm.OpcodeI32Eqz,
+				wasm.OpcodeBrIf, 0,
+				wasm.OpcodeDrop,
+				wasm.OpcodeLocalGet, 0, wasm.OpcodeI32Const, 1, wasm.OpcodeI32Sub,
+				wasm.OpcodeCall, 1,
+				wasm.OpcodeI32Const, 1, wasm.OpcodeI32Add,
+				wasm.OpcodeEnd,
+			}},
+			// depth_twice calls depth twice, which only succeeds if the depth is restored on return.
+			{Body: []byte{
+				wasm.OpcodeLocalGet, 0, wasm.OpcodeCall, 1,
+				wasm.OpcodeLocalGet, 0, wasm.OpcodeCall, 1,
+				wasm.OpcodeI32Add,
+				wasm.OpcodeEnd,
+			}},
+			// count_down tail calls itself n times, which doesn't increase the depth.
+			{Body: []byte{
+				wasm.OpcodeLocalGet, 0, wasm.OpcodeI32Eqz, wasm.OpcodeIf, 0x40,
+				wasm.OpcodeLocalGet, 0, wasm.OpcodeReturn,
+				wasm.OpcodeEnd,
+				wasm.OpcodeLocalGet, 0, wasm.OpcodeI32Const, 1, wasm.OpcodeI32Sub,
+				wasm.OpcodeReturnCall, 3,
+				wasm.OpcodeEnd,
+			}},
+		},
+		ExportSection: []wasm.Export{
+			{Name: "recurse", Type: wasm.ExternTypeFunc, Index: 0},
+			{Name: "depth", Type: wasm.ExternTypeFunc, Index: 1},
+			{Name: "depth_twice", Type: wasm.ExternTypeFunc, Index: 2},
+			{Name: "count_down", Type: wasm.ExternTypeFunc, Index: 3},
+		},
+	}))
+	require.NoError(t, err)
+
+	const maxCallDepth = 100
+	m, err := r.InstantiateModule(testCtx, compiled, wazero.NewModuleConfig().WithMaxCallDepth(maxCallDepth))
+	require.NoError(t, err)
+
+	_, err = m.ExportedFunction("recurse").Call(testCtx)
+	require.ErrorIs(t, err, wasmruntime.ErrRuntimeCallStackExhausted)
+
+	// The outermost call of depth is the first, so n == maxCallDepth-1 is the deepest allowed.
+	depth := m.ExportedFunction("depth")
+	res, err := depth.Call(testCtx, maxCallDepth-1)
+	require.NoError(t, err)
+	require.Equal(t, uint64(maxCallDepth-1), res[0])
+	_, err = depth.Call(testCtx, maxCallDepth)
+	require.ErrorIs(t, err, wasmruntime.ErrRuntimeCallStackExhausted)
+
+	res, err = m.ExportedFunction("depth_twice").Call(testCtx, maxCallDepth-2)
+	require.NoError(t, err)
+	require.Equal(t, uint64(2*(maxCallDepth-2)), res[0])
+
+	res, err = m.ExportedFunction("count_down").Call(testCtx, 10*maxCallDepth)
+	require.NoError(t, err)
+	require.Zero(t, res[0])
+
+	// The module is still usable after trapping.
+	require.False(t, m.IsClosed())
+	res, err = depth.Call(testCtx, 1)
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), res[0])
+}
+
+// testSignedUnsigned ensures signed and unsigned comparisons and extensions
+// agree across engines on values straddling the sign boundary.
+func testSignedUnsigned(t *testing.T, r wazero.Runtime) {
+	i32Values := []int32{0, 1, -1, 0x7f, -0x80, 0x80, 0x7fff, -0x8000, 0x8000, math.MaxInt32, math.MinInt32}
+	i64Values := []int64{0, 1, -1, 0x7f, -0x80, 0x7fff, -0x8000, math.MaxInt32, math.MinInt32, math.MaxUint32, math.MaxInt64, math.MinInt64}
+
+	type binaryOp struct {
+		name     string
+		opcode   wasm.Opcode
+		expected func(x, y int64) bool
+	}
+	i32Cmps := []binaryOp{
+		{name: "i32.lt_s", opcode: wasm.OpcodeI32LtS, expected: func(x, y int64) bool { return int32(x) < int32(y) }},
+		{name: "i32.lt_u", opcode: wasm.OpcodeI32LtU, expected: func(x, y int64) bool { return uint32(x) < uint32(y) }},
+		{name: "i32.gt_s", opcode: wasm.OpcodeI32GtS, expected: func(x, y int64) bool { return int32(x) > int32(y) }},
+		{name: "i32.gt_u", opcode: wasm.OpcodeI32GtU, expected: func(x, y int64) bool { return uint32(x) > uint32(y) }},
+		{name: "i32.le_s", opcode: wasm.OpcodeI32LeS, expected: func(x, y int64) bool { return int32(x) <= int32(y) }},
+		{name: "i32.le_u", opcode: wasm.OpcodeI32LeU, expected: func(x, y int64) bool { return uint32(x) <= uint32(y) }},
+		{name: "i32.ge_s", opcode: wasm.OpcodeI32GeS, expected: func(x, y int64) bool { return int32(x) >= int32(y) }},
+		{name: "i32.ge_u", opcode: wasm.OpcodeI32GeU, expected: func(x, y int64) bool { return uint32(x) >= uint32(y) }},
+	}
+	i64Cmps := []binaryOp{
+		{name: "i64.lt_s", opcode: wasm.OpcodeI64LtS, expected: func(x, y int64) bool { return x < y }},
+		{name: "i64.lt_u", opcode: wasm.OpcodeI64LtU, expected: func(x, y int64) bool { return uint64(x) < uint64(y) }},
+		{name: "i64.gt_s", opcode: wasm.OpcodeI64GtS, expected: func(x, y int64) bool { return x > y }},
+		{name: "i64.gt_u", opcode: wasm.OpcodeI64GtU, expected: func(x, y int64) bool { return uint64(x) > uint64(y) }},
+		{name: "i64.le_s", opcode: wasm.OpcodeI64LeS, expected: func(x, y int64) bool { return x <= y }},
+		{name: "i64.le_u", opcode: wasm.OpcodeI64LeU, expected: func(x, y int64) bool { return uint64(x) <= uint64(y) }},
+		{name: "i64.ge_s", opcode: wasm.OpcodeI64GeS, expected: func(x, y int64) bool { return x >= y }},
+		{name: "i64.ge_u", opcode: wasm.OpcodeI64GeU, expected: func(x, y int64) bool { return uint64(x) >= uint64(y) }},
+	}
+
+	type unaryOp struct {
+		name     string
+		opcode   wasm.Opcode
+		typ      wasm.FunctionType
+		expected func(x int64) uint64
+	}
+	i32_i32 := wasm.FunctionType{Params: []wasm.ValueType{i32}, Results: []wasm.ValueType{i32}}
+	i32_i64 := wasm.FunctionType{Params: []wasm.ValueType{i32}, Results: []wasm.ValueType{i64}}
+	i64_i64 := wasm.FunctionType{Params: []wasm.ValueType{i64}, Results: []wasm.ValueType{i64}}
+	extends := []unaryOp{
+		{name: "i64.extend_i32_s", opcode: wasm.OpcodeI64ExtendI32S, typ: i32_i64, expected: func(x int64) uint64 { return uint64(int64(int32(x))) }},
+		{name: "i64.extend_i32_u", opcode: wasm.OpcodeI64ExtendI32U, typ: i32_i64, expected: func(x int64) uint64 { return uint64(uint32(x)) }},
+		{name: "i32.extend8_s", opcode: wasm.OpcodeI32Extend8S, typ: i32_i32, expected: func(x int64) uint64 { return uint64(uint32(int32(int8(x)))) }},
+		{name: "i32.extend16_s", opcode: wasm.OpcodeI32Extend16S, typ: i32_i32, expected: func(x int64) uint64 { return uint64(uint32(int32(int16(x)))) }},
+		{name: "i64.extend8_s", opcode: wasm.OpcodeI64Extend8S, typ: i64_i64, expected: func(x int64) uint64 { return uint64(int64(int8(x))) }},
+		{name: "i64.extend16_s", opcode: wasm.OpcodeI64Extend16S, typ: i64_i64, expected: func(x int64) uint64 { return uint64(int64(int16(x))) }},
+		{name: "i64.extend32_s", opcode: wasm.OpcodeI64Extend32S, typ: i64_i64, expected: func(x int64) uint64 { return uint64(int64(int32(x))) }},
+	}
+
+	// Define one exported function per opcode, which applies it to its params.
+	m := &wasm.Module{}
+	addFunc := func(name string, typ wasm.FunctionType, body []byte) {
+		idx := wasm.Index(len(m.FunctionSection))
+		m.TypeSection = append(m.TypeSection, typ)
+		m.FunctionSection = append(m.FunctionSection, idx)
+		m.CodeSection = append(m.CodeSection, wasm.Code{Body: body})
+		m.ExportSection = append(m.ExportSection, wasm.Export{Name: name, Type: wasm.ExternTypeFunc, Index: idx})
+	}
+	for _, ops := range []struct {
+		cmps []binaryOp
+		typ  wasm.ValueType
+	}{{cmps: i32Cmps, typ: i32}, {cmps: i64Cmps, typ: i64}} {
+		typ := wasm.FunctionType{Params: []wasm.ValueType{ops.typ, ops.typ}, Results: []wasm.ValueType{i32}}
+		for _, op := range ops.cmps {
+			addFunc(op.name, typ, []byte{wasm.OpcodeLocalGet, 0, wasm.OpcodeLocalGet, 1, op.opcode, wasm.OpcodeEnd})
+		}
+	}
+	for _, op := range extends {
+		addFunc(op.name, op.typ, []byte{wasm.OpcodeLocalGet, 0, op.opcode, wasm.OpcodeEnd})
+	}
+
+	module, err := r.Instantiate(testCtx, binaryencoding.EncodeModule(m))
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, module.Close(testCtx))
+	}()
+
+	i64Params := make([]int64, 0, len(i64Values))
+	i64Params = append(i64Params, i64Values...)
+	i32Params := make([]int64, 0, len(i32Values))
+	for _, v := range i32Values {
+		i32Params = append(i32Params, int64(v))
+	}
+
+	for _, ops := range []struct {
+		cmps   []binaryOp
+		params []int64
+		encode func(int64) uint64
+	}{
+		{cmps: i32Cmps, params: i32Params, encode: func(v int64) uint64 { return api.EncodeI32(int32(v)) }},
+		{cmps: i64Cmps, params: i64Params, encode: func(v int64) uint64 { return api.EncodeI64(v) }},
+	} {
+		for _, op := range ops.cmps {
+			f := module.ExportedFunction(op.name)
+			for _, x := range ops.params {
+				for _, y := range ops.params {
+					res, err := f.Call(testCtx, ops.encode(x), ops.encode(y))
+					require.NoError(t, err)
+					var expected uint64
+					if op.expected(x, y) {
+						expected = 1
+					}
+					require.Equal(t, expected, res[0], "%s(%#x, %#x)", op.name, x, y)
+				}
+			}
+		}
+	}
+
+	for _, op := range extends {
+		f := module.ExportedFunction(op.name)
+		params, encode := i64Params, api.EncodeI64
+		if op.typ.Params[0] == i32 {
+			params, encode = i32Params, func(v int64) uint64 { return api.EncodeI32(int32(v)) }
+		}
+		for _, x := range params {
+			res, err := f.Call(testCtx, encode(x))
+			require.NoError(t, err)
+			require.Equal(t, op.expected(x), res[0], "%s(%#x)", op.name, x)
+		}
+	}
+}
+
 func testUnreachable(t *testing.T, r wazero.Runtime) {
 	callUnreachable := func() {
 		panic("panic in host function")
@@ -579,8 +971,8 @@ func callHostFunctionIndirect(t *testing.T, r wazero.Runtime) {
 		NameSection:     &wasm.NameSection{ModuleName: originWasmModule},
 	}
 
-	require.NoError(t, importingModule.Validate(api.CoreFeaturesV2))
-	require.NoError(t, originModule.Validate(api.CoreFeaturesV2))
+	require.NoError(t, importingModule.Validate(api.CoreFeaturesV2, wasm.MaximumBlockNestingDepth))
+	require.NoError(t, originModule.Validate(api.CoreFeaturesV2, wasm.MaximumBlockNestingDepth))
 	importingModuleBytes := binaryencoding.EncodeModule(importingModule)
 	originModuleBytes := binaryencoding.EncodeModule(originModule)
 
@@ -637,7 +1029,7 @@ func callReturnImportWasm(t *testing.T, importedModule, importingModule string,
 			},
 		},
 	}
-	require.NoError(t, module.Validate(api.CoreFeaturesV2))
+	require.NoError(t, module.Validate(api.CoreFeaturesV2, wasm.MaximumBlockNestingDepth))
 	return binaryencoding.EncodeModule(module)
 }
 
@@ -673,10 +1065,1455 @@ func callOuterInnerWasm(t *testing.T, importedModule, importingModule string) []
 			},
 		},
 	}
-	require.NoError(t, module.Validate(api.CoreFeaturesV2))
+	require.NoError(t, module.Validate(api.CoreFeaturesV2, wasm.MaximumBlockNestingDepth))
 	return binaryencoding.EncodeModule(module)
 }
 
+// testMemoryInit ensures passive data segments are only copied into memory by
+// memory.init, which traps when either the segment or the memory is too small.
+func testMemoryInit(t *testing.T, r wazero.Runtime) {
+	memoryInit := func(segment byte) []byte {
+		return []byte{
+			wasm.OpcodeLocalGet, 0, wasm.OpcodeLocalGet, 1, wasm.OpcodeLocalGet, 2,
+			wasm.OpcodeMiscPrefix, wasm.OpcodeMiscMemoryInit, segment, 0, // segment index, memory index
+			wasm.OpcodeEnd,
+		}
+	}
+	dataCount := uint32(2)
+	bin := binaryencoding.EncodeModule(&wasm.Module{
+		TypeSection:     []wasm.FunctionType{{Params: []wasm.ValueType{i32, i32, i32}}, {}},
+		FunctionSection: []wasm.Index{0, 0, 1},
+		CodeSection: []wasm.Code{
+			{Body: memoryInit(1)},
+			{Body: memoryInit(0)},
+			{Body: []byte{wasm.OpcodeMiscPrefix, wasm.OpcodeMiscDataDrop, 1, wasm.OpcodeEnd}},
+		},
+		MemorySection: &wasm.Memory{Min: 1, Cap: 1, Max: 1, IsMaxEncoded: true},
+		DataSection: []wasm.DataSegment{
+			{OffsetExpression: wasm.ConstantExpression{Opcode: wasm.OpcodeI32Const, Data: []byte{0}}, Init: []byte{0xff}},
+			{Passive: true, Init: []byte("hello")},
+		},
+		DataCountSection: &dataCount,
+		ExportSection: []wasm.Export{
+			{Name: "init", Type: wasm.ExternTypeFunc, Index: 0},
+			{Name: "init_active", Type: wasm.ExternTypeFunc, Index: 1},
+			{Name: "drop", Type: wasm.ExternTypeFunc, Index: 2},
+		},
+	})
+
+	mod, err := r.Instantiate(testCtx, bin)
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, mod.Close(testCtx))
+	}()
+
+	mem := mod.Memory()
+	init, initActive, drop := mod.ExportedFunction("init"), mod.ExportedFunction("init_active"), mod.ExportedFunction("drop")
+	memSize := uint64(mem.Size())
+
+	// Only the active segment was applied on instantiation.
+	buf, ok := mem.Read(0, 6)
+	require.True(t, ok)
+	require.Equal(t, []byte{0xff, 0, 0, 0, 0, 0}, buf)
+
+	t.Run("ok", func(t *testing.T) {
+		for _, tc := range []struct{ dst, src, len uint64 }{
+			{dst: 10, src: 1, len: 3},
+			{dst: memSize - 5, src: 0, len: 5}, // end of memory
+			{dst: memSize, src: 5, len: 0},     // empty at the end of both
+		} {
+			_, err := init.Call(testCtx, tc.dst, tc.src, tc.len)
+			require.NoError(t, err)
+		}
+		buf, ok := mem.Read(10, 3)
+		require.True(t, ok)
+		require.Equal(t, "ell", string(buf))
+		buf, ok = mem.Read(uint32(memSize-5), 5)
+		require.True(t, ok)
+		require.Equal(t, "hello", string(buf))
+	})
+
+	t.Run("out of bounds", func(t *testing.T) {
+		for _, tc := range []struct {
+			name          string
+			dst, src, len uint64
+		}{
+			{name: "src+len exceeds segment", dst: 100, src: 3, len: 3},
+			{name: "src exceeds segment", dst: 100, src: 6, len: 0},
+			{name: "src+len overflows", dst: 100, src: 1, len: math.MaxUint32},
+			{name: "dst+len exceeds memory", dst: memSize - 4, src: 0, len: 5},
+			{name: "dst exceeds memory", dst: memSize + 1, src: 0, len: 0},
+			{name: "dst+len overflows", dst: math.MaxUint32, src: 0, len: 1},
+		} {
+			tc := tc
+			t.Run(tc.name, func(t *testing.T) {
+				_, err := init.Call(testCtx, tc.dst, tc.src, tc.len)
+				require.ErrorIs(t, err, wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
+			})
+		}
+		// Nothing was written before trapping.
+		buf, ok := mem.Read(100, 3)
+		require.True(t, ok)
+		require.Equal(t, []byte{0, 0, 0}, buf)
+	})
+
+	t.Run("active segment is dropped", func(t *testing.T) {
+		_, err := initActive.Call(testCtx, 100, 0, 0)
+		require.NoError(t, err)
+		_, err = initActive.Call(testCtx, 100, 0, 1)
+		require.ErrorIs(t, err, wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
+	})
+
+	t.Run("data.drop", func(t *testing.T) {
+		_, err := drop.Call(testCtx)
+		require.NoError(t, err)
+		// Dropping again is allowed.
+		_, err = drop.Call(testCtx)
+		require.NoError(t, err)
+
+		// The dropped segment is empty, so only zero bytes at its start can be copied.
+		_, err = init.Call(testCtx, 100, 0, 0)
+		require.NoError(t, err)
+		_, err = init.Call(testCtx, 100, 0, 1)
+		require.ErrorIs(t, err, wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
+		_, err = init.Call(testCtx, 100, 1, 0)
+		require.ErrorIs(t, err, wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
+	})
+
+	t.Run("data.drop is per instance", func(t *testing.T) {
+		other, err := r.InstantiateWithConfig(testCtx, bin, wazero.NewModuleConfig().WithName("other"))
+		require.NoError(t, err)
+		defer func() {
+			require.NoError(t, other.Close(testCtx))
+		}()
+
+		// The segment dropped by the first instance is intact in the second one.
+		_, err = other.ExportedFunction("init").Call(testCtx, 20, 0, 5)
+		require.NoError(t, err)
+		buf, ok := other.Memory().Read(20, 5)
+		require.True(t, ok)
+		require.Equal(t, "hello", string(buf))
+
+		_, err = other.ExportedFunction("drop").Call(testCtx)
+		require.NoError(t, err)
+		_, err = other.ExportedFunction("init").Call(testCtx, 20, 0, 1)
+		require.ErrorIs(t, err, wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
+	})
+}
+
+// testTableGrowSet ensures functions stored in a table via api.Table can be
+// called by the guest with call_indirect.
+func testTableGrowSet(t *testing.T, r wazero.Runtime) {
+	host, err := r.NewHostModuleBuilder("host").
+		NewFunctionBuilder().WithFunc(func() uint32 { return 3 }).Export("three").
+		Instantiate(testCtx)
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, host.Close(testCtx))
+	}()
+
+	v_i32 := wasm.FunctionType{Results: []wasm.ValueType{i32}}
+	constFunc := func(v byte) wasm.Code {
+		return wasm.Code{Body: []byte{wasm.OpcodeI32Const, v, wasm.OpcodeEnd}}
+	}
+	tableMax := uint32(4)
+	mod := func(name string) api.Module {
+		compiled, err := r.CompileModule(testCtx, binaryencoding.EncodeModule(&wasm.Module{
+			TypeSection:     []wasm.FunctionType{v_i32, {Params: []wasm.ValueType{i32}, Results: []wasm.ValueType{i32}}},
+			FunctionSection: []wasm.Index{0, 0, 1},
+			CodeSection: []wasm.Code{
+				constFunc(1),
+				constFunc(2),
+				{Body: []byte{wasm.OpcodeLocalGet, 0, wasm.OpcodeCallIndirect, 0, 0, wasm.OpcodeEnd}},
+			},
+			TableSection: []wasm.Table{{Min: 1, Max: &tableMax, Type: wasm.RefTypeFuncref}},
+			ElementSection: []wasm.ElementSegment{{
+				OffsetExpr: wasm.ConstantExpression{Opcode: wasm.OpcodeI32Const, Data: []byte{0}},
+				Init:       []wasm.Index{0},
+				Type:       wasm.RefTypeFuncref,
+			}},
+			ExportSection: []wasm.Export{
+				{Name: "one", Type: wasm.ExternTypeFunc, Index: 0},
+				{Name: "two", Type: wasm.ExternTypeFunc, Index: 1},
+				{Name: "dispatch", Type: wasm.ExternTypeFunc, Index: 2},
+				{Name: "table", Type: wasm.ExternTypeTable, Index: 0},
+			},
+		}))
+		require.NoError(t, err)
+		m, err := r.InstantiateModule(testCtx, compiled, wazero.NewModuleConfig().WithName(name))
+		require.NoError(t, err)
+		return m
+	}
+	guest, other := mod("guest"), mod("other")
+	defer func() {
+		require.NoError(t, guest.Close(testCtx))
+		require.NoError(t, other.Close(testCtx))
+	}()
+
+	require.Nil(t, guest.ExportedTable("dispatch"))
+	table := guest.ExportedTable("table")
+	require.NotNil(t, table)
+	require.Equal(t, uint32(1), table.Size())
+
+	dispatch := func(idx uint64) uint64 {
+		res, err := guest.ExportedFunction("dispatch").Call(testCtx, idx)
+		require.NoError(t, err)
+		return res[0]
+	}
+	require.Equal(t, uint64(1), dispatch(0))
+
+	// Grow with a function from the same module, another module and a host module.
+	for i, fn := range []api.Function{
+		guest.ExportedFunction("two"),
+		other.ExportedFunction("one"),
+		host.ExportedFunction("three"),
+	} {
+		size, ok := table.Grow(1, api.FuncRef(fn))
+		require.True(t, ok)
+		require.Equal(t, uint32(i+1), size)
+	}
+	require.Equal(t, uint64(2), dispatch(1))
+	require.Equal(t, uint64(1), dispatch(2))
+	require.Equal(t, uint64(3), dispatch(3))
+
+	// Beyond the max.
+	size, ok := table.Grow(1, api.Reference{})
+	require.False(t, ok)
+	require.Equal(t, tableMax, size)
+
+	// Replace an existing entry.
+	require.True(t, table.Set(0, api.FuncRef(other.ExportedFunction("two"))))
+	require.Equal(t, uint64(2), dispatch(0))
+
+	// Null entries trap on call.
+	require.True(t, table.Set(0, api.Reference{}))
+	_, err = guest.ExportedFunction("dispatch").Call(testCtx, 0)
+	require.ErrorIs(t, err, wasmruntime.ErrRuntimeInvalidTableAccess)
+
+	// Invalid references.
+	require.False(t, table.Set(0, api.ExternRef(1)))
+	require.False(t, table.Set(tableMax, api.Reference{}))
+}
+
+// testTableEntries ensures api.Table Entries resolves function references to
+// the module defining them, including through imports.
+func testTableEntries(t *testing.T, r wazero.Runtime) {
+	host, err := r.NewHostModuleBuilder("host").
+		NewFunctionBuilder().WithFunc(func() uint32 { return 3 }).Export("three").
+		Instantiate(testCtx)
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, host.Close(testCtx))
+	}()
+
+	v_i32 := wasm.FunctionType{Results: []wasm.ValueType{i32}}
+	other, err := r.Instantiate(testCtx, binaryencoding.EncodeModule(&wasm.Module{
+		TypeSection:     []wasm.FunctionType{v_i32},
+		FunctionSection: []wasm.Index{0, 0},
+		CodeSection: []wasm.Code{
+			{Body: []byte{wasm.OpcodeI32Const, 1, wasm.OpcodeEnd}},
+			{Body: []byte{wasm.OpcodeI32Const, 2, wasm.OpcodeEnd}},
+		},
+		ExportSection: []wasm.Export{{Name: "two", Type: wasm.ExternTypeFunc, Index: 1}},
+	}))
+	require.NoError(t, err)
+
+	guest, err := r.InstantiateWithConfig(testCtx, binaryencoding.EncodeModule(&wasm.Module{
+		TypeSection:     []wasm.FunctionType{v_i32},
+		ImportSection:   []wasm.Import{{Module: "host", Name: "three", Type: wasm.ExternTypeFunc, DescFunc: 0}},
+		FunctionSection: []wasm.Index{0},
+		CodeSection:     []wasm.Code{{Body: []byte{wasm.OpcodeI32Const, 1, wasm.OpcodeEnd}}},
+		TableSection: []wasm.Table{
+			{Min: 4, Type: wasm.RefTypeFuncref},
+			{Min: 2, Type: wasm.RefTypeExternref},
+		},
+		ElementSection: []wasm.ElementSegment{{
+			OffsetExpr: wasm.ConstantExpression{Opcode: wasm.OpcodeI32Const, Data: []byte{0}},
+			Init:       []wasm.Index{1, 0},
+			Type:       wasm.RefTypeFuncref,
+		}},
+		ExportSection: []wasm.Export{
+			{Name: "table", Type: wasm.ExternTypeTable, Index: 0},
+			{Name: "externs", Type: wasm.ExternTypeTable, Index: 1},
+		},
+	}), wazero.NewModuleConfig().WithName("guest"))
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, guest.Close(testCtx))
+	}()
+
+	table := guest.ExportedTable("table")
+	require.True(t, table.Set(2, api.FuncRef(other.ExportedFunction("two"))))
+	require.Equal(t, []api.TableEntry{
+		{Kind: api.TableEntryFunction, ModuleName: "guest", FunctionIndex: 1},
+		// The imported function is resolved to its host module.
+		{Kind: api.TableEntryFunction, ModuleName: "host", FunctionIndex: 0, Host: true},
+		{Kind: api.TableEntryFunction, ModuleName: other.Name(), FunctionIndex: 1},
+		{Kind: api.TableEntryNull},
+	}, table.Entries())
+
+	// A function of a closed module can't be resolved anymore.
+	require.NoError(t, other.Close(testCtx))
+	require.Equal(t, api.TableEntryUnresolved, table.Entries()[2].Kind)
+
+	externs := guest.ExportedTable("externs")
+	require.True(t, externs.Set(1, api.ExternRef(0xbeef)))
+	require.Equal(t, []api.TableEntry{
+		{Kind: api.TableEntryNull},
+		{Kind: api.TableEntryExternref, Externref: 0xbeef},
+	}, externs.Entries())
+}
+
+func testCallIndirect(t *testing.T, r wazero.Runtime) {
+	host, err := r.NewHostModuleBuilder("host").
+		NewFunctionBuilder().WithFunc(func(x uint32) uint32 { return x * 3 }).Export("triple").
+		Instantiate(testCtx)
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, host.Close(testCtx))
+	}()
+
+	i32_i32 := wasm.FunctionType{Params: []wasm.ValueType{i32}, Results: []wasm.ValueType{i32}}
+	v_v := wasm.FunctionType{}
+	guest, err := r.Instantiate(testCtx, binaryencoding.EncodeModule(&wasm.Module{
+		TypeSection:     []wasm.FunctionType{i32_i32, v_v},
+		ImportSection:   []wasm.Import{{Module: "host", Name: "triple", Type: wasm.ExternTypeFunc, DescFunc: 0}},
+		FunctionSection: []wasm.Index{0},
+		CodeSection: []wasm.Code{{Body: []byte{
+			wasm.OpcodeLocalGet, 0, wasm.OpcodeI32Const, 2, wasm.OpcodeI32Mul, wasm.OpcodeEnd,
+		}}},
+		TableSection: []wasm.Table{
+			{Min: 3, Type: wasm.RefTypeFuncref},
+			{Min: 1, Type: wasm.RefTypeExternref},
+		},
+		ElementSection: []wasm.ElementSegment{{
+			OffsetExpr: wasm.ConstantExpression{Opcode: wasm.OpcodeI32Const, Data: []byte{0}},
+			Init:       []wasm.Index{1, 0},
+			Type:       wasm.RefTypeFuncref,
+		}},
+	}))
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, guest.Close(testCtx))
+	}()
+
+	results, err := guest.CallIndirect(testCtx, 0, 0, 0, 21)
+	require.NoError(t, err)
+	require.Equal(t, []uint64{42}, results)
+
+	results, err = guest.CallIndirect(testCtx, 0, 1, 0, 14)
+	require.NoError(t, err)
+	require.Equal(t, []uint64{42}, results)
+
+	// Traps are the same as of call_indirect in the guest.
+	_, err = guest.CallIndirect(testCtx, 0, 2, 0, 1)
+	require.ErrorIs(t, err, wasmruntime.ErrRuntimeInvalidTableAccess)
+	_, err = guest.CallIndirect(testCtx, 0, 3, 0, 1)
+	require.ErrorIs(t, err, wasmruntime.ErrRuntimeInvalidTableAccess)
+	_, err = guest.CallIndirect(testCtx, 0, 0, 1)
+	require.ErrorIs(t, err, wasmruntime.ErrRuntimeIndirectCallTypeMismatch)
+
+	// Indices which wouldn't validate in the guest are errors.
+	_, err = guest.CallIndirect(testCtx, 1, 0, 0, 1)
+	require.EqualError(t, err, "table[1] has type externref, but call_indirect requires funcref")
+	_, err = guest.CallIndirect(testCtx, 2, 0, 0, 1)
+	require.EqualError(t, err, "table[2] out of range")
+	_, err = guest.CallIndirect(testCtx, 0, 0, 2, 1)
+	require.EqualError(t, err, "type[2] out of range")
+}
+
+// testTailCall ensures return_call and return_call_indirect don't grow the
+// call stack, including when the callee has more parameters than the caller.
+func testMultiMemory(t *testing.T, r wazero.Runtime) {
+	i32i32i32_v := wasm.FunctionType{Params: []wasm.ValueType{i32, i32, i32}}
+	i32_i32 := wasm.FunctionType{Params: []wasm.ValueType{i32}, Results: []wasm.ValueType{i32}}
+	i32i32_v := wasm.FunctionType{Params: []wasm.ValueType{i32, i32}}
+	v_i32 := wasm.FunctionType{Results: []wasm.ValueType{i32}}
+	threeParams := []byte{wasm.OpcodeLocalGet, 0, wasm.OpcodeLocalGet, 1, wasm.OpcodeLocalGet, 2}
+	dataCount := uint32(2)
+
+	compiled, err := r.CompileModule(testCtx, binaryencoding.EncodeModule(&wasm.Module{
+		TypeSection:     []wasm.FunctionType{i32i32i32_v, i32_i32, i32i32_v, v_i32},
+		FunctionSection: []wasm.Index{0, 0, 0, 1, 2, 3, 1},
+		CodeSection: []wasm.Code{
+			// memory.copy from the memory 1 into the memory 0.
+			{Body: append(threeParams, wasm.OpcodeMiscPrefix, wasm.OpcodeMiscMemoryCopy, 0, 1, wasm.OpcodeEnd)},
+			{Body: append(threeParams, wasm.OpcodeMiscPrefix, wasm.OpcodeMiscMemoryFill, 1, wasm.OpcodeEnd)},
+			{Body: append(threeParams, wasm.OpcodeMiscPrefix, wasm.OpcodeMiscMemoryInit, 1, 1, wasm.OpcodeEnd)},
+			// The alignment is flagged with wasm.MemArgMemoryIndexFlag, followed by the memory index and the offset.
+			{Body: []byte{wasm.OpcodeLocalGet, 0, wasm.OpcodeI32Load8U, wasm.MemArgMemoryIndexFlag, 1, 0, wasm.OpcodeEnd}},
+			{Body: []byte{wasm.OpcodeLocalGet, 0, wasm.OpcodeLocalGet, 1, wasm.OpcodeI32Store8, wasm.MemArgMemoryIndexFlag, 1, 0, wasm.OpcodeEnd}},
+			{Body: []byte{wasm.OpcodeMemorySize, 1, wasm.OpcodeEnd}},
+			{Body: []byte{wasm.OpcodeLocalGet, 0, wasm.OpcodeMemoryGrow, 1, wasm.OpcodeEnd}},
+		},
+		MemorySection:           &wasm.Memory{Min: 1, Cap: 1, Max: 2, IsMaxEncoded: true},
+		AdditionalMemorySection: []wasm.Memory{{Min: 1, Cap: 1, Max: 2, IsMaxEncoded: true}},
+		DataSection: []wasm.DataSegment{
+			{OffsetExpression: wasm.ConstantExpression{Opcode: wasm.OpcodeI32Const, Data: []byte{0}}, Init: []byte("hello"), MemoryIndex: 1},
+			{Passive: true, Init: []byte("world")},
+		},
+		DataCountSection: &dataCount,
+		ExportSection: []wasm.Export{
+			{Name: "copy", Type: wasm.ExternTypeFunc, Index: 0},
+			{Name: "fill", Type: wasm.ExternTypeFunc, Index: 1},
+			{Name: "init", Type: wasm.ExternTypeFunc, Index: 2},
+			{Name: "load", Type: wasm.ExternTypeFunc, Index: 3},
+			{Name: "store", Type: wasm.ExternTypeFunc, Index: 4},
+			{Name: "size", Type: wasm.ExternTypeFunc, Index: 5},
+			{Name: "grow", Type: wasm.ExternTypeFunc, Index: 6},
+			{Name: "mem0", Type: wasm.ExternTypeMemory, Index: 0},
+			{Name: "mem1", Type: wasm.ExternTypeMemory, Index: 1},
+		},
+	}))
+	require.NoError(t, err)
+
+	mod, err := r.InstantiateModule(testCtx, compiled, wazero.NewModuleConfig())
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, mod.Close(testCtx))
+	}()
+
+	mem0, mem1 := mod.ExportedMemory("mem0"), mod.ExportedMemory("mem1")
+	require.Equal(t, mod.Memory(), mem0)
+	require.Equal(t, 2, len(mod.ExportedMemoryDefinitions()))
+
+	// The active data segment was applied to the memory 1 only.
+	buf, ok := mem1.Read(0, 5)
+	require.True(t, ok)
+	require.Equal(t, "hello", string(buf))
+	buf, ok = mem0.Read(0, 5)
+	require.True(t, ok)
+	require.Equal(t, make([]byte, 5), buf)
+
+	t.Run("copy", func(t *testing.T) {
+		_, err := mod.ExportedFunction("copy").Call(testCtx, 10, 0, 5)
+		require.NoError(t, err)
+		buf, ok := mem0.Read(10, 5)
+		require.True(t, ok)
+		require.Equal(t, "hello", string(buf))
+
+		// The source is bounds checked against the memory 1, not the memory 0.
+		_, err = mod.ExportedFunction("copy").Call(testCtx, 0, uint64(mem1.Size()-2), 5)
+		require.ErrorIs(t, err, wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
+	})
+
+	t.Run("load and store", func(t *testing.T) {
+		_, err := mod.ExportedFunction("store").Call(testCtx, 100, 'x')
+		require.NoError(t, err)
+		v, ok := mem1.ReadByte(100)
+		require.True(t, ok)
+		require.Equal(t, byte('x'), v)
+		v, ok = mem0.ReadByte(100)
+		require.True(t, ok)
+		require.Equal(t, byte(0), v)
+
+		res, err := mod.ExportedFunction("load").Call(testCtx, 1)
+		require.NoError(t, err)
+		require.Equal(t, uint64('e'), res[0])
+	})
+
+	t.Run("fill and init", func(t *testing.T) {
+		_, err := mod.ExportedFunction("fill").Call(testCtx, 200, 'z', 3)
+		require.NoError(t, err)
+		_, err = mod.ExportedFunction("init").Call(testCtx, 203, 0, 5)
+		require.NoError(t, err)
+		buf, ok := mem1.Read(200, 8)
+		require.True(t, ok)
+		require.Equal(t, "zzzworld", string(buf))
+		buf, ok = mem0.Read(200, 8)
+		require.True(t, ok)
+		require.Equal(t, make([]byte, 8), buf)
+	})
+
+	t.Run("size and grow", func(t *testing.T) {
+		res, err := mod.ExportedFunction("grow").Call(testCtx, 1)
+		require.NoError(t, err)
+		require.Equal(t, uint64(1), res[0])
+		res, err = mod.ExportedFunction("size").Call(testCtx)
+		require.NoError(t, err)
+		require.Equal(t, uint64(2), res[0])
+		require.Equal(t, uint32(wasm.MemoryPageSize), mem0.Size())
+		require.Equal(t, uint32(2*wasm.MemoryPageSize), mem1.Size())
+	})
+}
+
+// testFloatLoadStoreBits ensures plain loads and stores of floats preserve the exact bit pattern, including the
+// payload of NaNs and signaling NaNs, as they must not be canonicalized.
+// testRelaxedSIMD ensures the relaxed-SIMD instructions have the results documented on
+// experimental.CoreFeaturesRelaxedSIMD, which are the same in every engine and on every call.
+func testRelaxedSIMD(t *testing.T, r wazero.Runtime) {
+	f32x4 := func(l0, l1, l2, l3 float32) []uint64 {
+		return []uint64{
+			uint64(math.Float32bits(l1))<<32 | uint64(math.Float32bits(l0)),
+			uint64(math.Float32bits(l3))<<32 | uint64(math.Float32bits(l2)),
+		}
+	}
+	f64x2 := func(l0, l1 float64) []uint64 {
+		return []uint64{math.Float64bits(l0), math.Float64bits(l1)}
+	}
+	i32x4 := func(l0, l1, l2, l3 int32) []uint64 {
+		return []uint64{uint64(uint32(l1))<<32 | uint64(uint32(l0)), uint64(uint32(l3))<<32 | uint64(uint32(l2))}
+	}
+	// The product of 1+2^-12 and itself rounds to 1+2^-11 unless fused with the addition.
+	f32Inexact, f64Inexact := float32(1+1.0/(1<<12)), 1+1.0/(1<<30)
+
+	tests := []struct {
+		op       wasm.OpcodeVecRelaxed
+		params   [][]uint64
+		expected []uint64
+	}{
+		{
+			op:       wasm.OpcodeVecRelaxedI8x16Swizzle,
+			params:   [][]uint64{{0x0706050403020100, 0x0f0e0d0c0b0a0908}, {0x030201ff000f1080, 0x0505050505050505}},
+			expected: []uint64{0x03020100000f0000, 0x0505050505050505}, // out of range indices select 0.
+		},
+		{
+			op:       wasm.OpcodeVecRelaxedI32x4TruncF32x4S,
+			params:   [][]uint64{f32x4(float32(math.NaN()), 3e9, -2.5, 1.9)},
+			expected: i32x4(0, math.MaxInt32, -2, 1),
+		},
+		{
+			op:       wasm.OpcodeVecRelaxedI32x4TruncF32x4U,
+			params:   [][]uint64{f32x4(float32(math.NaN()), 5e9, -2.5, 3e9)},
+			expected: i32x4(0, -1, 0, -1294967296), // 3e9 as uint32
+		},
+		{
+			op:       wasm.OpcodeVecRelaxedI32x4TruncF64x2SZero,
+			params:   [][]uint64{f64x2(-1e20, 2.7)},
+			expected: i32x4(math.MinInt32, 2, 0, 0),
+		},
+		{
+			op:       wasm.OpcodeVecRelaxedI32x4TruncF64x2UZero,
+			params:   [][]uint64{f64x2(math.NaN(), 1e20)},
+			expected: i32x4(0, -1, 0, 0),
+		},
+		{
+			op: wasm.OpcodeVecRelaxedF32x4Madd,
+			params: [][]uint64{
+				f32x4(f32Inexact, 2, -1.5, 0),
+				f32x4(f32Inexact, 3, 2, 5),
+				f32x4(-1, 4, 0.5, float32(math.Copysign(0, -1))),
+			},
+			expected: f32x4(1.0/(1<<11), 10, -2.5, 0),
+		},
+		{
+			op: wasm.OpcodeVecRelaxedF32x4Nmadd,
+			params: [][]uint64{
+				f32x4(f32Inexact, 2, -1.5, 0),
+				f32x4(f32Inexact, 3, 2, 5),
+				f32x4(1, 4, 0.5, float32(math.Copysign(0, -1))),
+			},
+			expected: f32x4(-1.0/(1<<11), -2, 3.5, float32(math.Copysign(0, -1))),
+		},
+		{
+			op:       wasm.OpcodeVecRelaxedF64x2Madd,
+			params:   [][]uint64{f64x2(f64Inexact, 2), f64x2(f64Inexact, 3), f64x2(-1, 4)},
+			expected: f64x2(1.0/(1<<29), 10),
+		},
+		{
+			op:       wasm.OpcodeVecRelaxedF64x2Nmadd,
+			params:   [][]uint64{f64x2(f64Inexact, 2), f64x2(f64Inexact, 3), f64x2(1, 4)},
+			expected: f64x2(-1.0/(1<<29), -2),
+		},
+		{
+			op: wasm.OpcodeVecRelaxedF32x4Min,
+			params: [][]uint64{
+				f32x4(float32(math.Copysign(0, -1)), 0, 1, float32(math.Inf(-1))),
+				f32x4(0, float32(math.Copysign(0, -1)), 2, 3),
+			},
+			expected: f32x4(float32(math.Copysign(0, -1)), float32(math.Copysign(0, -1)), 1, float32(math.Inf(-1))),
+		},
+		{
+			op: wasm.OpcodeVecRelaxedF32x4Max,
+			params: [][]uint64{
+				f32x4(float32(math.Copysign(0, -1)), 0, 1, float32(math.Inf(-1))),
+				f32x4(0, float32(math.Copysign(0, -1)), 2, 3),
+			},
+			expected: f32x4(0, 0, 2, 3),
+		},
+		{
+			op:       wasm.OpcodeVecRelaxedF64x2Min,
+			params:   [][]uint64{f64x2(math.Copysign(0, -1), 5), f64x2(0, -1)},
+			expected: f64x2(math.Copysign(0, -1), -1),
+		},
+		{
+			op:       wasm.OpcodeVecRelaxedF64x2Max,
+			params:   [][]uint64{f64x2(math.Copysign(0, -1), 5), f64x2(0, -1)},
+			expected: f64x2(0, 5),
+		},
+		{
+			op:       wasm.OpcodeVecRelaxedI16x8Q15mulrS,
+			params:   [][]uint64{{0xffff_7fff_4000_8000, 0}, {0x0001_7fff_4000_8000, 0}},
+			expected: []uint64{0x0000_7ffe_2000_7fff, 0}, // -1 * -1 saturates.
+		},
+		{
+			op:       wasm.OpcodeVecRelaxedI16x8DotI8x16I7x16S,
+			params:   [][]uint64{{0x04fd02017f7f8080, 0}, {0xf80706057f7f8080, 0}},
+			expected: []uint64{0xffcb_0011_7e02_7fff, 0}, // -128*-128*2 saturates.
+		},
+		{
+			op:       wasm.OpcodeVecRelaxedI32x4DotI8x16I7x16AddS,
+			params:   [][]uint64{{0x04fd02017f7f8080, 0}, {0xf80706057f7f8080, 0}, i32x4(1, 2, 3, 4)},
+			expected: i32x4(0x7fff+0x7e02+1, 0x11-0x35+2, 3, 4),
+		},
+	}
+	for _, lanes := range []wasm.OpcodeVecRelaxed{
+		wasm.OpcodeVecRelaxedI8x16Laneselect, wasm.OpcodeVecRelaxedI16x8Laneselect,
+		wasm.OpcodeVecRelaxedI32x4Laneselect, wasm.OpcodeVecRelaxedI64x2Laneselect,
+	} {
+		// Each bit is selected by the mask, regardless of the lane shape.
+		tests = append(tests, struct {
+			op       wasm.OpcodeVecRelaxed
+			params   [][]uint64
+			expected []uint64
+		}{
+			op: lanes,
+			params: [][]uint64{
+				{0x1111111111111111, 0x1111111111111111},
+				{0x2222222222222222, 0x2222222222222222},
+				{0xff00ff00ff00ff00, 0x0f0f0f0f0f0f0f0f},
+			},
+			expected: []uint64{0x1122112211221122, 0x2121212121212121},
+		})
+	}
+
+	// Each function applies the instruction to its parameters.
+	m := &wasm.Module{}
+	for i, tc := range tests {
+		ft := wasm.FunctionType{Results: []wasm.ValueType{v128}}
+		var body []byte
+		for j := range tc.params {
+			ft.Params = append(ft.Params, v128)
+			body = append(body, wasm.OpcodeLocalGet, byte(j))
+		}
+		body = append(body, wasm.OpcodeVecPrefix, 0x80|tc.op, 0x02, wasm.OpcodeEnd)
+		m.TypeSection = append(m.TypeSection, ft)
+		m.FunctionSection = append(m.FunctionSection, wasm.Index(i))
+		m.CodeSection = append(m.CodeSection, wasm.Code{Body: body})
+		m.ExportSection = append(m.ExportSection, wasm.Export{
+			Name: wasm.VecRelaxedInstructionName(tc.op), Type: wasm.ExternTypeFunc, Index: wasm.Index(i),
+		})
+	}
+	mod, err := r.Instantiate(testCtx, binaryencoding.EncodeModule(m))
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, mod.Close(testCtx))
+	}()
+
+	for _, tt := range tests {
+		tc := tt
+		name := wasm.VecRelaxedInstructionName(tc.op)
+		t.Run(name, func(t *testing.T) {
+			var params []uint64
+			for _, p := range tc.params {
+				params = append(params, p...)
+			}
+			// Call more than once to ensure the results are stable.
+			for i := 0; i < 3; i++ {
+				results, err := mod.ExportedFunction(name).Call(testCtx, params...)
+				require.NoError(t, err)
+				require.Equal(t, tc.expected, results)
+			}
+		})
+	}
+}
+
+// testMemory64 ensures a 64-bit memory declared with a max over 4GiB grows up to the limit of the runtime, and that
+// addresses which don't fit in 32 bits are out of bounds.
+func testMemory64(t *testing.T, r wazero.Runtime) {
+	i64_i32 := wasm.FunctionType{Params: []wasm.ValueType{i64}, Results: []wasm.ValueType{i32}}
+	i64i32_v := wasm.FunctionType{Params: []wasm.ValueType{i64, i32}}
+	v_i64 := wasm.FunctionType{Results: []wasm.ValueType{i64}}
+	i64_i64 := wasm.FunctionType{Params: []wasm.ValueType{i64}, Results: []wasm.ValueType{i64}}
+	i64i32i64_v := wasm.FunctionType{Params: []wasm.ValueType{i64, i32, i64}}
+	// The offset of load_far is 4GiB, which doesn't fit in 32 bits.
+	farOffset := leb128.EncodeUint64(1 << 32)
+
+	bin := binaryencoding.EncodeModule(&wasm.Module{
+		TypeSection:     []wasm.FunctionType{i64_i32, i64i32_v, v_i64, i64_i64, i64i32i64_v},
+		FunctionSection: []wasm.Index{0, 1, 2, 3, 4, 0},
+		CodeSection: []wasm.Code{
+			{Body: []byte{wasm.OpcodeLocalGet, 0, wasm.OpcodeI32Load8U, 0, 0, wasm.OpcodeEnd}},
+			{Body: []byte{wasm.OpcodeLocalGet, 0, wasm.OpcodeLocalGet, 1, wasm.OpcodeI32Store8, 0, 0, wasm.OpcodeEnd}},
+			{Body: []byte{wasm.OpcodeMemorySize, 0, wasm.OpcodeEnd}},
+			{Body: []byte{wasm.OpcodeLocalGet, 0, wasm.OpcodeMemoryGrow, 0, wasm.OpcodeEnd}},
+			{Body: []byte{
+				wasm.OpcodeLocalGet, 0, wasm.OpcodeLocalGet, 1, wasm.OpcodeLocalGet, 2,
+				wasm.OpcodeMiscPrefix, wasm.OpcodeMiscMemoryFill, 0, wasm.OpcodeEnd,
+			}},
+			{Body: append(append([]byte{wasm.OpcodeLocalGet, 0, wasm.OpcodeI32Load8U, 0}, farOffset...), wasm.OpcodeEnd)},
+		},
+		MemorySection: &wasm.Memory{Min: 1, Max: 2, IsMaxEncoded: true, Is64: true},
+		ExportSection: []wasm.Export{
+			{Name: "load", Type: wasm.ExternTypeFunc, Index: 0},
+			{Name: "store", Type: wasm.ExternTypeFunc, Index: 1},
+			{Name: "size", Type: wasm.ExternTypeFunc, Index: 2},
+			{Name: "grow", Type: wasm.ExternTypeFunc, Index: 3},
+			{Name: "fill", Type: wasm.ExternTypeFunc, Index: 4},
+			{Name: "load_far", Type: wasm.ExternTypeFunc, Index: 5},
+		},
+	})
+	// wasm.Memory cannot hold a max over 4GiB, so replace the encoded max of 2 pages with 2^20 pages (64GiB).
+	maxPages := leb128.EncodeUint64(1 << 20)
+	bin = bytes.Replace(bin,
+		[]byte{wasm.SectionIDMemory, 4, 1, 0x05, 1, 2},
+		append([]byte{wasm.SectionIDMemory, byte(3 + len(maxPages)), 1, 0x05, 1}, maxPages...), 1)
+
+	mod, err := r.Instantiate(testCtx, bin)
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, mod.Close(testCtx))
+	}()
+
+	size, grow := mod.ExportedFunction("size"), mod.ExportedFunction("grow")
+	load, store := mod.ExportedFunction("load"), mod.ExportedFunction("store")
+
+	t.Run("grow within the limit", func(t *testing.T) {
+		res, err := size.Call(testCtx)
+		require.NoError(t, err)
+		require.Equal(t, uint64(1), res[0])
+
+		res, err = grow.Call(testCtx, 1)
+		require.NoError(t, err)
+		require.Equal(t, uint64(1), res[0])
+
+		res, err = size.Call(testCtx)
+		require.NoError(t, err)
+		require.Equal(t, uint64(2), res[0])
+
+		// The max is valid, but over the limit of the runtime which is 4GiB at most.
+		for _, delta := range []uint64{uint64(wasm.MemoryLimitPages) + 1, 1 << 20, 1 << 32, math.MaxUint64} {
+			res, err = grow.Call(testCtx, delta)
+			require.NoError(t, err)
+			require.Equal(t, uint64(math.MaxUint64), res[0], delta) // -1 as i64
+		}
+	})
+
+	t.Run("load and store", func(t *testing.T) {
+		addr := uint64(wasm.MemoryPageSize + 5) // in the grown page.
+		_, err := store.Call(testCtx, addr, 'x')
+		require.NoError(t, err)
+		res, err := load.Call(testCtx, addr)
+		require.NoError(t, err)
+		require.Equal(t, uint64('x'), res[0])
+		v, ok := mod.Memory().ReadByte(uint32(addr))
+		require.True(t, ok)
+		require.Equal(t, byte('x'), v)
+
+		_, err = mod.ExportedFunction("fill").Call(testCtx, 0, 'y', 3)
+		require.NoError(t, err)
+		buf, ok := mod.Memory().Read(0, 4)
+		require.True(t, ok)
+		require.Equal(t, []byte("yyy\x00"), buf)
+	})
+
+	t.Run("out of bounds", func(t *testing.T) {
+		// The upper 32 bits are never ignored.
+		for _, addr := range []uint64{1 << 32, 1<<32 + 5, math.MaxUint64} {
+			_, err := load.Call(testCtx, addr)
+			require.ErrorIs(t, err, wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess, addr)
+			_, err = store.Call(testCtx, addr, 0)
+			require.ErrorIs(t, err, wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess, addr)
+		}
+		_, err := mod.ExportedFunction("load_far").Call(testCtx, 0)
+		require.ErrorIs(t, err, wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
+		_, err = mod.ExportedFunction("fill").Call(testCtx, 0, 0, 1<<32)
+		require.ErrorIs(t, err, wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
+	})
+}
+
+// testSharedMemoryGrow ensures memory.grow doesn't move a shared memory, as other threads could be accessing it.
+func testSharedMemoryGrow(t *testing.T, r wazero.Runtime) {
+	bin := binaryencoding.EncodeModule(&wasm.Module{
+		TypeSection:     []wasm.FunctionType{{Params: []wasm.ValueType{i32}, Results: []wasm.ValueType{i32}}},
+		FunctionSection: []wasm.Index{0},
+		CodeSection: []wasm.Code{{Body: []byte{
+			wasm.OpcodeLocalGet, 0, wasm.OpcodeMemoryGrow, 0, wasm.OpcodeEnd,
+		}}},
+		MemorySection: &wasm.Memory{Min: 1, Cap: 1, Max: 4, IsMaxEncoded: true, IsShared: true},
+		ExportSection: []wasm.Export{
+			{Name: "grow", Type: wasm.ExternTypeFunc, Index: 0},
+			{Name: "memory", Type: wasm.ExternTypeMemory, Index: 0},
+		},
+	})
+	mod, err := r.Instantiate(testCtx, bin)
+	require.NoError(t, err)
+	defer mod.Close(testCtx)
+
+	mem := mod.ExportedMemory("memory")
+	before, ok := mem.Read(0, 1)
+	require.True(t, ok)
+
+	grow := mod.ExportedFunction("grow")
+	res, err := grow.Call(testCtx, 3)
+	require.NoError(t, err)
+	require.Equal(t, []uint64{1}, res)
+	require.Equal(t, uint32(4*65536), mem.Size())
+
+	after, ok := mem.Read(0, 1)
+	require.True(t, ok)
+	require.Equal(t, &before[0], &after[0])
+
+	// Growing past the max fails.
+	res, err = grow.Call(testCtx, 1)
+	require.NoError(t, err)
+	require.Equal(t, []uint64{0xffffffff}, res)
+}
+
+// testAtomic ensures the atomic instructions have the single-threaded semantics documented on
+// experimental.CoreFeaturesThreads, including the trap on an unaligned effective address.
+func testAtomic(t *testing.T, r wazero.Runtime) {
+	tests := []struct {
+		name        string
+		op          wasm.OpcodeAtomic
+		offset      byte
+		memory      []byte // at address zero before the call
+		params      []uint64
+		expected    []uint64
+		expectedMem []byte // at address zero after the call, or the same as memory
+		expectedErr error
+	}{
+		{
+			name:     "load",
+			op:       wasm.OpcodeAtomicI64Load,
+			memory:   []byte{1, 2, 3, 4, 5, 6, 7, 8},
+			params:   []uint64{0},
+			expected: []uint64{0x0807060504030201},
+		},
+		{
+			name:     "load aligned by offset",
+			op:       wasm.OpcodeAtomicI32Load16U,
+			offset:   1,
+			memory:   []byte{0, 0, 0xff, 0xff},
+			params:   []uint64{1},
+			expected: []uint64{0xffff},
+		},
+		{
+			name:        "load unaligned",
+			op:          wasm.OpcodeAtomicI32Load,
+			params:      []uint64{2},
+			expectedErr: wasmruntime.ErrRuntimeUnalignedAtomic,
+		},
+		{
+			name:        "store unaligned by offset",
+			op:          wasm.OpcodeAtomicI64Store,
+			offset:      4,
+			params:      []uint64{0, 1},
+			expectedErr: wasmruntime.ErrRuntimeUnalignedAtomic,
+		},
+		{
+			name:        "rmw unaligned",
+			op:          wasm.OpcodeAtomicI32Rmw16AndU,
+			params:      []uint64{1, 0},
+			expectedErr: wasmruntime.ErrRuntimeUnalignedAtomic,
+		},
+		{
+			name:        "out of bounds",
+			op:          wasm.OpcodeAtomicI32Load,
+			params:      []uint64{uint64(wasm.MemoryPageSize)},
+			expectedErr: wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess,
+		},
+		{
+			name:        "store8 truncates",
+			op:          wasm.OpcodeAtomicI32Store8,
+			memory:      []byte{0, 0xaa},
+			params:      []uint64{0, 0x1234},
+			expectedMem: []byte{0x34, 0xaa},
+		},
+		{
+			name:        "rmw8.add_u wraps around",
+			op:          wasm.OpcodeAtomicI32Rmw8AddU,
+			memory:      []byte{0, 0xff, 0xaa},
+			params:      []uint64{1, 2},
+			expected:    []uint64{0xff},
+			expectedMem: []byte{0, 1, 0xaa},
+		},
+		{
+			name:        "rmw.sub wraps around",
+			op:          wasm.OpcodeAtomicI64RmwSub,
+			memory:      []byte{1, 0, 0, 0, 0, 0, 0, 0},
+			params:      []uint64{0, 2},
+			expected:    []uint64{1},
+			expectedMem: []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+		},
+		{
+			name:        "rmw32.xchg_u",
+			op:          wasm.OpcodeAtomicI64Rmw32XchgU,
+			memory:      []byte{0xff, 0xff, 0xff, 0xff, 0xaa},
+			params:      []uint64{0, 0x1_0000_0002},
+			expected:    []uint64{0xffffffff},
+			expectedMem: []byte{2, 0, 0, 0, 0xaa},
+		},
+		{
+			name:        "rmw.cmpxchg hit",
+			op:          wasm.OpcodeAtomicI32RmwCmpxchg,
+			memory:      []byte{5, 0, 0, 0},
+			params:      []uint64{0, 5, 9},
+			expected:    []uint64{5},
+			expectedMem: []byte{9, 0, 0, 0},
+		},
+		{
+			name:     "rmw.cmpxchg miss",
+			op:       wasm.OpcodeAtomicI32RmwCmpxchg,
+			memory:   []byte{5, 0, 0, 0},
+			params:   []uint64{0, 6, 9},
+			expected: []uint64{5},
+		},
+		{
+			// The expected value is wrapped to the size of the access before the comparison.
+			name:        "rmw8.cmpxchg_u hit with high bits",
+			op:          wasm.OpcodeAtomicI64Rmw8CmpxchgU,
+			memory:      []byte{0x12, 0xaa},
+			params:      []uint64{0, 0x7712, 0x34},
+			expected:    []uint64{0x12},
+			expectedMem: []byte{0x34, 0xaa},
+		},
+		{
+			name:     "rmw16.cmpxchg_u miss",
+			op:       wasm.OpcodeAtomicI32Rmw16CmpxchgU,
+			memory:   []byte{0x12, 0x34},
+			params:   []uint64{0, 0x1234, 0},
+			expected: []uint64{0x3412},
+		},
+		{
+			name:     "wait32 not-equal",
+			op:       wasm.OpcodeAtomicMemoryWait32,
+			memory:   []byte{1, 0, 0, 0},
+			params:   []uint64{0, 0, api.EncodeI64(-1)},
+			expected: []uint64{1},
+		},
+		{
+			name:     "wait64 timed-out",
+			op:       wasm.OpcodeAtomicMemoryWait64,
+			memory:   []byte{1, 0, 0, 0, 0, 0, 0, 0},
+			params:   []uint64{0, 1, 0},
+			expected: []uint64{2},
+		},
+		{
+			name:     "notify",
+			op:       wasm.OpcodeAtomicMemoryNotify,
+			params:   []uint64{4, 1},
+			expected: []uint64{0},
+		},
+		{
+			name:        "notify unaligned",
+			op:          wasm.OpcodeAtomicMemoryNotify,
+			params:      []uint64{2, 1},
+			expectedErr: wasmruntime.ErrRuntimeUnalignedAtomic,
+		},
+	}
+
+	// Each function applies the instruction to its parameters.
+	m := &wasm.Module{MemorySection: &wasm.Memory{Min: 1, Cap: 1, Max: 1, IsMaxEncoded: true, IsShared: true}}
+	for i, tc := range tests {
+		var ft wasm.FunctionType
+		var body []byte
+		vt, size := wasm.AtomicMemoryAccess(tc.op)
+		for j := range tc.params {
+			switch {
+			case j == 0:
+				ft.Params = append(ft.Params, i32)
+			case j == 2 && (tc.op == wasm.OpcodeAtomicMemoryWait32 || tc.op == wasm.OpcodeAtomicMemoryWait64):
+				ft.Params = append(ft.Params, i64) // timeout
+			case tc.op == wasm.OpcodeAtomicMemoryNotify:
+				ft.Params = append(ft.Params, i32) // count
+			default:
+				ft.Params = append(ft.Params, vt)
+			}
+			body = append(body, wasm.OpcodeLocalGet, byte(j))
+		}
+		switch {
+		case tc.op == wasm.OpcodeAtomicMemoryNotify || tc.op == wasm.OpcodeAtomicMemoryWait32 ||
+			tc.op == wasm.OpcodeAtomicMemoryWait64:
+			ft.Results = []wasm.ValueType{i32}
+		case tc.op < wasm.OpcodeAtomicI32Store || tc.op >= wasm.OpcodeAtomicI32RmwAdd:
+			ft.Results = []wasm.ValueType{vt}
+		}
+		align := byte(bits.TrailingZeros32(size))
+		body = append(body, wasm.OpcodeAtomicPrefix, tc.op, align, tc.offset, wasm.OpcodeEnd)
+		m.TypeSection = append(m.TypeSection, ft)
+		m.FunctionSection = append(m.FunctionSection, wasm.Index(i))
+		m.CodeSection = append(m.CodeSection, wasm.Code{Body: body})
+		m.ExportSection = append(m.ExportSection, wasm.Export{Name: tc.name, Type: wasm.ExternTypeFunc, Index: wasm.Index(i)})
+	}
+	mod, err := r.Instantiate(testCtx, binaryencoding.EncodeModule(m))
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, mod.Close(testCtx))
+	}()
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			mem := mod.Memory()
+			require.True(t, mem.Write(0, make([]byte, 16)))
+			require.True(t, mem.Write(0, tc.memory))
+
+			results, err := mod.ExportedFunction(tc.name).Call(testCtx, tc.params...)
+			if tc.expectedErr != nil {
+				require.ErrorIs(t, err, tc.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, results)
+
+			expectedMem := tc.expectedMem
+			if expectedMem == nil {
+				expectedMem = tc.memory
+			}
+			actual, ok := mem.Read(0, uint32(len(expectedMem)))
+			require.True(t, ok)
+			require.Equal(t, expectedMem, actual)
+		})
+	}
+}
+
+func testFloatLoadStoreBits(t *testing.T, r wazero.Runtime) {
+	i32i32_v := wasm.FunctionType{Params: []wasm.ValueType{i32, i32}}
+	i32_f32 := wasm.FunctionType{Params: []wasm.ValueType{i32}, Results: []wasm.ValueType{f32}}
+	i32_f64 := wasm.FunctionType{Params: []wasm.ValueType{i32}, Results: []wasm.ValueType{f64}}
+	i32f32_v := wasm.FunctionType{Params: []wasm.ValueType{i32, f32}}
+	i32f64_v := wasm.FunctionType{Params: []wasm.ValueType{i32, f64}}
+	twoParams := []byte{wasm.OpcodeLocalGet, 0, wasm.OpcodeLocalGet, 1}
+
+	mod, err := r.Instantiate(testCtx, binaryencoding.EncodeModule(&wasm.Module{
+		TypeSection:     []wasm.FunctionType{i32i32_v, i32_f32, i32_f64, i32f32_v, i32f64_v},
+		FunctionSection: []wasm.Index{0, 0, 1, 2, 3, 4},
+		CodeSection: []wasm.Code{
+			// Copies the float at the second param to the address at the first param.
+			{Body: append(twoParams, wasm.OpcodeF32Load, 2, 0, wasm.OpcodeF32Store, 2, 0, wasm.OpcodeEnd)},
+			{Body: append(twoParams, wasm.OpcodeF64Load, 3, 0, wasm.OpcodeF64Store, 3, 0, wasm.OpcodeEnd)},
+			{Body: []byte{wasm.OpcodeLocalGet, 0, wasm.OpcodeF32Load, 2, 0, wasm.OpcodeEnd}},
+			{Body: []byte{wasm.OpcodeLocalGet, 0, wasm.OpcodeF64Load, 3, 0, wasm.OpcodeEnd}},
+			{Body: append(twoParams, wasm.OpcodeF32Store, 2, 0, wasm.OpcodeEnd)},
+			{Body: append(twoParams, wasm.OpcodeF64Store, 3, 0, wasm.OpcodeEnd)},
+		},
+		MemorySection: &wasm.Memory{Min: 1, Cap: 1, Max: 1},
+		ExportSection: []wasm.Export{
+			{Name: "copy_f32", Type: wasm.ExternTypeFunc, Index: 0},
+			{Name: "copy_f64", Type: wasm.ExternTypeFunc, Index: 1},
+			{Name: "load_f32", Type: wasm.ExternTypeFunc, Index: 2},
+			{Name: "load_f64", Type: wasm.ExternTypeFunc, Index: 3},
+			{Name: "store_f32", Type: wasm.ExternTypeFunc, Index: 4},
+			{Name: "store_f64", Type: wasm.ExternTypeFunc, Index: 5},
+		},
+	}))
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, mod.Close(testCtx))
+	}()
+	mem := mod.Memory()
+
+	for _, bits := range []uint32{
+		0x7f800001, // signaling NaN with the smallest payload
+		0xffa00005, // negative signaling NaN
+		0x7fc12345, // quiet NaN with a payload
+		0xffffffff, // negative quiet NaN with all payload bits set
+		0x80000001, // negative subnormal
+	} {
+		t.Run(fmt.Sprintf("f32 %#x", bits), func(t *testing.T) {
+			require.True(t, mem.WriteUint32Le(0, bits))
+			_, err := mod.ExportedFunction("copy_f32").Call(testCtx, 8, 0)
+			require.NoError(t, err)
+			actual, ok := mem.ReadUint32Le(8)
+			require.True(t, ok)
+			require.Equal(t, bits, actual)
+
+			res, err := mod.ExportedFunction("load_f32").Call(testCtx, 0)
+			require.NoError(t, err)
+			require.Equal(t, bits, uint32(res[0]))
+
+			_, err = mod.ExportedFunction("store_f32").Call(testCtx, 16, uint64(bits))
+			require.NoError(t, err)
+			actual, ok = mem.ReadUint32Le(16)
+			require.True(t, ok)
+			require.Equal(t, bits, actual)
+		})
+	}
+
+	for _, bits := range []uint64{
+		0x7ff0000000000001, // signaling NaN with the smallest payload
+		0xfff4000000000abc, // negative signaling NaN
+		0x7ff8000012345678, // quiet NaN with a payload
+		0xffffffffffffffff, // negative quiet NaN with all payload bits set
+		0x8000000000000001, // negative subnormal
+	} {
+		t.Run(fmt.Sprintf("f64 %#x", bits), func(t *testing.T) {
+			require.True(t, mem.WriteUint64Le(0, bits))
+			_, err := mod.ExportedFunction("copy_f64").Call(testCtx, 8, 0)
+			require.NoError(t, err)
+			actual, ok := mem.ReadUint64Le(8)
+			require.True(t, ok)
+			require.Equal(t, bits, actual)
+
+			res, err := mod.ExportedFunction("load_f64").Call(testCtx, 0)
+			require.NoError(t, err)
+			require.Equal(t, bits, res[0])
+
+			_, err = mod.ExportedFunction("store_f64").Call(testCtx, 16, bits)
+			require.NoError(t, err)
+			actual, ok = mem.ReadUint64Le(16)
+			require.True(t, ok)
+			require.Equal(t, bits, actual)
+		})
+	}
+}
+
+// testTableInitiallyNull ensures the slots of tables without element segments are null references of the table's
+// element type, and that calling through one traps.
+func testTableInitiallyNull(t *testing.T, r wazero.Runtime) {
+	i32_i32 := wasm.FunctionType{Params: []wasm.ValueType{i32}, Results: []wasm.ValueType{i32}}
+	v_v := wasm.FunctionType{}
+	i32_v := wasm.FunctionType{Params: []wasm.ValueType{i32}}
+
+	mod, err := r.Instantiate(testCtx, binaryencoding.EncodeModule(&wasm.Module{
+		TypeSection:     []wasm.FunctionType{i32_i32, v_v, i32_v},
+		FunctionSection: []wasm.Index{0, 0, 2},
+		CodeSection: []wasm.Code{
+			{Body: []byte{wasm.OpcodeLocalGet, 0, wasm.OpcodeTableGet, 0, wasm.OpcodeRefIsNull, wasm.OpcodeEnd}},
+			{Body: []byte{wasm.OpcodeLocalGet, 0, wasm.OpcodeTableGet, 1, wasm.OpcodeRefIsNull, wasm.OpcodeEnd}},
+			{Body: []byte{wasm.OpcodeLocalGet, 0, wasm.OpcodeCallIndirect, 1, 0, wasm.OpcodeEnd}},
+		},
+		TableSection: []wasm.Table{
+			{Min: 3, Type: wasm.RefTypeFuncref},
+			{Min: 2, Type: wasm.RefTypeExternref},
+		},
+		ExportSection: []wasm.Export{
+			{Name: "is_null_funcref", Type: wasm.ExternTypeFunc, Index: 0},
+			{Name: "is_null_externref", Type: wasm.ExternTypeFunc, Index: 1},
+			{Name: "call", Type: wasm.ExternTypeFunc, Index: 2},
+		},
+	}))
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, mod.Close(testCtx))
+	}()
+
+	for _, tc := range []struct {
+		name string
+		size uint64
+	}{
+		{name: "is_null_funcref", size: 3},
+		{name: "is_null_externref", size: 2},
+	} {
+		for i := uint64(0); i < tc.size; i++ {
+			res, err := mod.ExportedFunction(tc.name).Call(testCtx, i)
+			require.NoError(t, err)
+			require.Equal(t, uint64(1), res[0], "%s(%d)", tc.name, i)
+		}
+	}
+
+	for i := uint64(0); i < 3; i++ {
+		_, err = mod.ExportedFunction("call").Call(testCtx, i)
+		require.ErrorIs(t, err, wasmruntime.ErrRuntimeInvalidTableAccess)
+	}
+}
+
+// testTableBulkOps shuffles two function tables used as vtables with table.init, table.copy and table.fill, and
+// ensures out-of-bounds operations trap before modifying either table.
+func testTableBulkOps(t *testing.T, r wazero.Runtime) {
+	v_i32 := wasm.FunctionType{Results: []wasm.ValueType{i32}}
+	i32i32i32_v := wasm.FunctionType{Params: []wasm.ValueType{i32, i32, i32}}
+	i32_i32 := wasm.FunctionType{Params: []wasm.ValueType{i32}, Results: []wasm.ValueType{i32}}
+
+	bulk := func(op ...byte) wasm.Code {
+		body := []byte{wasm.OpcodeLocalGet, 0, wasm.OpcodeLocalGet, 1, wasm.OpcodeLocalGet, 2, wasm.OpcodeMiscPrefix}
+		return wasm.Code{Body: append(append(body, op...), wasm.OpcodeEnd)}
+	}
+	callIndirect := func(table byte) wasm.Code {
+		return wasm.Code{Body: []byte{wasm.OpcodeLocalGet, 0, wasm.OpcodeCallIndirect, 0, table, wasm.OpcodeEnd}}
+	}
+
+	mod, err := r.Instantiate(testCtx, binaryencoding.EncodeModule(&wasm.Module{
+		TypeSection:     []wasm.FunctionType{v_i32, i32i32i32_v, i32_i32, {}},
+		FunctionSection: []wasm.Index{0, 0, 0, 0, 1, 1, 1, 1, 3, 2, 2},
+		CodeSection: []wasm.Code{
+			// The methods placed in the tables, each returning a distinct value.
+			{Body: []byte{wasm.OpcodeI32Const, 10, wasm.OpcodeEnd}},
+			{Body: []byte{wasm.OpcodeI32Const, 11, wasm.OpcodeEnd}},
+			{Body: []byte{wasm.OpcodeI32Const, 12, wasm.OpcodeEnd}},
+			{Body: []byte{wasm.OpcodeI32Const, 13, wasm.OpcodeEnd}},
+			bulk(wasm.OpcodeMiscTableInit, 0, 0), // segment index, table index
+			bulk(wasm.OpcodeMiscTableCopy, 1, 0), // dst table index, src table index
+			bulk(wasm.OpcodeMiscTableCopy, 0, 0),
+			// fill(dst, src, len) fills table 1 with the element at src in table 0.
+			{Body: []byte{
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeLocalGet, 1, wasm.OpcodeTableGet, 0,
+				wasm.OpcodeLocalGet, 2,
+				wasm.OpcodeMiscPrefix, wasm.OpcodeMiscTableFill, 1,
+				wasm.OpcodeEnd,
+			}},
+			{Body: []byte{wasm.OpcodeMiscPrefix, wasm.OpcodeMiscElemDrop, 0, wasm.OpcodeEnd}},
+			callIndirect(0),
+			callIndirect(1),
+		},
+		TableSection: []wasm.Table{
+			{Min: 4, Type: wasm.RefTypeFuncref},
+			{Min: 4, Type: wasm.RefTypeFuncref},
+		},
+		ElementSection: []wasm.ElementSegment{
+			{Mode: wasm.ElementModePassive, Type: wasm.RefTypeFuncref, Init: []wasm.Index{0, 1, 2, 3}},
+		},
+		ExportSection: []wasm.Export{
+			{Name: "init", Type: wasm.ExternTypeFunc, Index: 4},
+			{Name: "copy", Type: wasm.ExternTypeFunc, Index: 5},
+			{Name: "shuffle", Type: wasm.ExternTypeFunc, Index: 6},
+			{Name: "fill", Type: wasm.ExternTypeFunc, Index: 7},
+			{Name: "drop", Type: wasm.ExternTypeFunc, Index: 8},
+			{Name: "call0", Type: wasm.ExternTypeFunc, Index: 9},
+			{Name: "call1", Type: wasm.ExternTypeFunc, Index: 10},
+		},
+	}))
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, mod.Close(testCtx))
+	}()
+
+	// vtable returns the results of calling each slot of the table, or -1 for a null slot.
+	vtable := func(call string) []int {
+		ret := make([]int, 4)
+		for i := range ret {
+			res, err := mod.ExportedFunction(call).Call(testCtx, uint64(i))
+			if err != nil {
+				require.ErrorIs(t, err, wasmruntime.ErrRuntimeInvalidTableAccess)
+				ret[i] = -1
+				continue
+			}
+			ret[i] = int(res[0])
+		}
+		return ret
+	}
+	call := func(name string, params ...uint64) error {
+		_, err := mod.ExportedFunction(name).Call(testCtx, params...)
+		return err
+	}
+
+	require.Equal(t, []int{-1, -1, -1, -1}, vtable("call0"))
+	require.Equal(t, []int{-1, -1, -1, -1}, vtable("call1"))
+
+	t.Run("ok", func(t *testing.T) {
+		require.NoError(t, call("init", 0, 0, 4))
+		require.Equal(t, []int{10, 11, 12, 13}, vtable("call0"))
+
+		// Overlapping copies within one table behave as if through a temporary buffer.
+		require.NoError(t, call("shuffle", 1, 0, 3))
+		require.Equal(t, []int{10, 10, 11, 12}, vtable("call0"))
+		require.NoError(t, call("shuffle", 0, 1, 3))
+		require.Equal(t, []int{10, 11, 12, 12}, vtable("call0"))
+
+		require.NoError(t, call("copy", 0, 0, 4))
+		require.Equal(t, []int{10, 11, 12, 12}, vtable("call1"))
+		require.NoError(t, call("fill", 1, 0, 2))
+		require.Equal(t, []int{10, 10, 10, 12}, vtable("call1"))
+
+		// Empty operations at the end of the tables and segment are allowed.
+		require.NoError(t, call("init", 4, 4, 0))
+		require.NoError(t, call("copy", 4, 4, 0))
+		require.NoError(t, call("shuffle", 4, 4, 0))
+		require.NoError(t, call("fill", 4, 0, 0))
+	})
+
+	t.Run("out of bounds", func(t *testing.T) {
+		for _, tc := range []struct {
+			name, fn      string
+			dst, src, len uint64
+		}{
+			{name: "init dst+len exceeds table", fn: "init", dst: 3, src: 0, len: 2},
+			{name: "init src+len exceeds segment", fn: "init", dst: 0, src: 2, len: 3},
+			{name: "init dst exceeds table", fn: "init", dst: 5, src: 0, len: 0},
+			{name: "copy dst+len exceeds table", fn: "copy", dst: 3, src: 0, len: 2},
+			{name: "copy src+len exceeds table", fn: "copy", dst: 0, src: 3, len: 2},
+			{name: "copy len overflows", fn: "copy", dst: 1, src: 0, len: math.MaxUint32},
+			{name: "shuffle src+len exceeds table", fn: "shuffle", dst: 0, src: 3, len: 2},
+			{name: "fill dst+len exceeds table", fn: "fill", dst: 3, src: 0, len: 2},
+			{name: "fill dst exceeds table", fn: "fill", dst: 5, src: 0, len: 0},
+		} {
+			tc := tc
+			t.Run(tc.name, func(t *testing.T) {
+				err := call(tc.fn, tc.dst, tc.src, tc.len)
+				require.ErrorIs(t, err, wasmruntime.ErrRuntimeInvalidTableAccess)
+			})
+		}
+		// Neither table was modified before trapping.
+		require.Equal(t, []int{10, 11, 12, 12}, vtable("call0"))
+		require.Equal(t, []int{10, 10, 10, 12}, vtable("call1"))
+	})
+
+	t.Run("elem.drop", func(t *testing.T) {
+		require.NoError(t, call("drop"))
+		require.NoError(t, call("drop")) // dropping twice is allowed.
+		require.NoError(t, call("init", 0, 0, 0))
+		require.ErrorIs(t, call("init", 0, 0, 1), wasmruntime.ErrRuntimeInvalidTableAccess)
+		require.Equal(t, []int{10, 11, 12, 12}, vtable("call0"))
+	})
+
+	t.Run("element type mismatch", func(t *testing.T) {
+		_, err := r.CompileModule(testCtx, binaryencoding.EncodeModule(&wasm.Module{
+			TypeSection:     []wasm.FunctionType{i32i32i32_v},
+			FunctionSection: []wasm.Index{0},
+			CodeSection:     []wasm.Code{bulk(wasm.OpcodeMiscTableCopy, 1, 0)},
+			TableSection: []wasm.Table{
+				{Min: 1, Type: wasm.RefTypeFuncref},
+				{Min: 1, Type: wasm.RefTypeExternref},
+			},
+		}))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "table type mismatch for table.copy")
+	})
+}
+
+func testTailCall(t *testing.T, r wazero.Runtime) {
+	i64i64_i64 := wasm.FunctionType{Params: []wasm.ValueType{i64, i64}, Results: []wasm.ValueType{i64}}
+	i64_i64 := wasm.FunctionType{Params: []wasm.ValueType{i64}, Results: []wasm.ValueType{i64}}
+	tenI64_i64 := wasm.FunctionType{Params: make([]wasm.ValueType, 10), Results: []wasm.ValueType{i64}}
+	for i := range tenI64_i64.Params {
+		tenI64_i64.Params[i] = i64
+	}
+
+	// sum returns acc plus the sum of 1 to n, calling itself n times.
+	sum := func(call ...byte) wasm.Code {
+		body := []byte{
+			wasm.OpcodeLocalGet, 0, wasm.OpcodeI64Eqz, wasm.OpcodeIf, 0x40,
+			wasm.OpcodeLocalGet, 1, wasm.OpcodeReturn,
+			wasm.OpcodeEnd,
+			wasm.OpcodeLocalGet, 0, wasm.OpcodeI64Const, 1, wasm.OpcodeI64Sub,
+			wasm.OpcodeLocalGet, 1, wasm.OpcodeLocalGet, 0, wasm.OpcodeI64Add,
+		}
+		body = append(body, call...)
+		return wasm.Code{Body: append(body, wasm.OpcodeEnd)}
+	}
+	manyParams := []byte{}
+	for i := 0; i < 10; i++ {
+		manyParams = append(manyParams, wasm.OpcodeLocalGet, 0)
+	}
+	sumParams := []byte{wasm.OpcodeLocalGet, 0}
+	for i := byte(1); i < 10; i++ {
+		sumParams = append(sumParams, wasm.OpcodeLocalGet, i, wasm.OpcodeI64Add)
+	}
+
+	mod, err := r.Instantiate(testCtx, binaryencoding.EncodeModule(&wasm.Module{
+		TypeSection:     []wasm.FunctionType{i64i64_i64, i64_i64, tenI64_i64},
+		FunctionSection: []wasm.Index{0, 0, 1, 2, 1},
+		CodeSection: []wasm.Code{
+			sum(wasm.OpcodeReturnCall, 0),
+			sum(wasm.OpcodeI32Const, 0, wasm.OpcodeReturnCallIndirect, 0, 0),
+			{Body: append(manyParams, wasm.OpcodeReturnCall, 3, wasm.OpcodeEnd)},
+			{Body: append(sumParams, wasm.OpcodeEnd)},
+			{Body: []byte{wasm.OpcodeLocalGet, 0, wasm.OpcodeI32Const, 0, wasm.OpcodeReturnCallIndirect, 1, 0, wasm.OpcodeEnd}},
+		},
+		TableSection: []wasm.Table{{Min: 1, Type: wasm.RefTypeFuncref}},
+		ElementSection: []wasm.ElementSegment{{
+			OffsetExpr: wasm.ConstantExpression{Opcode: wasm.OpcodeI32Const, Data: []byte{0}},
+			Init:       []wasm.Index{1},
+			Type:       wasm.RefTypeFuncref,
+		}},
+		ExportSection: []wasm.Export{
+			{Name: "sum", Type: wasm.ExternTypeFunc, Index: 0},
+			{Name: "sum_indirect", Type: wasm.ExternTypeFunc, Index: 1},
+			{Name: "times_ten", Type: wasm.ExternTypeFunc, Index: 2},
+			{Name: "type_mismatch", Type: wasm.ExternTypeFunc, Index: 4},
+		},
+	}))
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, mod.Close(testCtx))
+	}()
+
+	// Deeper than the call stack limit of the interpreter.
+	const n = 100_000
+	for _, name := range []string{"sum", "sum_indirect"} {
+		res, err := mod.ExportedFunction(name).Call(testCtx, n, 1)
+		require.NoError(t, err, name)
+		require.Equal(t, uint64(n*(n+1)/2+1), res[0], name)
+	}
+
+	res, err := mod.ExportedFunction("times_ten").Call(testCtx, 7)
+	require.NoError(t, err)
+	require.Equal(t, uint64(70), res[0])
+
+	_, err = mod.ExportedFunction("type_mismatch").Call(testCtx, 7)
+	require.ErrorIs(t, err, wasmruntime.ErrRuntimeIndirectCallTypeMismatch)
+}
+
+// testMutableGlobalSet ensures values written via api.MutableGlobal are seen by
+// subsequent guest calls, and vice versa.
+func testMutableGlobalSet(t *testing.T, r wazero.Runtime) {
+	valueTypes := []wasm.ValueType{i32, i64, f32, f64}
+	zeros := map[wasm.ValueType]wasm.ConstantExpression{
+		i32: {Opcode: wasm.OpcodeI32Const, Data: []byte{0}},
+		i64: {Opcode: wasm.OpcodeI64Const, Data: []byte{0}},
+		f32: {Opcode: wasm.OpcodeF32Const, Data: make([]byte, 4)},
+		f64: {Opcode: wasm.OpcodeF64Const, Data: make([]byte, 8)},
+	}
+
+	m := &wasm.Module{}
+	for i, vt := range valueTypes {
+		idx := wasm.Index(i)
+		name := wasm.ValueTypeName(vt)
+		m.TypeSection = append(m.TypeSection,
+			wasm.FunctionType{Results: []wasm.ValueType{vt}},
+			wasm.FunctionType{Params: []wasm.ValueType{vt}},
+		)
+		m.FunctionSection = append(m.FunctionSection, 2*idx, 2*idx+1)
+		m.CodeSection = append(m.CodeSection,
+			wasm.Code{Body: []byte{wasm.OpcodeGlobalGet, byte(idx), wasm.OpcodeEnd}},
+			wasm.Code{Body: []byte{wasm.OpcodeLocalGet, 0, wasm.OpcodeGlobalSet, byte(idx), wasm.OpcodeEnd}},
+		)
+		m.GlobalSection = append(m.GlobalSection, wasm.Global{Type: wasm.GlobalType{ValType: vt, Mutable: true}, Init: zeros[vt]})
+		m.ExportSection = append(m.ExportSection,
+			wasm.Export{Name: name, Type: wasm.ExternTypeGlobal, Index: idx},
+			wasm.Export{Name: "get_" + name, Type: wasm.ExternTypeFunc, Index: 2 * idx},
+			wasm.Export{Name: "set_" + name, Type: wasm.ExternTypeFunc, Index: 2*idx + 1},
+		)
+	}
+	m.GlobalSection = append(m.GlobalSection, wasm.Global{Type: wasm.GlobalType{ValType: i32}, Init: zeros[i32]})
+	m.ExportSection = append(m.ExportSection, wasm.Export{Name: "const", Type: wasm.ExternTypeGlobal, Index: wasm.Index(len(valueTypes))})
+
+	mod, err := r.Instantiate(testCtx, binaryencoding.EncodeModule(m))
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, mod.Close(testCtx))
+	}()
+
+	tests := []struct {
+		name       string
+		hostValue  uint64
+		guestValue uint64
+	}{
+		{name: "i32", hostValue: api.EncodeI32(-1), guestValue: api.EncodeI32(math.MaxInt32)},
+		{name: "i64", hostValue: api.EncodeI64(math.MinInt64), guestValue: api.EncodeI64(-1)},
+		{name: "f32", hostValue: api.EncodeF32(1.5), guestValue: api.EncodeF32(float32(math.Inf(-1)))},
+		{name: "f64", hostValue: api.EncodeF64(math.Pi), guestValue: api.EncodeF64(-0.25)},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			g, ok := mod.ExportedGlobal(tc.name).(api.MutableGlobal)
+			require.True(t, ok)
+
+			// Writes from the host are visible to the guest.
+			g.Set(tc.hostValue)
+			res, err := mod.ExportedFunction("get_" + tc.name).Call(testCtx)
+			require.NoError(t, err)
+			require.Equal(t, tc.hostValue, res[0])
+
+			// Writes from the guest are visible to the host.
+			_, err = mod.ExportedFunction("set_"+tc.name).Call(testCtx, tc.guestValue)
+			require.NoError(t, err)
+			require.Equal(t, tc.guestValue, g.Get())
+		})
+	}
+
+	t.Run("immutable", func(t *testing.T) {
+		g := mod.ExportedGlobal("const")
+		require.NotNil(t, g)
+		_, ok := g.(api.MutableGlobal)
+		require.False(t, ok)
+	})
+}
+
 func testCloseInFlight(t *testing.T, r wazero.Runtime) {
 	tests := []struct {
 		name, function                        string
@@ -1536,7 +3373,7 @@ func testListenerStackIteratorOffset(t *testing.T, r wazero.Runtime) {
 		},
 		CustomSections: []*wasm.CustomSection{{Name: ".debug_info", Data: minimalDWARFInfo}},
 	})
-	decoded, err := binary.DecodeModule(encoded, api.CoreFeaturesV2, 0, false, true, true)
+	decoded, err := binary.DecodeModule(encoded, api.CoreFeaturesV2, 0, false, true, true, wasm.DefaultDecodeLimits)
 	require.NoError(t, err)
 
 	f1offset := decoded.CodeSection[0].BodyOffsetInCodeSection
@@ -176,9 +176,23 @@ func runDWARFTest(t *testing.T, r wazero.Runtime, bin []byte, exp string) {
 
 	_, err := wasi_snapshot_preview1.Instantiate(testCtx, r)
 	require.NoError(t, err)
-	_, err = r.Instantiate(testCtx, bin)
+	compiled, err := r.CompileModule(testCtx, bin)
+	require.NoError(t, err)
+	_, err = r.InstantiateModule(testCtx, compiled, wazero.NewModuleConfig())
 	require.Error(t, err)
 
+	// The structured frames have the same source lines as the message, which SourceLine finds from their offset.
+	lines := wazero.DWARFLines(compiled)
+	require.NotNil(t, lines)
+	frames := wazero.ErrStackTrace(err)
+	require.NotEqual(t, "", frames[0].File)
+	for _, f := range frames {
+		file, line, ok := lines.SourceLine(f.FunctionIndex, f.Offset)
+		require.Equal(t, f.File != "", ok)
+		require.Equal(t, f.File, file)
+		require.Equal(t, f.Line, line)
+	}
+
 	errStr := err.Error()
 
 	// Since stack traces change where the binary is compiled, we sanitize each line
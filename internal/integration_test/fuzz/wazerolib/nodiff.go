@@ -400,7 +400,7 @@ func allowedErrorDuringInstantiation(errMsg string) bool {
 	}
 
 	// Start function failure is neither instantiation nor compilation error, but rather a runtime error, so that is fine.
-	if strings.HasPrefix(errMsg, "start function[") && strings.Contains(errMsg, "failed: wasm error:") {
+	if strings.Contains(errMsg, "start function[") && strings.Contains(errMsg, "failed: wasm error:") {
 		return true
 	}
 	return false
@@ -10,16 +10,6 @@ const (
 	maxVarintLen32 = 5
 	maxVarintLen33 = maxVarintLen32
 	maxVarintLen64 = 10
-
-	int33Mask  int64 = 1 << 7
-	int33Mask2       = ^int33Mask
-	int33Mask3       = 1 << 6
-	int33Mask4       = 8589934591 // 2^33-1
-	int33Mask5       = 1 << 32
-	int33Mask6       = int33Mask4 + 1 // 2^33
-
-	int64Mask3 = 1 << 6
-	int64Mask4 = ^0
 )
 
 var (
@@ -130,19 +120,27 @@ func decodeUint32(next nextByte) (ret uint32, bytesRead uint64, err error) {
 	return 0, 0, errOverflow32
 }
 
+func DecodeUint64(r io.ByteReader) (ret uint64, bytesRead uint64, err error) {
+	return decodeUint64(func(_ int) (byte, error) { return r.ReadByte() })
+}
+
 func LoadUint64(buf []byte) (ret uint64, bytesRead uint64, err error) {
-	bufLen := len(buf)
-	if bufLen == 0 {
-		return 0, 0, io.EOF
-	}
+	return decodeUint64(func(i int) (byte, error) {
+		if i >= len(buf) {
+			return 0, io.EOF
+		}
+		return buf[i], nil
+	})
+}
 
+func decodeUint64(next nextByte) (ret uint64, bytesRead uint64, err error) {
 	// Derived from https://github.com/golang/go/blob/go1.20/src/encoding/binary/varint.go
 	var s uint64
 	for i := 0; i < maxVarintLen64; i++ {
-		if i >= bufLen {
-			return 0, 0, io.EOF
+		b, err := next(i)
+		if err != nil {
+			return 0, 0, err
 		}
-		b := buf[i]
 		if b < 0x80 {
 			// Unused bits (non first bit) must all be zero.
 			if i == maxVarintLen64-1 && b > 1 {
@@ -181,21 +179,22 @@ func decodeInt32(next nextByte) (ret int32, bytesRead uint64, err error) {
 		shift += 7
 		bytesRead++
 		if b&0x80 == 0 {
-			if shift < 32 && (b&0x40) != 0 {
-				ret |= ^0 << shift
-			}
-			// Over flow checks.
-			// fixme: can be optimized.
-			if bytesRead > maxVarintLen32 {
-				return 0, 0, errOverflow32
-			} else if unused := b & 0b00110000; bytesRead == maxVarintLen32 && ret < 0 && unused != 0b00110000 {
-				return 0, 0, errOverflow32
-			} else if bytesRead == maxVarintLen32 && ret >= 0 && unused != 0x00 {
-				return 0, 0, errOverflow32
-			}
-			return
+			break
+		} else if bytesRead == maxVarintLen32 {
+			return 0, 0, errOverflow32
 		}
 	}
+
+	if bytesRead == maxVarintLen32 {
+		// The last byte carries bits 28-34, where bits above 31 must equal
+		// the sign bit 31.
+		if signAndUnused := b & 0b0111_1000; signAndUnused != 0 && signAndUnused != 0b0111_1000 {
+			return 0, 0, errOverflow32
+		}
+	} else if b&0x40 != 0 {
+		ret |= ^0 << shift
+	}
+	return
 }
 
 // DecodeInt33AsInt64 is a special cased decoder for wasm.BlockType which is encoded as a positive signed integer, yet
@@ -204,40 +203,32 @@ func decodeInt32(next nextByte) (ret int32, bytesRead uint64, err error) {
 // See https://webassembly.github.io/spec/core/binary/instructions.html#control-instructions
 func DecodeInt33AsInt64(r io.ByteReader) (ret int64, bytesRead uint64, err error) {
 	var shift int
-	var b int64
-	var rb byte
-	for shift < 35 {
-		rb, err = r.ReadByte()
+	var b byte
+	for {
+		b, err = r.ReadByte()
 		if err != nil {
 			return 0, 0, fmt.Errorf("readByte failed: %w", err)
 		}
-		b = int64(rb)
-		ret |= (b & int33Mask2) << shift
+		ret |= (int64(b) & 0x7f) << shift
 		shift += 7
 		bytesRead++
-		if b&int33Mask == 0 {
+		if b&0x80 == 0 {
 			break
+		} else if bytesRead == maxVarintLen33 {
+			return 0, 0, errOverflow33
 		}
 	}
 
-	// fixme: can be optimized
-	if shift < 33 && (b&int33Mask3) == int33Mask3 {
-		ret |= int33Mask4 << shift
-	}
-	ret = ret & int33Mask4
-
-	// if 33rd bit == 1, we translate it as a corresponding signed-33bit minus value
-	if ret&int33Mask5 > 0 {
-		ret = ret - int33Mask6
-	}
-	// Over flow checks.
-	// fixme: can be optimized.
-	if bytesRead > maxVarintLen33 {
-		return 0, 0, errOverflow33
-	} else if unused := b & 0b00100000; bytesRead == maxVarintLen33 && ret < 0 && unused != 0b00100000 {
-		return 0, 0, errOverflow33
-	} else if bytesRead == maxVarintLen33 && ret >= 0 && unused != 0x00 {
-		return 0, 0, errOverflow33
+	if bytesRead == maxVarintLen33 {
+		// The last byte carries bits 28-34, where bits above 32 must equal
+		// the sign bit 32.
+		if signAndUnused := b & 0b0111_0000; signAndUnused != 0 && signAndUnused != 0b0111_0000 {
+			return 0, 0, errOverflow33
+		}
+		// Sign-extend from bit 32.
+		ret = ret << 31 >> 31
+	} else if b&0x40 != 0 {
+		ret |= ^0 << shift
 	}
 	return ret, bytesRead, nil
 }
@@ -267,19 +258,20 @@ func decodeInt64(next nextByte) (ret int64, bytesRead uint64, err error) {
 		shift += 7
 		bytesRead++
 		if b&0x80 == 0 {
-			if shift < 64 && (b&int64Mask3) == int64Mask3 {
-				ret |= int64Mask4 << shift
-			}
-			// Over flow checks.
-			// fixme: can be optimized.
-			if bytesRead > maxVarintLen64 {
-				return 0, 0, errOverflow64
-			} else if unused := b & 0b00111110; bytesRead == maxVarintLen64 && ret < 0 && unused != 0b00111110 {
-				return 0, 0, errOverflow64
-			} else if bytesRead == maxVarintLen64 && ret >= 0 && unused != 0x00 {
-				return 0, 0, errOverflow64
-			}
-			return
+			break
+		} else if bytesRead == maxVarintLen64 {
+			return 0, 0, errOverflow64
+		}
+	}
+
+	if bytesRead == maxVarintLen64 {
+		// The last byte carries bits 63-69, where bits above 63 must equal
+		// the sign bit 63.
+		if signAndUnused := b & 0b0111_1111; signAndUnused != 0 && signAndUnused != 0b0111_1111 {
+			return 0, 0, errOverflow64
 		}
+	} else if b&0x40 != 0 {
+		ret |= ^0 << shift
 	}
+	return
 }
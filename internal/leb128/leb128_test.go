@@ -148,6 +148,15 @@ func TestDecodeUint64(t *testing.T) {
 			require.Equal(t, c.exp, actual)
 			require.Equal(t, uint64(len(c.bytes)), num)
 		}
+
+		actual, num, err = DecodeUint64(bytes.NewReader(c.bytes))
+		if c.expErr {
+			require.Error(t, err)
+		} else {
+			require.NoError(t, err)
+			require.Equal(t, c.exp, actual)
+			require.Equal(t, uint64(len(c.bytes)), num)
+		}
 	}
 }
 
@@ -228,3 +237,70 @@ func TestDecodeInt64(t *testing.T) {
 		require.Equal(t, uint64(len(c.bytes)), num)
 	}
 }
+
+// TestDecode_maxLength ensures padded encodings up to the maximum length of
+// the type are accepted, while longer ones or those with bits beyond the type
+// width are rejected.
+func TestDecode_maxLength(t *testing.T) {
+	decodeUint32 := func(b []byte) (int64, uint64, error) {
+		v, n, err := LoadUint32(b)
+		return int64(v), n, err
+	}
+	decodeUint64 := func(b []byte) (int64, uint64, error) {
+		v, n, err := LoadUint64(b)
+		return int64(v), n, err
+	}
+	decodeInt32 := func(b []byte) (int64, uint64, error) {
+		v, n, err := LoadInt32(b)
+		return int64(v), n, err
+	}
+	decodeInt33 := func(b []byte) (int64, uint64, error) {
+		return DecodeInt33AsInt64(bytes.NewReader(b))
+	}
+
+	tests := []struct {
+		name   string
+		decode func([]byte) (int64, uint64, error)
+		bytes  []byte
+		exp    int64
+		expErr string
+	}{
+		{name: "uint32 padded", decode: decodeUint32, bytes: []byte{0x80, 0x80, 0x80, 0x80, 0x00}, exp: 0},
+		{name: "uint32 overlong", decode: decodeUint32, bytes: []byte{0x80, 0x80, 0x80, 0x80, 0x80, 0x00}, expErr: "overflows a 32-bit integer"},
+		{name: "uint32 overflow", decode: decodeUint32, bytes: []byte{0xff, 0xff, 0xff, 0xff, 0x1f}, expErr: "overflows a 32-bit integer"},
+		{name: "uint64 padded", decode: decodeUint64, bytes: []byte{0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x00}, exp: 0},
+		{name: "uint64 overlong", decode: decodeUint64, bytes: []byte{0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x00}, expErr: "overflows a 64-bit integer"},
+		{name: "uint64 overflow", decode: decodeUint64, bytes: []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x03}, expErr: "overflows a 64-bit integer"},
+		{name: "int32 padded", decode: decodeInt32, bytes: []byte{0x80, 0x80, 0x80, 0x80, 0x00}, exp: 0},
+		{name: "int32 padded negative", decode: decodeInt32, bytes: []byte{0xff, 0xff, 0xff, 0xff, 0x7f}, exp: -1},
+		{name: "int32 min", decode: decodeInt32, bytes: []byte{0x80, 0x80, 0x80, 0x80, 0x78}, exp: math.MinInt32},
+		{name: "int32 overlong", decode: decodeInt32, bytes: []byte{0x80, 0x80, 0x80, 0x80, 0x80, 0x00}, expErr: "overflows a 32-bit integer"},
+		{name: "int32 overflow positive", decode: decodeInt32, bytes: []byte{0x80, 0x80, 0x80, 0x80, 0x47}, expErr: "overflows a 32-bit integer"},
+		{name: "int32 overflow negative", decode: decodeInt32, bytes: []byte{0xff, 0xff, 0xff, 0xff, 0x3f}, expErr: "overflows a 32-bit integer"},
+		{name: "int33 padded", decode: decodeInt33, bytes: []byte{0x80, 0x80, 0x80, 0x80, 0x00}, exp: 0},
+		{name: "int33 padded negative", decode: decodeInt33, bytes: []byte{0xff, 0xff, 0xff, 0xff, 0x7f}, exp: -1},
+		{name: "int33 max", decode: decodeInt33, bytes: []byte{0xff, 0xff, 0xff, 0xff, 0x0f}, exp: math.MaxUint32},
+		{name: "int33 overlong", decode: decodeInt33, bytes: []byte{0x80, 0x80, 0x80, 0x80, 0x80, 0x00}, expErr: "overflows a 33-bit integer"},
+		{name: "int33 overflow positive", decode: decodeInt33, bytes: []byte{0x80, 0x80, 0x80, 0x80, 0x20}, expErr: "overflows a 33-bit integer"},
+		{name: "int33 overflow negative", decode: decodeInt33, bytes: []byte{0xff, 0xff, 0xff, 0xff, 0x5f}, expErr: "overflows a 33-bit integer"},
+		{name: "int64 padded", decode: LoadInt64, bytes: []byte{0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x00}, exp: 0},
+		{name: "int64 padded negative", decode: LoadInt64, bytes: []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x7f}, exp: -1},
+		{name: "int64 overlong", decode: LoadInt64, bytes: []byte{0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x00}, expErr: "overflows a 64-bit integer"},
+		{name: "int64 overflow positive", decode: LoadInt64, bytes: []byte{0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x02}, expErr: "overflows a 64-bit integer"},
+		{name: "int64 overflow negative", decode: LoadInt64, bytes: []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x41}, expErr: "overflows a 64-bit integer"},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			actual, num, err := tc.decode(tc.bytes)
+			if tc.expErr != "" {
+				require.EqualError(t, err, tc.expErr)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, tc.exp, actual)
+				require.Equal(t, uint64(len(tc.bytes)), num)
+			}
+		})
+	}
+}
@@ -0,0 +1,158 @@
+// Package memio adapts windows of api.Memory to the interfaces of package io.
+package memio
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/tetratelabs/wazero/api"
+)
+
+var errNegativeOffset = errors.New("negative offset")
+
+// window is a range of memory, which is bounds checked on each access as the
+// memory can grow after the window is created.
+type window struct {
+	mem            api.Memory
+	offset, length uint32
+}
+
+// view returns the part of the window starting at pos, or an error if the
+// window is out of range of the memory.
+func (w *window) view(pos uint32) ([]byte, error) {
+	buf, ok := w.mem.Read(w.offset, w.length)
+	if !ok {
+		return nil, fmt.Errorf("out of memory range: offset=%d, length=%d, memory size=%d",
+			w.offset, w.length, w.mem.Size())
+	}
+	return buf[pos:], nil
+}
+
+// NewReader implements api.Memory Reader.
+func NewReader(mem api.Memory, offset, length uint32) io.Reader {
+	return &reader{w: window{mem: mem, offset: offset, length: length}}
+}
+
+type reader struct {
+	w   window
+	pos uint32
+}
+
+// Read implements io.Reader
+func (r *reader) Read(p []byte) (int, error) {
+	if r.pos == r.w.length {
+		return 0, io.EOF
+	}
+	buf, err := r.w.view(r.pos)
+	if err != nil {
+		return 0, err
+	}
+	n := copy(p, buf)
+	r.pos += uint32(n)
+	return n, nil
+}
+
+// NewWriter implements api.Memory Writer.
+func NewWriter(mem api.Memory, offset, length uint32) io.Writer {
+	return &writer{w: window{mem: mem, offset: offset, length: length}}
+}
+
+type writer struct {
+	w   window
+	pos uint32
+}
+
+// Write implements io.Writer
+func (w *writer) Write(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	buf, err := w.w.view(w.pos)
+	if err != nil {
+		return 0, err
+	}
+	n := copy(buf, p)
+	w.pos += uint32(n)
+	if n < len(p) {
+		return n, io.ErrShortWrite
+	}
+	return n, nil
+}
+
+// ReadFrom implements api.Memory ReadFrom.
+func ReadFrom(mem api.Memory, offset uint32, r io.Reader, limit uint32) (uint32, error) {
+	w := window{mem: mem, offset: offset, length: limit}
+	// Check the whole window before reading, so that nothing is written when
+	// it is out of range.
+	if _, err := w.view(0); err != nil {
+		return 0, err
+	}
+	var n uint32
+	for n < limit {
+		buf, err := w.view(n)
+		if err != nil {
+			return n, err
+		}
+		nr, err := r.Read(buf)
+		n += uint32(nr)
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+// NewReaderAt implements api.Memory ReaderAt.
+func NewReaderAt(mem api.Memory, offset, length uint32) io.ReaderAt {
+	return &readerAt{w: window{mem: mem, offset: offset, length: length}}
+}
+
+type readerAt struct{ w window }
+
+// ReadAt implements io.ReaderAt
+func (r *readerAt) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, errNegativeOffset
+	} else if off >= int64(r.w.length) {
+		return 0, io.EOF
+	}
+	buf, err := r.w.view(uint32(off))
+	if err != nil {
+		return 0, err
+	}
+	n := copy(p, buf)
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// NewWriterAt implements api.Memory WriterAt.
+func NewWriterAt(mem api.Memory, offset, length uint32) io.WriterAt {
+	return &writerAt{w: window{mem: mem, offset: offset, length: length}}
+}
+
+type writerAt struct{ w window }
+
+// WriteAt implements io.WriterAt
+func (w *writerAt) WriteAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, errNegativeOffset
+	} else if len(p) == 0 {
+		return 0, nil
+	} else if off >= int64(w.w.length) {
+		return 0, io.ErrShortWrite
+	}
+	buf, err := w.w.view(uint32(off))
+	if err != nil {
+		return 0, err
+	}
+	n := copy(buf, p)
+	if n < len(p) {
+		return n, io.ErrShortWrite
+	}
+	return n, nil
+}
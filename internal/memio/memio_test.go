@@ -0,0 +1,185 @@
+package memio_test
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/tetratelabs/wazero/experimental/wazerotest"
+	"github.com/tetratelabs/wazero/internal/memio"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+const memorySize = wazerotest.PageSize
+
+func TestReader(t *testing.T) {
+	mem := wazerotest.NewMemory(memorySize)
+	copy(mem.Bytes[10:], "hello world")
+
+	t.Run("reads until EOF", func(t *testing.T) {
+		buf, err := io.ReadAll(memio.NewReader(mem, 10, 5))
+		require.NoError(t, err)
+		require.Equal(t, "hello", string(buf))
+	})
+
+	t.Run("reads in chunks", func(t *testing.T) {
+		r := memio.NewReader(mem, 10, 11)
+		p := make([]byte, 6)
+		n, err := r.Read(p)
+		require.NoError(t, err)
+		require.Equal(t, "hello ", string(p[:n]))
+		n, err = r.Read(p)
+		require.NoError(t, err)
+		require.Equal(t, "world", string(p[:n]))
+		_, err = r.Read(p)
+		require.Equal(t, io.EOF, err)
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		_, err := memio.NewReader(mem, memorySize, 0).Read(make([]byte, 1))
+		require.Equal(t, io.EOF, err)
+	})
+
+	t.Run("out of range", func(t *testing.T) {
+		_, err := memio.NewReader(mem, memorySize-1, 2).Read(make([]byte, 1))
+		require.EqualError(t, err, "out of memory range: offset=65535, length=2, memory size=65536")
+	})
+
+	t.Run("in range after grow", func(t *testing.T) {
+		mem := wazerotest.NewMemory(memorySize)
+		r := memio.NewReader(mem, memorySize-1, 2)
+		_, err := r.Read(make([]byte, 1))
+		require.Error(t, err)
+
+		_, ok := mem.Grow(1)
+		require.True(t, ok)
+		buf, err := io.ReadAll(r)
+		require.NoError(t, err)
+		require.Equal(t, []byte{0, 0}, buf)
+	})
+}
+
+func TestReaderAt(t *testing.T) {
+	mem := wazerotest.NewMemory(memorySize)
+	copy(mem.Bytes[10:], "hello world")
+	r := memio.NewReaderAt(mem, 10, 11)
+
+	p := make([]byte, 5)
+	n, err := r.ReadAt(p, 6)
+	require.NoError(t, err)
+	require.Equal(t, "world", string(p[:n]))
+
+	n, err = r.ReadAt(p, 8)
+	require.Equal(t, io.EOF, err)
+	require.Equal(t, "rld", string(p[:n]))
+
+	_, err = r.ReadAt(p, 11)
+	require.Equal(t, io.EOF, err)
+
+	_, err = r.ReadAt(p, -1)
+	require.EqualError(t, err, "negative offset")
+
+	_, err = memio.NewReaderAt(mem, memorySize, 1).ReadAt(p, 0)
+	require.EqualError(t, err, "out of memory range: offset=65536, length=1, memory size=65536")
+}
+
+func TestWriter(t *testing.T) {
+	t.Run("copies a stream", func(t *testing.T) {
+		mem := wazerotest.NewMemory(memorySize)
+		n, err := io.Copy(memio.NewWriter(mem, 10, 11), strings.NewReader("hello world"))
+		require.NoError(t, err)
+		require.Equal(t, int64(11), n)
+		require.Equal(t, "hello world", string(mem.Bytes[10:21]))
+	})
+
+	t.Run("writes past the range don't touch memory", func(t *testing.T) {
+		mem := wazerotest.NewMemory(memorySize)
+		w := memio.NewWriter(mem, 10, 8)
+		n, err := w.Write([]byte("hello "))
+		require.NoError(t, err)
+		require.Equal(t, 6, n)
+
+		n, err = w.Write([]byte("world"))
+		require.Equal(t, io.ErrShortWrite, err)
+		require.Equal(t, 2, n)
+		require.Equal(t, "hello wo", string(mem.Bytes[10:18]))
+		require.Equal(t, make([]byte, 3), mem.Bytes[18:21])
+
+		n, err = w.Write([]byte("!"))
+		require.Equal(t, io.ErrShortWrite, err)
+		require.Equal(t, 0, n)
+	})
+
+	t.Run("out of range", func(t *testing.T) {
+		mem := wazerotest.NewMemory(memorySize)
+		_, err := memio.NewWriter(mem, memorySize-1, 2).Write([]byte{1})
+		require.EqualError(t, err, "out of memory range: offset=65535, length=2, memory size=65536")
+		require.Equal(t, make([]byte, memorySize), mem.Bytes)
+	})
+}
+
+func TestWriterAt(t *testing.T) {
+	mem := wazerotest.NewMemory(memorySize)
+	w := memio.NewWriterAt(mem, 10, 11)
+
+	n, err := w.WriteAt([]byte("world"), 6)
+	require.NoError(t, err)
+	require.Equal(t, 5, n)
+	n, err = w.WriteAt([]byte("hello!"), 0)
+	require.NoError(t, err)
+	require.Equal(t, 6, n)
+	require.Equal(t, "hello!world", string(mem.Bytes[10:21]))
+
+	n, err = w.WriteAt([]byte("abcd"), 9)
+	require.Equal(t, io.ErrShortWrite, err)
+	require.Equal(t, 2, n)
+	require.Equal(t, "hello!worab", string(mem.Bytes[10:21]))
+	require.Equal(t, byte(0), mem.Bytes[21])
+
+	_, err = w.WriteAt([]byte("a"), -1)
+	require.EqualError(t, err, "negative offset")
+
+	before := append([]byte(nil), mem.Bytes...)
+	_, err = memio.NewWriterAt(mem, memorySize, 1).WriteAt([]byte("a"), 0)
+	require.EqualError(t, err, "out of memory range: offset=65536, length=1, memory size=65536")
+	require.Equal(t, before, mem.Bytes)
+}
+
+func TestReadFrom(t *testing.T) {
+	t.Run("reads until EOF", func(t *testing.T) {
+		mem := wazerotest.NewMemory(memorySize)
+		n, err := memio.ReadFrom(mem, 10, strings.NewReader("hello world"), 20)
+		require.NoError(t, err)
+		require.Equal(t, uint32(11), n)
+		require.Equal(t, "hello world", string(mem.Bytes[10:21]))
+	})
+
+	t.Run("stops at the limit", func(t *testing.T) {
+		mem := wazerotest.NewMemory(memorySize)
+		r := strings.NewReader("hello world")
+		n, err := memio.ReadFrom(mem, 10, iotest.OneByteReader(r), 5)
+		require.NoError(t, err)
+		require.Equal(t, uint32(5), n)
+		require.Equal(t, "hello", string(mem.Bytes[10:15]))
+		require.Equal(t, make([]byte, 6), mem.Bytes[15:21])
+		require.Equal(t, 6, r.Len())
+	})
+
+	t.Run("reader error", func(t *testing.T) {
+		mem := wazerotest.NewMemory(memorySize)
+		r := iotest.TimeoutReader(iotest.HalfReader(strings.NewReader("hello world")))
+		n, err := memio.ReadFrom(mem, 10, r, 11)
+		require.Equal(t, iotest.ErrTimeout, err)
+		require.Equal(t, uint32(6), n)
+		require.Equal(t, "hello ", string(mem.Bytes[10:16]))
+	})
+
+	t.Run("out of range", func(t *testing.T) {
+		mem := wazerotest.NewMemory(memorySize)
+		n, err := memio.ReadFrom(mem, memorySize-1, strings.NewReader("a"), 2)
+		require.EqualError(t, err, "out of memory range: offset=65535, length=2, memory size=65536")
+		require.Zero(t, n)
+		require.Equal(t, make([]byte, memorySize), mem.Bytes)
+	})
+}
@@ -14,6 +14,34 @@ const (
 	CpuExtraFeatureABM = uint64(1) << 5
 )
 
+// cpuFeatureNames are the names of flags that the compiler considers, in the
+// order returned by CpuFeatureNames.
+var cpuFeatureNames = []struct {
+	name  string
+	flag  uint64
+	extra bool
+}{
+	{name: "sse3", flag: CpuFeatureSSE3},
+	{name: "sse4.1", flag: CpuFeatureSSE4_1},
+	{name: "sse4.2", flag: CpuFeatureSSE4_2},
+	{name: "abm", flag: CpuExtraFeatureABM, extra: true},
+}
+
+// CpuFeatureNames returns the names of the CPU features that the compiler
+// considers and this CPU supports, e.g. "sse4.1".
+func CpuFeatureNames() []string {
+	return cpuFeatureNamesOf(CpuFeatures)
+}
+
+func cpuFeatureNamesOf(flags CpuFeatureFlags) (names []string) {
+	for _, f := range cpuFeatureNames {
+		if (f.extra && flags.HasExtra(f.flag)) || (!f.extra && flags.Has(f.flag)) {
+			names = append(names, f.name)
+		}
+	}
+	return
+}
+
 // CpuFeatures exposes the capabilities for this CPU, queried via the Has, HasExtra methods
 var CpuFeatures CpuFeatureFlags = loadCpuFeatureFlags()
 
@@ -16,3 +16,13 @@ func TestAmd64CpuId_cpuHasFeature(t *testing.T) {
 	require.True(t, flags.HasExtra(CpuExtraFeatureABM))
 	require.False(t, flags.HasExtra(1<<6)) // some other value
 }
+
+func TestAmd64CpuId_cpuFeatureNamesOf(t *testing.T) {
+	require.Nil(t, cpuFeatureNamesOf(&cpuFeatureFlags{}))
+
+	flags := &cpuFeatureFlags{
+		flags:      CpuFeatureSSE3 | CpuFeatureSSE4_1,
+		extraFlags: CpuExtraFeatureABM,
+	}
+	require.Equal(t, []string{"sse3", "sse4.1", "abm"}, cpuFeatureNamesOf(flags))
+}
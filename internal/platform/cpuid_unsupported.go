@@ -0,0 +1,9 @@
+//go:build !amd64
+
+package platform
+
+// CpuFeatureNames returns nil as the compiler doesn't currently consider
+// optional CPU features on this architecture.
+func CpuFeatureNames() []string {
+	return nil
+}
@@ -0,0 +1,25 @@
+package platform
+
+import "runtime"
+
+// EnterStrictFloat locks the calling goroutine to its OS thread, and disables flushing denormal floating-point
+// inputs and results to zero on the thread, so that arithmetic conforms to IEEE 754 regardless of how the host
+// process configured it, e.g. in C code. The returned value must be passed to ExitStrictFloat.
+//
+// Note: This has no effect unless StrictFloatSupported.
+func EnterStrictFloat() (prev uint64) {
+	runtime.LockOSThread()
+	prev = getFloatControl()
+	if prev&floatControlFlushToZero != 0 {
+		setFloatControl(prev &^ floatControlFlushToZero)
+	}
+	return
+}
+
+// ExitStrictFloat restores the floating-point control of the thread changed by EnterStrictFloat, and unlocks it.
+func ExitStrictFloat(prev uint64) {
+	if prev&floatControlFlushToZero != 0 {
+		setFloatControl(prev)
+	}
+	runtime.UnlockOSThread()
+}
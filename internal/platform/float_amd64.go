@@ -0,0 +1,13 @@
+package platform
+
+// StrictFloatSupported is true when EnterStrictFloat can control the handling of denormals.
+const StrictFloatSupported = true
+
+// floatControlFlushToZero are the bits of MXCSR which flush denormals to zero: FTZ for results and DAZ for inputs.
+const floatControlFlushToZero = 1<<15 | 1<<6
+
+// getFloatControl returns MXCSR.
+func getFloatControl() uint64
+
+// setFloatControl sets MXCSR.
+func setFloatControl(v uint64)
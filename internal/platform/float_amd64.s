@@ -0,0 +1,12 @@
+#include "textflag.h"
+
+// func getFloatControl() uint64
+TEXT ·getFloatControl(SB), NOSPLIT, $0-8
+	MOVQ    $0, ret+0(FP)
+	STMXCSR ret+0(FP)
+	RET
+
+// func setFloatControl(v uint64)
+TEXT ·setFloatControl(SB), NOSPLIT, $0-8
+	LDMXCSR v+0(FP)
+	RET
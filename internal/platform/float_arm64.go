@@ -0,0 +1,14 @@
+package platform
+
+// StrictFloatSupported is true when EnterStrictFloat can control the handling of denormals.
+const StrictFloatSupported = true
+
+// floatControlFlushToZero are the bits of FPCR which flush denormals to zero: FZ and FZ16, as well as FIZ and AH
+// which change it when the alternate floating-point behavior is implemented.
+const floatControlFlushToZero = 1<<24 | 1<<19 | 1<<1 | 1<<0
+
+// getFloatControl returns FPCR.
+func getFloatControl() uint64
+
+// setFloatControl sets FPCR.
+func setFloatControl(v uint64)
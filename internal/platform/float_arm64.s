@@ -0,0 +1,13 @@
+#include "textflag.h"
+
+// func getFloatControl() uint64
+TEXT ·getFloatControl(SB), NOSPLIT, $0-8
+	MRS  FPCR, R0
+	MOVD R0, ret+0(FP)
+	RET
+
+// func setFloatControl(v uint64)
+TEXT ·setFloatControl(SB), NOSPLIT, $0-8
+	MOVD v+0(FP), R0
+	MSR  R0, FPCR
+	RET
@@ -0,0 +1,42 @@
+package platform
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+// mul is a variable to prevent constant folding.
+var mul = func(x, y float64) float64 { return x * y }
+
+// flushToZero is FTZ of MXCSR on amd64, or FZ of FPCR on arm64, which are implemented by any CPU.
+var flushToZero = map[string]uint64{"amd64": 1 << 15, "arm64": 1 << 24}[runtime.GOARCH]
+
+func TestEnterStrictFloat(t *testing.T) {
+	if !StrictFloatSupported {
+		t.Skip()
+	}
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	// The result is denormal.
+	const x, y, expected = 1e-300, 1e-10, 1e-310
+
+	orig := getFloatControl()
+	require.Zero(t, orig&floatControlFlushToZero)
+	require.Equal(t, expected, mul(x, y))
+
+	// Flush to zero as the host process could on this thread.
+	setFloatControl(orig | flushToZero)
+	defer setFloatControl(orig)
+	require.Equal(t, 0.0, mul(x, y))
+
+	prev := EnterStrictFloat()
+	require.Equal(t, expected, mul(x, y))
+	ExitStrictFloat(prev)
+
+	// Exception flags may be set by the arithmetic, so only the control bits are compared.
+	require.Equal(t, flushToZero, getFloatControl()&flushToZero)
+	require.Equal(t, 0.0, mul(x, y))
+}
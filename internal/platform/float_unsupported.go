@@ -0,0 +1,12 @@
+//go:build !(amd64 || arm64)
+
+package platform
+
+// StrictFloatSupported is true when EnterStrictFloat can control the handling of denormals.
+const StrictFloatSupported = false
+
+const floatControlFlushToZero = 0
+
+func getFloatControl() uint64 { return 0 }
+
+func setFloatControl(uint64) {}
@@ -0,0 +1,19 @@
+//go:build darwin || linux
+
+package platform
+
+import "syscall"
+
+// MlockSupported is true when Mlock can lock memory in RAM.
+const MlockSupported = true
+
+// Mlock locks the pages of b in RAM, faulting them in, so that accessing b never faults. This fails, for example, if b
+// exceeds the RLIMIT_MEMLOCK of the process.
+func Mlock(b []byte) error {
+	return syscall.Mlock(b)
+}
+
+// Munlock unlocks the pages of b, locked by Mlock.
+func Munlock(b []byte) error {
+	return syscall.Munlock(b)
+}
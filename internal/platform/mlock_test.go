@@ -0,0 +1,21 @@
+package platform
+
+import (
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestMlock(t *testing.T) {
+	b := make([]byte, 64*1024)
+	if !MlockSupported {
+		require.Error(t, Mlock(b))
+		return
+	}
+
+	if err := Mlock(b); err != nil {
+		// The process may not be allowed to lock memory, due to RLIMIT_MEMLOCK.
+		t.Skip(err)
+	}
+	require.NoError(t, Munlock(b))
+}
@@ -0,0 +1,23 @@
+//go:build !(darwin || linux)
+
+package platform
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// MlockSupported is true when Mlock can lock memory in RAM.
+const MlockSupported = false
+
+var errMlockUnsupported = fmt.Errorf("mlock unsupported on GOOS=%s", runtime.GOOS)
+
+// Mlock locks the pages of b in RAM, faulting them in, so that accessing b never faults.
+func Mlock([]byte) error {
+	return errMlockUnsupported
+}
+
+// Munlock unlocks the pages of b, locked by Mlock.
+func Munlock([]byte) error {
+	return errMlockUnsupported
+}
@@ -34,6 +34,9 @@ type ConfigKey struct{}
 type Config struct {
 	// TCPAddresses is a slice of the configured host:port pairs.
 	TCPAddresses []TCPAddress
+
+	// TCPListeners are listeners opened by the host, pre-opened after TCPAddresses.
+	TCPListeners []*net.TCPListener
 }
 
 // TCPAddress is a host:port pair to pre-open.
@@ -54,15 +57,27 @@ func (c *Config) WithTCPListener(host string, port int) *Config {
 	return &ret
 }
 
+// WithListener implements the method of the same name in experimental/sock/Config.
+//
+// However, to avoid cyclic dependencies, this is returning the *Config in this scope.
+// The interface is implemented in experimental/sock/Config via delegation.
+func (c *Config) WithListener(ln *net.TCPListener) *Config {
+	ret := c.clone()
+	ret.TCPListeners = append(ret.TCPListeners, ln)
+	return &ret
+}
+
 // Makes a deep copy of this sockConfig.
 func (c *Config) clone() Config {
 	ret := *c
 	ret.TCPAddresses = make([]TCPAddress, 0, len(c.TCPAddresses))
 	ret.TCPAddresses = append(ret.TCPAddresses, c.TCPAddresses...)
+	ret.TCPListeners = append([]*net.TCPListener(nil), c.TCPListeners...)
 	return ret
 }
 
-// BuildTCPListeners build listeners from the current configuration.
+// BuildTCPListeners build listeners from the current configuration, followed by
+// TCPListeners.
 func (c *Config) BuildTCPListeners() (tcpListeners []*net.TCPListener, err error) {
 	for _, tcpAddr := range c.TCPAddresses {
 		var ln net.Listener
@@ -79,9 +94,9 @@ func (c *Config) BuildTCPListeners() (tcpListeners []*net.TCPListener, err error
 		for _, l := range tcpListeners {
 			_ = l.Close() // Ignore errors, we are already cleaning.
 		}
-		tcpListeners = nil
+		return nil, err
 	}
-	return
+	return append(tcpListeners, c.TCPListeners...), nil
 }
 
 func (t TCPAddress) String() string {
@@ -18,10 +18,43 @@ type StdinFile struct {
 	io.Reader
 }
 
+// StdinPoller is implemented by an io.Reader passed to StdinFile, which can
+// wait for data to become available, for example a live stream.
+//
+// Poll returns true when data is ready to read, waiting up to timeoutMillis.
+// A negative timeoutMillis waits indefinitely.
+type StdinPoller interface {
+	Poll(timeoutMillis int32) (ready bool)
+}
+
 // Read implements the same method as documented on sys.File
+//
+// When the Reader returns no data without an error, and it implements
+// StdinPoller, this blocks until data is available instead of returning zero,
+// which the guest would otherwise interpret as EOF.
 func (f *StdinFile) Read(buf []byte) (int, experimentalsys.Errno) {
-	n, err := f.Reader.Read(buf)
-	return n, experimentalsys.UnwrapOSError(err)
+	for {
+		n, err := f.Reader.Read(buf)
+		if n != 0 || err != nil || len(buf) == 0 {
+			return n, experimentalsys.UnwrapOSError(err)
+		}
+		poller, ok := f.Reader.(StdinPoller)
+		if !ok {
+			return 0, 0
+		}
+		poller.Poll(-1)
+	}
+}
+
+// Poll implements the same method as documented on fsapi.File
+func (f *StdinFile) Poll(flag fsapi.Pflag, timeoutMillis int32) (ready bool, errno experimentalsys.Errno) {
+	if flag != fsapi.POLLIN {
+		return false, experimentalsys.ENOTSUP
+	}
+	if poller, ok := f.Reader.(StdinPoller); ok {
+		return poller.Poll(timeoutMillis), 0
+	}
+	return true, 0 // Unknown, so don't block the guest.
 }
 
 type writerFile struct {
@@ -36,6 +69,18 @@ func (f *writerFile) Write(buf []byte) (int, experimentalsys.Errno) {
 	return n, experimentalsys.UnwrapOSError(err)
 }
 
+// Close implements the same method as documented on sys.File
+//
+// Note: This doesn't close the underlying io.Writer, as it is owned by the
+// caller. However, buffered writers such as bufio.Writer are flushed, so that
+// no output is lost when the module is closed.
+func (f *writerFile) Close() experimentalsys.Errno {
+	if flusher, ok := f.w.(interface{ Flush() error }); ok {
+		return experimentalsys.UnwrapOSError(flusher.Flush())
+	}
+	return 0
+}
+
 // noopStdinFile is a fs.ModeDevice file for use implementing FdStdin. This is
 // safer than reading from os.DevNull as it can never overrun operating system
 // file descriptors.
@@ -81,6 +126,16 @@ func (noopStdioFile) IsDir() (bool, experimentalsys.Errno) {
 	return false, 0
 }
 
+// Pread implements the same method as documented on sys.File
+func (noopStdioFile) Pread([]byte, int64) (int, experimentalsys.Errno) {
+	return 0, experimentalsys.ESPIPE // stdio streams can't be positioned
+}
+
+// Pwrite implements the same method as documented on sys.File
+func (noopStdioFile) Pwrite([]byte, int64) (int, experimentalsys.Errno) {
+	return 0, experimentalsys.ESPIPE // stdio streams can't be positioned
+}
+
 // Close implements the same method as documented on sys.File
 func (noopStdioFile) Close() (errno experimentalsys.Errno) { return }
 
@@ -1,10 +1,17 @@
 package sys
 
 import (
+	"bufio"
+	"bytes"
+	"io"
 	"io/fs"
 	"os"
+	"strings"
 	"testing"
+	"testing/iotest"
 
+	experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
+	"github.com/tetratelabs/wazero/internal/fsapi"
 	"github.com/tetratelabs/wazero/internal/testing/require"
 )
 
@@ -136,3 +143,107 @@ func TestStdio(t *testing.T) {
 		}
 	}
 }
+
+func TestWriterFile_Close(t *testing.T) {
+	t.Run("flushes buffered writer", func(t *testing.T) {
+		var out bytes.Buffer
+		w := bufio.NewWriter(&out)
+		entry, err := stdioWriterFileEntry("stdout", w)
+		require.NoError(t, err)
+
+		n, errno := entry.File.Write([]byte("wazero"))
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, 6, n)
+		require.Zero(t, out.Len()) // still buffered
+
+		require.EqualErrno(t, 0, entry.File.Close())
+		require.Equal(t, "wazero", out.String())
+	})
+
+	t.Run("unbuffered writer", func(t *testing.T) {
+		var out bytes.Buffer
+		entry, err := stdioWriterFileEntry("stdout", &out)
+		require.NoError(t, err)
+
+		_, errno := entry.File.Write([]byte("wazero"))
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, "wazero", out.String())
+		require.EqualErrno(t, 0, entry.File.Close())
+	})
+}
+
+// pollingReader returns no data until Poll is called, like a live stream.
+type pollingReader struct {
+	data   []byte
+	ready  bool
+	polled []int32
+}
+
+// Read implements io.Reader
+func (r *pollingReader) Read(buf []byte) (int, error) {
+	if !r.ready {
+		return 0, nil
+	} else if len(r.data) == 0 {
+		return 0, io.EOF
+	}
+	n := copy(buf, r.data)
+	r.data = r.data[n:]
+	return n, nil
+}
+
+// Poll implements StdinPoller
+func (r *pollingReader) Poll(timeoutMillis int32) bool {
+	r.polled = append(r.polled, timeoutMillis)
+	if timeoutMillis < 0 {
+		r.ready = true
+	}
+	return r.ready
+}
+
+func TestStdinFile_Read_poller(t *testing.T) {
+	r := &pollingReader{data: []byte("wazero")}
+	f := &StdinFile{Reader: r}
+
+	buf := make([]byte, 10)
+	n, errno := f.Read(buf)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, "wazero", string(buf[:n]))
+	require.Equal(t, []int32{-1}, r.polled) // blocked until ready
+
+	// EOF is a zero-length read without an error.
+	n, errno = f.Read(buf)
+	require.EqualErrno(t, 0, errno)
+	require.Zero(t, n)
+	require.Equal(t, []int32{-1}, r.polled) // EOF doesn't poll
+
+	t.Run("without poller", func(t *testing.T) {
+		f := &StdinFile{Reader: iotest.ErrReader(nil)}
+		n, errno := f.Read(buf)
+		require.EqualErrno(t, 0, errno)
+		require.Zero(t, n)
+	})
+}
+
+func TestStdinFile_Poll(t *testing.T) {
+	r := &pollingReader{}
+	f := &StdinFile{Reader: r}
+
+	ready, errno := f.Poll(fsapi.POLLIN, 10)
+	require.EqualErrno(t, 0, errno)
+	require.False(t, ready)
+
+	ready, errno = f.Poll(fsapi.POLLIN, -1)
+	require.EqualErrno(t, 0, errno)
+	require.True(t, ready)
+	require.Equal(t, []int32{10, -1}, r.polled)
+
+	_, errno = f.Poll(fsapi.POLLOUT, 0)
+	require.EqualErrno(t, experimentalsys.ENOTSUP, errno)
+
+	t.Run("without poller", func(t *testing.T) {
+		f := &StdinFile{Reader: strings.NewReader("wazero")}
+		ready, errno := f.Poll(fsapi.POLLIN, 0)
+		require.EqualErrno(t, 0, errno)
+		require.True(t, ready)
+	})
+}
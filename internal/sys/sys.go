@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io"
 	"net"
+	"sync/atomic"
 	"time"
 
 	experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
@@ -26,6 +27,14 @@ type Context struct {
 	osyield            sys.Osyield
 	randSource         io.Reader
 	fsc                FSContext
+
+	// fuel is the remaining fuel when fuelLimited.
+	fuel        atomic.Uint64
+	fuelLimited bool
+
+	// maxCallDepth is the maximum nesting of calls into Wasm functions, or
+	// zero if unlimited.
+	maxCallDepth uint32
 }
 
 // Args is like os.Args and defaults to nil.
@@ -109,6 +118,50 @@ func (c *Context) RandSource() io.Reader {
 	return c.randSource
 }
 
+// SetFuel limits the module to the given amount of fuel.
+// See wazero.ModuleConfig WithFuel
+func (c *Context) SetFuel(fuel uint64) {
+	c.fuel.Store(fuel)
+	c.fuelLimited = true
+}
+
+// Fuel returns the remaining fuel and whether fuel is limited at all.
+func (c *Context) Fuel() (fuel uint64, limited bool) {
+	return c.fuel.Load(), c.fuelLimited
+}
+
+// ConsumeFuel consumes n units of fuel, returning false if fewer remain, in
+// which case the remaining fuel is consumed. This always returns true when
+// fuel is not limited.
+func (c *Context) ConsumeFuel(n uint64) bool {
+	if !c.fuelLimited {
+		return true
+	}
+	for {
+		fuel := c.fuel.Load()
+		enough := fuel >= n
+		remaining := uint64(0)
+		if enough {
+			remaining = fuel - n
+		}
+		if c.fuel.CompareAndSwap(fuel, remaining) {
+			return enough
+		}
+	}
+}
+
+// SetMaxCallDepth limits the nesting of calls into Wasm functions.
+// See wazero.ModuleConfig WithMaxCallDepth
+func (c *Context) SetMaxCallDepth(n uint32) {
+	c.maxCallDepth = n
+}
+
+// MaxCallDepth returns the maximum nesting of calls into Wasm functions, or
+// zero if unlimited.
+func (c *Context) MaxCallDepth() uint32 {
+	return c.maxCallDepth
+}
+
 // DefaultContext returns Context with no values set except a possible nil
 // sys.FS.
 //
@@ -19,6 +19,50 @@ func TestContext_WalltimeNanos(t *testing.T) {
 	require.Equal(t, int64(1640995200000000000), sysCtx.WalltimeNanos())
 }
 
+func TestContext_ConsumeFuel(t *testing.T) {
+	t.Run("unlimited", func(t *testing.T) {
+		sysCtx := DefaultContext(nil)
+
+		for i := 0; i < 10; i++ {
+			require.True(t, sysCtx.ConsumeFuel(1))
+		}
+		_, limited := sysCtx.Fuel()
+		require.False(t, limited)
+	})
+	t.Run("limited", func(t *testing.T) {
+		sysCtx := DefaultContext(nil)
+		sysCtx.SetFuel(2)
+
+		require.True(t, sysCtx.ConsumeFuel(1))
+		fuel, limited := sysCtx.Fuel()
+		require.True(t, limited)
+		require.Equal(t, uint64(1), fuel)
+
+		require.True(t, sysCtx.ConsumeFuel(1))
+		require.False(t, sysCtx.ConsumeFuel(1))
+		require.False(t, sysCtx.ConsumeFuel(1))
+		fuel, _ = sysCtx.Fuel()
+		require.Zero(t, fuel)
+	})
+	t.Run("more than remaining", func(t *testing.T) {
+		sysCtx := DefaultContext(nil)
+		sysCtx.SetFuel(5)
+
+		require.True(t, sysCtx.ConsumeFuel(3))
+		require.False(t, sysCtx.ConsumeFuel(3))
+		fuel, _ := sysCtx.Fuel()
+		require.Zero(t, fuel)
+	})
+}
+
+func TestContext_MaxCallDepth(t *testing.T) {
+	sysCtx := DefaultContext(nil)
+	require.Zero(t, sysCtx.MaxCallDepth())
+
+	sysCtx.SetMaxCallDepth(10)
+	require.Equal(t, uint32(10), sysCtx.MaxCallDepth())
+}
+
 func TestDefaultSysContext(t *testing.T) {
 	testFS := &sysfs.AdaptFS{FS: fstest.FS}
 
@@ -69,6 +69,25 @@ func TestRegularFileSetNonblock(t *testing.T) {
 	require.False(t, rF.IsNonblock())
 }
 
+func TestPipePreadPwrite(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("pipes on windows don't report ESPIPE")
+	}
+
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+	defer r.Close()
+	defer w.Close()
+
+	rF := newOsFile("", experimentalsys.O_RDONLY, 0, r)
+	_, errno := rF.Pread(make([]byte, 8), 0)
+	require.EqualErrno(t, experimentalsys.ESPIPE, errno)
+
+	wF := newOsFile("", experimentalsys.O_WRONLY, 0, w)
+	_, errno = wF.Pwrite([]byte("wazero"), 0)
+	require.EqualErrno(t, experimentalsys.ESPIPE, errno)
+}
+
 func TestReadFdNonblock(t *testing.T) {
 	// Test using os.Pipe as it is known to support non-blocking reads.
 	r, w, err := os.Pipe()
@@ -0,0 +1,615 @@
+package sysfs
+
+import (
+	"io"
+	"io/fs"
+	"math"
+	"sort"
+	"strings"
+
+	experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
+	"github.com/tetratelabs/wazero/internal/platform"
+	"github.com/tetratelabs/wazero/sys"
+)
+
+// NewMemFS returns an empty, writable in-memory sys.FS.
+//
+// Files can't grow beyond 1 GiB: writing or truncating past that size
+// returns EFBIG instead of allocating host memory.
+//
+// Note: This is not safe for concurrent use.
+func NewMemFS() experimentalsys.FS {
+	m := &memFS{}
+	m.root = m.newNode(fs.ModeDir | 0o777)
+	return m
+}
+
+// memFileMaxSize is the maximum size of a file in memFS. The guest controls
+// the offsets and sizes of files, so this bounds what it can allocate.
+const memFileMaxSize = 1 << 30
+
+// memFS is not exported because the root node and inode counter must be
+// maintained together.
+type memFS struct {
+	experimentalsys.UnimplementedFS
+
+	root *memNode
+	// lastIno is the last inode assigned by newNode.
+	lastIno sys.Inode
+}
+
+// memNode is a file or directory. Hard links share the same node.
+type memNode struct {
+	ino  sys.Inode
+	mode fs.FileMode
+	// nlink is the count of directory entries that refer to this node.
+	nlink uint64
+
+	atim, mtim, ctim sys.EpochNanos
+
+	// data is the content of a regular file.
+	data []byte
+	// children are the entries of a directory, or nil if a regular file.
+	children map[string]*memNode
+}
+
+func (n *memNode) isDir() bool {
+	return n.mode.IsDir()
+}
+
+func (n *memNode) stat() sys.Stat_t {
+	return sys.Stat_t{
+		Ino:   n.ino,
+		Mode:  n.mode,
+		Nlink: n.nlink,
+		Size:  int64(len(n.data)),
+		Atim:  n.atim,
+		Mtim:  n.mtim,
+		Ctim:  n.ctim,
+	}
+}
+
+// String implements fmt.Stringer
+func (m *memFS) String() string {
+	return "memfs"
+}
+
+func (m *memFS) newNode(mode fs.FileMode) *memNode {
+	m.lastIno++
+	now := memNow()
+	n := &memNode{ino: m.lastIno, mode: mode, nlink: 1, atim: now, mtim: now, ctim: now}
+	if mode.IsDir() {
+		n.children = map[string]*memNode{}
+	}
+	return n
+}
+
+func memNow() sys.EpochNanos {
+	sec, nsec := platform.Walltime()
+	return sec*1e9 + int64(nsec)
+}
+
+// splitPath returns the cleaned path components, which are empty for the
+// root directory.
+func splitPath(path string) ([]string, experimentalsys.Errno) {
+	path = cleanPath(path)
+	switch path {
+	case "", ".":
+		return nil, 0
+	}
+	if path == ".." || strings.HasPrefix(path, "../") {
+		return nil, experimentalsys.ENOENT // there's nothing above the root.
+	}
+	return strings.Split(path, "/"), 0
+}
+
+// lookup returns the node at the given path.
+func (m *memFS) lookup(path string) (*memNode, experimentalsys.Errno) {
+	names, errno := splitPath(path)
+	if errno != 0 {
+		return nil, errno
+	}
+	n := m.root
+	for _, name := range names {
+		if !n.isDir() {
+			return nil, experimentalsys.ENOTDIR
+		}
+		child, ok := n.children[name]
+		if !ok {
+			return nil, experimentalsys.ENOENT
+		}
+		n = child
+	}
+	return n, 0
+}
+
+// lookupParent returns the directory containing the given path and the base
+// name of the path. This returns EINVAL for the root directory.
+func (m *memFS) lookupParent(path string) (*memNode, string, experimentalsys.Errno) {
+	names, errno := splitPath(path)
+	if errno != 0 {
+		return nil, "", errno
+	} else if len(names) == 0 {
+		return nil, "", experimentalsys.EINVAL
+	}
+	dir, errno := m.lookup(strings.Join(names[:len(names)-1], "/"))
+	if errno != 0 {
+		return nil, "", errno
+	} else if !dir.isDir() {
+		return nil, "", experimentalsys.ENOTDIR
+	}
+	return dir, names[len(names)-1], 0
+}
+
+// OpenFile implements the same method as documented on sys.FS
+func (m *memFS) OpenFile(path string, flag experimentalsys.Oflag, perm fs.FileMode) (experimentalsys.File, experimentalsys.Errno) {
+	var readable, writable bool
+	// Mask the mutually exclusive bits as they determine write mode.
+	switch flag & (experimentalsys.O_RDONLY | experimentalsys.O_WRONLY | experimentalsys.O_RDWR) {
+	case experimentalsys.O_WRONLY:
+		writable = true
+	case experimentalsys.O_RDWR:
+		readable, writable = true, true
+	default: // sys.O_RDONLY (integer zero)
+		readable = true
+	}
+
+	n, errno := m.lookup(path)
+	switch errno {
+	case 0:
+		if flag&(experimentalsys.O_CREAT|experimentalsys.O_EXCL) == experimentalsys.O_CREAT|experimentalsys.O_EXCL {
+			return nil, experimentalsys.EEXIST
+		}
+	case experimentalsys.ENOENT:
+		if flag&experimentalsys.O_CREAT == 0 {
+			return nil, experimentalsys.ENOENT
+		}
+		dir, name, errno := m.lookupParent(path)
+		if errno != 0 {
+			return nil, errno
+		}
+		n = m.newNode(perm & fs.ModePerm)
+		dir.children[name] = n
+		dir.mtim = n.mtim
+	default:
+		return nil, errno
+	}
+
+	if n.isDir() {
+		if writable {
+			return nil, experimentalsys.EISDIR
+		}
+	} else if flag&experimentalsys.O_DIRECTORY != 0 {
+		return nil, experimentalsys.ENOTDIR
+	} else if writable && flag&experimentalsys.O_TRUNC != 0 {
+		n.data = n.data[:0]
+		n.mtim = memNow()
+	}
+
+	return &memFile{
+		node:     n,
+		readable: readable,
+		writable: writable,
+		append:   flag&experimentalsys.O_APPEND != 0,
+	}, 0
+}
+
+// Lstat implements the same method as documented on sys.FS
+func (m *memFS) Lstat(path string) (sys.Stat_t, experimentalsys.Errno) {
+	// There are no symbolic links, so Lstat is the same as Stat.
+	return m.Stat(path)
+}
+
+// Stat implements the same method as documented on sys.FS
+func (m *memFS) Stat(path string) (sys.Stat_t, experimentalsys.Errno) {
+	n, errno := m.lookup(path)
+	if errno != 0 {
+		return sys.Stat_t{}, errno
+	}
+	return n.stat(), 0
+}
+
+// Mkdir implements the same method as documented on sys.FS
+func (m *memFS) Mkdir(path string, perm fs.FileMode) experimentalsys.Errno {
+	if n, errno := m.lookup(path); errno == 0 {
+		if n.isDir() {
+			return experimentalsys.EEXIST
+		}
+		return experimentalsys.ENOTDIR
+	}
+	dir, name, errno := m.lookupParent(path)
+	if errno != 0 {
+		return errno
+	}
+	n := m.newNode(fs.ModeDir | perm&fs.ModePerm)
+	dir.children[name] = n
+	dir.mtim = n.mtim
+	return 0
+}
+
+// Chmod implements the same method as documented on sys.FS
+func (m *memFS) Chmod(path string, perm fs.FileMode) experimentalsys.Errno {
+	n, errno := m.lookup(path)
+	if errno != 0 {
+		return errno
+	}
+	n.mode = n.mode&fs.ModeType | perm&fs.ModePerm
+	n.ctim = memNow()
+	return 0
+}
+
+// Rename implements the same method as documented on sys.FS
+func (m *memFS) Rename(from, to string) experimentalsys.Errno {
+	fromDir, fromName, errno := m.lookupParent(from)
+	if errno != 0 {
+		return errno
+	}
+	n, ok := fromDir.children[fromName]
+	if !ok {
+		return experimentalsys.ENOENT
+	}
+	toDir, toName, errno := m.lookupParent(to)
+	if errno != 0 {
+		return errno
+	}
+
+	// A directory can't be moved into itself or one of its descendants.
+	if n.isDir() && containsDir(n, toDir) {
+		return experimentalsys.EINVAL
+	}
+
+	if existing, ok := toDir.children[toName]; ok {
+		if existing == n {
+			return 0 // renaming to itself, or a hard link to the same node.
+		}
+		switch {
+		case n.isDir() && !existing.isDir():
+			return experimentalsys.ENOTDIR
+		case !n.isDir() && existing.isDir():
+			return experimentalsys.EISDIR
+		case existing.isDir() && len(existing.children) > 0:
+			return experimentalsys.ENOTEMPTY
+		}
+		existing.nlink--
+	}
+
+	delete(fromDir.children, fromName)
+	toDir.children[toName] = n
+	now := memNow()
+	fromDir.mtim, toDir.mtim, n.ctim = now, now, now
+	return 0
+}
+
+// containsDir returns true if target is dir or one of its descendants.
+func containsDir(dir, target *memNode) bool {
+	if dir == target {
+		return true
+	}
+	for _, child := range dir.children {
+		if child.isDir() && containsDir(child, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// Rmdir implements the same method as documented on sys.FS
+func (m *memFS) Rmdir(path string) experimentalsys.Errno {
+	dir, name, errno := m.lookupParent(path)
+	if errno != 0 {
+		return errno
+	}
+	n, ok := dir.children[name]
+	if !ok {
+		return experimentalsys.ENOENT
+	} else if !n.isDir() {
+		return experimentalsys.ENOTDIR
+	} else if len(n.children) > 0 {
+		return experimentalsys.ENOTEMPTY
+	}
+	delete(dir.children, name)
+	n.nlink = 0
+	dir.mtim = memNow()
+	return 0
+}
+
+// Unlink implements the same method as documented on sys.FS
+func (m *memFS) Unlink(path string) experimentalsys.Errno {
+	dir, name, errno := m.lookupParent(path)
+	if errno != 0 {
+		return errno
+	}
+	n, ok := dir.children[name]
+	if !ok {
+		return experimentalsys.ENOENT
+	} else if n.isDir() {
+		return experimentalsys.EISDIR
+	}
+	delete(dir.children, name)
+	n.nlink--
+	dir.mtim = memNow()
+	return 0
+}
+
+// Link implements the same method as documented on sys.FS
+func (m *memFS) Link(oldPath, newPath string) experimentalsys.Errno {
+	n, errno := m.lookup(oldPath)
+	if errno != 0 {
+		return errno
+	} else if n.isDir() {
+		return experimentalsys.EPERM
+	}
+	dir, name, errno := m.lookupParent(newPath)
+	if errno != 0 {
+		return errno
+	}
+	if existing, ok := dir.children[name]; ok {
+		if existing.isDir() {
+			return experimentalsys.EISDIR
+		}
+		return experimentalsys.EEXIST
+	}
+	dir.children[name] = n
+	n.nlink++
+	now := memNow()
+	dir.mtim, n.ctim = now, now
+	return 0
+}
+
+// Utimens implements the same method as documented on sys.FS
+func (m *memFS) Utimens(path string, atim, mtim int64) experimentalsys.Errno {
+	n, errno := m.lookup(path)
+	if errno != 0 {
+		return errno
+	}
+	n.utimens(atim, mtim)
+	return 0
+}
+
+func (n *memNode) utimens(atim, mtim int64) {
+	if atim != experimentalsys.UTIME_OMIT {
+		n.atim = atim
+	}
+	if mtim != experimentalsys.UTIME_OMIT {
+		n.mtim = mtim
+	}
+	n.ctim = memNow()
+}
+
+// memFile is an open file or directory in a memFS.
+type memFile struct {
+	experimentalsys.UnimplementedFile
+
+	node               *memNode
+	readable, writable bool
+	append             bool
+	closed             bool
+
+	// offset is the position of the next Read or Write.
+	offset int64
+
+	// dirents are the remaining entries to return from Readdir. This is a
+	// snapshot taken on the first Readdir, and reset by Seek to zero.
+	dirents []experimentalsys.Dirent
+	// direntsRead is true if dirents were already initialized.
+	direntsRead bool
+}
+
+// Ino implements the same method as documented on sys.File
+func (f *memFile) Ino() (sys.Inode, experimentalsys.Errno) {
+	return f.node.ino, 0
+}
+
+// IsDir implements the same method as documented on sys.File
+func (f *memFile) IsDir() (bool, experimentalsys.Errno) {
+	return f.node.isDir(), 0
+}
+
+// IsAppend implements the same method as documented on sys.File
+func (f *memFile) IsAppend() bool {
+	return f.append
+}
+
+// SetAppend implements the same method as documented on sys.File
+func (f *memFile) SetAppend(enable bool) experimentalsys.Errno {
+	f.append = enable
+	return 0
+}
+
+// Stat implements the same method as documented on sys.File
+func (f *memFile) Stat() (sys.Stat_t, experimentalsys.Errno) {
+	if f.closed {
+		return sys.Stat_t{}, experimentalsys.EBADF
+	}
+	return f.node.stat(), 0
+}
+
+// Read implements the same method as documented on sys.File
+func (f *memFile) Read(buf []byte) (n int, errno experimentalsys.Errno) {
+	if n, errno = f.Pread(buf, f.offset); errno == 0 {
+		f.offset += int64(n)
+	}
+	return
+}
+
+// Pread implements the same method as documented on sys.File
+func (f *memFile) Pread(buf []byte, off int64) (int, experimentalsys.Errno) {
+	if errno := f.checkAccess(f.readable); errno != 0 {
+		return 0, errno
+	} else if off < 0 {
+		return 0, experimentalsys.EINVAL
+	}
+	data := f.node.data
+	if off >= int64(len(data)) {
+		return 0, 0 // EOF
+	}
+	return copy(buf, data[off:]), 0
+}
+
+// Seek implements the same method as documented on sys.File
+func (f *memFile) Seek(offset int64, whence int) (int64, experimentalsys.Errno) {
+	if f.closed {
+		return 0, experimentalsys.EBADF
+	}
+
+	if f.node.isDir() {
+		if offset != 0 || whence != io.SeekStart {
+			return 0, experimentalsys.EINVAL
+		}
+		f.dirents, f.direntsRead = nil, false
+		return 0, 0
+	}
+
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += f.offset
+	case io.SeekEnd:
+		offset += int64(len(f.node.data))
+	default:
+		return 0, experimentalsys.EINVAL
+	}
+	if offset < 0 {
+		return 0, experimentalsys.EINVAL
+	}
+	f.offset = offset
+	return offset, 0
+}
+
+// Readdir implements the same method as documented on sys.File
+func (f *memFile) Readdir(n int) (dirents []experimentalsys.Dirent, errno experimentalsys.Errno) {
+	if f.closed || !f.node.isDir() {
+		return nil, experimentalsys.EBADF
+	}
+
+	if !f.direntsRead {
+		f.direntsRead = true
+		names := make([]string, 0, len(f.node.children))
+		for name := range f.node.children {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			child := f.node.children[name]
+			f.dirents = append(f.dirents, experimentalsys.Dirent{
+				Ino:  child.ino,
+				Name: name,
+				Type: child.mode.Type(),
+			})
+		}
+	}
+
+	if n <= 0 || n > len(f.dirents) {
+		n = len(f.dirents)
+	}
+	dirents, f.dirents = f.dirents[:n:n], f.dirents[n:]
+	return
+}
+
+// Write implements the same method as documented on sys.File
+func (f *memFile) Write(buf []byte) (n int, errno experimentalsys.Errno) {
+	if f.append {
+		f.offset = int64(len(f.node.data))
+	}
+	if n, errno = f.Pwrite(buf, f.offset); errno == 0 {
+		f.offset += int64(n)
+	}
+	return
+}
+
+// Pwrite implements the same method as documented on sys.File
+func (f *memFile) Pwrite(buf []byte, off int64) (int, experimentalsys.Errno) {
+	if errno := f.checkAccess(f.writable); errno != 0 {
+		return 0, errno
+	} else if off < 0 {
+		return 0, experimentalsys.EINVAL
+	}
+	if len(buf) == 0 {
+		return 0, 0
+	}
+	if off > math.MaxInt64-int64(len(buf)) {
+		return 0, experimentalsys.EINVAL
+	}
+	end := off + int64(len(buf))
+	if end > memFileMaxSize {
+		return 0, experimentalsys.EFBIG
+	} else if end > int64(len(f.node.data)) {
+		f.node.resize(end)
+	}
+	copy(f.node.data[off:], buf)
+	f.node.mtim = memNow()
+	return len(buf), 0
+}
+
+// Truncate implements the same method as documented on sys.File
+func (f *memFile) Truncate(size int64) experimentalsys.Errno {
+	if errno := f.checkAccess(f.writable); errno != 0 {
+		return errno
+	} else if size < 0 {
+		return experimentalsys.EINVAL
+	} else if size > memFileMaxSize {
+		return experimentalsys.EFBIG
+	}
+	f.node.resize(size)
+	f.node.mtim = memNow()
+	return 0
+}
+
+// resize grows or shrinks the data to the given size, filling any new bytes
+// with zero. The caller must ensure size is within memFileMaxSize.
+func (n *memNode) resize(size int64) {
+	if size <= int64(len(n.data)) {
+		n.data = n.data[:size]
+		return
+	}
+	if size <= int64(cap(n.data)) {
+		prev := len(n.data)
+		n.data = n.data[:size]
+		for i := prev; i < len(n.data); i++ {
+			n.data[i] = 0
+		}
+		return
+	}
+	data := make([]byte, size)
+	copy(data, n.data)
+	n.data = data
+}
+
+// checkAccess returns EBADF if the file is closed or not opened with the
+// required access, and EISDIR if it is a directory.
+func (f *memFile) checkAccess(ok bool) experimentalsys.Errno {
+	if f.closed || !ok {
+		return experimentalsys.EBADF
+	} else if f.node.isDir() {
+		return experimentalsys.EISDIR
+	}
+	return 0
+}
+
+// Sync implements the same method as documented on sys.File
+func (f *memFile) Sync() experimentalsys.Errno {
+	if f.closed {
+		return experimentalsys.EBADF
+	}
+	return 0
+}
+
+// Datasync implements the same method as documented on sys.File
+func (f *memFile) Datasync() experimentalsys.Errno {
+	return f.Sync()
+}
+
+// Utimens implements the same method as documented on sys.File
+func (f *memFile) Utimens(atim, mtim int64) experimentalsys.Errno {
+	if f.closed {
+		return experimentalsys.EBADF
+	}
+	f.node.utimens(atim, mtim)
+	return 0
+}
+
+// Close implements the same method as documented on sys.File
+func (f *memFile) Close() experimentalsys.Errno {
+	f.closed = true
+	return 0
+}
@@ -0,0 +1,313 @@
+package sysfs
+
+import (
+	"io"
+	"io/fs"
+	"math"
+	"sort"
+	"testing"
+
+	experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
+	"github.com/tetratelabs/wazero/internal/fstest"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+// writeMemTestFiles writes files defined in fstest.FS to the given memFS.
+func writeMemTestFiles(t *testing.T, testFS experimentalsys.FS) {
+	names := make([]string, 0, len(fstest.FS))
+	for name := range fstest.FS {
+		names = append(names, name)
+	}
+	sort.Strings(names) // parent directories before their contents.
+
+	for _, name := range names {
+		file := fstest.FS[name]
+		if name == "." {
+			continue
+		} else if file.Mode.IsDir() {
+			require.EqualErrno(t, 0, testFS.Mkdir(name, file.Mode.Perm()))
+			continue
+		}
+		f, errno := testFS.OpenFile(name, experimentalsys.O_WRONLY|experimentalsys.O_CREAT, file.Mode.Perm())
+		require.EqualErrno(t, 0, errno)
+		_, errno = f.Write(file.Data)
+		require.EqualErrno(t, 0, errno)
+		require.EqualErrno(t, 0, f.Close())
+	}
+}
+
+func TestMemFS_OpenFile(t *testing.T) {
+	testFS := NewMemFS()
+	writeMemTestFiles(t, testFS)
+
+	testOpen_Read(t, testFS, true, true)
+
+	t.Run("O_EXCL", func(t *testing.T) {
+		_, errno := testFS.OpenFile("animals.txt", experimentalsys.O_RDWR|experimentalsys.O_CREAT|experimentalsys.O_EXCL, 0o644)
+		require.EqualErrno(t, experimentalsys.EEXIST, errno)
+	})
+
+	t.Run("O_DIRECTORY on a file is ENOTDIR", func(t *testing.T) {
+		_, errno := testFS.OpenFile("animals.txt", experimentalsys.O_RDONLY|experimentalsys.O_DIRECTORY, 0)
+		require.EqualErrno(t, experimentalsys.ENOTDIR, errno)
+	})
+
+	t.Run("O_CREAT in a missing directory is ENOENT", func(t *testing.T) {
+		_, errno := testFS.OpenFile("nope/file", experimentalsys.O_RDWR|experimentalsys.O_CREAT, 0o644)
+		require.EqualErrno(t, experimentalsys.ENOENT, errno)
+	})
+
+	t.Run("path through a file is ENOTDIR", func(t *testing.T) {
+		_, errno := testFS.OpenFile("animals.txt/file", experimentalsys.O_RDONLY, 0)
+		require.EqualErrno(t, experimentalsys.ENOTDIR, errno)
+	})
+
+	t.Run("creates a file visible to Readdir", func(t *testing.T) {
+		f, errno := testFS.OpenFile("emptydir/new.txt", experimentalsys.O_RDWR|experimentalsys.O_CREAT, 0o600)
+		require.EqualErrno(t, 0, errno)
+		require.EqualErrno(t, 0, f.Close())
+
+		d, errno := testFS.OpenFile("emptydir", experimentalsys.O_RDONLY, 0)
+		require.EqualErrno(t, 0, errno)
+		defer d.Close()
+
+		dirents, errno := d.Readdir(-1)
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, 1, len(dirents))
+		require.Equal(t, "new.txt", dirents[0].Name)
+
+		// Seek to zero resets the directory.
+		_, errno = d.Seek(0, io.SeekStart)
+		require.EqualErrno(t, 0, errno)
+		dirents, errno = d.Readdir(-1)
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, 1, len(dirents))
+
+		require.EqualErrno(t, 0, testFS.Unlink("emptydir/new.txt"))
+	})
+}
+
+func TestMemFS_Write(t *testing.T) {
+	testFS := NewMemFS()
+
+	f, errno := testFS.OpenFile("file", experimentalsys.O_RDWR|experimentalsys.O_CREAT, 0o600)
+	require.EqualErrno(t, 0, errno)
+	defer f.Close()
+
+	requireContents := func(t *testing.T, expected string) {
+		st, errno := f.Stat()
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, int64(len(expected)), st.Size)
+
+		buf := make([]byte, len(expected)+1)
+		n, errno := f.Pread(buf, 0)
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, expected, string(buf[:n]))
+	}
+
+	n, errno := f.Write([]byte("wazero"))
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, 6, n)
+	requireContents(t, "wazero")
+
+	t.Run("Write continues from the offset", func(t *testing.T) {
+		_, errno := f.Seek(2, io.SeekStart)
+		require.EqualErrno(t, 0, errno)
+		_, errno = f.Write([]byte("Z"))
+		require.EqualErrno(t, 0, errno)
+		requireContents(t, "waZero")
+
+		offset, errno := f.Seek(0, io.SeekCurrent)
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, int64(3), offset)
+	})
+
+	t.Run("Pwrite past the end fills with zero", func(t *testing.T) {
+		_, errno := f.Pwrite([]byte("!"), 8)
+		require.EqualErrno(t, 0, errno)
+		requireContents(t, "waZero\x00\x00!")
+
+		// Pwrite doesn't change the offset.
+		offset, errno := f.Seek(0, io.SeekCurrent)
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, int64(3), offset)
+	})
+
+	t.Run("Pwrite rejects invalid or too large offsets", func(t *testing.T) {
+		_, errno := f.Pwrite([]byte("!"), -1)
+		require.EqualErrno(t, experimentalsys.EINVAL, errno)
+		_, errno = f.Pwrite([]byte("!"), math.MaxInt64)
+		require.EqualErrno(t, experimentalsys.EINVAL, errno)
+		_, errno = f.Pwrite([]byte("!"), memFileMaxSize)
+		require.EqualErrno(t, experimentalsys.EFBIG, errno)
+		requireContents(t, "waZero\x00\x00!")
+	})
+
+	t.Run("Truncate", func(t *testing.T) {
+		require.EqualErrno(t, 0, f.Truncate(2))
+		requireContents(t, "wa")
+
+		// Growing after shrinking must not expose the old bytes.
+		require.EqualErrno(t, 0, f.Truncate(4))
+		requireContents(t, "wa\x00\x00")
+
+		require.EqualErrno(t, experimentalsys.EINVAL, f.Truncate(-1))
+		require.EqualErrno(t, experimentalsys.EFBIG, f.Truncate(memFileMaxSize+1))
+		requireContents(t, "wa\x00\x00")
+	})
+
+	t.Run("O_APPEND", func(t *testing.T) {
+		a, errno := testFS.OpenFile("file", experimentalsys.O_WRONLY|experimentalsys.O_APPEND, 0)
+		require.EqualErrno(t, 0, errno)
+		defer a.Close()
+
+		_, errno = a.Write([]byte("!"))
+		require.EqualErrno(t, 0, errno)
+		requireContents(t, "wa\x00\x00!")
+	})
+
+	t.Run("O_TRUNC", func(t *testing.T) {
+		tr, errno := testFS.OpenFile("file", experimentalsys.O_WRONLY|experimentalsys.O_TRUNC, 0)
+		require.EqualErrno(t, 0, errno)
+		require.EqualErrno(t, 0, tr.Close())
+		requireContents(t, "")
+	})
+
+	t.Run("closed", func(t *testing.T) {
+		c, errno := testFS.OpenFile("file", experimentalsys.O_RDWR, 0)
+		require.EqualErrno(t, 0, errno)
+		require.EqualErrno(t, 0, c.Close())
+
+		_, errno = c.Write([]byte("!"))
+		require.EqualErrno(t, experimentalsys.EBADF, errno)
+		_, errno = c.Read(make([]byte, 1))
+		require.EqualErrno(t, experimentalsys.EBADF, errno)
+	})
+}
+
+func TestMemFS_Mkdir(t *testing.T) {
+	testFS := NewMemFS()
+
+	require.EqualErrno(t, 0, testFS.Mkdir("dir", 0o700))
+	st, errno := testFS.Stat("dir")
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, fs.ModeDir|0o700, st.Mode)
+
+	require.EqualErrno(t, experimentalsys.EEXIST, testFS.Mkdir("dir", 0o700))
+	require.EqualErrno(t, experimentalsys.ENOENT, testFS.Mkdir("nope/dir", 0o700))
+
+	f, errno := testFS.OpenFile("file", experimentalsys.O_RDWR|experimentalsys.O_CREAT, 0o600)
+	require.EqualErrno(t, 0, errno)
+	require.EqualErrno(t, 0, f.Close())
+	require.EqualErrno(t, experimentalsys.ENOTDIR, testFS.Mkdir("file", 0o700))
+}
+
+func TestMemFS_Rename(t *testing.T) {
+	testFS := NewMemFS()
+	writeMemTestFiles(t, testFS)
+
+	require.EqualErrno(t, 0, testFS.Rename("animals.txt", "sub/animals.txt"))
+	_, errno := testFS.Stat("animals.txt")
+	require.EqualErrno(t, experimentalsys.ENOENT, errno)
+	st, errno := testFS.Stat("sub/animals.txt")
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, int64(len(fstest.FS["animals.txt"].Data)), st.Size)
+
+	require.EqualErrno(t, 0, testFS.Rename("emptydir", "sub/emptydir"))
+
+	tests := []struct {
+		name, from, to string
+		expectedErrno  experimentalsys.Errno
+	}{
+		{name: "from doesn't exist", from: "nope", to: "sub/nope", expectedErrno: experimentalsys.ENOENT},
+		{name: "dir to file", from: "dir", to: "empty.txt", expectedErrno: experimentalsys.ENOTDIR},
+		{name: "file to dir", from: "empty.txt", to: "dir", expectedErrno: experimentalsys.EISDIR},
+		{name: "dir to non-empty dir", from: "sub/emptydir", to: "dir", expectedErrno: experimentalsys.ENOTEMPTY},
+		{name: "dir into itself", from: "dir", to: "dir/a-/dir", expectedErrno: experimentalsys.EINVAL},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			require.EqualErrno(t, tc.expectedErrno, testFS.Rename(tc.from, tc.to))
+		})
+	}
+}
+
+func TestMemFS_Rmdir(t *testing.T) {
+	testFS := NewMemFS()
+	writeMemTestFiles(t, testFS)
+
+	require.EqualErrno(t, experimentalsys.ENOTEMPTY, testFS.Rmdir("sub"))
+	require.EqualErrno(t, experimentalsys.ENOTDIR, testFS.Rmdir("empty.txt"))
+	require.EqualErrno(t, experimentalsys.ENOENT, testFS.Rmdir("nope"))
+
+	require.EqualErrno(t, 0, testFS.Rmdir("emptydir"))
+	_, errno := testFS.Stat("emptydir")
+	require.EqualErrno(t, experimentalsys.ENOENT, errno)
+}
+
+func TestMemFS_Unlink(t *testing.T) {
+	testFS := NewMemFS()
+	writeMemTestFiles(t, testFS)
+
+	require.EqualErrno(t, experimentalsys.EISDIR, testFS.Unlink("sub"))
+	require.EqualErrno(t, experimentalsys.ENOENT, testFS.Unlink("nope"))
+
+	// An open file remains readable after it is unlinked.
+	f, errno := testFS.OpenFile("sub/test.txt", experimentalsys.O_RDONLY, 0)
+	require.EqualErrno(t, 0, errno)
+	defer f.Close()
+
+	require.EqualErrno(t, 0, testFS.Unlink("sub/test.txt"))
+	_, errno = testFS.Stat("sub/test.txt")
+	require.EqualErrno(t, experimentalsys.ENOENT, errno)
+
+	b, err := io.ReadAll(&memFileReader{f})
+	require.NoError(t, err)
+	require.Equal(t, fstest.FS["sub/test.txt"].Data, b)
+}
+
+func TestMemFS_Link(t *testing.T) {
+	testFS := NewMemFS()
+	writeMemTestFiles(t, testFS)
+
+	require.EqualErrno(t, 0, testFS.Link("animals.txt", "dir/animals.txt"))
+	st, errno := testFS.Stat("dir/animals.txt")
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, uint64(2), st.Nlink)
+
+	require.EqualErrno(t, experimentalsys.EEXIST, testFS.Link("animals.txt", "empty.txt"))
+	require.EqualErrno(t, experimentalsys.EPERM, testFS.Link("sub", "sub2"))
+}
+
+func TestMemFS_Utimens(t *testing.T) {
+	testFS := NewMemFS()
+	writeMemTestFiles(t, testFS)
+
+	require.EqualErrno(t, 0, testFS.Utimens("animals.txt", 1, experimentalsys.UTIME_OMIT))
+	before, errno := testFS.Stat("animals.txt")
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, int64(1), before.Atim)
+
+	require.EqualErrno(t, 0, testFS.Utimens("animals.txt", experimentalsys.UTIME_OMIT, 2))
+	after, errno := testFS.Stat("animals.txt")
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, int64(1), after.Atim)
+	require.Equal(t, int64(2), after.Mtim)
+}
+
+// memFileReader adapts a sys.File to io.Reader.
+type memFileReader struct {
+	f experimentalsys.File
+}
+
+func (r *memFileReader) Read(p []byte) (int, error) {
+	n, errno := r.f.Read(p)
+	if errno != 0 {
+		return n, errno
+	} else if n == 0 && len(p) > 0 {
+		return 0, io.EOF
+	}
+	return n, nil
+}
@@ -5,6 +5,12 @@ import (
 )
 
 func encodeConstantExpression(expr wasm.ConstantExpression) (ret []byte) {
+	if wasm.IsExtendedConstOpcode(expr.Opcode) {
+		// The last instruction is held as the opcode, and preceded by the other instructions.
+		ret = append(ret, expr.Data...)
+		ret = append(ret, expr.Opcode)
+		return append(ret, wasm.OpcodeEnd)
+	}
 	ret = append(ret, expr.Opcode)
 	ret = append(ret, expr.Data...)
 	ret = append(ret, wasm.OpcodeEnd)
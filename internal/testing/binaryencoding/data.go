@@ -6,9 +6,12 @@ import (
 )
 
 func encodeDataSegment(d *wasm.DataSegment) (ret []byte) {
-	// Currently multiple memories are not supported.
 	if d.Passive {
 		ret = append(ret, leb128.EncodeInt32(1)...)
+	} else if d.MemoryIndex != 0 {
+		ret = append(ret, leb128.EncodeInt32(2)...) // active segment with memory index
+		ret = append(ret, leb128.EncodeUint32(d.MemoryIndex)...)
+		ret = append(ret, encodeConstantExpression(d.OffsetExpression)...)
 	} else {
 		ret = append(ret, leb128.EncodeInt32(0)...) // active segment
 		ret = append(ret, encodeConstantExpression(d.OffsetExpression)...)
@@ -21,17 +21,23 @@ func ensureElementKindFuncRef(r *bytes.Reader) error {
 
 // encodeCode returns the wasm.ElementSegment encoded in WebAssembly 1.0 (20191205) Binary Format.
 //
+// Passive and declarative segments are encoded as in WebAssembly 2.0, limited to function indexes.
+//
 // https://www.w3.org/TR/2019/REC-wasm-core-1-20191205/#element-section%E2%91%A0
+// https://www.w3.org/TR/2022/WD-wasm-core-2-20220419/binary/modules.html#element-section
 func encodeElement(e *wasm.ElementSegment) (ret []byte) {
-	if e.Mode == wasm.ElementModeActive {
+	switch e.Mode {
+	case wasm.ElementModeActive:
 		ret = append(ret, leb128.EncodeInt32(int32(e.TableIndex))...)
 		ret = append(ret, encodeConstantExpression(e.OffsetExpr)...)
-		ret = append(ret, leb128.EncodeUint32(uint32(len(e.Init)))...)
-		for _, idx := range e.Init {
-			ret = append(ret, leb128.EncodeInt32(int32(idx))...)
-		}
-	} else {
-		panic("TODO: support encoding for non-active elements in bulk-memory-operations proposal")
+	case wasm.ElementModePassive:
+		ret = append(ret, 0x1, 0x0) // prefix, ElemKind
+	case wasm.ElementModeDeclarative:
+		ret = append(ret, 0x3, 0x0) // prefix, ElemKind
+	}
+	ret = append(ret, leb128.EncodeUint32(uint32(len(e.Init)))...)
+	for _, idx := range e.Init {
+		ret = append(ret, leb128.EncodeInt32(int32(idx))...)
 	}
 	return
 }
@@ -5,9 +5,45 @@ import (
 	"testing"
 
 	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasm"
 )
 
 func Test_ensureElementKindFuncRef(t *testing.T) {
 	require.NoError(t, ensureElementKindFuncRef(bytes.NewReader([]byte{0x0})))
 	require.Error(t, ensureElementKindFuncRef(bytes.NewReader([]byte{0x1})))
 }
+
+func Test_encodeElement(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    *wasm.ElementSegment
+		expected []byte
+	}{
+		{
+			name: "active",
+			input: &wasm.ElementSegment{
+				OffsetExpr: wasm.ConstantExpression{Opcode: wasm.OpcodeI32Const, Data: []byte{1}},
+				Init:       []wasm.Index{0, 2},
+				Type:       wasm.RefTypeFuncref,
+			},
+			expected: []byte{0x0, wasm.OpcodeI32Const, 1, wasm.OpcodeEnd, 2, 0, 2},
+		},
+		{
+			name:     "passive",
+			input:    &wasm.ElementSegment{Init: []wasm.Index{0, 2}, Type: wasm.RefTypeFuncref, Mode: wasm.ElementModePassive},
+			expected: []byte{0x1, 0x0, 2, 0, 2},
+		},
+		{
+			name:     "declarative",
+			input:    &wasm.ElementSegment{Init: []wasm.Index{1}, Type: wasm.RefTypeFuncref, Mode: wasm.ElementModeDeclarative},
+			expected: []byte{0x3, 0x0, 1, 1},
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expected, encodeElement(tc.input))
+		})
+	}
+}
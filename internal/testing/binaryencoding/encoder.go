@@ -25,7 +25,10 @@ func EncodeModule(m *wasm.Module) (bytes []byte) {
 		bytes = append(bytes, encodeTableSection(m.TableSection)...)
 	}
 	if m.SectionElementCount(wasm.SectionIDMemory) > 0 {
-		bytes = append(bytes, encodeMemorySection(m.MemorySection)...)
+		bytes = append(bytes, encodeMemorySection(m.MemorySection, m.AdditionalMemorySection)...)
+	}
+	if m.SectionElementCount(wasm.SectionIDTag) > 0 {
+		bytes = append(bytes, encodeTagSection(m.TagSection)...)
 	}
 	if m.SectionElementCount(wasm.SectionIDGlobal) > 0 {
 		bytes = append(bytes, encodeGlobalSection(m.GlobalSection)...)
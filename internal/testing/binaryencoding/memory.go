@@ -12,5 +12,13 @@ func EncodeMemory(i *wasm.Memory) []byte {
 	if !i.IsMaxEncoded {
 		maxPtr = nil
 	}
-	return EncodeLimitsType(i.Min, maxPtr)
+	ret := EncodeLimitsType(i.Min, maxPtr)
+	if i.IsShared {
+		ret[0] = 0x03 // the flag of a shared memory, which always has a max.
+	}
+	if i.Is64 {
+		// The flag of a 64-bit memory. The limits are the same in LEB128 regardless of their width.
+		ret[0] |= 0x04
+	}
+	return ret
 }
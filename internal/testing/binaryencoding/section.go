@@ -51,6 +51,19 @@ func EncodeFunctionSection(typeIndices []wasm.Index) []byte {
 	return encodeSection(wasm.SectionIDFunction, contents)
 }
 
+// encodeTagSection encodes a wasm.SectionIDTag for the type indices of module-defined tags, as defined by the
+// exception-handling proposal.
+//
+// See https://github.com/WebAssembly/exception-handling/blob/main/proposals/exception-handling/legacy/Exceptions.md#tag-section
+func encodeTagSection(typeIndices []wasm.Index) []byte {
+	contents := leb128.EncodeUint32(uint32(len(typeIndices)))
+	for _, index := range typeIndices {
+		contents = append(contents, 0) // exception attribute
+		contents = append(contents, leb128.EncodeUint32(index)...)
+	}
+	return encodeSection(wasm.SectionIDTag, contents)
+}
+
 // encodeCodeSection encodes a wasm.SectionIDCode for the module-defined function in WebAssembly 1.0 (20191205)
 // Binary Format.
 //
@@ -84,8 +97,18 @@ func encodeTableSection(tables []wasm.Table) []byte {
 //
 // See EncodeMemory
 // See https://www.w3.org/TR/2019/REC-wasm-core-1-20191205/#memory-section%E2%91%A0
-func encodeMemorySection(memory *wasm.Memory) []byte {
-	contents := append([]byte{1}, EncodeMemory(memory)...)
+func encodeMemorySection(memory *wasm.Memory, additional []wasm.Memory) []byte {
+	count := uint32(len(additional))
+	if memory != nil {
+		count++
+	}
+	contents := leb128.EncodeUint32(count)
+	if memory != nil {
+		contents = append(contents, EncodeMemory(memory)...)
+	}
+	for i := range additional {
+		contents = append(contents, EncodeMemory(&additional[i])...)
+	}
 	return encodeSection(wasm.SectionIDMemory, contents)
 }
 
@@ -278,6 +278,8 @@ func ToErrno(errno sys.Errno) Errno {
 		return ErrnoExist
 	case sys.EFAULT:
 		return ErrnoFault
+	case sys.EFBIG:
+		return ErrnoFbig
 	case sys.EINTR:
 		return ErrnoIntr
 	case sys.EINVAL:
@@ -308,6 +310,8 @@ func ToErrno(errno sys.Errno) Errno {
 		return ErrnoPerm
 	case sys.EROFS:
 		return ErrnoRofs
+	case sys.ESPIPE:
+		return ErrnoSpipe
 	default:
 		return ErrnoIo
 	}
@@ -41,6 +41,11 @@ func TestToErrno(t *testing.T) {
 			input:    sys.EFAULT,
 			expected: ErrnoFault,
 		},
+		{
+			name:     "sys.EFBIG",
+			input:    sys.EFBIG,
+			expected: ErrnoFbig,
+		},
 		{
 			name:     "sys.EINTR",
 			input:    sys.EINTR,
@@ -111,6 +116,11 @@ func TestToErrno(t *testing.T) {
 			input:    sys.EROFS,
 			expected: ErrnoRofs,
 		},
+		{
+			name:     "sys.ESPIPE",
+			input:    sys.ESPIPE,
+			expected: ErrnoSpipe,
+		},
 		{
 			name:     "sys.EqualErrno unexpected == ErrnoIo",
 			input:    sys.Errno(0xfe),
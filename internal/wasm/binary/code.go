@@ -4,7 +4,6 @@ import (
 	"bytes"
 	"fmt"
 	"io"
-	"math"
 
 	"github.com/tetratelabs/wazero/internal/leb128"
 	"github.com/tetratelabs/wazero/internal/wasm"
@@ -53,8 +52,8 @@ func decodeCode(r *bytes.Reader, codeSectionStart uint64, ret *wasm.Code) (err e
 		}
 	}
 
-	if sum > math.MaxUint32 {
-		return fmt.Errorf("too many locals: %d", sum)
+	if sum > uint64(wasm.MaximumLocals) {
+		return fmt.Errorf("too many locals: %d given with limit %d", sum, wasm.MaximumLocals)
 	}
 
 	// Rewind the buffer.
@@ -6,6 +6,7 @@ import (
 	"io"
 
 	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
 	"github.com/tetratelabs/wazero/internal/ieee754"
 	"github.com/tetratelabs/wazero/internal/leb128"
 	"github.com/tetratelabs/wazero/internal/wasm"
@@ -93,6 +94,10 @@ func decodeConstantExpression(r *bytes.Reader, enabledFeatures api.CoreFeatures,
 	}
 
 	if b != wasm.OpcodeEnd {
+		if enabledFeatures.IsEnabled(experimental.CoreFeaturesExtendedConst) &&
+			(opcode == wasm.OpcodeI32Const || opcode == wasm.OpcodeI64Const || opcode == wasm.OpcodeGlobalGet) {
+			return decodeExtendedConstExpression(r, offsetAtData-1, b, ret)
+		}
 		return fmt.Errorf("constant expression has been not terminated")
 	}
 
@@ -103,3 +108,45 @@ func decodeConstantExpression(r *bytes.Reader, enabledFeatures api.CoreFeatures,
 	ret.Opcode = opcode
 	return nil
 }
+
+// decodeExtendedConstExpression decodes the rest of a constant expression of experimental.CoreFeaturesExtendedConst,
+// given the offset of its first instruction, and the opcode which follows that instruction. The expression is folded
+// into a single constant, unless it uses global.get.
+func decodeExtendedConstExpression(r *bytes.Reader, offsetAtStart int64, b byte, ret *wasm.ConstantExpression) (err error) {
+	last, offsetAtLast := b, r.Size()-int64(r.Len())-1
+	for b != wasm.OpcodeEnd {
+		switch b {
+		case wasm.OpcodeI32Const:
+			_, _, err = leb128.DecodeInt32(r)
+		case wasm.OpcodeI64Const:
+			_, _, err = leb128.DecodeInt64(r)
+		case wasm.OpcodeGlobalGet:
+			_, _, err = leb128.DecodeUint32(r)
+		default:
+			if !wasm.IsExtendedConstOpcode(b) {
+				return fmt.Errorf("%v for const expression opt code: %#x", ErrInvalidByte, b)
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("read value: %v", err)
+		}
+
+		last, offsetAtLast = b, r.Size()-int64(r.Len())-1
+		if b, err = r.ReadByte(); err != nil {
+			return fmt.Errorf("look for end opcode: %v", err)
+		}
+	}
+
+	if !wasm.IsExtendedConstOpcode(last) {
+		return fmt.Errorf("constant expression must end with an arithmetic instruction, but ends with %s",
+			wasm.InstructionName(last))
+	}
+
+	ret.Data = make([]byte, offsetAtLast-offsetAtStart)
+	if _, err = r.ReadAt(ret.Data, offsetAtStart); err != nil {
+		return fmt.Errorf("error re-buffering ConstantExpression.Data")
+	}
+	ret.Opcode = last
+	wasm.FoldConstantExpression(ret)
+	return nil
+}
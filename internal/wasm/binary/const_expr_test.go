@@ -6,6 +6,7 @@ import (
 	"testing"
 
 	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
 	"github.com/tetratelabs/wazero/internal/testing/require"
 	"github.com/tetratelabs/wazero/internal/wasm"
 )
@@ -93,6 +94,89 @@ func TestDecodeConstantExpression(t *testing.T) {
 	}
 }
 
+func TestDecodeConstantExpression_ExtendedConst(t *testing.T) {
+	tests := []struct {
+		name string
+		in   []byte
+		exp  wasm.ConstantExpression
+	}{
+		{
+			name: "single instruction",
+			in:   []byte{wasm.OpcodeI32Const, 1, wasm.OpcodeEnd},
+			exp:  wasm.ConstantExpression{Opcode: wasm.OpcodeI32Const, Data: []byte{1}},
+		},
+		{
+			name: "global.get isn't folded",
+			in: []byte{
+				wasm.OpcodeGlobalGet, 0,
+				wasm.OpcodeI32Const, 0x80, 0, // Multi byte one.
+				wasm.OpcodeI32Add,
+				wasm.OpcodeEnd,
+			},
+			exp: wasm.ConstantExpression{
+				Opcode: wasm.OpcodeI32Add,
+				Data:   []byte{wasm.OpcodeGlobalGet, 0, wasm.OpcodeI32Const, 0x80, 0},
+			},
+		},
+		{
+			name: "i32 folded",
+			in: []byte{
+				wasm.OpcodeI32Const, 2,
+				wasm.OpcodeI32Const, 3,
+				wasm.OpcodeI32Mul,
+				wasm.OpcodeI32Const, 7,
+				wasm.OpcodeI32Sub,
+				wasm.OpcodeEnd,
+			},
+			exp: wasm.ConstantExpression{Opcode: wasm.OpcodeI32Const, Data: []byte{0x7f}}, // -1
+		},
+		{
+			name: "i32 folded with overflow",
+			in: []byte{
+				wasm.OpcodeI32Const, 0xff, 0xff, 0xff, 0xff, 0x07, // math.MaxInt32
+				wasm.OpcodeI32Const, 1,
+				wasm.OpcodeI32Add,
+				wasm.OpcodeEnd,
+			},
+			exp: wasm.ConstantExpression{Opcode: wasm.OpcodeI32Const, Data: []byte{0x80, 0x80, 0x80, 0x80, 0x78}}, // math.MinInt32
+		},
+		{
+			name: "i64 folded",
+			in: []byte{
+				wasm.OpcodeI64Const, 2,
+				wasm.OpcodeI64Const, 3,
+				wasm.OpcodeI64Add,
+				wasm.OpcodeEnd,
+			},
+			exp: wasm.ConstantExpression{Opcode: wasm.OpcodeI64Const, Data: []byte{5}},
+		},
+		{
+			name: "type mismatch isn't folded",
+			in: []byte{
+				wasm.OpcodeI64Const, 2,
+				wasm.OpcodeI32Const, 3,
+				wasm.OpcodeI32Add,
+				wasm.OpcodeEnd,
+			},
+			exp: wasm.ConstantExpression{
+				Opcode: wasm.OpcodeI32Add,
+				Data:   []byte{wasm.OpcodeI64Const, 2, wasm.OpcodeI32Const, 3},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			var actual wasm.ConstantExpression
+			err := decodeConstantExpression(bytes.NewReader(tc.in),
+				api.CoreFeaturesV2|experimental.CoreFeaturesExtendedConst, &actual)
+			require.NoError(t, err)
+			require.Equal(t, tc.exp, actual)
+		})
+	}
+}
+
 func TestDecodeConstantExpression_errors(t *testing.T) {
 	tests := []struct {
 		in          []byte
@@ -178,6 +262,44 @@ func TestDecodeConstantExpression_errors(t *testing.T) {
 			expectedErr: "read vector const instruction immediates: needs 16 bytes but was 8 bytes",
 			features:    api.CoreFeatureSIMD,
 		},
+		{
+			in: []byte{
+				wasm.OpcodeI32Const, 1,
+				wasm.OpcodeI32Const, 1,
+				wasm.OpcodeI32Add,
+				wasm.OpcodeEnd,
+			},
+			expectedErr: "constant expression has been not terminated",
+			features:    api.CoreFeaturesV2,
+		},
+		{
+			in: []byte{
+				wasm.OpcodeI32Const, 1,
+				wasm.OpcodeI32Const, 1,
+				wasm.OpcodeI32DivS,
+				wasm.OpcodeEnd,
+			},
+			expectedErr: "invalid byte for const expression opt code: 0x6d",
+			features:    api.CoreFeaturesV2 | experimental.CoreFeaturesExtendedConst,
+		},
+		{
+			in: []byte{
+				wasm.OpcodeI32Const, 1,
+				wasm.OpcodeI32Const, 1,
+				wasm.OpcodeEnd,
+			},
+			expectedErr: "constant expression must end with an arithmetic instruction, but ends with i32.const",
+			features:    api.CoreFeaturesV2 | experimental.CoreFeaturesExtendedConst,
+		},
+		{
+			in: []byte{
+				wasm.OpcodeI32Const, 1,
+				wasm.OpcodeI32Const, 1,
+				wasm.OpcodeI32Add,
+			},
+			expectedErr: "look for end opcode: EOF",
+			features:    api.CoreFeaturesV2 | experimental.CoreFeaturesExtendedConst,
+		},
 	}
 
 	for _, tt := range tests {
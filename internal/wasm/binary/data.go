@@ -6,6 +6,7 @@ import (
 	"io"
 
 	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
 	"github.com/tetratelabs/wazero/internal/leb128"
 	"github.com/tetratelabs/wazero/internal/wasm"
 )
@@ -48,8 +49,11 @@ func decodeDataSegment(r *bytes.Reader, enabledFeatures api.CoreFeatures, ret *w
 			if err != nil {
 				return fmt.Errorf("read memory index: %v", err)
 			} else if d != 0 {
-				return fmt.Errorf("memory index must be zero but was %d", d)
+				if err = enabledFeatures.RequireEnabled(experimental.CoreFeaturesMultiMemory); err != nil {
+					return fmt.Errorf("memory index must be zero but was %d", d)
+				}
 			}
+			ret.MemoryIndex = d
 		}
 
 		err = decodeConstantExpression(r, enabledFeatures, &ret.OffsetExpression)
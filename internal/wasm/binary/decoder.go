@@ -21,6 +21,7 @@ func DecodeModule(
 	memoryLimitPages uint32,
 	memoryCapacityFromMax,
 	dwarfEnabled, storeCustomSections bool,
+	limits wasm.DecodeLimits,
 ) (*wasm.Module, error) {
 	r := bytes.NewReader(binary)
 
@@ -42,6 +43,7 @@ func DecodeModule(
 	for {
 		// TODO: except custom sections, all others are required to be in order, but we aren't checking yet.
 		// See https://www.w3.org/TR/2019/REC-wasm-core-1-20191205/#modules%E2%91%A0%E2%93%AA
+		sectionStart := len(binary) - r.Len()
 		sectionID, err := r.ReadByte()
 		if err == io.EOF {
 			break
@@ -104,24 +106,26 @@ func DecodeModule(
 				m.NameSection, err = decodeNameSection(r, uint64(limit))
 			}
 		case wasm.SectionIDType:
-			m.TypeSection, err = decodeTypeSection(enabledFeatures, r)
+			m.TypeSection, err = decodeTypeSection(enabledFeatures, r, limits.MaxTypes)
 		case wasm.SectionIDImport:
-			m.ImportSection, m.ImportPerModule, m.ImportFunctionCount, m.ImportGlobalCount, m.ImportMemoryCount, m.ImportTableCount, err = decodeImportSection(r, memSizer, memoryLimitPages, enabledFeatures)
+			m.ImportSection, m.ImportPerModule, m.ImportFunctionCount, m.ImportGlobalCount, m.ImportMemoryCount, m.ImportTableCount, m.ImportTagCount, err = decodeImportSection(r, memSizer, memoryLimitPages, enabledFeatures, limits.MaxImports)
 			if err != nil {
 				return nil, err // avoid re-wrapping the error.
 			}
 		case wasm.SectionIDFunction:
-			m.FunctionSection, err = decodeFunctionSection(r)
+			m.FunctionSection, err = decodeFunctionSection(r, m.ImportFunctionCount, limits.MaxFunctions)
 		case wasm.SectionIDTable:
 			m.TableSection, err = decodeTableSection(r, enabledFeatures)
 		case wasm.SectionIDMemory:
-			m.MemorySection, err = decodeMemorySection(r, memSizer, memoryLimitPages)
+			m.MemorySection, m.AdditionalMemorySection, err = decodeMemorySection(r, enabledFeatures, m.ImportMemoryCount, memSizer, memoryLimitPages)
+		case wasm.SectionIDTag:
+			m.TagSection, err = decodeTagSection(r, enabledFeatures)
 		case wasm.SectionIDGlobal:
 			if m.GlobalSection, err = decodeGlobalSection(r, enabledFeatures); err != nil {
 				return nil, err // avoid re-wrapping the error.
 			}
 		case wasm.SectionIDExport:
-			m.ExportSection, m.Exports, err = decodeExportSection(r)
+			m.ExportSection, m.Exports, err = decodeExportSection(r, limits.MaxExports)
 		case wasm.SectionIDStart:
 			if m.StartSection != nil {
 				return nil, errors.New("multiple start sections are invalid")
@@ -130,7 +134,7 @@ func DecodeModule(
 		case wasm.SectionIDElement:
 			m.ElementSection, err = decodeElementSection(r, enabledFeatures)
 		case wasm.SectionIDCode:
-			m.CodeSection, err = decodeCodeSection(r)
+			m.CodeSection, err = decodeCodeSection(r, m.ImportFunctionCount, limits.MaxFunctions)
 		case wasm.SectionIDData:
 			m.DataSection, err = decodeDataSection(r, enabledFeatures)
 		case wasm.SectionIDDataCount:
@@ -148,8 +152,9 @@ func DecodeModule(
 		}
 
 		if err != nil {
-			return nil, fmt.Errorf("section %s: %v", wasm.SectionIDName(sectionID), err)
+			return nil, fmt.Errorf("section %s: %w", wasm.SectionIDName(sectionID), err)
 		}
+		m.SectionRanges = append(m.SectionRanges, wasm.SectionRange{ID: sectionID, Start: sectionStart, End: len(binary) - r.Len()})
 	}
 
 	if dwarfEnabled {
@@ -161,6 +166,9 @@ func DecodeModule(
 	if functionCount != codeCount {
 		return nil, fmt.Errorf("function and code section have inconsistent lengths: %d != %d", functionCount, codeCount)
 	}
+	if m.DataCountSection != nil && int(*m.DataCountSection) != len(m.DataSection) {
+		return nil, fmt.Errorf("data count and data section have inconsistent lengths: %d != %d", *m.DataCountSection, len(m.DataSection))
+	}
 	return m, nil
 }
 
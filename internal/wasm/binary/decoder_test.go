@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
 	"github.com/tetratelabs/wazero/internal/testing/binaryencoding"
 	"github.com/tetratelabs/wazero/internal/testing/dwarftestdata"
 	"github.com/tetratelabs/wazero/internal/testing/require"
@@ -121,7 +122,7 @@ func TestDecodeModule(t *testing.T) {
 		tc := tt
 
 		t.Run(tc.name, func(t *testing.T) {
-			m, e := DecodeModule(binaryencoding.EncodeModule(tc.input), api.CoreFeaturesV1, wasm.MemoryLimitPages, false, false, false)
+			m, e := DecodeModule(binaryencoding.EncodeModule(tc.input), api.CoreFeaturesV1, wasm.MemoryLimitPages, false, false, false, wasm.DefaultDecodeLimits)
 			require.NoError(t, e)
 			// Set the FunctionType keys on the input.
 			for i := range tc.input.TypeSection {
@@ -136,6 +137,7 @@ func TestDecodeModule(t *testing.T) {
 				}
 				tc.input.ImportPerModule = expImportPerModule
 			}
+			m.SectionRanges = nil // see TestDecodeModule_SectionRanges
 			require.Equal(t, tc.input, m)
 		})
 	}
@@ -145,9 +147,11 @@ func TestDecodeModule(t *testing.T) {
 			wasm.SectionIDCustom, 0xf, // 15 bytes in this section
 			0x04, 'm', 'e', 'm', 'e',
 			1, 2, 3, 4, 5, 6, 7, 8, 9, 0)
-		m, e := DecodeModule(input, api.CoreFeaturesV1, wasm.MemoryLimitPages, false, false, false)
+		m, e := DecodeModule(input, api.CoreFeaturesV1, wasm.MemoryLimitPages, false, false, false, wasm.DefaultDecodeLimits)
 		require.NoError(t, e)
-		require.Equal(t, &wasm.Module{}, m)
+		require.Equal(t, &wasm.Module{
+			SectionRanges: []wasm.SectionRange{{ID: wasm.SectionIDCustom, Start: 8, End: 25}},
+		}, m)
 	})
 
 	t.Run("reads custom sections", func(t *testing.T) {
@@ -155,7 +159,7 @@ func TestDecodeModule(t *testing.T) {
 			wasm.SectionIDCustom, 0xf, // 15 bytes in this section
 			0x04, 'm', 'e', 'm', 'e',
 			1, 2, 3, 4, 5, 6, 7, 8, 9, 0)
-		m, e := DecodeModule(input, api.CoreFeaturesV2, wasm.MemoryLimitPages, false, false, true)
+		m, e := DecodeModule(input, api.CoreFeaturesV2, wasm.MemoryLimitPages, false, false, true, wasm.DefaultDecodeLimits)
 		require.NoError(t, e)
 		require.Equal(t, &wasm.Module{
 			CustomSections: []*wasm.CustomSection{
@@ -164,9 +168,31 @@ func TestDecodeModule(t *testing.T) {
 					Data: []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 0},
 				},
 			},
+			SectionRanges: []wasm.SectionRange{{ID: wasm.SectionIDCustom, Start: 8, End: 25}},
 		}, m)
 	})
 
+	t.Run("reads custom sections in order, including duplicates", func(t *testing.T) {
+		input := append(append(Magic, version...),
+			wasm.SectionIDCustom, 0x6, // 6 bytes in this section
+			0x04, 'm', 'e', 'm', 'e',
+			1,
+			wasm.SectionIDType, 1, 0, // empty type section
+			wasm.SectionIDCustom, 0x4, // 4 bytes in this section
+			0x02, 'h', 'i',
+			2,
+			wasm.SectionIDCustom, 0x6, // 6 bytes in this section
+			0x04, 'm', 'e', 'm', 'e',
+			3)
+		m, e := DecodeModule(input, api.CoreFeaturesV2, wasm.MemoryLimitPages, false, false, true, wasm.DefaultDecodeLimits)
+		require.NoError(t, e)
+		require.Equal(t, []*wasm.CustomSection{
+			{Name: "meme", Data: []byte{1}},
+			{Name: "hi", Data: []byte{2}},
+			{Name: "meme", Data: []byte{3}},
+		}, m.CustomSections)
+	})
+
 	t.Run("skips custom section, but not name", func(t *testing.T) {
 		input := append(append(Magic, version...),
 			wasm.SectionIDCustom, 0xf, // 15 bytes in this section
@@ -177,9 +203,15 @@ func TestDecodeModule(t *testing.T) {
 			subsectionIDModuleName, 0x07, // 7 bytes in this subsection
 			0x06, // the Module name simple is 6 bytes long
 			's', 'i', 'm', 'p', 'l', 'e')
-		m, e := DecodeModule(input, api.CoreFeaturesV1, wasm.MemoryLimitPages, false, false, false)
+		m, e := DecodeModule(input, api.CoreFeaturesV1, wasm.MemoryLimitPages, false, false, false, wasm.DefaultDecodeLimits)
 		require.NoError(t, e)
-		require.Equal(t, &wasm.Module{NameSection: &wasm.NameSection{ModuleName: "simple"}}, m)
+		require.Equal(t, &wasm.Module{
+			NameSection: &wasm.NameSection{ModuleName: "simple"},
+			SectionRanges: []wasm.SectionRange{
+				{ID: wasm.SectionIDCustom, Start: 8, End: 25},
+				{ID: wasm.SectionIDCustom, Start: 25, End: 41},
+			},
+		}, m)
 	})
 
 	t.Run("read custom sections and name separately", func(t *testing.T) {
@@ -192,7 +224,7 @@ func TestDecodeModule(t *testing.T) {
 			subsectionIDModuleName, 0x07, // 7 bytes in this subsection
 			0x06, // the Module name simple is 6 bytes long
 			's', 'i', 'm', 'p', 'l', 'e')
-		m, e := DecodeModule(input, api.CoreFeaturesV2, wasm.MemoryLimitPages, false, false, true)
+		m, e := DecodeModule(input, api.CoreFeaturesV2, wasm.MemoryLimitPages, false, false, true, wasm.DefaultDecodeLimits)
 		require.NoError(t, e)
 		require.Equal(t, &wasm.Module{
 			NameSection: &wasm.NameSection{ModuleName: "simple"},
@@ -202,17 +234,21 @@ func TestDecodeModule(t *testing.T) {
 					Data: []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 0},
 				},
 			},
+			SectionRanges: []wasm.SectionRange{
+				{ID: wasm.SectionIDCustom, Start: 8, End: 25},
+				{ID: wasm.SectionIDCustom, Start: 25, End: 41},
+			},
 		}, m)
 	})
 
 	t.Run("DWARF enabled", func(t *testing.T) {
-		m, err := DecodeModule(dwarftestdata.ZigWasm, api.CoreFeaturesV2, wasm.MemoryLimitPages, false, true, true)
+		m, err := DecodeModule(dwarftestdata.ZigWasm, api.CoreFeaturesV2, wasm.MemoryLimitPages, false, true, true, wasm.DefaultDecodeLimits)
 		require.NoError(t, err)
 		require.NotNil(t, m.DWARFLines)
 	})
 
 	t.Run("DWARF disabled", func(t *testing.T) {
-		m, err := DecodeModule(dwarftestdata.ZigWasm, api.CoreFeaturesV2, wasm.MemoryLimitPages, false, false, true)
+		m, err := DecodeModule(dwarftestdata.ZigWasm, api.CoreFeaturesV2, wasm.MemoryLimitPages, false, false, true, wasm.DefaultDecodeLimits)
 		require.NoError(t, err)
 		require.Nil(t, m.DWARFLines)
 	})
@@ -220,9 +256,101 @@ func TestDecodeModule(t *testing.T) {
 	t.Run("data count section disabled", func(t *testing.T) {
 		input := append(append(Magic, version...),
 			wasm.SectionIDDataCount, 1, 0)
-		_, e := DecodeModule(input, api.CoreFeaturesV1, wasm.MemoryLimitPages, false, false, false)
+		_, e := DecodeModule(input, api.CoreFeaturesV1, wasm.MemoryLimitPages, false, false, false, wasm.DefaultDecodeLimits)
 		require.EqualError(t, e, `data count section not supported as feature "bulk-memory-operations" is disabled`)
 	})
+
+	t.Run("data count section doesn't match data section", func(t *testing.T) {
+		input := append(append(Magic, version...),
+			wasm.SectionIDDataCount, 1, 2,
+			wasm.SectionIDData, 3, 1, 1, 0) // one passive segment of no bytes
+		_, e := DecodeModule(input, api.CoreFeaturesV2, wasm.MemoryLimitPages, false, false, false, wasm.DefaultDecodeLimits)
+		require.EqualError(t, e, `data count and data section have inconsistent lengths: 2 != 1`)
+	})
+
+	t.Run("tag import, section and export", func(t *testing.T) {
+		input := &wasm.Module{
+			ImportTagCount: 1,
+			TypeSection: []wasm.FunctionType{
+				{Params: []wasm.ValueType{i32}},
+				{Params: []wasm.ValueType{f32, i32}},
+			},
+			ImportSection: []wasm.Import{
+				{Module: "env", Name: "cpp_exception", Type: wasm.ExternTypeTag, DescTag: 0},
+			},
+			TagSection: []wasm.Index{1, 0},
+			ExportSection: []wasm.Export{
+				{Name: "imported", Type: wasm.ExternTypeTag, Index: 0},
+				{Name: "defined", Type: wasm.ExternTypeTag, Index: 2},
+			},
+		}
+		m, e := DecodeModule(binaryencoding.EncodeModule(input), api.CoreFeaturesV2|experimental.CoreFeaturesExceptionHandling,
+			wasm.MemoryLimitPages, false, false, false, wasm.DefaultDecodeLimits)
+		require.NoError(t, e)
+		require.Equal(t, wasm.Index(1), m.ImportTagCount)
+		require.Equal(t, input.ImportSection, m.ImportSection)
+		require.Equal(t, []wasm.Index{1, 0}, m.TagSection)
+		require.Equal(t, input.ExportSection, m.ExportSection)
+		require.Equal(t, wasm.SectionIDTag, m.SectionRanges[2].ID)
+	})
+
+	t.Run("tag section disabled", func(t *testing.T) {
+		input := append(append(Magic, version...),
+			wasm.SectionIDTag, 3, 1, 0, 0)
+		_, e := DecodeModule(input, api.CoreFeaturesV2, wasm.MemoryLimitPages, false, false, false, wasm.DefaultDecodeLimits)
+		require.EqualError(t, e, `section tag: tag section not supported as feature "exception-handling" is disabled`)
+	})
+
+	t.Run("tag import disabled", func(t *testing.T) {
+		input := append(append(Magic, version...),
+			wasm.SectionIDImport, 8, 1, 1, 'm', 1, 'n', wasm.ExternTypeTag, 0, 0)
+		_, e := DecodeModule(input, api.CoreFeaturesV2, wasm.MemoryLimitPages, false, false, false, wasm.DefaultDecodeLimits)
+		require.EqualError(t, e, `import[0] tag[m.n]: tag import not supported as feature "exception-handling" is disabled`)
+	})
+
+	t.Run("tag attribute", func(t *testing.T) {
+		input := append(append(Magic, version...),
+			wasm.SectionIDTag, 3, 1, 1, 0)
+		_, e := DecodeModule(input, api.CoreFeaturesV2|experimental.CoreFeaturesExceptionHandling, wasm.MemoryLimitPages, false, false, false, wasm.DefaultDecodeLimits)
+		require.EqualError(t, e, `section tag: tag[0]: invalid byte: invalid tag attribute: 0x1 != 0`)
+	})
+}
+
+func TestDecodeModule_SectionRanges(t *testing.T) {
+	data := make([]byte, 200) // large enough that the section size needs two bytes.
+	input := append(append(Magic, version...),
+		wasm.SectionIDType, 4, 1, 0x60, 0, 0, // one type of no params or results
+		wasm.SectionIDCustom, 0xca, 0x01, // 202 bytes in this section
+		0x01, 'c')
+	input = append(input, data...)
+	input = append(input, wasm.SectionIDFunction, 2, 1, 0)
+	input = append(input, wasm.SectionIDCode, 4, 1, 2, 0, wasm.OpcodeEnd)
+
+	m, e := DecodeModule(input, api.CoreFeaturesV2, wasm.MemoryLimitPages, false, false, false, wasm.DefaultDecodeLimits)
+	require.NoError(t, e)
+	require.Equal(t, []wasm.SectionRange{
+		{ID: wasm.SectionIDType, Start: 8, End: 14},
+		{ID: wasm.SectionIDCustom, Start: 14, End: 219},
+		{ID: wasm.SectionIDFunction, Start: 219, End: 223},
+		{ID: wasm.SectionIDCode, Start: 223, End: 229},
+	}, m.SectionRanges)
+
+	// The ranges are contiguous, and cover the binary following the header.
+	roundTrip := append(append([]byte{}, Magic...), version...)
+	for _, r := range m.SectionRanges {
+		roundTrip = append(roundTrip, input[r.Start:r.End]...)
+	}
+	require.Equal(t, input, roundTrip)
+
+	// Replacing the custom section results in a valid module.
+	custom := m.SectionRanges[1]
+	replaced := append(append([]byte{}, input[:custom.Start]...),
+		wasm.SectionIDCustom, 3, 0x01, 'c', 42)
+	replaced = append(replaced, input[custom.End:]...)
+	m, e = DecodeModule(replaced, api.CoreFeaturesV2, wasm.MemoryLimitPages, false, false, true, wasm.DefaultDecodeLimits)
+	require.NoError(t, e)
+	require.Equal(t, []*wasm.CustomSection{{Name: "c", Data: []byte{42}}}, m.CustomSections)
+	require.Equal(t, wasm.SectionRange{ID: wasm.SectionIDCustom, Start: 14, End: 19}, m.SectionRanges[1])
 }
 
 func TestDecodeModule_Errors(t *testing.T) {
@@ -270,7 +398,7 @@ func TestDecodeModule_Errors(t *testing.T) {
 		tc := tt
 
 		t.Run(tc.name, func(t *testing.T) {
-			_, e := DecodeModule(tc.input, api.CoreFeaturesV1, wasm.MemoryLimitPages, false, false, false)
+			_, e := DecodeModule(tc.input, api.CoreFeaturesV1, wasm.MemoryLimitPages, false, false, false, wasm.DefaultDecodeLimits)
 			require.EqualError(t, e, tc.expectedErr)
 		})
 	}
@@ -72,11 +72,8 @@ func decodeElementConstExprVector(r *bytes.Reader, elemType wasm.RefType, enable
 			vec[i] = wasm.ElementInitNullReference
 		case wasm.OpcodeGlobalGet:
 			i32, _, _ := leb128.LoadInt32(expr.Data)
-			if elemType != wasm.RefTypeFuncref {
-				return nil, fmt.Errorf("element type mismatch: want %s, but requires funcref", wasm.RefTypeName(elemType))
-			}
-			// Resolving the function index is done at instantiation phase. See the comment on
-			// wasm.ElementInitImportedGlobalFunctionReference.
+			// The type of the global is validated against elemType with the module, and resolving the reference is
+			// done at instantiation phase. See the comment on wasm.ElementInitImportedGlobalFunctionReference.
 			vec[i] = wasm.ElementInitImportedGlobalFunctionReference | wasm.Index(i32)
 		default:
 			return nil, fmt.Errorf("const expr must be either ref.null or ref.func but was %s", wasm.InstructionName(expr.Opcode))
@@ -104,6 +104,16 @@ func Test_decodeElementConstExprVector(t *testing.T) {
 			refType:  wasm.RefTypeExternref,
 			features: api.CoreFeatureBulkMemoryOperations,
 		},
+		{
+			in: []byte{
+				2, // Two indexes.
+				wasm.OpcodeRefNull, wasm.RefTypeExternref, wasm.OpcodeEnd,
+				wasm.OpcodeGlobalGet, 0, wasm.OpcodeEnd,
+			},
+			exp:      []wasm.Index{wasm.ElementInitNullReference, wasm.ElementInitImportedGlobalFunctionReference | 0},
+			refType:  wasm.RefTypeExternref,
+			features: api.CoreFeatureBulkMemoryOperations,
+		},
 	}
 
 	for i, tt := range tests {
@@ -168,13 +178,6 @@ func Test_decodeElementConstExprVector_errors(t *testing.T) {
 			features: api.CoreFeaturesV2,
 			expErr:   "too large function index in Element init: 4294967295",
 		},
-		{
-			name:     "type mismatch - global.get",
-			in:       []byte{1, wasm.OpcodeGlobalGet, 0, wasm.OpcodeEnd},
-			refType:  wasm.RefTypeExternref,
-			features: api.CoreFeaturesV2,
-			expErr:   "element type mismatch: want externref, but requires funcref",
-		},
 	}
 
 	for _, tt := range tests {
@@ -2,6 +2,7 @@ package binary
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 
 	"github.com/tetratelabs/wazero/internal/leb128"
@@ -9,28 +10,75 @@ import (
 
 // decodeLimitsType returns the `limitsType` (min, max) decoded with the WebAssembly 1.0 (20191205) Binary Format.
 //
+// When allowShared is true, the flag of a shared memory of the threads proposal is also accepted, which must have a
+// max.
+//
 // See https://www.w3.org/TR/2019/REC-wasm-core-1-20191205/#limits%E2%91%A6
-func decodeLimitsType(r *bytes.Reader) (min uint32, max *uint32, err error) {
+// See https://github.com/WebAssembly/threads/blob/main/proposals/threads/Overview.md#spec-changes
+func decodeLimitsType(r *bytes.Reader, allowShared bool) (min uint32, max *uint32, shared bool, err error) {
+	min64, max64, shared, _, err := decodeLimitsType64(r, allowShared, false)
+	if err != nil {
+		return
+	}
+	// Without is64, the limits are decoded as 32-bit.
+	min = uint32(min64)
+	if max64 != nil {
+		m := uint32(*max64)
+		max = &m
+	}
+	return
+}
+
+// decodeLimitsType64 is like decodeLimitsType, except the limits are 64-bit. When allow64 is true, the flags of a
+// 64-bit memory of the memory64 proposal are also accepted, and is64 is true when the limits are decoded as 64-bit.
+// Otherwise, they are decoded as 32-bit.
+//
+// See https://github.com/WebAssembly/memory64/blob/main/proposals/memory64/Overview.md#binary-format
+func decodeLimitsType64(r *bytes.Reader, allowShared, allow64 bool) (min uint64, max *uint64, shared, is64 bool, err error) {
 	var flag byte
 	if flag, err = r.ReadByte(); err != nil {
 		err = fmt.Errorf("read leading byte: %v", err)
 		return
 	}
 
+	if flag&0x04 != 0 && allow64 {
+		flag, is64 = flag&^0x04, true
+	}
+	if allowShared {
+		switch flag {
+		case 0x02:
+			err = errors.New("shared memory must have a max")
+			return
+		case 0x03:
+			flag, shared = 0x01, true
+		}
+	}
+
+	decode := func() (v uint64, err error) {
+		if is64 {
+			v, _, err = leb128.DecodeUint64(r)
+		} else {
+			var v32 uint32
+			v32, _, err = leb128.DecodeUint32(r)
+			v = uint64(v32)
+		}
+		return
+	}
+
 	switch flag {
 	case 0x00:
-		min, _, err = leb128.DecodeUint32(r)
+		min, err = decode()
 		if err != nil {
 			err = fmt.Errorf("read min of limit: %v", err)
 		}
 	case 0x01:
-		min, _, err = leb128.DecodeUint32(r)
+		min, err = decode()
 		if err != nil {
 			err = fmt.Errorf("read min of limit: %v", err)
 			return
 		}
-		var m uint32
-		if m, _, err = leb128.DecodeUint32(r); err != nil {
+		var m uint64
+		if m, err = decode(); err != nil {
 			err = fmt.Errorf("read max of limit: %v", err)
 		} else {
 			max = &m
@@ -3,6 +3,8 @@ package binary
 import (
 	"bytes"
 
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
 	"github.com/tetratelabs/wazero/internal/wasm"
 )
 
@@ -11,16 +13,46 @@ import (
 // See https://www.w3.org/TR/2019/REC-wasm-core-1-20191205/#binary-memory
 func decodeMemory(
 	r *bytes.Reader,
+	enabledFeatures api.CoreFeatures,
 	memorySizer func(minPages uint32, maxPages *uint32) (min, capacity, max uint32),
 	memoryLimitPages uint32,
 ) (*wasm.Memory, error) {
-	min, maxP, err := decodeLimitsType(r)
+	min64, max64, shared, is64, err := decodeLimitsType64(r,
+		enabledFeatures.IsEnabled(experimental.CoreFeaturesThreads),
+		enabledFeatures.IsEnabled(experimental.CoreFeaturesMemory64))
 	if err != nil {
 		return nil, err
 	}
 
+	var min uint32
+	var maxP *uint32
+	if is64 {
+		if min64 > uint64(memoryLimitPages) {
+			return nil, &api.LimitExceededError{Resource: "memory min pages", Requested: min64, Limit: uint64(memoryLimitPages)}
+		}
+		min = uint32(min64)
+		if max64 != nil {
+			if *max64 > wasm.MemoryLimitPages64 {
+				return nil, &api.LimitExceededError{Resource: "memory max pages", Requested: *max64, Limit: wasm.MemoryLimitPages64}
+			}
+			// A larger max is valid for a 64-bit memory, but cannot be allocated: cap it to the run-time limit, so
+			// that memory.grow fails beyond it.
+			max := memoryLimitPages
+			if *max64 < uint64(max) {
+				max = uint32(*max64)
+			}
+			maxP = &max
+		}
+	} else {
+		min = uint32(min64)
+		if max64 != nil {
+			max := uint32(*max64)
+			maxP = &max
+		}
+	}
+
 	min, capacity, max := memorySizer(min, maxP)
-	mem := &wasm.Memory{Min: min, Cap: capacity, Max: max, IsMaxEncoded: maxP != nil}
+	mem := &wasm.Memory{Min: min, Cap: capacity, Max: max, IsMaxEncoded: maxP != nil, IsShared: shared, Is64: is64}
 
 	return mem, mem.Validate(memoryLimitPages)
 }
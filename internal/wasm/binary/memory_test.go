@@ -5,6 +5,9 @@ import (
 	"fmt"
 	"testing"
 
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
+
 	"github.com/tetratelabs/wazero/internal/testing/binaryencoding"
 	"github.com/tetratelabs/wazero/internal/testing/require"
 	"github.com/tetratelabs/wazero/internal/wasm"
@@ -182,7 +185,7 @@ func TestMemoryType(t *testing.T) {
 				expectedDecoded.Max = tmax
 			}
 
-			binary, err := decodeMemory(bytes.NewReader(b), newMemorySizer(tmax, false), tmax)
+			binary, err := decodeMemory(bytes.NewReader(b), api.CoreFeaturesV2, newMemorySizer(tmax, false), tmax)
 			require.NoError(t, err)
 			require.Equal(t, binary, expectedDecoded)
 		})
@@ -193,24 +196,36 @@ func TestDecodeMemoryType_Errors(t *testing.T) {
 	max := wasm.MemoryLimitPages
 
 	tests := []struct {
-		name        string
-		input       []byte
-		expectedErr string
+		name                  string
+		input                 []byte
+		memoryCapacityFromMax bool
+		expectedErr           string
 	}{
 		{
 			name:        "max < min",
 			input:       []byte{0x1, 0x80, 0x80, 0x4, 0},
 			expectedErr: "min 65536 pages (4 Gi) > max 0 pages (0 Ki)",
 		},
+		{
+			name:        "max < min small",
+			input:       []byte{0x1, 0x2, 0x1},
+			expectedErr: "min 2 pages (128 Ki) > max 1 pages (64 Ki)",
+		},
+		{
+			name:                  "max < min memoryCapacityFromMax",
+			input:                 []byte{0x1, 0x2, 0x1},
+			memoryCapacityFromMax: true,
+			expectedErr:           "min 2 pages (128 Ki) > max 1 pages (64 Ki)",
+		},
 		{
 			name:        "min > limit",
 			input:       []byte{0x0, 0xff, 0xff, 0xff, 0xff, 0xf},
-			expectedErr: "min 4294967295 pages (3 Ti) over limit of 65536 pages (4 Gi)",
+			expectedErr: "memory min pages: 4294967295 over limit of 65536",
 		},
 		{
 			name:        "max > limit",
 			input:       []byte{0x1, 0, 0xff, 0xff, 0xff, 0xff, 0xf},
-			expectedErr: "max 4294967295 pages (3 Ti) over limit of 65536 pages (4 Gi)",
+			expectedErr: "memory max pages: 4294967295 over limit of 65536",
 		},
 	}
 
@@ -218,8 +233,102 @@ func TestDecodeMemoryType_Errors(t *testing.T) {
 		tc := tt
 
 		t.Run(tc.name, func(t *testing.T) {
-			_, err := decodeMemory(bytes.NewReader(tc.input), newMemorySizer(max, false), max)
+			_, err := decodeMemory(bytes.NewReader(tc.input), api.CoreFeaturesV2, newMemorySizer(max, tc.memoryCapacityFromMax), max)
 			require.EqualError(t, err, tc.expectedErr)
 		})
 	}
 }
+
+func TestDecodeMemoryType_Shared(t *testing.T) {
+	max := wasm.MemoryLimitPages
+	input := []byte{0x3, 1, 2}
+
+	t.Run("threads enabled", func(t *testing.T) {
+		features := api.CoreFeaturesV2 | experimental.CoreFeaturesThreads
+		mem, err := decodeMemory(bytes.NewReader(input), features, newMemorySizer(max, false), max)
+		require.NoError(t, err)
+		require.Equal(t, &wasm.Memory{Min: 1, Cap: 1, Max: 2, IsMaxEncoded: true, IsShared: true}, mem)
+	})
+
+	t.Run("threads disabled", func(t *testing.T) {
+		_, err := decodeMemory(bytes.NewReader(input), api.CoreFeaturesV2, newMemorySizer(max, false), max)
+		require.EqualError(t, err, "invalid byte for limits: 0x3 != 0x00 or 0x01")
+	})
+
+	t.Run("max required", func(t *testing.T) {
+		features := api.CoreFeaturesV2 | experimental.CoreFeaturesThreads
+		_, err := decodeMemory(bytes.NewReader([]byte{0x2, 1}), features, newMemorySizer(max, false), max)
+		require.EqualError(t, err, "shared memory must have a max")
+
+		// The same applies to a 64-bit memory.
+		features |= experimental.CoreFeaturesMemory64
+		_, err = decodeMemory(bytes.NewReader([]byte{0x6, 1}), features, newMemorySizer(max, false), max)
+		require.EqualError(t, err, "shared memory must have a max")
+		mem, err := decodeMemory(bytes.NewReader([]byte{0x7, 1, 2}), features, newMemorySizer(max, false), max)
+		require.NoError(t, err)
+		require.Equal(t, &wasm.Memory{Min: 1, Cap: 1, Max: 2, IsMaxEncoded: true, IsShared: true, Is64: true}, mem)
+	})
+
+	t.Run("max capped to the limit", func(t *testing.T) {
+		// A shared memory is allocated with the capacity of its max, so the limit bounds what a module can reserve.
+		features := api.CoreFeaturesV2 | experimental.CoreFeaturesThreads
+		mem, err := decodeMemory(bytes.NewReader([]byte{0x3, 1, 0x80, 0x80, 0x04}), features, newMemorySizer(10, false), 10)
+		require.NoError(t, err)
+		require.Equal(t, &wasm.Memory{Min: 1, Cap: 1, Max: 10, IsMaxEncoded: true, IsShared: true}, mem)
+		require.Equal(t, wasm.MemoryPagesToBytesNum(10), uint64(cap(wasm.NewMemoryInstance(mem).Buffer)))
+	})
+}
+
+func TestDecodeMemoryType_Memory64(t *testing.T) {
+	max := wasm.MemoryLimitPages
+	features := api.CoreFeaturesV2 | experimental.CoreFeaturesMemory64
+
+	tests := []struct {
+		name     string
+		input    []byte
+		expected *wasm.Memory
+	}{
+		{
+			name:     "min",
+			input:    []byte{0x4, 1},
+			expected: &wasm.Memory{Min: 1, Cap: 1, Max: max, Is64: true},
+		},
+		{
+			name:     "min and max",
+			input:    []byte{0x5, 1, 2},
+			expected: &wasm.Memory{Min: 1, Cap: 1, Max: 2, IsMaxEncoded: true, Is64: true},
+		},
+		{
+			name:     "max over 4GiB is capped to the limit",
+			input:    []byte{0x5, 1, 0x80, 0x80, 0x80, 0x80, 0x10}, // 2^32 pages
+			expected: &wasm.Memory{Min: 1, Cap: 1, Max: max, IsMaxEncoded: true, Is64: true},
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+
+		t.Run(tc.name, func(t *testing.T) {
+			mem, err := decodeMemory(bytes.NewReader(tc.input), features, newMemorySizer(max, false), max)
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, mem)
+		})
+	}
+
+	t.Run("min over the limit", func(t *testing.T) {
+		input := []byte{0x4, 0x80, 0x80, 0x80, 0x80, 0x10} // 2^32 pages
+		_, err := decodeMemory(bytes.NewReader(input), features, newMemorySizer(max, false), max)
+		require.EqualError(t, err, "memory min pages: 4294967296 over limit of 65536")
+	})
+
+	t.Run("max over 2^48 pages", func(t *testing.T) {
+		input := []byte{0x5, 1, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x01} // 2^49 pages
+		_, err := decodeMemory(bytes.NewReader(input), features, newMemorySizer(max, false), max)
+		require.EqualError(t, err, "memory max pages: 562949953421312 over limit of 281474976710656")
+	})
+
+	t.Run("memory64 disabled", func(t *testing.T) {
+		_, err := decodeMemory(bytes.NewReader([]byte{0x5, 1, 2}), api.CoreFeaturesV2, newMemorySizer(max, false), max)
+		require.EqualError(t, err, "invalid byte for limits: 0x5 != 0x00 or 0x01")
+	})
+}
@@ -6,14 +6,17 @@ import (
 	"io"
 
 	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
 	"github.com/tetratelabs/wazero/internal/leb128"
 	"github.com/tetratelabs/wazero/internal/wasm"
 )
 
-func decodeTypeSection(enabledFeatures api.CoreFeatures, r *bytes.Reader) ([]wasm.FunctionType, error) {
+func decodeTypeSection(enabledFeatures api.CoreFeatures, r *bytes.Reader, maxTypes uint32) ([]wasm.FunctionType, error) {
 	vs, _, err := leb128.DecodeUint32(r)
 	if err != nil {
 		return nil, fmt.Errorf("get size of vector: %w", err)
+	} else if vs > maxTypes {
+		return nil, fmt.Errorf("too many types in a module: %d given with limit %d", vs, maxTypes)
 	}
 
 	result := make([]wasm.FunctionType, vs)
@@ -31,14 +34,18 @@ func decodeImportSection(
 	memorySizer memorySizer,
 	memoryLimitPages uint32,
 	enabledFeatures api.CoreFeatures,
+	maxImports uint32,
 ) (result []wasm.Import,
 	perModule map[string][]*wasm.Import,
-	funcCount, globalCount, memoryCount, tableCount wasm.Index, err error,
+	funcCount, globalCount, memoryCount, tableCount, tagCount wasm.Index, err error,
 ) {
 	vs, _, err := leb128.DecodeUint32(r)
 	if err != nil {
 		err = fmt.Errorf("get size of vector: %w", err)
 		return
+	} else if vs > maxImports {
+		err = fmt.Errorf("too many imports in a module: %d given with limit %d", vs, maxImports)
+		return
 	}
 
 	perModule = make(map[string][]*wasm.Import)
@@ -61,16 +68,23 @@ func decodeImportSection(
 		case wasm.ExternTypeTable:
 			imp.IndexPerType = tableCount
 			tableCount++
+		case wasm.ExternTypeTag:
+			imp.IndexPerType = tagCount
+			tagCount++
 		}
 		perModule[imp.Module] = append(perModule[imp.Module], imp)
 	}
 	return
 }
 
-func decodeFunctionSection(r *bytes.Reader) ([]uint32, error) {
+// decodeFunctionSection decodes the function section, whose entries count toward maxFunctions after the imported
+// functions.
+func decodeFunctionSection(r *bytes.Reader, importedFunctions, maxFunctions uint32) ([]uint32, error) {
 	vs, _, err := leb128.DecodeUint32(r)
 	if err != nil {
 		return nil, fmt.Errorf("get size of vector: %w", err)
+	} else if total := uint64(importedFunctions) + uint64(vs); total > uint64(maxFunctions) {
+		return nil, fmt.Errorf("too many functions in a module: %d given with limit %d", total, maxFunctions)
 	}
 
 	result := make([]uint32, vs)
@@ -103,23 +117,48 @@ func decodeTableSection(r *bytes.Reader, enabledFeatures api.CoreFeatures) ([]wa
 	return ret, nil
 }
 
+// decodeMemorySection decodes the memory section, returning the memory at index zero unless one is imported, and any
+// other memory, which requires experimental.CoreFeaturesMultiMemory.
 func decodeMemorySection(
 	r *bytes.Reader,
+	enabledFeatures api.CoreFeatures,
+	importMemoryCount wasm.Index,
 	memorySizer memorySizer,
 	memoryLimitPages uint32,
-) (*wasm.Memory, error) {
+) (*wasm.Memory, []wasm.Memory, error) {
 	vs, _, err := leb128.DecodeUint32(r)
 	if err != nil {
-		return nil, fmt.Errorf("error reading size")
+		return nil, nil, fmt.Errorf("error reading size")
 	}
-	if vs > 1 {
-		return nil, fmt.Errorf("at most one memory allowed in module, but read %d", vs)
+	if vs > 1 || (vs == 1 && importMemoryCount > 0) {
+		if err = enabledFeatures.RequireEnabled(experimental.CoreFeaturesMultiMemory); err != nil {
+			return nil, nil, fmt.Errorf("at most one memory allowed in module, but read %d", vs+importMemoryCount)
+		}
 	} else if vs == 0 {
 		// memory count can be zero.
-		return nil, nil
+		return nil, nil, nil
 	}
 
-	return decodeMemory(r, memorySizer, memoryLimitPages)
+	var first *wasm.Memory
+	if importMemoryCount == 0 {
+		if first, err = decodeMemory(r, enabledFeatures, memorySizer, memoryLimitPages); err != nil {
+			return nil, nil, err
+		}
+		vs--
+	}
+
+	var additional []wasm.Memory
+	if vs > 0 {
+		additional = make([]wasm.Memory, vs)
+		for i := range additional {
+			mem, err := decodeMemory(r, enabledFeatures, memorySizer, memoryLimitPages)
+			if err != nil {
+				return nil, nil, err
+			}
+			additional[i] = *mem
+		}
+	}
+	return first, additional, nil
 }
 
 func decodeGlobalSection(r *bytes.Reader, enabledFeatures api.CoreFeatures) ([]wasm.Global, error) {
@@ -137,10 +176,12 @@ func decodeGlobalSection(r *bytes.Reader, enabledFeatures api.CoreFeatures) ([]w
 	return result, nil
 }
 
-func decodeExportSection(r *bytes.Reader) ([]wasm.Export, map[string]*wasm.Export, error) {
+func decodeExportSection(r *bytes.Reader, maxExports uint32) ([]wasm.Export, map[string]*wasm.Export, error) {
 	vs, _, sizeErr := leb128.DecodeUint32(r)
 	if sizeErr != nil {
 		return nil, nil, fmt.Errorf("get size of vector: %v", sizeErr)
+	} else if vs > maxExports {
+		return nil, nil, fmt.Errorf("too many exports in a module: %d given with limit %d", vs, maxExports)
 	}
 
 	exportMap := make(map[string]*wasm.Export, vs)
@@ -160,6 +201,25 @@ func decodeExportSection(r *bytes.Reader) ([]wasm.Export, map[string]*wasm.Expor
 	return exportSection, exportMap, nil
 }
 
+// decodeTagSection decodes the type index of each tag, which requires experimental.CoreFeaturesExceptionHandling.
+func decodeTagSection(r *bytes.Reader, enabledFeatures api.CoreFeatures) ([]wasm.Index, error) {
+	if err := enabledFeatures.RequireEnabled(experimental.CoreFeaturesExceptionHandling); err != nil {
+		return nil, fmt.Errorf("tag section not supported as %v", err)
+	}
+	vs, _, err := leb128.DecodeUint32(r)
+	if err != nil {
+		return nil, fmt.Errorf("get size of vector: %w", err)
+	}
+
+	result := make([]wasm.Index, vs)
+	for i := uint32(0); i < vs; i++ {
+		if result[i], err = decodeTagType(r); err != nil {
+			return nil, fmt.Errorf("tag[%d]: %w", i, err)
+		}
+	}
+	return result, nil
+}
+
 func decodeStartSection(r *bytes.Reader) (*wasm.Index, error) {
 	vs, _, err := leb128.DecodeUint32(r)
 	if err != nil {
@@ -183,11 +243,15 @@ func decodeElementSection(r *bytes.Reader, enabledFeatures api.CoreFeatures) ([]
 	return result, nil
 }
 
-func decodeCodeSection(r *bytes.Reader) ([]wasm.Code, error) {
+// decodeCodeSection decodes the code section, whose entries count toward maxFunctions after the imported functions, as
+// the function section.
+func decodeCodeSection(r *bytes.Reader, importedFunctions, maxFunctions uint32) ([]wasm.Code, error) {
 	codeSectionStart := uint64(r.Len())
 	vs, _, err := leb128.DecodeUint32(r)
 	if err != nil {
 		return nil, fmt.Errorf("get size of vector: %w", err)
+	} else if total := uint64(importedFunctions) + uint64(vs); total > uint64(maxFunctions) {
+		return nil, fmt.Errorf("too many functions in a module: %d given with limit %d", total, maxFunctions)
 	}
 
 	result := make([]wasm.Code, vs)
@@ -2,9 +2,14 @@ package binary
 
 import (
 	"bytes"
+	"fmt"
+	"math"
+	"strconv"
 	"testing"
 
 	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
+	"github.com/tetratelabs/wazero/internal/leb128"
 	"github.com/tetratelabs/wazero/internal/testing/binaryencoding"
 	"github.com/tetratelabs/wazero/internal/testing/require"
 	"github.com/tetratelabs/wazero/internal/wasm"
@@ -104,9 +109,55 @@ func TestMemorySection(t *testing.T) {
 		tc := tt
 
 		t.Run(tc.name, func(t *testing.T) {
-			memories, err := decodeMemorySection(bytes.NewReader(tc.input), newMemorySizer(max, false), max)
+			memory, additional, err := decodeMemorySection(bytes.NewReader(tc.input), api.CoreFeaturesV2, 0, newMemorySizer(max, false), max)
 			require.NoError(t, err)
-			require.Equal(t, tc.expected, memories)
+			require.Equal(t, tc.expected, memory)
+			require.Nil(t, additional)
+		})
+	}
+}
+
+func TestMemorySection_MultiMemory(t *testing.T) {
+	max := wasm.MemoryLimitPages
+	features := api.CoreFeaturesV2 | experimental.CoreFeaturesMultiMemory
+
+	three := uint32(3)
+	tests := []struct {
+		name               string
+		input              []byte
+		importMemoryCount  wasm.Index
+		expected           *wasm.Memory
+		expectedAdditional []wasm.Memory
+	}{
+		{
+			name: "two memories",
+			input: []byte{
+				0x02,       // 2 memories
+				0x00, 0x01, // (memory 1)
+				0x01, 0x02, 0x03, // (memory 2 3)
+			},
+			expected:           &wasm.Memory{Min: 1, Cap: 1, Max: max},
+			expectedAdditional: []wasm.Memory{{Min: 2, Cap: 2, Max: three, IsMaxEncoded: true}},
+		},
+		{
+			name: "imported memory",
+			input: []byte{
+				0x01,             // 1 memory
+				0x01, 0x02, 0x03, // (memory 2 3)
+			},
+			importMemoryCount:  1,
+			expectedAdditional: []wasm.Memory{{Min: 2, Cap: 2, Max: three, IsMaxEncoded: true}},
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+
+		t.Run(tc.name, func(t *testing.T) {
+			memory, additional, err := decodeMemorySection(bytes.NewReader(tc.input), features, tc.importMemoryCount, newMemorySizer(max, false), max)
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, memory)
+			require.Equal(t, tc.expectedAdditional, additional)
 		})
 	}
 }
@@ -115,9 +166,10 @@ func TestMemorySection_Errors(t *testing.T) {
 	max := wasm.MemoryLimitPages
 
 	tests := []struct {
-		name        string
-		input       []byte
-		expectedErr string
+		name              string
+		input             []byte
+		importMemoryCount wasm.Index
+		expectedErr       string
 	}{
 		{
 			name: "min and min with max",
@@ -128,13 +180,22 @@ func TestMemorySection_Errors(t *testing.T) {
 			},
 			expectedErr: "at most one memory allowed in module, but read 2",
 		},
+		{
+			name: "imported memory",
+			input: []byte{
+				0x01,       // 1 memory
+				0x00, 0x01, // (memory 1)
+			},
+			importMemoryCount: 1,
+			expectedErr:       "at most one memory allowed in module, but read 2",
+		},
 	}
 
 	for _, tt := range tests {
 		tc := tt
 
 		t.Run(tc.name, func(t *testing.T) {
-			_, err := decodeMemorySection(bytes.NewReader(tc.input), newMemorySizer(max, false), max)
+			_, _, err := decodeMemorySection(bytes.NewReader(tc.input), api.CoreFeaturesV2, tc.importMemoryCount, newMemorySizer(max, false), max)
 			require.EqualError(t, err, tc.expectedErr)
 		})
 	}
@@ -166,7 +227,7 @@ func TestDecodeExportSection(t *testing.T) {
 		tc := tt
 
 		t.Run(tc.name, func(t *testing.T) {
-			actual, actualExpMap, err := decodeExportSection(bytes.NewReader(tc.input))
+			actual, actualExpMap, err := decodeExportSection(bytes.NewReader(tc.input), wasm.MaximumExports)
 			require.NoError(t, err)
 			require.Equal(t, tc.expected, actual)
 
@@ -214,7 +275,7 @@ func TestDecodeExportSection_Errors(t *testing.T) {
 		tc := tt
 
 		t.Run(tc.name, func(t *testing.T) {
-			_, _, err := decodeExportSection(bytes.NewReader(tc.input))
+			_, _, err := decodeExportSection(bytes.NewReader(tc.input), wasm.MaximumExports)
 			require.EqualError(t, err, tc.expectedErr)
 		})
 	}
@@ -241,3 +302,129 @@ func TestDecodeDataCountSection(t *testing.T) {
 		require.NoError(t, err)
 	})
 }
+
+func TestDecodeSection_countLimits(t *testing.T) {
+	// vector encodes count entries produced by entry, preceded by the count.
+	vector := func(count uint32, entry func(i uint32) []byte) []byte {
+		ret := leb128.EncodeUint32(count)
+		for i := uint32(0); i < count; i++ {
+			ret = append(ret, entry(i)...)
+		}
+		return ret
+	}
+	importEntry := func(i uint32) []byte {
+		return []byte{0x01, 'm', 0x01, 'f', wasm.ExternTypeFunc, 0x00} // func[0] type[0]
+	}
+	exportEntry := func(i uint32) []byte {
+		name := strconv.Itoa(int(i)) // unique name
+		return append(append([]byte{byte(len(name))}, name...), wasm.ExternTypeFunc, 0x00)
+	}
+	functionEntry := func(i uint32) []byte { return []byte{0x00} } // type[0]
+	codeEntry := func(i uint32) []byte { return []byte{0x02, 0x00, wasm.OpcodeEnd} }
+	typeEntry := func(i uint32) []byte { return []byte{0x60, 0x00, 0x00} } // func() -> ()
+
+	tests := []struct {
+		name, countName string
+		limit           uint32
+		entry           func(uint32) []byte
+		decode          func(r *bytes.Reader, limit uint32) error
+	}{
+		{
+			name:      "types",
+			countName: "types",
+			limit:     wasm.MaximumTypes,
+			entry:     typeEntry,
+			decode: func(r *bytes.Reader, limit uint32) error {
+				_, err := decodeTypeSection(api.CoreFeaturesV2, r, limit)
+				return err
+			},
+		},
+		{
+			name:      "imports",
+			countName: "imports",
+			limit:     wasm.MaximumImports,
+			entry:     importEntry,
+			decode: func(r *bytes.Reader, limit uint32) error {
+				_, _, _, _, _, _, _, err := decodeImportSection(r, newMemorySizer(wasm.MemoryLimitPages, false), wasm.MemoryLimitPages, api.CoreFeaturesV2, limit)
+				return err
+			},
+		},
+		{
+			name:      "exports",
+			countName: "exports",
+			limit:     wasm.MaximumExports,
+			entry:     exportEntry,
+			decode: func(r *bytes.Reader, limit uint32) error {
+				_, _, err := decodeExportSection(r, limit)
+				return err
+			},
+		},
+		{
+			name:      "functions",
+			countName: "functions",
+			limit:     wasm.MaximumFunctions,
+			entry:     functionEntry,
+			decode: func(r *bytes.Reader, limit uint32) error {
+				_, err := decodeFunctionSection(r, 0, limit)
+				return err
+			},
+		},
+		{
+			name:      "code",
+			countName: "functions",
+			limit:     wasm.MaximumFunctions,
+			entry:     codeEntry,
+			decode: func(r *bytes.Reader, limit uint32) error {
+				_, err := decodeCodeSection(r, 0, limit)
+				return err
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			require.NoError(t, tc.decode(bytes.NewReader(vector(tc.limit, tc.entry)), tc.limit))
+
+			// Exceeding the limit fails before reading any entries, so the
+			// entries aren't allocated: the count is all there is to read.
+			err := tc.decode(bytes.NewReader(leb128.EncodeUint32(tc.limit+1)), tc.limit)
+			require.EqualError(t, err, fmt.Sprintf("too many %s in a module: %d given with limit %d", tc.countName, tc.limit+1, tc.limit))
+
+			// Were it allocated, this count would exhaust memory.
+			err = tc.decode(bytes.NewReader(leb128.EncodeUint32(math.MaxUint32)), tc.limit)
+			require.EqualError(t, err, fmt.Sprintf("too many %s in a module: %d given with limit %d", tc.countName, uint32(math.MaxUint32), tc.limit))
+
+			// A lower limit is enforced the same way.
+			require.NoError(t, tc.decode(bytes.NewReader(vector(2, tc.entry)), 2))
+			err = tc.decode(bytes.NewReader(vector(3, tc.entry)), 2)
+			require.EqualError(t, err, fmt.Sprintf("too many %s in a module: 3 given with limit 2", tc.countName))
+		})
+	}
+
+	t.Run("imported functions", func(t *testing.T) {
+		_, err := decodeFunctionSection(bytes.NewReader(vector(2, functionEntry)), 1, 3)
+		require.NoError(t, err)
+		_, err = decodeFunctionSection(bytes.NewReader(vector(3, functionEntry)), 1, 3)
+		require.EqualError(t, err, "too many functions in a module: 4 given with limit 3")
+		_, err = decodeCodeSection(bytes.NewReader(vector(3, codeEntry)), 1, 3)
+		require.EqualError(t, err, "too many functions in a module: 4 given with limit 3")
+		_, err = decodeFunctionSection(bytes.NewReader(leb128.EncodeUint32(math.MaxUint32)), math.MaxUint32, 3)
+		require.EqualError(t, err, fmt.Sprintf("too many functions in a module: %d given with limit 3", 2*uint64(math.MaxUint32)))
+	})
+
+	t.Run("locals", func(t *testing.T) {
+		code := func(locals uint32) []byte {
+			body := append([]byte{0x01}, leb128.EncodeUint32(locals)...) // one local entry
+			body = append(body, wasm.ValueTypeI32, wasm.OpcodeEnd)
+			return append(leb128.EncodeUint32(uint32(len(body))), body...)
+		}
+
+		var c wasm.Code
+		require.NoError(t, decodeCode(bytes.NewReader(code(wasm.MaximumLocals)), 0, &c))
+		require.Equal(t, int(wasm.MaximumLocals), len(c.LocalTypes))
+
+		err := decodeCode(bytes.NewReader(code(wasm.MaximumLocals+1)), 0, &c)
+		require.EqualError(t, err, fmt.Sprintf("too many locals: %d given with limit %d", wasm.MaximumLocals+1, wasm.MaximumLocals))
+	})
+}
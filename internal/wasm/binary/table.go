@@ -23,7 +23,7 @@ func decodeTable(r *bytes.Reader, enabledFeatures api.CoreFeatures, ret *wasm.Ta
 		}
 	}
 
-	ret.Min, ret.Max, err = decodeLimitsType(r)
+	ret.Min, ret.Max, _, err = decodeLimitsType(r, false)
 	if err != nil {
 		return fmt.Errorf("read limits: %v", err)
 	}
@@ -32,7 +32,7 @@ func decodeTable(r *bytes.Reader, enabledFeatures api.CoreFeatures, ret *wasm.Ta
 	}
 	if ret.Max != nil {
 		if *ret.Max < ret.Min {
-			return fmt.Errorf("table size minimum must not be greater than maximum")
+			return fmt.Errorf("table size minimum must not be greater than maximum: min %d > max %d", ret.Min, *ret.Max)
 		}
 	}
 	return
@@ -84,9 +84,14 @@ func TestDecodeTableType_Errors(t *testing.T) {
 		{
 			name:        "max < min",
 			input:       []byte{wasm.RefTypeFuncref, 0x1, 0x80, 0x80, 0x4, 0},
-			expectedErr: "table size minimum must not be greater than maximum",
+			expectedErr: "table size minimum must not be greater than maximum: min 65536 > max 0",
 			features:    api.CoreFeatureReferenceTypes,
 		},
+		{
+			name:        "max < min funcref",
+			input:       []byte{wasm.RefTypeFuncref, 0x1, 0x2, 0x1},
+			expectedErr: "table size minimum must not be greater than maximum: min 2 > max 1",
+		},
 		{
 			name:        "min > limit",
 			input:       []byte{wasm.RefTypeFuncref, 0x0, 0xff, 0xff, 0xff, 0xff, 0xf},
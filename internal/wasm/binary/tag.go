@@ -0,0 +1,28 @@
+package binary
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/tetratelabs/wazero/internal/leb128"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+// decodeTagType decodes the attribute and the type index of a tag, and returns the type index. The only attribute
+// defined is zero, for exceptions.
+//
+// See https://github.com/WebAssembly/exception-handling/blob/main/proposals/exception-handling/legacy/Exceptions.md#tag-section
+func decodeTagType(r *bytes.Reader) (wasm.Index, error) {
+	attribute, err := r.ReadByte()
+	if err != nil {
+		return 0, fmt.Errorf("read attribute: %w", err)
+	} else if attribute != 0 {
+		return 0, fmt.Errorf("%w: invalid tag attribute: %#x != 0", ErrInvalidByte, attribute)
+	}
+
+	typeIndex, _, err := leb128.DecodeUint32(r)
+	if err != nil {
+		return 0, fmt.Errorf("read type index: %w", err)
+	}
+	return typeIndex, nil
+}
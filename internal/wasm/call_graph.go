@@ -0,0 +1,178 @@
+package wasm
+
+import (
+	"fmt"
+
+	"github.com/tetratelabs/wazero/internal/leb128"
+)
+
+// CallEdge is an edge of the call graph returned by Module.CallGraph.
+type CallEdge struct {
+	// Caller is the index of the function containing the call instruction.
+	Caller Index
+	// Callee is the index of the function called by OpcodeCall or
+	// OpcodeReturnCall. This is zero when Indirect is true.
+	Callee Index
+	// Indirect is true when this edge is an OpcodeCallIndirect or
+	// OpcodeReturnCallIndirect, which may call any function whose type
+	// matches TypeIndex.
+	Indirect bool
+	// TypeIndex is the index in the TypeSection of the signature of the
+	// callee. This is only set when Indirect is true.
+	TypeIndex Index
+}
+
+// CallGraph returns the call edges of all functions defined in this module,
+// ordered by caller and then by the first occurrence of each distinct callee
+// in the function body. Imported functions have no edges as their bodies are
+// not known.
+//
+// Note: This scans every function body, so the result should be cached by
+// the caller if used repeatedly. The module must be validated beforehand.
+func (m *Module) CallGraph() (edges []CallEdge) {
+	for i := range m.CodeSection {
+		body := m.CodeSection[i].Body
+		if body == nil { // host function
+			continue
+		}
+		caller := m.ImportFunctionCount + Index(i)
+		seen := map[CallEdge]struct{}{}
+		forEachCall(body, func(e CallEdge) {
+			e.Caller = caller
+			if _, ok := seen[e]; ok {
+				return
+			}
+			seen[e] = struct{}{}
+			edges = append(edges, e)
+		})
+	}
+	return
+}
+
+// LeafInstructionCount returns the number of instructions in the body of the
+// function at the local index, i.e. excluding imported functions, and whether
+// it is a leaf function which doesn't contain any call instruction.
+//
+// Note: The module must be validated beforehand.
+func (m *Module) LeafInstructionCount(localIdx Index) (count int, leaf bool) {
+	leaf = true
+	count = forEachCall(m.CodeSection[localIdx].Body, func(CallEdge) { leaf = false })
+	return
+}
+
+// forEachCall invokes fn for each call or call_indirect instruction in the
+// validated function body, and returns the number of instructions in it. Only
+// Callee, Indirect and TypeIndex are set.
+func forEachCall(body []byte, fn func(CallEdge)) (count int) {
+	pc := 0
+	u32 := func() uint32 {
+		v, n, err := leb128.LoadUint32(body[pc:])
+		if err != nil {
+			panic(fmt.Errorf("BUG: invalid function body: %w", err))
+		}
+		pc += int(n)
+		return v
+	}
+	s64 := func() {
+		_, n, err := leb128.LoadInt64(body[pc:])
+		if err != nil {
+			panic(fmt.Errorf("BUG: invalid function body: %w", err))
+		}
+		pc += int(n)
+	}
+	memArg := func() {
+		if u32()&MemArgMemoryIndexFlag != 0 {
+			u32() // memory index
+		}
+		// The offset is 64-bit for a 64-bit memory.
+		_, n, err := leb128.LoadUint64(body[pc:])
+		if err != nil {
+			panic(fmt.Errorf("BUG: invalid function body: %w", err))
+		}
+		pc += int(n)
+	}
+
+	for ; pc < len(body); count++ {
+		op := body[pc]
+		pc++
+		switch {
+		case op == OpcodeBlock || op == OpcodeLoop || op == OpcodeIf || op == OpcodeTry:
+			s64() // block type is a signed 33-bit integer.
+		case op == OpcodeBr || op == OpcodeBrIf:
+			u32()
+		case op == OpcodeCatch || op == OpcodeThrow || op == OpcodeRethrow || op == OpcodeDelegate:
+			u32() // tag or label index
+		case op == OpcodeBrTable:
+			for n := u32(); n > 0; n-- {
+				u32()
+			}
+			u32() // default target
+		case op == OpcodeCall || op == OpcodeReturnCall:
+			fn(CallEdge{Callee: u32()})
+		case op == OpcodeCallIndirect || op == OpcodeReturnCallIndirect:
+			typeIndex := u32()
+			u32() // table index
+			fn(CallEdge{Indirect: true, TypeIndex: typeIndex})
+		case op == OpcodeTypedSelect:
+			pc += int(u32()) // one byte per value type
+		case op >= OpcodeLocalGet && op <= OpcodeTableSet:
+			u32()
+		case op >= OpcodeI32Load && op <= OpcodeI64Store32:
+			memArg()
+		case op == OpcodeMemorySize || op == OpcodeMemoryGrow:
+			u32() // memory index
+		case op == OpcodeI32Const || op == OpcodeI64Const:
+			s64()
+		case op == OpcodeF32Const:
+			pc += 4
+		case op == OpcodeF64Const:
+			pc += 8
+		case op == OpcodeRefNull:
+			pc++ // reference type
+		case op == OpcodeRefFunc:
+			u32()
+		case op == OpcodeMiscPrefix:
+			switch OpcodeMisc(u32()) {
+			case OpcodeMiscMemoryInit:
+				u32() // data index
+				u32() // memory index
+			case OpcodeMiscMemoryCopy:
+				u32() // destination memory index
+				u32() // source memory index
+			case OpcodeMiscMemoryFill:
+				u32() // memory index
+			case OpcodeMiscTableInit, OpcodeMiscTableCopy:
+				u32()
+				u32()
+			case OpcodeMiscDataDrop, OpcodeMiscElemDrop, OpcodeMiscTableGrow, OpcodeMiscTableSize, OpcodeMiscTableFill:
+				u32()
+			}
+		case op == OpcodeAtomicPrefix:
+			if atomicOp := body[pc]; atomicOp == OpcodeAtomicFence {
+				pc += 2 // reserved byte
+			} else {
+				pc++
+				memArg()
+			}
+		case op == OpcodeVecPrefix:
+			if _, ok := VecRelaxedOpcode(body[pc:]); ok {
+				pc += 2 // relaxed-SIMD opcodes take two bytes and have no immediates.
+				break
+			}
+			vecOp := body[pc]
+			pc++
+			switch {
+			case vecOp <= OpcodeVecV128Store, vecOp == OpcodeVecV128Load32zero, vecOp == OpcodeVecV128Load64zero:
+				memArg()
+			case vecOp >= OpcodeVecV128Load8Lane && vecOp <= OpcodeVecV128Store64Lane:
+				memArg()
+				pc++ // lane index
+			case vecOp == OpcodeVecV128Const || vecOp == OpcodeVecV128i8x16Shuffle:
+				pc += 16
+			case vecOp >= OpcodeVecI8x16ExtractLaneS && vecOp <= OpcodeVecF64x2ReplaceLane:
+				pc++ // lane index
+			}
+		}
+	}
+	return
+}
@@ -0,0 +1,194 @@
+package wasm
+
+import (
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestModule_CallGraph(t *testing.T) {
+	v_v := FunctionType{}
+	i32_v := FunctionType{Params: []ValueType{ValueTypeI32}}
+
+	tests := []struct {
+		name     string
+		module   *Module
+		expected []CallEdge
+	}{
+		{
+			name:   "no functions",
+			module: &Module{},
+		},
+		{
+			name: "no calls",
+			module: &Module{
+				TypeSection:     []FunctionType{v_v},
+				FunctionSection: []Index{0},
+				CodeSection:     []Code{{Body: []byte{OpcodeEnd}}},
+			},
+		},
+		{
+			name: "direct calls",
+			module: &Module{
+				TypeSection:     []FunctionType{v_v},
+				FunctionSection: []Index{0, 0, 0},
+				CodeSection: []Code{
+					{Body: []byte{OpcodeCall, 1, OpcodeCall, 2, OpcodeCall, 1, OpcodeEnd}},
+					{Body: []byte{OpcodeCall, 1, OpcodeEnd}}, // recursive
+					{Body: []byte{OpcodeEnd}},
+				},
+			},
+			expected: []CallEdge{
+				{Caller: 0, Callee: 1},
+				{Caller: 0, Callee: 2},
+				{Caller: 1, Callee: 1},
+			},
+		},
+		{
+			name: "imported functions",
+			module: &Module{
+				TypeSection:         []FunctionType{v_v},
+				ImportSection:       []Import{{Type: ExternTypeFunc, DescFunc: 0}, {Type: ExternTypeFunc, DescFunc: 0}},
+				ImportFunctionCount: 2,
+				FunctionSection:     []Index{0, 0},
+				CodeSection: []Code{
+					{Body: []byte{OpcodeCall, 0, OpcodeCall, 3, OpcodeEnd}},
+					{Body: []byte{OpcodeCall, 1, OpcodeEnd}},
+				},
+			},
+			expected: []CallEdge{
+				{Caller: 2, Callee: 0},
+				{Caller: 2, Callee: 3},
+				{Caller: 3, Callee: 1},
+			},
+		},
+		{
+			name: "call_indirect",
+			module: &Module{
+				TypeSection:     []FunctionType{v_v, i32_v},
+				FunctionSection: []Index{0, 1},
+				TableSection:    []Table{{Min: 1, Type: RefTypeFuncref}, {Min: 1, Type: RefTypeFuncref}},
+				CodeSection: []Code{
+					{Body: []byte{
+						OpcodeI32Const, 0, OpcodeCallIndirect, 0, 0,
+						OpcodeI32Const, 0, OpcodeCallIndirect, 0, 1, // same type, different table
+						OpcodeI32Const, 0, OpcodeI32Const, 0, OpcodeCallIndirect, 1, 0,
+						OpcodeCall, 1,
+						OpcodeEnd,
+					}},
+					{Body: []byte{OpcodeEnd}},
+				},
+			},
+			expected: []CallEdge{
+				{Caller: 0, Indirect: true, TypeIndex: 0},
+				{Caller: 0, Indirect: true, TypeIndex: 1},
+				{Caller: 0, Callee: 1},
+			},
+		},
+		{
+			name: "tail calls",
+			module: &Module{
+				TypeSection:     []FunctionType{v_v},
+				FunctionSection: []Index{0, 0},
+				TableSection:    []Table{{Min: 1, Type: RefTypeFuncref}},
+				CodeSection: []Code{
+					{Body: []byte{OpcodeI32Const, 0, OpcodeReturnCallIndirect, 0, 0, OpcodeEnd}},
+					{Body: []byte{OpcodeReturnCall, 0, OpcodeEnd}},
+				},
+			},
+			expected: []CallEdge{
+				{Caller: 0, Indirect: true, TypeIndex: 0},
+				{Caller: 1, Callee: 0},
+			},
+		},
+		{
+			name: "immediates resembling call opcodes",
+			module: &Module{
+				TypeSection:     []FunctionType{v_v},
+				FunctionSection: []Index{0, 0},
+				CodeSection: []Code{
+					{Body: []byte{
+						OpcodeBlock, 0x40,
+						OpcodeI32Const, 0x90, 0x01, OpcodeDrop, // 0x90 is 0x10 | continuation bit.
+						OpcodeI64Const, 0x10, OpcodeDrop,
+						OpcodeF32Const, 0x10, 0x11, 0x10, 0x11, OpcodeDrop,
+						OpcodeF64Const, 0x10, 0x10, 0x10, 0x10, 0x11, 0x11, 0x11, 0x11, OpcodeDrop,
+						OpcodeI32Const, 0, OpcodeBrTable, 2, 0x10, 0x11, 0, // invalid targets, but only the encoding matters.
+						OpcodeEnd,
+						OpcodeVecPrefix, OpcodeVecV128Const,
+						0x10, 0x10, 0x10, 0x10, 0x10, 0x10, 0x10, 0x10, 0x10, 0x10, 0x10, 0x10, 0x10, 0x10, 0x10, 0x10,
+						OpcodeVecPrefix, OpcodeVecI8x16ExtractLaneS, 0x10, OpcodeDrop,
+						OpcodeI32Const, 0, OpcodeI32Const, 0, OpcodeI32Const, 0,
+						OpcodeMiscPrefix, OpcodeMiscMemoryInit, 0x10, 0,
+						OpcodeI32Const, 0, OpcodeI32Load, 0x2, 0x10, OpcodeDrop,
+						OpcodeCall, 1,
+						OpcodeEnd,
+					}},
+					{Body: []byte{OpcodeEnd}},
+				},
+			},
+			expected: []CallEdge{{Caller: 0, Callee: 1}},
+		},
+		{
+			name: "multi-byte memory indices",
+			module: &Module{
+				TypeSection:     []FunctionType{v_v},
+				FunctionSection: []Index{0, 0},
+				CodeSection: []Code{
+					{Body: []byte{
+						OpcodeI32Const, 0, OpcodeI32Const, 0, OpcodeI32Const, 0,
+						OpcodeMiscPrefix, OpcodeMiscMemoryCopy, 0x81, 0x10, 0x90, 0x10, // 0x90 is 0x10 | continuation bit.
+						OpcodeI32Const, 0, OpcodeI32Const, 0, OpcodeI32Const, 0,
+						OpcodeMiscPrefix, OpcodeMiscMemoryFill, 0x90, 0x10,
+						OpcodeCall, 1,
+						OpcodeEnd,
+					}},
+					{Body: []byte{OpcodeEnd}},
+				},
+			},
+			expected: []CallEdge{{Caller: 0, Callee: 1}},
+		},
+		{
+			name: "host functions",
+			module: &Module{
+				TypeSection:     []FunctionType{v_v},
+				FunctionSection: []Index{0},
+				CodeSection:     []Code{MustParseGoReflectFuncCode(func() {})},
+				IsHostModule:    true,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expected, tc.module.CallGraph())
+		})
+	}
+}
+
+func TestModule_LeafInstructionCount(t *testing.T) {
+	m := &Module{
+		TypeSection:         []FunctionType{{}},
+		ImportSection:       []Import{{Type: ExternTypeFunc, DescFunc: 0}},
+		ImportFunctionCount: 1,
+		FunctionSection:     []Index{0, 0, 0},
+		CodeSection: []Code{
+			{Body: []byte{OpcodeEnd}},
+			{Body: []byte{OpcodeI32Const, 0x80, 0x01, OpcodeI32Const, 1, OpcodeI32Add, OpcodeDrop, OpcodeEnd}},
+			{Body: []byte{OpcodeCall, 0, OpcodeEnd}},
+		},
+	}
+
+	count, leaf := m.LeafInstructionCount(0)
+	require.Equal(t, 1, count)
+	require.True(t, leaf)
+
+	count, leaf = m.LeafInstructionCount(1)
+	require.Equal(t, 5, count)
+	require.True(t, leaf)
+
+	count, leaf = m.LeafInstructionCount(2)
+	require.Equal(t, 2, count)
+	require.False(t, leaf)
+}
@@ -0,0 +1,147 @@
+package wasm
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/internal/internalapi"
+	internalsys "github.com/tetratelabs/wazero/internal/sys"
+	"github.com/tetratelabs/wazero/internal/wasmruntime"
+	"github.com/tetratelabs/wazero/sys"
+)
+
+// InstantiateDifferential instantiates the source of the module on another engine, as ModuleInstance.Differential.
+// The result isn't registered in the Store, so it can't be imported, but it imports the same modules as m.
+func (s *Store) InstantiateDifferential(
+	ctx context.Context,
+	engine Engine,
+	m *ModuleInstance,
+	sys *internalsys.Context,
+	beforeStart BeforeStart,
+	deferStart bool,
+) (err error) {
+	var memoryBuffer []byte
+	if m.memoryBufferSize > 0 { // Borrow a buffer of the same size, so that grows fail alike.
+		memoryBuffer = make([]byte, m.memoryBufferSize)
+	}
+	m.Differential, err = s.instantiate(ctx, m.Source, m.ModuleName, sys, m.TypeIDs, InstantiateOptions{
+		Engine:         engine,
+		BeforeStart:    beforeStart,
+		DeferStart:     deferStart,
+		ImportRenames:  m.importRenames,
+		ImportStubs:    m.importStubs,
+		MinMemoryPages: m.minMemoryPages,
+		MemoryBuffer:   memoryBuffer,
+	})
+	return
+}
+
+// differentialFunction calls a function of a module and of its ModuleInstance.Differential, and errs if the results,
+// traps or memories differ.
+type differentialFunction struct {
+	internalapi.WazeroOnlyType
+
+	name               string
+	m, differential    *ModuleInstance
+	fn, differentialFn api.Function
+}
+
+// Definition implements the same method as documented on api.Function.
+func (f *differentialFunction) Definition() api.FunctionDefinition {
+	return f.fn.Definition()
+}
+
+// Call implements the same method as documented on api.Function.
+func (f *differentialFunction) Call(ctx context.Context, params ...uint64) ([]uint64, error) {
+	results, err := f.fn.Call(ctx, params...)
+	differentialResults, differentialErr := f.differentialFn.Call(ctx, params...)
+	if e := f.compare(params, results, differentialResults, err, differentialErr); e != nil {
+		return nil, e
+	}
+	return results, err
+}
+
+// CallWithStack implements the same method as documented on api.Function.
+func (f *differentialFunction) CallWithStack(ctx context.Context, stack []uint64) error {
+	params := append([]uint64(nil), stack[:len(f.fn.Definition().ParamTypes())]...)
+	differentialStack := append([]uint64(nil), stack...)
+	err := f.fn.CallWithStack(ctx, stack)
+	differentialErr := f.differentialFn.CallWithStack(ctx, differentialStack)
+
+	resultCount := len(f.fn.Definition().ResultTypes())
+	return firstError(f.compare(params, stack[:resultCount], differentialStack[:resultCount], err, differentialErr), err)
+}
+
+func firstError(err, other error) error {
+	if err != nil {
+		return err
+	}
+	return other
+}
+
+// compare returns an error describing the first difference between the calls, if any.
+func (f *differentialFunction) compare(params, results, differentialResults []uint64, err, differentialErr error) error {
+	if kind, differentialKind := trapKind(err), trapKind(differentialErr); kind != differentialKind {
+		return f.errorf(params, "trap %q != %q", kind, differentialKind)
+	} else if err == nil {
+		for i := range results {
+			if results[i] != differentialResults[i] {
+				return f.errorf(params, "results %v != %v", results, differentialResults)
+			}
+		}
+	}
+
+	// Imported memories are shared, so only compare those defined by the module.
+	source := f.m.Source
+	for i := source.ImportMemoryCount; i < source.memoryCount(source.MemorySection); i++ {
+		mem, differentialMem := f.m.MemoryAt(i), f.differential.MemoryAt(i)
+		if offset, ok := firstDifference(mem.Buffer, differentialMem.Buffer); ok {
+			return f.errorf(params, "memory[%d] differs at offset %d", i, offset)
+		}
+	}
+	return nil
+}
+
+func (f *differentialFunction) errorf(params []uint64, format string, args ...interface{}) error {
+	return fmt.Errorf("differential check of %s.%s%v failed: %s", f.m.ModuleName, f.name, params, fmt.Sprintf(format, args...))
+}
+
+// firstDifference returns the first offset where the memories differ, including their length.
+func firstDifference(a, b []byte) (int, bool) {
+	if bytes.Equal(a, b) {
+		return 0, false
+	}
+	n := len(a)
+	if len(b) < n {
+		n = len(b)
+	}
+	for i := 0; i < n; i++ {
+		if a[i] != b[i] {
+			return i, true
+		}
+	}
+	return n, true
+}
+
+// trapKind returns the kind of error returned by a call, ignoring the stack trace which differs between engines.
+func trapKind(err error) string {
+	var exitErr *sys.ExitError
+	var wasmErr *wasmruntime.Error
+	switch {
+	case err == nil:
+		return ""
+	case errors.As(err, &exitErr):
+		return fmt.Sprintf("exit code %d", exitErr.ExitCode())
+	case errors.As(err, &wasmErr):
+		return wasmErr.Error()
+	}
+	msg := err.Error()
+	if i := strings.IndexByte(msg, '\n'); i >= 0 {
+		msg = msg[:i]
+	}
+	return msg
+}
@@ -0,0 +1,165 @@
+package wasm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/internal/internalapi"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasmruntime"
+	"github.com/tetratelabs/wazero/sys"
+)
+
+// stackFunction implements api.Function with a function of the stack.
+type stackFunction struct {
+	internalapi.WazeroOnlyType
+	def  api.FunctionDefinition
+	call func(m *ModuleInstance, stack []uint64) error
+	m    *ModuleInstance
+}
+
+// Definition implements the same method as documented on api.Function.
+func (f *stackFunction) Definition() api.FunctionDefinition { return f.def }
+
+// Call implements the same method as documented on api.Function.
+func (f *stackFunction) Call(ctx context.Context, params ...uint64) ([]uint64, error) {
+	stack := make([]uint64, 1)
+	copy(stack, params)
+	err := f.CallWithStack(ctx, stack)
+	return stack, err
+}
+
+// CallWithStack implements the same method as documented on api.Function.
+func (f *stackFunction) CallWithStack(_ context.Context, stack []uint64) error {
+	return f.call(f.m, stack)
+}
+
+func TestDifferentialFunction(t *testing.T) {
+	increment := func(_ *ModuleInstance, stack []uint64) error {
+		stack[0]++
+		return nil
+	}
+	unreachable := func(frame string) func(*ModuleInstance, []uint64) error {
+		return func(*ModuleInstance, []uint64) error {
+			return fmt.Errorf("wasm error: %w (recovered by wazero)\nwasm stack trace:\n\t%s", wasmruntime.ErrRuntimeUnreachable, frame)
+		}
+	}
+
+	tests := []struct {
+		name               string
+		call, differential func(*ModuleInstance, []uint64) error
+		expectedResults    []uint64
+		expectedErr        string
+	}{
+		{
+			name:            "same results",
+			call:            increment,
+			differential:    increment,
+			expectedResults: []uint64{2},
+		},
+		{
+			name: "different results",
+			call: increment,
+			differential: func(_ *ModuleInstance, stack []uint64) error {
+				stack[0] += 2
+				return nil
+			},
+			expectedErr: "differential check of m.f[1] failed: results [2] != [3]",
+		},
+		{
+			name:         "same trap",
+			call:         unreachable("m.f(i32) i32"),
+			differential: unreachable(".f(i32) i32"),
+			expectedErr: `wasm error: unreachable (recovered by wazero)
+wasm stack trace:
+	m.f(i32) i32`,
+		},
+		{
+			name:         "different trap",
+			call:         increment,
+			differential: unreachable("m.f(i32) i32"),
+			expectedErr:  `differential check of m.f[1] failed: trap "" != "unreachable"`,
+		},
+		{
+			name: "different exit code",
+			call: func(*ModuleInstance, []uint64) error {
+				return sys.NewExitError(1)
+			},
+			differential: func(*ModuleInstance, []uint64) error {
+				return sys.NewExitError(2)
+			},
+			expectedErr: `differential check of m.f[1] failed: trap "exit code 1" != "exit code 2"`,
+		},
+		{
+			name: "different error",
+			call: func(*ModuleInstance, []uint64) error {
+				return errors.New("a\nb")
+			},
+			differential: func(*ModuleInstance, []uint64) error {
+				return errors.New("c\nb")
+			},
+			expectedErr: `differential check of m.f[1] failed: trap "a" != "c"`,
+		},
+		{
+			name: "different memory",
+			call: func(m *ModuleInstance, stack []uint64) error {
+				m.MemoryInstance.Buffer[3] = 1
+				return increment(m, stack)
+			},
+			differential: increment,
+			expectedErr:  "differential check of m.f[1] failed: memory[0] differs at offset 3",
+		},
+		{
+			name: "different memory size",
+			call: increment,
+			differential: func(m *ModuleInstance, stack []uint64) error {
+				m.MemoryInstance.Buffer = append(m.MemoryInstance.Buffer, 0)
+				return increment(m, stack)
+			},
+			expectedErr: fmt.Sprintf("differential check of m.f[1] failed: memory[0] differs at offset %d", MemoryPageSize),
+		},
+	}
+
+	source := &Module{
+		TypeSection:     []FunctionType{{Params: []ValueType{ValueTypeI32}, Results: []ValueType{ValueTypeI32}}},
+		FunctionSection: []Index{0},
+		CodeSection:     []Code{{Body: []byte{OpcodeEnd}}},
+		MemorySection:   &Memory{Min: 1, Cap: 1, Max: 1},
+	}
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			m := &ModuleInstance{ModuleName: "m", Source: source, MemoryInstance: NewMemoryInstance(source.MemorySection)}
+			d := &ModuleInstance{ModuleName: "m", Source: source, MemoryInstance: NewMemoryInstance(source.MemorySection)}
+			f := &differentialFunction{
+				name:           "f",
+				m:              m,
+				differential:   d,
+				fn:             &stackFunction{def: source.FunctionDefinition(0), call: tc.call, m: m},
+				differentialFn: &stackFunction{def: source.FunctionDefinition(0), call: tc.differential, m: d},
+			}
+
+			results, err := f.Call(context.Background(), 1)
+			if tc.expectedErr != "" {
+				require.EqualError(t, err, tc.expectedErr)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, tc.expectedResults, results)
+			}
+
+			// Reset the memories, and check the same happens when calling with a stack.
+			m.MemoryInstance, d.MemoryInstance = NewMemoryInstance(source.MemorySection), NewMemoryInstance(source.MemorySection)
+			stack := []uint64{1}
+			err = f.CallWithStack(context.Background(), stack)
+			if tc.expectedErr != "" {
+				require.EqualError(t, err, tc.expectedErr)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, tc.expectedResults, stack)
+			}
+		})
+	}
+}
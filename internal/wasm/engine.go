@@ -5,6 +5,7 @@ import (
 
 	"github.com/tetratelabs/wazero/api"
 	"github.com/tetratelabs/wazero/experimental"
+	"github.com/tetratelabs/wazero/internal/wasmruntime"
 )
 
 // Engine is a Store-scoped mechanism to compile functions declared or imported by a module.
@@ -57,4 +58,82 @@ type ModuleEngine interface {
 	// FunctionInstanceReference returns Reference for the given Index for a FunctionInstance. The returned values are used by
 	// the initialization via ElementSegment.
 	FunctionInstanceReference(funcIndex Index) Reference
+
+	// ReferencedFunctionIndex returns the Index of the function which the Reference points to, when it was returned
+	// by FunctionInstanceReference of this ModuleEngine, or false otherwise.
+	ReferencedFunctionIndex(ref Reference) (Index, bool)
+}
+
+// FunctionReferencer is implemented by the api.Function returned by
+// ModuleEngine.NewFunction, so that the host can store it in a table.
+type FunctionReferencer interface {
+	// FunctionInstanceReference returns the same Reference as
+	// ModuleEngine.FunctionInstanceReference does for this function.
+	FunctionInstanceReference() Reference
+}
+
+// Disassembler is implemented by an Engine which compiles functions to
+// machine code, so that it can return the instructions of a function.
+type Disassembler interface {
+	// Disassemble returns the machine instructions of the function at the
+	// given Index of the module, which must have been compiled by this
+	// Engine and be defined in the module, i.e. not imported.
+	Disassemble(module *Module, funcIdx Index) ([]MachineInstruction, error)
+}
+
+// BoundsCheckCounter is implemented by an Engine which can eliminate the
+// bounds checks of memory accesses, so that it can count them per function.
+type BoundsCheckCounter interface {
+	// BoundsCheckStats returns the bounds checks of the loads and stores in
+	// the function at the given Index of the module, which must have been
+	// compiled by this Engine and be defined in the module.
+	BoundsCheckStats(module *Module, funcIdx Index) (BoundsCheckStats, error)
+}
+
+// TrapSiteLister is implemented by an Engine which decides where functions can
+// trap when compiling them, so that it can list those sites per function.
+type TrapSiteLister interface {
+	// TrapSites returns the sites which can trap in the function at the given
+	// Index of the module, ordered by TrapSite.Offset. The function must have
+	// been compiled by this Engine and be defined in the module.
+	TrapSites(module *Module, funcIdx Index) ([]TrapSite, error)
+}
+
+// MemorySanitizer is implemented by an Engine whose functions check loads
+// from Sanitized memories, so that the Store only tracks the initialized bytes
+// of the memories of modules it instantiates. See Store.MemorySanitizer.
+type MemorySanitizer interface {
+	// SanitizesMemory is a marker with no behavior.
+	SanitizesMemory()
+}
+
+// BoundsCheckStats is returned by BoundsCheckCounter.BoundsCheckStats.
+type BoundsCheckStats struct {
+	// Emitted is the number of bounds checks compiled.
+	Emitted int
+	// Eliminated is the number of memory accesses compiled without a bounds
+	// check, as one was already known to be in bounds.
+	Eliminated int
+}
+
+// TrapSite is returned by TrapSiteLister.TrapSites.
+type TrapSite struct {
+	// Offset is the offset in the code section of the instruction which can
+	// trap, as in the stack trace of the trap.
+	Offset uint64
+	// Cause is the error of the trap.
+	Cause *wasmruntime.Error
+}
+
+// MachineInstruction is an instruction returned by Disassembler.Disassemble.
+type MachineInstruction struct {
+	// Address is the offset of the instruction from the beginning of the
+	// function's machine code.
+	Address uint64
+	// Size is the size in bytes of the instruction.
+	Size uint32
+	// Mnemonic is the name of the instruction, e.g. "add".
+	Mnemonic string
+	// Operands are the formatted operands of the instruction.
+	Operands []string
 }
@@ -8,12 +8,33 @@ import (
 	"strings"
 
 	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
 	"github.com/tetratelabs/wazero/internal/leb128"
 )
 
 // The wazero specific limitation described at RATIONALE.md.
 const maximumValuesOnStack = 1 << 27
 
+// InstructionError is returned when validating the body of a function fails, to locate the failing instruction.
+type InstructionError struct {
+	// Index is the index of the function in the FunctionSection.
+	Index Index
+	// Offset is the offset of the instruction in Code.Body.
+	Offset uint64
+	// Err is the validation error.
+	Err error
+}
+
+// Error implements the error interface, returning the message of Err as the location is for tools.
+func (e *InstructionError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap allows use of errors.Is and errors.As on Err.
+func (e *InstructionError) Unwrap() error {
+	return e.Err
+}
+
 // validateFunction validates the instruction sequence of a function.
 // following the specification https://www.w3.org/TR/2019/REC-wasm-core-1-20191205/#instructions%E2%91%A2.
 //
@@ -29,10 +50,18 @@ const maximumValuesOnStack = 1 << 27
 func (m *Module) validateFunction(sts *stacks, enabledFeatures api.CoreFeatures, idx Index, functions []Index,
 	globals []GlobalType, memory *Memory, tables []Table, declaredFunctionIndexes map[Index]struct{}, br *bytes.Reader,
 ) error {
-	return m.validateFunctionWithMaxStackValues(sts, enabledFeatures, idx, functions, globals, memory, tables, maximumValuesOnStack, declaredFunctionIndexes, br)
+	return m.validateFunctionWithMaxStackValues(sts, enabledFeatures, idx, functions, globals, memory, tables, maximumValuesOnStack, MaximumBlockNestingDepth, declaredFunctionIndexes, br)
 }
 
-func readMemArg(pc uint64, body []byte) (align, offset uint32, read uint64, err error) {
+// MemArgMemoryIndexFlag is set in the alignment of a "memarg" immediate when a memory index follows the alignment,
+// which is only valid with experimental.CoreFeaturesMultiMemory. The flag is not part of the alignment.
+//
+// See https://github.com/WebAssembly/multi-memory/blob/main/proposals/multi-memory/Overview.md#binary-format
+const MemArgMemoryIndexFlag = 0x40
+
+// readMemArg reads a "memarg" immediate, and returns its alignment and the type of the address operand of the memory
+// it refers to: ValueTypeI64 for a 64-bit memory, whose offset is also 64-bit, and otherwise ValueTypeI32.
+func (m *Module) readMemArg(pc uint64, body []byte, enabledFeatures api.CoreFeatures, memory *Memory) (align uint32, addrType ValueType, read uint64, err error) {
 	align, num, err := leb128.LoadUint32(body[pc:])
 	if err != nil {
 		err = fmt.Errorf("read memory align: %v", err)
@@ -40,20 +69,55 @@ func readMemArg(pc uint64, body []byte) (align, offset uint32, read uint64, err
 	}
 	read += num
 
-	offset, num, err = leb128.LoadUint32(body[pc+num:])
+	var index Index
+	if align&MemArgMemoryIndexFlag != 0 {
+		if !enabledFeatures.IsEnabled(experimental.CoreFeaturesMultiMemory) {
+			err = fmt.Errorf("invalid memory alignment")
+			return
+		}
+		align &^= MemArgMemoryIndexFlag
+		index, num, err = readMemoryIndex(body[pc+read:], m.memoryCount(memory))
+		if err != nil {
+			return
+		}
+		read += num
+	}
+
+	addrType = m.memoryAt(memory, index).addressType()
+	if addrType == ValueTypeI64 {
+		_, num, err = leb128.LoadUint64(body[pc+read:])
+	} else {
+		_, num, err = leb128.LoadUint32(body[pc+read:])
+	}
 	if err != nil {
 		err = fmt.Errorf("read memory offset: %v", err)
 		return
 	}
 
 	read += num
-	return align, offset, read, nil
+	return align, addrType, read, nil
 }
 
-// validateFunctionWithMaxStackValues is like validateFunction, but allows overriding maxStackValues for testing.
+// readMemoryIndex reads a memory index immediate encoded as experimental.CoreFeaturesMultiMemory defines, and ensures
+// it is within the memoryCount.
+func readMemoryIndex(body []byte, memoryCount Index) (index Index, read uint64, err error) {
+	index, read, err = leb128.LoadUint32(body)
+	if err != nil {
+		err = fmt.Errorf("read memory index: %v", err)
+		return
+	}
+	if index >= memoryCount {
+		err = fmt.Errorf("unknown memory %d", index)
+	}
+	return
+}
+
+// validateFunctionWithMaxStackValues is like validateFunction, but allows overriding maxStackValues for testing, and
+// maxBlockNestingDepth for configuration.
 //
 // * stacks is to track the state of Wasm value and control frame stacks at anypoint of execution, and reused to reduce allocation.
 // * maxStackValues is the maximum height of values stack which the target is allowed to reach.
+// * maxBlockNestingDepth is the maximum number of block, loop or if instructions which can enclose each other.
 func (m *Module) validateFunctionWithMaxStackValues(
 	sts *stacks,
 	enabledFeatures api.CoreFeatures,
@@ -63,9 +127,18 @@ func (m *Module) validateFunctionWithMaxStackValues(
 	memory *Memory,
 	tables []Table,
 	maxStackValues int,
+	maxBlockNestingDepth uint32,
 	declaredFunctionIndexes map[Index]struct{},
 	br *bytes.Reader,
-) error {
+) (err error) {
+	// instructionStart is the offset of the instruction being validated, for the InstructionError.
+	var instructionStart uint64
+	defer func() {
+		if err != nil {
+			err = &InstructionError{Index: idx, Offset: instructionStart, Err: err}
+		}
+	}()
+
 	functionType := &m.TypeSection[m.FunctionSection[idx]]
 	code := &m.CodeSection[idx]
 	body := code.Body
@@ -75,10 +148,12 @@ func (m *Module) validateFunctionWithMaxStackValues(
 	valueTypeStack := &sts.vs
 	// We start with the outermost control block which is for function return if the code branches into it.
 	controlBlockStack := &sts.cs
+	memoryCount := m.memoryCount(memory)
 
 	// Now start walking through all the instructions in the body while tracking
 	// control blocks and value types to check the validity of all instructions.
 	for pc := uint64(0); pc < uint64(len(body)); pc++ {
+		instructionStart = pc
 		op := body[pc]
 		if false {
 			var instName string
@@ -92,12 +167,20 @@ func (m *Module) validateFunctionWithMaxStackValues(
 			fmt.Printf("handling %s, stack=%s, blocks: %v\n", instName, valueTypeStack.stack, controlBlockStack)
 		}
 
+		// Reject deep nesting before it grows the control block stack. Note:
+		// the stack always contains the frame of the function itself.
+		if op == OpcodeBlock || op == OpcodeLoop || op == OpcodeIf || op == OpcodeTry {
+			if depth := uint32(len(controlBlockStack.stack)); depth > maxBlockNestingDepth {
+				return fmt.Errorf("%s at depth %d exceeds block nesting limit %d", InstructionName(op), depth, maxBlockNestingDepth)
+			}
+		}
+
 		if OpcodeI32Load <= op && op <= OpcodeI64Store32 {
 			if memory == nil {
 				return fmt.Errorf("memory must exist for %s", InstructionName(op))
 			}
 			pc++
-			align, _, read, err := readMemArg(pc, body)
+			align, addrType, read, err := m.readMemArg(pc, body, enabledFeatures, memory)
 			if err != nil {
 				return err
 			}
@@ -107,7 +190,7 @@ func (m *Module) validateFunctionWithMaxStackValues(
 				if 1<<align > 32/8 {
 					return fmt.Errorf("invalid memory alignment")
 				}
-				if err := valueTypeStack.popAndVerifyType(ValueTypeI32); err != nil {
+				if err := valueTypeStack.popAndVerifyType(addrType); err != nil {
 					return err
 				}
 				valueTypeStack.push(ValueTypeI32)
@@ -115,7 +198,7 @@ func (m *Module) validateFunctionWithMaxStackValues(
 				if 1<<align > 32/8 {
 					return fmt.Errorf("invalid memory alignment")
 				}
-				if err := valueTypeStack.popAndVerifyType(ValueTypeI32); err != nil {
+				if err := valueTypeStack.popAndVerifyType(addrType); err != nil {
 					return err
 				}
 				valueTypeStack.push(ValueTypeF32)
@@ -126,7 +209,7 @@ func (m *Module) validateFunctionWithMaxStackValues(
 				if err := valueTypeStack.popAndVerifyType(ValueTypeI32); err != nil {
 					return err
 				}
-				if err := valueTypeStack.popAndVerifyType(ValueTypeI32); err != nil {
+				if err := valueTypeStack.popAndVerifyType(addrType); err != nil {
 					return err
 				}
 			case OpcodeF32Store:
@@ -136,14 +219,14 @@ func (m *Module) validateFunctionWithMaxStackValues(
 				if err := valueTypeStack.popAndVerifyType(ValueTypeF32); err != nil {
 					return err
 				}
-				if err := valueTypeStack.popAndVerifyType(ValueTypeI32); err != nil {
+				if err := valueTypeStack.popAndVerifyType(addrType); err != nil {
 					return err
 				}
 			case OpcodeI64Load:
 				if 1<<align > 64/8 {
 					return fmt.Errorf("invalid memory alignment")
 				}
-				if err := valueTypeStack.popAndVerifyType(ValueTypeI32); err != nil {
+				if err := valueTypeStack.popAndVerifyType(addrType); err != nil {
 					return err
 				}
 				valueTypeStack.push(ValueTypeI64)
@@ -151,7 +234,7 @@ func (m *Module) validateFunctionWithMaxStackValues(
 				if 1<<align > 64/8 {
 					return fmt.Errorf("invalid memory alignment")
 				}
-				if err := valueTypeStack.popAndVerifyType(ValueTypeI32); err != nil {
+				if err := valueTypeStack.popAndVerifyType(addrType); err != nil {
 					return err
 				}
 				valueTypeStack.push(ValueTypeF64)
@@ -162,7 +245,7 @@ func (m *Module) validateFunctionWithMaxStackValues(
 				if err := valueTypeStack.popAndVerifyType(ValueTypeI64); err != nil {
 					return err
 				}
-				if err := valueTypeStack.popAndVerifyType(ValueTypeI32); err != nil {
+				if err := valueTypeStack.popAndVerifyType(addrType); err != nil {
 					return err
 				}
 			case OpcodeF64Store:
@@ -172,14 +255,14 @@ func (m *Module) validateFunctionWithMaxStackValues(
 				if err := valueTypeStack.popAndVerifyType(ValueTypeF64); err != nil {
 					return err
 				}
-				if err := valueTypeStack.popAndVerifyType(ValueTypeI32); err != nil {
+				if err := valueTypeStack.popAndVerifyType(addrType); err != nil {
 					return err
 				}
 			case OpcodeI32Load8S:
 				if 1<<align > 1 {
 					return fmt.Errorf("invalid memory alignment")
 				}
-				if err := valueTypeStack.popAndVerifyType(ValueTypeI32); err != nil {
+				if err := valueTypeStack.popAndVerifyType(addrType); err != nil {
 					return err
 				}
 				valueTypeStack.push(ValueTypeI32)
@@ -187,7 +270,7 @@ func (m *Module) validateFunctionWithMaxStackValues(
 				if 1<<align > 1 {
 					return fmt.Errorf("invalid memory alignment")
 				}
-				if err := valueTypeStack.popAndVerifyType(ValueTypeI32); err != nil {
+				if err := valueTypeStack.popAndVerifyType(addrType); err != nil {
 					return err
 				}
 				valueTypeStack.push(ValueTypeI32)
@@ -195,7 +278,7 @@ func (m *Module) validateFunctionWithMaxStackValues(
 				if 1<<align > 1 {
 					return fmt.Errorf("invalid memory alignment")
 				}
-				if err := valueTypeStack.popAndVerifyType(ValueTypeI32); err != nil {
+				if err := valueTypeStack.popAndVerifyType(addrType); err != nil {
 					return err
 				}
 				valueTypeStack.push(ValueTypeI64)
@@ -206,7 +289,7 @@ func (m *Module) validateFunctionWithMaxStackValues(
 				if err := valueTypeStack.popAndVerifyType(ValueTypeI32); err != nil {
 					return err
 				}
-				if err := valueTypeStack.popAndVerifyType(ValueTypeI32); err != nil {
+				if err := valueTypeStack.popAndVerifyType(addrType); err != nil {
 					return err
 				}
 			case OpcodeI64Store8:
@@ -216,14 +299,14 @@ func (m *Module) validateFunctionWithMaxStackValues(
 				if err := valueTypeStack.popAndVerifyType(ValueTypeI64); err != nil {
 					return err
 				}
-				if err := valueTypeStack.popAndVerifyType(ValueTypeI32); err != nil {
+				if err := valueTypeStack.popAndVerifyType(addrType); err != nil {
 					return err
 				}
 			case OpcodeI32Load16S, OpcodeI32Load16U:
 				if 1<<align > 16/8 {
 					return fmt.Errorf("invalid memory alignment")
 				}
-				if err := valueTypeStack.popAndVerifyType(ValueTypeI32); err != nil {
+				if err := valueTypeStack.popAndVerifyType(addrType); err != nil {
 					return err
 				}
 				valueTypeStack.push(ValueTypeI32)
@@ -231,7 +314,7 @@ func (m *Module) validateFunctionWithMaxStackValues(
 				if 1<<align > 16/8 {
 					return fmt.Errorf("invalid memory alignment")
 				}
-				if err := valueTypeStack.popAndVerifyType(ValueTypeI32); err != nil {
+				if err := valueTypeStack.popAndVerifyType(addrType); err != nil {
 					return err
 				}
 				valueTypeStack.push(ValueTypeI64)
@@ -242,7 +325,7 @@ func (m *Module) validateFunctionWithMaxStackValues(
 				if err := valueTypeStack.popAndVerifyType(ValueTypeI32); err != nil {
 					return err
 				}
-				if err := valueTypeStack.popAndVerifyType(ValueTypeI32); err != nil {
+				if err := valueTypeStack.popAndVerifyType(addrType); err != nil {
 					return err
 				}
 			case OpcodeI64Store16:
@@ -252,14 +335,14 @@ func (m *Module) validateFunctionWithMaxStackValues(
 				if err := valueTypeStack.popAndVerifyType(ValueTypeI64); err != nil {
 					return err
 				}
-				if err := valueTypeStack.popAndVerifyType(ValueTypeI32); err != nil {
+				if err := valueTypeStack.popAndVerifyType(addrType); err != nil {
 					return err
 				}
 			case OpcodeI64Load32S, OpcodeI64Load32U:
 				if 1<<align > 32/8 {
 					return fmt.Errorf("invalid memory alignment")
 				}
-				if err := valueTypeStack.popAndVerifyType(ValueTypeI32); err != nil {
+				if err := valueTypeStack.popAndVerifyType(addrType); err != nil {
 					return err
 				}
 				valueTypeStack.push(ValueTypeI64)
@@ -270,7 +353,7 @@ func (m *Module) validateFunctionWithMaxStackValues(
 				if err := valueTypeStack.popAndVerifyType(ValueTypeI64); err != nil {
 					return err
 				}
-				if err := valueTypeStack.popAndVerifyType(ValueTypeI32); err != nil {
+				if err := valueTypeStack.popAndVerifyType(addrType); err != nil {
 					return err
 				}
 			}
@@ -279,21 +362,32 @@ func (m *Module) validateFunctionWithMaxStackValues(
 				return fmt.Errorf("memory must exist for %s", InstructionName(op))
 			}
 			pc++
-			val, num, err := leb128.LoadUint32(body[pc:])
-			if err != nil {
-				return fmt.Errorf("read immediate: %v", err)
-			}
-			if val != 0 || num != 1 {
-				return fmt.Errorf("memory instruction reserved bytes not zero with 1 byte")
+			var num uint64
+			var index Index
+			if enabledFeatures.IsEnabled(experimental.CoreFeaturesMultiMemory) {
+				var err error
+				if index, num, err = readMemoryIndex(body[pc:], memoryCount); err != nil {
+					return fmt.Errorf("%s: %v", InstructionName(op), err)
+				}
+			} else {
+				val, n, err := leb128.LoadUint32(body[pc:])
+				if err != nil {
+					return fmt.Errorf("read immediate: %v", err)
+				}
+				if val != 0 || n != 1 {
+					return fmt.Errorf("memory instruction reserved bytes not zero with 1 byte")
+				}
+				num = n
 			}
+			addrType := m.memoryAt(memory, index).addressType()
 			switch Opcode(op) {
 			case OpcodeMemoryGrow:
-				if err := valueTypeStack.popAndVerifyType(ValueTypeI32); err != nil {
+				if err := valueTypeStack.popAndVerifyType(addrType); err != nil {
 					return err
 				}
-				valueTypeStack.push(ValueTypeI32)
+				valueTypeStack.push(addrType)
 			case OpcodeMemorySize:
-				valueTypeStack.push(ValueTypeI32)
+				valueTypeStack.push(addrType)
 			}
 			pc += num - 1
 		} else if OpcodeI32Const <= op && op <= OpcodeF64Const {
@@ -379,7 +473,7 @@ func (m *Module) validateFunctionWithMaxStackValues(
 				if index >= uint32(len(globals)) {
 					return fmt.Errorf("invalid global index")
 				} else if !globals[index].Mutable {
-					return fmt.Errorf("%s when not mutable", OpcodeGlobalSetName)
+					return fmt.Errorf("%s on immutable global[%d]", OpcodeGlobalSetName, index)
 				} else if err := valueTypeStack.popAndVerifyType(
 					globals[index].ValType); err != nil {
 					return err
@@ -527,7 +621,12 @@ func (m *Module) validateFunctionWithMaxStackValues(
 
 			// br_table instruction is stack-polymorphic.
 			valueTypeStack.unreachable()
-		} else if op == OpcodeCall {
+		} else if op == OpcodeCall || op == OpcodeReturnCall {
+			if op == OpcodeReturnCall {
+				if err := enabledFeatures.RequireEnabled(experimental.CoreFeaturesTailCall); err != nil {
+					return fmt.Errorf("%s invalid as %v", OpcodeReturnCallName, err)
+				}
+			}
 			pc++
 			index, num, err := leb128.LoadUint32(body[pc:])
 			if err != nil {
@@ -540,13 +639,26 @@ func (m *Module) validateFunctionWithMaxStackValues(
 			funcType := &m.TypeSection[functions[index]]
 			for i := 0; i < len(funcType.Params); i++ {
 				if err := valueTypeStack.popAndVerifyType(funcType.Params[len(funcType.Params)-1-i]); err != nil {
-					return fmt.Errorf("type mismatch on %s operation param type: %v", OpcodeCallName, err)
+					return fmt.Errorf("type mismatch on %s operation param type: %v", InstructionName(op), err)
 				}
 			}
-			for _, exp := range funcType.Results {
-				valueTypeStack.push(exp)
+			if op == OpcodeReturnCall {
+				if err := validateReturnCallResults(OpcodeReturnCallName, funcType, functionType); err != nil {
+					return err
+				}
+				// return_call instruction is stack-polymorphic.
+				valueTypeStack.unreachable()
+			} else {
+				for _, exp := range funcType.Results {
+					valueTypeStack.push(exp)
+				}
+			}
+		} else if op == OpcodeCallIndirect || op == OpcodeReturnCallIndirect {
+			if op == OpcodeReturnCallIndirect {
+				if err := enabledFeatures.RequireEnabled(experimental.CoreFeaturesTailCall); err != nil {
+					return fmt.Errorf("%s invalid as %v", OpcodeReturnCallIndirectName, err)
+				}
 			}
-		} else if op == OpcodeCallIndirect {
 			pc++
 			typeIndex, num, err := leb128.LoadUint32(body[pc:])
 			if err != nil {
@@ -555,7 +667,7 @@ func (m *Module) validateFunctionWithMaxStackValues(
 			pc += num
 
 			if int(typeIndex) >= len(m.TypeSection) {
-				return fmt.Errorf("invalid type index at %s: %d", OpcodeCallIndirectName, typeIndex)
+				return fmt.Errorf("invalid type index at %s: %d", InstructionName(op), typeIndex)
 			}
 
 			tableIndex, num, err := leb128.LoadUint32(body[pc:])
@@ -575,20 +687,28 @@ func (m *Module) validateFunctionWithMaxStackValues(
 
 			table := tables[tableIndex]
 			if table.Type != RefTypeFuncref {
-				return fmt.Errorf("table is not funcref type but was %s for %s", RefTypeName(table.Type), OpcodeCallIndirectName)
+				return fmt.Errorf("table is not funcref type but was %s for %s", RefTypeName(table.Type), InstructionName(op))
 			}
 
 			if err = valueTypeStack.popAndVerifyType(ValueTypeI32); err != nil {
-				return fmt.Errorf("cannot pop the offset in table for %s", OpcodeCallIndirectName)
+				return fmt.Errorf("cannot pop the offset in table for %s", InstructionName(op))
 			}
 			funcType := &m.TypeSection[typeIndex]
 			for i := 0; i < len(funcType.Params); i++ {
 				if err = valueTypeStack.popAndVerifyType(funcType.Params[len(funcType.Params)-1-i]); err != nil {
-					return fmt.Errorf("type mismatch on %s operation input type", OpcodeCallIndirectName)
+					return fmt.Errorf("type mismatch on %s operation input type", InstructionName(op))
 				}
 			}
-			for _, exp := range funcType.Results {
-				valueTypeStack.push(exp)
+			if op == OpcodeReturnCallIndirect {
+				if err := validateReturnCallResults(OpcodeReturnCallIndirectName, funcType, functionType); err != nil {
+					return err
+				}
+				// return_call_indirect instruction is stack-polymorphic.
+				valueTypeStack.unreachable()
+			} else {
+				for _, exp := range funcType.Results {
+					valueTypeStack.push(exp)
+				}
 			}
 		} else if OpcodeI32Eqz <= op && op <= OpcodeI64Extend32S {
 			switch op {
@@ -918,16 +1038,14 @@ func (m *Module) validateFunctionWithMaxStackValues(
 					if err != nil {
 						return fmt.Errorf("failed to read data segment index for %s: %v", MiscInstructionName(miscOpcode), err)
 					}
-					if int(index) >= len(m.DataSection) {
-						return fmt.Errorf("index %d out of range of data section(len=%d)", index, len(m.DataSection))
+					if index >= *m.DataCountSection {
+						return fmt.Errorf("index %d out of range of data count section(%d)", index, *m.DataCountSection)
 					}
 					pc += num - 1
 				case OpcodeMiscMemoryInit, OpcodeMiscMemoryCopy, OpcodeMiscMemoryFill:
 					if memory == nil {
 						return fmt.Errorf("memory must exist for %s", MiscInstructionName(miscOpcode))
 					}
-					params = []ValueType{ValueTypeI32, ValueTypeI32, ValueTypeI32}
-
 					if miscOpcode == OpcodeMiscMemoryInit {
 						if m.DataCountSection == nil {
 							return fmt.Errorf("%s requires data count section", MiscInstructionName(miscOpcode))
@@ -939,23 +1057,29 @@ func (m *Module) validateFunctionWithMaxStackValues(
 						if err != nil {
 							return fmt.Errorf("failed to read data segment index for %s: %v", MiscInstructionName(miscOpcode), err)
 						}
-						if int(index) >= len(m.DataSection) {
-							return fmt.Errorf("index %d out of range of data section(len=%d)", index, len(m.DataSection))
+						if index >= *m.DataCountSection {
+							return fmt.Errorf("index %d out of range of data count section(%d)", index, *m.DataCountSection)
 						}
 						pc += num - 1
 					}
 
-					pc++
-					val, num, err := leb128.LoadUint32(body[pc:])
-					if err != nil {
-						return fmt.Errorf("failed to read memory index for %s: %v", MiscInstructionName(miscOpcode), err)
-					}
-					if val != 0 || num != 1 {
-						return fmt.Errorf("%s reserved byte must be zero encoded with 1 byte", MiscInstructionName(miscOpcode))
-					}
+					// memory.copy has two memory indexes, the destination followed by the source.
+					indexCount := 1
 					if miscOpcode == OpcodeMiscMemoryCopy {
+						indexCount = 2
+					}
+					var indexes [2]Index
+					for i := 0; i < indexCount; i++ {
 						pc++
-						// memory.copy needs two memory index which are reserved as zero.
+						if enabledFeatures.IsEnabled(experimental.CoreFeaturesMultiMemory) {
+							index, num, err := readMemoryIndex(body[pc:], memoryCount)
+							if err != nil {
+								return fmt.Errorf("%s: %v", MiscInstructionName(miscOpcode), err)
+							}
+							indexes[i] = index
+							pc += num - 1
+							continue
+						}
 						val, num, err := leb128.LoadUint32(body[pc:])
 						if err != nil {
 							return fmt.Errorf("failed to read memory index for %s: %v", MiscInstructionName(miscOpcode), err)
@@ -965,6 +1089,23 @@ func (m *Module) validateFunctionWithMaxStackValues(
 						}
 					}
 
+					// The addresses and lengths in a 64-bit memory are i64, except the length of memory.copy, which
+					// is only i64 when both memories are 64-bit.
+					dst := m.memoryAt(memory, indexes[0]).addressType()
+					switch miscOpcode {
+					case OpcodeMiscMemoryInit:
+						params = []ValueType{dst, ValueTypeI32, ValueTypeI32}
+					case OpcodeMiscMemoryCopy:
+						src := m.memoryAt(memory, indexes[1]).addressType()
+						n := ValueTypeI32
+						if dst == ValueTypeI64 && src == ValueTypeI64 {
+							n = ValueTypeI64
+						}
+						params = []ValueType{dst, src, n}
+					case OpcodeMiscMemoryFill:
+						params = []ValueType{dst, ValueTypeI32, dst}
+					}
+
 				case OpcodeMiscTableInit:
 					params = []ValueType{ValueTypeI32, ValueTypeI32, ValueTypeI32}
 					pc++
@@ -1089,6 +1230,13 @@ func (m *Module) validateFunctionWithMaxStackValues(
 			// Vector instructions come with two bytes where the first byte is always OpcodeVecPrefix,
 			// and the second byte determines the actual instruction.
 			vecOpcode := body[pc]
+			if relaxedOpcode, ok := VecRelaxedOpcode(body[pc:]); ok {
+				pc++ // relaxed-SIMD opcodes take two bytes.
+				if err := validateVecRelaxed(relaxedOpcode, valueTypeStack, enabledFeatures); err != nil {
+					return err
+				}
+				continue
+			}
 			if err := enabledFeatures.RequireEnabled(api.CoreFeatureSIMD); err != nil {
 				return fmt.Errorf("%s invalid as %v", vectorInstructionName[vecOpcode], err)
 			}
@@ -1115,7 +1263,7 @@ func (m *Module) validateFunctionWithMaxStackValues(
 					return fmt.Errorf("memory must exist for %s", VectorInstructionName(vecOpcode))
 				}
 				pc++
-				align, _, read, err := readMemArg(pc, body)
+				align, addrType, read, err := m.readMemArg(pc, body, enabledFeatures, memory)
 				if err != nil {
 					return err
 				}
@@ -1144,7 +1292,7 @@ func (m *Module) validateFunctionWithMaxStackValues(
 				if 1<<align > maxAlign {
 					return fmt.Errorf("invalid memory alignment %d for %s", align, VectorInstructionName(vecOpcode))
 				}
-				if err := valueTypeStack.popAndVerifyType(ValueTypeI32); err != nil {
+				if err := valueTypeStack.popAndVerifyType(addrType); err != nil {
 					return fmt.Errorf("cannot pop the operand for %s: %v", VectorInstructionName(vecOpcode), err)
 				}
 				valueTypeStack.push(ValueTypeV128)
@@ -1153,7 +1301,7 @@ func (m *Module) validateFunctionWithMaxStackValues(
 					return fmt.Errorf("memory must exist for %s", VectorInstructionName(vecOpcode))
 				}
 				pc++
-				align, _, read, err := readMemArg(pc, body)
+				align, addrType, read, err := m.readMemArg(pc, body, enabledFeatures, memory)
 				if err != nil {
 					return err
 				}
@@ -1164,7 +1312,7 @@ func (m *Module) validateFunctionWithMaxStackValues(
 				if err := valueTypeStack.popAndVerifyType(ValueTypeV128); err != nil {
 					return fmt.Errorf("cannot pop the operand for %s: %v", OpcodeVecV128StoreName, err)
 				}
-				if err := valueTypeStack.popAndVerifyType(ValueTypeI32); err != nil {
+				if err := valueTypeStack.popAndVerifyType(addrType); err != nil {
 					return fmt.Errorf("cannot pop the operand for %s: %v", OpcodeVecV128StoreName, err)
 				}
 			case OpcodeVecV128Load8Lane, OpcodeVecV128Load16Lane, OpcodeVecV128Load32Lane, OpcodeVecV128Load64Lane:
@@ -1173,7 +1321,7 @@ func (m *Module) validateFunctionWithMaxStackValues(
 				}
 				attr := vecLoadLanes[vecOpcode]
 				pc++
-				align, _, read, err := readMemArg(pc, body)
+				align, addrType, read, err := m.readMemArg(pc, body, enabledFeatures, memory)
 				if err != nil {
 					return err
 				}
@@ -1191,7 +1339,7 @@ func (m *Module) validateFunctionWithMaxStackValues(
 				if err := valueTypeStack.popAndVerifyType(ValueTypeV128); err != nil {
 					return fmt.Errorf("cannot pop the operand for %s: %v", vectorInstructionName[vecOpcode], err)
 				}
-				if err := valueTypeStack.popAndVerifyType(ValueTypeI32); err != nil {
+				if err := valueTypeStack.popAndVerifyType(addrType); err != nil {
 					return fmt.Errorf("cannot pop the operand for %s: %v", vectorInstructionName[vecOpcode], err)
 				}
 				valueTypeStack.push(ValueTypeV128)
@@ -1201,7 +1349,7 @@ func (m *Module) validateFunctionWithMaxStackValues(
 				}
 				attr := vecStoreLanes[vecOpcode]
 				pc++
-				align, _, read, err := readMemArg(pc, body)
+				align, addrType, read, err := m.readMemArg(pc, body, enabledFeatures, memory)
 				if err != nil {
 					return err
 				}
@@ -1219,7 +1367,7 @@ func (m *Module) validateFunctionWithMaxStackValues(
 				if err := valueTypeStack.popAndVerifyType(ValueTypeV128); err != nil {
 					return fmt.Errorf("cannot pop the operand for %s: %v", vectorInstructionName[vecOpcode], err)
 				}
-				if err := valueTypeStack.popAndVerifyType(ValueTypeI32); err != nil {
+				if err := valueTypeStack.popAndVerifyType(addrType); err != nil {
 					return fmt.Errorf("cannot pop the operand for %s: %v", vectorInstructionName[vecOpcode], err)
 				}
 			case OpcodeVecI8x16ExtractLaneS,
@@ -1388,6 +1536,13 @@ func (m *Module) validateFunctionWithMaxStackValues(
 			default:
 				return fmt.Errorf("TODO: SIMD instruction %s will be implemented in #506", vectorInstructionName[vecOpcode])
 			}
+		} else if op == OpcodeAtomicPrefix {
+			pc++
+			read, err := m.validateAtomic(pc, body, valueTypeStack, enabledFeatures, memory)
+			if err != nil {
+				return err
+			}
+			pc += read
 		} else if op == OpcodeBlock {
 			br.Reset(body[pc+1:])
 			bt, num, err := DecodeBlockType(m.TypeSection, br, enabledFeatures)
@@ -1455,6 +1610,126 @@ func (m *Module) validateFunctionWithMaxStackValues(
 			for _, p := range bl.blockType.Params {
 				valueTypeStack.push(p)
 			}
+		} else if op == OpcodeTry {
+			if err := enabledFeatures.RequireEnabled(experimental.CoreFeaturesExceptionHandling); err != nil {
+				return fmt.Errorf("%s invalid as %v", OpcodeTryName, err)
+			}
+			m.UsesExceptionHandling = true
+			br.Reset(body[pc+1:])
+			bt, num, err := DecodeBlockType(m.TypeSection, br, enabledFeatures)
+			if err != nil {
+				return fmt.Errorf("read block: %w", err)
+			}
+			controlBlockStack.push(pc, 0, 0, bt, num, op)
+			if err = valueTypeStack.popParams(op, bt.Params, false); err != nil {
+				return err
+			}
+			// Plus we have to push any block params again.
+			for _, p := range bt.Params {
+				valueTypeStack.push(p)
+			}
+			valueTypeStack.pushStackLimit(len(bt.Params))
+			pc += num
+		} else if op == OpcodeCatch || op == OpcodeCatchAll {
+			if err := enabledFeatures.RequireEnabled(experimental.CoreFeaturesExceptionHandling); err != nil {
+				return fmt.Errorf("%s invalid as %v", InstructionName(op), err)
+			}
+			if len(controlBlockStack.stack) == 0 {
+				return fmt.Errorf("redundant %s instruction at %#x", InstructionName(op), pc)
+			}
+			bl := &controlBlockStack.stack[len(controlBlockStack.stack)-1]
+			if bl.op != OpcodeTry && bl.op != OpcodeCatch {
+				return fmt.Errorf("%s must follow %s or %s", InstructionName(op), OpcodeTryName, OpcodeCatchName)
+			}
+			var tagType *FunctionType
+			if op == OpcodeCatch {
+				pc++
+				index, num, err := leb128.LoadUint32(body[pc:])
+				if err != nil {
+					return fmt.Errorf("read immediate: %v", err)
+				} else if tagType = m.TagType(index); tagType == nil {
+					return fmt.Errorf("unknown tag %d", index)
+				}
+				pc += num - 1
+			}
+			// Check the type soundness of the instructions before this handler.
+			if err := valueTypeStack.popResults(bl.op, bl.blockType.Results, true); err != nil {
+				return err
+			}
+			// The handler begins with only the values carried by the exception.
+			valueTypeStack.resetAtStackLimit()
+			if tagType != nil {
+				for _, p := range tagType.Params {
+					valueTypeStack.push(p)
+				}
+			}
+			bl.op = op
+		} else if op == OpcodeThrow {
+			if err := enabledFeatures.RequireEnabled(experimental.CoreFeaturesExceptionHandling); err != nil {
+				return fmt.Errorf("%s invalid as %v", OpcodeThrowName, err)
+			}
+			pc++
+			index, num, err := leb128.LoadUint32(body[pc:])
+			if err != nil {
+				return fmt.Errorf("read immediate: %v", err)
+			}
+			tagType := m.TagType(index)
+			if tagType == nil {
+				return fmt.Errorf("unknown tag %d", index)
+			}
+			pc += num - 1
+			if err = valueTypeStack.popParams(op, tagType.Params, false); err != nil {
+				return err
+			}
+			// throw instruction is stack-polymorphic.
+			valueTypeStack.unreachable()
+		} else if op == OpcodeRethrow {
+			if err := enabledFeatures.RequireEnabled(experimental.CoreFeaturesExceptionHandling); err != nil {
+				return fmt.Errorf("%s invalid as %v", OpcodeRethrowName, err)
+			}
+			pc++
+			index, num, err := leb128.LoadUint32(body[pc:])
+			if err != nil {
+				return fmt.Errorf("read immediate: %v", err)
+			} else if int(index) >= len(controlBlockStack.stack) {
+				return fmt.Errorf("invalid %s operation: index out of range", OpcodeRethrowName)
+			}
+			pc += num - 1
+			if target := &controlBlockStack.stack[len(controlBlockStack.stack)-int(index)-1]; target.op != OpcodeCatch && target.op != OpcodeCatchAll {
+				return fmt.Errorf("invalid %s operation: label %d isn't a %s or %s", OpcodeRethrowName, index, OpcodeCatchName, OpcodeCatchAllName)
+			}
+			// rethrow instruction is stack-polymorphic.
+			valueTypeStack.unreachable()
+		} else if op == OpcodeDelegate {
+			if err := enabledFeatures.RequireEnabled(experimental.CoreFeaturesExceptionHandling); err != nil {
+				return fmt.Errorf("%s invalid as %v", OpcodeDelegateName, err)
+			}
+			if len(controlBlockStack.stack) == 0 {
+				return fmt.Errorf("redundant %s instruction at %#x", OpcodeDelegateName, pc)
+			} else if bl := &controlBlockStack.stack[len(controlBlockStack.stack)-1]; bl.op != OpcodeTry {
+				return fmt.Errorf("%s must follow %s", OpcodeDelegateName, OpcodeTryName)
+			}
+			pc++
+			index, num, err := leb128.LoadUint32(body[pc:])
+			if err != nil {
+				return fmt.Errorf("read immediate: %v", err)
+			}
+			pc += num - 1
+			// Like OpcodeEnd, delegate terminates the try block.
+			bl := controlBlockStack.pop()
+			bl.endAt = pc
+			if err := valueTypeStack.requireStackValues(false, OpcodeTryName, bl.blockType.Results, true); err != nil {
+				return err
+			}
+			valueTypeStack.resetAtStackLimit()
+			for _, exp := range bl.blockType.Results {
+				valueTypeStack.push(exp)
+			}
+			valueTypeStack.popStackLimit()
+			// The label is relative to the blocks enclosing the try block.
+			if int(index) >= len(controlBlockStack.stack) {
+				return fmt.Errorf("invalid %s operation: index out of range", OpcodeDelegateName)
+			}
 		} else if op == OpcodeEnd {
 			if len(controlBlockStack.stack) == 0 {
 				return fmt.Errorf("redundant End instruction at %#x", pc)
@@ -1566,6 +1841,17 @@ func (m *Module) validateFunctionWithMaxStackValues(
 	return nil
 }
 
+// validateReturnCallResults ensures the results of the callee of a tail call
+// are the same as those of the calling function, as the callee returns them
+// to the caller of the calling function.
+func validateReturnCallResults(name string, callee, caller *FunctionType) error {
+	if !bytes.Equal(callee.Results, caller.Results) {
+		return fmt.Errorf("type mismatch on %s operation: callee type %s must have the same results as the function type %s",
+			name, callee, caller)
+	}
+	return nil
+}
+
 var vecExtractLanes = [...]struct {
 	laneCeil   byte
 	resultType ValueType
@@ -1907,10 +2193,19 @@ func DecodeBlockType(types []FunctionType, r *bytes.Reader, enabledFeatures api.
 	case -4: // 0x7c in original byte = f64
 		ret = blockType_v_f64
 	case -5: // 0x7b in original byte = v128
+		if err = enabledFeatures.RequireEnabled(api.CoreFeatureSIMD); err != nil {
+			return nil, num, fmt.Errorf("block with v128 result invalid as %v", err)
+		}
 		ret = blockType_v_v128
 	case -16: // 0x70 in original byte = funcref
+		if err = enabledFeatures.RequireEnabled(api.CoreFeatureReferenceTypes); err != nil {
+			return nil, num, fmt.Errorf("block with funcref result invalid as %v", err)
+		}
 		ret = blockType_v_funcref
 	case -17: // 0x6f in original byte = externref
+		if err = enabledFeatures.RequireEnabled(api.CoreFeatureReferenceTypes); err != nil {
+			return nil, num, fmt.Errorf("block with externref result invalid as %v", err)
+		}
 		ret = blockType_v_externref
 	default:
 		if err = enabledFeatures.RequireEnabled(api.CoreFeatureMultiValue); err != nil {
@@ -1952,3 +2247,113 @@ func SplitCallStack(ft *FunctionType, stack []uint64) (params []uint64, results
 	}
 	return
 }
+
+// ZeroExtend32BitResults clears the upper 32-bits of any i32 or f32 results.
+// Engines which only write the lower 32-bits of a result's stack slot call
+// this, so the result doesn't include bits left over from a previous value in
+// the same slot, e.g. an i64 param.
+func ZeroExtend32BitResults(ft *FunctionType, results []uint64) {
+	i := 0
+	for _, t := range ft.Results {
+		switch t {
+		case ValueTypeI32, ValueTypeF32:
+			results[i] = uint64(uint32(results[i]))
+		case ValueTypeV128:
+			i++ // v128 takes two slots.
+		}
+		i++
+	}
+}
+
+// validateVecRelaxed validates the relaxed-SIMD instruction of the given opcode, all of which only take and return
+// ValueTypeV128.
+func validateVecRelaxed(op OpcodeVecRelaxed, valueTypeStack *valueTypeStack, enabledFeatures api.CoreFeatures) error {
+	name := VecRelaxedInstructionName(op)
+	if name == "" {
+		return fmt.Errorf("invalid relaxed-SIMD instruction 0x%x", op)
+	}
+	if err := enabledFeatures.RequireEnabled(experimental.CoreFeaturesRelaxedSIMD); err != nil {
+		return fmt.Errorf("%s invalid as %v", name, err)
+	}
+
+	var params int
+	switch op {
+	case OpcodeVecRelaxedI32x4TruncF32x4S, OpcodeVecRelaxedI32x4TruncF32x4U,
+		OpcodeVecRelaxedI32x4TruncF64x2SZero, OpcodeVecRelaxedI32x4TruncF64x2UZero:
+		params = 1
+	case OpcodeVecRelaxedF32x4Madd, OpcodeVecRelaxedF32x4Nmadd, OpcodeVecRelaxedF64x2Madd, OpcodeVecRelaxedF64x2Nmadd,
+		OpcodeVecRelaxedI8x16Laneselect, OpcodeVecRelaxedI16x8Laneselect,
+		OpcodeVecRelaxedI32x4Laneselect, OpcodeVecRelaxedI64x2Laneselect,
+		OpcodeVecRelaxedI32x4DotI8x16I7x16AddS:
+		params = 3
+	default:
+		params = 2
+	}
+	for i := 0; i < params; i++ {
+		if err := valueTypeStack.popAndVerifyType(ValueTypeV128); err != nil {
+			return fmt.Errorf("cannot pop the operand for %s: %v", name, err)
+		}
+	}
+	valueTypeStack.push(ValueTypeV128)
+	return nil
+}
+
+// validateAtomic validates the atomic instruction whose OpcodeAtomic is at body[pc], returning the number of bytes of
+// its immediates.
+func (m *Module) validateAtomic(pc uint64, body []byte, valueTypeStack *valueTypeStack, enabledFeatures api.CoreFeatures,
+	memory *Memory,
+) (read uint64, err error) {
+	op := body[pc]
+	name := AtomicInstructionName(op)
+	if name == "" {
+		return 0, fmt.Errorf("invalid atomic instruction 0x%x", op)
+	}
+	if err = enabledFeatures.RequireEnabled(experimental.CoreFeaturesThreads); err != nil {
+		return 0, fmt.Errorf("%s invalid as %v", name, err)
+	}
+
+	if op == OpcodeAtomicFence {
+		if pc+1 >= uint64(len(body)) || body[pc+1] != 0 {
+			return 0, fmt.Errorf("%s reserved byte must be zero", name)
+		}
+		return 1, nil
+	}
+
+	if memory == nil {
+		return 0, fmt.Errorf("memory must exist for %s", name)
+	}
+	align, addrType, read, err := m.readMemArg(pc+1, body, enabledFeatures, memory)
+	if err != nil {
+		return 0, err
+	}
+	t, size := AtomicMemoryAccess(op)
+	if 1<<align != size {
+		return 0, fmt.Errorf("invalid memory alignment for %s: must be %d", name, size)
+	}
+
+	var params []ValueType
+	var result ValueType
+	switch {
+	case op == OpcodeAtomicMemoryNotify:
+		params, result = []ValueType{addrType, ValueTypeI32}, ValueTypeI32
+	case op == OpcodeAtomicMemoryWait32 || op == OpcodeAtomicMemoryWait64:
+		params, result = []ValueType{addrType, t, ValueTypeI64}, ValueTypeI32
+	case op < OpcodeAtomicI32Store:
+		params, result = []ValueType{addrType}, t
+	case op < OpcodeAtomicI32RmwAdd:
+		params = []ValueType{addrType, t}
+	case op < OpcodeAtomicI32RmwCmpxchg:
+		params, result = []ValueType{addrType, t}, t
+	default:
+		params, result = []ValueType{addrType, t, t}, t
+	}
+	for i := len(params) - 1; i >= 0; i-- {
+		if err = valueTypeStack.popAndVerifyType(params[i]); err != nil {
+			return 0, fmt.Errorf("cannot pop the operand for %s: %v", name, err)
+		}
+	}
+	if result != 0 {
+		valueTypeStack.push(result)
+	}
+	return read, nil
+}
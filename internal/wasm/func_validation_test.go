@@ -6,6 +6,7 @@ import (
 	"testing"
 
 	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
 	"github.com/tetratelabs/wazero/internal/leb128"
 	"github.com/tetratelabs/wazero/internal/testing/require"
 )
@@ -36,18 +37,63 @@ func TestModule_ValidateFunction_validateFunctionWithMaxStackValues(t *testing.T
 
 	t.Run("not exceed", func(t *testing.T) {
 		err := m.validateFunctionWithMaxStackValues(&stacks{}, api.CoreFeaturesV1,
-			0, []Index{0}, nil, nil, nil, max+1, nil, bytes.NewReader(nil))
+			0, []Index{0}, nil, nil, nil, max+1, MaximumBlockNestingDepth, nil, bytes.NewReader(nil))
 		require.NoError(t, err)
 	})
 	t.Run("exceed", func(t *testing.T) {
 		err := m.validateFunctionWithMaxStackValues(&stacks{}, api.CoreFeaturesV1,
-			0, []Index{0}, nil, nil, nil, max, nil, bytes.NewReader(nil))
+			0, []Index{0}, nil, nil, nil, max, MaximumBlockNestingDepth, nil, bytes.NewReader(nil))
 		require.Error(t, err)
 		expMsg := fmt.Sprintf("function may have %d stack values, which exceeds limit %d", valuesNum, max)
 		require.Equal(t, expMsg, err.Error())
 	})
 }
 
+func TestModule_ValidateFunction_maxBlockNestingDepth(t *testing.T) {
+	// nested returns a function body of depth nested instructions of op.
+	nested := func(op Opcode, depth int) []byte {
+		var body []byte
+		for i := 0; i < depth; i++ {
+			if op == OpcodeIf {
+				body = append(body, OpcodeI32Const, 1)
+			}
+			body = append(body, op, 0x40) // 0x40 is the empty block type.
+		}
+		for i := 0; i < depth; i++ {
+			body = append(body, OpcodeEnd)
+		}
+		return append(body, OpcodeEnd)
+	}
+
+	for _, op := range []Opcode{OpcodeBlock, OpcodeLoop, OpcodeIf} {
+		op := op
+		t.Run(InstructionName(op), func(t *testing.T) {
+			const max = 10
+			validate := func(depth int, maxBlockNestingDepth uint32) error {
+				m := &Module{
+					TypeSection:     []FunctionType{v_v},
+					FunctionSection: []Index{0},
+					CodeSection:     []Code{{Body: nested(op, depth)}},
+				}
+				return m.validateFunctionWithMaxStackValues(&stacks{}, api.CoreFeaturesV1,
+					0, []Index{0}, nil, nil, nil, maximumValuesOnStack, maxBlockNestingDepth, nil, bytes.NewReader(nil))
+			}
+
+			t.Run("not exceed", func(t *testing.T) {
+				require.NoError(t, validate(max, max))
+			})
+			t.Run("exceed", func(t *testing.T) {
+				err := validate(max+1, max)
+				require.EqualError(t, err, fmt.Sprintf("%s at depth 11 exceeds block nesting limit 10", InstructionName(op)))
+			})
+			t.Run("pathological", func(t *testing.T) {
+				err := validate(int(MaximumBlockNestingDepth)*4, MaximumBlockNestingDepth)
+				require.EqualError(t, err, fmt.Sprintf("%s at depth 65537 exceeds block nesting limit 65536", InstructionName(op)))
+			})
+		})
+	}
+}
+
 func TestModule_ValidateFunction_SignExtensionOps(t *testing.T) {
 	tests := []struct {
 		input                Opcode
@@ -291,7 +337,7 @@ func TestModule_ValidateFunction_BulkMemoryOperations(t *testing.T) {
 
 				body = append(body, OpcodeEnd)
 
-				c := uint32(0)
+				c := uint32(1)
 				m := &Module{
 					TypeSection:      []FunctionType{v_v},
 					FunctionSection:  []Index{0},
@@ -346,7 +392,7 @@ func TestModule_ValidateFunction_BulkMemoryOperations(t *testing.T) {
 				flag:        api.CoreFeatureBulkMemoryOperations,
 				memory:      &Memory{},
 				dataSection: []DataSegment{{}},
-				expectedErr: "index 100 out of range of data section(len=1)",
+				expectedErr: "index 100 out of range of data count section(1)",
 			},
 			{
 				body:        []byte{OpcodeMiscPrefix, OpcodeMiscMemoryInit, 0},
@@ -407,7 +453,7 @@ func TestModule_ValidateFunction_BulkMemoryOperations(t *testing.T) {
 				flag:        api.CoreFeatureBulkMemoryOperations,
 				memory:      &Memory{},
 				dataSection: []DataSegment{{}},
-				expectedErr: "index 100 out of range of data section(len=1)",
+				expectedErr: "index 100 out of range of data count section(1)",
 			},
 			// memory.copy
 			{
@@ -662,7 +708,7 @@ func TestModule_ValidateFunction_BulkMemoryOperations(t *testing.T) {
 					DataSection:     tc.dataSection,
 				}
 				if !tc.dataCountSectionNil {
-					c := uint32(0)
+					c := uint32(len(tc.dataSection))
 					m.DataCountSection = &c
 				}
 				err := m.validateFunction(&stacks{}, tc.flag, 0, []Index{0}, nil, tc.memory, tc.tables, nil, bytes.NewReader(nil))
@@ -678,6 +724,7 @@ var (
 	f64f32_i64                          = initFt([]ValueType{f64, f32}, []ValueType{i64})
 	f64i32_v128i64                      = initFt([]ValueType{f64, i32}, []ValueType{v128, i64})
 	i32_i32                             = initFt([]ValueType{i32}, []ValueType{i32})
+	i32_i64                             = initFt([]ValueType{i32}, []ValueType{i64})
 	i32f64_v                            = initFt([]ValueType{i32, f64}, nil)
 	i32i32_i32                          = initFt([]ValueType{i32, i32}, []ValueType{i32})
 	i32_v                               = initFt([]ValueType{i32}, nil)
@@ -2260,6 +2307,502 @@ func TestModule_funcValidation_CallIndirect(t *testing.T) {
 	})
 }
 
+func TestModule_funcValidation_ReturnCall(t *testing.T) {
+	tailCall := api.CoreFeaturesV2 | experimental.CoreFeaturesTailCall
+	tests := []struct {
+		name        string
+		body        []byte
+		types       []FunctionType
+		features    api.CoreFeatures
+		expectedErr string
+	}{
+		{
+			name: "return_call",
+			body: []byte{OpcodeLocalGet, 0, OpcodeReturnCall, 1, OpcodeEnd},
+		},
+		{
+			name: "return_call_indirect",
+			body: []byte{OpcodeLocalGet, 0, OpcodeI32Const, 0, OpcodeReturnCallIndirect, 0, 0, OpcodeEnd},
+		},
+		{
+			name: "return_call stack-polymorphic",
+			// The values below the arguments and the unreachable code are ignored, like return.
+			body: []byte{
+				OpcodeF32Const, 0, 0, 0, 0,
+				OpcodeLocalGet, 0, OpcodeReturnCall, 0,
+				OpcodeI32Add,
+				OpcodeEnd,
+			},
+		},
+		{
+			name:        "return_call disabled",
+			body:        []byte{OpcodeLocalGet, 0, OpcodeReturnCall, 1, OpcodeEnd},
+			features:    api.CoreFeaturesV2,
+			expectedErr: `return_call invalid as feature "tail-call" is disabled`,
+		},
+		{
+			name:        "return_call_indirect disabled",
+			body:        []byte{OpcodeLocalGet, 0, OpcodeI32Const, 0, OpcodeReturnCallIndirect, 0, 0, OpcodeEnd},
+			features:    api.CoreFeaturesV2,
+			expectedErr: `return_call_indirect invalid as feature "tail-call" is disabled`,
+		},
+		{
+			name:        "return_call param mismatch",
+			body:        []byte{OpcodeI64Const, 0, OpcodeReturnCall, 1, OpcodeEnd},
+			expectedErr: "type mismatch on return_call operation param type: type mismatch: expected i32, but was i64",
+		},
+		{
+			name:        "return_call result mismatch",
+			body:        []byte{OpcodeLocalGet, 0, OpcodeReturnCall, 2, OpcodeEnd},
+			types:       []FunctionType{i32_i32, i32_i64},
+			expectedErr: "type mismatch on return_call operation: callee type i32_i64 must have the same results as the function type i32_i32",
+		},
+		{
+			name:        "return_call_indirect result mismatch",
+			body:        []byte{OpcodeLocalGet, 0, OpcodeI32Const, 0, OpcodeReturnCallIndirect, 1, 0, OpcodeEnd},
+			types:       []FunctionType{i32_i32, i32_i64},
+			expectedErr: "type mismatch on return_call_indirect operation: callee type i32_i64 must have the same results as the function type i32_i32",
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			types := tc.types
+			if types == nil {
+				types = []FunctionType{i32_i32}
+			}
+			features := tc.features
+			if features == 0 {
+				features = tailCall
+			}
+			m := &Module{
+				TypeSection:     types,
+				FunctionSection: []Index{0},
+				CodeSection:     []Code{{Body: tc.body}},
+			}
+			// Function 1 has the same type as function 0, and function 2 the last type.
+			functions := []Index{0, 0, Index(len(types) - 1)}
+			err := m.validateFunction(&stacks{}, features,
+				0, functions, nil, nil, []Table{{Type: RefTypeFuncref}}, nil, bytes.NewReader(nil))
+			if tc.expectedErr != "" {
+				require.EqualError(t, err, tc.expectedErr)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestModule_funcValidation_ExceptionHandling(t *testing.T) {
+	exceptionHandling := api.CoreFeaturesV2 | experimental.CoreFeaturesExceptionHandling
+	// Tag 0 is imported and carries no values, and tag 1 is defined and carries an i32.
+	const tagNone, tagI32 = 0, 1
+	tests := []struct {
+		name        string
+		body        []byte
+		features    api.CoreFeatures
+		expectedErr string
+	}{
+		{
+			name: "try catch",
+			body: []byte{
+				OpcodeTry, ValueTypeI32,
+				OpcodeI32Const, 1, OpcodeThrow, tagI32,
+				OpcodeCatch, tagI32, // the value carried by the exception is the result.
+				OpcodeCatch, tagNone, OpcodeI32Const, 0,
+				OpcodeCatchAll, OpcodeI32Const, 2,
+				OpcodeEnd,
+				OpcodeEnd,
+			},
+		},
+		{
+			name: "try without handlers",
+			body: []byte{OpcodeTry, ValueTypeI32, OpcodeI32Const, 0, OpcodeEnd, OpcodeEnd},
+		},
+		{
+			name: "rethrow",
+			body: []byte{
+				OpcodeTry, ValueTypeI32,
+				OpcodeI32Const, 0,
+				OpcodeCatchAll, OpcodeBlock, 0x40, OpcodeRethrow, 1, OpcodeEnd, OpcodeI32Const, 0,
+				OpcodeEnd,
+				OpcodeEnd,
+			},
+		},
+		{
+			name: "delegate",
+			body: []byte{
+				OpcodeTry, ValueTypeI32,
+				OpcodeTry, 0x40, OpcodeNop, OpcodeDelegate, 0, // to the outer try
+				OpcodeI32Const, 3,
+				OpcodeCatch, tagI32,
+				OpcodeEnd,
+				OpcodeEnd,
+			},
+		},
+		{
+			name: "delegate to caller",
+			body: []byte{OpcodeTry, 0x40, OpcodeDelegate, 0, OpcodeI32Const, 0, OpcodeEnd},
+		},
+		{
+			name:        "try disabled",
+			body:        []byte{OpcodeTry, ValueTypeI32, OpcodeI32Const, 0, OpcodeEnd, OpcodeEnd},
+			features:    api.CoreFeaturesV2,
+			expectedErr: `try invalid as feature "exception-handling" is disabled`,
+		},
+		{
+			name:        "throw disabled",
+			body:        []byte{OpcodeThrow, tagNone, OpcodeEnd},
+			features:    api.CoreFeaturesV2,
+			expectedErr: `throw invalid as feature "exception-handling" is disabled`,
+		},
+		{
+			name:        "catch without try",
+			body:        []byte{OpcodeBlock, 0x40, OpcodeCatch, tagNone, OpcodeEnd, OpcodeI32Const, 0, OpcodeEnd},
+			expectedErr: "catch must follow try or catch",
+		},
+		{
+			name:        "catch after catch_all",
+			body:        []byte{OpcodeTry, 0x40, OpcodeCatchAll, OpcodeCatch, tagNone, OpcodeEnd, OpcodeI32Const, 0, OpcodeEnd},
+			expectedErr: "catch must follow try or catch",
+		},
+		{
+			name:        "catch unknown tag",
+			body:        []byte{OpcodeTry, 0x40, OpcodeCatch, 2, OpcodeEnd, OpcodeI32Const, 0, OpcodeEnd},
+			expectedErr: "unknown tag 2",
+		},
+		{
+			name:        "throw unknown tag",
+			body:        []byte{OpcodeThrow, 2, OpcodeEnd},
+			expectedErr: "unknown tag 2",
+		},
+		{
+			name:        "throw param mismatch",
+			body:        []byte{OpcodeI64Const, 0, OpcodeThrow, tagI32, OpcodeEnd},
+			expectedErr: "cannot use i64 in throw block as param[0] type i32",
+		},
+		{
+			name:        "catch result mismatch",
+			body:        []byte{OpcodeTry, ValueTypeI32, OpcodeI32Const, 0, OpcodeCatch, tagNone, OpcodeEnd, OpcodeEnd},
+			expectedErr: "not enough results in catch block\n\thave ()\n\twant (i32)",
+		},
+		{
+			name:        "rethrow outside catch",
+			body:        []byte{OpcodeTry, 0x40, OpcodeRethrow, 0, OpcodeEnd, OpcodeI32Const, 0, OpcodeEnd},
+			expectedErr: "invalid rethrow operation: label 0 isn't a catch or catch_all",
+		},
+		{
+			name:        "delegate after catch",
+			body:        []byte{OpcodeTry, 0x40, OpcodeCatchAll, OpcodeDelegate, 0, OpcodeI32Const, 0, OpcodeEnd},
+			expectedErr: "delegate must follow try",
+		},
+		{
+			name:        "delegate index out of range",
+			body:        []byte{OpcodeTry, 0x40, OpcodeDelegate, 1, OpcodeI32Const, 0, OpcodeEnd},
+			expectedErr: "invalid delegate operation: index out of range",
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			features := tc.features
+			if features == 0 {
+				features = exceptionHandling
+			}
+			m := &Module{
+				TypeSection:     []FunctionType{v_i32, i32_v, v_v},
+				ImportSection:   []Import{{Type: ExternTypeTag, DescTag: 2}},
+				ImportTagCount:  1,
+				TagSection:      []Index{1},
+				FunctionSection: []Index{0},
+				CodeSection:     []Code{{Body: tc.body}},
+			}
+			err := m.validateFunction(&stacks{}, features,
+				0, []Index{0}, nil, nil, nil, nil, bytes.NewReader(nil))
+			if tc.expectedErr != "" {
+				require.EqualError(t, err, tc.expectedErr)
+			} else {
+				require.NoError(t, err)
+				require.True(t, m.UsesExceptionHandling)
+			}
+		})
+	}
+}
+
+func TestModule_funcValidation_MultiMemory(t *testing.T) {
+	multiMemory := api.CoreFeaturesV2 | experimental.CoreFeaturesMultiMemory
+	// i32.load8_u from the given memory, with the alignment flagged by MemArgMemoryIndexFlag.
+	load := func(memoryIndex byte) []byte {
+		return []byte{OpcodeI32Const, 0, OpcodeI32Load8U, MemArgMemoryIndexFlag, memoryIndex, 0, OpcodeDrop, OpcodeEnd}
+	}
+	copyMemory := func(dst, src byte) []byte {
+		return []byte{
+			OpcodeI32Const, 0, OpcodeI32Const, 0, OpcodeI32Const, 0,
+			OpcodeMiscPrefix, OpcodeMiscMemoryCopy, dst, src,
+			OpcodeEnd,
+		}
+	}
+	tests := []struct {
+		name        string
+		body        []byte
+		features    api.CoreFeatures
+		expectedErr string
+	}{
+		{name: "load", body: load(1)},
+		{name: "load memory 0", body: load(0)},
+		{
+			name: "load memory index with multiple bytes",
+			body: []byte{OpcodeI32Const, 0, OpcodeI32Load8U, MemArgMemoryIndexFlag, 0x81, 0x00, 0, OpcodeDrop, OpcodeEnd},
+		},
+		{name: "load unknown memory", body: load(2), expectedErr: "unknown memory 2"},
+		{name: "load disabled", body: load(1), features: api.CoreFeaturesV2, expectedErr: "invalid memory alignment"},
+		{name: "memory.copy", body: copyMemory(0, 1)},
+		{name: "memory.copy unknown memory", body: copyMemory(2, 0), expectedErr: "memory.copy: unknown memory 2"},
+		{
+			name:        "memory.copy disabled",
+			body:        copyMemory(0, 1),
+			features:    api.CoreFeaturesV2,
+			expectedErr: "memory.copy reserved byte must be zero encoded with 1 byte",
+		},
+		{name: "memory.grow", body: []byte{OpcodeI32Const, 1, OpcodeMemoryGrow, 1, OpcodeDrop, OpcodeEnd}},
+		{
+			name:        "memory.size unknown memory",
+			body:        []byte{OpcodeMemorySize, 2, OpcodeDrop, OpcodeEnd},
+			expectedErr: "memory.size: unknown memory 2",
+		},
+		{
+			name:        "memory.fill unknown memory",
+			body:        []byte{OpcodeI32Const, 0, OpcodeI32Const, 0, OpcodeI32Const, 0, OpcodeMiscPrefix, OpcodeMiscMemoryFill, 2, OpcodeEnd},
+			expectedErr: "memory.fill: unknown memory 2",
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			features := tc.features
+			if features == 0 {
+				features = multiMemory
+			}
+			m := &Module{
+				TypeSection:             []FunctionType{v_v},
+				FunctionSection:         []Index{0},
+				CodeSection:             []Code{{Body: tc.body}},
+				AdditionalMemorySection: []Memory{{}},
+			}
+			err := m.validateFunction(&stacks{}, features,
+				0, []Index{0}, nil, &Memory{}, nil, nil, bytes.NewReader(nil))
+			if tc.expectedErr != "" {
+				require.EqualError(t, err, tc.expectedErr)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestModule_funcValidation_Memory64(t *testing.T) {
+	memory64 := api.CoreFeaturesV2 | experimental.CoreFeaturesMemory64
+	// farOffset doesn't fit in 32 bits.
+	farOffset := leb128.EncodeUint64(1 << 32)
+	tests := []struct {
+		name        string
+		body        []byte
+		is32        bool
+		expectedErr string
+	}{
+		{name: "load", body: []byte{OpcodeI64Const, 0, OpcodeI32Load, 2, 0, OpcodeDrop, OpcodeEnd}},
+		{
+			name: "load with offset over 4GiB",
+			body: append(append([]byte{OpcodeI64Const, 0, OpcodeI32Load8U, 0}, farOffset...), OpcodeDrop, OpcodeEnd),
+		},
+		{
+			name:        "load with offset over 4GiB from 32-bit memory",
+			body:        append(append([]byte{OpcodeI32Const, 0, OpcodeI32Load8U, 0}, farOffset...), OpcodeDrop, OpcodeEnd),
+			is32:        true,
+			expectedErr: "read memory offset: overflows a 32-bit integer",
+		},
+		{
+			name:        "load with i32 address",
+			body:        []byte{OpcodeI32Const, 0, OpcodeI32Load, 2, 0, OpcodeDrop, OpcodeEnd},
+			expectedErr: "type mismatch: expected i64, but was i32",
+		},
+		{name: "store", body: []byte{OpcodeI64Const, 0, OpcodeI32Const, 0, OpcodeI32Store, 2, 0, OpcodeEnd}},
+		{name: "memory.size", body: []byte{OpcodeMemorySize, 0, OpcodeI64Eqz, OpcodeDrop, OpcodeEnd}},
+		{name: "memory.grow", body: []byte{OpcodeI64Const, 1, OpcodeMemoryGrow, 0, OpcodeI64Eqz, OpcodeDrop, OpcodeEnd}},
+		{
+			name:        "memory.grow with i32 delta",
+			body:        []byte{OpcodeI32Const, 1, OpcodeMemoryGrow, 0, OpcodeDrop, OpcodeEnd},
+			expectedErr: "type mismatch: expected i64, but was i32",
+		},
+		{
+			name: "memory.fill",
+			body: []byte{OpcodeI64Const, 0, OpcodeI32Const, 0, OpcodeI64Const, 0, OpcodeMiscPrefix, OpcodeMiscMemoryFill, 0, OpcodeEnd},
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			m := &Module{TypeSection: []FunctionType{v_v}, FunctionSection: []Index{0}, CodeSection: []Code{{Body: tc.body}}}
+			err := m.validateFunction(&stacks{}, memory64,
+				0, []Index{0}, nil, &Memory{Is64: !tc.is32}, nil, nil, bytes.NewReader(nil))
+			if tc.expectedErr != "" {
+				require.EqualError(t, err, tc.expectedErr)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestModule_funcValidation_RelaxedSIMD(t *testing.T) {
+	relaxedSIMD := api.CoreFeaturesV2 | experimental.CoreFeaturesRelaxedSIMD
+	// relaxed applies the relaxed-SIMD instruction to the given number of zero vectors, then drops the result.
+	relaxed := func(op OpcodeVecRelaxed, params int) (body []byte) {
+		for i := 0; i < params; i++ {
+			body = append(body, OpcodeVecPrefix, OpcodeVecV128Const)
+			body = append(body, make([]byte, 16)...)
+		}
+		return append(body, OpcodeVecPrefix, 0x80|op, 0x02, OpcodeDrop, OpcodeEnd)
+	}
+	tests := []struct {
+		name        string
+		body        []byte
+		features    api.CoreFeatures
+		expectedErr string
+	}{
+		{name: "i8x16.relaxed_swizzle", body: relaxed(OpcodeVecRelaxedI8x16Swizzle, 2)},
+		{name: "i32x4.relaxed_trunc_f32x4_s", body: relaxed(OpcodeVecRelaxedI32x4TruncF32x4S, 1)},
+		{name: "f64x2.relaxed_nmadd", body: relaxed(OpcodeVecRelaxedF64x2Nmadd, 3)},
+		{name: "i64x2.relaxed_laneselect", body: relaxed(OpcodeVecRelaxedI64x2Laneselect, 3)},
+		{name: "i32x4.relaxed_dot_i8x16_i7x16_add_s", body: relaxed(OpcodeVecRelaxedI32x4DotI8x16I7x16AddS, 3)},
+		{
+			name:        "disabled",
+			body:        relaxed(OpcodeVecRelaxedF32x4Madd, 3),
+			features:    api.CoreFeaturesV2,
+			expectedErr: `f32x4.relaxed_madd invalid as feature "relaxed-simd" is disabled`,
+		},
+		{
+			name:        "missing operand",
+			body:        relaxed(OpcodeVecRelaxedF32x4Madd, 2),
+			expectedErr: "cannot pop the operand for f32x4.relaxed_madd: v128 missing",
+		},
+		{
+			name:        "unknown",
+			body:        relaxed(0x14, 2),
+			expectedErr: "invalid relaxed-SIMD instruction 0x14",
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			features := tc.features
+			if features == 0 {
+				features = relaxedSIMD
+			}
+			m := &Module{TypeSection: []FunctionType{v_v}, FunctionSection: []Index{0}, CodeSection: []Code{{Body: tc.body}}}
+			err := m.validateFunction(&stacks{}, features, 0, []Index{0}, nil, nil, nil, nil, bytes.NewReader(nil))
+			if tc.expectedErr != "" {
+				require.EqualError(t, err, tc.expectedErr)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestModule_funcValidation_Atomic(t *testing.T) {
+	threads := api.CoreFeaturesV2 | experimental.CoreFeaturesThreads
+	// atomic applies the atomic instruction with the given alignment to zero operands of the given types, then drops
+	// its result if it has one.
+	atomic := func(op OpcodeAtomic, align byte, result bool, params ...ValueType) (body []byte) {
+		for _, vt := range params {
+			if vt == ValueTypeI64 {
+				body = append(body, OpcodeI64Const, 0)
+			} else {
+				body = append(body, OpcodeI32Const, 0)
+			}
+		}
+		body = append(body, OpcodeAtomicPrefix, op, align, 0)
+		if result {
+			body = append(body, OpcodeDrop)
+		}
+		return append(body, OpcodeEnd)
+	}
+	tests := []struct {
+		name        string
+		body        []byte
+		features    api.CoreFeatures
+		noMemory    bool
+		expectedErr string
+	}{
+		{name: "memory.atomic.notify", body: atomic(OpcodeAtomicMemoryNotify, 2, true, i32, i32)},
+		{name: "memory.atomic.wait64", body: atomic(OpcodeAtomicMemoryWait64, 3, true, i32, i64, i64)},
+		{name: "atomic.fence", body: []byte{OpcodeAtomicPrefix, OpcodeAtomicFence, 0, OpcodeEnd}},
+		{name: "i64.atomic.load32_u", body: atomic(OpcodeAtomicI64Load32U, 2, true, i32)},
+		{name: "i32.atomic.store8", body: atomic(OpcodeAtomicI32Store8, 0, false, i32, i32)},
+		{name: "i64.atomic.rmw16.sub_u", body: atomic(OpcodeAtomicI64Rmw16SubU, 1, true, i32, i64)},
+		{name: "i32.atomic.rmw.cmpxchg", body: atomic(OpcodeAtomicI32RmwCmpxchg, 2, true, i32, i32, i32)},
+		{
+			name:        "disabled",
+			body:        atomic(OpcodeAtomicI32Store, 2, false, i32, i32),
+			features:    api.CoreFeaturesV2,
+			expectedErr: `i32.atomic.store invalid as feature "threads" is disabled`,
+		},
+		{
+			name:        "no memory",
+			body:        atomic(OpcodeAtomicI32Store, 2, false, i32, i32),
+			noMemory:    true,
+			expectedErr: "memory must exist for i32.atomic.store",
+		},
+		{
+			name:        "alignment not natural",
+			body:        atomic(OpcodeAtomicI64Store, 2, false, i32, i64),
+			expectedErr: "invalid memory alignment for i64.atomic.store: must be 8",
+		},
+		{
+			name:        "operand type",
+			body:        atomic(OpcodeAtomicI64Store, 3, false, i32, i32),
+			expectedErr: "cannot pop the operand for i64.atomic.store: type mismatch: expected i64, but was i32",
+		},
+		{
+			name:        "fence reserved byte",
+			body:        []byte{OpcodeAtomicPrefix, OpcodeAtomicFence, 1, OpcodeEnd},
+			expectedErr: "atomic.fence reserved byte must be zero",
+		},
+		{
+			name:        "unknown",
+			body:        atomic(0x4f, 2, false, i32),
+			expectedErr: "invalid atomic instruction 0x4f",
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			features := tc.features
+			if features == 0 {
+				features = threads
+			}
+			memory := &Memory{Min: 1, Max: 1, IsShared: true}
+			if tc.noMemory {
+				memory = nil
+			}
+			m := &Module{TypeSection: []FunctionType{v_v}, FunctionSection: []Index{0}, CodeSection: []Code{{Body: tc.body}}}
+			err := m.validateFunction(&stacks{}, features, 0, []Index{0}, nil, memory, nil, nil, bytes.NewReader(nil))
+			if tc.expectedErr != "" {
+				require.EqualError(t, err, tc.expectedErr)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
 func TestModule_funcValidation_RefTypes(t *testing.T) {
 	tests := []struct {
 		name                    string
@@ -2613,6 +3156,29 @@ func TestModule_funcValidation_TableGetSet(t *testing.T) {
 			flag:        api.CoreFeatureReferenceTypes,
 			expectedErr: `cannot pop the operand for table.set: type mismatch: expected funcref, but was externref`,
 		},
+		{
+			name: "table.get result to table.set (funcref)",
+			body: []byte{
+				OpcodeI32Const, 0,
+				OpcodeI32Const, 0,
+				OpcodeTableGet, 0,
+				OpcodeTableSet, 0,
+				OpcodeEnd,
+			},
+			flag: api.CoreFeatureReferenceTypes,
+		},
+		{
+			name: "table.get result to table.set type mismatch (src=externref, dst=funcref)",
+			body: []byte{
+				OpcodeI32Const, 0,
+				OpcodeI32Const, 0,
+				OpcodeTableGet, 1,
+				OpcodeTableSet, 0,
+				OpcodeEnd,
+			},
+			flag:        api.CoreFeatureReferenceTypes,
+			expectedErr: `cannot pop the operand for table.set: type mismatch: expected funcref, but was externref`,
+		},
 		{
 			name: "table.set (disabled)",
 			body: []byte{
@@ -3379,6 +3945,19 @@ func TestDecodeBlockType(t *testing.T) {
 			require.Equal(t, expected, actual)
 		}
 	})
+	t.Run("disabled features", func(t *testing.T) {
+		for _, tc := range []struct {
+			in          byte
+			expectedErr string
+		}{
+			{in: 0x7b, expectedErr: `block with v128 result invalid as feature "simd" is disabled`},
+			{in: 0x70, expectedErr: `block with funcref result invalid as feature "reference-types" is disabled`},
+			{in: 0x6f, expectedErr: `block with externref result invalid as feature "reference-types" is disabled`},
+		} {
+			_, _, err := DecodeBlockType(nil, bytes.NewReader([]byte{tc.in}), api.CoreFeaturesV1)
+			require.EqualError(t, err, tc.expectedErr)
+		}
+	})
 }
 
 // TestFuncValidation_UnreachableBrTable_NotModifyTypes ensures that we do not modify the
@@ -3678,3 +4257,22 @@ func Test_SplitCallStack(t *testing.T) {
 		})
 	}
 }
+
+func Test_ZeroExtend32BitResults(t *testing.T) {
+	ft := &FunctionType{Results: []ValueType{ValueTypeI32, ValueTypeV128, ValueTypeI64, ValueTypeF32, ValueTypeF64}}
+	results := []uint64{
+		0xffffffff_00000001,                      // i32
+		0xffffffff_ffffffff, 0xffffffff_ffffffff, // v128
+		0xffffffff_00000002, // i64
+		0xffffffff_3f800000, // f32
+		0xffffffff_00000003, // f64
+	}
+	ZeroExtend32BitResults(ft, results)
+	require.Equal(t, []uint64{
+		1,
+		0xffffffff_ffffffff, 0xffffffff_ffffffff,
+		0xffffffff_00000002,
+		0x3f800000,
+		0xffffffff_00000003,
+	}, results)
+}
@@ -0,0 +1,252 @@
+package wasm
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/tetratelabs/wazero/internal/leb128"
+)
+
+// BodyInstruction is an instruction decoded from a function body by Module.FunctionBody.
+type BodyInstruction struct {
+	// Offset is the offset of the opcode from the start of the function body, which follows its locals.
+	Offset uint32
+	// Opcode is the opcode of the instruction, or OpcodeMiscPrefix, OpcodeVecPrefix or OpcodeAtomicPrefix for those
+	// with a Subopcode.
+	Opcode Opcode
+	// Subopcode is the OpcodeMisc, OpcodeVec or OpcodeAtomic following the prefix of Opcode, or zero. An
+	// OpcodeVecRelaxed is added to OpcodeVecRelaxedOffset, which is its value in the binary format.
+	Subopcode uint32
+	// Immediates are the immediate arguments of the instruction in their encoding order:
+	//
+	//   - Block types are the signed 33-bit integer, e.g. -64 (0x40) for an empty block type.
+	//   - OpcodeBrTable has its label count, each label, then the default label.
+	//   - OpcodeTypedSelect has its value type count, then each value type.
+	//   - Memory arguments are the alignment exponent, the offset and the memory index, which is zero unless
+	//     encoded with MemArgMemoryIndexFlag. A lane index follows for vector lane loads and stores.
+	//   - OpcodeAtomicFence has its reserved byte.
+	//   - Constants are encoded as api.EncodeI32 and similar, and OpcodeVecV128Const and
+	//     OpcodeVecV128i8x16Shuffle have two little-endian halves: the low then the high 64 bits.
+	//   - Everything else is an index, e.g. of a local, function or lane.
+	Immediates []uint64
+}
+
+// FunctionBody decodes the instructions of the function at the given index, including imported functions in the
+// index space, so it corresponds to api.FunctionDefinition Index. The result includes every OpcodeElse and
+// OpcodeEnd, so block nesting can be reconstructed, ending with the OpcodeEnd of the function.
+//
+// An error is returned for imported functions and those of host modules, as their bodies are not known.
+func (m *Module) FunctionBody(funcIdx Index) ([]BodyInstruction, error) {
+	if funcIdx < m.ImportFunctionCount {
+		return nil, fmt.Errorf("function[%d] is imported", funcIdx)
+	}
+	codeIdx := funcIdx - m.ImportFunctionCount
+	if codeIdx >= Index(len(m.CodeSection)) {
+		return nil, fmt.Errorf("function[%d] out of range", funcIdx)
+	}
+	body := m.CodeSection[codeIdx].Body
+	if body == nil {
+		return nil, fmt.Errorf("function[%d] is a host function", funcIdx)
+	}
+	ret, err := decodeFunctionBody(body)
+	if err != nil {
+		return nil, fmt.Errorf("function[%d]: %w", funcIdx, err)
+	}
+	return ret, nil
+}
+
+// bodyReader reads the immediates of a function body, retaining the first error.
+type bodyReader struct {
+	body []byte
+	pc   int
+	err  error
+}
+
+var errUnexpectedEnd = errors.New("unexpected end of function body")
+
+func (r *bodyReader) byte() byte {
+	if r.err != nil {
+		return 0
+	} else if r.pc >= len(r.body) {
+		r.err = errUnexpectedEnd
+		return 0
+	}
+	b := r.body[r.pc]
+	r.pc++
+	return b
+}
+
+func (r *bodyReader) bytes(n int) []byte {
+	if r.err != nil {
+		return nil
+	} else if len(r.body)-r.pc < n {
+		r.err = errUnexpectedEnd
+		return nil
+	}
+	b := r.body[r.pc : r.pc+n]
+	r.pc += n
+	return b
+}
+
+func (r *bodyReader) u32() uint32 {
+	if r.err != nil {
+		return 0
+	}
+	v, n, err := leb128.LoadUint32(r.body[r.pc:])
+	if err != nil {
+		r.err = err
+		return 0
+	}
+	r.pc += int(n)
+	return v
+}
+
+func (r *bodyReader) u64() uint64 {
+	if r.err != nil {
+		return 0
+	}
+	v, n, err := leb128.LoadUint64(r.body[r.pc:])
+	if err != nil {
+		r.err = err
+		return 0
+	}
+	r.pc += int(n)
+	return v
+}
+
+func (r *bodyReader) s64() int64 {
+	if r.err != nil {
+		return 0
+	}
+	v, n, err := leb128.LoadInt64(r.body[r.pc:])
+	if err != nil {
+		r.err = err
+		return 0
+	}
+	r.pc += int(n)
+	return v
+}
+
+// memArg reads a "memarg" immediate as the alignment, offset and memory index.
+func (r *bodyReader) memArg() []uint64 {
+	align := r.u32()
+	var memIdx uint32
+	if align&MemArgMemoryIndexFlag != 0 {
+		align &^= MemArgMemoryIndexFlag
+		memIdx = r.u32()
+	}
+	offset := r.u64() // 64-bit for a 64-bit memory.
+	return []uint64{uint64(align), offset, uint64(memIdx)}
+}
+
+// decodeFunctionBody decodes the instructions of a function body, excluding its locals, as documented on
+// BodyInstruction.
+func decodeFunctionBody(body []byte) (ret []BodyInstruction, err error) {
+	r := &bodyReader{body: body}
+	for r.pc < len(body) {
+		in := BodyInstruction{Offset: uint32(r.pc)}
+		in.Opcode = r.byte()
+		switch op := in.Opcode; {
+		case op == OpcodeBlock || op == OpcodeLoop || op == OpcodeIf || op == OpcodeTry:
+			in.Immediates = []uint64{uint64(r.s64())} // block type is a signed 33-bit integer.
+		case op == OpcodeBr || op == OpcodeBrIf || op == OpcodeCall || op == OpcodeReturnCall || op == OpcodeRefFunc:
+			in.Immediates = []uint64{uint64(r.u32())}
+		case op == OpcodeCatch || op == OpcodeThrow || op == OpcodeRethrow || op == OpcodeDelegate:
+			in.Immediates = []uint64{uint64(r.u32())} // tag or label index
+		case op == OpcodeBrTable:
+			n := r.u32()
+			in.Immediates = []uint64{uint64(n)}
+			for i := uint32(0); i <= n && r.err == nil; i++ { // includes the default label
+				in.Immediates = append(in.Immediates, uint64(r.u32()))
+			}
+		case op == OpcodeCallIndirect || op == OpcodeReturnCallIndirect:
+			typeIndex := r.u32()
+			in.Immediates = []uint64{uint64(typeIndex), uint64(r.u32())}
+		case op == OpcodeTypedSelect:
+			n := r.u32()
+			in.Immediates = []uint64{uint64(n)}
+			for _, vt := range r.bytes(int(n)) {
+				in.Immediates = append(in.Immediates, uint64(vt))
+			}
+		case op >= OpcodeLocalGet && op <= OpcodeTableSet:
+			in.Immediates = []uint64{uint64(r.u32())}
+		case op >= OpcodeI32Load && op <= OpcodeI64Store32:
+			in.Immediates = r.memArg()
+		case op == OpcodeMemorySize || op == OpcodeMemoryGrow:
+			in.Immediates = []uint64{uint64(r.u32())}
+		case op == OpcodeI32Const:
+			in.Immediates = []uint64{uint64(uint32(r.s64()))}
+		case op == OpcodeI64Const:
+			in.Immediates = []uint64{uint64(r.s64())}
+		case op == OpcodeF32Const:
+			if b := r.bytes(4); b != nil {
+				in.Immediates = []uint64{uint64(binary.LittleEndian.Uint32(b))}
+			}
+		case op == OpcodeF64Const:
+			if b := r.bytes(8); b != nil {
+				in.Immediates = []uint64{binary.LittleEndian.Uint64(b)}
+			}
+		case op == OpcodeRefNull:
+			in.Immediates = []uint64{uint64(r.byte())} // reference type
+		case op == OpcodeMiscPrefix:
+			in.Subopcode = r.u32()
+			switch OpcodeMisc(in.Subopcode) {
+			case OpcodeMiscMemoryInit, OpcodeMiscMemoryCopy, OpcodeMiscTableInit, OpcodeMiscTableCopy:
+				first := r.u32()
+				in.Immediates = []uint64{uint64(first), uint64(r.u32())}
+			case OpcodeMiscDataDrop, OpcodeMiscElemDrop, OpcodeMiscMemoryFill,
+				OpcodeMiscTableGrow, OpcodeMiscTableSize, OpcodeMiscTableFill:
+				in.Immediates = []uint64{uint64(r.u32())}
+			}
+		case op == OpcodeAtomicPrefix:
+			in.Subopcode = uint32(r.byte())
+			if in.Subopcode == uint32(OpcodeAtomicFence) {
+				in.Immediates = []uint64{uint64(r.byte())} // reserved byte
+			} else {
+				in.Immediates = r.memArg()
+			}
+		case op == OpcodeVecPrefix:
+			if relaxedOp, ok := VecRelaxedOpcode(r.body[r.pc:]); ok {
+				in.Subopcode = OpcodeVecRelaxedOffset + uint32(relaxedOp)
+				r.pc += 2
+				break
+			}
+			vecOp := r.byte()
+			in.Subopcode = uint32(vecOp)
+			switch {
+			case vecOp <= OpcodeVecV128Store, vecOp == OpcodeVecV128Load32zero, vecOp == OpcodeVecV128Load64zero:
+				in.Immediates = r.memArg()
+			case vecOp >= OpcodeVecV128Load8Lane && vecOp <= OpcodeVecV128Store64Lane:
+				in.Immediates = append(r.memArg(), uint64(r.byte()))
+			case vecOp == OpcodeVecV128Const || vecOp == OpcodeVecV128i8x16Shuffle:
+				if b := r.bytes(16); b != nil {
+					in.Immediates = []uint64{binary.LittleEndian.Uint64(b), binary.LittleEndian.Uint64(b[8:])}
+				}
+			case vecOp >= OpcodeVecI8x16ExtractLaneS && vecOp <= OpcodeVecF64x2ReplaceLane:
+				in.Immediates = []uint64{uint64(r.byte())} // lane index
+			}
+		}
+		if r.err != nil {
+			return nil, fmt.Errorf("invalid %s at offset %d: %w", in.Name(), in.Offset, r.err)
+		}
+		ret = append(ret, in)
+	}
+	return
+}
+
+// Name returns the name of the instruction, e.g. "i32.add".
+func (in *BodyInstruction) Name() string {
+	switch in.Opcode {
+	case OpcodeMiscPrefix:
+		return MiscInstructionName(OpcodeMisc(in.Subopcode))
+	case OpcodeAtomicPrefix:
+		return AtomicInstructionName(OpcodeAtomic(in.Subopcode))
+	case OpcodeVecPrefix:
+		if in.Subopcode >= OpcodeVecRelaxedOffset {
+			return VecRelaxedInstructionName(OpcodeVecRelaxed(in.Subopcode - OpcodeVecRelaxedOffset))
+		}
+		return VectorInstructionName(OpcodeVec(in.Subopcode))
+	}
+	return InstructionName(in.Opcode)
+}
@@ -0,0 +1,209 @@
+package wasm
+
+import (
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestModule_FunctionBody(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     []byte
+		expected []BodyInstruction
+	}{
+		{
+			name:     "empty",
+			body:     []byte{OpcodeEnd},
+			expected: []BodyInstruction{{Offset: 0, Opcode: OpcodeEnd}},
+		},
+		{
+			name: "block nesting",
+			body: []byte{
+				OpcodeBlock, 0x40,
+				OpcodeLoop, 0x7f, // i32 result
+				OpcodeI32Const, 0x7f, // -1
+				OpcodeBrIf, 1,
+				OpcodeI32Const, 1,
+				OpcodeEnd,
+				OpcodeIf, 0x40,
+				OpcodeElse,
+				OpcodeBrTable, 2, 0, 1, 0,
+				OpcodeEnd,
+				OpcodeEnd,
+				OpcodeEnd,
+			},
+			expected: []BodyInstruction{
+				{Offset: 0, Opcode: OpcodeBlock, Immediates: []uint64{0xffffffffffffffc0}}, // -64
+				{Offset: 2, Opcode: OpcodeLoop, Immediates: []uint64{0xffffffffffffffff}},  // -1
+				{Offset: 4, Opcode: OpcodeI32Const, Immediates: []uint64{0xffffffff}},
+				{Offset: 6, Opcode: OpcodeBrIf, Immediates: []uint64{1}},
+				{Offset: 8, Opcode: OpcodeI32Const, Immediates: []uint64{1}},
+				{Offset: 10, Opcode: OpcodeEnd},
+				{Offset: 11, Opcode: OpcodeIf, Immediates: []uint64{0xffffffffffffffc0}},
+				{Offset: 13, Opcode: OpcodeElse},
+				{Offset: 14, Opcode: OpcodeBrTable, Immediates: []uint64{2, 0, 1, 0}},
+				{Offset: 19, Opcode: OpcodeEnd},
+				{Offset: 20, Opcode: OpcodeEnd},
+				{Offset: 21, Opcode: OpcodeEnd},
+			},
+		},
+		{
+			name: "memory",
+			body: []byte{
+				OpcodeI32Const, 0,
+				OpcodeI64Load, 3, 0x80, 0x01, // offset 128
+				OpcodeI32Const, 0,
+				OpcodeI32Load, 2 | MemArgMemoryIndexFlag, 1, 4,
+				OpcodeMemoryGrow, 0,
+				OpcodeMiscPrefix, OpcodeMiscMemoryCopy, 1, 0,
+				OpcodeEnd,
+			},
+			expected: []BodyInstruction{
+				{Offset: 0, Opcode: OpcodeI32Const, Immediates: []uint64{0}},
+				{Offset: 2, Opcode: OpcodeI64Load, Immediates: []uint64{3, 128, 0}},
+				{Offset: 6, Opcode: OpcodeI32Const, Immediates: []uint64{0}},
+				{Offset: 8, Opcode: OpcodeI32Load, Immediates: []uint64{2, 4, 1}},
+				{Offset: 12, Opcode: OpcodeMemoryGrow, Immediates: []uint64{0}},
+				{Offset: 14, Opcode: OpcodeMiscPrefix, Subopcode: uint32(OpcodeMiscMemoryCopy), Immediates: []uint64{1, 0}},
+				{Offset: 18, Opcode: OpcodeEnd},
+			},
+		},
+		{
+			name: "constants and calls",
+			body: []byte{
+				OpcodeF32Const, 0x00, 0x00, 0x80, 0x3f, // 1.0
+				OpcodeF64Const, 0, 0, 0, 0, 0, 0, 0xf0, 0x3f, // 1.0
+				OpcodeI64Const, 0x7e, // -2
+				OpcodeRefNull, RefTypeFuncref,
+				OpcodeTypedSelect, 1, ValueTypeI32,
+				OpcodeCallIndirect, 1, 0,
+				OpcodeLocalGet, 2,
+				OpcodeReturnCall, 3,
+				OpcodeEnd,
+			},
+			expected: []BodyInstruction{
+				{Offset: 0, Opcode: OpcodeF32Const, Immediates: []uint64{0x3f800000}},
+				{Offset: 5, Opcode: OpcodeF64Const, Immediates: []uint64{0x3ff0000000000000}},
+				{Offset: 14, Opcode: OpcodeI64Const, Immediates: []uint64{0xfffffffffffffffe}},
+				{Offset: 16, Opcode: OpcodeRefNull, Immediates: []uint64{uint64(RefTypeFuncref)}},
+				{Offset: 18, Opcode: OpcodeTypedSelect, Immediates: []uint64{1, uint64(ValueTypeI32)}},
+				{Offset: 21, Opcode: OpcodeCallIndirect, Immediates: []uint64{1, 0}},
+				{Offset: 24, Opcode: OpcodeLocalGet, Immediates: []uint64{2}},
+				{Offset: 26, Opcode: OpcodeReturnCall, Immediates: []uint64{3}},
+				{Offset: 28, Opcode: OpcodeEnd},
+			},
+		},
+		{
+			name: "vector",
+			body: []byte{
+				OpcodeVecPrefix, OpcodeVecV128Const, 1, 0, 0, 0, 0, 0, 0, 0, 2, 0, 0, 0, 0, 0, 0, 0,
+				OpcodeVecPrefix, OpcodeVecI32x4ExtractLane, 3,
+				OpcodeDrop,
+				OpcodeI32Const, 0,
+				OpcodeVecPrefix, OpcodeVecV128Load8Lane, 0, 8, 15,
+				OpcodeVecPrefix, 0x80 | OpcodeVecRelaxedI8x16Swizzle, 0x02,
+				OpcodeDrop,
+				OpcodeEnd,
+			},
+			expected: []BodyInstruction{
+				{Offset: 0, Opcode: OpcodeVecPrefix, Subopcode: uint32(OpcodeVecV128Const), Immediates: []uint64{1, 2}},
+				{Offset: 18, Opcode: OpcodeVecPrefix, Subopcode: uint32(OpcodeVecI32x4ExtractLane), Immediates: []uint64{3}},
+				{Offset: 21, Opcode: OpcodeDrop},
+				{Offset: 22, Opcode: OpcodeI32Const, Immediates: []uint64{0}},
+				{Offset: 24, Opcode: OpcodeVecPrefix, Subopcode: uint32(OpcodeVecV128Load8Lane), Immediates: []uint64{0, 8, 0, 15}},
+				{Offset: 29, Opcode: OpcodeVecPrefix, Subopcode: OpcodeVecRelaxedOffset + uint32(OpcodeVecRelaxedI8x16Swizzle)},
+				{Offset: 32, Opcode: OpcodeDrop},
+				{Offset: 33, Opcode: OpcodeEnd},
+			},
+		},
+		{
+			name: "exception handling",
+			body: []byte{
+				OpcodeTry, 0x40,
+				OpcodeThrow, 1,
+				OpcodeCatch, 1,
+				OpcodeRethrow, 0,
+				OpcodeCatchAll,
+				OpcodeEnd,
+				OpcodeTry, 0x40,
+				OpcodeDelegate, 0,
+				OpcodeEnd,
+			},
+			expected: []BodyInstruction{
+				{Offset: 0, Opcode: OpcodeTry, Immediates: []uint64{0xffffffffffffffc0}}, // -64
+				{Offset: 2, Opcode: OpcodeThrow, Immediates: []uint64{1}},
+				{Offset: 4, Opcode: OpcodeCatch, Immediates: []uint64{1}},
+				{Offset: 6, Opcode: OpcodeRethrow, Immediates: []uint64{0}},
+				{Offset: 8, Opcode: OpcodeCatchAll},
+				{Offset: 9, Opcode: OpcodeEnd},
+				{Offset: 10, Opcode: OpcodeTry, Immediates: []uint64{0xffffffffffffffc0}},
+				{Offset: 12, Opcode: OpcodeDelegate, Immediates: []uint64{0}},
+				{Offset: 14, Opcode: OpcodeEnd},
+			},
+		},
+		{
+			name: "atomic",
+			body: []byte{
+				OpcodeI32Const, 0,
+				OpcodeAtomicPrefix, OpcodeAtomicI32Load, 2, 4,
+				OpcodeDrop,
+				OpcodeAtomicPrefix, OpcodeAtomicFence, 0,
+				OpcodeEnd,
+			},
+			expected: []BodyInstruction{
+				{Offset: 0, Opcode: OpcodeI32Const, Immediates: []uint64{0}},
+				{Offset: 2, Opcode: OpcodeAtomicPrefix, Subopcode: uint32(OpcodeAtomicI32Load), Immediates: []uint64{2, 4, 0}},
+				{Offset: 6, Opcode: OpcodeDrop},
+				{Offset: 7, Opcode: OpcodeAtomicPrefix, Subopcode: uint32(OpcodeAtomicFence), Immediates: []uint64{0}},
+				{Offset: 10, Opcode: OpcodeEnd},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			m := &Module{ImportFunctionCount: 1, CodeSection: []Code{{Body: tc.body}}}
+			body, err := m.FunctionBody(1)
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, body)
+		})
+	}
+}
+
+func TestModule_FunctionBody_Errors(t *testing.T) {
+	m := &Module{
+		ImportFunctionCount: 1,
+		CodeSection: []Code{
+			{GoFunc: &struct{}{}},
+			{Body: []byte{OpcodeI32Const}},
+		},
+	}
+
+	tests := []struct {
+		name        string
+		funcIdx     Index
+		expectedErr string
+	}{
+		{name: "imported", funcIdx: 0, expectedErr: "function[0] is imported"},
+		{name: "host", funcIdx: 1, expectedErr: "function[1] is a host function"},
+		{name: "truncated", funcIdx: 2, expectedErr: "function[2]: invalid i32.const at offset 0: readByte failed: EOF"},
+		{name: "out of range", funcIdx: 3, expectedErr: "function[3] out of range"},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := m.FunctionBody(tc.funcIdx)
+			require.EqualError(t, err, tc.expectedErr)
+		})
+	}
+}
+
+func TestBodyInstruction_Name(t *testing.T) {
+	require.Equal(t, "i32.add", (&BodyInstruction{Opcode: OpcodeI32Add}).Name())
+	require.Equal(t, "memory.copy", (&BodyInstruction{Opcode: OpcodeMiscPrefix, Subopcode: uint32(OpcodeMiscMemoryCopy)}).Name())
+	require.Equal(t, "i32x4.extract_lane", (&BodyInstruction{Opcode: OpcodeVecPrefix, Subopcode: uint32(OpcodeVecI32x4ExtractLane)}).Name())
+	require.Equal(t, "f32x4.relaxed_madd", (&BodyInstruction{Opcode: OpcodeVecPrefix, Subopcode: 0x105}).Name())
+}
@@ -101,12 +101,15 @@ func (m *Module) buildFunctionDefinitionsOnce() {
 		d.Debugname = wasmdebug.FuncName(moduleName, funcName, funcIdx)
 		d.paramNames = paramNames(localNames, funcIdx, len(d.Functype.Params))
 		d.resultNames = paramNames(resultNames, funcIdx, len(d.Functype.Results))
+	}
 
-		for i := range m.ExportSection {
-			e := &m.ExportSection[i]
-			if e.Type == ExternTypeFunc && e.Index == funcIdx {
-				d.exportNames = append(d.exportNames, e.Name)
-			}
+	// Collect the export names in one pass, rather than scanning the exports per function, as there can be
+	// many of both, such as in a host module with hundreds of functions.
+	for i := range m.ExportSection {
+		e := &m.ExportSection[i]
+		if e.Type == ExternTypeFunc {
+			d := &m.FunctionDefinitionSection[e.Index]
+			d.exportNames = append(d.exportNames, e.Name)
 		}
 	}
 }
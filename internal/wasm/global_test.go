@@ -352,3 +352,37 @@ func TestPublicModule_Global(t *testing.T) {
 		})
 	}
 }
+
+func TestModuleInstance_GlobalValues(t *testing.T) {
+	s := newStore()
+	module, err := s.Instantiate(context.Background(), &Module{
+		GlobalSection: []Global{
+			{
+				Type: GlobalType{ValType: ValueTypeI32},
+				Init: ConstantExpression{Opcode: OpcodeI32Const, Data: leb128.EncodeInt32(1)},
+			},
+			{
+				Type: GlobalType{ValType: ValueTypeI64, Mutable: true},
+				Init: ConstantExpression{Opcode: OpcodeI64Const, Data: leb128.EncodeInt64(2)},
+			},
+			{ // not exported
+				Type: GlobalType{ValType: ValueTypeF64},
+				Init: ConstantExpression{Opcode: OpcodeF64Const, Data: u64.LeBytes(api.EncodeF64(3.0))},
+			},
+		},
+		Exports: map[string]*Export{
+			"const":   {Type: ExternTypeGlobal, Name: "const", Index: 0},
+			"mutable": {Type: ExternTypeGlobal, Name: "mutable", Index: 1},
+		},
+	}, t.Name(), nil, nil)
+	require.NoError(t, err)
+
+	require.Equal(t, map[string]uint64{"const": 1, "mutable": 2}, module.GlobalValues())
+	require.Equal(t, map[string]api.ValueType{"const": ValueTypeI32, "mutable": ValueTypeI64}, module.GlobalTypes())
+
+	// The result is a copy, so must be called again to see updates.
+	values := module.GlobalValues()
+	module.ExportedGlobal("mutable").(api.MutableGlobal).Set(4)
+	require.Equal(t, uint64(2), values["mutable"])
+	require.Equal(t, uint64(4), module.GlobalValues()["mutable"])
+}
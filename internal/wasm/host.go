@@ -72,7 +72,7 @@ func NewHostModule(
 	// compilation of host modules is not costly as it's merely small trampolines vs the real-world native Wasm binary.
 	// TODO: refactor engines so that we can properly cache compiled machine codes for host modules.
 	m.AssignModuleID([]byte(fmt.Sprintf("@@@@@@@@%p", m)), // @@@@@@@@ = any 8 bytes different from Wasm header.
-		nil, false)
+		nil, false, false)
 	return
 }
 
@@ -124,11 +124,17 @@ func addFuncs(
 	m.FunctionSection = make([]Index, 0, funcCount)
 	m.CodeSection = make([]Code, 0, funcCount)
 
+	// Index existing types by signature, so that adding many functions doesn't rescan them each time.
+	typeIndexes := make(map[string]Index, len(m.TypeSection))
+	for i := range m.TypeSection {
+		typeIndexes[m.TypeSection[i].key()] = Index(i)
+	}
+
 	idx := Index(0)
 	for _, name := range exportNames {
 		hf := nameToHostFunc[name]
 		debugName := wasmdebug.FuncName(moduleName, name, idx)
-		typeIdx, typeErr := m.maybeAddType(hf.ParamTypes, hf.ResultTypes, enabledFeatures)
+		typeIdx, typeErr := m.maybeAddType(hf.ParamTypes, hf.ResultTypes, enabledFeatures, typeIndexes)
 		if typeErr != nil {
 			return fmt.Errorf("func[%s] %v", debugName, typeErr)
 		}
@@ -159,21 +165,22 @@ func addFuncs(
 	return nil
 }
 
-func (m *Module) maybeAddType(params, results []ValueType, enabledFeatures api.CoreFeatures) (Index, error) {
+// maybeAddType returns the index of the type with the given signature, adding it to the TypeSection if absent.
+// typeIndexes are the indexes of the types already in the TypeSection, by FunctionType.key.
+func (m *Module) maybeAddType(params, results []ValueType, enabledFeatures api.CoreFeatures, typeIndexes map[string]Index) (Index, error) {
 	if len(results) > 1 {
 		// Guard >1.0 feature multi-value
 		if err := enabledFeatures.RequireEnabled(api.CoreFeatureMultiValue); err != nil {
 			return 0, fmt.Errorf("multiple result types invalid as %v", err)
 		}
 	}
-	for i := range m.TypeSection {
-		t := &m.TypeSection[i]
-		if t.EqualsSignature(params, results) {
-			return Index(i), nil
-		}
+	key := (&FunctionType{Params: params, Results: results}).key()
+	if idx, ok := typeIndexes[key]; ok {
+		return idx, nil
 	}
 
 	result := m.SectionElementCount(SectionIDType)
 	m.TypeSection = append(m.TypeSection, FunctionType{Params: params, Results: results})
+	typeIndexes[key] = result
 	return result, nil
 }
@@ -0,0 +1,118 @@
+package wasm
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/tetratelabs/wazero/api"
+)
+
+// InstantiateImportStubs instantiates a host module for each imported module name in stubs, exporting a function per
+// stubbed name which returns the given results, with the signature of the function import of module it resolves.
+//
+// The returned modules aren't registered in the Store, so they can't be imported by name. Pass them to
+// InstantiateWithOptions, which closes them with the module importing them.
+func (s *Store) InstantiateImportStubs(ctx context.Context, module *Module, stubs map[string]map[string][]uint64) (ret map[string]*ModuleInstance, err error) {
+	ret = make(map[string]*ModuleInstance, len(stubs))
+	defer func() {
+		if err != nil {
+			for _, stub := range ret {
+				_ = stub.closeWithExitCode(ctx, 0)
+			}
+			ret = nil
+		}
+	}()
+
+	for moduleName, nameToResults := range stubs {
+		var exportNames []string
+		nameToHostFunc := make(map[string]*HostFunc, len(nameToResults))
+		for _, i := range module.ImportPerModule[moduleName] {
+			results, ok := nameToResults[i.Name]
+			if !ok || i.Type != ExternTypeFunc {
+				continue
+			} else if _, ok = nameToHostFunc[i.Name]; ok {
+				continue // Imported more than once.
+			}
+			typ := &module.TypeSection[i.DescFunc]
+			if len(results) != typ.ResultNumInUint64 {
+				return nil, fmt.Errorf("import stub %s.%s has %d results, but the import returns %d",
+					moduleName, i.Name, len(results), typ.ResultNumInUint64)
+			}
+			exportNames = append(exportNames, i.Name)
+			nameToHostFunc[i.Name] = &HostFunc{
+				ExportName:  i.Name,
+				ParamTypes:  typ.Params,
+				ResultTypes: typ.Results,
+				Code:        Code{GoFunc: constantResults(results)},
+			}
+		}
+		if len(exportNames) != len(nameToResults) {
+			return nil, fmt.Errorf("import stub %s.%s doesn't match a function import",
+				moduleName, firstMissing(nameToResults, nameToHostFunc))
+		}
+
+		var stub *ModuleInstance
+		if stub, err = s.instantiateHostModule(ctx, moduleName, exportNames, nameToHostFunc); err != nil {
+			return
+		}
+		ret[moduleName] = stub
+	}
+	return
+}
+
+// instantiateHostModule compiles and instantiates a host module without registering it.
+func (s *Store) instantiateHostModule(ctx context.Context, moduleName string, exportNames []string, nameToHostFunc map[string]*HostFunc) (*ModuleInstance, error) {
+	module, err := NewHostModule(moduleName, exportNames, nameToHostFunc, s.EnabledFeatures)
+	if err != nil {
+		return nil, err
+	} else if err = module.Validate(s.EnabledFeatures, MaximumBlockNestingDepth); err != nil {
+		return nil, err
+	} else if err = s.Engine.CompileModule(ctx, module, nil, false); err != nil {
+		return nil, err
+	}
+
+	typeIDs, err := s.GetFunctionTypeIDs(module.TypeSection)
+	if err != nil {
+		s.Engine.DeleteCompiledModule(module)
+		return nil, err
+	}
+	m, err := s.instantiate(ctx, module, moduleName, nil, typeIDs, InstantiateOptions{})
+	if err != nil {
+		s.Engine.DeleteCompiledModule(module)
+		return nil, err
+	}
+	m.CodeCloser = compiledCode{s.Engine, module}
+	return m, nil
+}
+
+// compiledCode is an api.Closer which deletes the code compiled by the engine for the module.
+type compiledCode struct {
+	engine Engine
+	module *Module
+}
+
+// Close implements api.Closer.
+func (c compiledCode) Close(context.Context) error {
+	c.engine.DeleteCompiledModule(c.module)
+	return nil
+}
+
+// constantResults returns a function which ignores its parameters and returns the results.
+func constantResults(results []uint64) api.GoFunction {
+	return api.GoFunc(func(_ context.Context, stack []uint64) {
+		copy(stack, results)
+	})
+}
+
+// firstMissing returns the first name in nameToResults, in lexical order, which isn't in nameToHostFunc.
+func firstMissing(nameToResults map[string][]uint64, nameToHostFunc map[string]*HostFunc) string {
+	var missing []string
+	for name := range nameToResults {
+		if _, ok := nameToHostFunc[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+	sort.Strings(missing)
+	return missing[0]
+}
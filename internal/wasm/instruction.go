@@ -20,6 +20,24 @@ const (
 	// OpcodeElse brackets a sequence of instructions enclosed by an OpcodeIf. A branch instruction on a then label
 	// breaks out to after the OpcodeEnd on the enclosing OpcodeIf.
 	OpcodeElse Opcode = 0x05
+
+	// OpcodeTry brackets a sequence of instructions whose exceptions are handled by a following OpcodeCatch or
+	// OpcodeCatchAll, or passed on by OpcodeDelegate. A branch instruction on a try label breaks out to after its
+	// OpcodeEnd.
+	//
+	// Note: This and the other exception handling instructions are only valid when
+	// experimental.CoreFeaturesExceptionHandling is enabled.
+	// See https://github.com/WebAssembly/exception-handling/blob/main/proposals/exception-handling/legacy/Exceptions.md
+	OpcodeTry Opcode = 0x06
+	// OpcodeCatch starts a handler of an OpcodeTry for exceptions of the tag in its immediate, which begins with the
+	// values carried by the exception on the stack.
+	OpcodeCatch Opcode = 0x07
+	// OpcodeThrow is a stack-polymorphic opcode that throws an exception of the tag in its immediate, carrying the
+	// values of the params of the tag type.
+	OpcodeThrow Opcode = 0x08
+	// OpcodeRethrow is a stack-polymorphic opcode that throws the exception caught by the OpcodeCatch or
+	// OpcodeCatchAll of the label in its immediate again.
+	OpcodeRethrow Opcode = 0x09
 	// OpcodeEnd terminates a control instruction OpcodeBlock, OpcodeLoop or OpcodeIf.
 	OpcodeEnd Opcode = 0x0b
 
@@ -42,6 +60,24 @@ const (
 	OpcodeCall         Opcode = 0x10
 	OpcodeCallIndirect Opcode = 0x11
 
+	// OpcodeReturnCall is like OpcodeCall, except the callee replaces the
+	// caller's frame instead of pushing a new one. The callee's results must
+	// match those of the current function.
+	//
+	// Note: This is only valid when the tail-call proposal is enabled.
+	// See https://github.com/WebAssembly/tail-call/blob/main/proposals/tail-call/Overview.md
+	OpcodeReturnCall Opcode = 0x12
+
+	// OpcodeReturnCallIndirect is the OpcodeCallIndirect variant of
+	// OpcodeReturnCall.
+	OpcodeReturnCallIndirect Opcode = 0x13
+
+	// OpcodeDelegate terminates an OpcodeTry without handlers, passing its exceptions to the handlers of the try block
+	// of the label in its immediate, or to the caller when the label is the function.
+	OpcodeDelegate Opcode = 0x18
+	// OpcodeCatchAll starts a handler of an OpcodeTry for exceptions of any tag.
+	OpcodeCatchAll Opcode = 0x19
+
 	// parametric instructions
 
 	OpcodeDrop        Opcode = 0x1a
@@ -280,6 +316,10 @@ const (
 	// OpcodeVecPrefix is the prefix of all vector isntructions introduced in
 	// CoreFeatureSIMD.
 	OpcodeVecPrefix Opcode = 0xfd
+
+	// OpcodeAtomicPrefix is the prefix of all atomic instructions introduced in
+	// experimental.CoreFeaturesThreads.
+	OpcodeAtomicPrefix Opcode = 0xfe
 )
 
 // OpcodeMisc represents opcodes of the miscellaneous operations.
@@ -785,7 +825,7 @@ const (
 	OpcodeF32ConvertI32SName    = "f32.convert_i32_s"
 	OpcodeF32ConvertI32UName    = "f32.convert_i32_u"
 	OpcodeF32ConvertI64SName    = "f32.convert_i64_s"
-	OpcodeF32ConvertI64UName    = "f32.convert_i64u"
+	OpcodeF32ConvertI64UName    = "f32.convert_i64_u"
 	OpcodeF32DemoteF64Name      = "f32.demote_f64"
 	OpcodeF64ConvertI32SName    = "f64.convert_i32_s"
 	OpcodeF64ConvertI32UName    = "f64.convert_i32_u"
@@ -812,8 +852,19 @@ const (
 	OpcodeI64Extend16SName = "i64.extend16_s"
 	OpcodeI64Extend32SName = "i64.extend32_s"
 
-	OpcodeMiscPrefixName = "misc_prefix"
-	OpcodeVecPrefixName  = "vector_prefix"
+	OpcodeMiscPrefixName   = "misc_prefix"
+	OpcodeVecPrefixName    = "vector_prefix"
+	OpcodeAtomicPrefixName = "atomic_prefix"
+
+	OpcodeReturnCallName         = "return_call"
+	OpcodeReturnCallIndirectName = "return_call_indirect"
+
+	OpcodeTryName      = "try"
+	OpcodeCatchName    = "catch"
+	OpcodeThrowName    = "throw"
+	OpcodeRethrowName  = "rethrow"
+	OpcodeDelegateName = "delegate"
+	OpcodeCatchAllName = "catch_all"
 )
 
 var instructionNames = [256]string{
@@ -1008,6 +1059,24 @@ var instructionNames = [256]string{
 
 	OpcodeMiscPrefix: OpcodeMiscPrefixName,
 	OpcodeVecPrefix:  OpcodeVecPrefixName,
+
+	// Below are toggled with experimental.CoreFeaturesThreads
+
+	OpcodeAtomicPrefix: OpcodeAtomicPrefixName,
+
+	// Below are toggled with experimental.CoreFeaturesTailCall
+
+	OpcodeReturnCall:         OpcodeReturnCallName,
+	OpcodeReturnCallIndirect: OpcodeReturnCallIndirectName,
+
+	// Below are toggled with experimental.CoreFeaturesExceptionHandling
+
+	OpcodeTry:      OpcodeTryName,
+	OpcodeCatch:    OpcodeCatchName,
+	OpcodeThrow:    OpcodeThrowName,
+	OpcodeRethrow:  OpcodeRethrowName,
+	OpcodeDelegate: OpcodeDelegateName,
+	OpcodeCatchAll: OpcodeCatchAllName,
 }
 
 // InstructionName returns the instruction corresponding to this binary Opcode.
@@ -1547,3 +1616,367 @@ var vectorInstructionName = map[OpcodeVec]string{
 func VectorInstructionName(oc OpcodeVec) (ret string) {
 	return vectorInstructionName[oc]
 }
+
+// OpcodeVecRelaxed represents an opcode of a relaxed-SIMD instruction, which is
+// prefixed by OpcodeVecPrefix. These are encoded as the unsigned LEB128 of 0x100
+// plus the OpcodeVecRelaxed, so unlike OpcodeVec they take two bytes: the
+// OpcodeVecRelaxed with the high bit set, then 0x02. See VecRelaxedOpcode.
+//
+// These opcodes are toggled with experimental.CoreFeaturesRelaxedSIMD.
+type OpcodeVecRelaxed = byte
+
+// OpcodeVecRelaxedOffset is added to an OpcodeVecRelaxed for its value as an unsigned LEB128.
+const OpcodeVecRelaxedOffset = 0x100
+
+const (
+	OpcodeVecRelaxedI8x16Swizzle           OpcodeVecRelaxed = 0x00
+	OpcodeVecRelaxedI32x4TruncF32x4S       OpcodeVecRelaxed = 0x01
+	OpcodeVecRelaxedI32x4TruncF32x4U       OpcodeVecRelaxed = 0x02
+	OpcodeVecRelaxedI32x4TruncF64x2SZero   OpcodeVecRelaxed = 0x03
+	OpcodeVecRelaxedI32x4TruncF64x2UZero   OpcodeVecRelaxed = 0x04
+	OpcodeVecRelaxedF32x4Madd              OpcodeVecRelaxed = 0x05
+	OpcodeVecRelaxedF32x4Nmadd             OpcodeVecRelaxed = 0x06
+	OpcodeVecRelaxedF64x2Madd              OpcodeVecRelaxed = 0x07
+	OpcodeVecRelaxedF64x2Nmadd             OpcodeVecRelaxed = 0x08
+	OpcodeVecRelaxedI8x16Laneselect        OpcodeVecRelaxed = 0x09
+	OpcodeVecRelaxedI16x8Laneselect        OpcodeVecRelaxed = 0x0a
+	OpcodeVecRelaxedI32x4Laneselect        OpcodeVecRelaxed = 0x0b
+	OpcodeVecRelaxedI64x2Laneselect        OpcodeVecRelaxed = 0x0c
+	OpcodeVecRelaxedF32x4Min               OpcodeVecRelaxed = 0x0d
+	OpcodeVecRelaxedF32x4Max               OpcodeVecRelaxed = 0x0e
+	OpcodeVecRelaxedF64x2Min               OpcodeVecRelaxed = 0x0f
+	OpcodeVecRelaxedF64x2Max               OpcodeVecRelaxed = 0x10
+	OpcodeVecRelaxedI16x8Q15mulrS          OpcodeVecRelaxed = 0x11
+	OpcodeVecRelaxedI16x8DotI8x16I7x16S    OpcodeVecRelaxed = 0x12
+	OpcodeVecRelaxedI32x4DotI8x16I7x16AddS OpcodeVecRelaxed = 0x13
+)
+
+const (
+	OpcodeVecRelaxedI8x16SwizzleName           = "i8x16.relaxed_swizzle"
+	OpcodeVecRelaxedI32x4TruncF32x4SName       = "i32x4.relaxed_trunc_f32x4_s"
+	OpcodeVecRelaxedI32x4TruncF32x4UName       = "i32x4.relaxed_trunc_f32x4_u"
+	OpcodeVecRelaxedI32x4TruncF64x2SZeroName   = "i32x4.relaxed_trunc_f64x2_s_zero"
+	OpcodeVecRelaxedI32x4TruncF64x2UZeroName   = "i32x4.relaxed_trunc_f64x2_u_zero"
+	OpcodeVecRelaxedF32x4MaddName              = "f32x4.relaxed_madd"
+	OpcodeVecRelaxedF32x4NmaddName             = "f32x4.relaxed_nmadd"
+	OpcodeVecRelaxedF64x2MaddName              = "f64x2.relaxed_madd"
+	OpcodeVecRelaxedF64x2NmaddName             = "f64x2.relaxed_nmadd"
+	OpcodeVecRelaxedI8x16LaneselectName        = "i8x16.relaxed_laneselect"
+	OpcodeVecRelaxedI16x8LaneselectName        = "i16x8.relaxed_laneselect"
+	OpcodeVecRelaxedI32x4LaneselectName        = "i32x4.relaxed_laneselect"
+	OpcodeVecRelaxedI64x2LaneselectName        = "i64x2.relaxed_laneselect"
+	OpcodeVecRelaxedF32x4MinName               = "f32x4.relaxed_min"
+	OpcodeVecRelaxedF32x4MaxName               = "f32x4.relaxed_max"
+	OpcodeVecRelaxedF64x2MinName               = "f64x2.relaxed_min"
+	OpcodeVecRelaxedF64x2MaxName               = "f64x2.relaxed_max"
+	OpcodeVecRelaxedI16x8Q15mulrSName          = "i16x8.relaxed_q15mulr_s"
+	OpcodeVecRelaxedI16x8DotI8x16I7x16SName    = "i16x8.relaxed_dot_i8x16_i7x16_s"
+	OpcodeVecRelaxedI32x4DotI8x16I7x16AddSName = "i32x4.relaxed_dot_i8x16_i7x16_add_s"
+)
+
+var vecRelaxedInstructionName = map[OpcodeVecRelaxed]string{
+	OpcodeVecRelaxedI8x16Swizzle:           OpcodeVecRelaxedI8x16SwizzleName,
+	OpcodeVecRelaxedI32x4TruncF32x4S:       OpcodeVecRelaxedI32x4TruncF32x4SName,
+	OpcodeVecRelaxedI32x4TruncF32x4U:       OpcodeVecRelaxedI32x4TruncF32x4UName,
+	OpcodeVecRelaxedI32x4TruncF64x2SZero:   OpcodeVecRelaxedI32x4TruncF64x2SZeroName,
+	OpcodeVecRelaxedI32x4TruncF64x2UZero:   OpcodeVecRelaxedI32x4TruncF64x2UZeroName,
+	OpcodeVecRelaxedF32x4Madd:              OpcodeVecRelaxedF32x4MaddName,
+	OpcodeVecRelaxedF32x4Nmadd:             OpcodeVecRelaxedF32x4NmaddName,
+	OpcodeVecRelaxedF64x2Madd:              OpcodeVecRelaxedF64x2MaddName,
+	OpcodeVecRelaxedF64x2Nmadd:             OpcodeVecRelaxedF64x2NmaddName,
+	OpcodeVecRelaxedI8x16Laneselect:        OpcodeVecRelaxedI8x16LaneselectName,
+	OpcodeVecRelaxedI16x8Laneselect:        OpcodeVecRelaxedI16x8LaneselectName,
+	OpcodeVecRelaxedI32x4Laneselect:        OpcodeVecRelaxedI32x4LaneselectName,
+	OpcodeVecRelaxedI64x2Laneselect:        OpcodeVecRelaxedI64x2LaneselectName,
+	OpcodeVecRelaxedF32x4Min:               OpcodeVecRelaxedF32x4MinName,
+	OpcodeVecRelaxedF32x4Max:               OpcodeVecRelaxedF32x4MaxName,
+	OpcodeVecRelaxedF64x2Min:               OpcodeVecRelaxedF64x2MinName,
+	OpcodeVecRelaxedF64x2Max:               OpcodeVecRelaxedF64x2MaxName,
+	OpcodeVecRelaxedI16x8Q15mulrS:          OpcodeVecRelaxedI16x8Q15mulrSName,
+	OpcodeVecRelaxedI16x8DotI8x16I7x16S:    OpcodeVecRelaxedI16x8DotI8x16I7x16SName,
+	OpcodeVecRelaxedI32x4DotI8x16I7x16AddS: OpcodeVecRelaxedI32x4DotI8x16I7x16AddSName,
+}
+
+// VecRelaxedInstructionName returns the instruction name corresponding to the relaxed-SIMD Opcode.
+func VecRelaxedInstructionName(oc OpcodeVecRelaxed) string {
+	return vecRelaxedInstructionName[oc]
+}
+
+// VecRelaxedOpcode returns the OpcodeVecRelaxed encoded at the start of b, which are the bytes following
+// OpcodeVecPrefix, or false if they encode an OpcodeVec instead. When true, the instruction is two bytes long.
+func VecRelaxedOpcode(b []byte) (OpcodeVecRelaxed, bool) {
+	if len(b) >= 2 && b[0]&0x80 != 0 && b[1] == 0x02 {
+		return b[0] &^ 0x80, true
+	}
+	return 0, false
+}
+
+// OpcodeAtomic represents an opcode of an atomic instruction, which has a
+// multi-byte encoding and is prefixed by OpcodeAtomicPrefix.
+//
+// These opcodes are toggled with experimental.CoreFeaturesThreads.
+type OpcodeAtomic = byte
+
+const (
+	OpcodeAtomicMemoryNotify OpcodeAtomic = 0x00
+	OpcodeAtomicMemoryWait32 OpcodeAtomic = 0x01
+	OpcodeAtomicMemoryWait64 OpcodeAtomic = 0x02
+	OpcodeAtomicFence        OpcodeAtomic = 0x03
+
+	OpcodeAtomicI32Load    OpcodeAtomic = 0x10
+	OpcodeAtomicI64Load    OpcodeAtomic = 0x11
+	OpcodeAtomicI32Load8U  OpcodeAtomic = 0x12
+	OpcodeAtomicI32Load16U OpcodeAtomic = 0x13
+	OpcodeAtomicI64Load8U  OpcodeAtomic = 0x14
+	OpcodeAtomicI64Load16U OpcodeAtomic = 0x15
+	OpcodeAtomicI64Load32U OpcodeAtomic = 0x16
+
+	OpcodeAtomicI32Store   OpcodeAtomic = 0x17
+	OpcodeAtomicI64Store   OpcodeAtomic = 0x18
+	OpcodeAtomicI32Store8  OpcodeAtomic = 0x19
+	OpcodeAtomicI32Store16 OpcodeAtomic = 0x1a
+	OpcodeAtomicI64Store8  OpcodeAtomic = 0x1b
+	OpcodeAtomicI64Store16 OpcodeAtomic = 0x1c
+	OpcodeAtomicI64Store32 OpcodeAtomic = 0x1d
+
+	OpcodeAtomicI32RmwAdd    OpcodeAtomic = 0x1e
+	OpcodeAtomicI64RmwAdd    OpcodeAtomic = 0x1f
+	OpcodeAtomicI32Rmw8AddU  OpcodeAtomic = 0x20
+	OpcodeAtomicI32Rmw16AddU OpcodeAtomic = 0x21
+	OpcodeAtomicI64Rmw8AddU  OpcodeAtomic = 0x22
+	OpcodeAtomicI64Rmw16AddU OpcodeAtomic = 0x23
+	OpcodeAtomicI64Rmw32AddU OpcodeAtomic = 0x24
+
+	OpcodeAtomicI32RmwSub    OpcodeAtomic = 0x25
+	OpcodeAtomicI64RmwSub    OpcodeAtomic = 0x26
+	OpcodeAtomicI32Rmw8SubU  OpcodeAtomic = 0x27
+	OpcodeAtomicI32Rmw16SubU OpcodeAtomic = 0x28
+	OpcodeAtomicI64Rmw8SubU  OpcodeAtomic = 0x29
+	OpcodeAtomicI64Rmw16SubU OpcodeAtomic = 0x2a
+	OpcodeAtomicI64Rmw32SubU OpcodeAtomic = 0x2b
+
+	OpcodeAtomicI32RmwAnd    OpcodeAtomic = 0x2c
+	OpcodeAtomicI64RmwAnd    OpcodeAtomic = 0x2d
+	OpcodeAtomicI32Rmw8AndU  OpcodeAtomic = 0x2e
+	OpcodeAtomicI32Rmw16AndU OpcodeAtomic = 0x2f
+	OpcodeAtomicI64Rmw8AndU  OpcodeAtomic = 0x30
+	OpcodeAtomicI64Rmw16AndU OpcodeAtomic = 0x31
+	OpcodeAtomicI64Rmw32AndU OpcodeAtomic = 0x32
+
+	OpcodeAtomicI32RmwOr    OpcodeAtomic = 0x33
+	OpcodeAtomicI64RmwOr    OpcodeAtomic = 0x34
+	OpcodeAtomicI32Rmw8OrU  OpcodeAtomic = 0x35
+	OpcodeAtomicI32Rmw16OrU OpcodeAtomic = 0x36
+	OpcodeAtomicI64Rmw8OrU  OpcodeAtomic = 0x37
+	OpcodeAtomicI64Rmw16OrU OpcodeAtomic = 0x38
+	OpcodeAtomicI64Rmw32OrU OpcodeAtomic = 0x39
+
+	OpcodeAtomicI32RmwXor    OpcodeAtomic = 0x3a
+	OpcodeAtomicI64RmwXor    OpcodeAtomic = 0x3b
+	OpcodeAtomicI32Rmw8XorU  OpcodeAtomic = 0x3c
+	OpcodeAtomicI32Rmw16XorU OpcodeAtomic = 0x3d
+	OpcodeAtomicI64Rmw8XorU  OpcodeAtomic = 0x3e
+	OpcodeAtomicI64Rmw16XorU OpcodeAtomic = 0x3f
+	OpcodeAtomicI64Rmw32XorU OpcodeAtomic = 0x40
+
+	OpcodeAtomicI32RmwXchg    OpcodeAtomic = 0x41
+	OpcodeAtomicI64RmwXchg    OpcodeAtomic = 0x42
+	OpcodeAtomicI32Rmw8XchgU  OpcodeAtomic = 0x43
+	OpcodeAtomicI32Rmw16XchgU OpcodeAtomic = 0x44
+	OpcodeAtomicI64Rmw8XchgU  OpcodeAtomic = 0x45
+	OpcodeAtomicI64Rmw16XchgU OpcodeAtomic = 0x46
+	OpcodeAtomicI64Rmw32XchgU OpcodeAtomic = 0x47
+
+	OpcodeAtomicI32RmwCmpxchg    OpcodeAtomic = 0x48
+	OpcodeAtomicI64RmwCmpxchg    OpcodeAtomic = 0x49
+	OpcodeAtomicI32Rmw8CmpxchgU  OpcodeAtomic = 0x4a
+	OpcodeAtomicI32Rmw16CmpxchgU OpcodeAtomic = 0x4b
+	OpcodeAtomicI64Rmw8CmpxchgU  OpcodeAtomic = 0x4c
+	OpcodeAtomicI64Rmw16CmpxchgU OpcodeAtomic = 0x4d
+	OpcodeAtomicI64Rmw32CmpxchgU OpcodeAtomic = 0x4e
+)
+
+const (
+	OpcodeAtomicMemoryNotifyName = "memory.atomic.notify"
+	OpcodeAtomicMemoryWait32Name = "memory.atomic.wait32"
+	OpcodeAtomicMemoryWait64Name = "memory.atomic.wait64"
+	OpcodeAtomicFenceName        = "atomic.fence"
+
+	OpcodeAtomicI32LoadName    = "i32.atomic.load"
+	OpcodeAtomicI64LoadName    = "i64.atomic.load"
+	OpcodeAtomicI32Load8UName  = "i32.atomic.load8_u"
+	OpcodeAtomicI32Load16UName = "i32.atomic.load16_u"
+	OpcodeAtomicI64Load8UName  = "i64.atomic.load8_u"
+	OpcodeAtomicI64Load16UName = "i64.atomic.load16_u"
+	OpcodeAtomicI64Load32UName = "i64.atomic.load32_u"
+
+	OpcodeAtomicI32StoreName   = "i32.atomic.store"
+	OpcodeAtomicI64StoreName   = "i64.atomic.store"
+	OpcodeAtomicI32Store8Name  = "i32.atomic.store8"
+	OpcodeAtomicI32Store16Name = "i32.atomic.store16"
+	OpcodeAtomicI64Store8Name  = "i64.atomic.store8"
+	OpcodeAtomicI64Store16Name = "i64.atomic.store16"
+	OpcodeAtomicI64Store32Name = "i64.atomic.store32"
+
+	OpcodeAtomicI32RmwAddName    = "i32.atomic.rmw.add"
+	OpcodeAtomicI64RmwAddName    = "i64.atomic.rmw.add"
+	OpcodeAtomicI32Rmw8AddUName  = "i32.atomic.rmw8.add_u"
+	OpcodeAtomicI32Rmw16AddUName = "i32.atomic.rmw16.add_u"
+	OpcodeAtomicI64Rmw8AddUName  = "i64.atomic.rmw8.add_u"
+	OpcodeAtomicI64Rmw16AddUName = "i64.atomic.rmw16.add_u"
+	OpcodeAtomicI64Rmw32AddUName = "i64.atomic.rmw32.add_u"
+
+	OpcodeAtomicI32RmwSubName    = "i32.atomic.rmw.sub"
+	OpcodeAtomicI64RmwSubName    = "i64.atomic.rmw.sub"
+	OpcodeAtomicI32Rmw8SubUName  = "i32.atomic.rmw8.sub_u"
+	OpcodeAtomicI32Rmw16SubUName = "i32.atomic.rmw16.sub_u"
+	OpcodeAtomicI64Rmw8SubUName  = "i64.atomic.rmw8.sub_u"
+	OpcodeAtomicI64Rmw16SubUName = "i64.atomic.rmw16.sub_u"
+	OpcodeAtomicI64Rmw32SubUName = "i64.atomic.rmw32.sub_u"
+
+	OpcodeAtomicI32RmwAndName    = "i32.atomic.rmw.and"
+	OpcodeAtomicI64RmwAndName    = "i64.atomic.rmw.and"
+	OpcodeAtomicI32Rmw8AndUName  = "i32.atomic.rmw8.and_u"
+	OpcodeAtomicI32Rmw16AndUName = "i32.atomic.rmw16.and_u"
+	OpcodeAtomicI64Rmw8AndUName  = "i64.atomic.rmw8.and_u"
+	OpcodeAtomicI64Rmw16AndUName = "i64.atomic.rmw16.and_u"
+	OpcodeAtomicI64Rmw32AndUName = "i64.atomic.rmw32.and_u"
+
+	OpcodeAtomicI32RmwOrName    = "i32.atomic.rmw.or"
+	OpcodeAtomicI64RmwOrName    = "i64.atomic.rmw.or"
+	OpcodeAtomicI32Rmw8OrUName  = "i32.atomic.rmw8.or_u"
+	OpcodeAtomicI32Rmw16OrUName = "i32.atomic.rmw16.or_u"
+	OpcodeAtomicI64Rmw8OrUName  = "i64.atomic.rmw8.or_u"
+	OpcodeAtomicI64Rmw16OrUName = "i64.atomic.rmw16.or_u"
+	OpcodeAtomicI64Rmw32OrUName = "i64.atomic.rmw32.or_u"
+
+	OpcodeAtomicI32RmwXorName    = "i32.atomic.rmw.xor"
+	OpcodeAtomicI64RmwXorName    = "i64.atomic.rmw.xor"
+	OpcodeAtomicI32Rmw8XorUName  = "i32.atomic.rmw8.xor_u"
+	OpcodeAtomicI32Rmw16XorUName = "i32.atomic.rmw16.xor_u"
+	OpcodeAtomicI64Rmw8XorUName  = "i64.atomic.rmw8.xor_u"
+	OpcodeAtomicI64Rmw16XorUName = "i64.atomic.rmw16.xor_u"
+	OpcodeAtomicI64Rmw32XorUName = "i64.atomic.rmw32.xor_u"
+
+	OpcodeAtomicI32RmwXchgName    = "i32.atomic.rmw.xchg"
+	OpcodeAtomicI64RmwXchgName    = "i64.atomic.rmw.xchg"
+	OpcodeAtomicI32Rmw8XchgUName  = "i32.atomic.rmw8.xchg_u"
+	OpcodeAtomicI32Rmw16XchgUName = "i32.atomic.rmw16.xchg_u"
+	OpcodeAtomicI64Rmw8XchgUName  = "i64.atomic.rmw8.xchg_u"
+	OpcodeAtomicI64Rmw16XchgUName = "i64.atomic.rmw16.xchg_u"
+	OpcodeAtomicI64Rmw32XchgUName = "i64.atomic.rmw32.xchg_u"
+
+	OpcodeAtomicI32RmwCmpxchgName    = "i32.atomic.rmw.cmpxchg"
+	OpcodeAtomicI64RmwCmpxchgName    = "i64.atomic.rmw.cmpxchg"
+	OpcodeAtomicI32Rmw8CmpxchgUName  = "i32.atomic.rmw8.cmpxchg_u"
+	OpcodeAtomicI32Rmw16CmpxchgUName = "i32.atomic.rmw16.cmpxchg_u"
+	OpcodeAtomicI64Rmw8CmpxchgUName  = "i64.atomic.rmw8.cmpxchg_u"
+	OpcodeAtomicI64Rmw16CmpxchgUName = "i64.atomic.rmw16.cmpxchg_u"
+	OpcodeAtomicI64Rmw32CmpxchgUName = "i64.atomic.rmw32.cmpxchg_u"
+)
+
+var atomicInstructionName = map[OpcodeAtomic]string{
+	OpcodeAtomicMemoryNotify:     OpcodeAtomicMemoryNotifyName,
+	OpcodeAtomicMemoryWait32:     OpcodeAtomicMemoryWait32Name,
+	OpcodeAtomicMemoryWait64:     OpcodeAtomicMemoryWait64Name,
+	OpcodeAtomicFence:            OpcodeAtomicFenceName,
+	OpcodeAtomicI32Load:          OpcodeAtomicI32LoadName,
+	OpcodeAtomicI64Load:          OpcodeAtomicI64LoadName,
+	OpcodeAtomicI32Load8U:        OpcodeAtomicI32Load8UName,
+	OpcodeAtomicI32Load16U:       OpcodeAtomicI32Load16UName,
+	OpcodeAtomicI64Load8U:        OpcodeAtomicI64Load8UName,
+	OpcodeAtomicI64Load16U:       OpcodeAtomicI64Load16UName,
+	OpcodeAtomicI64Load32U:       OpcodeAtomicI64Load32UName,
+	OpcodeAtomicI32Store:         OpcodeAtomicI32StoreName,
+	OpcodeAtomicI64Store:         OpcodeAtomicI64StoreName,
+	OpcodeAtomicI32Store8:        OpcodeAtomicI32Store8Name,
+	OpcodeAtomicI32Store16:       OpcodeAtomicI32Store16Name,
+	OpcodeAtomicI64Store8:        OpcodeAtomicI64Store8Name,
+	OpcodeAtomicI64Store16:       OpcodeAtomicI64Store16Name,
+	OpcodeAtomicI64Store32:       OpcodeAtomicI64Store32Name,
+	OpcodeAtomicI32RmwAdd:        OpcodeAtomicI32RmwAddName,
+	OpcodeAtomicI64RmwAdd:        OpcodeAtomicI64RmwAddName,
+	OpcodeAtomicI32Rmw8AddU:      OpcodeAtomicI32Rmw8AddUName,
+	OpcodeAtomicI32Rmw16AddU:     OpcodeAtomicI32Rmw16AddUName,
+	OpcodeAtomicI64Rmw8AddU:      OpcodeAtomicI64Rmw8AddUName,
+	OpcodeAtomicI64Rmw16AddU:     OpcodeAtomicI64Rmw16AddUName,
+	OpcodeAtomicI64Rmw32AddU:     OpcodeAtomicI64Rmw32AddUName,
+	OpcodeAtomicI32RmwSub:        OpcodeAtomicI32RmwSubName,
+	OpcodeAtomicI64RmwSub:        OpcodeAtomicI64RmwSubName,
+	OpcodeAtomicI32Rmw8SubU:      OpcodeAtomicI32Rmw8SubUName,
+	OpcodeAtomicI32Rmw16SubU:     OpcodeAtomicI32Rmw16SubUName,
+	OpcodeAtomicI64Rmw8SubU:      OpcodeAtomicI64Rmw8SubUName,
+	OpcodeAtomicI64Rmw16SubU:     OpcodeAtomicI64Rmw16SubUName,
+	OpcodeAtomicI64Rmw32SubU:     OpcodeAtomicI64Rmw32SubUName,
+	OpcodeAtomicI32RmwAnd:        OpcodeAtomicI32RmwAndName,
+	OpcodeAtomicI64RmwAnd:        OpcodeAtomicI64RmwAndName,
+	OpcodeAtomicI32Rmw8AndU:      OpcodeAtomicI32Rmw8AndUName,
+	OpcodeAtomicI32Rmw16AndU:     OpcodeAtomicI32Rmw16AndUName,
+	OpcodeAtomicI64Rmw8AndU:      OpcodeAtomicI64Rmw8AndUName,
+	OpcodeAtomicI64Rmw16AndU:     OpcodeAtomicI64Rmw16AndUName,
+	OpcodeAtomicI64Rmw32AndU:     OpcodeAtomicI64Rmw32AndUName,
+	OpcodeAtomicI32RmwOr:         OpcodeAtomicI32RmwOrName,
+	OpcodeAtomicI64RmwOr:         OpcodeAtomicI64RmwOrName,
+	OpcodeAtomicI32Rmw8OrU:       OpcodeAtomicI32Rmw8OrUName,
+	OpcodeAtomicI32Rmw16OrU:      OpcodeAtomicI32Rmw16OrUName,
+	OpcodeAtomicI64Rmw8OrU:       OpcodeAtomicI64Rmw8OrUName,
+	OpcodeAtomicI64Rmw16OrU:      OpcodeAtomicI64Rmw16OrUName,
+	OpcodeAtomicI64Rmw32OrU:      OpcodeAtomicI64Rmw32OrUName,
+	OpcodeAtomicI32RmwXor:        OpcodeAtomicI32RmwXorName,
+	OpcodeAtomicI64RmwXor:        OpcodeAtomicI64RmwXorName,
+	OpcodeAtomicI32Rmw8XorU:      OpcodeAtomicI32Rmw8XorUName,
+	OpcodeAtomicI32Rmw16XorU:     OpcodeAtomicI32Rmw16XorUName,
+	OpcodeAtomicI64Rmw8XorU:      OpcodeAtomicI64Rmw8XorUName,
+	OpcodeAtomicI64Rmw16XorU:     OpcodeAtomicI64Rmw16XorUName,
+	OpcodeAtomicI64Rmw32XorU:     OpcodeAtomicI64Rmw32XorUName,
+	OpcodeAtomicI32RmwXchg:       OpcodeAtomicI32RmwXchgName,
+	OpcodeAtomicI64RmwXchg:       OpcodeAtomicI64RmwXchgName,
+	OpcodeAtomicI32Rmw8XchgU:     OpcodeAtomicI32Rmw8XchgUName,
+	OpcodeAtomicI32Rmw16XchgU:    OpcodeAtomicI32Rmw16XchgUName,
+	OpcodeAtomicI64Rmw8XchgU:     OpcodeAtomicI64Rmw8XchgUName,
+	OpcodeAtomicI64Rmw16XchgU:    OpcodeAtomicI64Rmw16XchgUName,
+	OpcodeAtomicI64Rmw32XchgU:    OpcodeAtomicI64Rmw32XchgUName,
+	OpcodeAtomicI32RmwCmpxchg:    OpcodeAtomicI32RmwCmpxchgName,
+	OpcodeAtomicI64RmwCmpxchg:    OpcodeAtomicI64RmwCmpxchgName,
+	OpcodeAtomicI32Rmw8CmpxchgU:  OpcodeAtomicI32Rmw8CmpxchgUName,
+	OpcodeAtomicI32Rmw16CmpxchgU: OpcodeAtomicI32Rmw16CmpxchgUName,
+	OpcodeAtomicI64Rmw8CmpxchgU:  OpcodeAtomicI64Rmw8CmpxchgUName,
+	OpcodeAtomicI64Rmw16CmpxchgU: OpcodeAtomicI64Rmw16CmpxchgUName,
+	OpcodeAtomicI64Rmw32CmpxchgU: OpcodeAtomicI64Rmw32CmpxchgUName,
+}
+
+// AtomicInstructionName returns the instruction name corresponding to the atomic Opcode.
+func AtomicInstructionName(oc OpcodeAtomic) (ret string) {
+	return atomicInstructionName[oc]
+}
+
+// AtomicMemoryAccess returns the type of the value which the atomic instruction loads or stores, and the size of the
+// access in bytes, which is also the alignment the effective address requires. The size is zero for
+// OpcodeAtomicFence, which doesn't access memory.
+func AtomicMemoryAccess(oc OpcodeAtomic) (ValueType, uint32) {
+	switch oc {
+	case OpcodeAtomicMemoryNotify, OpcodeAtomicMemoryWait32:
+		return ValueTypeI32, 4
+	case OpcodeAtomicMemoryWait64:
+		return ValueTypeI64, 8
+	case OpcodeAtomicFence:
+		return 0, 0
+	}
+	// The loads, stores and each read-modify-write operation are groups of seven opcodes of the same order.
+	switch (oc - OpcodeAtomicI32Load) % 7 {
+	case 0:
+		return ValueTypeI32, 4
+	case 1:
+		return ValueTypeI64, 8
+	case 2:
+		return ValueTypeI32, 1
+	case 3:
+		return ValueTypeI32, 2
+	case 4:
+		return ValueTypeI64, 1
+	case 5:
+		return ValueTypeI64, 2
+	default:
+		return ValueTypeI64, 4
+	}
+}
@@ -2,13 +2,20 @@ package wasm
 
 import (
 	"encoding/binary"
+	"errors"
 	"fmt"
+	"io"
 	"math"
+	"os"
 	"reflect"
+	"sync"
+	"sync/atomic"
 	"unsafe"
 
 	"github.com/tetratelabs/wazero/api"
 	"github.com/tetratelabs/wazero/internal/internalapi"
+	"github.com/tetratelabs/wazero/internal/memio"
+	"github.com/tetratelabs/wazero/internal/platform"
 )
 
 const (
@@ -19,6 +26,9 @@ const (
 	// MemoryLimitPages is maximum number of pages defined (2^16).
 	// See https://www.w3.org/TR/2019/REC-wasm-core-1-20191205/#grow-mem
 	MemoryLimitPages = uint32(65536)
+	// MemoryLimitPages64 is maximum number of pages defined (2^48) for a 64-bit memory.
+	// See https://github.com/WebAssembly/memory64/blob/main/proposals/memory64/Overview.md#validation
+	MemoryLimitPages64 = uint64(1) << 48
 	// MemoryPageSizeInBits satisfies the relation: "1 << MemoryPageSizeInBits == MemoryPageSize".
 	MemoryPageSizeInBits = 16
 )
@@ -36,20 +46,89 @@ type MemoryInstance struct {
 
 	Buffer        []byte
 	Min, Cap, Max uint32
+	// Is64 is true if the memory is indexed with i64 addresses. See Memory.Is64
+	Is64 bool
+	// Shared is true if the memory is declared shared. See Memory.IsShared
+	Shared bool
+	// GrowHook is called before the memory grows when non-nil, and fails Grow by returning false.
+	GrowHook func(previousPages, newPages uint32) bool
 	// definition is known at compile time.
 	definition api.MemoryDefinition
+	// locked is the part of Buffer locked in RAM by prefault, if any.
+	locked []byte
+	// pinMux guards pins and the growth of Buffer by Grow, so that Pinned never returns a buffer being moved.
+	pinMux sync.Mutex
+	// pins is the count of Pinned calls not yet released. Grow can't reallocate Buffer while this is non-zero.
+	pins int
+	// borrowed is true when Buffer is provided by the embedder, so Grow can't reallocate it beyond its capacity, and
+	// release drops it when the module is closed.
+	borrowed bool
+	// shadow is a bitmap of the bytes of Buffer which are initialized when Sanitized, or nil. See MarkInitialized.
+	shadow []uint64
 }
 
 // NewMemoryInstance creates a new instance based on the parameters in the SectionIDMemory.
+//
+// A shared memory is allocated with the capacity of its max, so that growing it never moves the buffer, as other
+// threads may access it concurrently.
 func NewMemoryInstance(memSec *Memory) *MemoryInstance {
+	capPages := memoryCapacityPages(memSec)
 	min := MemoryPagesToBytesNum(memSec.Min)
-	capacity := MemoryPagesToBytesNum(memSec.Cap)
+	capacity := MemoryPagesToBytesNum(capPages)
 	return &MemoryInstance{
 		Buffer: make([]byte, min, capacity),
 		Min:    memSec.Min,
-		Cap:    memSec.Cap,
+		Cap:    capPages,
 		Max:    memSec.Max,
+		Is64:   memSec.Is64,
+		Shared: memSec.IsShared,
+	}
+}
+
+// memoryCapacityPages returns the count of pages NewMemoryInstance allocates for memSec.
+func memoryCapacityPages(memSec *Memory) uint32 {
+	if memSec.IsShared {
+		return memSec.Max
+	}
+	return memSec.Cap
+}
+
+// checkMemoryCapacity errs if the capacity of memSec can't be allocated on this host, such as a shared memory with a
+// max of 4 GiB on a 32-bit host.
+func checkMemoryCapacity(memSec *Memory) error {
+	capPages := memoryCapacityPages(memSec)
+	if MemoryPagesToBytesNum(capPages) > math.MaxInt {
+		return &api.LimitExceededError{Resource: "memory capacity pages", Requested: uint64(capPages), Limit: uint64(math.MaxInt) >> MemoryPageSizeInBits}
 	}
+	return nil
+}
+
+// clone returns a memory with a copy of the contents of m, including its capacity, and without GrowHook.
+func (m *MemoryInstance) clone() *MemoryInstance {
+	buffer := make([]byte, len(m.Buffer), cap(m.Buffer))
+	copy(buffer, m.Buffer)
+	var shadow []uint64
+	if m.shadow != nil {
+		shadow = append([]uint64{}, m.shadow...)
+	}
+	return &MemoryInstance{
+		Buffer:     buffer,
+		Min:        m.Min,
+		Cap:        m.Cap,
+		Max:        m.Max,
+		Is64:       m.Is64,
+		Shared:     m.Shared,
+		definition: m.definition,
+		shadow:     shadow,
+	}
+}
+
+// addressType returns the type of the addresses of this memory. See Memory.Is64
+func (m *MemoryInstance) addressType() ValueType {
+	if m.Is64 {
+		return ValueTypeI64
+	}
+	return ValueTypeI32
 }
 
 // Definition implements the same method as documented on api.Memory.
@@ -111,6 +190,8 @@ func (m *MemoryInstance) Read(offset, byteCount uint32) ([]byte, bool) {
 	if !m.hasSize(offset, uint64(byteCount)) {
 		return nil, false
 	}
+	// The view can be written, so its bytes can't be known to be uninitialized anymore.
+	m.MarkInitialized(uint64(offset), uint64(byteCount))
 	return m.Buffer[offset : offset+byteCount : offset+byteCount], true
 }
 
@@ -120,6 +201,7 @@ func (m *MemoryInstance) WriteByte(offset uint32, v byte) bool {
 		return false
 	}
 	m.Buffer[offset] = v
+	m.MarkInitialized(uint64(offset), 1)
 	return true
 }
 
@@ -129,6 +211,7 @@ func (m *MemoryInstance) WriteUint16Le(offset uint32, v uint16) bool {
 		return false
 	}
 	binary.LittleEndian.PutUint16(m.Buffer[offset:], v)
+	m.MarkInitialized(uint64(offset), 2)
 	return true
 }
 
@@ -158,6 +241,7 @@ func (m *MemoryInstance) Write(offset uint32, val []byte) bool {
 		return false
 	}
 	copy(m.Buffer[offset:], val)
+	m.MarkInitialized(uint64(offset), uint64(len(val)))
 	return true
 }
 
@@ -167,9 +251,35 @@ func (m *MemoryInstance) WriteString(offset uint32, val string) bool {
 		return false
 	}
 	copy(m.Buffer[offset:], val)
+	m.MarkInitialized(uint64(offset), uint64(len(val)))
 	return true
 }
 
+// Reader implements the same method as documented on api.Memory.
+func (m *MemoryInstance) Reader(offset, byteCount uint32) io.Reader {
+	return memio.NewReader(m, offset, byteCount)
+}
+
+// ReaderAt implements the same method as documented on api.Memory.
+func (m *MemoryInstance) ReaderAt(offset, byteCount uint32) io.ReaderAt {
+	return memio.NewReaderAt(m, offset, byteCount)
+}
+
+// Writer implements the same method as documented on api.Memory.
+func (m *MemoryInstance) Writer(offset, byteCount uint32) io.Writer {
+	return memio.NewWriter(m, offset, byteCount)
+}
+
+// WriterAt implements the same method as documented on api.Memory.
+func (m *MemoryInstance) WriterAt(offset, byteCount uint32) io.WriterAt {
+	return memio.NewWriterAt(m, offset, byteCount)
+}
+
+// ReadFrom implements the same method as documented on api.Memory.
+func (m *MemoryInstance) ReadFrom(offset uint32, r io.Reader, limit uint32) (uint32, error) {
+	return memio.ReadFrom(m, offset, r, limit)
+}
+
 // MemoryPagesToBytesNum converts the given pages into the number of bytes contained in these pages.
 func MemoryPagesToBytesNum(pages uint32) (bytesNum uint64) {
 	return uint64(pages) << MemoryPageSizeInBits
@@ -182,11 +292,22 @@ func (m *MemoryInstance) Grow(delta uint32) (result uint32, ok bool) {
 		return currentPages, true
 	}
 
-	// If exceeds the max of memory size, we push -1 according to the spec.
+	// If exceeds the max of memory size, we push -1 according to the spec. The sum is checked in 64-bit, as the delta
+	// can be up to math.MaxUint32.
+	if uint64(currentPages)+uint64(delta) > uint64(m.Max) {
+		return 0, false
+	}
 	newPages := currentPages + delta
-	if newPages > m.Max {
+	if m.GrowHook != nil && !m.GrowHook(currentPages, newPages) {
 		return 0, false
-	} else if newPages > m.Cap { // grow the memory.
+	}
+	m.pinMux.Lock()
+	defer m.pinMux.Unlock()
+	if newPages > m.Cap { // grow the memory.
+		if m.pins > 0 || m.borrowed {
+			return 0, false // Reallocating would move the buffer passed to Pinned, or replace the borrowed one.
+		}
+		m.unlock() // The buffer is reallocated, so the pages locked by prefault are no longer used.
 		m.Buffer = append(m.Buffer, make([]byte, MemoryPagesToBytesNum(delta))...)
 		m.Cap = newPages
 		return currentPages, true
@@ -197,6 +318,96 @@ func (m *MemoryInstance) Grow(delta uint32) (result uint32, ok bool) {
 	}
 }
 
+// Pinned implements the same method as documented on api.Memory.
+func (m *MemoryInstance) Pinned() ([]byte, func(), error) {
+	m.pinMux.Lock()
+	defer m.pinMux.Unlock()
+	buf := m.Buffer
+	if len(buf) == 0 {
+		return nil, nil, errors.New("cannot pin an empty memory")
+	}
+	m.pins++
+	m.MarkInitialized(0, uint64(len(buf))) // Likewise Read.
+	var released atomic.Bool
+	release := func() {
+		if released.CompareAndSwap(false, true) {
+			m.pinMux.Lock()
+			m.pins--
+			m.pinMux.Unlock()
+		}
+	}
+	return buf, release, nil
+}
+
+// prefault writes to each page of the buffer, so that accessing it later doesn't fault, and locks the buffer in RAM
+// when possible. Otherwise, the buffer is only pre-faulted.
+func (m *MemoryInstance) prefault() {
+	buf := m.Buffer
+	for i, pageSize := 0, os.Getpagesize(); i < len(buf); i += pageSize {
+		b := buf[i] // Write the same value back, as data segments may have been applied.
+		buf[i] = b
+	}
+	if len(buf) > 0 && m.locked == nil && platform.Mlock(buf) == nil {
+		m.locked = buf
+	}
+}
+
+// unlock unlocks the buffer locked by prefault, if any.
+func (m *MemoryInstance) unlock() {
+	if m.locked != nil {
+		_ = platform.Munlock(m.locked)
+		m.locked = nil
+	}
+}
+
+// zero zeroes the whole buffer of the memory, including its capacity beyond the current size.
+func (m *MemoryInstance) zero() {
+	buf := m.Buffer[:cap(m.Buffer)]
+	for i := range buf {
+		buf[i] = 0
+	}
+}
+
+// release drops the reference to a borrowed Buffer, so that the embedder can reuse it.
+func (m *MemoryInstance) release() {
+	if m.borrowed {
+		m.Buffer = nil
+		m.Cap = 0
+	}
+}
+
+// Restore replaces the contents of the memory with the snapshot, growing it to the size of the snapshot. A smaller
+// snapshot leaves the memory at its current size, as memory never shrinks, and zeroes the bytes beyond the snapshot.
+func (m *MemoryInstance) Restore(snapshot []byte) error {
+	if uint32(len(snapshot))%MemoryPageSize != 0 {
+		return fmt.Errorf("snapshot size %d isn't a multiple of the page size", len(snapshot))
+	}
+	pages := memoryBytesNumToPages(uint64(len(snapshot)))
+	if pages < m.Min {
+		return fmt.Errorf("snapshot of %d pages is less than the minimum of %d pages", pages, m.Min)
+	} else if pages > m.Max {
+		return fmt.Errorf("snapshot of %d pages exceeds the maximum of %d pages", pages, m.Max)
+	}
+
+	if current := m.PageSize(); pages > current {
+		if _, ok := m.Grow(pages - current); !ok {
+			return fmt.Errorf("cannot grow memory to %d pages", pages)
+		}
+	}
+	copy(m.Buffer, snapshot)
+	tail := m.Buffer[len(snapshot):]
+	for i := range tail {
+		tail[i] = 0
+	}
+	if m.shadow != nil {
+		// The snapshot replaces all contents, which are considered initialized as it doesn't record which were. The
+		// zeroed bytes beyond it are uninitialized, like those of grown pages.
+		m.shadow = m.shadow[:0]
+		m.MarkInitialized(0, uint64(len(snapshot)))
+	}
+	return nil
+}
+
 // PageSize returns the current memory buffer size in pages.
 func (m *MemoryInstance) PageSize() (result uint32) {
 	return memoryBytesNumToPages(uint64(len(m.Buffer)))
@@ -265,6 +476,7 @@ func (m *MemoryInstance) writeUint32Le(offset uint32, v uint32) bool {
 		return false
 	}
 	binary.LittleEndian.PutUint32(m.Buffer[offset:], v)
+	m.MarkInitialized(uint64(offset), 4)
 	return true
 }
 
@@ -275,5 +487,6 @@ func (m *MemoryInstance) writeUint64Le(offset uint32, v uint64) bool {
 		return false
 	}
 	binary.LittleEndian.PutUint64(m.Buffer[offset:], v)
+	m.MarkInitialized(uint64(offset), 8)
 	return true
 }
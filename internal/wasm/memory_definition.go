@@ -38,10 +38,7 @@ func (m *Module) BuildMemoryDefinitions() {
 		moduleName = m.NameSection.ModuleName
 	}
 
-	memoryCount := m.ImportMemoryCount
-	if m.MemorySection != nil {
-		memoryCount++
-	}
+	memoryCount := m.ImportMemoryCount + m.SectionElementCount(SectionIDMemory)
 
 	if memoryCount == 0 {
 		return
@@ -63,11 +60,20 @@ func (m *Module) BuildMemoryDefinitions() {
 		importMemIdx++
 	}
 
+	memIdx := importMemIdx
 	if m.MemorySection != nil {
 		m.MemoryDefinitionSection = append(m.MemoryDefinitionSection, MemoryDefinition{
-			index:  importMemIdx,
+			index:  memIdx,
 			memory: m.MemorySection,
 		})
+		memIdx++
+	}
+	for i := range m.AdditionalMemorySection {
+		m.MemoryDefinitionSection = append(m.MemoryDefinitionSection, MemoryDefinition{
+			index:  memIdx,
+			memory: &m.AdditionalMemorySection[i],
+		})
+		memIdx++
 	}
 
 	for i := range m.MemoryDefinitionSection {
@@ -0,0 +1,129 @@
+package wasm
+
+// enableSanitizer makes m track which of its bytes are initialized, starting with none. See IsInitialized.
+func (m *MemoryInstance) enableSanitizer() {
+	m.shadow = make([]uint64, shadowWords(uint64(len(m.Buffer))))
+}
+
+// Sanitized returns true if m tracks which of its bytes are initialized.
+//
+// See wazero.RuntimeConfig WithMemorySanitizer
+func (m *MemoryInstance) Sanitized() bool {
+	return m.shadow != nil
+}
+
+// MemorySanitized returns true if any memory of the module is Sanitized, so that engines supporting it check the
+// accesses of its functions.
+func (m *ModuleInstance) MemorySanitized() bool {
+	if m.MemoryInstance != nil && m.MemoryInstance.Sanitized() {
+		return true
+	}
+	for _, mem := range m.AdditionalMemories {
+		if mem.Sanitized() {
+			return true
+		}
+	}
+	return false
+}
+
+// shadowWords returns the length of the shadow bitmap for the given count of bytes.
+func shadowWords(byteCount uint64) uint64 {
+	return (byteCount + 63) / 64
+}
+
+// MarkInitialized records the byteCount bytes at offset as initialized, when m is Sanitized. The range must be within
+// the buffer.
+func (m *MemoryInstance) MarkInitialized(offset, byteCount uint64) {
+	if m.shadow != nil { // Checked here so that this is inlined when not Sanitized.
+		m.markInitialized(offset, byteCount)
+	}
+}
+
+func (m *MemoryInstance) markInitialized(offset, byteCount uint64) {
+	m.growShadow(offset + byteCount)
+	for i, end := offset, offset+byteCount; i < end; {
+		if i%64 == 0 && end-i >= 64 {
+			m.shadow[i/64] = ^uint64(0)
+			i += 64
+		} else {
+			m.shadow[i/64] |= 1 << (i % 64)
+			i++
+		}
+	}
+}
+
+// IsInitialized returns true if the byteCount bytes at offset were all written, or m isn't Sanitized. The range must
+// be within the buffer.
+func (m *MemoryInstance) IsInitialized(offset, byteCount uint64) bool {
+	if m.shadow == nil {
+		return true
+	}
+	for i, end := offset, offset+byteCount; i < end; i++ {
+		// Words past the shadow are of pages grown since, which are uninitialized.
+		if w := i / 64; w >= uint64(len(m.shadow)) || m.shadow[w]&(1<<(i%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// CopyInitialized copies which of the byteCount bytes at srcOffset of src are initialized to those at dstOffset of m,
+// as memory.copy does for the bytes themselves, when m is Sanitized. The ranges must be within the buffers.
+func (m *MemoryInstance) CopyInitialized(dstOffset uint64, src *MemoryInstance, srcOffset, byteCount uint64) {
+	if m.shadow == nil || byteCount == 0 {
+		return
+	}
+	m.growShadow(dstOffset + byteCount)
+	copyBit := func(i uint64) {
+		if src.IsInitialized(srcOffset+i, 1) {
+			m.shadow[(dstOffset+i)/64] |= 1 << ((dstOffset + i) % 64)
+		} else {
+			m.shadow[(dstOffset+i)/64] &^= 1 << ((dstOffset + i) % 64)
+		}
+	}
+	// Copy backwards when the ranges overlap with the destination after the source, as copy does.
+	if m == src && dstOffset > srcOffset {
+		for i := byteCount; i > 0; i-- {
+			copyBit(i - 1)
+		}
+	} else {
+		for i := uint64(0); i < byteCount; i++ {
+			copyBit(i)
+		}
+	}
+}
+
+// growShadow ensures the shadow bitmap covers byteCount bytes, as the buffer may have grown since it was allocated.
+func (m *MemoryInstance) growShadow(byteCount uint64) {
+	if n := shadowWords(byteCount); n > uint64(len(m.shadow)) {
+		m.shadow = append(m.shadow, make([]uint64, n-uint64(len(m.shadow)))...)
+	}
+}
+
+// enableMemorySanitizer makes the memories defined by the module track which of their bytes are initialized.
+func (m *ModuleInstance) enableMemorySanitizer() {
+	if mem := m.MemoryInstance; mem != nil && m.Source.ImportMemoryCount == 0 {
+		mem.enableSanitizer()
+	}
+	for _, mem := range m.AdditionalMemories {
+		mem.enableSanitizer()
+	}
+}
+
+// markStaticDataInitialized marks the bytes of the memory below the "__heap_base" global, which LLVM-based toolchains
+// export, as initialized, as they hold the static data, including zero-initialized data absent from data segments, and
+// the stack.
+func (m *ModuleInstance) markStaticDataInitialized() {
+	if m.MemoryInstance == nil || !m.MemoryInstance.Sanitized() {
+		return
+	}
+	exp, ok := m.Exports["__heap_base"]
+	if !ok || exp.Type != ExternTypeGlobal {
+		return
+	}
+	heapBase := m.Globals[exp.Index].Val
+	if size := uint64(len(m.MemoryInstance.Buffer)); heapBase > size {
+		heapBase = size
+	}
+	m.MemoryInstance.MarkInitialized(0, heapBase)
+}
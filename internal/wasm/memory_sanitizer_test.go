@@ -0,0 +1,136 @@
+package wasm
+
+import (
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestMemoryInstance_Sanitized(t *testing.T) {
+	newMemory := func() *MemoryInstance {
+		m := NewMemoryInstance(&Memory{Min: 1, Cap: 2, Max: 2})
+		m.enableSanitizer()
+		return m
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		m := NewMemoryInstance(&Memory{Min: 1, Cap: 1, Max: 1})
+		require.False(t, m.Sanitized())
+		require.True(t, m.IsInitialized(0, 8))
+		m.MarkInitialized(0, 8)
+		require.Nil(t, m.shadow)
+	})
+
+	t.Run("mark", func(t *testing.T) {
+		m := newMemory()
+		require.True(t, m.Sanitized())
+		require.False(t, m.IsInitialized(0, 1))
+		require.True(t, m.IsInitialized(0, 0))
+
+		m.MarkInitialized(60, 10) // Across words.
+		require.True(t, m.IsInitialized(60, 10))
+		require.False(t, m.IsInitialized(59, 2))
+		require.False(t, m.IsInitialized(69, 2))
+
+		m.MarkInitialized(64, 256) // Whole words.
+		require.True(t, m.IsInitialized(60, 260))
+		require.False(t, m.IsInitialized(60, 261))
+	})
+
+	t.Run("write", func(t *testing.T) {
+		m := newMemory()
+		require.True(t, m.WriteByte(0, 1))
+		require.True(t, m.WriteUint16Le(2, 1))
+		require.True(t, m.WriteUint32Le(4, 1))
+		require.True(t, m.WriteUint64Le(8, 1))
+		require.True(t, m.IsInitialized(2, 14))
+		require.False(t, m.IsInitialized(0, 2))
+
+		require.True(t, m.Write(16, []byte{1, 2}))
+		require.True(t, m.WriteString(18, "ab"))
+		require.True(t, m.IsInitialized(16, 4))
+
+		// An out of bounds write doesn't mark.
+		require.False(t, m.WriteUint32Le(MemoryPageSize-2, 1))
+		require.False(t, m.IsInitialized(uint64(MemoryPageSize-2), 2))
+
+		// The view returned by Read can be written.
+		_, ok := m.Read(100, 4)
+		require.True(t, ok)
+		require.True(t, m.IsInitialized(100, 4))
+	})
+
+	t.Run("grow", func(t *testing.T) {
+		m := newMemory()
+		_, ok := m.Grow(1)
+		require.True(t, ok)
+		require.False(t, m.IsInitialized(uint64(MemoryPageSize), 1))
+		m.MarkInitialized(uint64(MemoryPageSize), 1)
+		require.True(t, m.IsInitialized(uint64(MemoryPageSize), 1))
+	})
+
+	t.Run("copy", func(t *testing.T) {
+		m := newMemory()
+		m.MarkInitialized(0, 2)
+		m.MarkInitialized(10, 2)
+
+		// Uninitialized bytes are copied as such.
+		m.CopyInitialized(9, m, 0, 4)
+		require.True(t, m.IsInitialized(9, 2))
+		require.False(t, m.IsInitialized(11, 1))
+		require.False(t, m.IsInitialized(12, 1))
+
+		// Overlapping, with the destination after the source.
+		m = newMemory()
+		m.MarkInitialized(0, 1)
+		m.CopyInitialized(1, m, 0, 3)
+		require.True(t, m.IsInitialized(0, 2))
+		require.False(t, m.IsInitialized(2, 1))
+
+		// From a memory which isn't sanitized.
+		m.CopyInitialized(100, NewMemoryInstance(&Memory{Min: 1, Cap: 1, Max: 1}), 0, 8)
+		require.True(t, m.IsInitialized(100, 8))
+	})
+
+	t.Run("clone", func(t *testing.T) {
+		m := newMemory()
+		m.MarkInitialized(0, 4)
+		c := m.clone()
+		c.MarkInitialized(4, 4)
+		require.True(t, c.IsInitialized(0, 8))
+		require.False(t, m.IsInitialized(0, 8))
+	})
+
+	t.Run("restore", func(t *testing.T) {
+		m := newMemory()
+		require.NoError(t, m.Restore(make([]byte, MemoryPageSize)))
+		require.True(t, m.IsInitialized(0, uint64(MemoryPageSize)))
+
+		// The bytes beyond a smaller snapshot are zeroed, so they are uninitialized.
+		_, ok := m.Grow(1)
+		require.True(t, ok)
+		m.MarkInitialized(0, uint64(len(m.Buffer)))
+		require.NoError(t, m.Restore(make([]byte, MemoryPageSize)))
+		require.True(t, m.IsInitialized(0, uint64(MemoryPageSize)))
+		require.False(t, m.IsInitialized(uint64(MemoryPageSize), 1))
+	})
+}
+
+func TestModuleInstance_markStaticDataInitialized(t *testing.T) {
+	mem := NewMemoryInstance(&Memory{Min: 1, Cap: 1, Max: 1})
+	mem.enableSanitizer()
+	m := &ModuleInstance{
+		MemoryInstance: mem,
+		Globals:        []*GlobalInstance{{Type: GlobalType{ValType: ValueTypeI32}, Val: 1024}},
+		Exports:        map[string]*Export{"__heap_base": {Type: ExternTypeGlobal, Index: 0}},
+	}
+	m.markStaticDataInitialized()
+	require.True(t, m.MemorySanitized())
+	require.True(t, mem.IsInitialized(0, 1024))
+	require.False(t, mem.IsInitialized(1024, 1))
+
+	// The value is limited to the memory size.
+	m.Globals[0].Val = 1 << 20
+	m.markStaticDataInitialized()
+	require.True(t, mem.IsInitialized(0, uint64(MemoryPageSize)))
+}
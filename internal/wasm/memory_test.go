@@ -1,13 +1,16 @@
 package wasm
 
 import (
+	"io"
 	"math"
 	"reflect"
 	"strings"
+	"sync"
 	"testing"
 	"unsafe"
 
 	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/internal/platform"
 	"github.com/tetratelabs/wazero/internal/testing/require"
 )
 
@@ -73,6 +76,11 @@ func TestMemoryInstance_Grow_Size(t *testing.T) {
 			require.False(t, ok)
 			require.Equal(t, uint32(9), m.PageSize())
 
+			// The new page size overflows uint32, which must not wrap around.
+			_, ok = m.Grow(math.MaxUint32)
+			require.False(t, ok)
+			require.Equal(t, uint32(9), m.PageSize())
+
 			// But growing one page is still permitted.
 			res, ok = m.Grow(1)
 			require.True(t, ok)
@@ -768,6 +776,30 @@ func TestMemoryInstance_WriteString(t *testing.T) {
 	require.False(t, ok)
 }
 
+func TestMemoryInstance_Reader_Writer(t *testing.T) {
+	mem := &MemoryInstance{Buffer: make([]byte, 8), Min: 1}
+
+	n, err := io.Copy(mem.Writer(2, 4), strings.NewReader("bear"))
+	require.NoError(t, err)
+	require.Equal(t, int64(4), n)
+	require.Equal(t, []byte{0, 0, 'b', 'e', 'a', 'r', 0, 0}, mem.Buffer)
+
+	_, err = mem.WriterAt(2, 4).WriteAt([]byte("ee"), 1)
+	require.NoError(t, err)
+
+	buf, err := io.ReadAll(mem.Reader(2, 4))
+	require.NoError(t, err)
+	require.Equal(t, "beer", string(buf))
+
+	buf = make([]byte, 2)
+	_, err = mem.ReaderAt(2, 4).ReadAt(buf, 2)
+	require.NoError(t, err)
+	require.Equal(t, "er", string(buf))
+
+	_, err = io.ReadAll(mem.Reader(6, 4))
+	require.Error(t, err)
+}
+
 func BenchmarkWriteString(b *testing.B) {
 	tests := []string{
 		"",
@@ -797,3 +829,155 @@ func BenchmarkWriteString(b *testing.B) {
 		})
 	}
 }
+
+func TestMemoryInstance_Grow_GrowHook(t *testing.T) {
+	m := NewMemoryInstance(&Memory{Min: 1, Cap: 1, Max: 10})
+	var calls [][2]uint32
+	m.GrowHook = func(previousPages, newPages uint32) bool {
+		calls = append(calls, [2]uint32{previousPages, newPages})
+		return newPages <= 3
+	}
+
+	res, ok := m.Grow(2)
+	require.True(t, ok)
+	require.Equal(t, uint32(1), res)
+
+	// A vetoed grow leaves the memory as is.
+	_, ok = m.Grow(1)
+	require.False(t, ok)
+	require.Equal(t, uint32(3), m.PageSize())
+
+	// The hook isn't called when not growing, or when over the max.
+	_, ok = m.Grow(0)
+	require.True(t, ok)
+	_, ok = m.Grow(10)
+	require.False(t, ok)
+
+	require.Equal(t, [][2]uint32{{1, 3}, {3, 4}}, calls)
+}
+
+func TestMemoryInstance_Pinned(t *testing.T) {
+	m := NewMemoryInstance(&Memory{Min: 1, Cap: 2, Max: 10})
+
+	buf, release, err := m.Pinned()
+	require.NoError(t, err)
+	require.Equal(t, &m.Buffer[0], &buf[0])
+	_, release2, err := m.Pinned()
+	require.NoError(t, err)
+
+	// Growing within the capacity doesn't move the buffer.
+	_, ok := m.Grow(1)
+	require.True(t, ok)
+	require.Equal(t, &m.Buffer[0], &buf[0])
+
+	// Otherwise, it fails until all pins are released, even if one is released twice.
+	_, ok = m.Grow(1)
+	require.False(t, ok)
+	release()
+	release()
+	_, ok = m.Grow(1)
+	require.False(t, ok)
+	release2()
+	_, ok = m.Grow(1)
+	require.True(t, ok)
+	require.Equal(t, uint32(3), m.PageSize())
+
+	t.Run("empty", func(t *testing.T) {
+		_, _, err := NewMemoryInstance(&Memory{Max: 1}).Pinned()
+		require.EqualError(t, err, "cannot pin an empty memory")
+	})
+
+	t.Run("concurrent with Grow", func(t *testing.T) {
+		// Each Grow reallocates, unless it runs after Pinned, in which case it fails. Either way, the pinned buffer
+		// must be the current one.
+		for i := 0; i < 100; i++ {
+			m := NewMemoryInstance(&Memory{Min: 1, Cap: 1, Max: 2})
+			var wg sync.WaitGroup
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				m.Grow(1)
+			}()
+			buf, release, err := m.Pinned()
+			require.NoError(t, err)
+			wg.Wait()
+			require.Equal(t, &m.Buffer[0], &buf[0])
+			release()
+		}
+	})
+}
+
+func TestMemoryInstance_Grow_shared(t *testing.T) {
+	m := NewMemoryInstance(&Memory{Min: 1, Cap: 1, Max: 4, IsShared: true})
+	require.True(t, m.Shared)
+	require.Equal(t, uint32(4), m.Cap)
+	require.Equal(t, MemoryPagesToBytesNum(4), uint64(cap(m.Buffer)))
+
+	// Growing never moves the buffer, up to the max.
+	base := &m.Buffer[0]
+	for i := 0; i < 3; i++ {
+		_, ok := m.Grow(1)
+		require.True(t, ok)
+		require.Equal(t, base, &m.Buffer[0])
+	}
+	require.Equal(t, uint32(4), m.PageSize())
+	_, ok := m.Grow(1)
+	require.False(t, ok)
+}
+
+func TestMemoryInstance_zero(t *testing.T) {
+	buf := []byte{1, 2, 3, 4}
+	// The capacity beyond the size is also zeroed.
+	m := &MemoryInstance{Buffer: buf[:2]}
+	m.zero()
+	require.Equal(t, make([]byte, 4), buf)
+}
+
+func TestMemoryInstance_prefault(t *testing.T) {
+	mem := NewMemoryInstance(&Memory{Min: 1, Cap: 1, Max: 2})
+	mem.Buffer[0], mem.Buffer[MemoryPageSize-1] = 1, 2
+
+	mem.prefault()
+	// The contents are left as is, as data segments are applied before.
+	require.Equal(t, byte(1), mem.Buffer[0])
+	require.Equal(t, byte(2), mem.Buffer[MemoryPageSize-1])
+	if mem.locked == nil {
+		t.Skip("memory wasn't locked, e.g. due to RLIMIT_MEMLOCK")
+	}
+	require.True(t, platform.MlockSupported)
+
+	// Growing beyond the capacity reallocates the buffer, so it is unlocked.
+	_, ok := mem.Grow(1)
+	require.True(t, ok)
+	require.Nil(t, mem.locked)
+}
+
+func TestMemoryInstance_Restore(t *testing.T) {
+	mem := NewMemoryInstance(&Memory{Min: 1, Cap: 3, Max: 3})
+	mem.Buffer[0] = 1
+	snapshot := append([]byte{}, mem.Buffer...)
+
+	// Restoring a smaller snapshot after growing keeps the size, zeroing the pages beyond the snapshot.
+	_, ok := mem.Grow(1)
+	require.True(t, ok)
+	base := &mem.Buffer[0]
+	mem.Buffer[0], mem.Buffer[MemoryPageSize] = 2, 2
+	require.NoError(t, mem.Restore(snapshot))
+	require.Equal(t, uint32(2), mem.PageSize())
+	require.Equal(t, base, &mem.Buffer[0])
+	require.Equal(t, snapshot, mem.Buffer[:MemoryPageSize])
+	require.Equal(t, make([]byte, MemoryPageSize), mem.Buffer[MemoryPageSize:])
+
+	// Restoring a larger snapshot grows the memory.
+	large := make([]byte, 3*MemoryPageSize)
+	large[len(large)-1] = 3
+	require.NoError(t, mem.Restore(large))
+	require.Equal(t, large, mem.Buffer)
+
+	t.Run("errors", func(t *testing.T) {
+		require.EqualError(t, mem.Restore(make([]byte, 1)), "snapshot size 1 isn't a multiple of the page size")
+		require.EqualError(t, mem.Restore(nil), "snapshot of 0 pages is less than the minimum of 1 pages")
+		require.EqualError(t, mem.Restore(make([]byte, 4*MemoryPageSize)), "snapshot of 4 pages exceeds the maximum of 3 pages")
+		require.Equal(t, large, mem.Buffer)
+	})
+}
@@ -51,6 +51,9 @@ type Module struct {
 	ImportGlobalCount,
 	ImportMemoryCount,
 	ImportTableCount Index
+	// ImportTagCount is the cached count of imports of ExternTypeTag, which requires
+	// experimental.CoreFeaturesExceptionHandling.
+	ImportTagCount Index
 	// ImportPerModule maps a module name to the list of Import to be imported from the module.
 	// This is used to do fast import resolution during instantiation.
 	ImportPerModule map[string][]*Import
@@ -90,13 +93,31 @@ type Module struct {
 	// this module at TableSection[0].
 	//
 	// Note: Version 1.0 (20191205) of the WebAssembly spec allows at most one memory definition per module, so the
-	// length of the MemorySection can be zero or one, and can only be one if there is no imported memory.
+	// length of the MemorySection can be zero or one, and can only be one if there is no imported memory. Any other
+	// memory is in AdditionalMemorySection.
 	//
 	// Note: In the Binary Format, this is SectionIDMemory.
 	//
 	// See https://www.w3.org/TR/2019/REC-wasm-core-1-20191205/#memory-section%E2%91%A0
 	MemorySection *Memory
 
+	// AdditionalMemorySection contains the memories defined in this module which follow the memory at index zero
+	// in the memory index space: when a memory is imported, this contains all memories defined in this module and
+	// MemorySection is nil. This can only be non-empty when experimental.CoreFeaturesMultiMemory is enabled.
+	//
+	// For example, given no imported memory, the memory index 2 is defined at AdditionalMemorySection[1].
+	AdditionalMemorySection []Memory
+
+	// TagSection contains the index in TypeSection of each tag defined in this module. A tag identifies the exceptions
+	// thrown by OpcodeThrow and caught by OpcodeCatch, and the params of its type are the values each exception carries.
+	//
+	// Tag indexes are offset by any imported tags, like function indexes.
+	//
+	// Note: In the Binary Format, this is SectionIDTag, which requires experimental.CoreFeaturesExceptionHandling.
+	//
+	// See https://github.com/WebAssembly/exception-handling/blob/main/proposals/exception-handling/legacy/Exceptions.md
+	TagSection []Index
+
 	// GlobalSection contains each global defined in this module.
 	//
 	// Global indexes are offset by any imported globals because the global index begins with imports, followed by
@@ -172,6 +193,10 @@ type Module struct {
 	// IsHostModule true if this is the host module, false otherwise.
 	IsHostModule bool
 
+	// UsesExceptionHandling is set by Validate when the module imports or defines a tag, or any function uses an
+	// instruction of experimental.CoreFeaturesExceptionHandling. Engines which can't run these reject the module.
+	UsesExceptionHandling bool
+
 	// functionDefinitionSectionInitOnce guards FunctionDefinitionSection so that it is initialized exactly once.
 	functionDefinitionSectionInitOnce sync.Once
 
@@ -185,6 +210,20 @@ type Module struct {
 	// as described in https://yurydelendik.github.io/webassembly-dwarf/, though it is not specified in the Wasm
 	// specification: https://github.com/WebAssembly/debugging/issues/1
 	DWARFLines *wasmdebug.DWARFLines
+
+	// SectionRanges are the byte ranges of each section in the binary format, in the order they were decoded. This is
+	// nil when the module wasn't decoded from the binary format.
+	SectionRanges []SectionRange
+}
+
+// SectionRange is the range of bytes of a section in the binary format.
+type SectionRange struct {
+	// ID is the SectionID of the section.
+	ID SectionID
+	// Start is the offset of the section ID.
+	Start int
+	// End is the offset following the last byte of the section contents.
+	End int
 }
 
 // ModuleID represents sha256 hash value uniquely assigned to Module.
@@ -198,9 +237,58 @@ const (
 	MaximumTableIndex    = uint32(1 << 27)
 )
 
+// The limits below are those of the WebAssembly JavaScript API, which all web
+// engines enforce. Modules exceeding these are not portable anyway, and
+// checking them early bounds the resources spent decoding adversarial input.
+//
+// See https://webassembly.github.io/spec/js-api/#limits
+const (
+	// MaximumFunctions is the maximum count of functions, including imported
+	// ones, in a module.
+	MaximumFunctions = uint32(1_000_000)
+	// MaximumTypes is the maximum count of types in a module.
+	MaximumTypes = uint32(1_000_000)
+	// MaximumImports is the maximum count of imports in a module.
+	MaximumImports = uint32(100_000)
+	// MaximumExports is the maximum count of exports in a module.
+	MaximumExports = uint32(100_000)
+	// MaximumLocals is the maximum count of locals in a function, including
+	// its parameters.
+	MaximumLocals = uint32(50_000)
+)
+
+// DecodeLimits are the maximum counts of declarations the binary decoder
+// accepts. They are checked as counts are read, before any entries are
+// allocated.
+type DecodeLimits struct {
+	// MaxFunctions is the maximum count of functions, including imported
+	// ones. It is checked against the entries of the function and code
+	// sections, after those of the import section.
+	MaxFunctions uint32
+	// MaxTypes is the maximum count of entries in the type section.
+	MaxTypes uint32
+	// MaxImports is the maximum count of entries in the import section.
+	MaxImports uint32
+	// MaxExports is the maximum count of entries in the export section.
+	MaxExports uint32
+}
+
+// DefaultDecodeLimits are the limits of the WebAssembly JavaScript API.
+var DefaultDecodeLimits = DecodeLimits{
+	MaxFunctions: MaximumFunctions,
+	MaxTypes:     MaximumTypes,
+	MaxImports:   MaximumImports,
+	MaxExports:   MaximumExports,
+}
+
+// MaximumBlockNestingDepth is the default limit of how deeply block, loop and
+// if instructions can be nested in a function. This bounds the resources spent
+// on pathological modules during validation and compilation.
+const MaximumBlockNestingDepth = uint32(1 << 16)
+
 // AssignModuleID calculates a sha256 checksum on `wasm` and other args, and set Module.ID to the result.
 // See the doc on Module.ID on what it's used for.
-func (m *Module) AssignModuleID(wasm []byte, listeners []experimental.FunctionListener, withEnsureTermination bool) {
+func (m *Module) AssignModuleID(wasm []byte, listeners []experimental.FunctionListener, withEnsureTermination, withCanonicalNaN bool) {
 	h := sha256.New()
 	h.Write(wasm)
 	// Use the pre-allocated space backed by m.ID below.
@@ -213,7 +301,9 @@ func (m *Module) AssignModuleID(wasm []byte, listeners []experimental.FunctionLi
 	}
 	// Write the flag of ensureTermination to the checksum.
 	m.ID[0] = boolToByte(withEnsureTermination)
-	h.Write(m.ID[:1])
+	// Write the flag of canonicalNaN to the checksum.
+	m.ID[1] = boolToByte(withCanonicalNaN)
+	h.Write(m.ID[:2])
 	// Get checksum by passing the slice underlying m.ID.
 	h.Sum(m.ID[:0])
 }
@@ -257,7 +347,25 @@ func (m *Module) typeOfFunction(funcIdx Index) *FunctionType {
 	return &m.TypeSection[typeIdx]
 }
 
-func (m *Module) Validate(enabledFeatures api.CoreFeatures) error {
+// Validate validates the module against enabledFeatures, rejecting any
+// function with blocks nested deeper than maxBlockNestingDepth.
+func (m *Module) Validate(enabledFeatures api.CoreFeatures, maxBlockNestingDepth uint32) error {
+	return m.validate(enabledFeatures, maxBlockNestingDepth, nil)
+}
+
+// ValidateAll is like Validate, but continues past the errors of an import or a function, which don't affect
+// validating the rest of the module, returning all errors found in order. Other errors stop validation, as the module
+// can't be validated further.
+func (m *Module) ValidateAll(enabledFeatures api.CoreFeatures, maxBlockNestingDepth uint32) (errs []error) {
+	if err := m.validate(enabledFeatures, maxBlockNestingDepth, &errs); err != nil {
+		errs = append(errs, err)
+	}
+	return
+}
+
+// validate returns the first error, unless errs is non-nil, in which case the errors of an import or a function are
+// appended to it instead. Any other error is returned.
+func (m *Module) validate(enabledFeatures api.CoreFeatures, maxBlockNestingDepth uint32, errs *[]error) error {
 	for i := range m.TypeSection {
 		tp := &m.TypeSection[i]
 		tp.CacheNumInUint64()
@@ -267,16 +375,20 @@ func (m *Module) Validate(enabledFeatures api.CoreFeatures) error {
 		return err
 	}
 
+	if err := m.validateValueTypes(enabledFeatures); err != nil {
+		return err
+	}
+
 	functions, globals, memory, tables, err := m.AllDeclarations()
 	if err != nil {
 		return err
 	}
 
-	if err = m.validateImports(enabledFeatures); err != nil {
+	if err = m.validateImports(enabledFeatures, errs); err != nil {
 		return err
 	}
 
-	if err = m.validateGlobals(globals, uint32(len(functions)), MaximumGlobals); err != nil {
+	if err = m.validateGlobals(enabledFeatures, globals, uint32(len(functions)), MaximumGlobals); err != nil {
 		return err
 	}
 
@@ -284,12 +396,21 @@ func (m *Module) Validate(enabledFeatures api.CoreFeatures) error {
 		return err
 	}
 
+	if err = m.validateTags(enabledFeatures); err != nil {
+		return err
+	}
+
 	if err = m.validateExports(enabledFeatures, functions, globals, memory, tables); err != nil {
 		return err
 	}
 
+	// Validate the data count first, as memory.init and data.drop are validated against it.
+	if err = m.validateDataCountSection(); err != nil {
+		return err
+	}
+
 	if m.CodeSection != nil {
-		if err = m.validateFunctions(enabledFeatures, functions, globals, memory, tables, MaximumFunctionIndex); err != nil {
+		if err = m.validateFunctions(enabledFeatures, functions, globals, memory, tables, MaximumFunctions, maxBlockNestingDepth, errs); err != nil {
 			return err
 		}
 	} // No need to validate host functions as NewHostModule validates
@@ -297,10 +418,6 @@ func (m *Module) Validate(enabledFeatures api.CoreFeatures) error {
 	if err = m.validateTable(enabledFeatures, tables, MaximumTableIndex); err != nil {
 		return err
 	}
-
-	if err = m.validateDataCountSection(); err != nil {
-		return err
-	}
 	return nil
 }
 
@@ -320,26 +437,65 @@ func (m *Module) validateStartSection() error {
 	return nil
 }
 
-func (m *Module) validateGlobals(globals []GlobalType, numFuncts, maxGlobals uint32) error {
+func (m *Module) validateGlobals(enabledFeatures api.CoreFeatures, globals []GlobalType, numFuncts, maxGlobals uint32) error {
 	if uint32(len(globals)) > maxGlobals {
 		return fmt.Errorf("too many globals in a module")
 	}
 
 	// Global initialization constant expression can only reference the imported globals.
 	// See the note on https://www.w3.org/TR/2019/REC-wasm-core-1-20191205/#constant-expressions%E2%91%A0
-	importedGlobals := globals[:m.ImportGlobalCount]
+	extendedConst := enabledFeatures.IsEnabled(experimental.CoreFeaturesExtendedConst)
+	initGlobals := globals[:m.ImportGlobalCount]
 	for i := range m.GlobalSection {
+		if extendedConst {
+			// Globals are initialized in order, so the ones defined before this one can be referenced as well.
+			initGlobals = globals[:m.ImportGlobalCount+uint32(i)]
+		}
 		g := &m.GlobalSection[i]
-		if err := validateConstExpression(importedGlobals, numFuncts, &g.Init, g.Type.ValType); err != nil {
+		if err := validateConstExpression(initGlobals, numFuncts, &g.Init, g.Type.ValType); err != nil {
 			return err
 		}
+		if extendedConst {
+			if err := validateConstExpressionImmutable(initGlobals[m.ImportGlobalCount:], m.ImportGlobalCount, &g.Init); err != nil {
+				return err
+			}
+		}
 	}
 	return nil
 }
 
-func (m *Module) validateFunctions(enabledFeatures api.CoreFeatures, functions []Index, globals []GlobalType, memory *Memory, tables []Table, maximumFunctionIndex uint32) error {
-	if uint32(len(functions)) > maximumFunctionIndex {
-		return fmt.Errorf("too many functions (%d) in a module", len(functions))
+// validateConstExpressionImmutable ensures the valid expr doesn't read any of the mutable definedGlobals, whose
+// indexes start after importCount. The value of a mutable global could change before it is read.
+func validateConstExpressionImmutable(definedGlobals []GlobalType, importCount uint32, expr *ConstantExpression) error {
+	check := func(op Opcode, imm uint64) error {
+		if op == OpcodeGlobalGet && imm >= uint64(importCount) && definedGlobals[imm-uint64(importCount)].Mutable {
+			return fmt.Errorf("const expression reads mutable global[%d]", imm)
+		}
+		return nil
+	}
+	if IsExtendedConstOpcode(expr.Opcode) {
+		return expr.extendedConstInstructions(check)
+	} else if expr.Opcode == OpcodeGlobalGet {
+		id, _, _ := leb128.LoadUint32(expr.Data)
+		return check(expr.Opcode, uint64(id))
+	}
+	return nil
+}
+
+// collectError appends err to errs unless errs is nil, in which case err is returned.
+func collectError(errs *[]error, err error) error {
+	if errs == nil {
+		return err
+	}
+	*errs = append(*errs, err)
+	return nil
+}
+
+// validateFunctions validates the functions defined in the module. Unless errs is nil, the errors of each function
+// are appended to it, and only errors preventing the validation of the other functions are returned.
+func (m *Module) validateFunctions(enabledFeatures api.CoreFeatures, functions []Index, globals []GlobalType, memory *Memory, tables []Table, maximumFunctions, maxBlockNestingDepth uint32, errs *[]error) error {
+	if uint32(len(functions)) > maximumFunctions {
+		return fmt.Errorf("too many functions in a module: %d given with limit %d", len(functions), maximumFunctions)
 	}
 
 	functionCount := m.SectionElementCount(SectionIDFunction)
@@ -371,8 +527,18 @@ func (m *Module) validateFunctions(enabledFeatures api.CoreFeatures, functions [
 		if c.GoFunc != nil {
 			continue
 		}
-		if err = m.validateFunction(vs, enabledFeatures, Index(idx), functions, globals, memory, tables, declaredFuncIndexes, br); err != nil {
-			return fmt.Errorf("invalid %s: %w", m.funcDesc(SectionIDFunction, Index(idx)), err)
+		if locals := len(m.TypeSection[typeIndex].Params) + len(c.LocalTypes); uint32(locals) > MaximumLocals {
+			if err = collectError(errs, fmt.Errorf("invalid %s: too many locals: %d given with limit %d",
+				m.funcDesc(SectionIDFunction, Index(idx)), locals, MaximumLocals)); err != nil {
+				return err
+			}
+			continue
+		}
+		if err = m.validateFunctionWithMaxStackValues(vs, enabledFeatures, Index(idx), functions, globals, memory, tables,
+			maximumValuesOnStack, maxBlockNestingDepth, declaredFuncIndexes, br); err != nil {
+			if err = collectError(errs, fmt.Errorf("invalid %s: %w", m.funcDesc(SectionIDFunction, Index(idx)), err)); err != nil {
+				return err
+			}
 		}
 	}
 	return nil
@@ -461,13 +627,22 @@ func (m *Module) validateMemory(memory *Memory, globals []GlobalType, _ api.Core
 		return fmt.Errorf("unknown memory")
 	}
 
+	memoryCount := m.memoryCount(memory)
+	for i := range m.DataSection {
+		d := &m.DataSection[i]
+		if !d.IsPassive() && d.MemoryIndex >= memoryCount {
+			return fmt.Errorf("%s[%d]: unknown memory %d", SectionIDName(SectionIDData), i, d.MemoryIndex)
+		}
+	}
+
 	// Constant expression can only reference imported globals.
 	// https://github.com/WebAssembly/spec/blob/5900d839f38641989a9d8df2df4aee0513365d39/test/core/data.wast#L84-L91
 	importedGlobals := globals[:m.ImportGlobalCount]
 	for i := range m.DataSection {
 		d := &m.DataSection[i]
 		if !d.IsPassive() {
-			if err := validateConstExpression(importedGlobals, 0, &d.OffsetExpression, ValueTypeI32); err != nil {
+			addrType := m.memoryAt(memory, d.MemoryIndex).addressType()
+			if err := validateConstExpression(importedGlobals, 0, &d.OffsetExpression, addrType); err != nil {
 				return fmt.Errorf("calculate offset: %w", err)
 			}
 		}
@@ -475,11 +650,65 @@ func (m *Module) validateMemory(memory *Memory, globals []GlobalType, _ api.Core
 	return nil
 }
 
-func (m *Module) validateImports(enabledFeatures api.CoreFeatures) error {
+// validateValueTypes ensures the value types of functions, locals and globals don't belong to a disabled feature, even
+// if no instruction of that feature uses them.
+func (m *Module) validateValueTypes(enabledFeatures api.CoreFeatures) error {
+	for i := range m.TypeSection {
+		tp := &m.TypeSection[i]
+		for _, types := range [][]ValueType{tp.Params, tp.Results} {
+			for _, vt := range types {
+				if err := requireValueTypeEnabled(enabledFeatures, vt); err != nil {
+					return fmt.Errorf("%s[%d]: %w", SectionIDName(SectionIDType), i, err)
+				}
+			}
+		}
+	}
+	for i := range m.ImportSection {
+		if imp := &m.ImportSection[i]; imp.Type == ExternTypeGlobal {
+			if err := requireValueTypeEnabled(enabledFeatures, imp.DescGlobal.ValType); err != nil {
+				return fmt.Errorf("%s[%d]: %w", SectionIDName(SectionIDImport), i, err)
+			}
+		}
+	}
+	for i := range m.GlobalSection {
+		if err := requireValueTypeEnabled(enabledFeatures, m.GlobalSection[i].Type.ValType); err != nil {
+			return fmt.Errorf("%s[%d]: %w", SectionIDName(SectionIDGlobal), i, err)
+		}
+	}
+	for i := range m.CodeSection {
+		for _, vt := range m.CodeSection[i].LocalTypes {
+			if err := requireValueTypeEnabled(enabledFeatures, vt); err != nil {
+				return fmt.Errorf("invalid function[%d]: local %w", i, err)
+			}
+		}
+	}
+	return nil
+}
+
+// requireValueTypeEnabled returns an error if the value type belongs to a disabled feature.
+func requireValueTypeEnabled(enabledFeatures api.CoreFeatures, vt ValueType) (err error) {
+	switch vt {
+	case ValueTypeV128:
+		err = enabledFeatures.RequireEnabled(api.CoreFeatureSIMD)
+	case ValueTypeFuncref, ValueTypeExternref:
+		err = enabledFeatures.RequireEnabled(api.CoreFeatureReferenceTypes)
+	}
+	if err != nil {
+		return fmt.Errorf("%s invalid as %w", ValueTypeName(vt), err)
+	}
+	return nil
+}
+
+// validateImports validates the imports of the module. Unless errs is nil, the errors of each import are appended to
+// it, except a type index out of range, which is returned as the functions can't be validated.
+func (m *Module) validateImports(enabledFeatures api.CoreFeatures, errs *[]error) error {
 	for i := range m.ImportSection {
 		imp := &m.ImportSection[i]
 		if imp.Module == "" {
-			return fmt.Errorf("import[%d] has an empty module name", i)
+			if err := collectError(errs, fmt.Errorf("import[%d] has an empty module name", i)); err != nil {
+				return err
+			}
+			continue
 		}
 		switch imp.Type {
 		case ExternTypeFunc:
@@ -491,7 +720,9 @@ func (m *Module) validateImports(enabledFeatures api.CoreFeatures) error {
 				continue
 			}
 			if err := enabledFeatures.RequireEnabled(api.CoreFeatureMutableGlobal); err != nil {
-				return fmt.Errorf("invalid import[%q.%q] global: %w", imp.Module, imp.Name, err)
+				if err = collectError(errs, fmt.Errorf("invalid import[%q.%q] global: %w", imp.Module, imp.Name, err)); err != nil {
+					return err
+				}
 			}
 		}
 	}
@@ -518,18 +749,75 @@ func (m *Module) validateExports(enabledFeatures api.CoreFeatures, functions []I
 				return fmt.Errorf("invalid export[%q] global[%d]: %w", exp.Name, index, err)
 			}
 		case ExternTypeMemory:
-			if index > 0 || memory == nil {
+			if index >= m.memoryCount(memory) {
 				return fmt.Errorf("memory for export[%q] out of range", exp.Name)
 			}
 		case ExternTypeTable:
 			if index >= uint32(len(tables)) {
 				return fmt.Errorf("table for export[%q] out of range", exp.Name)
 			}
+		case ExternTypeTag:
+			if index >= m.ImportTagCount+uint32(len(m.TagSection)) {
+				return fmt.Errorf("unknown tag for export[%q]", exp.Name)
+			}
 		}
 	}
 	return nil
 }
 
+// validateTags ensures the types of imported and defined tags exist and have no results, as the values of an
+// exception are the params of its tag type.
+func (m *Module) validateTags(enabledFeatures api.CoreFeatures) error {
+	if m.ImportTagCount == 0 && len(m.TagSection) == 0 {
+		return nil
+	}
+	if err := enabledFeatures.RequireEnabled(experimental.CoreFeaturesExceptionHandling); err != nil {
+		return fmt.Errorf("tags invalid as %w", err)
+	}
+	m.UsesExceptionHandling = true
+
+	for i := range m.ImportSection {
+		if imp := &m.ImportSection[i]; imp.Type == ExternTypeTag {
+			if err := m.validateTagType(imp.DescTag); err != nil {
+				return fmt.Errorf("invalid import[%q.%q] tag: %w", imp.Module, imp.Name, err)
+			}
+		}
+	}
+	for i, typeIndex := range m.TagSection {
+		if err := m.validateTagType(typeIndex); err != nil {
+			return fmt.Errorf("invalid tag[%d]: %w", m.ImportTagCount+Index(i), err)
+		}
+	}
+	return nil
+}
+
+func (m *Module) validateTagType(typeIndex Index) error {
+	if typeIndex >= uint32(len(m.TypeSection)) {
+		return fmt.Errorf("type index %d out of range", typeIndex)
+	}
+	if tp := &m.TypeSection[typeIndex]; len(tp.Results) > 0 {
+		return fmt.Errorf("type %s must have no results", tp)
+	}
+	return nil
+}
+
+// TagType returns the type of the tag at the index in the tag index space, which begins with imported tags, or nil
+// if there is none. This is only valid after validateTags.
+func (m *Module) TagType(index Index) *FunctionType {
+	if index < m.ImportTagCount {
+		for i := range m.ImportSection {
+			if imp := &m.ImportSection[i]; imp.Type == ExternTypeTag && imp.IndexPerType == index {
+				return &m.TypeSection[imp.DescTag]
+			}
+		}
+		return nil
+	}
+	if index -= m.ImportTagCount; index < uint32(len(m.TagSection)) {
+		return &m.TypeSection[m.TagSection[index]]
+	}
+	return nil
+}
+
 func validateConstExpression(globals []GlobalType, numFuncs uint32, expr *ConstantExpression, expectedType ValueType) (err error) {
 	var actualType ValueType
 	switch expr.Opcode {
@@ -590,6 +878,10 @@ func validateConstExpression(globals []GlobalType, numFuncs uint32, expr *Consta
 			return fmt.Errorf("%s needs 16 bytes but was %d bytes", OpcodeVecV128ConstName, len(expr.Data))
 		}
 		actualType = ValueTypeV128
+	case OpcodeI32Add, OpcodeI32Sub, OpcodeI32Mul, OpcodeI64Add, OpcodeI64Sub, OpcodeI64Mul:
+		if actualType, err = validateExtendedConstExpression(globals, expr); err != nil {
+			return err
+		}
 	default:
 		return fmt.Errorf("invalid opcode for const expression: 0x%x", expr.Opcode)
 	}
@@ -601,6 +893,42 @@ func validateConstExpression(globals []GlobalType, numFuncs uint32, expr *Consta
 	return nil
 }
 
+// validateExtendedConstExpression validates an expression of experimental.CoreFeaturesExtendedConst, and returns the
+// type of its value.
+func validateExtendedConstExpression(globals []GlobalType, expr *ConstantExpression) (ValueType, error) {
+	var stack []ValueType
+	err := expr.extendedConstInstructions(func(op Opcode, imm uint64) error {
+		switch op {
+		case OpcodeI32Const:
+			stack = append(stack, ValueTypeI32)
+		case OpcodeI64Const:
+			stack = append(stack, ValueTypeI64)
+		case OpcodeGlobalGet:
+			if uint64(len(globals)) <= imm {
+				return fmt.Errorf("global index out of range")
+			}
+			stack = append(stack, globals[imm].ValType)
+		default:
+			t := ValueTypeI64
+			if op == OpcodeI32Add || op == OpcodeI32Sub || op == OpcodeI32Mul {
+				t = ValueTypeI32
+			}
+			if len(stack) < 2 || stack[len(stack)-1] != t || stack[len(stack)-2] != t {
+				return fmt.Errorf("cannot use %s in const expression: operands must be two %s values",
+					InstructionName(op), ValueTypeName(t))
+			}
+			stack = stack[:len(stack)-1]
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	} else if len(stack) != 1 {
+		return 0, fmt.Errorf("const expression must produce a single value, but produces %d", len(stack))
+	}
+	return stack[0], nil
+}
+
 func (m *Module) validateDataCountSection() (err error) {
 	if m.DataCountSection != nil && int(*m.DataCountSection) != len(m.DataSection) {
 		err = fmt.Errorf("data count section (%d) doesn't match the length of data section (%d)",
@@ -610,13 +938,14 @@ func (m *Module) validateDataCountSection() (err error) {
 }
 
 func (m *ModuleInstance) buildGlobals(module *Module, funcRefResolver func(funcIndex Index) Reference) {
-	importedGlobals := m.Globals[:module.ImportGlobalCount]
 	for i := Index(0); i < Index(len(module.GlobalSection)); i++ {
 		gs := &module.GlobalSection[i]
 		g := &GlobalInstance{}
+		// Validation ensures the initializer only reads globals before this one, which are already initialized.
+		initialized := m.Globals[:i+module.ImportGlobalCount]
 		m.Globals[i+module.ImportGlobalCount] = g
 		g.Type = gs.Type
-		g.initialize(importedGlobals, &gs.Init, funcRefResolver)
+		g.initialize(initialized, &gs.Init, funcRefResolver)
 	}
 }
 
@@ -640,12 +969,149 @@ func paramNames(localNames IndirectNameMap, funcIdx uint32, paramLen int) []stri
 	return nil
 }
 
-func (m *ModuleInstance) buildMemory(module *Module) {
+// buildMemory builds the memories defined by the module. When minPages is greater than the minimum of the memory at
+// index zero, it is allocated with minPages instead, which errs when over its maximum.
+func (m *ModuleInstance) buildMemory(module *Module, minPages uint32, buffer []byte) error {
 	memSec := module.MemorySection
 	if memSec != nil {
-		m.MemoryInstance = NewMemoryInstance(memSec)
+		if buffer != nil {
+			// The buffer is zeroed, so it must not be in use by another module.
+			if err := m.s.borrowBuffer(buffer); err != nil {
+				return err
+			}
+			m.borrowedBuffer = buffer
+			mem, err := newBorrowedMemoryInstance(memSec, minPages, buffer)
+			if err != nil {
+				return err
+			}
+			m.MemoryInstance = mem
+		} else if minPages > memSec.Min {
+			if minPages > memSec.Max {
+				return &api.LimitExceededError{Resource: "memory min pages", Requested: uint64(minPages), Limit: uint64(memSec.Max)}
+			}
+			presized := *memSec
+			presized.Min = minPages
+			if presized.Cap < minPages {
+				presized.Cap = minPages
+			}
+			if err := checkMemoryCapacity(&presized); err != nil {
+				return err
+			}
+			m.MemoryInstance = NewMemoryInstance(&presized)
+			m.MemoryInstance.Min = memSec.Min // Restore can shrink to the declared minimum.
+		} else {
+			if err := checkMemoryCapacity(memSec); err != nil {
+				return err
+			}
+			m.MemoryInstance = NewMemoryInstance(memSec)
+		}
 		m.MemoryInstance.definition = &module.MemoryDefinitionSection[0]
 	}
+	if additional := module.AdditionalMemorySection; len(additional) > 0 {
+		m.AdditionalMemories = make([]*MemoryInstance, len(additional))
+		for i := range additional {
+			if err := checkMemoryCapacity(&additional[i]); err != nil {
+				return err
+			}
+			mem := NewMemoryInstance(&additional[i])
+			// The memory at index zero precedes these, whether imported or not.
+			mem.definition = &module.MemoryDefinitionSection[1+i]
+			m.AdditionalMemories[i] = mem
+		}
+	}
+	return nil
+}
+
+// newBorrowedMemoryInstance creates the memory defined by memSec backed by buffer, which is zeroed, as its capacity.
+// The memory starts with the larger of its minimum and minPages.
+func newBorrowedMemoryInstance(memSec *Memory, minPages uint32, buffer []byte) (*MemoryInstance, error) {
+	if uint64(len(buffer))%uint64(MemoryPageSize) != 0 {
+		return nil, fmt.Errorf("memory buffer size %d isn't a multiple of the page size", len(buffer))
+	}
+	bufferPages := memoryBytesNumToPages(uint64(len(buffer)))
+	if minPages < memSec.Min {
+		minPages = memSec.Min
+	}
+	if minPages > memSec.Max {
+		return nil, &api.LimitExceededError{Resource: "memory min pages", Requested: uint64(minPages), Limit: uint64(memSec.Max)}
+	} else if bufferPages < minPages {
+		return nil, fmt.Errorf("memory buffer of %d pages is less than the minimum of %d pages", bufferPages, minPages)
+	} else if memSec.IsShared && bufferPages < memSec.Max {
+		// Shared memories never move, so they can only grow within the buffer.
+		return nil, fmt.Errorf("memory buffer of %d pages is less than the maximum of %d pages of a shared memory", bufferPages, memSec.Max)
+	}
+	capPages := bufferPages
+	if capPages > memSec.Max {
+		capPages = memSec.Max
+	}
+	capacity := MemoryPagesToBytesNum(capPages)
+	buffer = buffer[:capacity:capacity]
+	for i := range buffer {
+		buffer[i] = 0
+	}
+	return &MemoryInstance{
+		Buffer:   buffer[:MemoryPagesToBytesNum(minPages)],
+		Min:      memSec.Min,
+		Cap:      capPages,
+		Max:      memSec.Max,
+		Is64:     memSec.Is64,
+		Shared:   memSec.IsShared,
+		borrowed: true,
+	}, nil
+}
+
+// borrowBuffer marks buffer as in use by a module until returnBuffer, or errs if it already is.
+func (s *Store) borrowBuffer(buffer []byte) error {
+	if cap(buffer) == 0 {
+		return nil // There are no bytes to share.
+	}
+	key := &buffer[:1][0]
+	s.borrowedBuffersMux.Lock()
+	defer s.borrowedBuffersMux.Unlock()
+	if _, ok := s.borrowedBuffers[key]; ok {
+		return errors.New("memory buffer is in use by another module")
+	}
+	if s.borrowedBuffers == nil {
+		s.borrowedBuffers = map[*byte]struct{}{}
+	}
+	s.borrowedBuffers[key] = struct{}{}
+	return nil
+}
+
+// returnBuffer marks buffer as no longer in use, so that another module can borrow it.
+func (s *Store) returnBuffer(buffer []byte) {
+	if cap(buffer) == 0 {
+		return
+	}
+	s.borrowedBuffersMux.Lock()
+	delete(s.borrowedBuffers, &buffer[:1][0])
+	s.borrowedBuffersMux.Unlock()
+}
+
+// returnBorrowedBuffer returns the buffer borrowed for the memory of this module, if any.
+func (m *ModuleInstance) returnBorrowedBuffer() {
+	if b := m.borrowedBuffer; b != nil {
+		m.s.returnBuffer(b)
+		m.borrowedBuffer = nil
+	}
+}
+
+// memoryCount returns the number of memories in the memory index space, given the memory at index zero, which is
+// either imported or the MemorySection.
+func (m *Module) memoryCount(memory *Memory) Index {
+	if memory == nil {
+		return 0
+	}
+	return 1 + Index(len(m.AdditionalMemorySection))
+}
+
+// memoryAt returns the memory at the given index in the memory index space, given the memory at index zero, which
+// is either imported or the MemorySection. The index must be less than memoryCount.
+func (m *Module) memoryAt(memory *Memory, index Index) *Memory {
+	if index == 0 {
+		return memory
+	}
+	return &m.AdditionalMemorySection[index-1]
 }
 
 // Index is the offset in an index, not necessarily an absolute position in a Module section. This is because
@@ -750,6 +1216,8 @@ type Import struct {
 	DescMem *Memory
 	// DescGlobal is the inlined GlobalType when Type equals ExternTypeGlobal
 	DescGlobal GlobalType
+	// DescTag is the index in Module.TypeSection when Type equals ExternTypeTag
+	DescTag Index
 	// IndexPerType has the index of this import per ExternType.
 	IndexPerType Index
 }
@@ -759,6 +1227,21 @@ type Memory struct {
 	Min, Cap, Max uint32
 	// IsMaxEncoded true if the Max is encoded in the original binary.
 	IsMaxEncoded bool
+	// IsShared is true if the memory is declared shared, which requires experimental.CoreFeaturesThreads.
+	IsShared bool
+	// Is64 is true if the memory is indexed with i64 addresses, which requires experimental.CoreFeaturesMemory64.
+	//
+	// Note: The limits of a 64-bit memory are decoded as 64-bit, but Max is capped at the runtime memory limit, as a
+	// larger memory cannot be allocated anyway.
+	Is64 bool
+}
+
+// addressType returns the type of the addresses of this memory: ValueTypeI64 if Is64, otherwise ValueTypeI32.
+func (m *Memory) addressType() ValueType {
+	if m.Is64 {
+		return ValueTypeI64
+	}
+	return ValueTypeI32
 }
 
 // Validate ensures values assigned to Min, Cap and Max are within valid thresholds.
@@ -766,11 +1249,9 @@ func (m *Memory) Validate(memoryLimitPages uint32) error {
 	min, capacity, max := m.Min, m.Cap, m.Max
 
 	if max > memoryLimitPages {
-		return fmt.Errorf("max %d pages (%s) over limit of %d pages (%s)",
-			max, PagesToUnitOfBytes(max), memoryLimitPages, PagesToUnitOfBytes(memoryLimitPages))
+		return &api.LimitExceededError{Resource: "memory max pages", Requested: uint64(max), Limit: uint64(memoryLimitPages)}
 	} else if min > memoryLimitPages {
-		return fmt.Errorf("min %d pages (%s) over limit of %d pages (%s)",
-			min, PagesToUnitOfBytes(min), memoryLimitPages, PagesToUnitOfBytes(memoryLimitPages))
+		return &api.LimitExceededError{Resource: "memory min pages", Requested: uint64(min), Limit: uint64(memoryLimitPages)}
 	} else if min > max {
 		return fmt.Errorf("min %d pages (%s) > max %d pages (%s)",
 			min, PagesToUnitOfBytes(min), max, PagesToUnitOfBytes(max))
@@ -778,8 +1259,7 @@ func (m *Memory) Validate(memoryLimitPages uint32) error {
 		return fmt.Errorf("capacity %d pages (%s) less than minimum %d pages (%s)",
 			capacity, PagesToUnitOfBytes(capacity), min, PagesToUnitOfBytes(min))
 	} else if capacity > memoryLimitPages {
-		return fmt.Errorf("capacity %d pages (%s) over limit of %d pages (%s)",
-			capacity, PagesToUnitOfBytes(capacity), memoryLimitPages, PagesToUnitOfBytes(memoryLimitPages))
+		return &api.LimitExceededError{Resource: "memory capacity pages", Requested: uint64(capacity), Limit: uint64(memoryLimitPages)}
 	}
 	return nil
 }
@@ -794,11 +1274,84 @@ type Global struct {
 	Init ConstantExpression
 }
 
+// ConstantExpression is an expression evaluated during instantiation, such as a global initializer.
+//
+// Opcode is usually the only instruction of the expression, and Data holds its immediates. An expression of
+// experimental.CoreFeaturesExtendedConst has an arithmetic instruction as Opcode, which is always the last
+// instruction, and Data holds the preceding instructions. See IsExtendedConstOpcode.
 type ConstantExpression struct {
 	Opcode Opcode
 	Data   []byte
 }
 
+// IsExtendedConstOpcode returns true if the opcode is one of the arithmetic instructions allowed in a
+// ConstantExpression by experimental.CoreFeaturesExtendedConst.
+func IsExtendedConstOpcode(op Opcode) bool {
+	switch op {
+	case OpcodeI32Add, OpcodeI32Sub, OpcodeI32Mul, OpcodeI64Add, OpcodeI64Sub, OpcodeI64Mul:
+		return true
+	}
+	return false
+}
+
+// extendedConstInstructions calls fn with each instruction of an expression of experimental.CoreFeaturesExtendedConst,
+// in order. imm is the immediate of i32.const, i64.const and global.get, and is zero for arithmetic instructions.
+func (e *ConstantExpression) extendedConstInstructions(fn func(op Opcode, imm uint64) error) error {
+	data := e.Data
+	for len(data) > 0 {
+		op := data[0]
+		data = data[1:]
+		var imm uint64
+		switch op {
+		case OpcodeI32Const:
+			v, n, err := leb128.LoadInt32(data)
+			if err != nil {
+				return fmt.Errorf("read i32: %w", err)
+			}
+			imm, data = uint64(uint32(v)), data[n:]
+		case OpcodeI64Const:
+			v, n, err := leb128.LoadInt64(data)
+			if err != nil {
+				return fmt.Errorf("read i64: %w", err)
+			}
+			imm, data = uint64(v), data[n:]
+		case OpcodeGlobalGet:
+			v, n, err := leb128.LoadUint32(data)
+			if err != nil {
+				return fmt.Errorf("read index of global: %w", err)
+			}
+			imm, data = uint64(v), data[n:]
+		default:
+			if !IsExtendedConstOpcode(op) {
+				return fmt.Errorf("invalid opcode for const expression: 0x%x", op)
+			}
+		}
+		if err := fn(op, imm); err != nil {
+			return err
+		}
+	}
+	return fn(e.Opcode, 0)
+}
+
+// FoldConstantExpression replaces an expression of experimental.CoreFeaturesExtendedConst with a single i32.const or
+// i64.const, when it doesn't use global.get. Otherwise, or if it is invalid, the expression is left as is, so that it
+// is evaluated during instantiation, or rejected by validation.
+func FoldConstantExpression(expr *ConstantExpression) {
+	if !IsExtendedConstOpcode(expr.Opcode) {
+		return
+	}
+	valType, err := validateExtendedConstExpression(nil, expr)
+	if err != nil {
+		return // global.get or a type error.
+	}
+	v := executeExtendedConstExpression(nil, expr)
+	if valType == ValueTypeI32 {
+		*expr = ConstantExpression{Opcode: OpcodeI32Const, Data: leb128.EncodeInt32(int32(v))}
+	} else {
+		*expr = ConstantExpression{Opcode: OpcodeI64Const, Data: leb128.EncodeInt64(int64(v))}
+	}
+}
+
 // Export is the binary representation of an export indicated by Type
 // See https://www.w3.org/TR/2019/REC-wasm-core-1-20191205/#binary-export
 type Export struct {
@@ -840,6 +1393,10 @@ type DataSegment struct {
 	OffsetExpression ConstantExpression
 	Init             []byte
 	Passive          bool
+
+	// MemoryIndex is the memory an active segment is copied into, which is only non-zero when
+	// experimental.CoreFeaturesMultiMemory is enabled.
+	MemoryIndex Index
 }
 
 // IsPassive returns true if this data segment is "passive" in the sense that memory offset and
@@ -984,6 +1541,12 @@ const (
 	// See https://www.w3.org/TR/2022/WD-wasm-core-2-20220419/binary/modules.html#data-count-section
 	// See https://www.w3.org/TR/2022/WD-wasm-core-2-20220419/appendix/changes.html#bulk-memory-and-table-instructions
 	SectionIDDataCount
+
+	// SectionIDTag may exist when experimental.CoreFeaturesExceptionHandling is enabled. It is ordered between the
+	// SectionIDMemory and SectionIDGlobal.
+	//
+	// See https://github.com/WebAssembly/exception-handling/blob/main/proposals/exception-handling/legacy/Exceptions.md#tag-section
+	SectionIDTag
 )
 
 // SectionIDName returns the canonical name of a module section.
@@ -1016,6 +1579,8 @@ func SectionIDName(sectionID SectionID) string {
 		return "data"
 	case SectionIDDataCount:
 		return "data_count"
+	case SectionIDTag:
+		return "tag"
 	}
 	return "unknown"
 }
@@ -1061,9 +1626,17 @@ const (
 	ExternTypeMemoryName = api.ExternTypeMemoryName
 	ExternTypeGlobal     = api.ExternTypeGlobal
 	ExternTypeGlobalName = api.ExternTypeGlobalName
+
+	// ExternTypeTag is the type of an import or export of a tag, which requires
+	// experimental.CoreFeaturesExceptionHandling. This isn't defined in api as tags can't be used outside a module.
+	ExternTypeTag     ExternType = 0x04
+	ExternTypeTagName            = "tag"
 )
 
-// ExternTypeName is an alias of api.ExternTypeName defined to simplify imports.
+// ExternTypeName is an alias of api.ExternTypeName defined to simplify imports, which also names ExternTypeTag.
 func ExternTypeName(t ValueType) string {
+	if t == ExternTypeTag {
+		return ExternTypeTagName
+	}
 	return api.ExternTypeName(t)
 }
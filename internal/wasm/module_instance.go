@@ -6,6 +6,8 @@ import (
 	"fmt"
 
 	"github.com/tetratelabs/wazero/api"
+	internalclose "github.com/tetratelabs/wazero/internal/close"
+	"github.com/tetratelabs/wazero/internal/wasmruntime"
 	"github.com/tetratelabs/wazero/sys"
 )
 
@@ -24,6 +26,43 @@ func (m *ModuleInstance) FailIfClosed() (err error) {
 	return nil
 }
 
+// FailIfClosedOrOutOfFuel is like FailIfClosed, except it also consumes n
+// units of fuel, closing the module with sys.ExitCodeFuelExhausted when fewer
+// remain.
+func (m *ModuleInstance) FailIfClosedOrOutOfFuel(n uint64) error {
+	if err := m.FailIfClosed(); err != nil {
+		return err
+	}
+	if m.Sys != nil && !m.Sys.ConsumeFuel(n) {
+		// Like closeModuleOnCanceledOrTimeout, defer the resource closure to FailIfClosed.
+		_ = m.closeWithExitCodeWithoutClosingResource(sys.ExitCodeFuelExhausted)
+		return m.FailIfClosed()
+	}
+	return nil
+}
+
+// Fuel returns the remaining fuel of this module and whether it is limited
+// at all.
+//
+// See wazero.ModuleConfig WithFuel
+func (m *ModuleInstance) Fuel() (fuel uint64, limited bool) {
+	if m.Sys == nil {
+		return 0, false
+	}
+	return m.Sys.Fuel()
+}
+
+// MaxCallDepth returns the maximum nesting of calls into Wasm functions
+// started from this module, or zero if unlimited.
+//
+// See wazero.ModuleConfig WithMaxCallDepth
+func (m *ModuleInstance) MaxCallDepth() uint32 {
+	if m.Sys == nil {
+		return 0
+	}
+	return m.Sys.MaxCallDepth()
+}
+
 // CloseModuleOnCanceledOrTimeout take a context `ctx`, which might be a Cancel or Timeout context,
 // and spawns the Goroutine to check the context is canceled ot deadline exceeded. If it reaches
 // one of the conditions, it sets the appropriate exit code.
@@ -157,6 +196,28 @@ func (m *ModuleInstance) ensureResourcesClosed(ctx context.Context) (err error)
 		m.Sys = nil
 	}
 
+	if m.ZeroMemoryOnClose {
+		m.zeroMemories()
+	}
+
+	if m.memoryPrefaulted {
+		m.definedMemories(func(mem *MemoryInstance) { mem.unlock() })
+	}
+	m.definedMemories((*MemoryInstance).release)
+	m.returnBorrowedBuffer()
+
+	if d := m.Differential; d != nil {
+		d.ZeroMemoryOnClose = m.ZeroMemoryOnClose
+		err = d.closeWithExitCode(ctx, uint32(m.Closed.Load()>>32))
+		m.Differential = nil
+	}
+
+	for _, stub := range m.importStubs {
+		if e := stub.closeWithExitCode(ctx, 0); e != nil && err == nil {
+			err = e
+		}
+	}
+
 	if m.CodeCloser == nil {
 		return
 	}
@@ -167,34 +228,298 @@ func (m *ModuleInstance) ensureResourcesClosed(ctx context.Context) (err error)
 	return
 }
 
+// zeroMemories zeroes the memories defined by this module, including any capacity beyond their current size. An
+// imported memory is left as is, as it is still used by the module which defines it.
+func (m *ModuleInstance) zeroMemories() {
+	m.definedMemories((*MemoryInstance).zero)
+}
+
+// definedMemories calls fn with each memory defined by this module, which excludes an imported memory.
+func (m *ModuleInstance) definedMemories(fn func(mem *MemoryInstance)) {
+	if mem := m.MemoryInstance; mem != nil && m.Source.ImportMemoryCount == 0 {
+		fn(mem)
+	}
+	for _, mem := range m.AdditionalMemories {
+		fn(mem)
+	}
+}
+
+// PrefaultMemory writes to each page of the memories defined by this module, so that the first accesses during calls
+// don't fault, and locks them in RAM where supported. Otherwise, or if the process may not lock that much memory, the
+// memories are only pre-faulted. The locks are released when the module is closed, or when a memory grows beyond its
+// capacity, as its buffer is then reallocated. An imported memory is left to the module which defines it.
+func (m *ModuleInstance) PrefaultMemory() {
+	m.memoryPrefaulted = true
+	m.definedMemories((*MemoryInstance).prefault)
+}
+
+// SetMemoryGrowHook sets the hook called before a memory defined by this module grows, which fails the growth by
+// returning false. An imported memory is left as is, as the module which defines it owns its hook.
+func (m *ModuleInstance) SetMemoryGrowHook(hook func(mod api.Module, previousPages, newPages uint32) bool) {
+	m.memoryGrowHook = hook
+	growHook := func(previousPages, newPages uint32) bool {
+		return hook(m, previousPages, newPages)
+	}
+	if mem := m.MemoryInstance; mem != nil && m.Source.ImportMemoryCount == 0 {
+		mem.GrowHook = growHook
+	}
+	for _, mem := range m.AdditionalMemories {
+		mem.GrowHook = growHook
+	}
+}
+
+// CallHostFunction calls fn, which is an api.GoModuleFunction or an api.GoFunction, on behalf of the caller module.
+//
+// When the caller has a HostPanicRecovery, a panic of fn is replaced with a panic of the error it returns, so that the
+// engine raises it as a trap. A sys.ExitError or a wasmruntime.Error isn't converted, as neither is a failure of fn.
+//
+// stackRemaining is the approximate stack remaining in bytes for the call, or -1 if unknown, which fn can read via
+// StackRemaining of the caller.
+//
+// When the caller has a HostCallLogger, it is called with the params and results of def once fn returns or panics.
+//
+// See wazero.ModuleConfig WithHostPanicRecovery and WithHostCallLogger
+func CallHostFunction(ctx context.Context, caller *ModuleInstance, def *FunctionDefinition, fn interface{}, stack []uint64, stackRemaining int) {
+	if caller.HostPanicRecovery != nil {
+		defer caller.recoverHostPanic()
+	}
+	if caller.HostCallLogger != nil {
+		// Copy the params, as the results overwrite them on the stack.
+		defer caller.logHostCall(def, append([]uint64(nil), stack[:def.Functype.ParamNumInUint64]...), stack)
+	}
+	// Restore the value of any outer host call, e.g. when a host function calls back into the guest.
+	outer := caller.hostCallStackRemaining.Swap(int64(stackRemaining) + 1)
+	defer caller.hostCallStackRemaining.Store(outer)
+	switch fn := fn.(type) {
+	case api.GoModuleFunction:
+		fn.Call(ctx, caller, stack)
+	case api.GoFunction:
+		fn.Call(ctx, stack)
+	}
+}
+
+// StackRemaining implements the same method as documented on api.Module.
+func (m *ModuleInstance) StackRemaining() int {
+	return int(m.hostCallStackRemaining.Load()) - 1
+}
+
+// recoverHostPanic is deferred by CallHostFunction to convert a panic with HostPanicRecovery.
+func (m *ModuleInstance) recoverHostPanic() {
+	recovered := recover()
+	switch recovered.(type) {
+	case nil:
+		return
+	case *sys.ExitError, *wasmruntime.Error:
+	default:
+		if err := m.HostPanicRecovery(recovered); err != nil {
+			recovered = err
+		}
+	}
+	panic(recovered)
+}
+
+// logHostCall is deferred by CallHostFunction to call HostCallLogger, with nil results when fn panicked.
+func (m *ModuleInstance) logHostCall(def *FunctionDefinition, params, stack []uint64) {
+	recovered := recover()
+	var results []uint64
+	if recovered == nil {
+		results = stack[:def.Functype.ResultNumInUint64]
+	}
+	m.HostCallLogger(def.moduleName, def.name, params, results)
+	if recovered != nil {
+		panic(recovered)
+	}
+}
+
 // Memory implements the same method as documented on api.Module.
 func (m *ModuleInstance) Memory() api.Memory {
 	return m.MemoryInstance
 }
 
+// MemoryAt returns the memory at the given index in the memory index space, where the index zero is MemoryInstance
+// and any other is in AdditionalMemories. The index must be valid for this module.
+func (m *ModuleInstance) MemoryAt(index Index) *MemoryInstance {
+	if index == 0 {
+		return m.MemoryInstance
+	}
+	return m.AdditionalMemories[index-1]
+}
+
+// SnapshotMemory implements the same method as documented on api.Module.
+func (m *ModuleInstance) SnapshotMemory() []byte {
+	if m.MemoryInstance == nil {
+		return nil
+	}
+	return append([]byte{}, m.MemoryInstance.Buffer...)
+}
+
+// RestoreMemory implements the same method as documented on api.Module.
+func (m *ModuleInstance) RestoreMemory(snapshot []byte) error {
+	if m.MemoryInstance == nil {
+		return fmt.Errorf("module[%s] has no memory", m.ModuleName)
+	}
+	return m.MemoryInstance.Restore(snapshot)
+}
+
+// Clone implements the same method as documented on api.Module.
+func (m *ModuleInstance) Clone(ctx context.Context, newName string) (api.Module, error) {
+	if err := m.FailIfClosed(); err != nil {
+		return nil, err
+	}
+	switch {
+	case m.CodeCloser != nil:
+		return nil, fmt.Errorf("module[%s] cannot be cloned as it closes its compiled code", m.ModuleName)
+	case m.Differential != nil:
+		return nil, fmt.Errorf("module[%s] cannot be cloned as it is checked against another engine", m.ModuleName)
+	case m.importStubs != nil:
+		return nil, fmt.Errorf("module[%s] cannot be cloned as it imports stubs", m.ModuleName)
+	case m.NewSysContext == nil:
+		return nil, fmt.Errorf("module[%s] cannot be cloned", m.ModuleName)
+	}
+
+	sysCtx, err := m.NewSysContext()
+	if err != nil {
+		return nil, err
+	}
+
+	c := &ModuleInstance{
+		ModuleName:        newName,
+		Exports:           m.Exports,
+		TypeIDs:           m.TypeIDs,
+		Sys:               sysCtx,
+		s:                 m.s,
+		engine:            m.engine,
+		Source:            m.Source,
+		ZeroMemoryOnClose: m.ZeroMemoryOnClose,
+		StrictFloat:       m.StrictFloat,
+		PprofLabels:       m.PprofLabels,
+		HostPanicRecovery: m.HostPanicRecovery,
+		HostCallLogger:    m.HostCallLogger,
+		NewSysContext:     m.NewSysContext,
+		startCalled:       true, // The state is copied after any start function.
+		importRenames:     m.importRenames,
+	}
+	if closeNotifier, ok := ctx.Value(internalclose.NotifierKey{}).(internalclose.Notifier); ok {
+		c.CloseNotifier = closeNotifier
+	}
+
+	if err = c.cloneState(m); err != nil {
+		return nil, err
+	}
+
+	if err = m.s.registerModule(c); err != nil {
+		_ = c.Close(ctx)
+		return nil, err
+	}
+	return c, nil
+}
+
+// cloneState instantiates c with copies of the tables, globals, memories and segments defined by m, and the same
+// imports, as Store.instantiate does for the Source.
+func (c *ModuleInstance) cloneState(m *ModuleInstance) (err error) {
+	module := m.Source
+	c.Tables = make([]*TableInstance, len(m.Tables))
+	c.Globals = make([]*GlobalInstance, len(m.Globals))
+	c.Tags = make([]*TagInstance, len(m.Tags))
+	if c.Engine, err = c.engine.NewModuleEngine(module, c); err != nil {
+		return
+	}
+	if err = c.resolveImports(module); err != nil {
+		return
+	}
+
+	// A reference to a function of m is replaced with the same function of c. Any other, such as to a function of
+	// another module set via an imported table, remains valid as is.
+	cloneRef := func(ref Reference) Reference {
+		if idx, ok := m.Engine.ReferencedFunctionIndex(ref); ok {
+			return c.Engine.FunctionInstanceReference(idx)
+		}
+		return ref
+	}
+
+	for i := module.ImportTableCount; i < Index(len(m.Tables)); i++ {
+		t := m.Tables[i]
+		refs := make([]Reference, len(t.References))
+		for j, ref := range t.References {
+			switch {
+			case ref == 0:
+			case t.Type == RefTypeExternref:
+				return fmt.Errorf("table[%d] cannot be cloned as it holds external references", i)
+			default:
+				refs[j] = cloneRef(ref)
+			}
+		}
+		c.Tables[i] = &TableInstance{References: refs, Min: t.Min, Max: t.Max, Type: t.Type}
+	}
+
+	for i := module.ImportGlobalCount; i < Index(len(m.Globals)); i++ {
+		g := *m.Globals[i]
+		if g.Type.ValType == ValueTypeFuncref {
+			g.Val = uint64(cloneRef(Reference(g.Val)))
+		}
+		c.Globals[i] = &g
+	}
+	// Like any other instance, the clone defines tags distinct from those of m.
+	c.buildTags(module)
+
+	if module.MemorySection != nil {
+		c.MemoryInstance = m.MemoryInstance.clone()
+	}
+	if len(m.AdditionalMemories) > 0 {
+		c.AdditionalMemories = make([]*MemoryInstance, len(m.AdditionalMemories))
+		for i, mem := range m.AdditionalMemories {
+			c.AdditionalMemories[i] = mem.clone()
+		}
+	}
+
+	// Data segments are never written, so only the dropped state needs to be copied.
+	c.DataInstances = append([]DataInstance(nil), m.DataInstances...)
+	if len(m.ElementInstances) > 0 {
+		c.ElementInstances = make([]ElementInstance, len(m.ElementInstances))
+		for i, elem := range m.ElementInstances {
+			if elem == nil {
+				continue // dropped
+			}
+			refs := make([]Reference, len(elem))
+			for j, ref := range elem {
+				if ref != 0 {
+					refs[j] = cloneRef(ref)
+				}
+			}
+			c.ElementInstances[i] = refs
+		}
+	}
+
+	c.Engine.DoneInstantiation()
+
+	if hook := m.memoryGrowHook; hook != nil {
+		c.SetMemoryGrowHook(hook)
+	}
+	if m.memoryPrefaulted {
+		c.PrefaultMemory()
+	}
+	return
+}
+
 // ExportedMemory implements the same method as documented on api.Module.
 func (m *ModuleInstance) ExportedMemory(name string) api.Memory {
-	_, err := m.getExport(name, ExternTypeMemory)
+	exp, err := m.getExport(name, ExternTypeMemory)
 	if err != nil {
 		return nil
 	}
-	// We Assume that we have at most one memory.
-	return m.MemoryInstance
+	return m.MemoryAt(exp.Index)
 }
 
 // ExportedMemoryDefinitions implements the same method as documented on
 // api.Module.
 func (m *ModuleInstance) ExportedMemoryDefinitions() map[string]api.MemoryDefinition {
-	// Special case as we currently only support one memory.
-	if mem := m.MemoryInstance; mem != nil {
-		// Now, find out if it is exported
-		for name, exp := range m.Exports {
-			if exp.Type == ExternTypeMemory {
-				return map[string]api.MemoryDefinition{name: mem.definition}
-			}
+	ret := map[string]api.MemoryDefinition{}
+	for name, exp := range m.Exports {
+		if exp.Type == ExternTypeMemory {
+			ret[name] = m.MemoryAt(exp.Index).definition
 		}
 	}
-	return map[string]api.MemoryDefinition{}
+	return ret
 }
 
 // ExportedFunction implements the same method as documented on api.Module.
@@ -203,7 +528,14 @@ func (m *ModuleInstance) ExportedFunction(name string) api.Function {
 	if err != nil {
 		return nil
 	}
-	return m.Engine.NewFunction(exp.Index)
+	fn := m.Engine.NewFunction(exp.Index)
+	if d := m.Differential; d != nil {
+		fn = &differentialFunction{name: name, m: m, differential: d, fn: fn, differentialFn: d.Engine.NewFunction(exp.Index)}
+	}
+	if m.PprofLabels {
+		fn = newPprofFunction(fn)
+	}
+	return fn
 }
 
 // ExportedFunctionDefinitions implements the same method as documented on
@@ -218,6 +550,15 @@ func (m *ModuleInstance) ExportedFunctionDefinitions() map[string]api.FunctionDe
 	return result
 }
 
+// ExportedTable implements the same method as documented on api.Module.
+func (m *ModuleInstance) ExportedTable(name string) api.Table {
+	exp, err := m.getExport(name, ExternTypeTable)
+	if err != nil {
+		return nil
+	}
+	return exportedTable{t: m.Tables[exp.Index], s: m.s}
+}
+
 // GlobalVal is an internal hack to get the lower 64 bits of a global.
 func (m *ModuleInstance) GlobalVal(idx Index) uint64 {
 	return m.Globals[idx].Val
@@ -236,6 +577,28 @@ func (m *ModuleInstance) ExportedGlobal(name string) api.Global {
 	return constantGlobal{g: g}
 }
 
+// GlobalValues implements the same method as documented on api.Module.
+func (m *ModuleInstance) GlobalValues() map[string]uint64 {
+	ret := map[string]uint64{}
+	for name, exp := range m.Exports {
+		if exp.Type == ExternTypeGlobal {
+			ret[name] = m.Globals[exp.Index].Val
+		}
+	}
+	return ret
+}
+
+// GlobalTypes implements the same method as documented on api.Module.
+func (m *ModuleInstance) GlobalTypes() map[string]api.ValueType {
+	ret := map[string]api.ValueType{}
+	for name, exp := range m.Exports {
+		if exp.Type == ExternTypeGlobal {
+			ret[name] = m.Globals[exp.Index].Type.ValType
+		}
+	}
+	return ret
+}
+
 // NumGlobal implements experimental.InternalModule.
 func (m *ModuleInstance) NumGlobal() int {
 	return len(m.Globals)
@@ -6,18 +6,20 @@ import (
 
 	"github.com/tetratelabs/wazero/api"
 	"github.com/tetratelabs/wazero/internal/internalapi"
+	"github.com/tetratelabs/wazero/internal/wasmdebug"
+	"github.com/tetratelabs/wazero/internal/wasmruntime"
 )
 
 // LookupFunction looks up the table by the given index, and returns the api.Function implementation if found,
 // otherwise this panics according to the same semantics as call_indirect instruction.
-// Currently, this is only used by emscripten which needs to do call_indirect-like operation in the host function.
+// This is used by emscripten, which needs to do call_indirect-like operation in the host function, and CallIndirect.
 func (m *ModuleInstance) LookupFunction(t *TableInstance, typeId FunctionTypeID, tableOffset Index) api.Function {
 	fm, index := m.Engine.LookupFunction(t, typeId, tableOffset)
 	if source := fm.Source; source.IsHostModule {
 		// This case, the found function is a host function stored in the table. Generally, Engine.NewFunction are only
 		// responsible for calling Wasm-defined functions (not designed for calling Go functions!). Hence we need to wrap
 		// the host function as a special case.
-		def := &source.FunctionDefinitionSection[index]
+		def := source.FunctionDefinition(index)
 		goF := source.CodeSection[index].GoFunc
 		switch typed := goF.(type) {
 		case api.GoFunction:
@@ -33,6 +35,36 @@ func (m *ModuleInstance) LookupFunction(t *TableInstance, typeId FunctionTypeID,
 	}
 }
 
+// CallIndirect implements the same method as documented on api.Module.
+func (m *ModuleInstance) CallIndirect(ctx context.Context, tableIdx, entryIdx, typeIdx uint32, params ...uint64) ([]uint64, error) {
+	if int(tableIdx) >= len(m.Tables) {
+		return nil, fmt.Errorf("table[%d] out of range", tableIdx)
+	} else if t := m.Tables[tableIdx]; t.Type != RefTypeFuncref {
+		return nil, fmt.Errorf("table[%d] has type %s, but call_indirect requires funcref", tableIdx, RefTypeName(t.Type))
+	} else if int(typeIdx) >= len(m.TypeIDs) {
+		return nil, fmt.Errorf("type[%d] out of range", typeIdx)
+	}
+	f, err := m.lookupIndirect(m.Tables[tableIdx], m.TypeIDs[typeIdx], entryIdx)
+	if err != nil {
+		return nil, err
+	}
+	return f.Call(ctx, params...)
+}
+
+// lookupIndirect is like LookupFunction, except it returns the trap of call_indirect as an error instead of panicking.
+func (m *ModuleInstance) lookupIndirect(t *TableInstance, typeId FunctionTypeID, tableOffset Index) (f api.Function, err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			if _, ok := recovered.(*wasmruntime.Error); !ok {
+				panic(recovered)
+			}
+			// There are no frames, as no function was called yet.
+			err = wasmdebug.NewErrorBuilder().FromRecovered(recovered)
+		}
+	}()
+	return m.LookupFunction(t, typeId, tableOffset), nil
+}
+
 // lookedUpGoFunction implements lookedUpGoModuleFunction.
 type lookedUpGoFunction struct {
 	internalapi.WazeroOnly
@@ -67,6 +99,9 @@ func (l *lookedUpGoFunction) Call(ctx context.Context, params ...uint64) ([]uint
 
 // CallWithStack implements api.Function.
 func (l *lookedUpGoFunction) CallWithStack(ctx context.Context, stack []uint64) error {
+	if _, _, err := SplitCallStack(l.def.Functype, stack); err != nil {
+		return err
+	}
 	// The Go host function always needs to access caller's module, in this case the one holding the table.
 	l.g.Call(ctx, l.lookedUpModule, stack)
 	return nil
@@ -21,7 +21,8 @@ func TestModuleInstance_LookupFunction(t *testing.T) {
 				called++
 			})},
 		},
-		FunctionDefinitionSection: []FunctionDefinition{{}, {}},
+		TypeSection:     []FunctionType{{}},
+		FunctionSection: []Index{0, 0},
 	}
 
 	me := &mockModuleEngine{
@@ -36,7 +37,7 @@ func TestModuleInstance_LookupFunction(t *testing.T) {
 		gf, ok := m.LookupFunction(nil, 0, 0).(*lookedUpGoFunction)
 		require.True(t, ok)
 		require.Nil(t, gf.lookedUpModule) // GoFunction doesn't need looked up module.
-		require.Equal(t, &hostModule.FunctionDefinitionSection[0], gf.def)
+		require.Equal(t, hostModule.FunctionDefinition(0), gf.def)
 		err := gf.CallWithStack(context.Background(), nil)
 		require.NoError(t, err)
 
@@ -44,7 +45,7 @@ func TestModuleInstance_LookupFunction(t *testing.T) {
 		require.True(t, ok)
 		require.Equal(t, m, gmf.lookedUpModule)
 		require.Equal(t, hostModule.CodeSection[1].GoFunc, gmf.g)
-		require.Equal(t, &hostModule.FunctionDefinitionSection[1], gmf.def)
+		require.Equal(t, hostModule.FunctionDefinition(1), gmf.def)
 		err = gmf.CallWithStack(context.Background(), nil)
 		require.NoError(t, err)
 
@@ -93,4 +94,20 @@ func Test_lookedUpGoModuleFunction(t *testing.T) {
 	require.True(t, called)
 	require.Equal(t, []uint64{1}, result)
 	require.Equal(t, 1, len(result))
+
+	t.Run("CallWithStack", func(t *testing.T) {
+		called = false
+		stack := []uint64{math.MaxUint64, math.Float64bits(math.Pi)}
+		err := l.CallWithStack(context.Background(), stack)
+		require.NoError(t, err)
+		require.True(t, called)
+		require.Equal(t, uint64(1), stack[0])
+
+		t.Run("errs when not enough parameters", func(t *testing.T) {
+			called = false
+			err := l.CallWithStack(context.Background(), []uint64{1})
+			require.EqualError(t, err, "need 2 params, but stack size is 1")
+			require.False(t, called)
+		})
+	})
 }
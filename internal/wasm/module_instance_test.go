@@ -385,6 +385,77 @@ func TestModuleInstance_CloseModuleOnCanceledOrTimeout(t *testing.T) {
 	})
 }
 
+func TestModuleInstance_MaxCallDepth(t *testing.T) {
+	cc := &ModuleInstance{ModuleName: "test"}
+	require.Zero(t, cc.MaxCallDepth())
+
+	cc.Sys = internalsys.DefaultContext(nil)
+	require.Zero(t, cc.MaxCallDepth())
+
+	cc.Sys.SetMaxCallDepth(10)
+	require.Equal(t, uint32(10), cc.MaxCallDepth())
+}
+
+func TestModuleInstance_Fuel(t *testing.T) {
+	cc := &ModuleInstance{ModuleName: "test"}
+	_, limited := cc.Fuel()
+	require.False(t, limited)
+
+	cc.Sys = internalsys.DefaultContext(nil)
+	_, limited = cc.Fuel()
+	require.False(t, limited)
+
+	cc.Sys.SetFuel(10)
+	fuel, limited := cc.Fuel()
+	require.True(t, limited)
+	require.Equal(t, uint64(10), fuel)
+}
+
+func TestModuleInstance_FailIfClosedOrOutOfFuel(t *testing.T) {
+	s := newStore()
+	t.Run("unlimited", func(t *testing.T) {
+		cc := &ModuleInstance{ModuleName: "test", s: s, Sys: internalsys.DefaultContext(nil)}
+		for i := 0; i < 10; i++ {
+			require.NoError(t, cc.FailIfClosedOrOutOfFuel(1))
+		}
+		require.False(t, cc.IsClosed())
+	})
+
+	t.Run("fuel exhausted", func(t *testing.T) {
+		cc := &ModuleInstance{ModuleName: "test", s: s, Sys: internalsys.DefaultContext(nil)}
+		cc.Sys.SetFuel(2)
+
+		require.NoError(t, cc.FailIfClosedOrOutOfFuel(1))
+		require.NoError(t, cc.FailIfClosedOrOutOfFuel(1))
+
+		err := cc.FailIfClosedOrOutOfFuel(1)
+		require.EqualError(t, err, "module closed with fuel exhausted")
+
+		// The resource must be closed in FailIfClosed.
+		require.Nil(t, cc.Sys)
+
+		// Subsequent calls fail the same way.
+		require.EqualError(t, cc.FailIfClosedOrOutOfFuel(1), "module closed with fuel exhausted")
+	})
+
+	t.Run("more than remaining", func(t *testing.T) {
+		cc := &ModuleInstance{ModuleName: "test", s: s, Sys: internalsys.DefaultContext(nil)}
+		cc.Sys.SetFuel(5)
+
+		require.NoError(t, cc.FailIfClosedOrOutOfFuel(3))
+		require.EqualError(t, cc.FailIfClosedOrOutOfFuel(3), "module closed with fuel exhausted")
+	})
+
+	t.Run("closed", func(t *testing.T) {
+		cc := &ModuleInstance{ModuleName: "test", s: s, Sys: internalsys.DefaultContext(nil)}
+		cc.Sys.SetFuel(1)
+		require.NoError(t, cc.CloseWithExitCode(testCtx, 2))
+
+		err := cc.FailIfClosedOrOutOfFuel(1)
+		require.EqualError(t, err, "module closed with exit_code(2)")
+	})
+}
+
 func TestModuleInstance_CloseWithCtxErr(t *testing.T) {
 	s := newStore()
 
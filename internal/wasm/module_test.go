@@ -2,6 +2,7 @@ package wasm
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"math"
 	"testing"
@@ -88,7 +89,7 @@ func TestMemory_Validate(t *testing.T) {
 		{
 			name:        "cap > maxLimit",
 			mem:         &Memory{Min: 2, Cap: math.MaxUint32, Max: 2},
-			expectedErr: "capacity 4294967295 pages (3 Ti) over limit of 65536 pages (4 Gi)",
+			expectedErr: "memory capacity pages: 4294967295 over limit of 65536",
 		},
 		{
 			name:        "max < min",
@@ -98,12 +99,12 @@ func TestMemory_Validate(t *testing.T) {
 		{
 			name:        "min > limit",
 			mem:         &Memory{Min: math.MaxUint32},
-			expectedErr: "min 4294967295 pages (3 Ti) over limit of 65536 pages (4 Gi)",
+			expectedErr: "memory min pages: 4294967295 over limit of 65536",
 		},
 		{
 			name:        "max > limit",
 			mem:         &Memory{Max: math.MaxUint32, IsMaxEncoded: true},
-			expectedErr: "max 4294967295 pages (3 Ti) over limit of 65536 pages (4 Gi)",
+			expectedErr: "memory max pages: 4294967295 over limit of 65536",
 		},
 	}
 
@@ -342,13 +343,24 @@ func TestModule_Validate_Errors(t *testing.T) {
 			},
 			expectedErr: "invalid start function: func[0] has an invalid type",
 		},
+		{
+			name: "global.set on an immutable imported global",
+			input: &Module{
+				ImportSection:     []Import{{Type: ExternTypeGlobal, Module: "env", Name: "g", DescGlobal: GlobalType{ValType: ValueTypeI32}}},
+				ImportGlobalCount: 1,
+				TypeSection:       []FunctionType{v_v},
+				FunctionSection:   []uint32{0},
+				CodeSection:       []Code{{Body: []byte{OpcodeI32Const, 1, OpcodeGlobalSet, 0, OpcodeEnd}}},
+			},
+			expectedErr: "invalid function[0]: global.set on immutable global[0]",
+		},
 	}
 
 	for _, tt := range tests {
 		tc := tt
 
 		t.Run(tc.name, func(t *testing.T) {
-			err := tc.input.Validate(api.CoreFeaturesV1)
+			err := tc.input.Validate(api.CoreFeaturesV1, MaximumBlockNestingDepth)
 			require.EqualError(t, err, tc.expectedErr)
 		})
 	}
@@ -396,7 +408,7 @@ func TestModule_validateStartSection(t *testing.T) {
 func TestModule_validateGlobals(t *testing.T) {
 	t.Run("too many globals", func(t *testing.T) {
 		m := Module{}
-		err := m.validateGlobals(make([]GlobalType, 10), 0, 9)
+		err := m.validateGlobals(api.CoreFeaturesV2, make([]GlobalType, 10), 0, 9)
 		require.Error(t, err)
 		require.EqualError(t, err, "too many globals in a module")
 	})
@@ -408,7 +420,7 @@ func TestModule_validateGlobals(t *testing.T) {
 				Init: ConstantExpression{Opcode: OpcodeGlobalGet, Data: []byte{1}},
 			},
 		}}
-		err := m.validateGlobals(nil, 0, 9)
+		err := m.validateGlobals(api.CoreFeaturesV2, nil, 0, 9)
 		require.Error(t, err)
 		require.EqualError(t, err, "global index out of range")
 	})
@@ -419,7 +431,7 @@ func TestModule_validateGlobals(t *testing.T) {
 				Init: ConstantExpression{Opcode: OpcodeUnreachable},
 			},
 		}}
-		err := m.validateGlobals(nil, 0, 9)
+		err := m.validateGlobals(api.CoreFeaturesV2, nil, 0, 9)
 		require.Error(t, err)
 		require.EqualError(t, err, "invalid opcode for const expression: 0x0")
 	})
@@ -430,7 +442,7 @@ func TestModule_validateGlobals(t *testing.T) {
 				Init: ConstantExpression{Opcode: OpcodeI32Const, Data: const0},
 			},
 		}}
-		err := m.validateGlobals(nil, 0, 9)
+		err := m.validateGlobals(api.CoreFeaturesV2, nil, 0, 9)
 		require.NoError(t, err)
 	})
 	t.Run("ok with imported global", func(t *testing.T) {
@@ -449,11 +461,234 @@ func TestModule_validateGlobals(t *testing.T) {
 			{ValType: ValueTypeI32}, // Imported one.
 			{},                      // the local one trying to validate.
 		}
-		err := m.validateGlobals(globalDeclarations, 0, 9)
+		err := m.validateGlobals(api.CoreFeaturesV2, globalDeclarations, 0, 9)
 		require.NoError(t, err)
 	})
 }
 
+func TestModule_validateGlobals_ExtendedConst(t *testing.T) {
+	extendedConst := api.CoreFeaturesV2 | experimental.CoreFeaturesExtendedConst
+	i32 := GlobalType{ValType: ValueTypeI32}
+	// global.get 0, i32.const 1, i32.add
+	addOne := ConstantExpression{Opcode: OpcodeI32Add, Data: []byte{OpcodeGlobalGet, 0, OpcodeI32Const, 1}}
+
+	tests := []struct {
+		name        string
+		globals     []GlobalType
+		inits       []ConstantExpression
+		features    api.CoreFeatures
+		expectedErr string
+	}{
+		{
+			name:    "backward reference",
+			globals: []GlobalType{i32, i32},
+			inits: []ConstantExpression{
+				{Opcode: OpcodeI32Const, Data: const0},
+				addOne,
+			},
+			features: extendedConst,
+		},
+		{
+			name:    "backward reference with global.get",
+			globals: []GlobalType{i32, i32},
+			inits: []ConstantExpression{
+				{Opcode: OpcodeI32Const, Data: const0},
+				{Opcode: OpcodeGlobalGet, Data: []byte{0}},
+			},
+			features: extendedConst,
+		},
+		{
+			name:    "backward reference without the feature",
+			globals: []GlobalType{i32, i32},
+			inits: []ConstantExpression{
+				{Opcode: OpcodeI32Const, Data: const0},
+				{Opcode: OpcodeGlobalGet, Data: []byte{0}},
+			},
+			features:    api.CoreFeaturesV2,
+			expectedErr: "global index out of range",
+		},
+		{
+			name:    "forward reference",
+			globals: []GlobalType{i32, i32},
+			inits: []ConstantExpression{
+				{Opcode: OpcodeI32Add, Data: []byte{OpcodeGlobalGet, 1, OpcodeI32Const, 1}},
+				{Opcode: OpcodeI32Const, Data: const0},
+			},
+			features:    extendedConst,
+			expectedErr: "global index out of range",
+		},
+		{
+			name:        "self reference",
+			globals:     []GlobalType{i32},
+			inits:       []ConstantExpression{addOne},
+			features:    extendedConst,
+			expectedErr: "global index out of range",
+		},
+		{
+			name:    "mutable reference",
+			globals: []GlobalType{{ValType: ValueTypeI32, Mutable: true}, i32},
+			inits: []ConstantExpression{
+				{Opcode: OpcodeI32Const, Data: const0},
+				addOne,
+			},
+			features:    extendedConst,
+			expectedErr: "const expression reads mutable global[0]",
+		},
+		{
+			name:    "type mismatch",
+			globals: []GlobalType{i32},
+			inits: []ConstantExpression{
+				{Opcode: OpcodeI32Add, Data: []byte{OpcodeI32Const, 1, OpcodeI64Const, 1}},
+			},
+			features:    extendedConst,
+			expectedErr: "cannot use i32.add in const expression: operands must be two i32 values",
+		},
+		{
+			name:    "result type mismatch",
+			globals: []GlobalType{{ValType: ValueTypeI64}},
+			inits: []ConstantExpression{
+				{Opcode: OpcodeI32Add, Data: []byte{OpcodeI32Const, 1, OpcodeI32Const, 1}},
+			},
+			features:    extendedConst,
+			expectedErr: "const expression type mismatch expected i64 but got i32",
+		},
+		{
+			name:    "more than one value",
+			globals: []GlobalType{i32},
+			inits: []ConstantExpression{
+				{Opcode: OpcodeI32Add, Data: []byte{OpcodeI32Const, 1, OpcodeI32Const, 1, OpcodeI32Const, 1}},
+			},
+			features:    extendedConst,
+			expectedErr: "const expression must produce a single value, but produces 2",
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			m := Module{}
+			for i := range tc.inits {
+				m.GlobalSection = append(m.GlobalSection, Global{Type: tc.globals[i], Init: tc.inits[i]})
+			}
+			err := m.validateGlobals(tc.features, tc.globals, 0, 9)
+			if tc.expectedErr == "" {
+				require.NoError(t, err)
+			} else {
+				require.EqualError(t, err, tc.expectedErr)
+			}
+		})
+	}
+}
+
+func TestModule_validateValueTypes(t *testing.T) {
+	noSIMD := api.CoreFeaturesV2.SetEnabled(api.CoreFeatureSIMD, false)
+	tests := []struct {
+		name        string
+		input       *Module
+		features    api.CoreFeatures
+		expectedErr string
+	}{
+		{
+			name:     "v128 enabled",
+			input:    &Module{TypeSection: []FunctionType{{Params: []ValueType{ValueTypeV128}}}},
+			features: api.CoreFeaturesV2,
+		},
+		{
+			name:        "v128 param",
+			input:       &Module{TypeSection: []FunctionType{{}, {Params: []ValueType{i32, ValueTypeV128}}}},
+			features:    noSIMD,
+			expectedErr: `type[1]: v128 invalid as feature "simd" is disabled`,
+		},
+		{
+			name:        "v128 result",
+			input:       &Module{TypeSection: []FunctionType{{Results: []ValueType{ValueTypeV128}}}},
+			features:    noSIMD,
+			expectedErr: `type[0]: v128 invalid as feature "simd" is disabled`,
+		},
+		{
+			name: "v128 imported global",
+			input: &Module{ImportSection: []Import{
+				{Type: ExternTypeFunc},
+				{Type: ExternTypeGlobal, DescGlobal: GlobalType{ValType: ValueTypeV128}},
+			}},
+			features:    noSIMD,
+			expectedErr: `import[1]: v128 invalid as feature "simd" is disabled`,
+		},
+		{
+			name:        "v128 global",
+			input:       &Module{GlobalSection: []Global{{Type: GlobalType{ValType: ValueTypeV128}}}},
+			features:    noSIMD,
+			expectedErr: `global[0]: v128 invalid as feature "simd" is disabled`,
+		},
+		{
+			name:        "v128 local",
+			input:       &Module{CodeSection: []Code{{LocalTypes: []ValueType{i32, ValueTypeV128}}}},
+			features:    noSIMD,
+			expectedErr: `invalid function[0]: local v128 invalid as feature "simd" is disabled`,
+		},
+		{
+			name:        "externref param",
+			input:       &Module{TypeSection: []FunctionType{{Params: []ValueType{ValueTypeExternref}}}},
+			features:    api.CoreFeaturesV1,
+			expectedErr: `type[0]: externref invalid as feature "reference-types" is disabled`,
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.input.validateValueTypes(tc.features)
+			if tc.expectedErr == "" {
+				require.NoError(t, err)
+			} else {
+				require.EqualError(t, err, tc.expectedErr)
+			}
+		})
+	}
+}
+
+func TestModule_ValidateAll(t *testing.T) {
+	m := &Module{
+		TypeSection: []FunctionType{v_v},
+		ImportSection: []Import{
+			{Module: "", Name: "a", Type: ExternTypeFunc},
+			{Module: "env", Name: "b", Type: ExternTypeFunc},
+			{Module: "", Name: "c", Type: ExternTypeFunc},
+		},
+		ImportFunctionCount: 3,
+		FunctionSection:     []Index{0, 0, 0},
+		CodeSection: []Code{
+			{Body: []byte{OpcodeI32Const, 0, OpcodeI64Const, 0, OpcodeI32Add, OpcodeEnd}},
+			{Body: []byte{OpcodeEnd}},
+			{Body: []byte{OpcodeNop, 0xff, OpcodeEnd}},
+		},
+	}
+
+	errs := m.ValidateAll(api.CoreFeaturesV2, MaximumBlockNestingDepth)
+	require.Equal(t, 4, len(errs))
+	require.EqualError(t, errs[0], "import[0] has an empty module name")
+	require.EqualError(t, errs[1], "import[2] has an empty module name")
+	require.EqualError(t, errs[2], "invalid function[0]: cannot pop the 1st operand for i32.add: type mismatch: expected i32, but was i64")
+	require.EqualError(t, errs[3], "invalid function[2]: invalid instruction 0xff")
+
+	var instructionErr *InstructionError
+	require.True(t, errors.As(errs[2], &instructionErr))
+	require.Equal(t, Index(0), instructionErr.Index)
+	require.Equal(t, uint64(4), instructionErr.Offset)
+	require.True(t, errors.As(errs[3], &instructionErr))
+	require.Equal(t, Index(2), instructionErr.Index)
+	require.Equal(t, uint64(1), instructionErr.Offset)
+
+	// Validate returns the first error.
+	require.Equal(t, errs[0], m.Validate(api.CoreFeaturesV2, MaximumBlockNestingDepth))
+
+	// An import whose type is out of range stops validation, as calls to it can't be validated.
+	m.ImportSection[1].DescFunc = 1
+	errs = m.ValidateAll(api.CoreFeaturesV2, MaximumBlockNestingDepth)
+	require.Equal(t, 2, len(errs))
+	require.EqualError(t, errs[1], `invalid import["env"."b"] function: type index out of range`)
+}
+
 func TestModule_validateFunctions(t *testing.T) {
 	t.Run("ok", func(t *testing.T) {
 		m := Module{
@@ -461,14 +696,33 @@ func TestModule_validateFunctions(t *testing.T) {
 			FunctionSection: []uint32{0},
 			CodeSection:     []Code{{Body: []byte{OpcodeI32Const, 0, OpcodeDrop, OpcodeEnd}}},
 		}
-		err := m.validateFunctions(api.CoreFeaturesV1, nil, nil, nil, nil, MaximumFunctionIndex)
+		err := m.validateFunctions(api.CoreFeaturesV1, nil, nil, nil, nil, MaximumFunctions, MaximumBlockNestingDepth, nil)
 		require.NoError(t, err)
 	})
 	t.Run("too many functions", func(t *testing.T) {
 		m := Module{}
-		err := m.validateFunctions(api.CoreFeaturesV1, []uint32{1, 2, 3, 4}, nil, nil, nil, 3)
+		err := m.validateFunctions(api.CoreFeaturesV1, []uint32{1, 2, 3}, nil, nil, nil, 3, MaximumBlockNestingDepth, nil)
+		require.NoError(t, err)
+		err = m.validateFunctions(api.CoreFeaturesV1, []uint32{1, 2, 3, 4}, nil, nil, nil, 3, MaximumBlockNestingDepth, nil)
 		require.Error(t, err)
-		require.EqualError(t, err, "too many functions (4) in a module")
+		require.EqualError(t, err, "too many functions in a module: 4 given with limit 3")
+	})
+	t.Run("too many locals", func(t *testing.T) {
+		i32_v := FunctionType{Params: []ValueType{i32}}
+		m := Module{
+			TypeSection:     []FunctionType{i32_v},
+			FunctionSection: []uint32{0},
+			CodeSection:     []Code{{LocalTypes: make([]ValueType, MaximumLocals-1), Body: []byte{OpcodeEnd}}},
+		}
+		for i := range m.CodeSection[0].LocalTypes {
+			m.CodeSection[0].LocalTypes[i] = i32
+		}
+		err := m.validateFunctions(api.CoreFeaturesV1, []uint32{0}, nil, nil, nil, MaximumFunctions, MaximumBlockNestingDepth, nil)
+		require.NoError(t, err)
+
+		m.CodeSection[0].LocalTypes = append(m.CodeSection[0].LocalTypes, i32)
+		err = m.validateFunctions(api.CoreFeaturesV1, []uint32{0}, nil, nil, nil, MaximumFunctions, MaximumBlockNestingDepth, nil)
+		require.EqualError(t, err, "invalid function[0]: too many locals: 50001 given with limit 50000")
 	})
 	t.Run("function, but no code", func(t *testing.T) {
 		m := Module{
@@ -476,7 +730,7 @@ func TestModule_validateFunctions(t *testing.T) {
 			FunctionSection: []Index{0},
 			CodeSection:     nil,
 		}
-		err := m.validateFunctions(api.CoreFeaturesV1, nil, nil, nil, nil, MaximumFunctionIndex)
+		err := m.validateFunctions(api.CoreFeaturesV1, nil, nil, nil, nil, MaximumFunctions, MaximumBlockNestingDepth, nil)
 		require.Error(t, err)
 		require.EqualError(t, err, "code count (0) != function count (1)")
 	})
@@ -486,7 +740,7 @@ func TestModule_validateFunctions(t *testing.T) {
 			FunctionSection: []Index{1},
 			CodeSection:     []Code{{Body: []byte{OpcodeEnd}}},
 		}
-		err := m.validateFunctions(api.CoreFeaturesV1, nil, nil, nil, nil, MaximumFunctionIndex)
+		err := m.validateFunctions(api.CoreFeaturesV1, nil, nil, nil, nil, MaximumFunctions, MaximumBlockNestingDepth, nil)
 		require.Error(t, err)
 		require.EqualError(t, err, "invalid function[0]: type section index 1 out of range")
 	})
@@ -496,7 +750,7 @@ func TestModule_validateFunctions(t *testing.T) {
 			FunctionSection: []Index{0},
 			CodeSection:     []Code{{Body: []byte{OpcodeF32Abs}}},
 		}
-		err := m.validateFunctions(api.CoreFeaturesV1, nil, nil, nil, nil, MaximumFunctionIndex)
+		err := m.validateFunctions(api.CoreFeaturesV1, nil, nil, nil, nil, MaximumFunctions, MaximumBlockNestingDepth, nil)
 		require.Error(t, err)
 		require.Contains(t, err.Error(), "invalid function[0]: cannot pop the 1st f32 operand")
 	})
@@ -507,7 +761,7 @@ func TestModule_validateFunctions(t *testing.T) {
 			CodeSection:     []Code{{Body: []byte{OpcodeF32Abs}}},
 			ExportSection:   []Export{{Name: "f1", Type: ExternTypeFunc, Index: 0}},
 		}
-		err := m.validateFunctions(api.CoreFeaturesV1, nil, nil, nil, nil, MaximumFunctionIndex)
+		err := m.validateFunctions(api.CoreFeaturesV1, nil, nil, nil, nil, MaximumFunctions, MaximumBlockNestingDepth, nil)
 		require.Error(t, err)
 		require.Contains(t, err.Error(), `invalid function[0] export["f1"]: cannot pop the 1st f32`)
 	})
@@ -520,7 +774,7 @@ func TestModule_validateFunctions(t *testing.T) {
 			CodeSection:         []Code{{Body: []byte{OpcodeF32Abs}}},
 			ExportSection:       []Export{{Name: "f1", Type: ExternTypeFunc, Index: 1}},
 		}
-		err := m.validateFunctions(api.CoreFeaturesV1, nil, nil, nil, nil, MaximumFunctionIndex)
+		err := m.validateFunctions(api.CoreFeaturesV1, nil, nil, nil, nil, MaximumFunctions, MaximumBlockNestingDepth, nil)
 		require.Error(t, err)
 		require.Contains(t, err.Error(), `invalid function[0] export["f1"]: cannot pop the 1st f32`)
 	})
@@ -534,7 +788,7 @@ func TestModule_validateFunctions(t *testing.T) {
 				{Name: "f2", Type: ExternTypeFunc, Index: 0},
 			},
 		}
-		err := m.validateFunctions(api.CoreFeaturesV1, nil, nil, nil, nil, MaximumFunctionIndex)
+		err := m.validateFunctions(api.CoreFeaturesV1, nil, nil, nil, nil, MaximumFunctions, MaximumBlockNestingDepth, nil)
 		require.Error(t, err)
 		require.Contains(t, err.Error(), `invalid function[0] export["f1","f2"]: cannot pop the 1st f32`)
 	})
@@ -634,11 +888,74 @@ func TestModule_validateImports(t *testing.T) {
 			if tc.i != nil {
 				m.ImportSection = []Import{*tc.i}
 			}
-			err := m.validateImports(tc.enabledFeatures)
+			err := m.validateImports(tc.enabledFeatures, nil)
+			if tc.expectedErr != "" {
+				require.EqualError(t, err, tc.expectedErr)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestModule_validateTags(t *testing.T) {
+	exceptionHandling := api.CoreFeaturesV2 | experimental.CoreFeaturesExceptionHandling
+	i32_v := FunctionType{Params: []ValueType{ValueTypeI32}}
+	v_i32 := FunctionType{Results: []ValueType{ValueTypeI32}}
+	tests := []struct {
+		name        string
+		input       *Module
+		features    api.CoreFeatures
+		expectedErr string
+	}{
+		{
+			name:  "none",
+			input: &Module{},
+		},
+		{
+			name: "imported and defined",
+			input: &Module{
+				TypeSection:    []FunctionType{i32_v},
+				ImportSection:  []Import{{Module: "m", Name: "t", Type: ExternTypeTag}},
+				ImportTagCount: 1,
+				TagSection:     []Index{0},
+			},
+		},
+		{
+			name:        "disabled",
+			input:       &Module{TypeSection: []FunctionType{i32_v}, TagSection: []Index{0}},
+			features:    api.CoreFeaturesV2,
+			expectedErr: `tags invalid as feature "exception-handling" is disabled`,
+		},
+		{
+			name:        "type out of range",
+			input:       &Module{TypeSection: []FunctionType{i32_v}, TagSection: []Index{1}},
+			expectedErr: "invalid tag[0]: type index 1 out of range",
+		},
+		{
+			name: "imported with results",
+			input: &Module{
+				TypeSection:    []FunctionType{v_i32},
+				ImportSection:  []Import{{Module: "m", Name: "t", Type: ExternTypeTag}},
+				ImportTagCount: 1,
+			},
+			expectedErr: `invalid import["m"."t"] tag: type v_i32 must have no results`,
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			features := tc.features
+			if features == 0 {
+				features = exceptionHandling
+			}
+			err := tc.input.validateTags(features)
 			if tc.expectedErr != "" {
 				require.EqualError(t, err, tc.expectedErr)
 			} else {
 				require.NoError(t, err)
+				require.Equal(t, len(tc.input.TagSection) > 0 || tc.input.ImportTagCount > 0, tc.input.UsesExceptionHandling)
 			}
 		})
 	}
@@ -838,7 +1155,7 @@ func TestModule_buildGlobals(t *testing.T) {
 func TestModule_buildMemoryInstance(t *testing.T) {
 	t.Run("nil", func(t *testing.T) {
 		m := ModuleInstance{}
-		m.buildMemory(&Module{})
+		require.NoError(t, m.buildMemory(&Module{}, 0, nil))
 		require.Nil(t, m.MemoryInstance)
 	})
 	t.Run("non-nil", func(t *testing.T) {
@@ -846,15 +1163,108 @@ func TestModule_buildMemoryInstance(t *testing.T) {
 		max := uint32(10)
 		mDef := MemoryDefinition{moduleName: "foo"}
 		m := ModuleInstance{}
-		m.buildMemory(&Module{
+		require.NoError(t, m.buildMemory(&Module{
 			MemorySection:           &Memory{Min: min, Cap: min, Max: max},
 			MemoryDefinitionSection: []MemoryDefinition{mDef},
-		})
+		}, 0, nil))
 		mem := m.MemoryInstance
 		require.Equal(t, min, mem.Min)
 		require.Equal(t, max, mem.Max)
 		require.Equal(t, &mDef, mem.definition)
 	})
+	t.Run("min pages", func(t *testing.T) {
+		module := &Module{
+			MemorySection:           &Memory{Min: 1, Cap: 1, Max: 10},
+			MemoryDefinitionSection: []MemoryDefinition{{}},
+		}
+		for _, tc := range []struct {
+			name                      string
+			minPages, pages, capacity uint32
+		}{
+			{name: "below min", minPages: 0, pages: 1, capacity: 1},
+			{name: "above min", minPages: 4, pages: 4, capacity: 4},
+			{name: "max", minPages: 10, pages: 10, capacity: 10},
+		} {
+			m := ModuleInstance{}
+			require.NoError(t, m.buildMemory(module, tc.minPages, nil), tc.name)
+			mem := m.MemoryInstance
+			require.Equal(t, MemoryPagesToBytesNum(tc.pages), uint64(len(mem.Buffer)), tc.name)
+			require.Equal(t, MemoryPagesToBytesNum(tc.capacity), uint64(cap(mem.Buffer)), tc.name)
+			require.Equal(t, uint32(1), mem.Min, tc.name)
+			require.Equal(t, uint32(10), mem.Max, tc.name)
+		}
+
+		m := ModuleInstance{}
+		err := m.buildMemory(module, 11, nil)
+		require.EqualError(t, err, "memory min pages: 11 over limit of 10")
+	})
+	t.Run("buffer", func(t *testing.T) {
+		module := &Module{
+			MemorySection:           &Memory{Min: 1, Cap: 1, Max: 10},
+			MemoryDefinitionSection: []MemoryDefinition{{}},
+		}
+		for _, tc := range []struct {
+			name                      string
+			minPages, bufferPages     uint32
+			pages, capacity, growable uint32
+		}{
+			{name: "min", minPages: 0, bufferPages: 1, pages: 1, capacity: 1, growable: 0},
+			{name: "capacity", minPages: 0, bufferPages: 4, pages: 1, capacity: 4, growable: 3},
+			{name: "min pages", minPages: 2, bufferPages: 4, pages: 2, capacity: 4, growable: 2},
+			{name: "over max", minPages: 0, bufferPages: 12, pages: 1, capacity: 10, growable: 9},
+		} {
+			buffer := make([]byte, MemoryPagesToBytesNum(tc.bufferPages))
+			for i := range buffer {
+				buffer[i] = 0xff
+			}
+			m := ModuleInstance{s: newStore()}
+			require.NoError(t, m.buildMemory(module, tc.minPages, buffer), tc.name)
+			mem := m.MemoryInstance
+			require.Equal(t, MemoryPagesToBytesNum(tc.pages), uint64(len(mem.Buffer)), tc.name)
+			require.Equal(t, MemoryPagesToBytesNum(tc.capacity), uint64(cap(mem.Buffer)), tc.name)
+			require.Equal(t, &buffer[0], &mem.Buffer[0], tc.name)
+			require.Equal(t, make([]byte, cap(mem.Buffer)), mem.Buffer[:cap(mem.Buffer)], tc.name)
+
+			_, ok := mem.Grow(tc.growable + 1)
+			require.False(t, ok, tc.name)
+			_, ok = mem.Grow(tc.growable)
+			require.True(t, ok, tc.name)
+			require.Equal(t, &buffer[0], &mem.Buffer[0], tc.name)
+
+			mem.release()
+			require.Nil(t, mem.Buffer, tc.name)
+		}
+
+		t.Run("in use", func(t *testing.T) {
+			s := newStore()
+			buffer := make([]byte, MemoryPageSize)
+			m := ModuleInstance{s: s}
+			require.NoError(t, m.buildMemory(module, 0, buffer))
+
+			other := ModuleInstance{s: s}
+			require.EqualError(t, other.buildMemory(module, 0, buffer), "memory buffer is in use by another module")
+
+			m.returnBorrowedBuffer()
+			require.Nil(t, m.borrowedBuffer)
+			require.NoError(t, other.buildMemory(module, 0, buffer))
+		})
+
+		for _, tc := range []struct {
+			name        string
+			minPages    uint32
+			buffer      []byte
+			expectedErr string
+		}{
+			{name: "unaligned", buffer: make([]byte, MemoryPageSize+1), expectedErr: "memory buffer size 65537 isn't a multiple of the page size"},
+			{name: "empty", buffer: []byte{}, expectedErr: "memory buffer of 0 pages is less than the minimum of 1 pages"},
+			{name: "min pages", minPages: 3, buffer: make([]byte, 2*MemoryPageSize), expectedErr: "memory buffer of 2 pages is less than the minimum of 3 pages"},
+			{name: "over max", minPages: 11, buffer: make([]byte, 11*MemoryPageSize), expectedErr: "memory min pages: 11 over limit of 10"},
+		} {
+			m := ModuleInstance{s: newStore()}
+			err := m.buildMemory(module, tc.minPages, tc.buffer)
+			require.EqualError(t, err, tc.expectedErr, tc.name)
+		}
+	})
 }
 
 func TestModule_validateDataCountSection(t *testing.T) {
@@ -1012,9 +1422,9 @@ func TestModule_declaredFunctionIndexes(t *testing.T) {
 }
 
 func TestModule_AssignModuleID(t *testing.T) {
-	getID := func(bin []byte, lsns []experimental.FunctionListener, withEnsureTermination bool) ModuleID {
+	getID := func(bin []byte, lsns []experimental.FunctionListener, withEnsureTermination, withCanonicalNaN bool) ModuleID {
 		m := Module{}
-		m.AssignModuleID(bin, lsns, withEnsureTermination)
+		m.AssignModuleID(bin, lsns, withEnsureTermination, withCanonicalNaN)
 		return m.ID
 	}
 
@@ -1025,10 +1435,13 @@ func TestModule_AssignModuleID(t *testing.T) {
 	for i, tc := range []struct {
 		bin                   []byte
 		withEnsureTermination bool
+		withCanonicalNaN      bool
 		listeners             []experimental.FunctionListener
 	}{
 		{bin: []byte{1, 2, 3}, withEnsureTermination: false},
 		{bin: []byte{1, 2, 3}, withEnsureTermination: true},
+		{bin: []byte{1, 2, 3}, withCanonicalNaN: true},
+		{bin: []byte{1, 2, 3}, withEnsureTermination: true, withCanonicalNaN: true},
 		{
 			bin:                   []byte{1, 2, 3},
 			listeners:             []experimental.FunctionListener{ml},
@@ -1082,7 +1495,7 @@ func TestModule_AssignModuleID(t *testing.T) {
 			withEnsureTermination: false,
 		},
 	} {
-		id := getID(tc.bin, tc.listeners, tc.withEnsureTermination)
+		id := getID(tc.bin, tc.listeners, tc.withEnsureTermination, tc.withCanonicalNaN)
 		_, exist := exists[id]
 		require.False(t, exist, i)
 		exists[id] = struct{}{}
@@ -0,0 +1,45 @@
+package wasm
+
+import (
+	"context"
+	"runtime/pprof"
+
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/internal/internalapi"
+)
+
+// PprofLabelKey is the key of the pprof label set by pprofFunction to the debug name of the function.
+const PprofLabelKey = "wasm_function"
+
+// pprofFunction calls a function in pprof.Do, so that CPU profile samples taken during the call carry its labels.
+type pprofFunction struct {
+	internalapi.WazeroOnlyType
+
+	fn     api.Function
+	labels pprof.LabelSet
+}
+
+func newPprofFunction(fn api.Function) *pprofFunction {
+	return &pprofFunction{fn: fn, labels: pprof.Labels(PprofLabelKey, fn.Definition().DebugName())}
+}
+
+// Definition implements the same method as documented on api.Function.
+func (f *pprofFunction) Definition() api.FunctionDefinition {
+	return f.fn.Definition()
+}
+
+// Call implements the same method as documented on api.Function.
+func (f *pprofFunction) Call(ctx context.Context, params ...uint64) (results []uint64, err error) {
+	pprof.Do(ctx, f.labels, func(ctx context.Context) {
+		results, err = f.fn.Call(ctx, params...)
+	})
+	return
+}
+
+// CallWithStack implements the same method as documented on api.Function.
+func (f *pprofFunction) CallWithStack(ctx context.Context, stack []uint64) (err error) {
+	pprof.Do(ctx, f.labels, func(ctx context.Context) {
+		err = f.fn.CallWithStack(ctx, stack)
+	})
+	return
+}
@@ -3,6 +3,7 @@ package wasm
 import (
 	"context"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"sync"
 	"sync/atomic"
@@ -47,6 +48,10 @@ type (
 		// EnabledFeatures are read-only to allow optimizations.
 		EnabledFeatures api.CoreFeatures
 
+		// MemorySanitizer is true when the memories defined by modules of an Engine implementing MemorySanitizer track
+		// which of their bytes are initialized, so that reads of others trap. See MemoryInstance.Sanitized.
+		MemorySanitizer bool
+
 		// Engine is a global context for a Store which is in responsible for compilation and execution of Wasm modules.
 		Engine Engine
 
@@ -60,6 +65,13 @@ type (
 
 		// mux is used to guard the fields from concurrent access.
 		mux sync.RWMutex
+
+		// borrowedBuffers holds the first byte of each buffer backing the memory of a module which isn't closed yet,
+		// so that two modules don't use the same buffer. See InstantiateOptions.MemoryBuffer.
+		borrowedBuffers map[*byte]struct{} // guarded by borrowedBuffersMux
+
+		// borrowedBuffersMux is separate from mux, as modules return their buffer on close while mux may be held.
+		borrowedBuffersMux sync.Mutex
 	}
 
 	// ModuleInstance represents instantiated wasm module.
@@ -95,6 +107,11 @@ type (
 		// or external objects (unimplemented).
 		ElementInstances []ElementInstance
 
+		// Tags holds the tags of the exception handling proposal, which begin with the imported ones.
+		//
+		// Note: this is after the fields whose offsets are hard-coded in the compiler engine.
+		Tags []*TagInstance
+
 		// Sys is exposed for use in special imports such as WASI, assemblyscript
 		// and gojs.
 		//
@@ -119,6 +136,8 @@ type (
 
 		// s is the Store on which this module is instantiated.
 		s *Store
+		// engine is the Engine which compiled Source, which is usually Store.Engine.
+		engine Engine
 		// prev and next hold the nodes in the linked list of ModuleInstance held by Store.
 		prev, next *ModuleInstance
 		// Source is a pointer to the Module from which this ModuleInstance derives.
@@ -126,6 +145,70 @@ type (
 
 		// CloseNotifier is an experimental hook called once on close.
 		CloseNotifier close.Notifier
+
+		// ZeroMemoryOnClose is true when the memories defined by this module are zeroed on close.
+		ZeroMemoryOnClose bool
+
+		// StrictFloat is true when calls into this module run with platform.EnterStrictFloat.
+		StrictFloat bool
+
+		// PprofLabels is true when calls of exported functions run in pprof.Do with a label naming the function.
+		// See pprofFunction.
+		PprofLabels bool
+
+		// HostPanicRecovery converts a panic of a host function called by this module into an error when non-nil.
+		// See CallHostFunction.
+		HostPanicRecovery func(recovered interface{}) error
+
+		// HostCallLogger is called with the params and results of each host function called by this module when
+		// non-nil. See CallHostFunction.
+		HostCallLogger func(module, name string, params, results []uint64)
+
+		// NewSysContext returns a Sys for a clone of this module, or nil when it cannot be cloned. See Clone.
+		NewSysContext func() (*internalsys.Context, error)
+
+		// AdditionalMemories are the memories after MemoryInstance in the memory index space, which only exist
+		// when experimental.CoreFeaturesMultiMemory is enabled. See Module.AdditionalMemorySection.
+		AdditionalMemories []*MemoryInstance
+
+		// Differential is non-nil when this module is also instantiated on another engine, so that calls to exported
+		// functions are checked against it. See Store.InstantiateDifferential.
+		Differential *ModuleInstance
+
+		// startCalled is true once the function of the start section was called, or if there's none. See RunStart.
+		startCalled bool
+
+		// importRenames maps the module names imported by Source to the names of the modules resolving them.
+		importRenames map[string]string
+		// importStubs maps the module names imported by Source to the modules resolving their stubbed functions.
+		// These are closed with this module.
+		importStubs map[string]*ModuleInstance
+		// minMemoryPages is the minimum pages of the memory defined by Source, if more than it declares.
+		minMemoryPages uint32
+		// memoryBufferSize is the size of the buffer the memory defined by Source is backed by, or zero if allocated.
+		memoryBufferSize int
+		// borrowedBuffer is the buffer backing the memory defined by Source until this module is closed, or nil.
+		// See Store.borrowBuffer.
+		borrowedBuffer []byte
+
+		// memoryGrowHook is the hook passed to SetMemoryGrowHook, which Clone sets on the clone as well.
+		memoryGrowHook func(mod api.Module, previousPages, newPages uint32) bool
+
+		// memoryPrefaulted is true once PrefaultMemory was called, so that Clone calls it on the clone as well.
+		memoryPrefaulted bool
+
+		// importedFunctions are the functions resolving the imported functions of Source, by import index.
+		importedFunctions []importedFunction
+
+		// hostCallStackRemaining is one more than the stack remaining in bytes, set by CallHostFunction during the
+		// call, so that the zero value is unknown. See StackRemaining.
+		hostCallStackRemaining atomic.Int64
+	}
+
+	// importedFunction is the function at index of the module resolving an import.
+	importedFunction struct {
+		module *ModuleInstance
+		index  Index
 	}
 
 	// DataInstance holds bytes corresponding to the data segment in a module.
@@ -166,21 +249,30 @@ func (m *ModuleInstance) GetFunctionTypeID(t *FunctionType) FunctionTypeID {
 func (m *ModuleInstance) buildElementInstances(elements []ElementSegment) {
 	m.ElementInstances = make([][]Reference, len(elements))
 	for i, elm := range elements {
-		if elm.Type == RefTypeFuncref && elm.Mode == ElementModePassive {
+		if elm.Mode == ElementModePassive {
 			// Only passive elements can be access as element instances.
 			// See https://www.w3.org/TR/2022/WD-wasm-core-2-20220419/syntax/modules.html#element-segments
 			inits := elm.Init
 			inst := make([]Reference, len(inits))
 			m.ElementInstances[i] = inst
-			for j, idx := range inits {
-				if idx != ElementInitNullReference {
-					inst[j] = m.Engine.FunctionInstanceReference(idx)
-				}
+			for j, init := range inits {
+				inst[j] = m.elementReference(init)
 			}
 		}
 	}
 }
 
+// elementReference returns the Reference of init, an element of ElementSegment.Init, which is either
+// ElementInitNullReference, a global wrapped with ElementInitImportedGlobalFunctionReference or a function index.
+func (m *ModuleInstance) elementReference(init Index) Reference {
+	if init == ElementInitNullReference {
+		return 0
+	} else if index, ok := unwrapElementInitGlobalReference(init); ok {
+		return Reference(m.Globals[index].Val)
+	}
+	return m.Engine.FunctionInstanceReference(init)
+}
+
 func (m *ModuleInstance) applyElements(elems []ElementSegment) {
 	for elemI := range elems {
 		elem := &elems[elemI]
@@ -189,17 +281,7 @@ func (m *ModuleInstance) applyElements(elems []ElementSegment) {
 			len(elem.Init) == 0 {
 			continue
 		}
-		var offset uint32
-		if elem.OffsetExpr.Opcode == OpcodeGlobalGet {
-			// Ignore error as it's already validated.
-			globalIdx, _, _ := leb128.LoadUint32(elem.OffsetExpr.Data)
-			global := m.Globals[globalIdx]
-			offset = uint32(global.Val)
-		} else {
-			// Ignore error as it's already validated.
-			o, _, _ := leb128.LoadInt32(elem.OffsetExpr.Data)
-			offset = uint32(o)
-		}
+		offset := uint32(executeConstExpressionOffset(m.Globals, &elem.OffsetExpr))
 
 		table := m.Tables[elem.TableIndex]
 		references := table.References
@@ -215,23 +297,19 @@ func (m *ModuleInstance) applyElements(elems []ElementSegment) {
 		}
 
 		if table.Type == RefTypeExternref {
-			for i := 0; i < len(elem.Init); i++ {
-				references[offset+uint32(i)] = Reference(0)
+			for i, init := range elem.Init {
+				var ref Reference // Only a global can initialize an externref other than null.
+				if index, ok := unwrapElementInitGlobalReference(init); ok {
+					ref = Reference(m.Globals[index].Val)
+				}
+				references[offset+uint32(i)] = ref
 			}
 		} else {
 			for i, init := range elem.Init {
 				if init == ElementInitNullReference {
 					continue
 				}
-
-				var ref Reference
-				if index, ok := unwrapElementInitGlobalReference(init); ok {
-					global := m.Globals[index]
-					ref = Reference(global.Val)
-				} else {
-					ref = m.Engine.FunctionInstanceReference(index)
-				}
-				references[offset+uint32(i)] = ref
+				references[offset+uint32(i)] = m.elementReference(init)
 			}
 		}
 	}
@@ -243,9 +321,9 @@ func (m *ModuleInstance) validateData(data []DataSegment) (err error) {
 	for i := range data {
 		d := &data[i]
 		if !d.IsPassive() {
-			offset := int(executeConstExpressionI32(m.Globals, &d.OffsetExpression))
-			ceil := offset + len(d.Init)
-			if offset < 0 || ceil > len(m.MemoryInstance.Buffer) {
+			offset := executeConstExpressionOffset(m.Globals, &d.OffsetExpression)
+			ceil := offset + int64(len(d.Init))
+			if offset < 0 || ceil > int64(len(m.MemoryAt(d.MemoryIndex).Buffer)) {
 				return fmt.Errorf("%s[%d]: out of bounds memory access", SectionIDName(SectionIDData), i)
 			}
 		}
@@ -256,18 +334,24 @@ func (m *ModuleInstance) validateData(data []DataSegment) (err error) {
 // applyData uses the given data segments and mutate the memory according to the initial contents on it
 // and populate the `DataInstances`. This is called after all the validation phase passes and out of
 // bounds memory access error here is not a validation error, but rather a runtime error.
+//
+// Only passive segments remain in `DataInstances`: active ones are dropped once applied, as if by data.drop.
+// See https://www.w3.org/TR/2022/WD-wasm-core-2-20220419/exec/modules.html#exec-instantiation
 func (m *ModuleInstance) applyData(data []DataSegment) error {
 	m.DataInstances = make([][]byte, len(data))
 	for i := range data {
 		d := &data[i]
-		m.DataInstances[i] = d.Init
-		if !d.IsPassive() {
-			offset := executeConstExpressionI32(m.Globals, &d.OffsetExpression)
-			if offset < 0 || int(offset)+len(d.Init) > len(m.MemoryInstance.Buffer) {
-				return fmt.Errorf("%s[%d]: out of bounds memory access", SectionIDName(SectionIDData), i)
-			}
-			copy(m.MemoryInstance.Buffer[offset:], d.Init)
+		if d.IsPassive() {
+			m.DataInstances[i] = d.Init
+			continue
 		}
+		offset := executeConstExpressionOffset(m.Globals, &d.OffsetExpression)
+		mem := m.MemoryAt(d.MemoryIndex)
+		if offset < 0 || offset+int64(len(d.Init)) > int64(len(mem.Buffer)) {
+			return fmt.Errorf("%s[%d]: out of bounds memory access", SectionIDName(SectionIDData), i)
+		}
+		copy(mem.Buffer[offset:], d.Init)
+		mem.MarkInitialized(uint64(offset), uint64(len(d.Init)))
 	}
 	return nil
 }
@@ -295,6 +379,37 @@ func NewStore(enabledFeatures api.CoreFeatures, engine Engine) *Store {
 	}
 }
 
+// BeforeStart is called with a module instance after its imports are resolved and its data and element segments are
+// applied, but before its start function is called. An error fails the instantiation.
+type BeforeStart func(ctx context.Context, m *ModuleInstance) error
+
+// InstantiateOptions are the options of Store.InstantiateWithOptions. The zero value instantiates the same as
+// Store.Instantiate.
+type InstantiateOptions struct {
+	// Engine is the Engine which compiled the module, or nil for Store.Engine.
+	Engine Engine
+
+	// BeforeStart is called prior to the start function when non-nil.
+	BeforeStart BeforeStart
+
+	// DeferStart is true when the start function isn't called until ModuleInstance.RunStart.
+	DeferStart bool
+
+	// ImportRenames maps imported module names to those of the modules resolving them instead.
+	ImportRenames map[string]string
+
+	// ImportStubs maps imported module names to modules whose exported functions resolve the imports of that name
+	// before ImportRenames applies. They are closed with the instantiated module.
+	ImportStubs map[string]*ModuleInstance
+
+	// MinMemoryPages is the minimum pages of the memory defined by the module when more than it declares.
+	MinMemoryPages uint32
+
+	// MemoryBuffer backs the memory defined by the module when non-nil. It can't back the memory of another module
+	// of the Store until that module is closed.
+	MemoryBuffer []byte
+}
+
 // Instantiate uses name instead of the Module.NameSection ModuleName as it allows instantiating the same module under
 // different names safely and concurrently.
 //
@@ -309,9 +424,21 @@ func (s *Store) Instantiate(
 	name string,
 	sys *internalsys.Context,
 	typeIDs []FunctionTypeID,
+) (*ModuleInstance, error) {
+	return s.InstantiateWithOptions(ctx, module, name, sys, typeIDs, InstantiateOptions{})
+}
+
+// InstantiateWithOptions is the same as Instantiate, except as configured by opts.
+func (s *Store) InstantiateWithOptions(
+	ctx context.Context,
+	module *Module,
+	name string,
+	sys *internalsys.Context,
+	typeIDs []FunctionTypeID,
+	opts InstantiateOptions,
 ) (*ModuleInstance, error) {
 	// Instantiate the module and add it to the store so that other modules can import it.
-	m, err := s.instantiate(ctx, module, name, sys, typeIDs)
+	m, err := s.instantiate(ctx, module, name, sys, typeIDs, opts)
 	if err != nil {
 		return nil, err
 	}
@@ -330,12 +457,25 @@ func (s *Store) instantiate(
 	name string,
 	sysCtx *internalsys.Context,
 	typeIDs []FunctionTypeID,
+	opts InstantiateOptions,
 ) (m *ModuleInstance, err error) {
-	m = &ModuleInstance{ModuleName: name, TypeIDs: typeIDs, Sys: sysCtx, s: s, Source: module}
+	engine := opts.Engine
+	if engine == nil {
+		engine = s.Engine
+	}
+	m = &ModuleInstance{ModuleName: name, TypeIDs: typeIDs, Sys: sysCtx, s: s, engine: engine, Source: module,
+		importRenames: opts.ImportRenames, importStubs: opts.ImportStubs, minMemoryPages: opts.MinMemoryPages,
+		memoryBufferSize: len(opts.MemoryBuffer)}
+	defer func(m *ModuleInstance) {
+		if err != nil { // The module is dropped, so don't keep its buffer in use.
+			m.returnBorrowedBuffer()
+		}
+	}(m)
 
 	m.Tables = make([]*TableInstance, int(module.ImportTableCount)+len(module.TableSection))
 	m.Globals = make([]*GlobalInstance, int(module.ImportGlobalCount)+len(module.GlobalSection))
-	m.Engine, err = s.Engine.NewModuleEngine(module, m)
+	m.Tags = make([]*TagInstance, int(module.ImportTagCount)+len(module.TagSection))
+	m.Engine, err = engine.NewModuleEngine(module, m)
 	if err != nil {
 		return nil, err
 	}
@@ -352,7 +492,13 @@ func (s *Store) instantiate(
 	}
 
 	m.buildGlobals(module, m.Engine.FunctionInstanceReference)
-	m.buildMemory(module)
+	m.buildTags(module)
+	if err = m.buildMemory(module, opts.MinMemoryPages, opts.MemoryBuffer); err != nil {
+		return nil, err
+	}
+	if _, ok := engine.(MemorySanitizer); ok && s.MemorySanitizer {
+		m.enableMemorySanitizer()
+	}
 	m.Exports = module.Exports
 
 	// As of reference types proposal, data segment validation must happen after instantiation,
@@ -372,39 +518,98 @@ func (s *Store) instantiate(
 		return nil, err
 	}
 
+	m.markStaticDataInitialized()
+
 	m.applyElements(module.ElementSection)
 
 	m.Engine.DoneInstantiation()
 
+	if opts.BeforeStart != nil {
+		if err = opts.BeforeStart(ctx, m); err != nil {
+			return nil, err
+		}
+	}
+
+	if !opts.DeferStart {
+		if err = m.RunStart(ctx); err != nil {
+			return nil, err
+		}
+	}
+	return
+}
+
+// RunStart implements the same method as documented on api.Module.
+func (m *ModuleInstance) RunStart(ctx context.Context) error {
+	if m.startCalled {
+		return nil
+	}
+	m.startCalled = true
+
 	// Execute the start function.
-	if module.StartSection != nil {
+	if module := m.Source; module.StartSection != nil {
 		funcIdx := *module.StartSection
 		ce := m.Engine.NewFunction(funcIdx)
-		_, err = ce.Call(ctx)
+		_, err := ce.Call(ctx)
 		if exitErr, ok := err.(*sys.ExitError); ok { // Don't wrap an exit error!
-			return nil, exitErr
+			return exitErr
 		} else if err != nil {
-			return nil, fmt.Errorf("start %s failed: %w", module.funcDesc(SectionIDFunction, funcIdx), err)
+			return &api.StartFunctionTrapError{
+				Module:   m.ModuleName,
+				Function: "start " + module.funcDesc(SectionIDFunction, funcIdx),
+				Cause:    err,
+			}
 		}
 	}
-	return
+	if m.Differential != nil {
+		return m.Differential.RunStart(ctx)
+	}
+	return nil
 }
 
 func (m *ModuleInstance) resolveImports(module *Module) (err error) {
+	m.importedFunctions = make([]importedFunction, module.ImportFunctionCount)
 	for moduleName, imports := range module.ImportPerModule {
-		var importedModule *ModuleInstance
-		importedModule, err = m.s.module(moduleName)
-		if err != nil {
-			return err
+		stub := m.importStubs[moduleName]
+		if renamed, ok := m.importRenames[moduleName]; ok {
+			// Renaming imports from an instantiated module would silently shadow it.
+			if _, err = m.s.module(moduleName); err == nil {
+				return fmt.Errorf("import rename from module[%s] to module[%s] collides with instantiated module[%s]",
+					moduleName, renamed, moduleName)
+			}
+			moduleName = renamed
 		}
 
+		// The module isn't needed when all of its imports are stubbed.
+		instantiated, _ := m.s.module(moduleName)
+
 		for _, i := range imports {
+			importedModule := instantiated
+			if stub != nil && i.Type == ExternTypeFunc {
+				if _, ok := stub.Exports[i.Name]; ok {
+					importedModule = stub
+				}
+			}
+			if importedModule == nil {
+				return &api.MissingImportError{Module: moduleName, Name: i.Name, Type: i.Type, ModuleMissing: true}
+			} else if _, ok := importedModule.Exports[i.Name]; !ok {
+				return &api.MissingImportError{Module: moduleName, Name: i.Name, Type: i.Type}
+			}
 			var imported *Export
 			imported, err = importedModule.getExport(i.Name, i.Type)
 			if err != nil {
 				return
 			}
 
+			foreign := importedModule.engine != m.engine
+			if foreign {
+				if err = m.checkForeignImport(i); err != nil {
+					return
+				}
+			}
+			if err = checkExceptionHandlingImport(module, importedModule.Source, i); err != nil {
+				return
+			}
+
 			switch i.Type {
 			case ExternTypeFunc:
 				expectedType := &module.TypeSection[i.DescFunc]
@@ -415,7 +620,12 @@ func (m *ModuleInstance) resolveImports(module *Module) (err error) {
 					return
 				}
 
-				m.Engine.ResolveImportedFunction(i.IndexPerType, imported.Index, importedModule.Engine)
+				m.importedFunctions[i.IndexPerType] = importedFunction{module: importedModule, index: imported.Index}
+				if foreign {
+					m.resolveForeignFunction(i, importedModule, imported.Index)
+				} else {
+					m.Engine.ResolveImportedFunction(i.IndexPerType, imported.Index, importedModule.Engine)
+				}
 			case ExternTypeTable:
 				expected := i.DescTable
 				importedTable := importedModule.Tables[imported.Index]
@@ -443,7 +653,18 @@ func (m *ModuleInstance) resolveImports(module *Module) (err error) {
 				m.Tables[i.IndexPerType] = importedTable
 			case ExternTypeMemory:
 				expected := i.DescMem
-				importedMemory := importedModule.MemoryInstance
+				importedMemory := importedModule.MemoryAt(imported.Index)
+
+				if expected.Is64 != importedMemory.Is64 {
+					err = errorInvalidImport(i, fmt.Errorf("address type mismatch: %s != %s",
+						ValueTypeName(expected.addressType()), ValueTypeName(importedMemory.addressType())))
+					return
+				}
+
+				if expected.IsShared != importedMemory.Shared {
+					err = errorInvalidImport(i, fmt.Errorf("shared mismatch: %t != %t", expected.IsShared, importedMemory.Shared))
+					return
+				}
 
 				if expected.Min > memoryBytesNumToPages(uint64(len(importedMemory.Buffer))) {
 					err = errorMinSizeMismatch(i, expected.Min, importedMemory.Min)
@@ -455,7 +676,9 @@ func (m *ModuleInstance) resolveImports(module *Module) (err error) {
 					return
 				}
 				m.MemoryInstance = importedMemory
-				m.Engine.ResolveImportedMemory(importedModule.Engine)
+				if !foreign {
+					m.Engine.ResolveImportedMemory(importedModule.Engine)
+				}
 			case ExternTypeGlobal:
 				expected := i.DescGlobal
 				importedGlobal := importedModule.Globals[imported.Index]
@@ -472,12 +695,94 @@ func (m *ModuleInstance) resolveImports(module *Module) (err error) {
 					return
 				}
 				m.Globals[i.IndexPerType] = importedGlobal
+			case ExternTypeTag:
+				expected := &module.TypeSection[i.DescTag]
+				importedTag := importedModule.Tags[imported.Index]
+				if !importedTag.Type.EqualsSignature(expected.Params, expected.Results) {
+					err = errorInvalidImport(i, fmt.Errorf("type mismatch: %s != %s", expected, importedTag.Type))
+					return
+				}
+				m.Tags[i.IndexPerType] = importedTag
 			}
 		}
 	}
 	return
 }
 
+// checkExceptionHandlingImport returns an error if the import would let a module which doesn't use exception handling
+// call a function of a module which does. Only the latter check whether their callees returned with an exception
+// being thrown, so the former would continue as if the call succeeded.
+//
+// Functions can also be shared via tables and globals holding function references, so these can only be imported
+// between modules which both, or neither, use exception handling.
+func checkExceptionHandlingImport(module, importedModule *Module, i *Import) error {
+	if module.UsesExceptionHandling == importedModule.UsesExceptionHandling {
+		return nil
+	}
+	switch i.Type {
+	case ExternTypeFunc:
+		if module.UsesExceptionHandling {
+			return nil
+		}
+	case ExternTypeTable:
+		if i.DescTable.Type != RefTypeFuncref {
+			return nil
+		}
+	case ExternTypeGlobal:
+		if i.DescGlobal.ValType != ValueTypeFuncref {
+			return nil
+		}
+	default:
+		return nil
+	}
+	return errorInvalidImport(i, errors.New("cannot share functions between modules which do and don't use exception handling"))
+}
+
+// ForeignFunctionResolver is implemented by a ModuleEngine which can import functions from a module instantiated by a
+// different Engine, e.g. the interpreter importing from a module compiled to machine code.
+type ForeignFunctionResolver interface {
+	// ResolveForeignFunction is like ModuleEngine.ResolveImportedFunction, except the function is called as a Go
+	// function: either api.GoModuleFunction, which receives the calling module, or api.GoFunction.
+	// `importedModule` and `indexInImportedModule` identify the imported function, e.g. for its definition.
+	ResolveForeignFunction(index Index, goFunc interface{}, importedModule *ModuleInstance, indexInImportedModule Index)
+}
+
+// checkForeignImport returns an error unless the import from a module of a different engine is supported.
+func (m *ModuleInstance) checkForeignImport(i *Import) error {
+	switch i.Type {
+	case ExternTypeFunc, ExternTypeMemory:
+		// Memory is engine-agnostic, but ModuleEngine.ResolveImportedMemory may not be.
+		if _, ok := m.Engine.(ForeignFunctionResolver); ok {
+			return nil
+		}
+	case ExternTypeGlobal:
+		// Globals hold references as engine-specific pointers.
+		if t := i.DescGlobal.ValType; t != ValueTypeFuncref {
+			return nil
+		}
+	}
+	return errorInvalidImport(i, errors.New("imported from a module compiled by a different engine"))
+}
+
+// resolveForeignFunction resolves an import from a module of a different engine as a Go function. Host functions are
+// imported as is, so that they receive this module as the caller. Otherwise, the function is called via its engine.
+func (m *ModuleInstance) resolveForeignFunction(i *Import, importedModule *ModuleInstance, index Index) {
+	src := importedModule.Source
+	var goFunc interface{}
+	if index >= src.ImportFunctionCount {
+		goFunc = src.CodeSection[index-src.ImportFunctionCount].GoFunc
+	}
+	if goFunc == nil {
+		f := importedModule.Engine.NewFunction(index)
+		goFunc = api.GoFunc(func(ctx context.Context, stack []uint64) {
+			if err := f.CallWithStack(ctx, stack); err != nil {
+				panic(err)
+			}
+		})
+	}
+	m.Engine.(ForeignFunctionResolver).ResolveForeignFunction(i.IndexPerType, goFunc, importedModule, index)
+}
+
 func errorMinSizeMismatch(i *Import, expected, actual uint32) error {
 	return errorInvalidImport(i, fmt.Errorf("minimum size mismatch: %d > %d", expected, actual))
 }
@@ -494,27 +799,76 @@ func errorInvalidImport(i *Import, err error) error {
 	return fmt.Errorf("import %s[%s.%s]: %w", ExternTypeName(i.Type), i.Module, i.Name, err)
 }
 
-// executeConstExpressionI32 executes the ConstantExpression which returns ValueTypeI32.
+// executeConstExpressionOffset executes the ConstantExpression which returns the offset of an active data or element
+// segment, which is ValueTypeI64 for a 64-bit memory and otherwise ValueTypeI32. An i32 offset is sign-extended, so
+// that negative offsets of either type are out of bounds. The offset of an element segment is its lower 32 bits.
 // The validity of the expression is ensured when calling this function as this is only called
 // during instantiation phrase, and the validation happens in compilation (validateConstExpression).
-func executeConstExpressionI32(importedGlobals []*GlobalInstance, expr *ConstantExpression) (ret int32) {
+func executeConstExpressionOffset(importedGlobals []*GlobalInstance, expr *ConstantExpression) (ret int64) {
 	switch expr.Opcode {
 	case OpcodeI32Const:
-		ret, _, _ = leb128.LoadInt32(expr.Data)
+		v, _, _ := leb128.LoadInt32(expr.Data)
+		ret = int64(v)
+	case OpcodeI64Const:
+		ret, _, _ = leb128.LoadInt64(expr.Data)
 	case OpcodeGlobalGet:
 		id, _, _ := leb128.LoadUint32(expr.Data)
 		g := importedGlobals[id]
-		ret = int32(g.Val)
+		if g.Type.ValType == ValueTypeI64 {
+			ret = int64(g.Val)
+		} else {
+			ret = int64(int32(g.Val))
+		}
+	case OpcodeI32Add, OpcodeI32Sub, OpcodeI32Mul:
+		ret = int64(int32(executeExtendedConstExpression(importedGlobals, expr)))
+	case OpcodeI64Add, OpcodeI64Sub, OpcodeI64Mul:
+		ret = int64(executeExtendedConstExpression(importedGlobals, expr))
 	}
 	return
 }
 
-// initialize initializes the value of this global instance given the const expr and imported globals.
+// executeExtendedConstExpression executes an expression of experimental.CoreFeaturesExtendedConst, given the globals
+// which it can read. The result of an i32 expression is in the lower 32 bits. As with the other const expressions, the
+// validity of the expression is ensured by validateConstExpression.
+func executeExtendedConstExpression(globals []*GlobalInstance, expr *ConstantExpression) uint64 {
+	var stack []uint64
+	_ = expr.extendedConstInstructions(func(op Opcode, imm uint64) error {
+		switch op {
+		case OpcodeI32Const, OpcodeI64Const:
+			stack = append(stack, imm)
+			return nil
+		case OpcodeGlobalGet:
+			stack = append(stack, globals[imm].Val)
+			return nil
+		}
+		x, y := stack[len(stack)-2], stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		switch op {
+		case OpcodeI32Add:
+			stack[len(stack)-1] = uint64(uint32(x) + uint32(y))
+		case OpcodeI32Sub:
+			stack[len(stack)-1] = uint64(uint32(x) - uint32(y))
+		case OpcodeI32Mul:
+			stack[len(stack)-1] = uint64(uint32(x) * uint32(y))
+		case OpcodeI64Add:
+			stack[len(stack)-1] = x + y
+		case OpcodeI64Sub:
+			stack[len(stack)-1] = x - y
+		case OpcodeI64Mul:
+			stack[len(stack)-1] = x * y
+		}
+		return nil
+	})
+	return stack[0]
+}
+
+// initialize initializes the value of this global instance given the const expr and the globals initialized before it.
 // funcRefResolver is called to get the actual funcref (engine specific) from the OpcodeRefFunc const expr.
 //
-// Global initialization constant expression can only reference the imported globals.
+// Global initialization constant expression can only reference the imported globals, unless
+// experimental.CoreFeaturesExtendedConst is enabled, which allows referencing any global defined before this one.
 // See the note on https://www.w3.org/TR/2019/REC-wasm-core-1-20191205/#constant-expressions%E2%91%A0
-func (g *GlobalInstance) initialize(importedGlobals []*GlobalInstance, expr *ConstantExpression, funcRefResolver func(funcIndex Index) Reference) {
+func (g *GlobalInstance) initialize(globals []*GlobalInstance, expr *ConstantExpression, funcRefResolver func(funcIndex Index) Reference) {
 	switch expr.Opcode {
 	case OpcodeI32Const:
 		// Treat constants as signed as their interpretation is not yet known per /RATIONALE.md
@@ -530,20 +884,20 @@ func (g *GlobalInstance) initialize(importedGlobals []*GlobalInstance, expr *Con
 		g.Val = binary.LittleEndian.Uint64(expr.Data)
 	case OpcodeGlobalGet:
 		id, _, _ := leb128.LoadUint32(expr.Data)
-		importedG := importedGlobals[id]
-		switch importedG.Type.ValType {
+		src := globals[id]
+		switch src.Type.ValType {
 		case ValueTypeI32:
-			g.Val = uint64(uint32(importedG.Val))
+			g.Val = uint64(uint32(src.Val))
 		case ValueTypeI64:
-			g.Val = importedG.Val
+			g.Val = src.Val
 		case ValueTypeF32:
-			g.Val = importedG.Val
+			g.Val = src.Val
 		case ValueTypeF64:
-			g.Val = importedG.Val
+			g.Val = src.Val
 		case ValueTypeV128:
-			g.Val, g.ValHi = importedG.Val, importedG.ValHi
+			g.Val, g.ValHi = src.Val, src.ValHi
 		case ValueTypeFuncref, ValueTypeExternref:
-			g.Val = importedG.Val
+			g.Val = src.Val
 		}
 	case OpcodeRefNull:
 		switch expr.Data[0] {
@@ -555,6 +909,8 @@ func (g *GlobalInstance) initialize(importedGlobals []*GlobalInstance, expr *Con
 		g.Val = uint64(funcRefResolver(v))
 	case OpcodeVecV128Const:
 		g.Val, g.ValHi = binary.LittleEndian.Uint64(expr.Data[0:8]), binary.LittleEndian.Uint64(expr.Data[8:16])
+	case OpcodeI32Add, OpcodeI32Sub, OpcodeI32Mul, OpcodeI64Add, OpcodeI64Sub, OpcodeI64Mul:
+		g.Val = executeExtendedConstExpression(globals, expr)
 	}
 }
 
@@ -599,7 +955,7 @@ func (s *Store) GetFunctionTypeID(t *FunctionType) (FunctionTypeID, error) {
 		}
 		l := len(s.typeIDs)
 		if uint32(l) >= s.functionMaxTypes {
-			return 0, fmt.Errorf("too many function types in a store")
+			return 0, &api.LimitExceededError{Resource: "function types in a store", Requested: uint64(l) + 1, Limit: uint64(s.functionMaxTypes)}
 		}
 		id = FunctionTypeID(l)
 		s.typeIDs[key] = id
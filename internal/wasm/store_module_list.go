@@ -27,7 +27,8 @@ func (s *Store) deleteModule(m *ModuleInstance) error {
 	m.prev = nil
 	m.next = nil
 
-	if m.ModuleName != "" {
+	// Only delete the name if it is this module's, e.g. not if m is a ModuleInstance.Differential.
+	if m.ModuleName != "" && s.nameToModule[m.ModuleName] == m {
 		delete(s.nameToModule, m.ModuleName)
 
 		// Shrink the map if it's allocated more than twice the size of the list
@@ -95,3 +96,28 @@ func (s *Store) Module(moduleName string) api.Module {
 	}
 	return m
 }
+
+// referencedFunction returns the module and index of the function which ref points to, following imports to the
+// module defining it, or false if no module of the store has the function.
+func (s *Store) referencedFunction(ref Reference) (*ModuleInstance, Index, bool) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+	for m := s.moduleList; m != nil; m = m.next {
+		if m.Engine == nil { // Still being instantiated.
+			continue
+		}
+		idx, ok := m.Engine.ReferencedFunctionIndex(ref)
+		if !ok {
+			continue
+		}
+		for idx < m.Source.ImportFunctionCount {
+			if int(idx) >= len(m.importedFunctions) {
+				return nil, 0, false
+			}
+			imported := m.importedFunctions[idx]
+			m, idx = imported.module, imported.index
+		}
+		return m, idx, true
+	}
+	return nil, 0, false
+}
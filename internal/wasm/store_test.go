@@ -341,6 +341,7 @@ func TestStore_Instantiate_Errors(t *testing.T) {
 				importedModuleName: {{Type: ExternTypeFunc, Module: importedModuleName, Name: "fn", DescFunc: 0}},
 				"non-exist":        {{Name: "fn", DescFunc: 0}},
 			},
+			ImportFunctionCount: 2,
 		}, importingModuleName, nil, nil)
 		require.EqualError(t, err, "module[non-exist] not instantiated")
 	})
@@ -398,7 +399,7 @@ func TestStore_Instantiate_Errors(t *testing.T) {
 		}
 
 		_, err = s.Instantiate(testCtx, importingModule, importingModuleName, nil, []FunctionTypeID{0})
-		require.EqualError(t, err, "start function[1] failed: call failed")
+		require.EqualError(t, err, "module[test] start function[1] failed: call failed")
 	})
 }
 
@@ -471,6 +472,16 @@ func (e *mockModuleEngine) FunctionInstanceReference(i Index) Reference {
 	return e.functionRefs[i]
 }
 
+// ReferencedFunctionIndex implements the same method as documented on wasm.ModuleEngine.
+func (e *mockModuleEngine) ReferencedFunctionIndex(ref Reference) (Index, bool) {
+	for i, r := range e.functionRefs {
+		if r == ref {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
 // ResolveImportedFunction implements the same method as documented on wasm.ModuleEngine.
 func (e *mockModuleEngine) ResolveImportedFunction(index, importedIndex Index, _ ModuleEngine) {
 	e.resolveImportsCalled[index] = importedIndex
@@ -680,6 +691,57 @@ func TestGlobalInstance_initialize(t *testing.T) {
 		require.Equal(t, uint64(0x1), g.Val)
 		require.Equal(t, uint64(0x2), g.ValHi)
 	})
+	t.Run("extended const", func(t *testing.T) {
+		globals := []*GlobalInstance{
+			{Val: 10, Type: GlobalType{ValType: ValueTypeI32}},
+			{Val: 1 << 40, Type: GlobalType{ValType: ValueTypeI64}},
+		}
+		tests := []struct {
+			name           string
+			valType        ValueType
+			expr           *ConstantExpression
+			expected       uint64
+			expectedOffset int64
+		}{
+			{
+				name:    "i32.add",
+				valType: ValueTypeI32,
+				// global.get 0, i32.const 1, i32.add
+				expr:           &ConstantExpression{Opcode: OpcodeI32Add, Data: []byte{OpcodeGlobalGet, 0, OpcodeI32Const, 1}},
+				expected:       11,
+				expectedOffset: 11,
+			},
+			{
+				name:    "i32.sub wraps",
+				valType: ValueTypeI32,
+				// global.get 0, i32.const 11, i32.sub
+				expr:           &ConstantExpression{Opcode: OpcodeI32Sub, Data: []byte{OpcodeGlobalGet, 0, OpcodeI32Const, 11}},
+				expected:       math.MaxUint32,
+				expectedOffset: -1,
+			},
+			{
+				name:    "i64.mul",
+				valType: ValueTypeI64,
+				// global.get 1, i64.const 3, i64.mul, i64.const 1, i64.add
+				expr: &ConstantExpression{Opcode: OpcodeI64Add, Data: []byte{
+					OpcodeGlobalGet, 1, OpcodeI64Const, 3, OpcodeI64Mul, OpcodeI64Const, 1,
+				}},
+				expected:       3<<40 + 1,
+				expectedOffset: 3<<40 + 1,
+			},
+		}
+
+		for _, tt := range tests {
+			tc := tt
+			t.Run(tc.name, func(t *testing.T) {
+				g := &GlobalInstance{Type: GlobalType{ValType: tc.valType}}
+				g.initialize(globals, tc.expr, nil)
+				require.Equal(t, tc.expected, g.Val)
+				// An i32 offset is sign-extended.
+				require.Equal(t, tc.expectedOffset, executeConstExpressionOffset(globals, tc.expr))
+			})
+		}
+	})
 }
 
 func Test_resolveImports(t *testing.T) {
@@ -772,6 +834,7 @@ func Test_resolveImports(t *testing.T) {
 			s.nameToModule[moduleName] = &ModuleInstance{
 				Globals: []*GlobalInstance{g},
 				Exports: map[string]*Export{name: {Type: ExternTypeGlobal, Index: 0}}, ModuleName: moduleName,
+				Source: &Module{},
 			}
 			err := m.resolveImports(
 				&Module{
@@ -790,6 +853,7 @@ func Test_resolveImports(t *testing.T) {
 					Index: 0,
 				}},
 				ModuleName: moduleName,
+				Source:     &Module{},
 			}
 			m := &ModuleInstance{Globals: make([]*GlobalInstance, 1), s: s}
 			err := m.resolveImports(&Module{
@@ -808,6 +872,7 @@ func Test_resolveImports(t *testing.T) {
 					Index: 0,
 				}},
 				ModuleName: moduleName,
+				Source:     &Module{},
 			}
 			m := &ModuleInstance{Globals: make([]*GlobalInstance, 1), s: s}
 			err := m.resolveImports(&Module{
@@ -818,6 +883,99 @@ func Test_resolveImports(t *testing.T) {
 			require.EqualError(t, err, "import global[test.target]: value type mismatch: f64 != i32")
 		})
 	})
+	t.Run("tag", func(t *testing.T) {
+		t.Run("ok", func(t *testing.T) {
+			s := newStore()
+			tag := &TagInstance{Type: &FunctionType{Params: []ValueType{i32}}}
+			s.nameToModule[moduleName] = &ModuleInstance{
+				Tags:       []*TagInstance{tag},
+				Exports:    map[string]*Export{name: {Type: ExternTypeTag, Index: 0}},
+				ModuleName: moduleName,
+				Source:     &Module{UsesExceptionHandling: true},
+			}
+			m := &ModuleInstance{Tags: make([]*TagInstance, 1), s: s}
+			err := m.resolveImports(&Module{
+				TypeSection:           []FunctionType{{Params: []ValueType{i32}}},
+				ImportPerModule:       map[string][]*Import{moduleName: {{Module: moduleName, Name: name, Type: ExternTypeTag, DescTag: 0}}},
+				UsesExceptionHandling: true,
+			})
+			require.NoError(t, err)
+			require.Equal(t, tag, m.Tags[0])
+		})
+		t.Run("type mismatch", func(t *testing.T) {
+			s := newStore()
+			s.nameToModule[moduleName] = &ModuleInstance{
+				Tags:       []*TagInstance{{Type: &FunctionType{Params: []ValueType{i32}}}},
+				Exports:    map[string]*Export{name: {Type: ExternTypeTag, Index: 0}},
+				ModuleName: moduleName,
+				Source:     &Module{UsesExceptionHandling: true},
+			}
+			m := &ModuleInstance{Tags: make([]*TagInstance, 1), s: s}
+			err := m.resolveImports(&Module{
+				TypeSection:           []FunctionType{{Params: []ValueType{i64}}},
+				ImportPerModule:       map[string][]*Import{moduleName: {{Module: moduleName, Name: name, Type: ExternTypeTag, DescTag: 0}}},
+				UsesExceptionHandling: true,
+			})
+			require.EqualError(t, err, "import tag[test.target]: type mismatch: i64_v != i32_v")
+		})
+	})
+	t.Run("exception handling", func(t *testing.T) {
+		for _, tc := range []struct {
+			name                           string
+			importerUsesEH, importeeUsesEH bool
+			imp                            *Import
+			expectedErr                    string
+		}{
+			{
+				name:           "func into module using it",
+				importerUsesEH: true,
+				imp:            &Import{Type: ExternTypeFunc},
+			},
+			{
+				name:           "func from module using it",
+				importeeUsesEH: true,
+				imp:            &Import{Type: ExternTypeFunc},
+				expectedErr:    "import func[test.target]: cannot share functions between modules which do and don't use exception handling",
+			},
+			{
+				name:           "funcref table",
+				importerUsesEH: true,
+				imp:            &Import{Type: ExternTypeTable, DescTable: Table{Type: RefTypeFuncref}},
+				expectedErr:    "import table[test.target]: cannot share functions between modules which do and don't use exception handling",
+			},
+			{
+				name:           "externref table",
+				importerUsesEH: true,
+				imp:            &Import{Type: ExternTypeTable, DescTable: Table{Type: RefTypeExternref}},
+			},
+			{
+				name:           "funcref global",
+				importeeUsesEH: true,
+				imp:            &Import{Type: ExternTypeGlobal, DescGlobal: GlobalType{ValType: ValueTypeFuncref}},
+				expectedErr:    "import global[test.target]: cannot share functions between modules which do and don't use exception handling",
+			},
+			{
+				name:           "i32 global",
+				importeeUsesEH: true,
+				imp:            &Import{Type: ExternTypeGlobal, DescGlobal: GlobalType{ValType: ValueTypeI32}},
+			},
+		} {
+			tc := tc
+			t.Run(tc.name, func(t *testing.T) {
+				tc.imp.Module, tc.imp.Name = moduleName, name
+				err := checkExceptionHandlingImport(
+					&Module{UsesExceptionHandling: tc.importerUsesEH},
+					&Module{UsesExceptionHandling: tc.importeeUsesEH},
+					tc.imp,
+				)
+				if tc.expectedErr != "" {
+					require.EqualError(t, err, tc.expectedErr)
+				} else {
+					require.NoError(t, err)
+				}
+			})
+		}
+	})
 	t.Run("memory", func(t *testing.T) {
 		t.Run("ok", func(t *testing.T) {
 			max := uint32(10)
@@ -830,6 +988,7 @@ func Test_resolveImports(t *testing.T) {
 					Type: ExternTypeMemory,
 				}},
 				ModuleName: moduleName,
+				Source:     &Module{},
 				Engine:     importedME,
 			}
 			m := &ModuleInstance{s: s, Engine: &mockModuleEngine{resolveImportsCalled: map[Index]Index{}}}
@@ -842,6 +1001,42 @@ func Test_resolveImports(t *testing.T) {
 			require.Equal(t, m.MemoryInstance, memoryInst)
 			require.Equal(t, importedME, m.Engine.(*mockModuleEngine).importedMemModEngine)
 		})
+		t.Run("address type mismatch", func(t *testing.T) {
+			s := newStore()
+			s.nameToModule[moduleName] = &ModuleInstance{
+				MemoryInstance: &MemoryInstance{Max: 10},
+				Exports: map[string]*Export{name: {
+					Type: ExternTypeMemory,
+				}},
+				ModuleName: moduleName,
+				Source:     &Module{},
+			}
+			m := &ModuleInstance{s: s}
+			err := m.resolveImports(&Module{
+				ImportPerModule: map[string][]*Import{
+					moduleName: {{Module: moduleName, Name: name, Type: ExternTypeMemory, DescMem: &Memory{Max: 10, Is64: true}}},
+				},
+			})
+			require.EqualError(t, err, "import memory[test.target]: address type mismatch: i64 != i32")
+		})
+		t.Run("shared mismatch", func(t *testing.T) {
+			s := newStore()
+			s.nameToModule[moduleName] = &ModuleInstance{
+				MemoryInstance: &MemoryInstance{Max: 10},
+				Exports: map[string]*Export{name: {
+					Type: ExternTypeMemory,
+				}},
+				ModuleName: moduleName,
+				Source:     &Module{},
+			}
+			m := &ModuleInstance{s: s}
+			err := m.resolveImports(&Module{
+				ImportPerModule: map[string][]*Import{
+					moduleName: {{Module: moduleName, Name: name, Type: ExternTypeMemory, DescMem: &Memory{Max: 10, IsShared: true}}},
+				},
+			})
+			require.EqualError(t, err, "import memory[test.target]: shared mismatch: true != false")
+		})
 		t.Run("minimum size mismatch", func(t *testing.T) {
 			importMemoryType := &Memory{Min: 2, Cap: 2}
 			s := newStore()
@@ -851,6 +1046,7 @@ func Test_resolveImports(t *testing.T) {
 					Type: ExternTypeMemory,
 				}},
 				ModuleName: moduleName,
+				Source:     &Module{},
 			}
 			m := &ModuleInstance{s: s}
 			err := m.resolveImports(&Module{
@@ -868,6 +1064,7 @@ func Test_resolveImports(t *testing.T) {
 					Type: ExternTypeMemory,
 				}},
 				ModuleName: moduleName,
+				Source:     &Module{},
 			}
 
 			max := uint32(10)
@@ -930,11 +1127,13 @@ func TestModuleInstance_applyData(t *testing.T) {
 		m := &ModuleInstance{MemoryInstance: &MemoryInstance{Buffer: make([]byte, 10)}}
 		err := m.applyData([]DataSegment{
 			{OffsetExpression: ConstantExpression{Opcode: OpcodeI32Const, Data: const0}, Init: []byte{0xa, 0xf}},
+			{Passive: true, Init: []byte{0x2, 0x3}},
 			{OffsetExpression: ConstantExpression{Opcode: OpcodeI32Const, Data: leb128.EncodeUint32(8)}, Init: []byte{0x1, 0x5}},
 		})
 		require.NoError(t, err)
 		require.Equal(t, []byte{0xa, 0xf, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x1, 0x5}, m.MemoryInstance.Buffer)
-		require.Equal(t, [][]byte{{0xa, 0xf}, {0x1, 0x5}}, m.DataInstances)
+		// Passive segments aren't applied, and active ones are dropped once applied.
+		require.Equal(t, [][]byte{nil, {0x2, 0x3}, nil}, m.DataInstances)
 	})
 	t.Run("error", func(t *testing.T) {
 		m := &ModuleInstance{MemoryInstance: &MemoryInstance{Buffer: make([]byte, 5)}}
@@ -977,6 +1176,12 @@ func TestModuleInstance_applyElements(t *testing.T) {
 			{Mode: ElementModeActive, OffsetExpr: ConstantExpression{Opcode: OpcodeI32Const, Data: []byte{5}}, Init: make([]Index, 5)},
 		})
 		require.Equal(t, []Reference{0, 0, 0, 0xffff, 0xffff, 0, 0, 0, 0, 0}, m.Tables[0].References)
+
+		m.Globals = []*GlobalInstance{{Val: 0xabcde}}
+		m.applyElements([]ElementSegment{
+			{Mode: ElementModeActive, OffsetExpr: ConstantExpression{Opcode: OpcodeI32Const, Data: []byte{3}}, Init: []Index{ElementInitNullReference, 0 | ElementInitImportedGlobalFunctionReference}},
+		})
+		require.Equal(t, []Reference{0, 0, 0, 0, 0xabcde, 0, 0, 0, 0, 0}, m.Tables[0].References)
 	})
 	t.Run("funcref", func(t *testing.T) {
 		e := &mockEngine{}
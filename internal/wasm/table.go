@@ -5,6 +5,7 @@ import (
 	"math"
 
 	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/internal/internalapi"
 	"github.com/tetratelabs/wazero/internal/leb128"
 )
 
@@ -147,6 +148,7 @@ func (m *Module) validateTable(enabledFeatures api.CoreFeatures, tables []Table,
 	// Create bounds checks as these can err prior to instantiation
 	funcCount := m.ImportFunctionCount + m.SectionElementCount(SectionIDFunction)
 	globalsCount := m.ImportGlobalCount + m.SectionElementCount(SectionIDGlobal)
+	var globals []GlobalType // Only read when an element is initialized by global.get.
 
 	// Now, we have to figure out which table elements can be resolved before instantiation and also fail early if there
 	// are any imported globals that are known to be invalid by their declarations.
@@ -155,28 +157,29 @@ func (m *Module) validateTable(enabledFeatures api.CoreFeatures, tables []Table,
 		idx := Index(i)
 		initCount := uint32(len(elem.Init))
 
-		if elem.Type == RefTypeFuncref {
-			// Any offset applied is to the element, not the function index: validate here if the funcidx is sound.
-			for ei, init := range elem.Init {
-				if init == ElementInitNullReference {
-					continue
+		// Any offset applied is to the element, not the function index: validate here if the funcidx is sound.
+		for ei, init := range elem.Init {
+			if init == ElementInitNullReference {
+				continue
+			}
+			index, ok := unwrapElementInitGlobalReference(init)
+			if ok {
+				if index >= globalsCount {
+					return fmt.Errorf("%s[%d].init[%d] globalidx %d out of range", SectionIDName(SectionIDElement), idx, ei, index)
 				}
-				index, ok := unwrapElementInitGlobalReference(init)
-				if ok {
-					if index >= globalsCount {
-						return fmt.Errorf("%s[%d].init[%d] globalidx %d out of range", SectionIDName(SectionIDElement), idx, ei, index)
-					}
-				} else {
-					if index >= funcCount {
-						return fmt.Errorf("%s[%d].init[%d] funcidx %d out of range", SectionIDName(SectionIDElement), idx, ei, index)
-					}
+				if globals == nil {
+					_, globals, _, _, _ = m.AllDeclarations()
 				}
-			}
-		} else {
-			for j, elem := range elem.Init {
-				if elem != ElementInitNullReference {
-					return fmt.Errorf("%s[%d].init[%d] must be ref.null but was %v", SectionIDName(SectionIDElement), idx, j, elem)
+				if int(index) < len(globals) { // Only declared globals have a known type.
+					if vt := globals[index].ValType; vt != elem.Type {
+						return fmt.Errorf("%s[%d].init[%d] global.get %d has type %s, but the element type is %s",
+							SectionIDName(SectionIDElement), idx, ei, index, ValueTypeName(vt), RefTypeName(elem.Type))
+					}
 				}
+			} else if elem.Type != RefTypeFuncref {
+				return fmt.Errorf("%s[%d].init[%d] must be ref.null but was %v", SectionIDName(SectionIDElement), idx, ei, init)
+			} else if index >= funcCount {
+				return fmt.Errorf("%s[%d].init[%d] funcidx %d out of range", SectionIDName(SectionIDElement), idx, ei, index)
 			}
 		}
 
@@ -216,6 +219,12 @@ func (m *Module) validateTable(enabledFeatures api.CoreFeatures, tables []Table,
 						return err
 					}
 				}
+			} else if IsExtendedConstOpcode(oc) {
+				// As with global.get, the offset can read imported globals, so it is only known during initialization.
+				_, globals, _, _, _ := m.AllDeclarations()
+				if err := validateConstExpression(globals[:m.ImportGlobalCount], 0, &elem.OffsetExpr, ValueTypeI32); err != nil {
+					return fmt.Errorf("%s[%d] has an invalid const expression: %w", SectionIDName(SectionIDElement), idx, err)
+				}
 			} else {
 				return fmt.Errorf("%s[%d] has an invalid const expression: %s", SectionIDName(SectionIDElement), idx, InstructionName(oc))
 			}
@@ -247,17 +256,7 @@ func (m *ModuleInstance) buildTables(module *Module, skipBoundCheck bool) (err e
 		for elemI := range module.ElementSection { // Do not loop over the value since elementSegments is a slice of value.
 			elem := &module.ElementSection[elemI]
 			table := m.Tables[elem.TableIndex]
-			var offset uint32
-			if elem.OffsetExpr.Opcode == OpcodeGlobalGet {
-				// Ignore error as it's already validated.
-				globalIdx, _, _ := leb128.LoadUint32(elem.OffsetExpr.Data)
-				global := m.Globals[globalIdx]
-				offset = uint32(global.Val)
-			} else { // i32.const
-				// Ignore error as it's already validated.
-				o, _, _ := leb128.LoadInt32(elem.OffsetExpr.Data)
-				offset = uint32(o)
-			}
+			offset := uint32(executeConstExpressionOffset(m.Globals, &elem.OffsetExpr))
 
 			// Check to see if we are out-of-bounds
 			initCount := uint64(len(elem.Init))
@@ -325,3 +324,81 @@ func (t *TableInstance) Grow(delta uint32, initialRef Reference) (currentLen uin
 	}
 	return
 }
+
+// compile-time check to ensure exportedTable is an api.Table.
+var _ api.Table = exportedTable{}
+
+// exportedTable wraps TableInstance to implement api.Table.
+type exportedTable struct {
+	internalapi.WazeroOnlyType
+	t *TableInstance
+	s *Store
+}
+
+// Size implements the same method as documented on api.Table.
+func (t exportedTable) Size() uint32 {
+	return uint32(len(t.t.References))
+}
+
+// Grow implements the same method as documented on api.Table.
+func (t exportedTable) Grow(delta uint32, init api.Reference) (uint32, bool) {
+	ref, ok := t.reference(init)
+	if !ok {
+		return t.Size(), false
+	}
+	prev := t.t.Grow(delta, ref)
+	if prev == 0xffffffff {
+		return t.Size(), false
+	}
+	return prev, true
+}
+
+// Set implements the same method as documented on api.Table.
+func (t exportedTable) Set(idx uint32, ref api.Reference) bool {
+	r, ok := t.reference(ref)
+	if !ok || idx >= t.Size() {
+		return false
+	}
+	t.t.References[idx] = r
+	return true
+}
+
+// Entries implements the same method as documented on api.Table.
+func (t exportedTable) Entries() []api.TableEntry {
+	entries := make([]api.TableEntry, len(t.t.References))
+	for i, ref := range t.t.References {
+		switch {
+		case ref == 0:
+			entries[i].Kind = api.TableEntryNull
+		case t.t.Type == RefTypeExternref:
+			entries[i] = api.TableEntry{Kind: api.TableEntryExternref, Externref: ref}
+		default:
+			if m, idx, ok := t.s.referencedFunction(ref); ok {
+				entries[i] = api.TableEntry{
+					Kind:          api.TableEntryFunction,
+					ModuleName:    m.ModuleName,
+					FunctionIndex: idx,
+					Host:          m.Source.IsHostModule,
+				}
+			} else {
+				entries[i].Kind = api.TableEntryUnresolved
+			}
+		}
+	}
+	return entries
+}
+
+// reference converts ref to a Reference, returning false if it doesn't match
+// the element type of the table.
+func (t exportedTable) reference(ref api.Reference) (Reference, bool) {
+	if fn := ref.Function(); fn != nil {
+		referencer, ok := fn.(FunctionReferencer)
+		if !ok || t.t.Type != RefTypeFuncref {
+			return 0, false
+		}
+		return referencer.FunctionInstanceReference(), true
+	} else if v := ref.Externref(); v != 0 {
+		return v, t.t.Type == RefTypeExternref
+	}
+	return 0, true // null is valid for any table.
+}
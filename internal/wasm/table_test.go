@@ -27,6 +27,7 @@ func Test_resolveImports_table(t *testing.T) {
 			Tables:     []*TableInstance{tableInst},
 			Exports:    map[string]*Export{name: {Type: ExternTypeTable, Index: 0}},
 			ModuleName: moduleName,
+			Source:     &Module{},
 		}
 		m := &ModuleInstance{Tables: make([]*TableInstance, 1), s: s}
 		err := m.resolveImports(&Module{
@@ -44,6 +45,7 @@ func Test_resolveImports_table(t *testing.T) {
 			Tables:     []*TableInstance{{Min: importTableType.Min - 1}},
 			Exports:    map[string]*Export{name: {Type: ExternTypeTable}},
 			ModuleName: moduleName,
+			Source:     &Module{},
 		}
 		m := &ModuleInstance{Tables: make([]*TableInstance, 1), s: s}
 		err := m.resolveImports(&Module{
@@ -61,6 +63,7 @@ func Test_resolveImports_table(t *testing.T) {
 			Tables:     []*TableInstance{{Min: importTableType.Min - 1}},
 			Exports:    map[string]*Export{name: {Type: ExternTypeTable}},
 			ModuleName: moduleName,
+			Source:     &Module{},
 		}
 		m := &ModuleInstance{Tables: make([]*TableInstance, 1), s: s}
 		err := m.resolveImports(&Module{
@@ -76,6 +79,7 @@ func Test_resolveImports_table(t *testing.T) {
 			Tables:     []*TableInstance{{Type: RefTypeFuncref}},
 			Exports:    map[string]*Export{name: {Type: ExternTypeTable}},
 			ModuleName: moduleName,
+			Source:     &Module{},
 		}
 		m := &ModuleInstance{Tables: make([]*TableInstance, 1), s: s}
 		err := m.resolveImports(&Module{
@@ -312,6 +316,41 @@ func TestModule_validateTable(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "imported global derived element offset - extended const",
+			input: &Module{
+				TypeSection: []FunctionType{{}},
+				ImportSection: []Import{
+					{Type: ExternTypeGlobal, DescGlobal: GlobalType{ValType: ValueTypeI32}},
+				},
+				ImportGlobalCount: 1,
+				TableSection:      []Table{{Type: RefTypeFuncref}},
+				FunctionSection:   []Index{0},
+				CodeSection:       []Code{codeEnd},
+				ElementSection: []ElementSegment{
+					{
+						// global.get 0, i32.const 4, i32.add
+						OffsetExpr: ConstantExpression{Opcode: OpcodeI32Add, Data: []byte{OpcodeGlobalGet, 0, OpcodeI32Const, 4}},
+						Init:       []Index{0},
+						Type:       RefTypeFuncref,
+					},
+				},
+			},
+		},
+		{
+			name: "externref element initialized by global",
+			input: &Module{
+				TableSection:  []Table{{Min: 2, Type: RefTypeExternref}},
+				GlobalSection: []Global{{Type: GlobalType{ValType: ValueTypeExternref}}},
+				ElementSection: []ElementSegment{
+					{
+						OffsetExpr: ConstantExpression{Opcode: OpcodeI32Const, Data: const0},
+						Init:       []Index{ElementInitNullReference, ElementInitImportedGlobalFunctionReference | 0},
+						Type:       RefTypeExternref,
+					},
+				},
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -548,6 +587,21 @@ func TestModule_validateTable_Errors(t *testing.T) {
 			},
 			expectedErr: "element[0].init[1] globalidx 100 out of range",
 		},
+		{
+			name: "element initialized by global of another type",
+			input: &Module{
+				TableSection:  []Table{{Min: 1, Type: RefTypeFuncref}},
+				GlobalSection: []Global{{Type: GlobalType{ValType: ValueTypeExternref}}},
+				ElementSection: []ElementSegment{
+					{
+						OffsetExpr: ConstantExpression{Opcode: OpcodeI32Const, Data: const0},
+						Init:       []Index{ElementInitImportedGlobalFunctionReference | 0},
+						Type:       RefTypeFuncref,
+					},
+				},
+			},
+			expectedErr: "element[0].init[0] global.get 0 has type externref, but the element type is funcref",
+		},
 		{
 			name: "imported global derived element offset - missing table",
 			input: &Module{
@@ -664,6 +718,43 @@ func TestModule_validateTable_Errors(t *testing.T) {
 			},
 			expectedErr: "element[0] (global.get 0): out of range of imported globals",
 		},
+		{
+			name: "extended const element offset - not imported global",
+			input: &Module{
+				TypeSection:     []FunctionType{{}},
+				TableSection:    []Table{{Type: RefTypeFuncref}},
+				FunctionSection: []Index{0},
+				GlobalSection:   []Global{{Type: GlobalType{ValType: ValueTypeI32}}},
+				CodeSection:     []Code{codeEnd},
+				ElementSection: []ElementSegment{
+					{
+						// global.get 0, i32.const 4, i32.add
+						OffsetExpr: ConstantExpression{Opcode: OpcodeI32Add, Data: []byte{OpcodeGlobalGet, 0, OpcodeI32Const, 4}},
+						Init:       []Index{0},
+						Type:       RefTypeFuncref,
+					},
+				},
+			},
+			expectedErr: "element[0] has an invalid const expression: global index out of range",
+		},
+		{
+			name: "extended const element offset - i64",
+			input: &Module{
+				TypeSection:     []FunctionType{{}},
+				TableSection:    []Table{{Type: RefTypeFuncref}},
+				FunctionSection: []Index{0},
+				CodeSection:     []Code{codeEnd},
+				ElementSection: []ElementSegment{
+					{
+						// i64.const 1, i64.const 4, i64.add
+						OffsetExpr: ConstantExpression{Opcode: OpcodeI64Add, Data: []byte{OpcodeI64Const, 1, OpcodeI64Const, 4}},
+						Init:       []Index{0},
+						Type:       RefTypeFuncref,
+					},
+				},
+			},
+			expectedErr: "element[0] has an invalid const expression: const expression type mismatch expected i32 but got i64",
+		},
 	}
 
 	for _, tt := range tests {
@@ -1073,6 +1164,71 @@ func TestTableInstance_Grow(t *testing.T) {
 	}
 }
 
+// referencedFunction is an api.Function implementing FunctionReferencer.
+type referencedFunction struct {
+	api.Function
+	ref Reference
+}
+
+// FunctionInstanceReference implements FunctionReferencer.
+func (f *referencedFunction) FunctionInstanceReference() Reference {
+	return f.ref
+}
+
+func Test_exportedTable(t *testing.T) {
+	max := uint32(3)
+	fn := &referencedFunction{ref: 0xf00}
+
+	t.Run("funcref", func(t *testing.T) {
+		table := exportedTable{t: &TableInstance{References: []Reference{0}, Max: &max, Type: RefTypeFuncref}}
+
+		size, ok := table.Grow(1, api.FuncRef(fn))
+		require.True(t, ok)
+		require.Equal(t, uint32(1), size)
+		require.Equal(t, []Reference{0, 0xf00}, table.t.References)
+
+		require.True(t, table.Set(0, api.FuncRef(fn)))
+		require.True(t, table.Set(1, api.Reference{}))
+		require.Equal(t, []Reference{0xf00, 0}, table.t.References)
+
+		// Out of range.
+		require.False(t, table.Set(2, api.FuncRef(fn)))
+
+		// Wrong type.
+		require.False(t, table.Set(0, api.ExternRef(1)))
+		size, ok = table.Grow(1, api.ExternRef(1))
+		require.False(t, ok)
+		require.Equal(t, uint32(2), size)
+
+		// Not from an engine.
+		require.False(t, table.Set(0, api.FuncRef(struct{ api.Function }{})))
+
+		// Beyond max.
+		size, ok = table.Grow(2, api.Reference{})
+		require.False(t, ok)
+		require.Equal(t, uint32(2), size)
+		size, ok = table.Grow(1, api.Reference{})
+		require.True(t, ok)
+		require.Equal(t, uint32(2), size)
+		require.Equal(t, uint32(3), table.Size())
+	})
+
+	t.Run("externref", func(t *testing.T) {
+		table := exportedTable{t: &TableInstance{Type: RefTypeExternref}}
+
+		size, ok := table.Grow(2, api.ExternRef(0xbeef))
+		require.True(t, ok)
+		require.Equal(t, uint32(0), size)
+		require.True(t, table.Set(1, api.Reference{}))
+		require.Equal(t, []Reference{0xbeef, 0}, table.t.References)
+
+		// Wrong type.
+		require.False(t, table.Set(0, api.FuncRef(fn)))
+		_, ok = table.Grow(1, api.FuncRef(fn))
+		require.False(t, ok)
+	})
+}
+
 func Test_unwrapElementInitGlobalReference(t *testing.T) {
 	actual, ok := unwrapElementInitGlobalReference(12345 | ElementInitImportedGlobalFunctionReference)
 	require.True(t, ok)
@@ -0,0 +1,15 @@
+package wasm
+
+// TagInstance is an instance of a tag, defined by the exception handling proposal. An exception is thrown with a tag,
+// and is only caught by the catch clauses of the same *TagInstance.
+type TagInstance struct {
+	// Type is the type of the tag, whose params are the values carried by its exceptions.
+	Type *FunctionType
+}
+
+// buildTags creates the instances of the tags defined by the module, which follow the imported ones.
+func (m *ModuleInstance) buildTags(module *Module) {
+	for i, typeIndex := range module.TagSection {
+		m.Tags[module.ImportTagCount+Index(i)] = &TagInstance{Type: &module.TypeSection[typeIndex]}
+	}
+}
@@ -0,0 +1,1053 @@
+// Package text decodes the WebAssembly Text Format into the same wasm.Module as the binary package, so that tests
+// and small embedded scripts can be written as text.
+//
+// See https://www.w3.org/TR/2019/REC-wasm-core-1-20191205/#text-format%E2%91%A0
+package text
+
+import (
+	"fmt"
+	"sort"
+	"unicode/utf8"
+
+	"github.com/tetratelabs/wazero/internal/leb128"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+// DecodeModule decodes the WebAssembly Text Format of a module, such as "(module (func (export "f")))".
+//
+// The result is the same as binary.DecodeModule would return for the module encoded with default features and limits,
+// except identifiers such as "$f" become a name section. Like the binary decoder, this only checks the syntax, so the
+// module still needs to be validated.
+//
+// # Notes
+//
+//   - This supports the WebAssembly 1.0 (20191205) instruction set, plus sign-extension, non-trapping float-to-int,
+//     bulk memory, reference types and multi-value. SIMD instructions are not supported.
+//   - The "module" wrapper is optional: the source can be a sequence of module fields.
+func DecodeModule(source []byte) (*wasm.Module, error) {
+	exprs, err := lex(source)
+	if err != nil {
+		return nil, err
+	}
+
+	fields, moduleName := exprs, ""
+	if len(exprs) == 1 && exprs[0].isList("module") {
+		fields = exprs[0].list[1:]
+		if len(fields) > 0 && fields[0].tokenType == tokenID {
+			moduleName, fields = fields[0].value[1:], fields[1:]
+		}
+	}
+	for _, f := range fields {
+		if f.tokenType != tokenList || len(f.list) == 0 || f.list[0].tokenType != tokenKeyword {
+			return nil, f.errorf("expected a module field, but was %s", f)
+		}
+	}
+
+	p := &moduleParser{
+		m:          &wasm.Module{},
+		types:      newNamespace("type"),
+		funcs:      newNamespace("func"),
+		tables:     newNamespace("table"),
+		memories:   newNamespace("memory"),
+		globals:    newNamespace("global"),
+		elems:      newNamespace("elem"),
+		datas:      newNamespace("data"),
+		fieldIndex: map[*sexpr]wasm.Index{},
+		moduleName: moduleName,
+	}
+	if err = p.decode(fields); err != nil {
+		return nil, err
+	}
+	return p.m, nil
+}
+
+// namespace resolves identifiers to indices in one index space, such as functions.
+type namespace struct {
+	// kind is the name of the index space used in errors, e.g. "func".
+	kind  string
+	ids   map[string]wasm.Index
+	count wasm.Index
+}
+
+func newNamespace(kind string) *namespace {
+	return &namespace{kind: kind, ids: map[string]wasm.Index{}}
+}
+
+// add returns the next index, which is associated with the identifier unless it is nil.
+func (n *namespace) add(id *sexpr) (wasm.Index, error) {
+	idx := n.count
+	n.count++
+	if id != nil {
+		if _, ok := n.ids[id.value]; ok {
+			return 0, id.errorf("duplicate %s %s", n.kind, id.value)
+		}
+		n.ids[id.value] = idx
+	}
+	return idx, nil
+}
+
+// resolve returns the index of an identifier or number.
+func (n *namespace) resolve(s *sexpr) (wasm.Index, error) {
+	switch s.tokenType {
+	case tokenID:
+		if idx, ok := n.ids[s.value]; ok {
+			return idx, nil
+		}
+		return 0, s.errorf("unknown %s %s", n.kind, s.value)
+	case tokenNum:
+		idx, err := decodeUint(s, 32)
+		return wasm.Index(idx), err
+	}
+	return 0, s.errorf("expected a %s index, but was %s", n.kind, s)
+}
+
+// nameMap returns the identifiers of the namespace without their leading '$', ordered by index.
+func (n *namespace) nameMap() (ret wasm.NameMap) {
+	for id, idx := range n.ids {
+		ret = append(ret, wasm.NameAssoc{Index: idx, Name: id[1:]})
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].Index < ret[j].Index })
+	return
+}
+
+// isIndex returns true if the expression can be resolved by a namespace.
+func isIndex(s *sexpr) bool {
+	return s.tokenType == tokenID || s.tokenType == tokenNum
+}
+
+// moduleParser decodes module fields into a wasm.Module.
+type moduleParser struct {
+	m *wasm.Module
+
+	types, funcs, tables, memories, globals, elems, datas *namespace
+
+	// fieldIndex is the index of each func, table, memory and global field in its index space.
+	fieldIndex map[*sexpr]wasm.Index
+	// funcs are the functions which are not imported, in the order of the code section.
+	definedFuncs []*definedFunc
+
+	moduleName string
+	localNames wasm.IndirectNameMap
+	// usedDataIdx is true when a function uses a data index, which requires the data count section.
+	usedDataIdx bool
+}
+
+// definedFunc is a function field which is not an import.
+type definedFunc struct {
+	idx      wasm.Index
+	paramIDs []*sexpr
+	// rest are the locals and instructions following the type use.
+	rest []*sexpr
+}
+
+// fieldHead is the common beginning of func, table, memory and global fields:
+// an optional identifier, inline exports and an optional inline import.
+type fieldHead struct {
+	id      *sexpr
+	exports []*sexpr
+	// module and name are set when the field is an inline import.
+	module, name *sexpr
+	rest         []*sexpr
+}
+
+func decodeFieldHead(f *sexpr) (h fieldHead, err error) {
+	rest := f.list[1:]
+	if len(rest) > 0 && rest[0].tokenType == tokenID {
+		h.id, rest = rest[0], rest[1:]
+	}
+	for len(rest) > 0 && rest[0].isList("export") {
+		exp := rest[0]
+		if len(exp.list) != 2 {
+			return h, exp.errorf("expected (export \"name\")")
+		}
+		h.exports, rest = append(h.exports, exp.list[1]), rest[1:]
+	}
+	if len(rest) > 0 && rest[0].isList("import") {
+		imp := rest[0]
+		if len(imp.list) != 3 {
+			return h, imp.errorf("expected (import \"module\" \"name\")")
+		}
+		h.module, h.name, rest = imp.list[1], imp.list[2], rest[1:]
+	}
+	h.rest = rest
+	return
+}
+
+func (p *moduleParser) decode(fields []*sexpr) error {
+	// Types are resolved first as any type use can refer to them, and implicit types must follow them.
+	for _, f := range fields {
+		if f.list[0].isKeyword("type") {
+			if err := p.decodeType(f); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Imports precede definitions in each index space, regardless of where they are written.
+	for _, f := range fields {
+		if err := p.decodeImport(f); err != nil {
+			return err
+		}
+	}
+	for _, f := range fields {
+		switch kind := f.list[0].value; kind {
+		case "func", "table", "memory", "global":
+			h, err := decodeFieldHead(f)
+			if err != nil {
+				return err
+			} else if h.module != nil {
+				continue
+			}
+			if p.fieldIndex[f], err = p.namespace(kind).add(h.id); err != nil {
+				return err
+			}
+			// Inline elements and data are segments without an identifier.
+			if kind == "table" && len(h.rest) == 2 && h.rest[1].isList("elem") {
+				_, _ = p.elems.add(nil)
+			} else if kind == "memory" && len(h.rest) == 1 && h.rest[0].isList("data") {
+				_, _ = p.datas.add(nil)
+			}
+		case "elem":
+			if _, err := p.elems.add(optionalID(f)); err != nil {
+				return err
+			}
+		case "data":
+			if _, err := p.datas.add(optionalID(f)); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Now that all identifiers are known, fields can be decoded in the order they are written.
+	for _, f := range fields {
+		var err error
+		switch f.list[0].value {
+		case "func", "table", "memory", "global":
+			err = p.decodeDefinition(f)
+		case "elem":
+			err = p.decodeElem(f)
+		case "data":
+			err = p.decodeData(f)
+		case "start":
+			err = p.decodeStart(f)
+		case "type", "import", "export":
+		default:
+			err = f.errorf("unknown module field %s", f.list[0].value)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, fn := range p.definedFuncs {
+		code, err := p.decodeCode(fn)
+		if err != nil {
+			return err
+		}
+		p.m.CodeSection = append(p.m.CodeSection, code)
+	}
+	if err := p.decodeExports(fields); err != nil {
+		return err
+	}
+
+	if p.usedDataIdx {
+		dataCount := uint32(len(p.m.DataSection))
+		p.m.DataCountSection = &dataCount
+	}
+	if functionNames := p.funcs.nameMap(); p.moduleName != "" || functionNames != nil || len(p.localNames) > 0 {
+		p.m.NameSection = &wasm.NameSection{ModuleName: p.moduleName, FunctionNames: functionNames}
+		if len(p.localNames) > 0 {
+			sort.Slice(p.localNames, func(i, j int) bool { return p.localNames[i].Index < p.localNames[j].Index })
+			p.m.NameSection.LocalNames = p.localNames
+		}
+	}
+	return nil
+}
+
+func (p *moduleParser) namespace(kind string) *namespace {
+	switch kind {
+	case "func":
+		return p.funcs
+	case "table":
+		return p.tables
+	case "memory":
+		return p.memories
+	default: // "global"
+		return p.globals
+	}
+}
+
+// optionalID returns the identifier following the keyword of a list, or nil if there is none.
+func optionalID(f *sexpr) *sexpr {
+	if len(f.list) > 1 && f.list[1].tokenType == tokenID {
+		return f.list[1]
+	}
+	return nil
+}
+
+// decodeType decodes a type field, such as "(type $t (func (param i32) (result i32)))".
+func (p *moduleParser) decodeType(f *sexpr) error {
+	id := optionalID(f)
+	rest := f.list[1:]
+	if id != nil {
+		rest = rest[1:]
+	}
+	if len(rest) != 1 || !rest[0].isList("func") {
+		return f.errorf("expected (func) in type")
+	}
+	params, results, _, sigRest, err := decodeSignature(rest[0].list[1:])
+	if err != nil {
+		return err
+	} else if len(sigRest) > 0 {
+		return sigRest[0].errorf("unexpected %s in type", sigRest[0])
+	}
+	if _, err = p.types.add(id); err != nil {
+		return err
+	}
+	p.addType(params, results)
+	return nil
+}
+
+// addType appends a function type to the type section, returning its index.
+func (p *moduleParser) addType(params, results []wasm.ValueType) wasm.Index {
+	ft := wasm.FunctionType{Params: params, Results: results}
+	// cache the key for the function type, as the binary decoder does.
+	_ = ft.String()
+	p.m.TypeSection = append(p.m.TypeSection, ft)
+	return wasm.Index(len(p.m.TypeSection) - 1)
+}
+
+// decodeSignature decodes parameters and results, such as "(param $x i32) (param i64 i64) (result i32)", returning
+// the identifier of each parameter or nil if it has none, and the remaining expressions.
+func decodeSignature(list []*sexpr) (params, results []wasm.ValueType, paramIDs []*sexpr, rest []*sexpr, err error) {
+	for len(list) > 0 && list[0].isList("param") {
+		types, ids, err := decodeValueTypeList(list[0], true)
+		if err != nil {
+			return nil, nil, nil, nil, err
+		}
+		params, paramIDs, list = append(params, types...), append(paramIDs, ids...), list[1:]
+	}
+	for len(list) > 0 && list[0].isList("result") {
+		types, _, err := decodeValueTypeList(list[0], false)
+		if err != nil {
+			return nil, nil, nil, nil, err
+		}
+		results, list = append(results, types...), list[1:]
+	}
+	return params, results, paramIDs, list, nil
+}
+
+// decodeValueTypeList decodes a list such as "(param $x i32)" or "(local i32 i64)", returning an identifier for each
+// type or nil if it has none.
+func decodeValueTypeList(l *sexpr, allowID bool) (types []wasm.ValueType, ids []*sexpr, err error) {
+	list := l.list[1:]
+	if allowID && len(list) > 0 && list[0].tokenType == tokenID {
+		if len(list) != 2 {
+			return nil, nil, l.errorf("expected one type after %s", list[0].value)
+		}
+		vt, err := decodeValueType(list[1])
+		return []wasm.ValueType{vt}, []*sexpr{list[0]}, err
+	}
+	for _, s := range list {
+		vt, err := decodeValueType(s)
+		if err != nil {
+			return nil, nil, err
+		}
+		types, ids = append(types, vt), append(ids, nil)
+	}
+	return
+}
+
+// decodeValueType decodes a value type, such as "i32".
+func decodeValueType(s *sexpr) (wasm.ValueType, error) {
+	if s.tokenType == tokenKeyword {
+		switch s.value {
+		case "i32":
+			return wasm.ValueTypeI32, nil
+		case "i64":
+			return wasm.ValueTypeI64, nil
+		case "f32":
+			return wasm.ValueTypeF32, nil
+		case "f64":
+			return wasm.ValueTypeF64, nil
+		case "v128":
+			return wasm.ValueTypeV128, nil
+		case "funcref":
+			return wasm.ValueTypeFuncref, nil
+		case "externref":
+			return wasm.ValueTypeExternref, nil
+		}
+	}
+	return 0, s.errorf("unknown value type %s", s)
+}
+
+// decodeRefType decodes a reference type, such as "funcref".
+func decodeRefType(s *sexpr) (wasm.RefType, error) {
+	if s.isKeyword("funcref") {
+		return wasm.RefTypeFuncref, nil
+	} else if s.isKeyword("externref") {
+		return wasm.RefTypeExternref, nil
+	}
+	return 0, s.errorf("unknown reference type %s", s)
+}
+
+// decodeTypeUse decodes an optional type reference followed by a signature, returning the index of the function
+// type. When there is no type reference, this is the index of the first equal type, which is added if not found.
+func (p *moduleParser) decodeTypeUse(list []*sexpr) (typeIdx wasm.Index, paramIDs []*sexpr, rest []*sexpr, err error) {
+	var typeRef *sexpr
+	if len(list) > 0 && list[0].isList("type") {
+		typeRef, list = list[0], list[1:]
+		if len(typeRef.list) != 2 {
+			return 0, nil, nil, typeRef.errorf("expected (type index)")
+		}
+	}
+	params, results, paramIDs, rest, err := decodeSignature(list)
+	if err != nil {
+		return 0, nil, nil, err
+	}
+
+	if typeRef == nil {
+		return p.typeIndex(params, results), paramIDs, rest, nil
+	}
+
+	if typeIdx, err = p.types.resolve(typeRef.list[1]); err != nil {
+		return 0, nil, nil, err
+	} else if int(typeIdx) >= len(p.m.TypeSection) {
+		return 0, nil, nil, typeRef.errorf("unknown type %d", typeIdx)
+	}
+	ft := &p.m.TypeSection[typeIdx]
+	if params == nil && results == nil {
+		paramIDs = make([]*sexpr, len(ft.Params))
+	} else if !ft.EqualsSignature(params, results) {
+		return 0, nil, nil, typeRef.errorf("signature doesn't match type %s", ft)
+	}
+	return typeIdx, paramIDs, rest, nil
+}
+
+// typeIndex returns the index of the first function type with the signature, adding it if there is none.
+func (p *moduleParser) typeIndex(params, results []wasm.ValueType) wasm.Index {
+	for i := range p.m.TypeSection {
+		if p.m.TypeSection[i].EqualsSignature(params, results) {
+			return wasm.Index(i)
+		}
+	}
+	return p.addType(params, results)
+}
+
+// decodeImport decodes an import field, such as "(import "env" "f" (func $f (param i32)))", or a func, table, memory
+// or global field with an inline import, such as "(func $f (import "env" "f") (param i32))".
+func (p *moduleParser) decodeImport(f *sexpr) error {
+	var kind *sexpr
+	var h fieldHead
+	switch f.list[0].value {
+	case "import":
+		if len(f.list) != 4 || f.list[3].tokenType != tokenList || len(f.list[3].list) == 0 {
+			return f.errorf("expected (import \"module\" \"name\" (desc))")
+		}
+		desc := f.list[3]
+		kind = desc.list[0]
+		var err error
+		if h, err = decodeFieldHead(desc); err != nil {
+			return err
+		} else if len(h.exports) > 0 || h.module != nil {
+			return desc.errorf("unexpected inline export or import in import")
+		}
+		h.module, h.name = f.list[1], f.list[2]
+	case "func", "table", "memory", "global":
+		var err error
+		if h, err = decodeFieldHead(f); err != nil {
+			return err
+		} else if h.module == nil {
+			return nil
+		}
+		kind = f.list[0]
+	default:
+		return nil
+	}
+
+	imp := wasm.Import{}
+	var err error
+	if imp.Module, err = decodeName(h.module); err != nil {
+		return err
+	} else if imp.Name, err = decodeName(h.name); err != nil {
+		return err
+	}
+
+	var rest []*sexpr
+	switch kind.value {
+	case "func":
+		imp.Type = wasm.ExternTypeFunc
+		var paramIDs []*sexpr
+		if imp.DescFunc, paramIDs, rest, err = p.decodeTypeUse(h.rest); err != nil {
+			return err
+		}
+		p.addLocalNames(p.funcs.count, paramIDs)
+	case "table":
+		imp.Type = wasm.ExternTypeTable
+		if imp.DescTable, rest, err = decodeTableType(kind, h.rest); err != nil {
+			return err
+		}
+	case "memory":
+		imp.Type = wasm.ExternTypeMemory
+		if imp.DescMem, rest, err = decodeMemoryType(kind, h.rest); err != nil {
+			return err
+		}
+	case "global":
+		imp.Type = wasm.ExternTypeGlobal
+		if len(h.rest) == 0 {
+			return kind.errorf("expected global type")
+		}
+		if imp.DescGlobal, err = decodeGlobalType(h.rest[0]); err != nil {
+			return err
+		}
+		rest = h.rest[1:]
+	default:
+		return kind.errorf("unknown import kind %s", kind.value)
+	}
+	if len(rest) > 0 {
+		return rest[0].errorf("unexpected %s in imported %s", rest[0], kind.value)
+	}
+
+	ns := p.namespace(kind.value)
+	if imp.IndexPerType, err = ns.add(h.id); err != nil {
+		return err
+	}
+	if f.list[0].value != "import" {
+		p.fieldIndex[f] = imp.IndexPerType
+	}
+	switch imp.Type {
+	case wasm.ExternTypeFunc:
+		p.m.ImportFunctionCount++
+	case wasm.ExternTypeTable:
+		p.m.ImportTableCount++
+	case wasm.ExternTypeMemory:
+		p.m.ImportMemoryCount++
+	case wasm.ExternTypeGlobal:
+		p.m.ImportGlobalCount++
+	}
+
+	// Pointers to imports are taken once all are added, as appending can move them.
+	p.m.ImportSection = append(p.m.ImportSection, imp)
+	p.m.ImportPerModule = make(map[string][]*wasm.Import)
+	for i := range p.m.ImportSection {
+		imp := &p.m.ImportSection[i]
+		p.m.ImportPerModule[imp.Module] = append(p.m.ImportPerModule[imp.Module], imp)
+	}
+	return nil
+}
+
+// decodeName decodes a string which must be valid UTF-8, such as an import or export name.
+func decodeName(s *sexpr) (string, error) {
+	if s.tokenType != tokenString {
+		return "", s.errorf("expected a name, but was %s", s)
+	} else if !utf8.ValidString(s.value) {
+		return "", s.errorf("name %s is not valid UTF-8", s)
+	}
+	return s.value, nil
+}
+
+// addLocalNames records the identifiers of the parameters and locals of a function, if it has any.
+func (p *moduleParser) addLocalNames(funcIdx wasm.Index, ids []*sexpr) {
+	var names wasm.NameMap
+	for i, id := range ids {
+		if id != nil {
+			names = append(names, wasm.NameAssoc{Index: wasm.Index(i), Name: id.value[1:]})
+		}
+	}
+	if names != nil {
+		p.localNames = append(p.localNames, wasm.NameMapAssoc{Index: funcIdx, NameMap: names})
+	}
+}
+
+// decodeLimits decodes a minimum followed by an optional maximum.
+func decodeLimits(list []*sexpr) (min uint32, max *uint32, rest []*sexpr, err error) {
+	if len(list) == 0 || list[0].tokenType != tokenNum {
+		return 0, nil, nil, fmt.Errorf("expected limits")
+	}
+	v, err := decodeUint(list[0], 32)
+	if err != nil {
+		return 0, nil, nil, err
+	}
+	min, list = uint32(v), list[1:]
+	if len(list) > 0 && list[0].tokenType == tokenNum {
+		if v, err = decodeUint(list[0], 32); err != nil {
+			return 0, nil, nil, err
+		}
+		m := uint32(v)
+		max, list = &m, list[1:]
+	}
+	return min, max, list, nil
+}
+
+// decodeTableType decodes limits followed by a reference type, such as "1 10 funcref".
+func decodeTableType(kind *sexpr, list []*sexpr) (t wasm.Table, rest []*sexpr, err error) {
+	if t.Min, t.Max, list, err = decodeLimits(list); err != nil {
+		return t, nil, kind.errorf("invalid table: %v", err)
+	} else if len(list) == 0 {
+		return t, nil, kind.errorf("expected reference type")
+	}
+	t.Type, err = decodeRefType(list[0])
+	return t, list[1:], err
+}
+
+// decodeMemoryType decodes limits in pages, such as "1 2", sized as the binary decoder does by default. The limits
+// may be preceded by "i64" for a 64-bit memory, and followed by "shared", which requires a max.
+func decodeMemoryType(kind *sexpr, list []*sexpr) (*wasm.Memory, []*sexpr, error) {
+	is64 := len(list) > 0 && list[0].isKeyword("i64")
+	if is64 {
+		list = list[1:]
+	}
+	min, max, rest, err := decodeLimits(list)
+	if err != nil {
+		return nil, nil, kind.errorf("invalid memory: %v", err)
+	}
+	shared := len(rest) > 0 && rest[0].isKeyword("shared")
+	if shared {
+		if max == nil {
+			return nil, nil, kind.errorf("invalid memory: shared memory must have a max")
+		}
+		rest = rest[1:]
+	}
+	mem, rest, err := newMemory(kind, min, max, rest)
+	if mem != nil {
+		mem.IsShared, mem.Is64 = shared, is64
+	}
+	return mem, rest, err
+}
+
+func newMemory(kind *sexpr, min uint32, max *uint32, rest []*sexpr) (*wasm.Memory, []*sexpr, error) {
+	mem := &wasm.Memory{Min: min, Cap: min, Max: wasm.MemoryLimitPages, IsMaxEncoded: max != nil}
+	if max != nil {
+		mem.Max = *max
+	}
+	if err := mem.Validate(wasm.MemoryLimitPages); err != nil {
+		return nil, nil, kind.errorf("invalid memory: %v", err)
+	}
+	return mem, rest, nil
+}
+
+// decodeGlobalType decodes a value type, or a mutable one such as "(mut i32)".
+func decodeGlobalType(s *sexpr) (gt wasm.GlobalType, err error) {
+	if s.isList("mut") {
+		if len(s.list) != 2 {
+			return gt, s.errorf("expected (mut type)")
+		}
+		gt.Mutable, s = true, s.list[1]
+	}
+	gt.ValType, err = decodeValueType(s)
+	return
+}
+
+// decodeDefinition decodes a func, table, memory or global field, unless it is an import.
+func (p *moduleParser) decodeDefinition(f *sexpr) error {
+	h, err := decodeFieldHead(f)
+	if err != nil {
+		return err
+	} else if h.module != nil {
+		return nil
+	}
+	kind := f.list[0]
+	switch kind.value {
+	case "func":
+		typeIdx, paramIDs, rest, err := p.decodeTypeUse(h.rest)
+		if err != nil {
+			return err
+		}
+		p.m.FunctionSection = append(p.m.FunctionSection, typeIdx)
+		p.definedFuncs = append(p.definedFuncs, &definedFunc{idx: p.fieldIndex[f], paramIDs: paramIDs, rest: rest})
+		return nil
+	case "table":
+		return p.decodeTable(f, h)
+	case "memory":
+		return p.decodeMemory(f, h)
+	default: // "global"
+		if len(h.rest) == 0 {
+			return kind.errorf("expected global type")
+		}
+		g := wasm.Global{}
+		if g.Type, err = decodeGlobalType(h.rest[0]); err != nil {
+			return err
+		} else if g.Init, err = p.decodeConstantExpression(kind, h.rest[1:]); err != nil {
+			return err
+		}
+		p.m.GlobalSection = append(p.m.GlobalSection, g)
+		return nil
+	}
+}
+
+// decodeTable decodes a table type, or a reference type followed by inline elements such as
+// "funcref (elem $f $g)", which is an abbreviation of a table sized for the elements and an active element segment.
+func (p *moduleParser) decodeTable(f *sexpr, h fieldHead) error {
+	kind := f.list[0]
+	if len(h.rest) == 2 && h.rest[1].isList("elem") {
+		refType, err := decodeRefType(h.rest[0])
+		if err != nil {
+			return err
+		}
+		init, err := p.decodeFuncIndices(h.rest[1].list[1:])
+		if err != nil {
+			return err
+		}
+		size := uint32(len(init))
+		p.m.TableSection = append(p.m.TableSection, wasm.Table{Min: size, Max: &size, Type: refType})
+		p.m.ElementSection = append(p.m.ElementSection, wasm.ElementSegment{
+			OffsetExpr: wasm.ConstantExpression{Opcode: wasm.OpcodeI32Const, Data: leb128.EncodeInt32(0)},
+			TableIndex: p.fieldIndex[f],
+			Init:       init,
+			Type:       refType,
+			Mode:       wasm.ElementModeActive,
+		})
+		return nil
+	}
+
+	t, rest, err := decodeTableType(kind, h.rest)
+	if err != nil {
+		return err
+	} else if len(rest) > 0 {
+		return rest[0].errorf("unexpected %s in table", rest[0])
+	}
+	p.m.TableSection = append(p.m.TableSection, t)
+	return nil
+}
+
+// decodeMemory decodes a memory type, or inline data such as "(data "hello")", which is an abbreviation of a memory
+// sized for the data and an active data segment.
+func (p *moduleParser) decodeMemory(f *sexpr, h fieldHead) error {
+	kind := f.list[0]
+	var mem *wasm.Memory
+	var err error
+	if len(h.rest) == 1 && h.rest[0].isList("data") {
+		init, err := decodeStrings(h.rest[0].list[1:])
+		if err != nil {
+			return err
+		}
+		pages := uint32((uint64(len(init)) + uint64(wasm.MemoryPageSize) - 1) / uint64(wasm.MemoryPageSize))
+		if mem, _, err = newMemory(kind, pages, &pages, nil); err != nil {
+			return err
+		}
+		p.m.DataSection = append(p.m.DataSection, wasm.DataSegment{
+			OffsetExpression: wasm.ConstantExpression{Opcode: wasm.OpcodeI32Const, Data: leb128.EncodeInt32(0)},
+			Init:             init,
+			MemoryIndex:      p.fieldIndex[f],
+		})
+	} else {
+		var rest []*sexpr
+		if mem, rest, err = decodeMemoryType(kind, h.rest); err != nil {
+			return err
+		} else if len(rest) > 0 {
+			return rest[0].errorf("unexpected %s in memory", rest[0])
+		}
+	}
+
+	// The first memory is in the memory section unless it was imported.
+	if p.m.MemorySection == nil && p.m.ImportMemoryCount == 0 {
+		p.m.MemorySection = mem
+	} else {
+		p.m.AdditionalMemorySection = append(p.m.AdditionalMemorySection, *mem)
+	}
+	return nil
+}
+
+// decodeStrings concatenates the bytes of strings, such as the contents of a data segment.
+func decodeStrings(list []*sexpr) ([]byte, error) {
+	ret := []byte{}
+	for _, s := range list {
+		if s.tokenType != tokenString {
+			return nil, s.errorf("expected a string, but was %s", s)
+		}
+		ret = append(ret, s.value...)
+	}
+	return ret, nil
+}
+
+// decodeFuncIndices resolves a list of functions, such as "$f $g 2".
+func (p *moduleParser) decodeFuncIndices(list []*sexpr) ([]wasm.Index, error) {
+	ret := make([]wasm.Index, 0, len(list))
+	for _, s := range list {
+		idx, err := p.funcs.resolve(s)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, idx)
+	}
+	return ret, nil
+}
+
+// decodeConstantExpression decodes the single instruction of a constant expression, which may be folded as in
+// "(i32.const 1)" or plain as in "i32.const 1".
+func (p *moduleParser) decodeConstantExpression(parent *sexpr, list []*sexpr) (ret wasm.ConstantExpression, err error) {
+	if len(list) == 1 && list[0].tokenType == tokenList && len(list[0].list) > 0 {
+		parent, list = list[0], list[0].list
+	}
+	if len(list) != 2 || list[0].tokenType != tokenKeyword {
+		return ret, parent.errorf("expected a constant instruction")
+	}
+
+	op, arg := list[0], list[1]
+	var v uint64
+	switch op.value {
+	case wasm.OpcodeI32ConstName:
+		if v, err = decodeInt(arg, 32); err == nil {
+			ret = wasm.ConstantExpression{Opcode: wasm.OpcodeI32Const, Data: leb128.EncodeInt32(int32(v))}
+		}
+	case wasm.OpcodeI64ConstName:
+		if v, err = decodeInt(arg, 64); err == nil {
+			ret = wasm.ConstantExpression{Opcode: wasm.OpcodeI64Const, Data: leb128.EncodeInt64(int64(v))}
+		}
+	case wasm.OpcodeF32ConstName:
+		if v, err = decodeFloat(arg, 32); err == nil {
+			ret = wasm.ConstantExpression{Opcode: wasm.OpcodeF32Const, Data: encodeFloat(v, 4)}
+		}
+	case wasm.OpcodeF64ConstName:
+		if v, err = decodeFloat(arg, 64); err == nil {
+			ret = wasm.ConstantExpression{Opcode: wasm.OpcodeF64Const, Data: encodeFloat(v, 8)}
+		}
+	case wasm.OpcodeGlobalGetName:
+		var idx wasm.Index
+		if idx, err = p.globals.resolve(arg); err == nil {
+			ret = wasm.ConstantExpression{Opcode: wasm.OpcodeGlobalGet, Data: leb128.EncodeUint32(idx)}
+		}
+	case wasm.OpcodeRefFuncName:
+		var idx wasm.Index
+		if idx, err = p.funcs.resolve(arg); err == nil {
+			ret = wasm.ConstantExpression{Opcode: wasm.OpcodeRefFunc, Data: leb128.EncodeUint32(idx)}
+		}
+	case wasm.OpcodeRefNullName:
+		var refType wasm.RefType
+		if refType, err = decodeHeapType(arg); err == nil {
+			ret = wasm.ConstantExpression{Opcode: wasm.OpcodeRefNull, Data: []byte{refType}}
+		}
+	default:
+		err = op.errorf("%s is not a constant instruction", op.value)
+	}
+	return
+}
+
+// decodeHeapType decodes the type of ref.null, which is "func" or "extern".
+func decodeHeapType(s *sexpr) (wasm.RefType, error) {
+	if s.isKeyword("func") {
+		return wasm.RefTypeFuncref, nil
+	} else if s.isKeyword("extern") {
+		return wasm.RefTypeExternref, nil
+	}
+	return 0, s.errorf("unknown heap type %s", s)
+}
+
+// encodeFloat returns the little-endian encoding of the float bits in size bytes.
+func encodeFloat(bits uint64, size int) []byte {
+	ret := make([]byte, size)
+	for i := range ret {
+		ret[i] = byte(bits >> (8 * i))
+	}
+	return ret
+}
+
+// isOffset returns true if the expression is the offset of an active segment, such as "(offset (i32.const 1))" or
+// the abbreviation "(i32.const 1)".
+func isOffset(s *sexpr) bool {
+	if s.tokenType != tokenList || len(s.list) == 0 || s.list[0].tokenType != tokenKeyword {
+		return false
+	}
+	switch s.list[0].value {
+	case "offset", wasm.OpcodeI32ConstName, wasm.OpcodeGlobalGetName:
+		return true
+	}
+	return false
+}
+
+// decodeOffset decodes the offset of an active segment.
+func (p *moduleParser) decodeOffset(s *sexpr) (wasm.ConstantExpression, error) {
+	if s.isList("offset") {
+		return p.decodeConstantExpression(s, s.list[1:])
+	}
+	return p.decodeConstantExpression(s, []*sexpr{s})
+}
+
+// decodeElem decodes an element segment, which can be active as in "(elem (i32.const 0) $f $g)", passive as in
+// "(elem func $f $g)" or declarative as in "(elem declare func $f)".
+func (p *moduleParser) decodeElem(f *sexpr) (err error) {
+	list := f.list[1:]
+	if optionalID(f) != nil {
+		list = list[1:]
+	}
+
+	e := wasm.ElementSegment{Mode: wasm.ElementModePassive, Type: wasm.RefTypeFuncref}
+	if len(list) > 0 && list[0].isKeyword("declare") {
+		e.Mode, list = wasm.ElementModeDeclarative, list[1:]
+	} else {
+		if len(list) > 0 && list[0].isList("table") {
+			if len(list[0].list) != 2 {
+				return list[0].errorf("expected (table index)")
+			}
+			if e.TableIndex, err = p.tables.resolve(list[0].list[1]); err != nil {
+				return err
+			}
+			list = list[1:]
+		}
+		if len(list) > 0 && isOffset(list[0]) {
+			e.Mode = wasm.ElementModeActive
+			if e.OffsetExpr, err = p.decodeOffset(list[0]); err != nil {
+				return err
+			}
+			list = list[1:]
+		}
+	}
+
+	switch {
+	case len(list) > 0 && list[0].isKeyword("func"):
+		e.Init, err = p.decodeFuncIndices(list[1:])
+	case len(list) > 0 && list[0].tokenType == tokenKeyword:
+		if e.Type, err = decodeRefType(list[0]); err != nil {
+			return err
+		}
+		e.Init, err = p.decodeElemExprs(list[1:])
+	case e.Mode == wasm.ElementModeActive:
+		// The MVP abbreviation "(elem (i32.const 0) $f $g)", which omits "func".
+		e.Init, err = p.decodeFuncIndices(list)
+	default:
+		return f.errorf("expected element list")
+	}
+	if err != nil {
+		return err
+	}
+	p.m.ElementSection = append(p.m.ElementSection, e)
+	return nil
+}
+
+// decodeElemExprs decodes element expressions such as "(ref.func $f) (item ref.null func)" into function indices,
+// where null references are wasm.ElementInitNullReference.
+func (p *moduleParser) decodeElemExprs(list []*sexpr) ([]wasm.Index, error) {
+	ret := make([]wasm.Index, 0, len(list))
+	for _, s := range list {
+		if s.tokenType != tokenList {
+			return nil, s.errorf("expected an element expression, but was %s", s)
+		}
+		expr, err := p.decodeConstantExpression(s, s.list)
+		if s.isList("item") {
+			expr, err = p.decodeConstantExpression(s, s.list[1:])
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch expr.Opcode {
+		case wasm.OpcodeRefNull:
+			ret = append(ret, wasm.ElementInitNullReference)
+		case wasm.OpcodeRefFunc:
+			idx, _, _ := leb128.LoadUint32(expr.Data)
+			ret = append(ret, idx)
+		default:
+			return nil, s.errorf("expected ref.func or ref.null")
+		}
+	}
+	return ret, nil
+}
+
+// decodeData decodes a data segment, which can be active as in "(data (i32.const 8) "hello")" or passive as in
+// "(data "hello")".
+func (p *moduleParser) decodeData(f *sexpr) (err error) {
+	list := f.list[1:]
+	if optionalID(f) != nil {
+		list = list[1:]
+	}
+
+	d := wasm.DataSegment{Passive: true}
+	if len(list) > 0 && list[0].isList("memory") {
+		if len(list[0].list) != 2 {
+			return list[0].errorf("expected (memory index)")
+		}
+		if d.MemoryIndex, err = p.memories.resolve(list[0].list[1]); err != nil {
+			return err
+		}
+		list = list[1:]
+	}
+	if len(list) > 0 && isOffset(list[0]) {
+		d.Passive = false
+		if d.OffsetExpression, err = p.decodeOffset(list[0]); err != nil {
+			return err
+		}
+		list = list[1:]
+	}
+	if d.Init, err = decodeStrings(list); err != nil {
+		return err
+	}
+	p.m.DataSection = append(p.m.DataSection, d)
+	return nil
+}
+
+// decodeStart decodes the start function, such as "(start $main)".
+func (p *moduleParser) decodeStart(f *sexpr) error {
+	if len(f.list) != 2 {
+		return f.errorf("expected (start index)")
+	} else if p.m.StartSection != nil {
+		return f.errorf("multiple start sections are invalid")
+	}
+	idx, err := p.funcs.resolve(f.list[1])
+	if err != nil {
+		return err
+	}
+	p.m.StartSection = &idx
+	return nil
+}
+
+// decodeExports decodes both export fields, such as "(export "f" (func $f))", and inline exports, in the order they
+// are written.
+func (p *moduleParser) decodeExports(fields []*sexpr) error {
+	for _, f := range fields {
+		switch kind := f.list[0].value; kind {
+		case "export":
+			if len(f.list) != 3 || f.list[2].tokenType != tokenList || len(f.list[2].list) != 2 {
+				return f.errorf("expected (export \"name\" (kind index))")
+			}
+			desc := f.list[2]
+			externType, ok := externTypes[desc.list[0].value]
+			if !ok {
+				return desc.errorf("unknown export kind %s", desc.list[0].value)
+			}
+			idx, err := p.namespace(desc.list[0].value).resolve(desc.list[1])
+			if err != nil {
+				return err
+			} else if err = p.addExport(f.list[1], externType, idx); err != nil {
+				return err
+			}
+		case "func", "table", "memory", "global":
+			h, err := decodeFieldHead(f)
+			if err != nil {
+				return err
+			}
+			for _, name := range h.exports {
+				if err = p.addExport(name, externTypes[kind], p.fieldIndex[f]); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	if p.m.ExportSection != nil {
+		p.m.Exports = make(map[string]*wasm.Export, len(p.m.ExportSection))
+		for i := range p.m.ExportSection {
+			p.m.Exports[p.m.ExportSection[i].Name] = &p.m.ExportSection[i]
+		}
+	}
+	return nil
+}
+
+var externTypes = map[string]wasm.ExternType{
+	"func":   wasm.ExternTypeFunc,
+	"table":  wasm.ExternTypeTable,
+	"memory": wasm.ExternTypeMemory,
+	"global": wasm.ExternTypeGlobal,
+}
+
+func (p *moduleParser) addExport(nameExpr *sexpr, externType wasm.ExternType, idx wasm.Index) error {
+	name, err := decodeName(nameExpr)
+	if err != nil {
+		return err
+	}
+	for i := range p.m.ExportSection {
+		if p.m.ExportSection[i].Name == name {
+			return nameExpr.errorf("duplicate export name %s", nameExpr)
+		}
+	}
+	p.m.ExportSection = append(p.m.ExportSection, wasm.Export{Type: externType, Name: name, Index: idx})
+	return nil
+}
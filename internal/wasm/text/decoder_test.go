@@ -0,0 +1,426 @@
+package text
+
+import (
+	"math"
+	"testing"
+
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/internal/leb128"
+	"github.com/tetratelabs/wazero/internal/testing/binaryencoding"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasm"
+	"github.com/tetratelabs/wazero/internal/wasm/binary"
+)
+
+const (
+	i32, i64, f32 = wasm.ValueTypeI32, wasm.ValueTypeI64, wasm.ValueTypeF32
+)
+
+func TestDecodeModule(t *testing.T) {
+	one := uint32(1)
+	zero := uint32(0)
+
+	tests := []struct {
+		name     string
+		input    string
+		expected *wasm.Module
+	}{
+		{
+			name:     "empty",
+			input:    "(module)",
+			expected: &wasm.Module{},
+		},
+		{
+			name:     "module name",
+			input:    "(module $math)",
+			expected: &wasm.Module{NameSection: &wasm.NameSection{ModuleName: "math"}},
+		},
+		{
+			name: "fields without module",
+			input: `(memory 1)
+(func)`,
+			expected: &wasm.Module{
+				TypeSection:     []wasm.FunctionType{{}},
+				FunctionSection: []wasm.Index{0},
+				CodeSection:     []wasm.Code{{LocalTypes: []wasm.ValueType{}, Body: []byte{wasm.OpcodeEnd}}},
+				MemorySection:   &wasm.Memory{Min: 1, Cap: 1, Max: wasm.MemoryLimitPages},
+			},
+		},
+		{
+			name: "types are deduplicated and follow explicit ones",
+			input: `(module
+	(func (param i32) (result i32) local.get 0)
+	(type $v_v (func))
+	(type (func (param i32) (result i32)))
+	(func (type $v_v))
+	(func (param i64))
+)`,
+			expected: &wasm.Module{
+				TypeSection: []wasm.FunctionType{
+					{},
+					{Params: []wasm.ValueType{i32}, Results: []wasm.ValueType{i32}},
+					{Params: []wasm.ValueType{i64}},
+				},
+				FunctionSection: []wasm.Index{1, 0, 2},
+				CodeSection: []wasm.Code{
+					{LocalTypes: []wasm.ValueType{}, Body: []byte{wasm.OpcodeLocalGet, 0, wasm.OpcodeEnd}},
+					{LocalTypes: []wasm.ValueType{}, Body: []byte{wasm.OpcodeEnd}},
+					{LocalTypes: []wasm.ValueType{}, Body: []byte{wasm.OpcodeEnd}},
+				},
+			},
+		},
+		{
+			// Implicit types are added in the order of the index space: imports first.
+			name: "imports precede definitions",
+			input: `(module
+	(func $add (param $x i32) (param $y i32) (result i32)
+		(i32.add (local.get $x) (call $double (local.get $y))))
+	(import "env" "double" (func $double (param i32) (result i32)))
+	(global $g (import "env" "g") (mut i64))
+	(memory (import "env" "memory") 1 2)
+	(table (import "other" "table") 2 funcref)
+)`,
+			expected: &wasm.Module{
+				TypeSection: []wasm.FunctionType{
+					{Params: []wasm.ValueType{i32}, Results: []wasm.ValueType{i32}},
+					{Params: []wasm.ValueType{i32, i32}, Results: []wasm.ValueType{i32}},
+				},
+				ImportSection: []wasm.Import{
+					{Module: "env", Name: "double", Type: wasm.ExternTypeFunc, DescFunc: 0},
+					{Module: "env", Name: "g", Type: wasm.ExternTypeGlobal, DescGlobal: wasm.GlobalType{ValType: i64, Mutable: true}},
+					{Module: "env", Name: "memory", Type: wasm.ExternTypeMemory, DescMem: &wasm.Memory{Min: 1, Cap: 1, Max: 2, IsMaxEncoded: true}},
+					{Module: "other", Name: "table", Type: wasm.ExternTypeTable, DescTable: wasm.Table{Min: 2, Type: wasm.RefTypeFuncref}},
+				},
+				ImportFunctionCount: 1,
+				ImportGlobalCount:   1,
+				ImportMemoryCount:   1,
+				ImportTableCount:    1,
+				FunctionSection:     []wasm.Index{1},
+				CodeSection: []wasm.Code{{LocalTypes: []wasm.ValueType{}, Body: []byte{
+					wasm.OpcodeLocalGet, 0, wasm.OpcodeLocalGet, 1, wasm.OpcodeCall, 0, wasm.OpcodeI32Add, wasm.OpcodeEnd,
+				}}},
+				NameSection: &wasm.NameSection{
+					FunctionNames: wasm.NameMap{{Index: 0, Name: "double"}, {Index: 1, Name: "add"}},
+					LocalNames: wasm.IndirectNameMap{
+						{Index: 1, NameMap: wasm.NameMap{{Index: 0, Name: "x"}, {Index: 1, Name: "y"}}},
+					},
+				},
+			},
+		},
+		{
+			name: "exports in written order",
+			input: `(module
+	(func $f (export "f") (export "g"))
+	(export "memory" (memory 0))
+	(memory $mem 1)
+	(global (export "answer") i32 (i32.const 42))
+	(export "h" (func $f))
+)`,
+			expected: &wasm.Module{
+				TypeSection:     []wasm.FunctionType{{}},
+				FunctionSection: []wasm.Index{0},
+				CodeSection:     []wasm.Code{{LocalTypes: []wasm.ValueType{}, Body: []byte{wasm.OpcodeEnd}}},
+				MemorySection:   &wasm.Memory{Min: 1, Cap: 1, Max: wasm.MemoryLimitPages},
+				GlobalSection: []wasm.Global{{
+					Type: wasm.GlobalType{ValType: i32},
+					Init: wasm.ConstantExpression{Opcode: wasm.OpcodeI32Const, Data: []byte{42}},
+				}},
+				ExportSection: []wasm.Export{
+					{Name: "f", Type: wasm.ExternTypeFunc},
+					{Name: "g", Type: wasm.ExternTypeFunc},
+					{Name: "memory", Type: wasm.ExternTypeMemory},
+					{Name: "answer", Type: wasm.ExternTypeGlobal},
+					{Name: "h", Type: wasm.ExternTypeFunc},
+				},
+				NameSection: &wasm.NameSection{FunctionNames: wasm.NameMap{{Index: 0, Name: "f"}}},
+			},
+		},
+		{
+			name: "data and elements",
+			input: `(module
+	(memory 1)
+	(data (i32.const 8) "hello" " world\n")
+	(data $passive "\00\ff")
+	(table $t 2 funcref)
+	(elem (i32.const 1) $f)
+	(elem $e func $f $f)
+	(elem declare func $f)
+	(func $f)
+	(start $f)
+)`,
+			expected: &wasm.Module{
+				TypeSection:     []wasm.FunctionType{{}},
+				FunctionSection: []wasm.Index{0},
+				CodeSection:     []wasm.Code{{LocalTypes: []wasm.ValueType{}, Body: []byte{wasm.OpcodeEnd}}},
+				MemorySection:   &wasm.Memory{Min: 1, Cap: 1, Max: wasm.MemoryLimitPages},
+				TableSection:    []wasm.Table{{Min: 2, Type: wasm.RefTypeFuncref}},
+				DataSection: []wasm.DataSegment{
+					{OffsetExpression: wasm.ConstantExpression{Opcode: wasm.OpcodeI32Const, Data: []byte{8}}, Init: []byte("hello world\n")},
+					{Passive: true, Init: []byte{0, 0xff}},
+				},
+				ElementSection: []wasm.ElementSegment{
+					{
+						OffsetExpr: wasm.ConstantExpression{Opcode: wasm.OpcodeI32Const, Data: []byte{1}},
+						Init:       []wasm.Index{0},
+						Type:       wasm.RefTypeFuncref,
+						Mode:       wasm.ElementModeActive,
+					},
+					{Init: []wasm.Index{0, 0}, Type: wasm.RefTypeFuncref, Mode: wasm.ElementModePassive},
+					{Init: []wasm.Index{0}, Type: wasm.RefTypeFuncref, Mode: wasm.ElementModeDeclarative},
+				},
+				StartSection: &zero,
+				NameSection:  &wasm.NameSection{FunctionNames: wasm.NameMap{{Index: 0, Name: "f"}}},
+			},
+		},
+		{
+			name: "inline data and elements",
+			input: `(module
+	(memory (data "hi"))
+	(table funcref (elem 0))
+	(func)
+)`,
+			expected: &wasm.Module{
+				TypeSection:     []wasm.FunctionType{{}},
+				FunctionSection: []wasm.Index{0},
+				CodeSection:     []wasm.Code{{LocalTypes: []wasm.ValueType{}, Body: []byte{wasm.OpcodeEnd}}},
+				MemorySection:   &wasm.Memory{Min: 1, Cap: 1, Max: 1, IsMaxEncoded: true},
+				TableSection:    []wasm.Table{{Min: 1, Max: &one, Type: wasm.RefTypeFuncref}},
+				DataSection: []wasm.DataSegment{
+					{OffsetExpression: wasm.ConstantExpression{Opcode: wasm.OpcodeI32Const, Data: []byte{0}}, Init: []byte("hi")},
+				},
+				ElementSection: []wasm.ElementSegment{{
+					OffsetExpr: wasm.ConstantExpression{Opcode: wasm.OpcodeI32Const, Data: []byte{0}},
+					Init:       []wasm.Index{0},
+					Type:       wasm.RefTypeFuncref,
+					Mode:       wasm.ElementModeActive,
+				}},
+			},
+		},
+		{
+			name: "locals",
+			input: `(module
+	(func (param $a i32) (param f32) (local $b i64) (local i32 f32)
+		local.get $b
+		drop)
+)`,
+			expected: &wasm.Module{
+				TypeSection:     []wasm.FunctionType{{Params: []wasm.ValueType{i32, f32}}},
+				FunctionSection: []wasm.Index{0},
+				CodeSection: []wasm.Code{{
+					LocalTypes: []wasm.ValueType{i64, i32, f32},
+					Body:       []byte{wasm.OpcodeLocalGet, 2, wasm.OpcodeDrop, wasm.OpcodeEnd},
+				}},
+				NameSection: &wasm.NameSection{
+					LocalNames: wasm.IndirectNameMap{
+						{Index: 0, NameMap: wasm.NameMap{{Index: 0, Name: "a"}, {Index: 2, Name: "b"}}},
+					},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			for i := range tc.expected.TypeSection {
+				_ = tc.expected.TypeSection[i].String()
+			}
+			setDerivedFields(tc.expected)
+			m, err := DecodeModule([]byte(tc.input))
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, m)
+		})
+	}
+}
+
+// setDerivedFields sets the fields which are redundant with the sections, to simplify test expectations.
+func setDerivedFields(m *wasm.Module) {
+	if m.ImportSection != nil {
+		m.ImportPerModule = map[string][]*wasm.Import{}
+		counts := map[wasm.ExternType]wasm.Index{}
+		for i := range m.ImportSection {
+			imp := &m.ImportSection[i]
+			imp.IndexPerType = counts[imp.Type]
+			counts[imp.Type]++
+			m.ImportPerModule[imp.Module] = append(m.ImportPerModule[imp.Module], imp)
+		}
+	}
+	if m.ExportSection != nil {
+		m.Exports = map[string]*wasm.Export{}
+		for i := range m.ExportSection {
+			m.Exports[m.ExportSection[i].Name] = &m.ExportSection[i]
+		}
+	}
+}
+
+// TestDecodeModule_binary ensures the result is the same as decoding the binary encoding of the module.
+func TestDecodeModule_binary(t *testing.T) {
+	m, err := DecodeModule([]byte(`(module $example
+	(type $i32_i32 (func (param i32) (result i32)))
+	(import "env" "log" (func $log (param i32)))
+	(import "env" "memory" (memory 1 4))
+	(global $counter (mut i32) (i32.const 0))
+	(global f64 (f64.const -0x1.8p1))
+	(table 2 funcref)
+	(elem (i32.const 0) $fac $sign)
+	(data (i32.const 16) "\de\ad\be\ef")
+	(func $fac (export "fac") (type $i32_i32)
+		(if (result i32) (i32.lt_s (local.get 0) (i32.const 2))
+			(then (i32.const 1))
+			(else (i32.mul (local.get 0) (call $fac (i32.sub (local.get 0) (i32.const 1)))))))
+	(func $sign (type $i32_i32)
+		local.get 0
+		i32.extend8_s)
+	(func (export "main") (param $p i32) (result i32) (local $i i32)
+		block $done
+			loop $loop
+				local.get $i
+				local.get $p
+				i32.ge_u
+				br_if $done
+				(global.set $counter (i32.add (global.get $counter) (i32.const 1)))
+				(local.set $i (i32.add (local.get $i) (i32.const 1)))
+				br $loop
+			end
+		end
+		(call $log (i32.load offset=16 (i32.const 0)))
+		(call_indirect (type $i32_i32) (local.get $p) (i32.const 0))
+		(i32.trunc_sat_f32_s (f32.const nan))
+		i32.add)
+	(func $init)
+	(start $init)
+)`))
+	require.NoError(t, err)
+
+	expected, err := binary.DecodeModule(binaryencoding.EncodeModule(m), api.CoreFeaturesV2, wasm.MemoryLimitPages, false, false, false, wasm.DefaultDecodeLimits)
+	require.NoError(t, err)
+	for i := range expected.CodeSection {
+		expected.CodeSection[i].BodyOffsetInCodeSection = 0
+	}
+	expected.SectionRanges = nil // only known when decoding the binary format.
+	require.Equal(t, expected, m)
+	require.NoError(t, m.Validate(api.CoreFeaturesV2, wasm.MemoryLimitPages))
+}
+
+func TestDecodeModule_instructions(t *testing.T) {
+	tests := []struct {
+		name, input string
+		expected    []byte
+	}{
+		{
+			name:     "plain and folded are the same",
+			input:    `(i32.add (i32.const 1) (i32.const 2)) i32.const 1 i32.const 2 i32.add drop drop`,
+			expected: []byte{0x41, 1, 0x41, 2, 0x6a, 0x41, 1, 0x41, 2, 0x6a, 0x1a, 0x1a},
+		},
+		{
+			name:     "integer constants",
+			input:    `(i32.const -1) (i32.const 0xffff_ffff) (i64.const -0x8000000000000000) drop drop drop`,
+			expected: append(append([]byte{0x41, 0x7f, 0x41, 0x7f, 0x42}, leb128.EncodeInt64(math.MinInt64)...), 0x1a, 0x1a, 0x1a),
+		},
+		{
+			name:     "float constants",
+			input:    `(f32.const -0.5) (f64.const inf) (f32.const nan:0x200000) drop drop drop`,
+			expected: []byte{0x43, 0, 0, 0, 0xbf, 0x44, 0, 0, 0, 0, 0, 0, 0xf0, 0x7f, 0x43, 0, 0, 0xa0, 0x7f, 0x1a, 0x1a, 0x1a},
+		},
+		{
+			name:     "labels",
+			input:    `block $outer (block $inner br $outer (br_if $inner (i32.const 0))) br 0 end $outer`,
+			expected: []byte{0x02, 0x40, 0x02, 0x40, 0x0c, 1, 0x41, 0, 0x0d, 0, 0x0b, 0x0c, 0, 0x0b},
+		},
+		{
+			name:     "br_table",
+			input:    `(block $a (block $b (br_table $b $a 0 (i32.const 1))))`,
+			expected: []byte{0x02, 0x40, 0x02, 0x40, 0x41, 1, 0x0e, 2, 0, 1, 0, 0x0b, 0x0b},
+		},
+		{
+			name:     "block types",
+			input:    `(block (result i32) i32.const 1) (loop (param i32) (result i32)) drop if (result i64 i64) unreachable else unreachable end drop drop`,
+			expected: []byte{0x02, 0x7f, 0x41, 1, 0x0b, 0x03, 1, 0x0b, 0x1a, 0x04, 2, 0x00, 0x05, 0x00, 0x0b, 0x1a, 0x1a},
+		},
+		{
+			name:     "memory",
+			input:    `(i64.store32 offset=3 align=1 (i32.const 0) (i64.load8_u (i32.const 1))) (drop (memory.grow (memory.size)))`,
+			expected: []byte{0x41, 0, 0x41, 1, 0x31, 0, 0, 0x3e, 0, 3, 0x3f, 0, 0x40, 0, 0x1a},
+		},
+		{
+			name:     "sign extension and saturating truncation",
+			input:    `(drop (i64.extend32_s (i64.trunc_sat_f64_u (f64.const 0))))`,
+			expected: []byte{0x44, 0, 0, 0, 0, 0, 0, 0, 0, 0xfc, 0x07, 0xc4, 0x1a},
+		},
+		{
+			name:     "select",
+			input:    `(select (i32.const 1) (i32.const 2) (i32.const 3)) (select (result i32) (i32.const 1) (i32.const 2) (i32.const 3)) drop drop`,
+			expected: []byte{0x41, 1, 0x41, 2, 0x41, 3, 0x1b, 0x41, 1, 0x41, 2, 0x41, 3, 0x1c, 1, 0x7f, 0x1a, 0x1a},
+		},
+		{
+			name:     "bulk memory",
+			input:    `(memory.init $d (i32.const 0) (i32.const 0) (i32.const 0)) (data.drop 0) (memory.copy (i32.const 0) (i32.const 0) (i32.const 0))`,
+			expected: []byte{0x41, 0, 0x41, 0, 0x41, 0, 0xfc, 0x08, 0, 0, 0xfc, 0x09, 0, 0x41, 0, 0x41, 0, 0x41, 0, 0xfc, 0x0a, 0, 0},
+		},
+		{
+			name:     "tables",
+			input:    `(table.init $t 0 (i32.const 0) (i32.const 0) (i32.const 0)) (table.copy 1 0 (i32.const 0) (i32.const 0) (i32.const 0)) (drop (table.size $t)) (elem.drop 0)`,
+			expected: []byte{0x41, 0, 0x41, 0, 0x41, 0, 0xfc, 0x0c, 0, 1, 0x41, 0, 0x41, 0, 0x41, 0, 0xfc, 0x0e, 1, 0, 0xfc, 0x10, 1, 0x1a, 0xfc, 0x0d, 0},
+		},
+		{
+			name:     "call_indirect",
+			input:    `(call_indirect $t (param i32) (i32.const 1) (i32.const 0))`,
+			expected: []byte{0x41, 1, 0x41, 0, 0x11, 1, 1},
+		},
+		{
+			name:     "references",
+			input:    `(drop (ref.is_null (ref.null extern))) (drop (ref.func 0))`,
+			expected: []byte{0xd0, 0x6f, 0xd1, 0x1a, 0xd2, 0, 0x1a},
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			m, err := DecodeModule([]byte(`(module
+	(memory 1)
+	(table 1 funcref)
+	(table $t 1 funcref)
+	(data $d "")
+	(elem func 0)
+	(func ` + tc.input + `))`))
+			require.NoError(t, err)
+			require.Equal(t, append(tc.expected, wasm.OpcodeEnd), m.CodeSection[0].Body)
+		})
+	}
+}
+
+func TestDecodeModule_errors(t *testing.T) {
+	tests := []struct {
+		name, input, expectedErr string
+	}{
+		{name: "not a field", input: "(module 1)", expectedErr: "1:9: expected a module field, but was 1"},
+		{name: "unknown field", input: "(module (funk))", expectedErr: "1:9: unknown module field funk"},
+		{name: "unbalanced", input: "(module (func)", expectedErr: "1:1: expected ')'"},
+		{name: "unknown func", input: "(module (func call $f))", expectedErr: "1:20: unknown func $f"},
+		{name: "duplicate func", input: "(module (func $f) (func $f))", expectedErr: "1:25: duplicate func $f"},
+		{name: "unknown instruction", input: "(module (func i32.foo))", expectedErr: "1:15: unknown instruction i32.foo"},
+		{name: "unknown label", input: "(module (func block br $l end))", expectedErr: "1:24: unknown label $l"},
+		{name: "missing end", input: "(module (func loop))", expectedErr: "1:15: missing end for loop"},
+		{name: "unexpected else", input: "(module (func block else end))", expectedErr: "1:21: unexpected else"},
+		{name: "unexpected end in folded block", input: "(module (func (block (br 0) end)))", expectedErr: "1:29: unexpected end"},
+		{name: "unexpected else in folded if", input: "(module (func (if (i32.const 0) (then else))))", expectedErr: "1:39: unexpected else"},
+		{name: "missing end in folded block", input: "(module (func (block loop)))", expectedErr: "1:22: missing end for loop"},
+		{name: "mismatching label", input: "(module (func block $a end $b))", expectedErr: "1:28: mismatching label $b"},
+		{name: "i32 out of range", input: "(module (func (drop (i32.const 0x1_0000_0000))))", expectedErr: "1:32: invalid i32: 0x1_0000_0000"},
+		{name: "invalid float", input: "(module (func (drop (f32.const 1__0))))", expectedErr: "1:32: invalid f32: 1__0"},
+		{name: "type mismatch", input: "(module (type (func)) (func (type 0) (param i32)))", expectedErr: "1:29: signature doesn't match type v_v"},
+		{name: "duplicate export", input: `(module (func (export "f") (export "f")))`, expectedErr: `1:36: duplicate export name "f"`},
+		{name: "unterminated string", input: `(module (data "x`, expectedErr: "1:15: unterminated string"},
+		{name: "invalid escape", input: `(module (data "\x"))`, expectedErr: "1:16: invalid escape"},
+		{name: "missing then", input: "(module (func (if (i32.const 1))))", expectedErr: "1:15: expected (then)"},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := DecodeModule([]byte(tc.input))
+			require.EqualError(t, err, tc.expectedErr)
+		})
+	}
+}
@@ -0,0 +1,813 @@
+package text
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"math/bits"
+	"strconv"
+	"strings"
+
+	"github.com/tetratelabs/wazero/internal/ieee754"
+	"github.com/tetratelabs/wazero/internal/leb128"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+// EncodeModule writes the WebAssembly Text Format of a decoded module, for debugging.
+//
+// Functions and locals are referenced by their name if the name section has one which is a valid identifier, and by
+// index otherwise. Instructions are written in plain (not folded) form, with the label of each block in a comment.
+//
+// # Notes
+//
+//   - The output can be decoded with DecodeModule, unless the module uses instructions it doesn't support, such as
+//     SIMD or multiple memories.
+//   - Custom sections other than the name section are not written.
+func EncodeModule(w io.Writer, m *wasm.Module) error {
+	e := &encoder{m: m}
+	e.init()
+	if err := e.module(); err != nil {
+		return err
+	}
+	_, err := w.Write(e.buf.Bytes())
+	return err
+}
+
+// encoder writes a module in the WebAssembly Text Format.
+type encoder struct {
+	m   *wasm.Module
+	buf bytes.Buffer
+
+	// funcTypes are the type indices of all functions, imported first.
+	funcTypes []wasm.Index
+	// funcIDs are the identifiers of functions which have a usable name, such as "$main".
+	funcIDs map[wasm.Index]string
+	// localIDs are the identifiers of locals which have a usable name, per function.
+	localIDs map[wasm.Index]map[wasm.Index]string
+}
+
+func (e *encoder) init() {
+	for i := range e.m.ImportSection {
+		if imp := &e.m.ImportSection[i]; imp.Type == wasm.ExternTypeFunc {
+			e.funcTypes = append(e.funcTypes, imp.DescFunc)
+		}
+	}
+	e.funcTypes = append(e.funcTypes, e.m.FunctionSection...)
+
+	e.funcIDs = map[wasm.Index]string{}
+	e.localIDs = map[wasm.Index]map[wasm.Index]string{}
+	if ns := e.m.NameSection; ns != nil {
+		e.funcIDs = identifiers(ns.FunctionNames)
+		for _, assoc := range ns.LocalNames {
+			e.localIDs[assoc.Index] = identifiers(assoc.NameMap)
+		}
+	}
+}
+
+// identifiers returns the identifier for each name which can be written as one, unless the name is not unique.
+func identifiers(names wasm.NameMap) map[wasm.Index]string {
+	counts := make(map[string]int, len(names))
+	for _, n := range names {
+		counts[n.Name]++
+	}
+	ret := make(map[wasm.Index]string, len(names))
+	for _, n := range names {
+		valid := n.Name != "" && counts[n.Name] == 1
+		for i := 0; i < len(n.Name) && valid; i++ {
+			valid = isIDChar(n.Name[i])
+		}
+		if valid {
+			ret[n.Index] = "$" + n.Name
+		}
+	}
+	return ret
+}
+
+func (e *encoder) printf(format string, args ...interface{}) {
+	fmt.Fprintf(&e.buf, format, args...)
+}
+
+// funcRef returns the identifier of the function, or its index if it has none.
+func (e *encoder) funcRef(idx wasm.Index) string {
+	if id, ok := e.funcIDs[idx]; ok {
+		return id
+	}
+	return strconv.FormatUint(uint64(idx), 10)
+}
+
+func (e *encoder) module() error {
+	e.buf.WriteString("(module")
+	if ns := e.m.NameSection; ns != nil && ns.ModuleName != "" {
+		if ids := identifiers(wasm.NameMap{{Name: ns.ModuleName}}); len(ids) == 1 {
+			e.buf.WriteString(" " + ids[0])
+		}
+	}
+
+	for i := range e.m.TypeSection {
+		e.printf("\n  (type (;%d;) (func", i)
+		e.signature(&e.m.TypeSection[i], nil)
+		e.buf.WriteString("))")
+	}
+
+	var counts [4]wasm.Index // per wasm.ExternType
+	for i := range e.m.ImportSection {
+		imp := &e.m.ImportSection[i]
+		e.printf("\n  (import %s %s (%s", quote(imp.Module), quote(imp.Name), wasm.ExternTypeName(imp.Type))
+		idx := counts[imp.Type]
+		counts[imp.Type]++
+		switch imp.Type {
+		case wasm.ExternTypeFunc:
+			e.funcHead(idx)
+		case wasm.ExternTypeTable:
+			e.printf(" (;%d;) %s", idx, tableType(&imp.DescTable))
+		case wasm.ExternTypeMemory:
+			e.printf(" (;%d;) %s", idx, memoryType(imp.DescMem))
+		case wasm.ExternTypeGlobal:
+			e.printf(" (;%d;) %s", idx, globalType(imp.DescGlobal))
+		}
+		e.buf.WriteString("))")
+	}
+
+	for i := range e.m.CodeSection {
+		idx := e.m.ImportFunctionCount + wasm.Index(i)
+		e.buf.WriteString("\n  (func")
+		e.funcHead(idx)
+		if err := e.code(idx, &e.m.CodeSection[i]); err != nil {
+			return fmt.Errorf("func[%d]: %w", idx, err)
+		}
+		e.buf.WriteString(")")
+	}
+
+	for i := range e.m.TableSection {
+		e.printf("\n  (table (;%d;) %s)", e.m.ImportTableCount+wasm.Index(i), tableType(&e.m.TableSection[i]))
+	}
+	memIdx := e.m.ImportMemoryCount
+	if e.m.MemorySection != nil {
+		e.printf("\n  (memory (;%d;) %s)", memIdx, memoryType(e.m.MemorySection))
+		memIdx++
+	}
+	for i := range e.m.AdditionalMemorySection {
+		e.printf("\n  (memory (;%d;) %s)", memIdx+wasm.Index(i), memoryType(&e.m.AdditionalMemorySection[i]))
+	}
+	for i := range e.m.GlobalSection {
+		g := &e.m.GlobalSection[i]
+		init, err := e.constantExpression(&g.Init)
+		if err != nil {
+			return fmt.Errorf("global[%d]: %w", i, err)
+		}
+		e.printf("\n  (global (;%d;) %s %s)", e.m.ImportGlobalCount+wasm.Index(i), globalType(g.Type), init)
+	}
+
+	for i := range e.m.ExportSection {
+		exp := &e.m.ExportSection[i]
+		ref := strconv.FormatUint(uint64(exp.Index), 10)
+		if exp.Type == wasm.ExternTypeFunc {
+			ref = e.funcRef(exp.Index)
+		}
+		e.printf("\n  (export %s (%s %s))", quote(exp.Name), wasm.ExternTypeName(exp.Type), ref)
+	}
+	if e.m.StartSection != nil {
+		e.printf("\n  (start %s)", e.funcRef(*e.m.StartSection))
+	}
+
+	for i := range e.m.ElementSection {
+		if err := e.elem(wasm.Index(i), &e.m.ElementSection[i]); err != nil {
+			return fmt.Errorf("elem[%d]: %w", i, err)
+		}
+	}
+	for i := range e.m.DataSection {
+		d := &e.m.DataSection[i]
+		e.printf("\n  (data (;%d;)", i)
+		if !d.Passive {
+			if d.MemoryIndex != 0 {
+				e.printf(" (memory %d)", d.MemoryIndex)
+			}
+			offset, err := e.constantExpression(&d.OffsetExpression)
+			if err != nil {
+				return fmt.Errorf("data[%d]: %w", i, err)
+			}
+			e.buf.WriteString(" " + offset)
+		}
+		e.printf(" %s)", quote(string(d.Init)))
+	}
+	e.buf.WriteString(")\n")
+	return nil
+}
+
+// funcHead writes the identifier, index and type use of a function, such as " $f (;1;) (type 0) (param $x i32)".
+func (e *encoder) funcHead(idx wasm.Index) {
+	if id, ok := e.funcIDs[idx]; ok {
+		e.buf.WriteString(" " + id)
+	}
+	typeIdx := e.funcTypes[idx]
+	e.printf(" (;%d;) (type %d)", idx, typeIdx)
+	if int(typeIdx) < len(e.m.TypeSection) {
+		e.signature(&e.m.TypeSection[typeIdx], e.localIDs[idx])
+	}
+}
+
+// signature writes the params and results of a function type, where params with an identifier are written
+// separately, such as " (param $x i32) (param i32 i32) (result i32)".
+func (e *encoder) signature(ft *wasm.FunctionType, ids map[wasm.Index]string) {
+	e.valueTypeList("param", ft.Params, 0, ids)
+	if len(ft.Results) > 0 {
+		e.buf.WriteString(" (result")
+		for _, vt := range ft.Results {
+			e.buf.WriteString(" " + wasm.ValueTypeName(vt))
+		}
+		e.buf.WriteString(")")
+	}
+}
+
+// valueTypeList writes params or locals starting at the index, grouping consecutive ones without an identifier.
+func (e *encoder) valueTypeList(keyword string, types []wasm.ValueType, start wasm.Index, ids map[wasm.Index]string) {
+	open := false
+	for i, vt := range types {
+		if id, ok := ids[start+wasm.Index(i)]; ok {
+			if open {
+				e.buf.WriteString(")")
+				open = false
+			}
+			e.printf(" (%s %s %s)", keyword, id, wasm.ValueTypeName(vt))
+			continue
+		}
+		if !open {
+			e.printf(" (%s", keyword)
+			open = true
+		}
+		e.buf.WriteString(" " + wasm.ValueTypeName(vt))
+	}
+	if open {
+		e.buf.WriteString(")")
+	}
+}
+
+func tableType(t *wasm.Table) string {
+	if t.Max != nil {
+		return fmt.Sprintf("%d %d %s", t.Min, *t.Max, wasm.RefTypeName(t.Type))
+	}
+	return fmt.Sprintf("%d %s", t.Min, wasm.RefTypeName(t.Type))
+}
+
+func memoryType(mem *wasm.Memory) string {
+	var prefix string
+	if mem.Is64 {
+		prefix = "i64 "
+	}
+	if mem.IsShared {
+		return fmt.Sprintf("%s%d %d shared", prefix, mem.Min, mem.Max)
+	} else if mem.IsMaxEncoded {
+		return fmt.Sprintf("%s%d %d", prefix, mem.Min, mem.Max)
+	}
+	return prefix + strconv.FormatUint(uint64(mem.Min), 10)
+}
+
+func globalType(gt wasm.GlobalType) string {
+	if gt.Mutable {
+		return "(mut " + wasm.ValueTypeName(gt.ValType) + ")"
+	}
+	return wasm.ValueTypeName(gt.ValType)
+}
+
+// constantExpression returns the folded text of a constant expression, such as "(i32.const 1)".
+func (e *encoder) constantExpression(expr *wasm.ConstantExpression) (string, error) {
+	r := bytes.NewReader(expr.Data)
+	var imm string
+	var err error
+	switch expr.Opcode {
+	case wasm.OpcodeI32Const, wasm.OpcodeI64Const, wasm.OpcodeF32Const, wasm.OpcodeF64Const, wasm.OpcodeGlobalGet:
+		imm, err = e.immediates(r, expr.Opcode, 0, nil)
+	case wasm.OpcodeRefFunc:
+		var idx uint32
+		if idx, _, err = leb128.DecodeUint32(r); err == nil {
+			imm = " " + e.funcRef(idx)
+		}
+	case wasm.OpcodeRefNull:
+		if len(expr.Data) != 1 {
+			return "", fmt.Errorf("invalid ref.null")
+		}
+		imm = " " + heapType(expr.Data[0])
+	case wasm.OpcodeVecPrefix:
+		if len(expr.Data) != 16 {
+			return "", fmt.Errorf("invalid v128.const")
+		}
+		return "(" + wasm.OpcodeVecV128ConstName + v128(expr.Data) + ")", nil
+	case wasm.OpcodeI32Add, wasm.OpcodeI32Sub, wasm.OpcodeI32Mul, wasm.OpcodeI64Add, wasm.OpcodeI64Sub, wasm.OpcodeI64Mul:
+		// An extended constant expression is written as a sequence of instructions, ending with expr.Opcode.
+		var sb strings.Builder
+		for r.Len() > 0 {
+			op, _ := r.ReadByte()
+			if imm, err = e.immediates(r, op, 0, nil); err != nil {
+				return "", err
+			}
+			sb.WriteString("(" + wasm.InstructionName(op) + imm + ") ")
+		}
+		return sb.String() + "(" + wasm.InstructionName(expr.Opcode) + ")", nil
+	default:
+		return "", fmt.Errorf("invalid constant expression opcode %#x", expr.Opcode)
+	}
+	if err != nil {
+		return "", err
+	}
+	return "(" + wasm.InstructionName(expr.Opcode) + imm + ")", nil
+}
+
+func heapType(refType wasm.RefType) string {
+	if refType == wasm.RefTypeExternref {
+		return "extern"
+	}
+	return "func"
+}
+
+// v128 returns the immediate of v128.const, which is written as four i32 lanes.
+func v128(data []byte) string {
+	var sb strings.Builder
+	sb.WriteString(" i32x4")
+	for i := 0; i < 16; i += 4 {
+		lane := uint32(data[i]) | uint32(data[i+1])<<8 | uint32(data[i+2])<<16 | uint32(data[i+3])<<24
+		fmt.Fprintf(&sb, " 0x%08x", lane)
+	}
+	return sb.String()
+}
+
+func (e *encoder) elem(idx wasm.Index, seg *wasm.ElementSegment) error {
+	e.printf("\n  (elem (;%d;)", idx)
+	switch seg.Mode {
+	case wasm.ElementModeActive:
+		if seg.TableIndex != 0 {
+			e.printf(" (table %d)", seg.TableIndex)
+		}
+		offset, err := e.constantExpression(&seg.OffsetExpr)
+		if err != nil {
+			return err
+		}
+		e.buf.WriteString(" " + offset)
+	case wasm.ElementModeDeclarative:
+		e.buf.WriteString(" declare")
+	}
+
+	// Null references can only be written as expressions.
+	hasNull := seg.Type != wasm.RefTypeFuncref
+	for _, init := range seg.Init {
+		hasNull = hasNull || init == wasm.ElementInitNullReference
+	}
+	if !hasNull {
+		e.buf.WriteString(" func")
+		for _, init := range seg.Init {
+			e.buf.WriteString(" " + e.funcRef(init))
+		}
+	} else {
+		e.buf.WriteString(" " + wasm.RefTypeName(seg.Type))
+		for _, init := range seg.Init {
+			if init == wasm.ElementInitNullReference {
+				e.buf.WriteString(" (ref.null " + heapType(seg.Type) + ")")
+			} else {
+				e.buf.WriteString(" (ref.func " + e.funcRef(init) + ")")
+			}
+		}
+	}
+	e.buf.WriteString(")")
+	return nil
+}
+
+// quote returns a string literal, escaping bytes which are not printable ASCII.
+func quote(s string) string {
+	var sb strings.Builder
+	sb.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '"' || c == '\\':
+			sb.WriteByte('\\')
+			sb.WriteByte(c)
+		case c >= 0x20 && c < 0x7f:
+			sb.WriteByte(c)
+		default:
+			fmt.Fprintf(&sb, "\\%02x", c)
+		}
+	}
+	sb.WriteByte('"')
+	return sb.String()
+}
+
+// formatFloat returns the text of float bits, which is the shortest decimal that decodes to the same value, or
+// "inf", "nan" or "nan:0x" followed by the payload.
+func formatFloat(bits uint64, bitSize int) string {
+	mantissaBits := 52
+	if bitSize == 32 {
+		mantissaBits = 23
+	}
+	sign := ""
+	if bits>>(bitSize-1) != 0 {
+		sign = "-"
+	}
+	exponentMask := uint64(1<<(bitSize-1)-1) &^ (1<<mantissaBits - 1)
+	if bits&exponentMask == exponentMask {
+		payload := bits & (1<<mantissaBits - 1)
+		switch payload {
+		case 0:
+			return sign + "inf"
+		case 1 << (mantissaBits - 1):
+			return sign + "nan"
+		}
+		return fmt.Sprintf("%snan:0x%x", sign, payload)
+	}
+	if bitSize == 32 {
+		return strconv.FormatFloat(float64(math.Float32frombits(uint32(bits))), 'g', -1, 32)
+	}
+	return strconv.FormatFloat(math.Float64frombits(bits), 'g', -1, 64)
+}
+
+// code writes the locals and instructions of a function body, one instruction per line.
+func (e *encoder) code(idx wasm.Index, code *wasm.Code) error {
+	paramCount := 0
+	if typeIdx := e.funcTypes[idx]; int(typeIdx) < len(e.m.TypeSection) {
+		paramCount = len(e.m.TypeSection[typeIdx].Params)
+	}
+	localIDs := e.localIDs[idx]
+	if len(code.LocalTypes) > 0 {
+		e.buf.WriteString("\n   ")
+		e.valueTypeList("local", code.LocalTypes, wasm.Index(paramCount), localIDs)
+	}
+
+	r := bytes.NewReader(code.Body)
+	depth := 0
+	for {
+		op, err := r.ReadByte()
+		if err == io.EOF {
+			return fmt.Errorf("missing end")
+		} else if err != nil {
+			return err
+		}
+
+		switch op {
+		case wasm.OpcodeEnd:
+			if depth == 0 {
+				if r.Len() > 0 {
+					return fmt.Errorf("unexpected bytes after end")
+				}
+				return nil
+			}
+			depth--
+			e.line(depth, "end")
+			continue
+		case wasm.OpcodeElse:
+			e.line(depth-1, "else")
+			continue
+		case wasm.OpcodeBlock, wasm.OpcodeLoop, wasm.OpcodeIf:
+			blockType, err := e.blockType(r)
+			if err != nil {
+				return err
+			}
+			depth++
+			e.line(depth-1, fmt.Sprintf("%s%s  ;; label = @%d", wasm.InstructionName(op), blockType, depth))
+			continue
+		}
+
+		name, imm, err := e.instruction(r, op, depth, localIDs)
+		if err != nil {
+			return err
+		}
+		e.line(depth, name+imm)
+	}
+}
+
+// line writes an instruction indented by its nesting depth.
+func (e *encoder) line(depth int, instr string) {
+	e.buf.WriteString("\n    ")
+	for i := 0; i < depth; i++ {
+		e.buf.WriteString("  ")
+	}
+	e.buf.WriteString(instr)
+}
+
+// blockType returns the text of a block type, such as " (result i32)", or "" for an empty block type.
+func (e *encoder) blockType(r *bytes.Reader) (string, error) {
+	bt, _, err := leb128.DecodeInt33AsInt64(r)
+	if err != nil {
+		return "", fmt.Errorf("read block type: %w", err)
+	}
+	switch {
+	case bt == -64: // 0x40
+		return "", nil
+	case bt < 0:
+		return " (result " + wasm.ValueTypeName(wasm.ValueType(bt&0x7f)) + ")", nil
+	case int(bt) < len(e.m.TypeSection):
+		var sb strings.Builder
+		fmt.Fprintf(&sb, " (type %d)", bt)
+		prev := e.buf
+		e.buf = bytes.Buffer{}
+		e.signature(&e.m.TypeSection[bt], nil)
+		sb.Write(e.buf.Bytes())
+		e.buf = prev
+		return sb.String(), nil
+	}
+	return "", fmt.Errorf("unknown type %d in block type", bt)
+}
+
+// instruction returns the name and immediates of a non-structured instruction.
+func (e *encoder) instruction(r *bytes.Reader, op wasm.Opcode, depth int, localIDs map[wasm.Index]string) (name, imm string, err error) {
+	switch op {
+	case wasm.OpcodeMiscPrefix:
+		return e.miscInstruction(r)
+	case wasm.OpcodeVecPrefix:
+		return e.vectorInstruction(r)
+	case wasm.OpcodeAtomicPrefix:
+		return e.atomicInstruction(r)
+	}
+	if name = wasm.InstructionName(op); name == "" {
+		return "", "", fmt.Errorf("invalid opcode %#x", op)
+	}
+	imm, err = e.immediates(r, op, depth, localIDs)
+	if op == wasm.OpcodeTypedSelect {
+		name = wasm.OpcodeSelectName
+	}
+	return
+}
+
+// immediates returns the text of the immediates of an instruction with a single-byte opcode, such as " 1".
+func (e *encoder) immediates(r *bytes.Reader, op wasm.Opcode, depth int, localIDs map[wasm.Index]string) (string, error) {
+	u32 := func() (uint32, error) {
+		v, _, err := leb128.DecodeUint32(r)
+		return v, err
+	}
+	index := func() (string, error) {
+		v, err := u32()
+		return " " + strconv.FormatUint(uint64(v), 10), err
+	}
+
+	switch op {
+	case wasm.OpcodeBr, wasm.OpcodeBrIf:
+		v, err := u32()
+		return label(v, depth), err
+	case wasm.OpcodeBrTable:
+		count, err := u32()
+		if err != nil {
+			return "", err
+		}
+		var sb strings.Builder
+		for i := uint32(0); i <= count; i++ { // The default label follows the count of labels.
+			v, err := u32()
+			if err != nil {
+				return "", err
+			}
+			sb.WriteString(label(v, depth))
+		}
+		return sb.String(), nil
+	case wasm.OpcodeCall, wasm.OpcodeReturnCall, wasm.OpcodeRefFunc:
+		v, err := u32()
+		return " " + e.funcRef(v), err
+	case wasm.OpcodeCallIndirect, wasm.OpcodeReturnCallIndirect:
+		typeIdx, err := u32()
+		if err != nil {
+			return "", err
+		}
+		tableIdx, err := u32()
+		if err != nil {
+			return "", err
+		}
+		ret := fmt.Sprintf(" (type %d)", typeIdx)
+		if tableIdx != 0 {
+			ret = fmt.Sprintf(" %d%s", tableIdx, ret)
+		}
+		return ret, nil
+	case wasm.OpcodeLocalGet, wasm.OpcodeLocalSet, wasm.OpcodeLocalTee:
+		v, err := u32()
+		if id, ok := localIDs[v]; ok {
+			return " " + id, err
+		}
+		return " " + strconv.FormatUint(uint64(v), 10), err
+	case wasm.OpcodeGlobalGet, wasm.OpcodeGlobalSet, wasm.OpcodeTableGet, wasm.OpcodeTableSet:
+		return index()
+	case wasm.OpcodeMemorySize, wasm.OpcodeMemoryGrow:
+		return memoryIndex(u32())
+	case wasm.OpcodeI32Const:
+		v, _, err := leb128.DecodeInt32(r)
+		return " " + strconv.FormatInt(int64(v), 10), err
+	case wasm.OpcodeI64Const:
+		v, _, err := leb128.DecodeInt64(r)
+		return " " + strconv.FormatInt(v, 10), err
+	case wasm.OpcodeF32Const:
+		buf := make([]byte, 4)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return "", err
+		}
+		v, _ := ieee754.DecodeFloat32(buf)
+		return " " + formatFloat(uint64(math.Float32bits(v)), 32), nil
+	case wasm.OpcodeF64Const:
+		buf := make([]byte, 8)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return "", err
+		}
+		v, _ := ieee754.DecodeFloat64(buf)
+		return " " + formatFloat(math.Float64bits(v), 64), nil
+	case wasm.OpcodeTypedSelect:
+		count, err := u32()
+		if err != nil {
+			return "", err
+		}
+		ret := " (result"
+		for i := uint32(0); i < count; i++ {
+			vt, err := r.ReadByte()
+			if err != nil {
+				return "", err
+			}
+			ret += " " + wasm.ValueTypeName(vt)
+		}
+		return ret + ")", nil
+	case wasm.OpcodeRefNull:
+		refType, err := r.ReadByte()
+		return " " + heapType(refType), err
+	}
+	if align, ok := naturalAlignments[op]; ok {
+		return memArg(r, align)
+	}
+	return "", nil
+}
+
+// label returns the text of a branch target, which is the relative depth with the label of the block in a comment,
+// such as " 1 (;@2;)".
+func label(v uint32, depth int) string {
+	if int(v) < depth {
+		return fmt.Sprintf(" %d (;@%d;)", v, depth-int(v))
+	}
+	return fmt.Sprintf(" %d", v)
+}
+
+// memoryIndex returns the text of a memory index immediate, which is omitted when zero.
+func memoryIndex(v uint32, err error) (string, error) {
+	if v == 0 {
+		return "", err
+	}
+	return " " + strconv.FormatUint(uint64(v), 10), err
+}
+
+// memArg returns the text of a memory argument, omitting a zero offset and the natural alignment.
+func memArg(r *bytes.Reader, natural uint64) (string, error) {
+	align, _, err := leb128.DecodeUint32(r)
+	if err != nil {
+		return "", fmt.Errorf("read memory align: %w", err)
+	}
+	var ret string
+	if align&wasm.MemArgMemoryIndexFlag != 0 {
+		align &^= wasm.MemArgMemoryIndexFlag
+		memIdx, _, err := leb128.DecodeUint32(r)
+		if err != nil {
+			return "", fmt.Errorf("read memory index: %w", err)
+		}
+		ret = " " + strconv.FormatUint(uint64(memIdx), 10)
+	}
+	offset, _, err := leb128.DecodeUint64(r) // 64-bit for a 64-bit memory.
+	if err != nil {
+		return "", fmt.Errorf("read memory offset: %w", err)
+	}
+	if offset != 0 {
+		ret += " offset=" + strconv.FormatUint(offset, 10)
+	}
+	if uint64(align) != natural {
+		ret += " align=" + strconv.FormatUint(1<<align, 10)
+	}
+	return ret, nil
+}
+
+func (e *encoder) miscInstruction(r *bytes.Reader) (name, imm string, err error) {
+	v, _, err := leb128.DecodeUint32(r)
+	if err != nil {
+		return "", "", err
+	}
+	op := wasm.OpcodeMisc(v)
+	if name = wasm.MiscInstructionName(op); name == "" || v > 0xff {
+		return "", "", fmt.Errorf("invalid misc opcode %#x", v)
+	}
+
+	indices := func(n int) ([]uint32, error) {
+		ret := make([]uint32, n)
+		for i := range ret {
+			if ret[i], _, err = leb128.DecodeUint32(r); err != nil {
+				return nil, err
+			}
+		}
+		return ret, nil
+	}
+	var idx []uint32
+	switch op {
+	case wasm.OpcodeMiscMemoryInit:
+		// The data index is followed by the memory index.
+		if idx, err = indices(2); err == nil {
+			imm = fmt.Sprintf(" %d", idx[0])
+			if idx[1] != 0 {
+				imm = fmt.Sprintf(" %d %d", idx[1], idx[0])
+			}
+		}
+	case wasm.OpcodeMiscDataDrop, wasm.OpcodeMiscElemDrop:
+		if idx, err = indices(1); err == nil {
+			imm = fmt.Sprintf(" %d", idx[0])
+		}
+	case wasm.OpcodeMiscMemoryCopy:
+		if idx, err = indices(2); err == nil && (idx[0] != 0 || idx[1] != 0) {
+			imm = fmt.Sprintf(" %d %d", idx[0], idx[1])
+		}
+	case wasm.OpcodeMiscMemoryFill:
+		if idx, err = indices(1); err == nil {
+			imm, err = memoryIndex(idx[0], nil)
+		}
+	case wasm.OpcodeMiscTableInit:
+		// The element index is followed by the table index, but the table is written first.
+		if idx, err = indices(2); err == nil {
+			imm = fmt.Sprintf(" %d %d", idx[1], idx[0])
+		}
+	case wasm.OpcodeMiscTableCopy:
+		if idx, err = indices(2); err == nil {
+			imm = fmt.Sprintf(" %d %d", idx[0], idx[1])
+		}
+	case wasm.OpcodeMiscTableGrow, wasm.OpcodeMiscTableSize, wasm.OpcodeMiscTableFill:
+		if idx, err = indices(1); err == nil {
+			imm = fmt.Sprintf(" %d", idx[0])
+		}
+	}
+	return
+}
+
+func (e *encoder) atomicInstruction(r *bytes.Reader) (name, imm string, err error) {
+	v, _, err := leb128.DecodeUint32(r)
+	if err != nil {
+		return "", "", err
+	}
+	op := wasm.OpcodeAtomic(v)
+	if name = wasm.AtomicInstructionName(op); name == "" || v > 0xff {
+		return "", "", fmt.Errorf("invalid atomic opcode %#x", v)
+	}
+	if op == wasm.OpcodeAtomicFence {
+		_, err = r.ReadByte() // reserved byte
+		return
+	}
+	_, size := wasm.AtomicMemoryAccess(op)
+	imm, err = memArg(r, uint64(bits.TrailingZeros32(size)))
+	return
+}
+
+// vectorNaturalAlignments are the default alignment of vector memory instructions, as the exponent of a power of
+// two.
+var vectorNaturalAlignments = map[wasm.OpcodeVec]uint64{
+	wasm.OpcodeVecV128Load: 4, wasm.OpcodeVecV128Load8x8s: 3, wasm.OpcodeVecV128Load8x8u: 3,
+	wasm.OpcodeVecV128Load16x4s: 3, wasm.OpcodeVecV128Load16x4u: 3, wasm.OpcodeVecV128Load32x2s: 3,
+	wasm.OpcodeVecV128Load32x2u: 3, wasm.OpcodeVecV128Load8Splat: 0, wasm.OpcodeVecV128Load16Splat: 1,
+	wasm.OpcodeVecV128Load32Splat: 2, wasm.OpcodeVecV128Load64Splat: 3, wasm.OpcodeVecV128Load32zero: 2,
+	wasm.OpcodeVecV128Load64zero: 3, wasm.OpcodeVecV128Store: 4,
+	wasm.OpcodeVecV128Load8Lane: 0, wasm.OpcodeVecV128Load16Lane: 1, wasm.OpcodeVecV128Load32Lane: 2,
+	wasm.OpcodeVecV128Load64Lane: 3, wasm.OpcodeVecV128Store8Lane: 0, wasm.OpcodeVecV128Store16Lane: 1,
+	wasm.OpcodeVecV128Store32Lane: 2, wasm.OpcodeVecV128Store64Lane: 3,
+}
+
+// vectorTextNames are the names of vector instructions whose wasm.VectorInstructionName differs from the text format.
+var vectorTextNames = map[wasm.OpcodeVec]string{
+	wasm.OpcodeVecV128i8x16Shuffle: "i8x16.shuffle",
+	wasm.OpcodeVecI8x16SubSatS:     "i8x16.sub_sat_s",
+	wasm.OpcodeVecI8x16SubSatU:     "i8x16.sub_sat_u",
+}
+
+func (e *encoder) vectorInstruction(r *bytes.Reader) (name, imm string, err error) {
+	v, _, err := leb128.DecodeUint32(r)
+	if err != nil {
+		return "", "", err
+	}
+	if v >= wasm.OpcodeVecRelaxedOffset && v-wasm.OpcodeVecRelaxedOffset <= 0xff {
+		if name = wasm.VecRelaxedInstructionName(wasm.OpcodeVecRelaxed(v - wasm.OpcodeVecRelaxedOffset)); name != "" {
+			return // relaxed-SIMD instructions have no immediates.
+		}
+	}
+	op := wasm.OpcodeVec(v)
+	if name = wasm.VectorInstructionName(op); name == "" || v > 0xff {
+		return "", "", fmt.Errorf("invalid vector opcode %#x", v)
+	}
+	if textName, ok := vectorTextNames[op]; ok {
+		name = textName
+	}
+
+	if align, ok := vectorNaturalAlignments[op]; ok {
+		if imm, err = memArg(r, align); err != nil {
+			return
+		}
+	}
+	switch {
+	case op == wasm.OpcodeVecV128Const:
+		buf := make([]byte, 16)
+		if _, err = io.ReadFull(r, buf); err == nil {
+			imm = v128(buf)
+		}
+	case op == wasm.OpcodeVecV128i8x16Shuffle:
+		buf := make([]byte, 16)
+		if _, err = io.ReadFull(r, buf); err == nil {
+			for _, lane := range buf {
+				imm += " " + strconv.Itoa(int(lane))
+			}
+		}
+	case op >= wasm.OpcodeVecI8x16ExtractLaneS && op <= wasm.OpcodeVecF64x2ReplaceLane,
+		op >= wasm.OpcodeVecV128Load8Lane && op <= wasm.OpcodeVecV128Store64Lane:
+		var lane byte
+		if lane, err = r.ReadByte(); err == nil {
+			imm += " " + strconv.Itoa(int(lane))
+		}
+	}
+	return
+}
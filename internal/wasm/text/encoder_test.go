@@ -0,0 +1,252 @@
+package text
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+func TestEncodeModule(t *testing.T) {
+	m, err := DecodeModule([]byte(`(module $example
+	(import "env" "log" (func $log (param i32)))
+	(memory 1 2)
+	(global $counter (mut i32) (i32.const 0))
+	(table 2 funcref)
+	(elem (i32.const 0) $sum $log)
+	(data (i32.const 16) "hi\00")
+	(func $sum (export "sum") (param $n i32) (result i32) (local $acc i32) (local f32 f32)
+		(block $done
+			(loop $loop
+				(br_if $done (i32.eqz (local.get $n)))
+				(local.set $acc (i32.add (local.get $acc) (local.get $n)))
+				(local.set $n (i32.sub (local.get $n) (i32.const 1)))
+				(br $loop)))
+		(f32.store offset=8 align=2 (i32.const 0) (f32.const -1.5))
+		(call $log (i32.load (i32.const 0)))
+		local.get $acc)
+)`))
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, EncodeModule(&buf, m))
+	require.Equal(t, `(module $example
+  (type (;0;) (func (param i32)))
+  (type (;1;) (func (param i32) (result i32)))
+  (import "env" "log" (func $log (;0;) (type 0) (param i32)))
+  (func $sum (;1;) (type 1) (param $n i32) (result i32)
+    (local $acc i32) (local f32 f32)
+    block  ;; label = @1
+      loop  ;; label = @2
+        local.get $n
+        i32.eqz
+        br_if 1 (;@1;)
+        local.get $acc
+        local.get $n
+        i32.add
+        local.set $acc
+        local.get $n
+        i32.const 1
+        i32.sub
+        local.set $n
+        br 0 (;@2;)
+      end
+    end
+    i32.const 0
+    f32.const -1.5
+    f32.store offset=8 align=2
+    i32.const 0
+    i32.load
+    call $log
+    local.get $acc)
+  (table (;0;) 2 funcref)
+  (memory (;0;) 1 2)
+  (global (;0;) (mut i32) (i32.const 0))
+  (export "sum" (func $sum))
+  (elem (;0;) (i32.const 0) func $sum $log)
+  (data (;0;) (i32.const 16) "hi\00"))
+`, buf.String())
+}
+
+// TestEncodeModule_roundTrip ensures decoding the text of a module results in the same module.
+func TestEncodeModule_roundTrip(t *testing.T) {
+	tests := []struct {
+		name, input string
+	}{
+		{
+			name: "control flow",
+			input: `(module
+	(type (func (param i32) (result i32 i32)))
+	(func (param i32) (result i32)
+		(block $a (result i32) (block $b (br_table $b $a 0 (i32.const 1) (i32.const 2))) i32.const 3)
+		(if (result i32) (local.get 0) (then (i32.const 1)) (else (i32.const 2)))
+		(loop (type 0) (param i32) (result i32 i32) local.get 0)
+		(select (result i32)) (select) (call_indirect 1 (param i32) (result i32)) return)
+	(table 1 funcref)
+	(table 1 funcref)
+)`,
+		},
+		{
+			name: "constants",
+			input: `(module
+	(func
+		(drop (i32.const -2147483648)) (drop (i64.const 0x7fffffffffffffff))
+		(drop (f32.const -0)) (drop (f32.const 0x1p-149)) (drop (f32.const nan:0x1)) (drop (f32.const -inf))
+		(drop (f64.const 0.1)) (drop (f64.const -nan)) (drop (f64.const 1e308))))`,
+		},
+		{
+			name: "memory",
+			input: `(module
+	(memory 1)
+	(data "\00\ff\"\\")
+	(data (i32.const 3) "x")
+	(func
+		(i64.store32 offset=3 align=1 (i32.const 0) (i64.load8_u (i32.const 1)))
+		(drop (memory.grow (memory.size)))
+		(memory.init 0 (i32.const 0) (i32.const 0) (i32.const 0)) (data.drop 0)
+		(memory.copy (i32.const 0) (i32.const 0) (i32.const 0))
+		(memory.fill (i32.const 0) (i32.const 0) (i32.const 0))))`,
+		},
+		{
+			name:  "shared memory",
+			input: `(module (memory 1 2 shared))`,
+		},
+		{
+			name:  "64-bit memory",
+			input: `(module (memory i64 1 2))`,
+		},
+		{
+			name: "tables",
+			input: `(module
+	(table $t 1 funcref)
+	(table $u 1 10 externref)
+	(elem $e func $f)
+	(elem declare func $f)
+	(elem (table $u) (i32.const 0) externref (ref.null extern))
+	(global funcref (ref.func $f))
+	(func $f
+		(table.init $t $e (i32.const 0) (i32.const 0) (i32.const 0)) (elem.drop $e)
+		(table.copy $t $t (i32.const 0) (i32.const 0) (i32.const 0))
+		(drop (table.grow $u (ref.null extern) (i32.const 1)))
+		(table.fill $u (i32.const 0) (table.get $u (i32.const 0)) (i32.const 1))
+		(table.set $t (i32.const 0) (ref.func $f))
+		(drop (ref.is_null (ref.null func)))
+		(drop (table.size $t))))`,
+		},
+		{
+			name: "names",
+			input: `(module $m
+	(import "a" "b" (func $imported))
+	(func $f (param $x i32) (local i64) (local $y f32) call $imported)
+	(func $g call $f)
+	(start $g))`,
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			m, err := DecodeModule([]byte(tc.input))
+			require.NoError(t, err)
+
+			var buf bytes.Buffer
+			require.NoError(t, EncodeModule(&buf, m))
+			decoded, err := DecodeModule(buf.Bytes())
+			require.NoError(t, err, buf.String())
+			require.Equal(t, m, decoded, buf.String())
+		})
+	}
+}
+
+func TestEncodeModule_vector(t *testing.T) {
+	m := &wasm.Module{
+		TypeSection:     []wasm.FunctionType{{}},
+		FunctionSection: []wasm.Index{0},
+		MemorySection:   &wasm.Memory{Min: 1},
+		CodeSection: []wasm.Code{{Body: []byte{
+			wasm.OpcodeI32Const, 0,
+			wasm.OpcodeVecPrefix, wasm.OpcodeVecV128Load, 3, 16,
+			wasm.OpcodeVecPrefix, wasm.OpcodeVecV128Const, 1, 0, 0, 0, 2, 0, 0, 0, 3, 0, 0, 0, 4, 0, 0, 0,
+			wasm.OpcodeVecPrefix, wasm.OpcodeVecV128i8x16Shuffle, 0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15,
+			wasm.OpcodeVecPrefix, 0x80 | wasm.OpcodeVecRelaxedI8x16Swizzle, 0x02,
+			wasm.OpcodeVecPrefix, wasm.OpcodeVecI32x4ExtractLane, 3,
+			wasm.OpcodeDrop,
+			wasm.OpcodeEnd,
+		}}},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, EncodeModule(&buf, m))
+	require.Equal(t, `(module
+  (type (;0;) (func))
+  (func (;0;) (type 0)
+    i32.const 0
+    v128.load offset=16 align=8
+    v128.const i32x4 0x00000001 0x00000002 0x00000003 0x00000004
+    i8x16.shuffle 0 1 2 3 4 5 6 7 8 9 10 11 12 13 14 15
+    i8x16.relaxed_swizzle
+    i32x4.extract_lane 3
+    drop)
+  (memory (;0;) 1))
+`, buf.String())
+}
+
+func TestEncodeModule_atomic(t *testing.T) {
+	m := &wasm.Module{
+		TypeSection:     []wasm.FunctionType{{}},
+		FunctionSection: []wasm.Index{0},
+		MemorySection:   &wasm.Memory{Min: 1, Max: 1, IsMaxEncoded: true, IsShared: true},
+		CodeSection: []wasm.Code{{Body: []byte{
+			wasm.OpcodeI32Const, 0,
+			wasm.OpcodeI64Const, 1,
+			wasm.OpcodeAtomicPrefix, wasm.OpcodeAtomicI64RmwAdd, 3, 8,
+			wasm.OpcodeDrop,
+			wasm.OpcodeI32Const, 0,
+			wasm.OpcodeAtomicPrefix, wasm.OpcodeAtomicI32Load16U, 1, 0,
+			wasm.OpcodeDrop,
+			wasm.OpcodeAtomicPrefix, wasm.OpcodeAtomicFence, 0,
+			wasm.OpcodeEnd,
+		}}},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, EncodeModule(&buf, m))
+	require.Equal(t, `(module
+  (type (;0;) (func))
+  (func (;0;) (type 0)
+    i32.const 0
+    i64.const 1
+    i64.atomic.rmw.add offset=8
+    drop
+    i32.const 0
+    i32.atomic.load16_u
+    drop
+    atomic.fence)
+  (memory (;0;) 1 1 shared))
+`, buf.String())
+}
+
+func TestEncodeModule_errors(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        []byte
+		expectedErr string
+	}{
+		{name: "missing end", body: []byte{wasm.OpcodeNop}, expectedErr: "func[0]: missing end"},
+		{name: "invalid opcode", body: []byte{0xff, wasm.OpcodeEnd}, expectedErr: "func[0]: invalid opcode 0xff"},
+		{name: "truncated immediate", body: []byte{wasm.OpcodeI32Const}, expectedErr: "func[0]: readByte failed: EOF"},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			m := &wasm.Module{
+				TypeSection:     []wasm.FunctionType{{}},
+				FunctionSection: []wasm.Index{0},
+				CodeSection:     []wasm.Code{{Body: tc.body}},
+			}
+			require.EqualError(t, EncodeModule(&bytes.Buffer{}, m), tc.expectedErr)
+		})
+	}
+}
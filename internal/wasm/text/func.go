@@ -0,0 +1,511 @@
+package text
+
+import (
+	"math/bits"
+
+	"github.com/tetratelabs/wazero/internal/leb128"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+// instruction is the binary encoding of an instruction without its immediates.
+type instruction struct {
+	opcode wasm.Opcode
+	// misc is the second byte of an instruction with wasm.OpcodeMiscPrefix.
+	misc wasm.OpcodeMisc
+}
+
+// instructions are the instructions supported in function bodies, keyed by name, e.g. "i32.add".
+var instructions = map[string]instruction{}
+
+func init() {
+	for i := 0; i < 256; i++ {
+		switch oc := wasm.Opcode(i); oc {
+		case wasm.OpcodeMiscPrefix, wasm.OpcodeVecPrefix, wasm.OpcodeTypedSelect:
+		default:
+			if name := wasm.InstructionName(oc); name != "" {
+				instructions[name] = instruction{opcode: oc}
+			}
+		}
+		if name := wasm.MiscInstructionName(wasm.OpcodeMisc(i)); name != "" {
+			instructions[name] = instruction{opcode: wasm.OpcodeMiscPrefix, misc: wasm.OpcodeMisc(i)}
+		}
+	}
+}
+
+// block is a block, loop or if instruction enclosing the current instruction.
+type block struct {
+	// op is the instruction which began the block, e.g. "loop".
+	op     *sexpr
+	opcode wasm.Opcode
+	// label is the identifier of the block or nil if it has none.
+	label *sexpr
+}
+
+// funcParser encodes the body of a function.
+type funcParser struct {
+	p      *moduleParser
+	locals *namespace
+	blocks []block
+	// foldedDepth is the count of blocks begun by enclosing folded instructions, which plain "else" and "end" can't close.
+	foldedDepth int
+	body        []byte
+}
+
+// decodeCode decodes the locals and instructions of a function, such as "(local $i i32) local.get $i drop".
+func (p *moduleParser) decodeCode(fn *definedFunc) (code wasm.Code, err error) {
+	f := &funcParser{p: p, locals: newNamespace("local")}
+	ids := fn.paramIDs
+	for _, id := range fn.paramIDs {
+		if _, err = f.locals.add(id); err != nil {
+			return
+		}
+	}
+
+	list := fn.rest
+	code.LocalTypes = []wasm.ValueType{}
+	for len(list) > 0 && list[0].isList("local") {
+		types, localIDs, err := decodeValueTypeList(list[0], true)
+		if err != nil {
+			return code, err
+		}
+		for _, id := range localIDs {
+			if _, err = f.locals.add(id); err != nil {
+				return code, err
+			}
+		}
+		code.LocalTypes, ids, list = append(code.LocalTypes, types...), append(ids, localIDs...), list[1:]
+	}
+	p.addLocalNames(fn.idx, ids)
+
+	if err = f.instrs(list); err != nil {
+		return
+	} else if len(f.blocks) > 0 {
+		op := f.blocks[len(f.blocks)-1].op
+		return code, op.errorf("missing end for %s", op.value)
+	}
+	code.Body = append(f.body, wasm.OpcodeEnd)
+	return
+}
+
+// instrs encodes a sequence of plain or folded instructions.
+func (f *funcParser) instrs(list []*sexpr) error {
+	for len(list) > 0 {
+		s := list[0]
+		if s.tokenType == tokenList {
+			if err := f.folded(s); err != nil {
+				return err
+			}
+			list = list[1:]
+			continue
+		}
+
+		n, err := f.plain(list)
+		if err != nil {
+			return err
+		}
+		list = list[n:]
+	}
+	return nil
+}
+
+// plain encodes the plain instruction at the beginning of the list, returning the count of expressions it used.
+// Structured instructions are encoded in parts: "block", "loop", "if", "else" and "end" are each plain instructions.
+func (f *funcParser) plain(list []*sexpr) (int, error) {
+	s := list[0]
+	if s.tokenType != tokenKeyword {
+		return 0, s.errorf("expected an instruction, but was %s", s)
+	}
+
+	switch s.value {
+	case wasm.OpcodeBlockName, wasm.OpcodeLoopName, wasm.OpcodeIfName:
+		b, blockType, rest, err := f.blockHead(s, list[1:])
+		if err != nil {
+			return 0, err
+		}
+		f.beginBlock(b, blockType)
+		return len(list) - len(rest), nil
+	case wasm.OpcodeElseName, wasm.OpcodeEndName:
+		if len(f.blocks) <= f.foldedDepth || (s.value == wasm.OpcodeElseName && f.blocks[len(f.blocks)-1].opcode != wasm.OpcodeIf) {
+			return 0, s.errorf("unexpected %s", s.value)
+		}
+		n := 1
+		if len(list) > 1 && list[1].tokenType == tokenID {
+			if label := f.blocks[len(f.blocks)-1].label; label == nil || label.value != list[1].value {
+				return 0, list[1].errorf("mismatching label %s", list[1].value)
+			}
+			n++
+		}
+		if s.value == wasm.OpcodeElseName {
+			f.body = append(f.body, wasm.OpcodeElse)
+		} else {
+			f.endBlock()
+		}
+		return n, nil
+	}
+
+	code, rest, err := f.instr(s, list[1:])
+	if err != nil {
+		return 0, err
+	}
+	f.body = append(f.body, code...)
+	return len(list) - len(rest), nil
+}
+
+// folded encodes a folded instruction, such as "(i32.add (local.get 0) (i32.const 1))", where the operands are
+// encoded before the instruction.
+func (f *funcParser) folded(s *sexpr) error {
+	if len(s.list) == 0 || s.list[0].tokenType != tokenKeyword {
+		return s.errorf("expected an instruction")
+	}
+	op, list := s.list[0], s.list[1:]
+
+	switch op.value {
+	case wasm.OpcodeBlockName, wasm.OpcodeLoopName:
+		b, blockType, rest, err := f.blockHead(op, list)
+		if err != nil {
+			return err
+		}
+		f.beginBlock(b, blockType)
+		if err = f.foldedBody(rest); err != nil {
+			return err
+		}
+		f.endBlock()
+		return nil
+	case wasm.OpcodeIfName:
+		// The condition is encoded before the block, followed by the "then" clause, which must be present even if
+		// empty, and the optional "else" clause.
+		b, blockType, rest, err := f.blockHead(op, list)
+		if err != nil {
+			return err
+		}
+		cond := rest
+		for len(rest) > 0 && !rest[0].isList("then") {
+			rest = rest[1:]
+		}
+		if len(rest) == 0 {
+			return s.errorf("expected (then)")
+		} else if err = f.instrs(cond[:len(cond)-len(rest)]); err != nil {
+			return err
+		}
+		f.beginBlock(b, blockType)
+		if err = f.foldedBody(rest[0].list[1:]); err != nil {
+			return err
+		}
+		if rest = rest[1:]; len(rest) > 0 && rest[0].isList("else") {
+			f.body = append(f.body, wasm.OpcodeElse)
+			if err = f.foldedBody(rest[0].list[1:]); err != nil {
+				return err
+			}
+			rest = rest[1:]
+		}
+		if len(rest) > 0 {
+			return rest[0].errorf("unexpected %s after (then)", rest[0])
+		}
+		f.endBlock()
+		return nil
+	case wasm.OpcodeElseName, wasm.OpcodeEndName:
+		return op.errorf("unexpected %s", op.value)
+	}
+
+	code, operands, err := f.instr(op, list)
+	if err != nil {
+		return err
+	}
+	for _, operand := range operands {
+		if operand.tokenType != tokenList {
+			return operand.errorf("expected a folded instruction, but was %s", operand)
+		}
+	}
+	if err = f.instrs(operands); err != nil {
+		return err
+	}
+	f.body = append(f.body, code...)
+	return nil
+}
+
+// foldedBody encodes the instructions of a folded block, which must leave the block open for the folded instruction
+// to end.
+func (f *funcParser) foldedBody(list []*sexpr) error {
+	outer := f.foldedDepth
+	f.foldedDepth = len(f.blocks)
+	defer func() { f.foldedDepth = outer }()
+
+	if err := f.instrs(list); err != nil {
+		return err
+	} else if len(f.blocks) > f.foldedDepth {
+		op := f.blocks[len(f.blocks)-1].op
+		return op.errorf("missing end for %s", op.value)
+	}
+	return nil
+}
+
+// blockHead decodes the optional label and block type of a block, loop or if, returning the remaining expressions.
+func (f *funcParser) blockHead(op *sexpr, list []*sexpr) (b block, blockType []byte, rest []*sexpr, err error) {
+	b.op, b.opcode = op, instructions[op.value].opcode
+	if len(list) > 0 && list[0].tokenType == tokenID {
+		b.label, list = list[0], list[1:]
+	}
+	blockType, rest, err = f.blockType(list)
+	return
+}
+
+func (f *funcParser) beginBlock(b block, blockType []byte) {
+	f.blocks = append(f.blocks, b)
+	f.body = append(append(f.body, b.opcode), blockType...)
+}
+
+func (f *funcParser) endBlock() {
+	f.blocks = f.blocks[:len(f.blocks)-1]
+	f.body = append(f.body, wasm.OpcodeEnd)
+}
+
+// blockType encodes the type of a block, which is empty, a single result value type, or a type index.
+//
+// See https://www.w3.org/TR/2022/WD-wasm-core-2-20220419/binary/instructions.html#binary-blocktype
+func (f *funcParser) blockType(list []*sexpr) ([]byte, []*sexpr, error) {
+	if len(list) > 0 && list[0].isList("type") {
+		typeIdx, _, rest, err := f.p.decodeTypeUse(list)
+		if err != nil {
+			return nil, nil, err
+		}
+		return leb128.EncodeInt64(int64(typeIdx)), rest, nil
+	}
+
+	params, results, paramIDs, rest, err := decodeSignature(list)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, id := range paramIDs {
+		if id != nil {
+			return nil, nil, id.errorf("unexpected identifier in block type")
+		}
+	}
+	switch {
+	case len(params) == 0 && len(results) == 0:
+		return []byte{0x40}, rest, nil
+	case len(params) == 0 && len(results) == 1:
+		return []byte{results[0]}, rest, nil
+	}
+	return leb128.EncodeInt64(int64(f.p.typeIndex(params, results))), rest, nil
+}
+
+// label encodes the depth of a label, which is an identifier of an enclosing block or a number.
+func (f *funcParser) label(s *sexpr) ([]byte, error) {
+	if s.tokenType == tokenID {
+		for i := len(f.blocks) - 1; i >= 0; i-- {
+			if label := f.blocks[i].label; label != nil && label.value == s.value {
+				return leb128.EncodeUint32(uint32(len(f.blocks) - 1 - i)), nil
+			}
+		}
+		return nil, s.errorf("unknown label %s", s.value)
+	}
+	depth, err := decodeUint(s, 32)
+	if err != nil {
+		return nil, err
+	}
+	return leb128.EncodeUint32(uint32(depth)), nil
+}
+
+// naturalAlignments are the default alignment of memory instructions, as the exponent of a power of two.
+var naturalAlignments = map[wasm.Opcode]uint64{
+	wasm.OpcodeI32Load: 2, wasm.OpcodeI64Load: 3, wasm.OpcodeF32Load: 2, wasm.OpcodeF64Load: 3,
+	wasm.OpcodeI32Load8S: 0, wasm.OpcodeI32Load8U: 0, wasm.OpcodeI32Load16S: 1, wasm.OpcodeI32Load16U: 1,
+	wasm.OpcodeI64Load8S: 0, wasm.OpcodeI64Load8U: 0, wasm.OpcodeI64Load16S: 1, wasm.OpcodeI64Load16U: 1,
+	wasm.OpcodeI64Load32S: 2, wasm.OpcodeI64Load32U: 2,
+	wasm.OpcodeI32Store: 2, wasm.OpcodeI64Store: 3, wasm.OpcodeF32Store: 2, wasm.OpcodeF64Store: 3,
+	wasm.OpcodeI32Store8: 0, wasm.OpcodeI32Store16: 1,
+	wasm.OpcodeI64Store8: 0, wasm.OpcodeI64Store16: 1, wasm.OpcodeI64Store32: 2,
+}
+
+// instr encodes an instruction which isn't structured, consuming its immediates from the list and returning the rest.
+func (f *funcParser) instr(op *sexpr, list []*sexpr) (code []byte, rest []*sexpr, err error) {
+	in, ok := instructions[op.value]
+	if !ok {
+		return nil, nil, op.errorf("unknown instruction %s", op.value)
+	}
+	code = []byte{in.opcode}
+
+	// index consumes an optional immediate index resolved by the namespace, using zero if there is none.
+	index := func(ns *namespace, required bool) error {
+		if len(list) == 0 || !isIndex(list[0]) {
+			if required {
+				return op.errorf("expected %s index for %s", ns.kind, op.value)
+			}
+			code = append(code, 0)
+			return nil
+		}
+		idx, err := ns.resolve(list[0])
+		if err != nil {
+			return err
+		}
+		code, list = append(code, leb128.EncodeUint32(idx)...), list[1:]
+		return nil
+	}
+
+	switch in.opcode {
+	case wasm.OpcodeBr, wasm.OpcodeBrIf:
+		if len(list) == 0 {
+			return nil, nil, op.errorf("expected label for %s", op.value)
+		}
+		var depth []byte
+		if depth, err = f.label(list[0]); err != nil {
+			return
+		}
+		code, list = append(code, depth...), list[1:]
+	case wasm.OpcodeBrTable:
+		var labels [][]byte
+		for len(list) > 0 && isIndex(list[0]) {
+			var depth []byte
+			if depth, err = f.label(list[0]); err != nil {
+				return
+			}
+			labels, list = append(labels, depth), list[1:]
+		}
+		if len(labels) == 0 {
+			return nil, nil, op.errorf("expected labels for %s", op.value)
+		}
+		// The last label is the default, which isn't counted.
+		code = append(code, leb128.EncodeUint32(uint32(len(labels)-1))...)
+		for _, depth := range labels {
+			code = append(code, depth...)
+		}
+	case wasm.OpcodeCall, wasm.OpcodeReturnCall, wasm.OpcodeRefFunc:
+		err = index(f.p.funcs, true)
+	case wasm.OpcodeCallIndirect, wasm.OpcodeReturnCallIndirect:
+		// The table index precedes the type use in text, but follows it in binary.
+		tableIdx := []byte{0}
+		if len(list) > 0 && isIndex(list[0]) {
+			idx, err := f.p.tables.resolve(list[0])
+			if err != nil {
+				return nil, nil, err
+			}
+			tableIdx, list = leb128.EncodeUint32(idx), list[1:]
+		}
+		var typeIdx wasm.Index
+		var paramIDs []*sexpr
+		if typeIdx, paramIDs, list, err = f.p.decodeTypeUse(list); err != nil {
+			return
+		}
+		for _, id := range paramIDs {
+			if id != nil {
+				return nil, nil, id.errorf("unexpected identifier in %s", op.value)
+			}
+		}
+		code = append(append(code, leb128.EncodeUint32(typeIdx)...), tableIdx...)
+	case wasm.OpcodeLocalGet, wasm.OpcodeLocalSet, wasm.OpcodeLocalTee:
+		err = index(f.locals, true)
+	case wasm.OpcodeGlobalGet, wasm.OpcodeGlobalSet:
+		err = index(f.p.globals, true)
+	case wasm.OpcodeTableGet, wasm.OpcodeTableSet:
+		err = index(f.p.tables, false)
+	case wasm.OpcodeMemorySize, wasm.OpcodeMemoryGrow:
+		err = index(f.p.memories, false)
+	case wasm.OpcodeI32Const, wasm.OpcodeI64Const, wasm.OpcodeF32Const, wasm.OpcodeF64Const:
+		if len(list) == 0 {
+			return nil, nil, op.errorf("expected value for %s", op.value)
+		}
+		var expr wasm.ConstantExpression
+		if expr, err = f.p.decodeConstantExpression(op, []*sexpr{op, list[0]}); err != nil {
+			return
+		}
+		code, list = append(code, expr.Data...), list[1:]
+	case wasm.OpcodeSelect:
+		// A select with result types is the typed select, e.g. "select (result i32)".
+		var results []wasm.ValueType
+		for len(list) > 0 && list[0].isList("result") {
+			types, _, err := decodeValueTypeList(list[0], false)
+			if err != nil {
+				return nil, nil, err
+			}
+			results, list = append(results, types...), list[1:]
+		}
+		if results != nil {
+			code = append([]byte{wasm.OpcodeTypedSelect}, leb128.EncodeUint32(uint32(len(results)))...)
+			code = append(code, results...)
+		}
+	case wasm.OpcodeRefNull:
+		if len(list) == 0 {
+			return nil, nil, op.errorf("expected heap type for %s", op.value)
+		}
+		var refType wasm.RefType
+		if refType, err = decodeHeapType(list[0]); err != nil {
+			return
+		}
+		code, list = append(code, refType), list[1:]
+	case wasm.OpcodeMiscPrefix:
+		code = append(code, in.misc)
+		switch in.misc {
+		case wasm.OpcodeMiscMemoryInit:
+			f.p.usedDataIdx = true
+			if err = index(f.p.datas, true); err == nil {
+				code = append(code, 0) // memory index
+			}
+		case wasm.OpcodeMiscDataDrop:
+			f.p.usedDataIdx = true
+			err = index(f.p.datas, true)
+		case wasm.OpcodeMiscMemoryCopy:
+			code = append(code, 0, 0) // memory indices
+		case wasm.OpcodeMiscMemoryFill:
+			code = append(code, 0) // memory index
+		case wasm.OpcodeMiscTableInit:
+			// The table index is optional and precedes the element index in text, but follows it in binary.
+			tableIdx := []byte{0}
+			if len(list) > 1 && isIndex(list[0]) && isIndex(list[1]) {
+				idx, err := f.p.tables.resolve(list[0])
+				if err != nil {
+					return nil, nil, err
+				}
+				tableIdx, list = leb128.EncodeUint32(idx), list[1:]
+			}
+			if err = index(f.p.elems, true); err == nil {
+				code = append(code, tableIdx...)
+			}
+		case wasm.OpcodeMiscElemDrop:
+			err = index(f.p.elems, true)
+		case wasm.OpcodeMiscTableCopy:
+			// Either both the destination and source table are written, or neither.
+			if len(list) > 1 && isIndex(list[0]) && isIndex(list[1]) {
+				if err = index(f.p.tables, true); err == nil {
+					err = index(f.p.tables, true)
+				}
+			} else {
+				code = append(code, 0, 0)
+			}
+		case wasm.OpcodeMiscTableGrow, wasm.OpcodeMiscTableSize, wasm.OpcodeMiscTableFill:
+			err = index(f.p.tables, false)
+		}
+	default:
+		if align, ok := naturalAlignments[in.opcode]; ok {
+			var memArg []byte
+			if memArg, list, err = decodeMemArg(op, list, align); err != nil {
+				return
+			}
+			code = append(code, memArg...)
+		}
+	}
+	return code, list, err
+}
+
+// decodeMemArg encodes the optional offset and alignment of a memory instruction, such as "offset=4 align=2".
+func decodeMemArg(op *sexpr, list []*sexpr, align uint64) ([]byte, []*sexpr, error) {
+	var offset uint64
+	if len(list) > 0 && list[0].tokenType == tokenKeyword && len(list[0].value) > 7 && list[0].value[:7] == "offset=" {
+		var ok bool
+		if offset, ok = parseUint(list[0].value[7:], 64); !ok {
+			return nil, nil, list[0].errorf("invalid offset %s", list[0].value[7:])
+		}
+		list = list[1:]
+	}
+	if len(list) > 0 && list[0].tokenType == tokenKeyword && len(list[0].value) > 6 && list[0].value[:6] == "align=" {
+		a, ok := parseUint(list[0].value[6:], 32)
+		if !ok || bits.OnesCount64(a) != 1 {
+			return nil, nil, list[0].errorf("invalid alignment %s", list[0].value[6:])
+		}
+		align, list = uint64(bits.TrailingZeros64(a)), list[1:]
+	}
+	if len(list) > 0 && list[0].tokenType == tokenKeyword && (len(list[0].value) > 7 && list[0].value[:7] == "offset=") {
+		return nil, nil, list[0].errorf("offset must precede alignment in %s", op.value)
+	}
+	// The offset is only 64-bit for a 64-bit memory, which validation checks.
+	return append(leb128.EncodeUint32(uint32(align)), leb128.EncodeUint64(offset)...), list, nil
+}
@@ -0,0 +1,276 @@
+package text
+
+import (
+	"fmt"
+	"strconv"
+	"unicode/utf8"
+)
+
+// tokenType is the kind of token read from the WebAssembly Text Format.
+type tokenType byte
+
+const (
+	// tokenKeyword is a token beginning with a lowercase letter, such as "module", "i32.add" or "offset=4".
+	tokenKeyword tokenType = iota + 1
+	// tokenNum is an integer or float, such as "-1", "0x1p-2" or "1_000".
+	//
+	// Note: "inf" and "nan" are keywords, as the lexer cannot know whether they are used as floats.
+	tokenNum
+	// tokenString is a quoted string, whose value is the decoded bytes.
+	tokenString
+	// tokenID is an identifier such as "$main", whose value includes the leading '$'.
+	tokenID
+	// tokenList is a parenthesized list, such as "(i32.const 1)".
+	tokenList
+)
+
+// sexpr is a token or a list of them, read from the WebAssembly Text Format.
+//
+// See https://www.w3.org/TR/2019/REC-wasm-core-1-20191205/#lexical-format%E2%91%A0
+type sexpr struct {
+	tokenType tokenType
+	// value is the text of a token, or the decoded bytes of a tokenString.
+	value string
+	// list is the contents of a tokenList.
+	list []*sexpr
+	// line and col are the 1-based position of the token, or the opening paren of a tokenList.
+	line, col uint32
+}
+
+// isKeyword returns true if this is a tokenKeyword with the given value.
+func (s *sexpr) isKeyword(value string) bool {
+	return s.tokenType == tokenKeyword && s.value == value
+}
+
+// isList returns true if this is a tokenList starting with the given keyword.
+func (s *sexpr) isList(keyword string) bool {
+	return s.tokenType == tokenList && len(s.list) > 0 && s.list[0].isKeyword(keyword)
+}
+
+// String returns the token as written in the source, or the keyword of a list, for use in errors.
+func (s *sexpr) String() string {
+	switch s.tokenType {
+	case tokenString:
+		return strconv.Quote(s.value)
+	case tokenList:
+		if len(s.list) > 0 && s.list[0].tokenType == tokenKeyword {
+			return "(" + s.list[0].value + ")"
+		}
+		return "()"
+	}
+	return s.value
+}
+
+// errorf returns an error prefixed with the position of the expression, such as "2:5: unknown func $f".
+func (s *sexpr) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("%d:%d: %s", s.line, s.col, fmt.Sprintf(format, args...))
+}
+
+// lexer splits the WebAssembly Text Format into a tree of sexpr.
+type lexer struct {
+	source    []byte
+	pos       int
+	line, col uint32
+}
+
+// lex returns the top-level expressions of the source.
+func lex(source []byte) ([]*sexpr, error) {
+	l := &lexer{source: source, line: 1, col: 1}
+	var stack [][]*sexpr
+	var parens []*sexpr
+	var current []*sexpr
+	for {
+		if err := l.skipWhitespace(); err != nil {
+			return nil, err
+		}
+		if l.pos == len(l.source) {
+			break
+		}
+
+		line, col := l.line, l.col
+		switch c := l.source[l.pos]; c {
+		case '(':
+			l.advance(1)
+			stack = append(stack, current)
+			parens = append(parens, &sexpr{tokenType: tokenList, line: line, col: col})
+			current = nil
+		case ')':
+			if len(stack) == 0 {
+				return nil, fmt.Errorf("%d:%d: unbalanced ')'", line, col)
+			}
+			l.advance(1)
+			list := parens[len(parens)-1]
+			list.list = current
+			parens = parens[:len(parens)-1]
+			current = append(stack[len(stack)-1], list)
+			stack = stack[:len(stack)-1]
+		case '"':
+			value, err := l.string()
+			if err != nil {
+				return nil, err
+			}
+			current = append(current, &sexpr{tokenType: tokenString, value: value, line: line, col: col})
+		default:
+			start := l.pos
+			for l.pos < len(l.source) && isIDChar(l.source[l.pos]) {
+				l.advance(1)
+			}
+			if l.pos == start {
+				return nil, fmt.Errorf("%d:%d: unexpected character %q", line, col, c)
+			}
+			value := string(l.source[start:l.pos])
+			current = append(current, &sexpr{tokenType: classify(value), value: value, line: line, col: col})
+		}
+	}
+
+	if len(parens) > 0 {
+		open := parens[len(parens)-1]
+		return nil, fmt.Errorf("%d:%d: expected ')'", open.line, open.col)
+	}
+	return current, nil
+}
+
+// classify returns the tokenType of a token made of idchars.
+func classify(value string) tokenType {
+	switch c := value[0]; {
+	case c == '$':
+		return tokenID
+	case c >= '0' && c <= '9', c == '+', c == '-':
+		return tokenNum
+	}
+	return tokenKeyword
+}
+
+// advance moves the position forward n bytes, which must not include a newline.
+func (l *lexer) advance(n int) {
+	l.pos += n
+	l.col += uint32(n)
+}
+
+// skipWhitespace skips spaces, line comments (";; ...") and nestable block comments ("(; ... ;)").
+func (l *lexer) skipWhitespace() error {
+	for l.pos < len(l.source) {
+		switch c := l.source[l.pos]; {
+		case c == '\n':
+			l.pos++
+			l.line++
+			l.col = 1
+		case c == ' ', c == '\t', c == '\r':
+			l.advance(1)
+		case c == ';' && l.peek(1) == ';':
+			for l.pos < len(l.source) && l.source[l.pos] != '\n' {
+				l.advance(1)
+			}
+		case c == '(' && l.peek(1) == ';':
+			line, col := l.line, l.col
+			l.advance(2)
+			for depth := 1; depth > 0; {
+				if l.pos == len(l.source) {
+					return fmt.Errorf("%d:%d: unterminated block comment", line, col)
+				}
+				switch {
+				case l.source[l.pos] == '(' && l.peek(1) == ';':
+					depth++
+					l.advance(2)
+				case l.source[l.pos] == ';' && l.peek(1) == ')':
+					depth--
+					l.advance(2)
+				case l.source[l.pos] == '\n':
+					l.pos++
+					l.line++
+					l.col = 1
+				default:
+					l.advance(1)
+				}
+			}
+		default:
+			return nil
+		}
+	}
+	return nil
+}
+
+// peek returns the byte at the offset from the current position, or zero if past the end.
+func (l *lexer) peek(offset int) byte {
+	if l.pos+offset < len(l.source) {
+		return l.source[l.pos+offset]
+	}
+	return 0
+}
+
+// string decodes a quoted string, which may contain arbitrary bytes via escapes.
+//
+// See https://www.w3.org/TR/2019/REC-wasm-core-1-20191205/#strings%E2%91%A0
+func (l *lexer) string() (string, error) {
+	line, col := l.line, l.col
+	l.advance(1) // opening quote
+	var ret []byte
+	for {
+		if l.pos == len(l.source) || l.source[l.pos] == '\n' {
+			return "", fmt.Errorf("%d:%d: unterminated string", line, col)
+		}
+		c := l.source[l.pos]
+		switch {
+		case c == '"':
+			l.advance(1)
+			return string(ret), nil
+		case c == '\\':
+			escLine, escCol := l.line, l.col
+			l.advance(1)
+			switch e := l.peek(0); e {
+			case 't':
+				ret = append(ret, '\t')
+			case 'n':
+				ret = append(ret, '\n')
+			case 'r':
+				ret = append(ret, '\r')
+			case '"', '\'', '\\':
+				ret = append(ret, e)
+			case 'u':
+				end := l.pos + 1
+				for end < len(l.source) && l.source[end] != '}' {
+					end++
+				}
+				if l.peek(1) != '{' || end == len(l.source) {
+					return "", fmt.Errorf("%d:%d: invalid unicode escape", escLine, escCol)
+				}
+				r, err := strconv.ParseUint(string(l.source[l.pos+2:end]), 16, 32)
+				if err != nil || !utf8.ValidRune(rune(r)) {
+					return "", fmt.Errorf("%d:%d: invalid unicode escape", escLine, escCol)
+				}
+				ret = utf8.AppendRune(ret, rune(r))
+				l.advance(end - l.pos)
+			default:
+				if l.pos+2 > len(l.source) {
+					return "", fmt.Errorf("%d:%d: invalid escape", escLine, escCol)
+				}
+				b, err := strconv.ParseUint(string(l.source[l.pos:l.pos+2]), 16, 8)
+				if err != nil {
+					return "", fmt.Errorf("%d:%d: invalid escape", escLine, escCol)
+				}
+				ret = append(ret, byte(b))
+				l.advance(1)
+			}
+			l.advance(1)
+		default:
+			ret = append(ret, c)
+			l.advance(1)
+		}
+	}
+}
+
+// isIDChar returns true if the character can be used in a keyword, number or identifier.
+//
+// See https://www.w3.org/TR/2019/REC-wasm-core-1-20191205/#text-idchar
+func isIDChar(c byte) bool {
+	switch {
+	case c >= '0' && c <= '9', c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z':
+		return true
+	}
+	switch c {
+	case '!', '#', '$', '%', '&', '\'', '*', '+', '-', '.', '/',
+		':', '<', '=', '>', '?', '@', '\\', '^', '_', '`', '|', '~':
+		return true
+	}
+	return false
+}
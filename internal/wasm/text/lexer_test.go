@@ -0,0 +1,52 @@
+package text
+
+import (
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestLex(t *testing.T) {
+	exprs, err := lex([]byte(`;; line comment
+(module $m (; block (; nested ;) comment ;)
+  (data "a\t\"\41\u{e9}") 0x1_0 -inf)`))
+	require.NoError(t, err)
+	require.Equal(t, 1, len(exprs))
+
+	module := exprs[0]
+	require.Equal(t, tokenList, module.tokenType)
+	require.Equal(t, [2]uint32{2, 1}, [2]uint32{module.line, module.col})
+
+	var types []tokenType
+	var values []string
+	for _, s := range module.list {
+		types, values = append(types, s.tokenType), append(values, s.value)
+	}
+	require.Equal(t, []tokenType{tokenKeyword, tokenID, tokenList, tokenNum, tokenNum}, types)
+	require.Equal(t, []string{"module", "$m", "", "0x1_0", "-inf"}, values)
+
+	data := module.list[2]
+	require.Equal(t, [2]uint32{3, 3}, [2]uint32{data.line, data.col})
+	require.Equal(t, "a\t\"Aé", data.list[1].value)
+}
+
+func TestLex_errors(t *testing.T) {
+	tests := []struct {
+		input, expectedErr string
+	}{
+		{input: ")", expectedErr: "1:1: unbalanced ')'"},
+		{input: "(a\n (b)", expectedErr: "1:1: expected ')'"},
+		{input: "(; (; ;)", expectedErr: "1:1: unterminated block comment"},
+		{input: `"a` + "\n" + `"`, expectedErr: "1:1: unterminated string"},
+		{input: `"\u{110000}"`, expectedErr: "1:2: invalid unicode escape"},
+		{input: "(a {)", expectedErr: "1:4: unexpected character '{'"},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.input, func(t *testing.T) {
+			_, err := lex([]byte(tc.input))
+			require.EqualError(t, err, tc.expectedErr)
+		})
+	}
+}
@@ -0,0 +1,138 @@
+package text
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// decodeUint decodes an unsigned integer of the given bit size, such as an index or memory offset.
+//
+// See https://www.w3.org/TR/2019/REC-wasm-core-1-20191205/#integers%E2%91%A6
+func decodeUint(s *sexpr, bitSize int) (uint64, error) {
+	if s.tokenType != tokenNum || s.value[0] == '+' || s.value[0] == '-' {
+		return 0, s.errorf("expected an unsigned integer, but was %s", s)
+	}
+	ret, ok := parseUint(s.value, bitSize)
+	if !ok {
+		return 0, s.errorf("invalid u%d: %s", bitSize, s.value)
+	}
+	return ret, nil
+}
+
+// decodeInt decodes an integer of the given bit size, which may be written signed or unsigned. The result is the
+// two's complement bits of the value, e.g. both "-1" and "0xffffffff" are 0xffffffff for a 32-bit integer.
+//
+// See https://www.w3.org/TR/2019/REC-wasm-core-1-20191205/#integers%E2%91%A6
+func decodeInt(s *sexpr, bitSize int) (uint64, error) {
+	if s.tokenType != tokenNum {
+		return 0, s.errorf("expected an integer, but was %s", s)
+	}
+	text := s.value
+	negative := text[0] == '-'
+	if text[0] == '+' || negative {
+		text = text[1:]
+	}
+	v, ok := parseUint(text, bitSize)
+	if ok && negative {
+		if v > 1<<(bitSize-1) {
+			ok = false
+		}
+		v = -v
+	}
+	if !ok {
+		return 0, s.errorf("invalid i%d: %s", bitSize, s.value)
+	}
+	if bitSize == 32 {
+		v = uint64(uint32(v))
+	}
+	return v, nil
+}
+
+// parseUint parses a decimal or hexadecimal integer, which may contain underscores between digits.
+func parseUint(text string, bitSize int) (uint64, bool) {
+	base := 10
+	if strings.HasPrefix(text, "0x") {
+		base, text = 16, text[2:]
+	}
+	if !validUnderscores(text) {
+		return 0, false
+	}
+	v, err := strconv.ParseUint(strings.ReplaceAll(text, "_", ""), base, bitSize)
+	return v, err == nil
+}
+
+// decodeFloat decodes the bits of a float of the given bit size, which can be decimal, hexadecimal, "inf", "nan" or
+// "nan:0x" followed by the payload of the NaN.
+//
+// See https://www.w3.org/TR/2019/REC-wasm-core-1-20191205/#floating-point%E2%91%A6
+func decodeFloat(s *sexpr, bitSize int) (uint64, error) {
+	if s.tokenType != tokenNum && s.tokenType != tokenKeyword {
+		return 0, s.errorf("expected a float, but was %s", s)
+	}
+	ret, ok := parseFloat(s.value, bitSize)
+	if !ok {
+		return 0, s.errorf("invalid f%d: %s", bitSize, s.value)
+	}
+	return ret, nil
+}
+
+func parseFloat(text string, bitSize int) (uint64, bool) {
+	var sign uint64
+	if text[0] == '+' || text[0] == '-' {
+		if text[0] == '-' {
+			sign = 1 << (bitSize - 1)
+		}
+		text = text[1:]
+	}
+
+	// The exponent is all ones for both infinity and NaN.
+	mantissaBits := 52
+	if bitSize == 32 {
+		mantissaBits = 23
+	}
+	inf := uint64(1<<(bitSize-1)-1) &^ (1<<mantissaBits - 1)
+	switch {
+	case text == "inf":
+		return sign | inf, true
+	case text == "nan":
+		// The canonical NaN has only the most significant bit of the mantissa set.
+		return sign | inf | 1<<(mantissaBits-1), true
+	case strings.HasPrefix(text, "nan:0x"):
+		payload, ok := parseUint(text[4:], 64)
+		if !ok || payload == 0 || payload >= 1<<mantissaBits {
+			return 0, false
+		}
+		return sign | inf | payload, true
+	case text == "" || text[0] < '0' || text[0] > '9':
+		return 0, false
+	}
+
+	digits := text
+	if strings.HasPrefix(text, "0x") {
+		digits = text[2:]
+		// Unlike the text format, Go requires an exponent in hexadecimal floats.
+		if !strings.ContainsAny(digits, "pP") {
+			text += "p0"
+		}
+	}
+	for _, part := range strings.FieldsFunc(digits, func(r rune) bool { return strings.ContainsRune(".eEpP+-", r) }) {
+		if !validUnderscores(part) {
+			return 0, false
+		}
+	}
+
+	v, err := strconv.ParseFloat(strings.ReplaceAll(text, "_", ""), bitSize)
+	if err != nil {
+		return 0, false
+	}
+	if bitSize == 32 {
+		return sign | uint64(math.Float32bits(float32(v))), true
+	}
+	return sign | math.Float64bits(v), true
+}
+
+// validUnderscores returns true unless an underscore is at the start or end, or next to another underscore.
+func validUnderscores(digits string) bool {
+	return digits != "" && digits[0] != '_' && digits[len(digits)-1] != '_' && !strings.Contains(digits, "__")
+}
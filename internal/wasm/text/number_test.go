@@ -0,0 +1,74 @@
+package text
+
+import (
+	"math"
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestDecodeInt(t *testing.T) {
+	tests := []struct {
+		input    string
+		bitSize  int
+		expected uint64
+	}{
+		{input: "0", bitSize: 32, expected: 0},
+		{input: "+42", bitSize: 32, expected: 42},
+		{input: "-1", bitSize: 32, expected: math.MaxUint32},
+		{input: "-2147483648", bitSize: 32, expected: 0x80000000},
+		{input: "4294967295", bitSize: 32, expected: math.MaxUint32},
+		{input: "0xdead_beef", bitSize: 32, expected: 0xdeadbeef},
+		{input: "-1", bitSize: 64, expected: math.MaxUint64},
+		{input: "1_000_000", bitSize: 64, expected: 1000000},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.input, func(t *testing.T) {
+			v, err := decodeInt(&sexpr{tokenType: tokenNum, value: tc.input}, tc.bitSize)
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, v)
+		})
+	}
+
+	for _, input := range []string{"4294967296", "-2147483649", "1__0", "_1", "0x", "010x"} {
+		_, err := decodeInt(&sexpr{tokenType: tokenNum, value: input}, 32)
+		require.Error(t, err, input)
+	}
+}
+
+func TestDecodeFloat(t *testing.T) {
+	tests := []struct {
+		input    string
+		bitSize  int
+		expected uint64
+	}{
+		{input: "1.5", bitSize: 32, expected: uint64(math.Float32bits(1.5))},
+		{input: "-0", bitSize: 32, expected: 0x80000000},
+		{input: "1e3", bitSize: 64, expected: math.Float64bits(1000)},
+		{input: "0x1.8p1", bitSize: 64, expected: math.Float64bits(3)},
+		{input: "0x10", bitSize: 32, expected: uint64(math.Float32bits(16))},
+		{input: "1_000.5", bitSize: 64, expected: math.Float64bits(1000.5)},
+		{input: "inf", bitSize: 32, expected: 0x7f800000},
+		{input: "-inf", bitSize: 64, expected: 0xfff0000000000000},
+		{input: "nan", bitSize: 32, expected: 0x7fc00000},
+		{input: "-nan", bitSize: 64, expected: 0xfff8000000000000},
+		{input: "nan:0x1", bitSize: 32, expected: 0x7f800001},
+		{input: "nan:0xf_ffff_ffff_ffff", bitSize: 64, expected: 0x7fffffffffffffff},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.input, func(t *testing.T) {
+			v, err := decodeFloat(&sexpr{tokenType: tokenNum, value: tc.input}, tc.bitSize)
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, v)
+		})
+	}
+
+	for _, input := range []string{"nan:0x0", "nan:0x800000", "1e39", ".5", "1__0", "infinity"} {
+		_, err := decodeFloat(&sexpr{tokenType: tokenNum, value: input}, 32)
+		require.Error(t, err, input)
+	}
+}
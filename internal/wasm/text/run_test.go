@@ -0,0 +1,61 @@
+package text_test
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/internal/testing/binaryencoding"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasm/text"
+)
+
+// TestDecodeModule_run ensures decoded modules can be instantiated and called.
+func TestDecodeModule_run(t *testing.T) {
+	m, err := text.DecodeModule([]byte(`(module
+	(memory (export "memory") 1)
+	(func $fac (export "fac") (param i64) (result i64)
+		(if (result i64) (i64.eqz (local.get 0))
+			(then (i64.const 1))
+			(else (i64.mul (local.get 0) (call $fac (i64.sub (local.get 0) (i64.const 1)))))))
+	(func (export "extend") (param i32) (result i64)
+		local.get 0
+		i64.extend_i32_u
+		i64.extend16_s)
+	(func (export "sum") (param $n i32) (result i32) (local $acc i32)
+		(block $done
+			(loop $loop
+				(br_if $done (i32.eqz (local.get $n)))
+				(local.set $acc (i32.add (local.get $acc) (local.get $n)))
+				(local.set $n (i32.sub (local.get $n) (i32.const 1)))
+				(br $loop)))
+		local.get $acc)
+	(func (export "store") (param i32 f64)
+		(f64.store offset=8 align=4 (local.get 0) (local.get 1)))
+)`))
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	r := wazero.NewRuntime(ctx)
+	defer r.Close(ctx)
+	mod, err := r.Instantiate(ctx, binaryencoding.EncodeModule(m))
+	require.NoError(t, err)
+
+	for _, tc := range []struct {
+		name             string
+		params, expected []uint64
+	}{
+		{name: "fac", params: []uint64{5}, expected: []uint64{120}},
+		{name: "extend", params: []uint64{0x8000}, expected: []uint64{0xffffffffffff8000}},
+		{name: "sum", params: []uint64{100}, expected: []uint64{5050}},
+		{name: "store", params: []uint64{8, math.Float64bits(1.5)}},
+	} {
+		res, err := mod.ExportedFunction(tc.name).Call(ctx, tc.params...)
+		require.NoError(t, err, tc.name)
+		require.Equal(t, tc.expected, res, tc.name)
+	}
+	v, ok := mod.Memory().ReadFloat64Le(16)
+	require.True(t, ok)
+	require.Equal(t, 1.5, v)
+}
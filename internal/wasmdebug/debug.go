@@ -4,6 +4,7 @@
 package wasmdebug
 
 import (
+	"errors"
 	"fmt"
 	"runtime"
 	"runtime/debug"
@@ -86,19 +87,36 @@ func signature(funcName string, paramTypes []api.ValueType, resultTypes []api.Va
 	return ret.String()
 }
 
+// Frame is a frame of the wasm stack trace attached to an error by ErrorBuilder.
+type Frame struct {
+	// ModuleName is the name of the module from the name section, or empty.
+	ModuleName string
+	// FunctionIndex is the position in the function index space, prefixed with imported functions.
+	FunctionIndex uint32
+	// FunctionName is the name of the function from the name section, or empty.
+	FunctionName string
+	// Offset is the offset of the instruction in the code section, which is only known when the module has DWARF
+	// sections. Otherwise, this is zero.
+	Offset uint64
+	// File and Line are the source location of the instruction from the DWARF line table, or empty when unknown.
+	// See DWARFLines.Annotate.
+	File string
+	Line int
+}
+
 // ErrorBuilder helps build consistent errors, particularly adding a WASM stack trace.
 //
 // AddFrame should be called beginning at the frame that panicked until no more frames exist. Once done, call Format.
 type ErrorBuilder interface {
 	// AddFrame adds the next frame.
 	//
-	// * funcName should be from FuncName
+	// * frame identifies the function and, when known, the instruction
 	// * paramTypes should be from wasm.FunctionType
 	// * resultTypes should be from wasm.FunctionType
 	// * sources is the source code information for this frame and can be empty.
 	//
 	// Note: paramTypes and resultTypes are present because signature misunderstanding, mismatch or overflow are common.
-	AddFrame(funcName string, paramTypes, resultTypes []api.ValueType, sources []string)
+	AddFrame(frame Frame, paramTypes, resultTypes []api.ValueType, sources []string)
 
 	// FromRecovered returns an error with the wasm stack trace appended to it.
 	FromRecovered(recovered interface{}) error
@@ -109,7 +127,34 @@ func NewErrorBuilder() ErrorBuilder {
 }
 
 type stackTrace struct {
-	frames []string
+	frames    []string
+	structure []Frame
+}
+
+// StackTraceError is the error returned by ErrorBuilder.FromRecovered, which retains the frames of the stack trace in
+// its message.
+type StackTraceError struct {
+	err    error
+	Frames []Frame
+}
+
+// Error implements error.
+func (e *StackTraceError) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap returns the recovered error, if any.
+func (e *StackTraceError) Unwrap() error {
+	return errors.Unwrap(e.err)
+}
+
+// Frames returns the frames of the stack trace of the error, or nil if it has none.
+func Frames(err error) []Frame {
+	var stErr *StackTraceError
+	if errors.As(err, &stErr) {
+		return stErr.Frames
+	}
+	return nil
 }
 
 // GoRuntimeErrorTracePrefix is the prefix coming before the Go runtime stack trace included in the face of runtime.Error.
@@ -125,6 +170,10 @@ func (s *stackTrace) FromRecovered(recovered interface{}) error {
 		return exitErr
 	}
 
+	return &StackTraceError{err: s.format(recovered), Frames: s.structure}
+}
+
+func (s *stackTrace) format(recovered interface{}) error {
 	stack := strings.Join(s.frames, "\n\t")
 
 	// If the error was internal, don't mention it was recovered.
@@ -148,8 +197,9 @@ func (s *stackTrace) FromRecovered(recovered interface{}) error {
 }
 
 // AddFrame implements ErrorBuilder.AddFrame
-func (s *stackTrace) AddFrame(funcName string, paramTypes, resultTypes []api.ValueType, sources []string) {
-	sig := signature(funcName, paramTypes, resultTypes)
+func (s *stackTrace) AddFrame(frame Frame, paramTypes, resultTypes []api.ValueType, sources []string) {
+	s.structure = append(s.structure, frame)
+	sig := signature(FuncName(frame.ModuleName, frame.FunctionName, frame.FunctionIndex), paramTypes, resultTypes)
 	s.frames = append(s.frames, sig)
 	for _, source := range sources {
 		s.frames = append(s.frames, "\t"+source)
@@ -2,6 +2,7 @@ package wasmdebug
 
 import (
 	"errors"
+	"fmt"
 	"runtime"
 	"testing"
 
@@ -69,6 +70,8 @@ var (
 	rteErr       = testRuntimeErr("index out of bounds")
 	i32          = api.ValueTypeI32
 	i32i32i32i32 = []api.ValueType{i32, i32, i32, i32}
+	fdWrite      = Frame{ModuleName: "wasi_snapshot_preview1", FunctionIndex: 1, FunctionName: "fd_write"}
+	xy           = Frame{ModuleName: "x", FunctionName: "y"}
 )
 
 func TestErrorBuilder(t *testing.T) {
@@ -81,7 +84,7 @@ func TestErrorBuilder(t *testing.T) {
 		{
 			name: "one",
 			build: func(builder ErrorBuilder) error {
-				builder.AddFrame("x.y", nil, nil, nil)
+				builder.AddFrame(xy, nil, nil, nil)
 				return builder.FromRecovered(argErr)
 			},
 			expectedErr: `invalid argument (recovered by wazero)
@@ -92,8 +95,8 @@ wasm stack trace:
 		{
 			name: "two",
 			build: func(builder ErrorBuilder) error {
-				builder.AddFrame("wasi_snapshot_preview1.fd_write", i32i32i32i32, []api.ValueType{i32}, nil)
-				builder.AddFrame("x.y", nil, nil, nil)
+				builder.AddFrame(fdWrite, i32i32i32i32, []api.ValueType{i32}, nil)
+				builder.AddFrame(xy, nil, nil, nil)
 				return builder.FromRecovered(argErr)
 			},
 			expectedErr: `invalid argument (recovered by wazero)
@@ -105,9 +108,9 @@ wasm stack trace:
 		{
 			name: "wasmruntime.Error",
 			build: func(builder ErrorBuilder) error {
-				builder.AddFrame("wasi_snapshot_preview1.fd_write", i32i32i32i32, []api.ValueType{i32},
+				builder.AddFrame(fdWrite, i32i32i32i32, []api.ValueType{i32},
 					[]string{"/opt/homebrew/Cellar/tinygo/0.26.0/src/runtime/runtime_tinygowasm.go:73:6"})
-				builder.AddFrame("x.y", nil, nil, nil)
+				builder.AddFrame(xy, nil, nil, nil)
 				return builder.FromRecovered(wasmruntime.ErrRuntimeStackOverflow)
 			},
 			expectedErr: `wasm error: stack overflow
@@ -129,10 +132,25 @@ wasm stack trace:
 	}
 }
 
+func TestFrames(t *testing.T) {
+	builder := NewErrorBuilder()
+	builder.AddFrame(Frame{ModuleName: "x", FunctionIndex: 2, Offset: 42}, nil, nil, nil)
+	builder.AddFrame(xy, nil, nil, nil)
+	withStackTrace := builder.FromRecovered(wasmruntime.ErrRuntimeUnreachable)
+
+	require.EqualError(t, withStackTrace, `wasm error: unreachable
+wasm stack trace:
+	x.$2()
+	x.y()`)
+	require.Equal(t, []Frame{{ModuleName: "x", FunctionIndex: 2, Offset: 42}, xy}, Frames(withStackTrace))
+	require.Equal(t, []Frame{{ModuleName: "x", FunctionIndex: 2, Offset: 42}, xy}, Frames(fmt.Errorf("wrapped: %w", withStackTrace)))
+	require.Nil(t, Frames(argErr))
+}
+
 func TestErrorBuilderGoRuntimeError(t *testing.T) {
 	builder := NewErrorBuilder()
-	builder.AddFrame("wasi_snapshot_preview1.fd_write", i32i32i32i32, []api.ValueType{i32}, nil)
-	builder.AddFrame("x.y", nil, nil, nil)
+	builder.AddFrame(fdWrite, i32i32i32i32, []api.ValueType{i32}, nil)
+	builder.AddFrame(xy, nil, nil, nil)
 	withStackTrace := builder.FromRecovered(rteErr)
 
 	require.Equal(t, rteErr, errors.Unwrap(withStackTrace))
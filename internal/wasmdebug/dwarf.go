@@ -59,9 +59,54 @@ func (d *DWARFLines) Line(instructionOffset uint64) (ret []string) {
 	d.mux.Lock()
 	defer d.mux.Unlock()
 
+	le, lineReader, inlinedRoutines, ok := d.find(instructionOffset)
+	if !ok {
+		return
+	}
+	return formatLines(instructionOffset, &le, lineReader, inlinedRoutines)
+}
+
+// SourceLine returns the file and line of the given instructionOffset which is an offset in the code section of the
+// original Wasm binary. When the instruction is in an inlined function, this is its location in that function, i.e.
+// the first line returned by Line. ok is false if the info is not found.
+func (d *DWARFLines) SourceLine(instructionOffset uint64) (file string, line int, ok bool) {
+	if d == nil {
+		return
+	}
+	d.mux.Lock()
+	defer d.mux.Unlock()
+
+	le, _, _, found := d.find(instructionOffset)
+	if !found || le.File == nil {
+		return
+	}
+	return le.File.Name, le.Line, true
+}
+
+// Annotate sets the File and Line of frame like SourceLine does for its Offset, and returns the same as Line, so that
+// the frame can be passed to ErrorBuilder.AddFrame with a single lookup.
+func (d *DWARFLines) Annotate(frame *Frame) (sources []string) {
+	if d == nil {
+		return
+	}
+	d.mux.Lock()
+	defer d.mux.Unlock()
+
+	le, lineReader, inlinedRoutines, ok := d.find(frame.Offset)
+	if !ok {
+		return
+	}
+	if le.File != nil {
+		frame.File, frame.Line = le.File.Name, le.Line
+	}
+	return formatLines(frame.Offset, &le, lineReader, inlinedRoutines)
+}
+
+// find returns the line entry of the given instructionOffset, the reader it was read from, and the inlined routines
+// containing the instruction, from the outermost. ok is false if the info is not found. d.mux must be held.
+func (d *DWARFLines) find(instructionOffset uint64) (le dwarf.LineEntry, lineReader *dwarf.LineReader, inlinedRoutines []*dwarf.Entry, ok bool) {
 	r := d.d.Reader()
 
-	var inlinedRoutines []*dwarf.Entry
 	var cu *dwarf.Entry
 	var inlinedDone bool
 entry:
@@ -119,10 +164,9 @@ entry:
 		return
 	}
 	var lines []line
-	var ok bool
-	var le dwarf.LineEntry
+	var cached bool
 	// Get the lines inside the entry.
-	if lines, ok = d.linesPerEntry[cu.Offset]; !ok {
+	if lines, cached = d.linesPerEntry[cu.Offset]; !cached {
 		// If not found, we create the list of lines by reading all the LineEntries in the Entry.
 		//
 		// Note that the dwarf.LineEntry.SeekPC API shouldn't be used because the Go's dwarf package assumes that
@@ -176,7 +220,13 @@ entry:
 		// If we reach this block, that means there's a bug in the []line creation logic above.
 		panic("BUG: stored dwarf.LineReaderPos is invalid")
 	}
+	ok = true
+	return
+}
 
+// formatLines formats the line entry le of instructionOffset found by DWARFLines.find, followed by the calls of the
+// inlinedRoutines when it's in an inlined function.
+func formatLines(instructionOffset uint64, le *dwarf.LineEntry, lineReader *dwarf.LineReader, inlinedRoutines []*dwarf.Entry) (ret []string) {
 	// In the inlined case, the line info is the innermost inlined function call.
 	inlined := len(inlinedRoutines) != 0
 	prefix := fmt.Sprintf("%#x: ", instructionOffset)
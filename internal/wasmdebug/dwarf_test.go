@@ -11,10 +11,11 @@ import (
 	"github.com/tetratelabs/wazero/internal/testing/require"
 	"github.com/tetratelabs/wazero/internal/wasm"
 	"github.com/tetratelabs/wazero/internal/wasm/binary"
+	"github.com/tetratelabs/wazero/internal/wasmdebug"
 )
 
 func TestDWARFLines_Line_Zig(t *testing.T) {
-	mod, err := binary.DecodeModule(dwarftestdata.ZigWasm, api.CoreFeaturesV2, wasm.MemoryLimitPages, false, true, false)
+	mod, err := binary.DecodeModule(dwarftestdata.ZigWasm, api.CoreFeaturesV2, wasm.MemoryLimitPages, false, true, false, wasm.DefaultDecodeLimits)
 	require.NoError(t, err)
 	require.NotNil(t, mod.DWARFLines)
 
@@ -72,11 +73,40 @@ func TestDWARFLines_Line_Zig(t *testing.T) {
 	}
 }
 
+func TestDWARFLines_SourceLine(t *testing.T) {
+	mod, err := binary.DecodeModule(dwarftestdata.ZigWasm, api.CoreFeaturesV2, wasm.MemoryLimitPages, false, true, false, wasm.DefaultDecodeLimits)
+	require.NoError(t, err)
+
+	// See TestDWARFLines_Line_Zig for these offsets.
+	const codeSecStart = 0x46
+
+	file, line, ok := mod.DWARFLines.SourceLine(0xa9 - codeSecStart)
+	require.True(t, ok)
+	require.True(t, strings.HasSuffix(file, "lib/std/builtin.zig"), file)
+	require.Equal(t, 889, line)
+
+	// The location in the innermost inlined function.
+	file, line, ok = mod.DWARFLines.SourceLine(0x6b - codeSecStart)
+	require.True(t, ok)
+	require.True(t, strings.HasSuffix(file, "zig/main.zig"), file)
+	require.Equal(t, 10, line)
+
+	frame := wasmdebug.Frame{Offset: 0x6b - codeSecStart}
+	sources := mod.DWARFLines.Annotate(&frame)
+	require.Equal(t, mod.DWARFLines.Line(frame.Offset), sources)
+	require.Equal(t, file, frame.File)
+	require.Equal(t, 10, frame.Line)
+
+	var nilLines *wasmdebug.DWARFLines
+	_, _, ok = nilLines.SourceLine(0)
+	require.False(t, ok)
+}
+
 func TestDWARFLines_Line_Rust(t *testing.T) {
 	if len(dwarftestdata.RustWasm) == 0 {
 		t.Skip()
 	}
-	mod, err := binary.DecodeModule(dwarftestdata.RustWasm, api.CoreFeaturesV2, wasm.MemoryLimitPages, false, true, false)
+	mod, err := binary.DecodeModule(dwarftestdata.RustWasm, api.CoreFeaturesV2, wasm.MemoryLimitPages, false, true, false, wasm.DefaultDecodeLimits)
 	require.NoError(t, err)
 	require.NotNil(t, mod.DWARFLines)
 
@@ -200,7 +230,7 @@ func TestDWARFLines_Line_Rust(t *testing.T) {
 }
 
 func TestDWARFLines_Line_TinyGo(t *testing.T) {
-	mod, err := binary.DecodeModule(dwarftestdata.TinyGoWasm, api.CoreFeaturesV2, wasm.MemoryLimitPages, false, true, false)
+	mod, err := binary.DecodeModule(dwarftestdata.TinyGoWasm, api.CoreFeaturesV2, wasm.MemoryLimitPages, false, true, false, wasm.DefaultDecodeLimits)
 	require.NoError(t, err)
 	require.NotNil(t, mod.DWARFLines)
 
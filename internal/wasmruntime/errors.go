@@ -27,6 +27,18 @@ var (
 	ErrRuntimeInvalidTableAccess = New("invalid table access")
 	// ErrRuntimeIndirectCallTypeMismatch indicates that the type check failed during call_indirect.
 	ErrRuntimeIndirectCallTypeMismatch = New("indirect call type mismatch")
+	// ErrRuntimeUnalignedAtomic indicates that an atomic instruction accessed memory at an effective address which
+	// isn't a multiple of the size of the access.
+	ErrRuntimeUnalignedAtomic = New("unaligned atomic")
+	// ErrRuntimeCallStackExhausted indicates that the nesting of calls into Wasm functions exceeded the limit set by
+	// wazero.ModuleConfig WithMaxCallDepth.
+	ErrRuntimeCallStackExhausted = New("call stack exhausted")
+	// ErrRuntimeUncaughtException indicates that an exception thrown by the program wasn't caught by any of the
+	// functions it propagated through.
+	ErrRuntimeUncaughtException = New("uncaught exception")
+	// ErrRuntimeUninitializedMemoryRead indicates that the program read bytes of memory which were never written, as
+	// detected by wazero.RuntimeConfig WithMemorySanitizer.
+	ErrRuntimeUninitializedMemoryRead = New("uninitialized memory read")
 )
 
 // Error is returned by a wasm.Engine during the execution of Wasm functions, and they indicate that the Wasm runtime
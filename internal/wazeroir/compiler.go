@@ -8,6 +8,7 @@ import (
 	"strings"
 
 	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
 	"github.com/tetratelabs/wazero/internal/leb128"
 	"github.com/tetratelabs/wazero/internal/wasm"
 )
@@ -183,6 +184,10 @@ type Compiler struct {
 	// globals holds the global types for all declared globals in the module where the target function exists.
 	globals []wasm.GlobalType
 
+	// memory64 holds whether each memory in the module where the target function exists is 64-bit, or is nil if
+	// none of them are.
+	memory64 []bool
+
 	// needSourceOffset is true if this module requires DWARF based stack trace.
 	needSourceOffset bool
 	// bodyOffsetInCodeSection is the offset of the body of this function in the original Wasm binary's code section.
@@ -296,6 +301,18 @@ func NewCompiler(enabledFeatures api.CoreFeatures, callFrameStackSizeInUint64 in
 		},
 		needSourceOffset: module.DWARFLines != nil,
 	}
+	if mem != nil {
+		memory64 := mem.Is64
+		for i := range module.AdditionalMemorySection {
+			memory64 = memory64 || module.AdditionalMemorySection[i].Is64
+		}
+		if memory64 {
+			c.memory64 = append(c.memory64, mem.Is64)
+			for i := range module.AdditionalMemorySection {
+				c.memory64 = append(c.memory64, module.AdditionalMemorySection[i].Is64)
+			}
+		}
+	}
 	return c, nil
 }
 
@@ -392,6 +409,12 @@ func (c *Compiler) handleInstruction() error {
 		peekValueType = c.stackPeek()
 	}
 
+	if c.memory64 != nil && !c.unreachableState.on {
+		if err := c.narrowMemory64Operands(op); err != nil {
+			return err
+		}
+	}
+
 	// Modify the stack according the current instruction.
 	// Note that some instructions will read "index" in
 	// applyToStack and advance c.pc inside the function.
@@ -781,6 +804,58 @@ operatorSwitch:
 		c.emit(
 			NewOperationCallIndirect(typeIndex, tableIndex),
 		)
+	case wasm.OpcodeReturnCall, wasm.OpcodeReturnCallIndirect:
+		var tableIndex uint32
+		if op == wasm.OpcodeReturnCallIndirect {
+			var n uint64
+			tableIndex, n, err = leb128.LoadUint32(c.body[c.pc+1:])
+			if err != nil {
+				return fmt.Errorf("read table index for %s: %w", wasm.OpcodeReturnCallIndirectName, err)
+			}
+			c.pc += n
+		}
+		if c.unreachableState.on {
+			break operatorSwitch
+		}
+
+		var typ *wasm.FunctionType
+		var call UnionOperation
+		if op == wasm.OpcodeReturnCall {
+			typ = &c.types[c.funcs[index]]
+			call = NewOperationReturnCall(index)
+		} else {
+			typ = &c.types[index]
+			call = NewOperationReturnCallIndirect(index, tableIndex)
+		}
+
+		if c.ensureTermination {
+			// Tail calls can loop forever without growing the stack, so check the exit code as loop headers do.
+			c.emit(NewOperationBuiltinFunctionCheckExitCode())
+		}
+		functionFrame := c.controlFrames.functionFrame()
+		if c.callFrameStackSizeInUint64 > 0 {
+			// The call frame of this function is below its locals, so the frame cannot be replaced by the callee's.
+			// Instead, this is a call followed by return, where the stack already has the callee's results.
+			c.emit(call)
+			c.emit(NewOperationDrop(c.getFrameDropRange(functionFrame, false)))
+		} else {
+			// The stack already has the callee's results instead of its arguments. Drop all the values in the function
+			// frame, including locals, except the arguments (and the table offset) so that the callee can replace this frame.
+			inputs := typ.ParamNumInUint64
+			if op == wasm.OpcodeReturnCallIndirect {
+				inputs++ // the offset in the table.
+			}
+			if end := c.stackLenInUint64(len(c.stack)) - typ.ResultNumInUint64 + inputs - 1; inputs <= end {
+				c.emit(NewOperationDrop(InclusiveRange{Start: int32(inputs), End: int32(end)}))
+			}
+			c.emit(call)
+		}
+
+		// Engines which don't reuse the frame return the callee's results, which are all that is left on the stack.
+		c.emit(NewOperationBr(functionFrame.asLabel()))
+
+		// return_call operation is stack-polymorphic, and mark the state as unreachable.
+		c.markUnreachable()
 	case wasm.OpcodeDrop:
 		r := InclusiveRange{Start: 0, End: 0}
 		if peekValueType == UnsignedTypeV128 {
@@ -1019,16 +1094,25 @@ operatorSwitch:
 		)
 	case wasm.OpcodeMemorySize:
 		c.result.UsesMemory = true
-		c.pc++ // Skip the reserved one byte.
+		memoryIndex, err := c.readMemoryIndex(wasm.OpcodeMemorySizeName)
+		if err != nil {
+			return err
+		}
 		c.emit(
-			NewOperationMemorySize(),
+			NewOperationMemorySize(memoryIndex),
 		)
+		c.extendMemory64Result(memoryIndex, false)
 	case wasm.OpcodeMemoryGrow:
 		c.result.UsesMemory = true
-		c.pc++ // Skip the reserved one byte.
+		memoryIndex, err := c.readMemoryIndex(wasm.OpcodeMemoryGrowName)
+		if err != nil {
+			return err
+		}
 		c.emit(
-			NewOperationMemoryGrow(),
+			NewOperationMemoryGrow(memoryIndex),
 		)
+		// Sign-extended so that the failure is still -1.
+		c.extendMemory64Result(memoryIndex, true)
 	case wasm.OpcodeI32Const:
 		val, num, err := leb128.LoadInt32(c.body[c.pc+1:])
 		if err != nil {
@@ -1658,9 +1742,13 @@ operatorSwitch:
 			if err != nil {
 				return fmt.Errorf("reading i32.const value: %v", err)
 			}
-			c.pc += num + 1 // +1 to skip the memory index which is fixed to zero.
+			c.pc += num
+			memoryIndex, err := c.readMemoryIndex(wasm.OpcodeMemoryInitName)
+			if err != nil {
+				return err
+			}
 			c.emit(
-				NewOperationMemoryInit(dataIndex),
+				NewOperationMemoryInit(dataIndex, memoryIndex),
 			)
 		case wasm.OpcodeMiscDataDrop:
 			dataIndex, num, err := leb128.LoadUint32(c.body[c.pc+1:])
@@ -1673,15 +1761,25 @@ operatorSwitch:
 			)
 		case wasm.OpcodeMiscMemoryCopy:
 			c.result.UsesMemory = true
-			c.pc += 2 // +2 to skip two memory indexes which are fixed to zero.
+			dstMemoryIndex, err := c.readMemoryIndex(wasm.OpcodeMemoryCopyName)
+			if err != nil {
+				return err
+			}
+			srcMemoryIndex, err := c.readMemoryIndex(wasm.OpcodeMemoryCopyName)
+			if err != nil {
+				return err
+			}
 			c.emit(
-				NewOperationMemoryCopy(),
+				NewOperationMemoryCopy(dstMemoryIndex, srcMemoryIndex),
 			)
 		case wasm.OpcodeMiscMemoryFill:
 			c.result.UsesMemory = true
-			c.pc += 1 // +1 to skip the memory index which is fixed to zero.
+			memoryIndex, err := c.readMemoryIndex(wasm.OpcodeMemoryFillName)
+			if err != nil {
+				return err
+			}
 			c.emit(
-				NewOperationMemoryFill(),
+				NewOperationMemoryFill(memoryIndex),
 			)
 		case wasm.OpcodeMiscTableInit:
 			elemIndex, num, err := leb128.LoadUint32(c.body[c.pc+1:])
@@ -1756,8 +1854,20 @@ operatorSwitch:
 		default:
 			return fmt.Errorf("unsupported misc instruction in wazeroir: 0x%x", op)
 		}
+	case wasm.OpcodeAtomicPrefix:
+		c.pc++
+		if err := c.compileAtomic(c.body[c.pc]); err != nil {
+			return err
+		}
 	case wasm.OpcodeVecPrefix:
 		c.pc++
+		if relaxedOp, ok := wasm.VecRelaxedOpcode(c.body[c.pc:]); ok {
+			c.pc++ // relaxed-SIMD opcodes take two bytes.
+			if err := c.compileVecRelaxed(relaxedOp); err != nil {
+				return err
+			}
+			break
+		}
 		switch vecOp := c.body[c.pc]; vecOp {
 		case wasm.OpcodeVecV128Const:
 			c.pc++
@@ -2857,6 +2967,255 @@ operatorSwitch:
 	return nil
 }
 
+// compileVecRelaxed emits the operations of the relaxed-SIMD instruction, which are those of the deterministic
+// instructions documented on experimental.CoreFeaturesRelaxedSIMD.
+//
+// Instructions without an equivalent are composed of several operations, which copy their operands with
+// OperationKindPick and drop them afterwards. Note: a vector takes two slots of the uint64 value stack.
+func (c *Compiler) compileVecRelaxed(op wasm.OpcodeVecRelaxed) error {
+	switch op {
+	case wasm.OpcodeVecRelaxedI8x16Swizzle:
+		c.emit(NewOperationV128Swizzle())
+	case wasm.OpcodeVecRelaxedI32x4TruncF32x4S:
+		c.emit(NewOperationV128ITruncSatFromF(ShapeF32x4, true))
+	case wasm.OpcodeVecRelaxedI32x4TruncF32x4U:
+		c.emit(NewOperationV128ITruncSatFromF(ShapeF32x4, false))
+	case wasm.OpcodeVecRelaxedI32x4TruncF64x2SZero:
+		c.emit(NewOperationV128ITruncSatFromF(ShapeF64x2, true))
+	case wasm.OpcodeVecRelaxedI32x4TruncF64x2UZero:
+		c.emit(NewOperationV128ITruncSatFromF(ShapeF64x2, false))
+	case wasm.OpcodeVecRelaxedF32x4Madd:
+		c.emitVecRelaxedMadd(ShapeF32x4, false)
+	case wasm.OpcodeVecRelaxedF32x4Nmadd:
+		c.emitVecRelaxedMadd(ShapeF32x4, true)
+	case wasm.OpcodeVecRelaxedF64x2Madd:
+		c.emitVecRelaxedMadd(ShapeF64x2, false)
+	case wasm.OpcodeVecRelaxedF64x2Nmadd:
+		c.emitVecRelaxedMadd(ShapeF64x2, true)
+	case wasm.OpcodeVecRelaxedI8x16Laneselect, wasm.OpcodeVecRelaxedI16x8Laneselect,
+		wasm.OpcodeVecRelaxedI32x4Laneselect, wasm.OpcodeVecRelaxedI64x2Laneselect:
+		c.emit(NewOperationV128Bitselect())
+	case wasm.OpcodeVecRelaxedF32x4Min:
+		c.emit(NewOperationV128Min(ShapeF32x4, false))
+	case wasm.OpcodeVecRelaxedF32x4Max:
+		c.emit(NewOperationV128Max(ShapeF32x4, false))
+	case wasm.OpcodeVecRelaxedF64x2Min:
+		c.emit(NewOperationV128Min(ShapeF64x2, false))
+	case wasm.OpcodeVecRelaxedF64x2Max:
+		c.emit(NewOperationV128Max(ShapeF64x2, false))
+	case wasm.OpcodeVecRelaxedI16x8Q15mulrS:
+		c.emit(NewOperationV128Q15mulrSatS())
+	case wasm.OpcodeVecRelaxedI16x8DotI8x16I7x16S:
+		c.emitVecRelaxedDot()
+	case wasm.OpcodeVecRelaxedI32x4DotI8x16I7x16AddS:
+		// [a, b, acc] -> [a, b, acc, a, b] -> [a, b, acc, dot] -> [a, b, acc+extadd_pairwise(dot)] -> [result]
+		c.emit(NewOperationPick(5, true))
+		c.emit(NewOperationPick(5, true))
+		c.emitVecRelaxedDot()
+		c.emit(NewOperationV128ExtAddPairwise(ShapeI16x8, true))
+		c.emit(NewOperationV128Add(ShapeI32x4))
+		c.emit(NewOperationDrop(InclusiveRange{Start: 2, End: 5}))
+	default:
+		return fmt.Errorf("unsupported relaxed-SIMD instruction in wazeroir: %s", wasm.VecRelaxedInstructionName(op))
+	}
+	return nil
+}
+
+// emitVecRelaxedMadd emits a*b+c, or -(a*b)+c when negate is true, for the operands [a, b, c] on the top of the stack.
+func (c *Compiler) emitVecRelaxedMadd(shape Shape, negate bool) {
+	// [a, b, c] -> [a, b, c, a, b] -> [a, b, c, a*b]
+	c.emit(NewOperationPick(5, true))
+	c.emit(NewOperationPick(5, true))
+	c.emit(NewOperationV128Mul(shape))
+	if negate {
+		c.emit(NewOperationV128Neg(shape))
+	}
+	// [a, b, c, a*b] -> [a, b, c, a*b, c] -> [a, b, c, a*b+c] -> [a*b+c]
+	c.emit(NewOperationPick(3, true))
+	c.emit(NewOperationV128Add(shape))
+	c.emit(NewOperationDrop(InclusiveRange{Start: 2, End: 7}))
+}
+
+// emitVecRelaxedDot emits i16x8.relaxed_dot_i8x16_i7x16_s for the operands [a, b] on the top of the stack, as the
+// narrowing with signed saturation of the pairwise sums of the products of their signed lanes.
+func (c *Compiler) emitVecRelaxedDot() {
+	// [a, b] -> [a, b, a, b] -> [a, b, lo], where lo are the sums of the products of the low lanes as i32x4.
+	c.emit(NewOperationPick(3, true))
+	c.emit(NewOperationPick(3, true))
+	c.emit(NewOperationV128ExtMul(ShapeI8x16, true, true))
+	c.emit(NewOperationV128ExtAddPairwise(ShapeI16x8, true))
+	// [a, b, lo] -> [a, b, lo, a, b] -> [a, b, lo, hi]
+	c.emit(NewOperationPick(5, true))
+	c.emit(NewOperationPick(5, true))
+	c.emit(NewOperationV128ExtMul(ShapeI8x16, true, false))
+	c.emit(NewOperationV128ExtAddPairwise(ShapeI16x8, true))
+	// [a, b, lo, hi] -> [a, b, narrow(lo, hi)] -> [narrow(lo, hi)]
+	c.emit(NewOperationV128Narrow(ShapeI32x4, true))
+	c.emit(NewOperationDrop(InclusiveRange{Start: 2, End: 5}))
+}
+
+// compileAtomic emits the operations of the atomic instruction, with the single-threaded semantics documented on
+// experimental.CoreFeaturesThreads. c.pc must point to its OpcodeAtomic.
+//
+// Each instruction which accesses memory first checks the alignment of its effective address with
+// OperationKindAtomicCheckAlignment, then is composed of the non-atomic operations, copying its operands with
+// OperationKindPick and dropping them afterwards.
+func (c *Compiler) compileAtomic(op wasm.OpcodeAtomic) error {
+	name := wasm.AtomicInstructionName(op)
+	if name == "" {
+		return fmt.Errorf("unsupported atomic instruction in wazeroir: 0x%x", op)
+	}
+	if op == wasm.OpcodeAtomicFence {
+		c.pc++ // reserved byte
+		return nil
+	}
+	arg, err := c.readMemoryArg(name)
+	if err != nil {
+		return err
+	}
+	t, size := wasm.AtomicMemoryAccess(op)
+	unsignedType, unsignedInt := UnsignedTypeI32, UnsignedInt32
+	if t == wasm.ValueTypeI64 {
+		unsignedType, unsignedInt = UnsignedTypeI64, UnsignedInt64
+	}
+
+	switch {
+	case op == wasm.OpcodeAtomicMemoryNotify:
+		// [addr, count] -> [0], after the checks of the address, as there are no waiters.
+		c.emitAtomicCheckAlignment(1, arg, size)
+		c.emit(NewOperationPick(1, false))
+		c.emitAtomicLoad(unsignedType, size, arg)
+		c.emit(NewOperationDrop(InclusiveRange{Start: 0, End: 2}))
+		c.emit(NewOperationConstI32(0))
+	case op == wasm.OpcodeAtomicMemoryWait32 || op == wasm.OpcodeAtomicMemoryWait64:
+		// [addr, expected, timeout] -> [addr, expected, timeout, loaded != expected] -> [2 - (loaded != expected)],
+		// which is one ("not-equal") or two ("timed-out") as there is no other thread to notify.
+		c.emitAtomicCheckAlignment(2, arg, size)
+		c.emit(NewOperationPick(2, false))
+		c.emitAtomicLoad(unsignedType, size, arg)
+		c.emit(NewOperationPick(2, false))
+		c.emit(NewOperationNe(unsignedType))
+		c.emit(NewOperationConstI32(2))
+		c.emit(NewOperationPick(1, false))
+		c.emit(NewOperationSub(UnsignedTypeI32))
+		c.emit(NewOperationDrop(InclusiveRange{Start: 1, End: 4}))
+	case op < wasm.OpcodeAtomicI32Store:
+		c.emitAtomicCheckAlignment(0, arg, size)
+		c.emitAtomicLoad(unsignedType, size, arg)
+	case op < wasm.OpcodeAtomicI32RmwAdd:
+		c.emitAtomicCheckAlignment(1, arg, size)
+		c.emitAtomicStore(unsignedType, size, arg)
+	case op < wasm.OpcodeAtomicI32RmwCmpxchg:
+		// [addr, v] -> [addr, v, old] -> [addr, v, old, addr, rmw(old, v)] -> [addr, v, old] -> [old]
+		c.emitAtomicCheckAlignment(1, arg, size)
+		c.emit(NewOperationPick(1, false))
+		c.emitAtomicLoad(unsignedType, size, arg)
+		c.emit(NewOperationPick(2, false))
+		rmw := (op - wasm.OpcodeAtomicI32RmwAdd) / 7
+		if rmw == (wasm.OpcodeAtomicI32RmwXchg-wasm.OpcodeAtomicI32RmwAdd)/7 {
+			c.emit(NewOperationPick(2, false))
+		} else {
+			c.emit(NewOperationPick(1, false))
+			c.emit(NewOperationPick(3, false))
+			switch rmw {
+			case 0:
+				c.emit(NewOperationAdd(unsignedType))
+			case 1:
+				c.emit(NewOperationSub(unsignedType))
+			case 2:
+				c.emit(NewOperationAnd(unsignedInt))
+			case 3:
+				c.emit(NewOperationOr(unsignedInt))
+			default:
+				c.emit(NewOperationXor(unsignedInt))
+			}
+		}
+		c.emitAtomicStore(unsignedType, size, arg)
+		c.emit(NewOperationDrop(InclusiveRange{Start: 1, End: 2}))
+	default:
+		// [addr, expected, replacement] -> [addr, expected, replacement, old]
+		c.emitAtomicCheckAlignment(2, arg, size)
+		c.emit(NewOperationPick(2, false))
+		c.emitAtomicLoad(unsignedType, size, arg)
+		// -> [addr, expected, replacement, old, addr, replacement, old, old == expected]
+		c.emit(NewOperationPick(3, false))
+		c.emit(NewOperationPick(2, false))
+		c.emit(NewOperationPick(2, false))
+		c.emit(NewOperationPick(3, false))
+		c.emit(NewOperationPick(6, false))
+		if bits := size * 8; bits < 32 || (bits == 32 && t == wasm.ValueTypeI64) {
+			// The expected value is compared with the loaded one, which is zero-extended.
+			if t == wasm.ValueTypeI64 {
+				c.emit(NewOperationConstI64(1<<bits - 1))
+			} else {
+				c.emit(NewOperationConstI32(1<<bits - 1))
+			}
+			c.emit(NewOperationAnd(unsignedInt))
+		}
+		c.emit(NewOperationEq(unsignedType))
+		// -> [addr, expected, replacement, old, addr, replacement or old] -> [addr, expected, replacement, old] -> [old]
+		c.emit(NewOperationSelect(false))
+		c.emitAtomicStore(unsignedType, size, arg)
+		c.emit(NewOperationDrop(InclusiveRange{Start: 1, End: 3}))
+	}
+	return nil
+}
+
+// emitAtomicCheckAlignment emits the check that the effective address of the atomic access of the given size is
+// aligned, where the address is at the given depth of the stack.
+func (c *Compiler) emitAtomicCheckAlignment(depth int, arg MemoryArg, size uint32) {
+	if size == 1 {
+		return // always aligned
+	}
+	c.emit(NewOperationPick(depth, false))
+	if arg.Offset != 0 {
+		// The low bits of the effective address are the same when the addition wraps.
+		c.emit(NewOperationConstI32(arg.Offset))
+		c.emit(NewOperationAdd(UnsignedTypeI32))
+	}
+	c.emit(NewOperationAtomicCheckAlignment(size - 1))
+}
+
+// emitAtomicLoad emits the load of the given size, zero-extended to the given type.
+func (c *Compiler) emitAtomicLoad(t UnsignedType, size uint32, arg MemoryArg) {
+	signedInt := SignedUint32
+	if t == UnsignedTypeI64 {
+		signedInt = SignedUint64
+	}
+	switch size {
+	case 1:
+		c.emit(NewOperationLoad8(signedInt, arg))
+	case 2:
+		c.emit(NewOperationLoad16(signedInt, arg))
+	case 4:
+		if t == UnsignedTypeI64 {
+			c.emit(NewOperationLoad32(false, arg))
+		} else {
+			c.emit(NewOperationLoad(t, arg))
+		}
+	default:
+		c.emit(NewOperationLoad(t, arg))
+	}
+}
+
+// emitAtomicStore emits the store of the given size, which truncates values of a larger type.
+func (c *Compiler) emitAtomicStore(t UnsignedType, size uint32, arg MemoryArg) {
+	switch size {
+	case 1:
+		c.emit(NewOperationStore8(arg))
+	case 2:
+		c.emit(NewOperationStore16(arg))
+	case 4:
+		if t == UnsignedTypeI64 {
+			c.emit(NewOperationStore32(arg))
+		} else {
+			c.emit(NewOperationStore(t, arg))
+		}
+	default:
+		c.emit(NewOperationStore(t, arg))
+	}
+}
+
 func (c *Compiler) nextFrameID() (id uint32) {
 	id = c.currentFrameID + 1
 	c.currentFrameID++
@@ -2870,6 +3229,8 @@ func (c *Compiler) applyToStack(opcode wasm.Opcode) (index uint32, err error) {
 		// and it DOES affect the signature of opcode.
 		wasm.OpcodeCall,
 		wasm.OpcodeCallIndirect,
+		wasm.OpcodeReturnCall,
+		wasm.OpcodeReturnCallIndirect,
 		wasm.OpcodeLocalGet,
 		wasm.OpcodeLocalSet,
 		wasm.OpcodeLocalTee,
@@ -3059,10 +3420,178 @@ func (c *Compiler) readMemoryArg(tag string) (MemoryArg, error) {
 		return MemoryArg{}, fmt.Errorf("reading alignment for %s: %w", tag, err)
 	}
 	c.pc += num
-	offset, num, err := leb128.LoadUint32(c.body[c.pc+1:])
+	var memoryIndex uint32
+	if alignment&wasm.MemArgMemoryIndexFlag != 0 && c.enabledFeatures.IsEnabled(experimental.CoreFeaturesMultiMemory) {
+		alignment &^= wasm.MemArgMemoryIndexFlag
+		if memoryIndex, err = c.readMemoryIndex(tag); err != nil {
+			return MemoryArg{}, err
+		}
+	}
+	offset, num, err := leb128.LoadUint64(c.body[c.pc+1:])
 	if err != nil {
 		return MemoryArg{}, fmt.Errorf("reading offset for %s: %w", tag, err)
 	}
 	c.pc += num
-	return MemoryArg{Offset: offset, Alignment: alignment}, nil
+	if offset > math.MaxUint32 {
+		// Only valid for a 64-bit memory, where the effective address is always out of bounds, so the narrowing of
+		// an address which doesn't fit in 32 bits traps before the access.
+		c.emit(NewOperationConstI64(math.MaxUint64))
+		c.emit(NewOperationNarrowAddress())
+		c.emit(NewOperationDrop(InclusiveRange{Start: 0, End: 0}))
+		offset = 0
+	}
+	return MemoryArg{Offset: uint32(offset), Alignment: alignment, MemoryIndex: memoryIndex}, nil
+}
+
+// narrowMemory64Operands narrows the i64 operands of the instruction at c.pc into i32 in place if it accesses a
+// 64-bit memory, so that the rest of its compilation is the same as for a 32-bit memory. This must be called before
+// the instruction is applied to the stack.
+//
+// Memories are never larger than 4GiB, so an address which doesn't fit in 32 bits is out of bounds and traps with
+// OperationKindNarrowAddress. The delta of memory.grow is clamped instead, as the instruction fails with -1.
+func (c *Compiler) narrowMemory64Operands(op wasm.Opcode) error {
+	// depth is the depth of the address operand in c.stack, which is the same for all instructions of a kind.
+	depth := -1
+	var memoryIndex uint32
+	var err error
+	switch {
+	case op >= wasm.OpcodeI32Load && op <= wasm.OpcodeI64Load32U:
+		depth = 0
+		memoryIndex, err = c.peekMemoryArgIndex(c.pc + 1)
+	case op >= wasm.OpcodeI32Store && op <= wasm.OpcodeI64Store32:
+		depth = 1
+		memoryIndex, err = c.peekMemoryArgIndex(c.pc + 1)
+	case op == wasm.OpcodeMemoryGrow:
+		if memoryIndex, _, err = leb128.LoadUint32(c.body[c.pc+1:]); err == nil && c.memory64[memoryIndex] {
+			// [delta] -> [delta, max, delta, max] -> [delta, max, delta <= max] -> [min(delta, max)]
+			c.emit(NewOperationConstI64(math.MaxUint32))
+			c.emit(NewOperationPick(1, false))
+			c.emit(NewOperationConstI64(math.MaxUint32))
+			c.emit(NewOperationLe(SignedTypeUint64))
+			c.emit(NewOperationSelect(false))
+			c.emit(NewOperationI32WrapFromI64())
+			c.stack[len(c.stack)-1] = UnsignedTypeI32
+		}
+	case op == wasm.OpcodeMiscPrefix:
+		miscOp, num, err := leb128.LoadUint32(c.body[c.pc+1:])
+		if err != nil {
+			return fmt.Errorf("failed to read misc opcode: %v", err)
+		}
+		pos := c.pc + 1 + num
+		switch byte(miscOp) {
+		case wasm.OpcodeMiscMemoryInit:
+			// [dst, offset, n], where only dst is an address.
+			if _, num, err = leb128.LoadUint32(c.body[pos:]); err == nil {
+				depth = 2
+				memoryIndex, _, err = leb128.LoadUint32(c.body[pos+num:])
+			}
+		case wasm.OpcodeMiscMemoryCopy:
+			// [dst, src, n], where n is i64 only if both are 64-bit memories.
+			var dst, src uint32
+			if dst, num, err = leb128.LoadUint32(c.body[pos:]); err != nil {
+				break
+			}
+			if src, _, err = leb128.LoadUint32(c.body[pos+num:]); err != nil {
+				break
+			}
+			if c.memory64[dst] {
+				c.narrowAddress(2)
+			}
+			if c.memory64[src] {
+				c.narrowAddress(1)
+			}
+			if c.memory64[dst] && c.memory64[src] {
+				c.narrowAddress(0)
+			}
+		case wasm.OpcodeMiscMemoryFill:
+			// [dst, value, n]
+			if memoryIndex, _, err = leb128.LoadUint32(c.body[pos:]); err == nil && c.memory64[memoryIndex] {
+				c.narrowAddress(0)
+				depth = 2
+			}
+		}
+	case op == wasm.OpcodeAtomicPrefix:
+		switch atomicOp := c.body[c.pc+1]; {
+		case atomicOp == wasm.OpcodeAtomicFence:
+		case atomicOp == wasm.OpcodeAtomicMemoryWait32 || atomicOp == wasm.OpcodeAtomicMemoryWait64 ||
+			atomicOp >= wasm.OpcodeAtomicI32RmwCmpxchg:
+			depth = 2
+		case atomicOp == wasm.OpcodeAtomicMemoryNotify || atomicOp >= wasm.OpcodeAtomicI32Store:
+			depth = 1
+		default:
+			depth = 0
+		}
+		if depth >= 0 {
+			memoryIndex, err = c.peekMemoryArgIndex(c.pc + 2)
+		}
+	case op == wasm.OpcodeVecPrefix:
+		switch vecOp := c.body[c.pc+1]; {
+		case vecOp <= wasm.OpcodeVecV128Load64Splat, vecOp == wasm.OpcodeVecV128Load32zero,
+			vecOp == wasm.OpcodeVecV128Load64zero:
+			depth = 0
+		case vecOp == wasm.OpcodeVecV128Store,
+			vecOp >= wasm.OpcodeVecV128Load8Lane && vecOp <= wasm.OpcodeVecV128Store64Lane:
+			depth = 1 // the address is followed by a v128.
+		}
+		if depth >= 0 {
+			memoryIndex, err = c.peekMemoryArgIndex(c.pc + 2)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("reading memory index: %w", err)
+	}
+	if depth >= 0 && c.memory64[memoryIndex] {
+		c.narrowAddress(depth)
+	}
+	return nil
+}
+
+// narrowAddress emits OperationKindNarrowAddress for the i64 at the given depth in c.stack, and replaces it with i32.
+func (c *Compiler) narrowAddress(depth int) {
+	i := len(c.stack) - 1 - depth
+	if i == len(c.stack)-1 {
+		c.emit(NewOperationNarrowAddress())
+	} else {
+		// [addr, ...] -> [addr, ..., addr] -> [addr, ..., narrowed] -> [narrowed, ...]
+		depthInUint64 := c.stackLenInUint64(len(c.stack)) - c.stackLenInUint64(i+1)
+		c.emit(NewOperationPick(depthInUint64, false))
+		c.emit(NewOperationNarrowAddress())
+		c.emit(NewOperationSet(depthInUint64+1, false))
+	}
+	c.stack[i] = UnsignedTypeI32
+}
+
+// extendMemory64Result extends the i32 result of memory.size or memory.grow on the given memory to i64 if it is a
+// 64-bit memory.
+func (c *Compiler) extendMemory64Result(memoryIndex uint32, signed bool) {
+	if c.memory64 == nil || !c.memory64[memoryIndex] || c.unreachableState.on {
+		return
+	}
+	c.emit(NewOperationExtend(signed))
+	c.stack[len(c.stack)-1] = UnsignedTypeI64
+}
+
+// peekMemoryArgIndex returns the memory index of the memory argument at the given position of c.body, without
+// advancing c.pc.
+func (c *Compiler) peekMemoryArgIndex(pos uint64) (uint32, error) {
+	alignment, num, err := leb128.LoadUint32(c.body[pos:])
+	if err != nil {
+		return 0, err
+	}
+	if alignment&wasm.MemArgMemoryIndexFlag == 0 || !c.enabledFeatures.IsEnabled(experimental.CoreFeaturesMultiMemory) {
+		return 0, nil
+	}
+	memoryIndex, _, err := leb128.LoadUint32(c.body[pos+num:])
+	return memoryIndex, err
+}
+
+// readMemoryIndex reads the memory index immediate, which is a single zero byte unless
+// experimental.CoreFeaturesMultiMemory is enabled.
+func (c *Compiler) readMemoryIndex(tag string) (uint32, error) {
+	index, num, err := leb128.LoadUint32(c.body[c.pc+1:])
+	if err != nil {
+		return 0, fmt.Errorf("reading memory index for %s: %w", tag, err)
+	}
+	c.pc += num
+	return index, nil
 }
@@ -6,6 +6,7 @@ import (
 	"testing"
 
 	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
 	"github.com/tetratelabs/wazero/internal/leb128"
 	"github.com/tetratelabs/wazero/internal/testing/require"
 	"github.com/tetratelabs/wazero/internal/wasm"
@@ -157,7 +158,7 @@ func TestCompile(t *testing.T) {
 			expected: &CompilationResult{
 				Operations: []UnionOperation{ // begin with params: [$delta]
 					NewOperationPick(0, false),                         // [$delta, $delta]
-					NewOperationMemoryGrow(),                           // [$delta, $old_size]
+					NewOperationMemoryGrow(0),                          // [$delta, $old_size]
 					NewOperationDrop(InclusiveRange{Start: 1, End: 1}), // [$old_size]
 					NewOperationBr(NewLabel(LabelKindReturn, 0)),       // return!
 				},
@@ -171,6 +172,37 @@ func TestCompile(t *testing.T) {
 				UsesMemory: true,
 			},
 		},
+		{
+			name:            "memory index",
+			enabledFeatures: api.CoreFeaturesV2 | experimental.CoreFeaturesMultiMemory,
+			module: &wasm.Module{
+				TypeSection:     []wasm.FunctionType{v_v},
+				FunctionSection: []wasm.Index{0},
+				CodeSection: []wasm.Code{{Body: []byte{
+					wasm.OpcodeI32Const, 8, // memory offset to load
+					wasm.OpcodeI32Load, wasm.MemArgMemoryIndexFlag | 0x2, 0x1, 0x4, // load alignment=2 memory=1 staticOffset=4
+					wasm.OpcodeI32Const, 0, wasm.OpcodeI32Const, 0, wasm.OpcodeI32Const, 0,
+					wasm.OpcodeMiscPrefix, wasm.OpcodeMiscMemoryCopy, 0x2, 0x1, // memory.copy from memory 1 to 2
+					wasm.OpcodeEnd,
+				}}},
+			},
+			expected: &CompilationResult{
+				Operations: []UnionOperation{ // begin with params: []
+					NewOperationConstI32(8), // [8]
+					NewOperationLoad(UnsignedTypeI32, MemoryArg{Alignment: 2, Offset: 4, MemoryIndex: 1}), // [x]
+					NewOperationConstI32(0),                      // [x, 0]
+					NewOperationConstI32(0),                      // [x, 0, 0]
+					NewOperationConstI32(0),                      // [x, 0, 0, 0]
+					NewOperationMemoryCopy(2, 1),                 // [x]
+					NewOperationDrop(InclusiveRange{}),           // []
+					NewOperationBr(NewLabel(LabelKindReturn, 0)), // return!
+				},
+				LabelCallers: map[Label]uint32{},
+				Types:        []wasm.FunctionType{v_v},
+				Functions:    []uint32{0},
+				UsesMemory:   true,
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -297,7 +329,7 @@ func TestCompile_BulkMemoryOperations(t *testing.T) {
 			NewOperationConstI32(16),                     // [16]
 			NewOperationConstI32(0),                      // [16, 0]
 			NewOperationConstI32(7),                      // [16, 0, 7]
-			NewOperationMemoryInit(1),                    // []
+			NewOperationMemoryInit(1, 0),                 // []
 			NewOperationDataDrop(1),                      // []
 			NewOperationBr(NewLabel(LabelKindReturn, 0)), // return!
 		},
@@ -317,6 +349,46 @@ func TestCompile_BulkMemoryOperations(t *testing.T) {
 	require.Equal(t, expected, actual)
 }
 
+func TestCompile_Memory64(t *testing.T) {
+	i64i32_v := wasm.FunctionType{Params: []wasm.ValueType{wasm.ValueTypeI64, i32}}
+	module := &wasm.Module{
+		TypeSection:     []wasm.FunctionType{i64i32_v},
+		FunctionSection: []wasm.Index{0},
+		MemorySection:   &wasm.Memory{Min: 1, Is64: true},
+		CodeSection: []wasm.Code{{Body: []byte{
+			wasm.OpcodeLocalGet, 0,
+			wasm.OpcodeLocalGet, 1,
+			wasm.OpcodeI32Store, 0x2, 0x8, // alignment=2 (natural alignment) staticOffset=8
+			wasm.OpcodeEnd,
+		}}},
+	}
+
+	expected := &CompilationResult{
+		Operations: []UnionOperation{ // begin with params: [$0, $1]
+			NewOperationPick(1, false),  // [$0, $1, $0]
+			NewOperationPick(1, false),  // [$0, $1, $0, $1]
+			NewOperationPick(1, false),  // [$0, $1, $0, $1, $0]
+			NewOperationNarrowAddress(), // [$0, $1, $0, $1, uint32($0)]
+			NewOperationSet(2, false),   // [$0, $1, uint32($0), $1]
+			NewOperationStore(UnsignedTypeI32, MemoryArg{Alignment: 2, Offset: 8}), // [$0, $1]
+			NewOperationDrop(InclusiveRange{Start: 0, End: 1}),                     // []
+			NewOperationBr(NewLabel(LabelKindReturn, 0)),                           // return!
+		},
+		HasMemory:    true,
+		UsesMemory:   true,
+		LabelCallers: map[Label]uint32{},
+		Functions:    []wasm.Index{0},
+		Types:        []wasm.FunctionType{i64i32_v},
+	}
+
+	c, err := NewCompiler(api.CoreFeaturesV2|experimental.CoreFeaturesMemory64, 0, module, false)
+	require.NoError(t, err)
+
+	actual, err := c.Next()
+	require.NoError(t, err)
+	require.Equal(t, expected, actual)
+}
+
 func TestCompile_MultiValue(t *testing.T) {
 	i32i32_i32i32 := wasm.FunctionType{
 		Params:            []wasm.ValueType{wasm.ValueTypeI32, wasm.ValueTypeI32},
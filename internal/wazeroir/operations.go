@@ -413,6 +413,10 @@ func (o OperationKind) String() (ret string) {
 		ret = "V128Narrow"
 	case OperationKindV128ITruncSatFromF:
 		ret = "V128ITruncSatFromF"
+	case OperationKindAtomicCheckAlignment:
+		ret = "AtomicCheckAlignment"
+	case OperationKindNarrowAddress:
+		ret = "NarrowAddress"
 	case OperationKindBuiltinFunctionCheckExitCode:
 		ret = "BuiltinFunctionCheckExitCode"
 	default:
@@ -702,6 +706,12 @@ const (
 	// OperationKindV128ITruncSatFromF is the Kind for NewOperationV128ITruncSatFromF.
 	OperationKindV128ITruncSatFromF
 
+	// OperationKindAtomicCheckAlignment is the Kind for NewOperationAtomicCheckAlignment.
+	OperationKindAtomicCheckAlignment
+
+	// OperationKindNarrowAddress is the Kind for NewOperationNarrowAddress.
+	OperationKindNarrowAddress
+
 	// OperationKindBuiltinFunctionCheckExitCode is the Kind for NewOperationBuiltinFunctionCheckExitCode.
 	OperationKindBuiltinFunctionCheckExitCode
 
@@ -717,6 +727,25 @@ func NewOperationBuiltinFunctionCheckExitCode() UnionOperation {
 	return UnionOperation{Kind: OperationKindBuiltinFunctionCheckExitCode}
 }
 
+// NewOperationAtomicCheckAlignment is a constructor for UnionOperation with OperationKindAtomicCheckAlignment.
+//
+// This corresponds to the alignment check of the instructions of experimental.CoreFeaturesThreads which access memory,
+// and pops the i32 effective address, so that the engines exit the execution with wasmruntime.ErrRuntimeUnalignedAtomic
+// error if it has any of the bits of mask.
+func NewOperationAtomicCheckAlignment(mask uint32) UnionOperation {
+	return UnionOperation{Kind: OperationKindAtomicCheckAlignment, U1: uint64(mask)}
+}
+
+// NewOperationNarrowAddress is a constructor for UnionOperation with OperationKindNarrowAddress.
+//
+// This corresponds to the i64 address operands of the instructions of experimental.CoreFeaturesMemory64 which access
+// memory, and replaces the i64 on top of the stack with the i32 of the same value, so that the engines exit the
+// execution with wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess error if it doesn't fit in 32 bits, as memories are
+// never larger than 4GiB.
+func NewOperationNarrowAddress() UnionOperation {
+	return UnionOperation{Kind: OperationKindNarrowAddress}
+}
+
 // Label is the unique identifier for each block in a single function in wazeroir
 // where "block" consists of multiple operations, and must End with branching operations
 // (e.g. OperationKindBr or OperationKindBrIf).
@@ -1007,9 +1036,13 @@ func (o UnionOperation) String() string {
 		OperationKindV128FloatDemote,
 		OperationKindV128FConvertFromI,
 		OperationKindV128Dot,
-		OperationKindV128Narrow:
+		OperationKindV128Narrow,
+		OperationKindNarrowAddress:
 		return o.Kind.String()
 
+	case OperationKindAtomicCheckAlignment:
+		return fmt.Sprintf("%s 0x%x", o.Kind, o.U1)
+
 	case OperationKindV128ITruncSatFromF:
 		if o.B3 {
 			return fmt.Sprintf("%s.%sS", o.Kind, shapeName(o.B1))
@@ -1085,6 +1118,18 @@ func NewOperationCall(functionIndex uint32) UnionOperation {
 	return UnionOperation{Kind: OperationKindCall, U1: uint64(functionIndex)}
 }
 
+// NewOperationReturnCall is a constructor for UnionOperation with OperationKindCall.
+//
+// This corresponds to wasm.OpcodeReturnCallName, and is the same as
+// NewOperationCall except B3 is true. Unless NewCompiler reserves the call
+// frames in the value stack (callFrameStackSizeInUint64 > 0), the values below
+// the arguments are already dropped, so engines can replace the current frame with the callee's.
+// Otherwise, engines can execute this as OperationKindCall, as it is followed
+// by the branch to the function's return.
+func NewOperationReturnCall(functionIndex uint32) UnionOperation {
+	return UnionOperation{Kind: OperationKindCall, U1: uint64(functionIndex), B3: true}
+}
+
 // NewOperationCallIndirect implements Operation.
 //
 // This corresponds to wasm.OpcodeCallIndirectName, and engines are expected to
@@ -1101,6 +1146,15 @@ func NewOperationCallIndirect(typeIndex, tableIndex uint32) UnionOperation {
 	return UnionOperation{Kind: OperationKindCallIndirect, U1: uint64(typeIndex), U2: uint64(tableIndex)}
 }
 
+// NewOperationReturnCallIndirect is a constructor for UnionOperation with
+// OperationKindCallIndirect.
+//
+// This corresponds to wasm.OpcodeReturnCallIndirectName, and is the same as
+// NewOperationCallIndirect except B3 is true. See NewOperationReturnCall.
+func NewOperationReturnCallIndirect(typeIndex, tableIndex uint32) UnionOperation {
+	return UnionOperation{Kind: OperationKindCallIndirect, U1: uint64(typeIndex), U2: uint64(tableIndex), B3: true}
+}
+
 // InclusiveRange is the range which spans across the value stack starting from the top to the bottom, and
 // both boundary are included in the range.
 type InclusiveRange struct {
@@ -1200,6 +1254,10 @@ type MemoryArg struct {
 	// Offset is the address offset added to the instruction's dynamic address operand, yielding a 33-bit effective
 	// address that is the zero-based index at which the memory is accessed. Default to zero.
 	Offset uint32
+
+	// MemoryIndex is the index of the accessed memory, which is only non-zero with
+	// experimental.CoreFeaturesMultiMemory. This is held in UnionOperation.U3.
+	MemoryIndex uint32
 }
 
 // NewOperationLoad is a constructor for UnionOperation with OperationKindLoad.
@@ -1209,7 +1267,7 @@ type MemoryArg struct {
 // The engines are expected to check the boundary of memory length, and exit the execution if this exceeds the boundary,
 // otherwise load the corresponding value following the semantics of the corresponding WebAssembly instruction.
 func NewOperationLoad(unsignedType UnsignedType, arg MemoryArg) UnionOperation {
-	return UnionOperation{Kind: OperationKindLoad, B1: byte(unsignedType), U1: uint64(arg.Alignment), U2: uint64(arg.Offset)}
+	return UnionOperation{Kind: OperationKindLoad, B1: byte(unsignedType), U1: uint64(arg.Alignment), U2: uint64(arg.Offset), U3: uint64(arg.MemoryIndex)}
 }
 
 // NewOperationLoad8 is a constructor for UnionOperation with OperationKindLoad8.
@@ -1219,7 +1277,7 @@ func NewOperationLoad(unsignedType UnsignedType, arg MemoryArg) UnionOperation {
 // The engines are expected to check the boundary of memory length, and exit the execution if this exceeds the boundary,
 // otherwise load the corresponding value following the semantics of the corresponding WebAssembly instruction.
 func NewOperationLoad8(signedInt SignedInt, arg MemoryArg) UnionOperation {
-	return UnionOperation{Kind: OperationKindLoad8, B1: byte(signedInt), U1: uint64(arg.Alignment), U2: uint64(arg.Offset)}
+	return UnionOperation{Kind: OperationKindLoad8, B1: byte(signedInt), U1: uint64(arg.Alignment), U2: uint64(arg.Offset), U3: uint64(arg.MemoryIndex)}
 }
 
 // NewOperationLoad16 is a constructor for UnionOperation with OperationKindLoad16.
@@ -1229,7 +1287,7 @@ func NewOperationLoad8(signedInt SignedInt, arg MemoryArg) UnionOperation {
 // The engines are expected to check the boundary of memory length, and exit the execution if this exceeds the boundary,
 // otherwise load the corresponding value following the semantics of the corresponding WebAssembly instruction.
 func NewOperationLoad16(signedInt SignedInt, arg MemoryArg) UnionOperation {
-	return UnionOperation{Kind: OperationKindLoad16, B1: byte(signedInt), U1: uint64(arg.Alignment), U2: uint64(arg.Offset)}
+	return UnionOperation{Kind: OperationKindLoad16, B1: byte(signedInt), U1: uint64(arg.Alignment), U2: uint64(arg.Offset), U3: uint64(arg.MemoryIndex)}
 }
 
 // NewOperationLoad32 is a constructor for UnionOperation with OperationKindLoad32.
@@ -1243,7 +1301,7 @@ func NewOperationLoad32(signed bool, arg MemoryArg) UnionOperation {
 	if signed {
 		sigB = 1
 	}
-	return UnionOperation{Kind: OperationKindLoad32, B1: sigB, U1: uint64(arg.Alignment), U2: uint64(arg.Offset)}
+	return UnionOperation{Kind: OperationKindLoad32, B1: sigB, U1: uint64(arg.Alignment), U2: uint64(arg.Offset), U3: uint64(arg.MemoryIndex)}
 }
 
 // NewOperationStore is a constructor for UnionOperation with OperationKindStore.
@@ -1253,7 +1311,7 @@ func NewOperationLoad32(signed bool, arg MemoryArg) UnionOperation {
 // The engines are expected to check the boundary of memory length, and exit the execution if this exceeds the boundary,
 // otherwise store the corresponding value following the semantics of the corresponding WebAssembly instruction.
 func NewOperationStore(unsignedType UnsignedType, arg MemoryArg) UnionOperation {
-	return UnionOperation{Kind: OperationKindStore, B1: byte(unsignedType), U1: uint64(arg.Alignment), U2: uint64(arg.Offset)}
+	return UnionOperation{Kind: OperationKindStore, B1: byte(unsignedType), U1: uint64(arg.Alignment), U2: uint64(arg.Offset), U3: uint64(arg.MemoryIndex)}
 }
 
 // NewOperationStore8 is a constructor for UnionOperation with OperationKindStore8.
@@ -1263,7 +1321,7 @@ func NewOperationStore(unsignedType UnsignedType, arg MemoryArg) UnionOperation
 // The engines are expected to check the boundary of memory length, and exit the execution if this exceeds the boundary,
 // otherwise store the corresponding value following the semantics of the corresponding WebAssembly instruction.
 func NewOperationStore8(arg MemoryArg) UnionOperation {
-	return UnionOperation{Kind: OperationKindStore8, U1: uint64(arg.Alignment), U2: uint64(arg.Offset)}
+	return UnionOperation{Kind: OperationKindStore8, U1: uint64(arg.Alignment), U2: uint64(arg.Offset), U3: uint64(arg.MemoryIndex)}
 }
 
 // NewOperationStore16 is a constructor for UnionOperation with OperationKindStore16.
@@ -1273,7 +1331,7 @@ func NewOperationStore8(arg MemoryArg) UnionOperation {
 // The engines are expected to check the boundary of memory length, and exit the execution if this exceeds the boundary,
 // otherwise store the corresponding value following the semantics of the corresponding WebAssembly instruction.
 func NewOperationStore16(arg MemoryArg) UnionOperation {
-	return UnionOperation{Kind: OperationKindStore16, U1: uint64(arg.Alignment), U2: uint64(arg.Offset)}
+	return UnionOperation{Kind: OperationKindStore16, U1: uint64(arg.Alignment), U2: uint64(arg.Offset), U3: uint64(arg.MemoryIndex)}
 }
 
 // NewOperationStore32 is a constructor for UnionOperation with OperationKindStore32.
@@ -1283,16 +1341,16 @@ func NewOperationStore16(arg MemoryArg) UnionOperation {
 // The engines are expected to check the boundary of memory length, and exit the execution if this exceeds the boundary,
 // otherwise store the corresponding value following the semantics of the corresponding WebAssembly instruction.
 func NewOperationStore32(arg MemoryArg) UnionOperation {
-	return UnionOperation{Kind: OperationKindStore32, U1: uint64(arg.Alignment), U2: uint64(arg.Offset)}
+	return UnionOperation{Kind: OperationKindStore32, U1: uint64(arg.Alignment), U2: uint64(arg.Offset), U3: uint64(arg.MemoryIndex)}
 }
 
 // NewOperationMemorySize is a constructor for UnionOperation with OperationKindMemorySize.
 //
 // This corresponds to wasm.OpcodeMemorySize.
 //
-// The engines are expected to push the current page size of the memory onto the stack.
-func NewOperationMemorySize() UnionOperation {
-	return UnionOperation{Kind: OperationKindMemorySize}
+// The engines are expected to push the current page size of the memory at memoryIndex onto the stack.
+func NewOperationMemorySize(memoryIndex uint32) UnionOperation {
+	return UnionOperation{Kind: OperationKindMemorySize, U1: uint64(memoryIndex)}
 }
 
 // NewOperationMemoryGrow is a constructor for UnionOperation with OperationKindMemoryGrow.
@@ -1301,9 +1359,9 @@ func NewOperationMemorySize() UnionOperation {
 //
 // The engines are expected to pop one value from the top of the stack, then
 // execute wasm.MemoryInstance Grow with the value, and push the previous
-// page size of the memory onto the stack.
-func NewOperationMemoryGrow() UnionOperation {
-	return UnionOperation{Kind: OperationKindMemoryGrow}
+// page size of the memory onto the stack. memoryIndex is the index of the memory to grow.
+func NewOperationMemoryGrow(memoryIndex uint32) UnionOperation {
+	return UnionOperation{Kind: OperationKindMemoryGrow, U1: uint64(memoryIndex)}
 }
 
 // NewOperationConstI32 is a constructor for UnionOperation with OperationConstI32.
@@ -1785,9 +1843,9 @@ func NewOperationSignExtend64From32() UnionOperation {
 // This corresponds to wasm.OpcodeMemoryInitName.
 //
 // dataIndex is the index of the data instance in ModuleInstance.DataInstances
-// by which this operation instantiates a part of the memory.
-func NewOperationMemoryInit(dataIndex uint32) UnionOperation {
-	return UnionOperation{Kind: OperationKindMemoryInit, U1: uint64(dataIndex)}
+// by which this operation instantiates a part of the memory at memoryIndex.
+func NewOperationMemoryInit(dataIndex, memoryIndex uint32) UnionOperation {
+	return UnionOperation{Kind: OperationKindMemoryInit, U1: uint64(dataIndex), U2: uint64(memoryIndex)}
 }
 
 // NewOperationDataDrop implements Operation.
@@ -1803,13 +1861,17 @@ func NewOperationDataDrop(dataIndex uint32) UnionOperation {
 // NewOperationMemoryCopy is a consuctor for UnionOperation with OperationKindMemoryCopy.
 //
 // This corresponds to wasm.OpcodeMemoryCopyName.
-func NewOperationMemoryCopy() UnionOperation {
-	return UnionOperation{Kind: OperationKindMemoryCopy}
+//
+// dstMemoryIndex and srcMemoryIndex are the indexes of the memories copied into and from.
+func NewOperationMemoryCopy(dstMemoryIndex, srcMemoryIndex uint32) UnionOperation {
+	return UnionOperation{Kind: OperationKindMemoryCopy, U1: uint64(dstMemoryIndex), U2: uint64(srcMemoryIndex)}
 }
 
 // NewOperationMemoryFill is a consuctor for UnionOperation with OperationKindMemoryFill.
-func NewOperationMemoryFill() UnionOperation {
-	return UnionOperation{Kind: OperationKindMemoryFill}
+//
+// memoryIndex is the index of the memory to fill.
+func NewOperationMemoryFill(memoryIndex uint32) UnionOperation {
+	return UnionOperation{Kind: OperationKindMemoryFill, U1: uint64(memoryIndex)}
 }
 
 // NewOperationTableInit is a constructor for UnionOperation with OperationKindTableInit.
@@ -1980,7 +2042,7 @@ const (
 //	wasm.OpcodeVecV128Load32SplatName wasm.OpcodeVecV128Load64SplatName wasm.OpcodeVecV128Load32zeroName
 //	wasm.OpcodeVecV128Load64zeroName
 func NewOperationV128Load(loadType V128LoadType, arg MemoryArg) UnionOperation {
-	return UnionOperation{Kind: OperationKindV128Load, B1: loadType, U1: uint64(arg.Alignment), U2: uint64(arg.Offset)}
+	return UnionOperation{Kind: OperationKindV128Load, B1: loadType, U1: uint64(arg.Alignment), U2: uint64(arg.Offset), U3: uint64(arg.MemoryIndex)}
 }
 
 // NewOperationV128LoadLane is a constructor for UnionOperation with OperationKindV128LoadLane.
@@ -1992,7 +2054,7 @@ func NewOperationV128Load(loadType V128LoadType, arg MemoryArg) UnionOperation {
 // laneIndex is >=0 && <(128/LaneSize).
 // laneSize is either 8, 16, 32, or 64.
 func NewOperationV128LoadLane(laneIndex, laneSize byte, arg MemoryArg) UnionOperation {
-	return UnionOperation{Kind: OperationKindV128LoadLane, B1: laneSize, B2: laneIndex, U1: uint64(arg.Alignment), U2: uint64(arg.Offset)}
+	return UnionOperation{Kind: OperationKindV128LoadLane, B1: laneSize, B2: laneIndex, U1: uint64(arg.Alignment), U2: uint64(arg.Offset), U3: uint64(arg.MemoryIndex)}
 }
 
 // NewOperationV128Store is a constructor for UnionOperation with OperationKindV128Store.
@@ -2005,6 +2067,7 @@ func NewOperationV128Store(arg MemoryArg) UnionOperation {
 		Kind: OperationKindV128Store,
 		U1:   uint64(arg.Alignment),
 		U2:   uint64(arg.Offset),
+		U3:   uint64(arg.MemoryIndex),
 	}
 }
 
@@ -2023,6 +2086,7 @@ func NewOperationV128StoreLane(laneIndex byte, laneSize byte, arg MemoryArg) Uni
 		B2:   laneIndex,
 		U1:   uint64(arg.Alignment),
 		U2:   uint64(arg.Offset),
+		U3:   uint64(arg.MemoryIndex),
 	}
 }
 
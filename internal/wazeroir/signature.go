@@ -122,6 +122,10 @@ var (
 	signature_I32I64_None = &signature{
 		in: []UnsignedType{UnsignedTypeI32, UnsignedTypeI64},
 	}
+	signature_I32I64_I64 = &signature{
+		in:  []UnsignedType{UnsignedTypeI32, UnsignedTypeI64},
+		out: []UnsignedType{UnsignedTypeI64},
+	}
 	signature_I32F32_None = &signature{
 		in: []UnsignedType{UnsignedTypeI32, UnsignedTypeF32},
 	}
@@ -159,6 +163,22 @@ var (
 	signature_I32I32I32_None = &signature{
 		in: []UnsignedType{UnsignedTypeI32, UnsignedTypeI32, UnsignedTypeI32},
 	}
+	signature_I32I32I32_I32 = &signature{
+		in:  []UnsignedType{UnsignedTypeI32, UnsignedTypeI32, UnsignedTypeI32},
+		out: []UnsignedType{UnsignedTypeI32},
+	}
+	signature_I32I32I64_I32 = &signature{
+		in:  []UnsignedType{UnsignedTypeI32, UnsignedTypeI32, UnsignedTypeI64},
+		out: []UnsignedType{UnsignedTypeI32},
+	}
+	signature_I32I64I64_I32 = &signature{
+		in:  []UnsignedType{UnsignedTypeI32, UnsignedTypeI64, UnsignedTypeI64},
+		out: []UnsignedType{UnsignedTypeI32},
+	}
+	signature_I32I64I64_I64 = &signature{
+		in:  []UnsignedType{UnsignedTypeI32, UnsignedTypeI64, UnsignedTypeI64},
+		out: []UnsignedType{UnsignedTypeI64},
+	}
 	signature_I32I64I32_None = &signature{
 		in: []UnsignedType{UnsignedTypeI32, UnsignedTypeI64, UnsignedTypeI32},
 	}
@@ -252,9 +272,9 @@ func (c *Compiler) wasmOpcodeSignature(op wasm.Opcode, index uint32) (*signature
 		return signature_I32_None, nil
 	case wasm.OpcodeReturn:
 		return signature_None_None, nil
-	case wasm.OpcodeCall:
+	case wasm.OpcodeCall, wasm.OpcodeReturnCall:
 		return c.funcTypeToSigs.get(c.funcs[index], false /* direct */), nil
-	case wasm.OpcodeCallIndirect:
+	case wasm.OpcodeCallIndirect, wasm.OpcodeReturnCallIndirect:
 		return c.funcTypeToSigs.get(index, true /* call_indirect */), nil
 	case wasm.OpcodeDrop:
 		return signature_Unknown_None, nil
@@ -476,7 +496,64 @@ func (c *Compiler) wasmOpcodeSignature(op wasm.Opcode, index uint32) (*signature
 		default:
 			return nil, fmt.Errorf("unsupported misc instruction in wazeroir: 0x%x", op)
 		}
+	case wasm.OpcodeAtomicPrefix:
+		atomicOp := c.body[c.pc+1]
+		if atomicOp == wasm.OpcodeAtomicFence {
+			return signature_None_None, nil
+		}
+		t, _ := wasm.AtomicMemoryAccess(atomicOp)
+		i64 := t == wasm.ValueTypeI64
+		switch {
+		case atomicOp == wasm.OpcodeAtomicMemoryNotify:
+			return signature_I32I32_I32, nil
+		case atomicOp == wasm.OpcodeAtomicMemoryWait32:
+			return signature_I32I32I64_I32, nil
+		case atomicOp == wasm.OpcodeAtomicMemoryWait64:
+			return signature_I32I64I64_I32, nil
+		case atomicOp < wasm.OpcodeAtomicI32Store:
+			if i64 {
+				return signature_I32_I64, nil
+			}
+			return signature_I32_I32, nil
+		case atomicOp < wasm.OpcodeAtomicI32RmwAdd:
+			if i64 {
+				return signature_I32I64_None, nil
+			}
+			return signature_I32I32_None, nil
+		case atomicOp < wasm.OpcodeAtomicI32RmwCmpxchg:
+			if i64 {
+				return signature_I32I64_I64, nil
+			}
+			return signature_I32I32_I32, nil
+		case atomicOp <= wasm.OpcodeAtomicI64Rmw32CmpxchgU:
+			if i64 {
+				return signature_I32I64I64_I64, nil
+			}
+			return signature_I32I32I32_I32, nil
+		default:
+			return nil, fmt.Errorf("unsupported atomic instruction in wazeroir: 0x%x", atomicOp)
+		}
 	case wasm.OpcodeVecPrefix:
+		if relaxedOp, ok := wasm.VecRelaxedOpcode(c.body[c.pc+1:]); ok {
+			switch relaxedOp {
+			case wasm.OpcodeVecRelaxedI32x4TruncF32x4S, wasm.OpcodeVecRelaxedI32x4TruncF32x4U,
+				wasm.OpcodeVecRelaxedI32x4TruncF64x2SZero, wasm.OpcodeVecRelaxedI32x4TruncF64x2UZero:
+				return signature_V128_V128, nil
+			case wasm.OpcodeVecRelaxedI8x16Swizzle,
+				wasm.OpcodeVecRelaxedF32x4Min, wasm.OpcodeVecRelaxedF32x4Max,
+				wasm.OpcodeVecRelaxedF64x2Min, wasm.OpcodeVecRelaxedF64x2Max,
+				wasm.OpcodeVecRelaxedI16x8Q15mulrS, wasm.OpcodeVecRelaxedI16x8DotI8x16I7x16S:
+				return signature_V128V128_V128, nil
+			case wasm.OpcodeVecRelaxedF32x4Madd, wasm.OpcodeVecRelaxedF32x4Nmadd,
+				wasm.OpcodeVecRelaxedF64x2Madd, wasm.OpcodeVecRelaxedF64x2Nmadd,
+				wasm.OpcodeVecRelaxedI8x16Laneselect, wasm.OpcodeVecRelaxedI16x8Laneselect,
+				wasm.OpcodeVecRelaxedI32x4Laneselect, wasm.OpcodeVecRelaxedI64x2Laneselect,
+				wasm.OpcodeVecRelaxedI32x4DotI8x16I7x16AddS:
+				return signature_V128V128V128_V32, nil
+			default:
+				return nil, fmt.Errorf("unsupported relaxed-SIMD instruction in wazeroir: 0x%x", relaxedOp)
+			}
+		}
 		switch vecOp := c.body[c.pc+1]; vecOp {
 		case wasm.OpcodeVecV128Const:
 			return signature_None_V128, nil
@@ -0,0 +1,64 @@
+package zstd
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestReader(t *testing.T) {
+	// hello is "hello, world\n" compressed with zstd.
+	hello := "\x28\xb5\x2f\xfd\x24\x0d\x69\x00\x00\x68\x65\x6c\x6c\x6f\x2c\x20\x77\x6f\x72\x6c\x64\x0a\x4c\x1f\xf9\xf1"
+
+	actual, err := io.ReadAll(NewReader(strings.NewReader(hello)))
+	require.NoError(t, err)
+	require.Equal(t, "hello, world\n", string(actual))
+}
+
+func TestReader_invalid(t *testing.T) {
+	tests := []struct {
+		name, input string
+	}{
+		{name: "empty frame", input: "\x28\xb5\x2f\xfd"},
+		{name: "bad magic", input: "\x28\xb5\x2f\xfe\x24\x0d\x69\x00\x00"},
+		{name: "bad checksum", input: "\x28\xb5\x2f\xfd\x24\x0d\x69\x00\x00\x68\x65\x6c\x6c\x6f\x2c\x20\x77\x6f\x72\x6c\x64\x0a\x4c\x1f\xf9\xf2"},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := io.ReadAll(NewReader(strings.NewReader(tc.input)))
+			require.Error(t, err)
+		})
+	}
+}
+
+// TestReader_testdata decompresses each file in testdata, which is named by
+// the first eight hexadecimal digits of the SHA256 of its contents.
+func TestReader_testdata(t *testing.T) {
+	samples, err := filepath.Glob(filepath.Join("testdata", "*.zst"))
+	require.NoError(t, err)
+	require.NotEqual(t, 0, len(samples))
+
+	for _, s := range samples {
+		sample := s
+		t.Run(filepath.Base(sample), func(t *testing.T) {
+			f, err := os.Open(sample)
+			require.NoError(t, err)
+			defer f.Close()
+
+			h := sha256.New()
+			_, err = io.Copy(h, NewReader(f))
+			require.NoError(t, err)
+
+			expected, _, _ := strings.Cut(filepath.Base(sample), ".")
+			require.Equal(t, expected, fmt.Sprintf("%x", h.Sum(nil))[:8])
+		})
+	}
+}
@@ -1,17 +1,28 @@
 package wazero
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	goruntime "runtime"
 	"sync/atomic"
+	"time"
 
 	"github.com/tetratelabs/wazero/api"
 	experimentalapi "github.com/tetratelabs/wazero/experimental"
 	internalclose "github.com/tetratelabs/wazero/internal/close"
+	"github.com/tetratelabs/wazero/internal/compilation"
+	"github.com/tetratelabs/wazero/internal/engine/interpreter"
+	"github.com/tetratelabs/wazero/internal/platform"
 	internalsock "github.com/tetratelabs/wazero/internal/sock"
 	internalsys "github.com/tetratelabs/wazero/internal/sys"
 	"github.com/tetratelabs/wazero/internal/wasm"
 	binaryformat "github.com/tetratelabs/wazero/internal/wasm/binary"
+	"github.com/tetratelabs/wazero/internal/wasmdebug"
+	"github.com/tetratelabs/wazero/internal/zstd"
 	"github.com/tetratelabs/wazero/sys"
 )
 
@@ -95,6 +106,8 @@ type Runtime interface {
 	//
 	//   - The resulting module name defaults to what was binary from the custom name section.
 	//   - Any pre-compilation done after decoding the source is dependent on RuntimeConfig.
+	//   - A gzip or zstd-compressed binary is detected by its magic number and
+	//     inflated before decoding. This errs if it inflates to more than 1 GiB.
 	//
 	// See https://www.w3.org/TR/2019/REC-wasm-core-1-20191205/#name-section%E2%91%A0
 	CompileModule(ctx context.Context, binary []byte) (CompiledModule, error)
@@ -159,15 +172,44 @@ func NewRuntimeWithConfig(ctx context.Context, rConfig RuntimeConfig) Runtime {
 		engine = config.newEngine(ctx, config.enabledFeatures, nil)
 	}
 	store := wasm.NewStore(config.enabledFeatures, engine)
+	store.MemorySanitizer = config.memorySanitizer
+
+	// The interpreter is only needed when modules are otherwise compiled. Modules with more than one memory are
+	// interpreted, as the compilers don't support them.
+	var interpreterEngine wasm.Engine
+	needInterpreter := config.fallbackInterpreter != nil || config.differentialCheck ||
+		config.enabledFeatures.IsEnabled(experimentalapi.CoreFeaturesMultiMemory)
+	if needInterpreter && config.engineKind != engineKindInterpreter {
+		if cacheImpl != nil {
+			interpreterEngine = cacheImpl.initEngine(engineKindInterpreter, interpreter.NewEngine, ctx, config.enabledFeatures)
+		} else {
+			interpreterEngine = interpreter.NewEngine(ctx, config.enabledFeatures, nil)
+		}
+	}
 	return &runtime{
 		cache:                 cacheImpl,
 		store:                 store,
+		interpreterEngine:     interpreterEngine,
+		fallbackInterpreter:   config.fallbackInterpreter,
+		differentialCheck:     config.differentialCheck,
+		callTracer:            config.callTracer,
 		enabledFeatures:       config.enabledFeatures,
 		memoryLimitPages:      config.memoryLimitPages,
 		memoryCapacityFromMax: config.memoryCapacityFromMax,
+		memoryZeroOnClose:     config.memoryZeroOnClose,
+		memoryPrefault:        config.memoryPrefault,
+		strictFloat:           config.strictFloat,
+		pprofLabels:           config.pprofLabels,
+		maxBlockNestingDepth:  config.maxBlockNestingDepth,
 		dwarfDisabled:         config.dwarfDisabled,
 		storeCustomSections:   config.storeCustomSections,
 		ensureTermination:     config.ensureTermination,
+		ssaDumper:             config.ssaDumper,
+		regAllocObserver:      config.regAllocObserver,
+		inliningThreshold:     config.inliningThreshold,
+		functionTimeout:       config.functionTimeout,
+		canonicalNaN:          config.canonicalNaN,
+		decodeLimits:          config.decodeLimits,
 	}
 }
 
@@ -175,9 +217,18 @@ func NewRuntimeWithConfig(ctx context.Context, rConfig RuntimeConfig) Runtime {
 type runtime struct {
 	store                 *wasm.Store
 	cache                 *cache
+	interpreterEngine     wasm.Engine
+	fallbackInterpreter   func(binary []byte) bool
+	differentialCheck     bool
+	callTracer            *callTracer
 	enabledFeatures       api.CoreFeatures
 	memoryLimitPages      uint32
 	memoryCapacityFromMax bool
+	memoryZeroOnClose     bool
+	memoryPrefault        bool
+	strictFloat           bool
+	pprofLabels           bool
+	maxBlockNestingDepth  uint32
 	dwarfDisabled         bool
 	storeCustomSections   bool
 
@@ -190,6 +241,12 @@ type runtime struct {
 	closed atomic.Uint64
 
 	ensureTermination bool
+	ssaDumper         func(funcName, stage, ssaText string)
+	regAllocObserver  func(funcName string, info RegAllocInfo)
+	inliningThreshold int
+	functionTimeout   time.Duration
+	canonicalNaN      bool
+	decodeLimits      wasm.DecodeLimits
 }
 
 // Module implements Runtime.Module.
@@ -205,12 +262,21 @@ func (r *runtime) CompileModule(ctx context.Context, binary []byte) (CompiledMod
 	if err := r.failIfClosed(); err != nil {
 		return nil, err
 	}
+	if r.strictFloat && !platform.StrictFloatSupported {
+		return nil, fmt.Errorf("WithStrictFloat is not supported on %s", goruntime.GOARCH)
+	}
+
+	original := binary // before inflating, for fallbackInterpreter.
+	binary, err := decompressModule(binary, maxInflatedModuleSize)
+	if err != nil {
+		return nil, err
+	}
 
 	internal, err := binaryformat.DecodeModule(binary, r.enabledFeatures,
-		r.memoryLimitPages, r.memoryCapacityFromMax, !r.dwarfDisabled, r.storeCustomSections)
+		r.memoryLimitPages, r.memoryCapacityFromMax, !r.dwarfDisabled, r.storeCustomSections, r.decodeLimits)
 	if err != nil {
 		return nil, err
-	} else if err = internal.Validate(r.enabledFeatures); err != nil {
+	} else if err = internal.Validate(r.enabledFeatures, r.maxBlockNestingDepth); err != nil {
 		// TODO: decoders should validate before returning, as that allows
 		// them to err with the correct position in the wasm binary.
 		return nil, err
@@ -220,7 +286,16 @@ func (r *runtime) CompileModule(ctx context.Context, binary []byte) (CompiledMod
 	// TODO: lazy initialization of memory definition.
 	internal.BuildMemoryDefinitions()
 
-	c := &compiledModule{module: internal, compiledEngine: r.store.Engine}
+	engine := r.store.Engine
+	if r.interpreterEngine != nil {
+		if len(internal.AdditionalMemorySection) > 0 || (r.fallbackInterpreter != nil && r.fallbackInterpreter(original)) {
+			engine = r.interpreterEngine
+		}
+	}
+	if _, ok := engine.(wasm.MemorySanitizer); r.store.MemorySanitizer && !ok {
+		return nil, errors.New("WithMemorySanitizer is not supported by the compiler: use wazero.NewRuntimeConfigInterpreter")
+	}
+	c := &compiledModule{module: internal, compiledEngine: engine}
 
 	// typeIDs are static and compile-time known.
 	typeIDs, err := r.store.GetFunctionTypeIDs(internal.TypeSection)
@@ -229,24 +304,59 @@ func (r *runtime) CompileModule(ctx context.Context, binary []byte) (CompiledMod
 	}
 	c.typeIDs = typeIDs
 
-	listeners, err := buildFunctionListeners(ctx, internal)
+	listeners, err := buildFunctionListeners(ctx, internal, r.callTracer)
 	if err != nil {
 		return nil, err
 	}
-	internal.AssignModuleID(binary, listeners, r.ensureTermination)
-	if err = r.store.Engine.CompileModule(ctx, internal, listeners, r.ensureTermination); err != nil {
+	internal.AssignModuleID(binary, listeners, r.ensureTermination, r.canonicalNaN)
+	if r.inliningThreshold > 0 {
+		ctx = context.WithValue(ctx, compilation.InliningThresholdKey{}, r.inliningThreshold)
+	}
+	if r.canonicalNaN {
+		ctx = context.WithValue(ctx, compilation.CanonicalNaNKey{}, true)
+	}
+	if r.functionTimeout > 0 {
+		ctx = context.WithValue(ctx, compilation.FunctionCompileTimeoutKey{}, r.functionTimeout)
+	}
+	if r.ssaDumper != nil {
+		ctx = context.WithValue(ctx, compilation.SSADumperKey{}, r.ssaDumper)
+	}
+	if observer := r.regAllocObserver; observer != nil {
+		ctx = context.WithValue(ctx, compilation.RegAllocObserverKey{}, func(funcName string, info *compilation.RegAllocInfo) {
+			observer(funcName, toRegAllocInfo(info))
+		})
+	}
+	if err = engine.CompileModule(ctx, internal, listeners, r.ensureTermination); err != nil {
 		return nil, err
 	}
+
+	// Compile the module on the interpreter as well, without listeners as these would observe each call twice.
+	if r.differentialCheck && r.interpreterEngine != nil && engine != r.interpreterEngine {
+		if err = r.interpreterEngine.CompileModule(ctx, internal, nil, r.ensureTermination); err != nil {
+			engine.DeleteCompiledModule(internal)
+			return nil, err
+		}
+		c.differentialEngine = r.interpreterEngine
+	}
 	return c, nil
 }
 
-func buildFunctionListeners(ctx context.Context, internal *wasm.Module) ([]experimentalapi.FunctionListener, error) {
+func buildFunctionListeners(ctx context.Context, internal *wasm.Module, tracer *callTracer) ([]experimentalapi.FunctionListener, error) {
 	// Test to see if internal code are using an experimental feature.
-	fnlf := ctx.Value(experimentalapi.FunctionListenerFactoryKey{})
-	if fnlf == nil {
+	var factory experimentalapi.FunctionListenerFactory
+	if fnlf := ctx.Value(experimentalapi.FunctionListenerFactoryKey{}); fnlf != nil {
+		factory = fnlf.(experimentalapi.FunctionListenerFactory)
+	}
+	if tracer != nil {
+		if factory == nil {
+			factory = tracer
+		} else {
+			factory = experimentalapi.MultiFunctionListenerFactory(factory, tracer)
+		}
+	}
+	if factory == nil {
 		return nil, nil
 	}
-	factory := fnlf.(experimentalapi.FunctionListenerFactory)
 	importCount := internal.ImportFunctionCount
 	listeners := make([]experimentalapi.FunctionListener, len(internal.FunctionSection))
 	for i := 0; i < len(listeners); i++ {
@@ -308,20 +418,116 @@ func (r *runtime) InstantiateModule(
 		name = code.module.NameSection.ModuleName
 	}
 
+	var beforeStart wasm.BeforeStart
+	if fn := config.beforeStart; fn != nil {
+		beforeStart = func(ctx context.Context, m *wasm.ModuleInstance) error {
+			if err := fn(ctx, m); err != nil {
+				return fmt.Errorf("module[%s] before start failed: %w", name, err)
+			}
+			return nil
+		}
+	}
+	// The grow hook is set before start, as the start function can grow memory.
+	if hook := config.memoryGrowHook; hook != nil {
+		next := beforeStart
+		beforeStart = func(ctx context.Context, m *wasm.ModuleInstance) error {
+			m.SetMemoryGrowHook(hook)
+			if next != nil {
+				return next(ctx, m)
+			}
+			return nil
+		}
+	}
+
+	// Likewise, the start function can call host functions.
+	if recoverFn := config.hostPanicRecovery; recoverFn != nil {
+		next := beforeStart
+		beforeStart = func(ctx context.Context, m *wasm.ModuleInstance) error {
+			m.HostPanicRecovery = recoverFn
+			if next != nil {
+				return next(ctx, m)
+			}
+			return nil
+		}
+	}
+
+	// Likewise, the start function can call host functions to log.
+	if logger := config.hostCallLogger; logger != nil {
+		next := beforeStart
+		beforeStart = func(ctx context.Context, m *wasm.ModuleInstance) error {
+			m.HostCallLogger = logger
+			if next != nil {
+				return next(ctx, m)
+			}
+			return nil
+		}
+	}
+
+	// Likewise, the start function can be latency-sensitive.
+	if r.memoryPrefault {
+		next := beforeStart
+		beforeStart = func(ctx context.Context, m *wasm.ModuleInstance) error {
+			m.PrefaultMemory()
+			if next != nil {
+				return next(ctx, m)
+			}
+			return nil
+		}
+	}
+
+	var importStubs map[string]*wasm.ModuleInstance
+	if config.importStubs != nil {
+		if importStubs, err = r.store.InstantiateImportStubs(ctx, code.module, config.importStubs); err != nil {
+			if code.closeWithModule {
+				_ = code.Close(ctx) // don't overwrite the error
+			}
+			return
+		}
+	}
+
 	// Instantiate the module.
-	mod, err = r.store.Instantiate(ctx, code.module, name, sysCtx, code.typeIDs)
+	mod, err = r.store.InstantiateWithOptions(ctx, code.module, name, sysCtx, code.typeIDs, wasm.InstantiateOptions{
+		Engine:         code.compiledEngine,
+		BeforeStart:    beforeStart,
+		DeferStart:     !config.startSection,
+		ImportRenames:  config.importRenames,
+		ImportStubs:    importStubs,
+		MinMemoryPages: config.minMemoryPages,
+		MemoryBuffer:   config.memoryBuffer,
+	})
 	if err != nil {
-		// If there was an error, don't leak the compiled module.
+		// If there was an error, don't leak the compiled module or the stubs.
 		if code.closeWithModule {
 			_ = code.Close(ctx) // don't overwrite the error
 		}
+		for _, stub := range importStubs {
+			_ = stub.Close(ctx)
+		}
 		return
 	}
 
+	if code.differentialEngine != nil {
+		if err = r.instantiateDifferential(ctx, code, mod.(*wasm.ModuleInstance), config, beforeStart); err != nil {
+			_ = mod.Close(ctx) // Don't leak the module on error.
+			if code.closeWithModule {
+				_ = code.Close(ctx) // don't overwrite the error
+			}
+			return nil, err
+		}
+	}
+
 	if closeNotifier, ok := ctx.Value(internalclose.NotifierKey{}).(internalclose.Notifier); ok {
 		mod.(*wasm.ModuleInstance).CloseNotifier = closeNotifier
 	}
 
+	mod.(*wasm.ModuleInstance).ZeroMemoryOnClose = r.memoryZeroOnClose
+	mod.(*wasm.ModuleInstance).NewSysContext = config.toSysContext
+	mod.(*wasm.ModuleInstance).StrictFloat = r.strictFloat
+	mod.(*wasm.ModuleInstance).PprofLabels = r.pprofLabels
+	if d := mod.(*wasm.ModuleInstance).Differential; d != nil {
+		d.StrictFloat = r.strictFloat
+	}
+
 	// Attach the code closer so that anything afterward closes the compiled
 	// code when closing the module.
 	if code.closeWithModule {
@@ -343,13 +549,31 @@ func (r *runtime) InstantiateModule(
 				}
 				return // Don't wrap an exit error
 			}
-			err = fmt.Errorf("module[%s] function[%s] failed: %w", name, fn, err)
+			err = &api.StartFunctionTrapError{Module: name, Function: fmt.Sprintf("function[%s]", fn), Cause: err}
 			return
 		}
 	}
 	return
 }
 
+// instantiateDifferential instantiates the module on the differentialEngine as well, with its own system context.
+func (r *runtime) instantiateDifferential(
+	ctx context.Context,
+	code *compiledModule,
+	mod *wasm.ModuleInstance,
+	config *moduleConfig,
+	beforeStart wasm.BeforeStart,
+) error {
+	sysCtx, err := config.toSysContext()
+	if err != nil {
+		return err
+	}
+	if err = r.store.InstantiateDifferential(ctx, code.differentialEngine, mod, sysCtx, beforeStart, !config.startSection); err != nil {
+		return fmt.Errorf("module[%s] differential instantiation failed: %w", mod.ModuleName, err)
+	}
+	return nil
+}
+
 // Close implements api.Closer embedded in Runtime.
 func (r *runtime) Close(ctx context.Context) error {
 	return r.CloseWithExitCode(ctx, 0)
@@ -369,6 +593,170 @@ func (r *runtime) CloseWithExitCode(ctx context.Context, exitCode uint32) error
 		if errCloseEngine := r.store.Engine.Close(); errCloseEngine != nil {
 			return errCloseEngine
 		}
+		if r.interpreterEngine != nil {
+			if errCloseEngine := r.interpreterEngine.Close(); errCloseEngine != nil {
+				return errCloseEngine
+			}
+		}
 	}
 	return err
 }
+
+// toRegAllocInfo converts the internal summary of the register allocation to the public one.
+func toRegAllocInfo(info *compilation.RegAllocInfo) RegAllocInfo {
+	ret := RegAllocInfo{VirtualRegisters: info.VirtualRegisters, Spills: info.Spills}
+	if len(info.SpilledRanges) > 0 {
+		ret.SpilledRanges = make([]LiveRange, len(info.SpilledRanges))
+		for i, r := range info.SpilledRanges {
+			ret.SpilledRanges[i] = LiveRange{
+				VirtualRegister: r.VirtualRegister,
+				Block:           r.Block,
+				Begin:           r.Begin,
+				End:             r.End,
+				LiveOut:         r.LiveOut,
+			}
+		}
+	}
+	return ret
+}
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// maxInflatedModuleSize bounds the size of a compressed module once inflated,
+// so that a small binary can't exhaust host memory.
+const maxInflatedModuleSize = 1 << 30
+
+// decompressModule inflates the binary if it begins with a known compression
+// magic number, or returns it as is otherwise. This errs if the binary
+// inflates to more than maxSize bytes.
+func decompressModule(binary []byte, maxSize int64) ([]byte, error) {
+	switch {
+	case bytes.HasPrefix(binary, gzipMagic):
+		zr, err := gzip.NewReader(bytes.NewReader(binary))
+		if err != nil {
+			return nil, fmt.Errorf("invalid gzip-compressed module: %w", err)
+		}
+		defer zr.Close()
+		return inflateModule("gzip", zr, maxSize)
+	case bytes.HasPrefix(binary, zstdMagic):
+		return inflateModule("zstd", zstd.NewReader(bytes.NewReader(binary)), maxSize)
+	}
+	return binary, nil
+}
+
+// inflateModule reads all of r, unless it has more than maxSize bytes.
+func inflateModule(format string, r io.Reader, maxSize int64) ([]byte, error) {
+	// Read one byte more than allowed to tell an exact fit from exceeding it.
+	inflated, err := io.ReadAll(io.LimitReader(r, maxSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s-compressed module: %w", format, err)
+	} else if int64(len(inflated)) > maxSize {
+		return nil, fmt.Errorf("%s-compressed module inflates to more than %d bytes", format, maxSize)
+	}
+	return inflated, nil
+}
+
+// Frame is a frame of the wasm stack trace of an error returned by a call
+// into a guest function. See ErrStackTrace.
+type Frame struct {
+	// ModuleName is the module name from the name section, or empty.
+	ModuleName string
+
+	// FunctionIndex is the position of the function in the function index
+	// space of its module, prefixed with imported functions.
+	FunctionIndex uint32
+
+	// FunctionName is the function name from the name section, or empty.
+	FunctionName string
+
+	// Offset is the offset of the instruction in the code section of the
+	// module, as used by DWARF line tables. This is only known when the
+	// module has DWARF sections and RuntimeConfig.WithDebugInfoEnabled is
+	// true. Otherwise, this is zero.
+	Offset uint64
+
+	// File and Line are the source location of the instruction, as given by
+	// SourceLines.SourceLine for Offset. These are empty unless Offset is
+	// known and the DWARF line table has an entry for it.
+	File string
+	Line int
+}
+
+// ErrStackTrace returns the wasm stack trace of an error returned by a call
+// into a guest function, beginning with the innermost frame, or nil if the
+// error has none. For example, a sys.ExitError has no stack trace.
+//
+// This gives the same frames as the "wasm stack trace" of the error message,
+// without needing to parse it.
+func ErrStackTrace(err error) []Frame {
+	frames := wasmdebug.Frames(err)
+	if frames == nil {
+		return nil
+	}
+	ret := make([]Frame, len(frames))
+	for i, f := range frames {
+		ret[i] = Frame(f)
+	}
+	return ret
+}
+
+// SourceLines maps the instructions of a module to their location in its
+// source code, using the DWARF line table in its custom sections. See
+// DWARFLines.
+type SourceLines struct {
+	module *wasm.Module
+}
+
+// DWARFLines returns the source lines of the compiled module, parsed from its
+// ".debug_line" and ".debug_info" custom sections, or nil if it has none.
+//
+// For example, this resolves the frames of an error returned by a guest
+// function, like its stack trace does:
+//
+//	lines := wazero.DWARFLines(compiled)
+//	for _, f := range wazero.ErrStackTrace(err) {
+//		if file, line, ok := lines.SourceLine(f.FunctionIndex, f.Offset); ok {
+//			fmt.Printf("%s:%d\n", file, line)
+//		}
+//	}
+//
+// # Notes
+//
+//   - This returns nil when RuntimeConfig.WithDebugInfoEnabled is false, as
+//     the DWARF sections aren't parsed then.
+//   - SourceLine can be called on nil, in which case it never finds a line.
+func DWARFLines(compiled CompiledModule) *SourceLines {
+	c, ok := compiled.(*compiledModule)
+	if !ok || c.module.DWARFLines == nil {
+		return nil
+	}
+	return &SourceLines{module: c.module}
+}
+
+// SourceLine returns the source file and line of the instruction at
+// codeOffset of the function funcIdx, e.g. of a Frame.
+//
+// codeOffset is relative to the beginning of the code section, like addresses
+// in the DWARF line table and Frame.Offset. When the instruction is in a
+// function inlined by the source compiler, this is its location in that
+// function, not in the caller.
+//
+// ok is false if funcIdx is imported, or codeOffset isn't within its body,
+// or the line table has no entry for it.
+func (s *SourceLines) SourceLine(funcIdx uint32, codeOffset uint64) (file string, line int, ok bool) {
+	if s == nil || funcIdx < s.module.ImportFunctionCount {
+		return
+	}
+	localIdx := funcIdx - s.module.ImportFunctionCount
+	if int(localIdx) >= len(s.module.CodeSection) {
+		return
+	}
+	code := &s.module.CodeSection[localIdx]
+	if codeOffset < code.BodyOffsetInCodeSection || codeOffset >= code.BodyOffsetInCodeSection+uint64(len(code.Body)) {
+		return
+	}
+	return s.module.DWARFLines.SourceLine(codeOffset)
+}
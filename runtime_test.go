@@ -1,21 +1,33 @@
 package wazero
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
 	_ "embed"
 	"errors"
+	"fmt"
+	"math"
+	goruntime "runtime"
+	"runtime/pprof"
+	"strconv"
+	"strings"
 	"sync"
 	"testing"
 	"time"
 
 	"github.com/tetratelabs/wazero/api"
 	"github.com/tetratelabs/wazero/experimental"
+	"github.com/tetratelabs/wazero/internal/compilation"
 	"github.com/tetratelabs/wazero/internal/filecache"
 	"github.com/tetratelabs/wazero/internal/leb128"
 	"github.com/tetratelabs/wazero/internal/platform"
 	"github.com/tetratelabs/wazero/internal/testing/binaryencoding"
+	"github.com/tetratelabs/wazero/internal/testing/dwarftestdata"
 	"github.com/tetratelabs/wazero/internal/testing/require"
 	"github.com/tetratelabs/wazero/internal/wasm"
+	"github.com/tetratelabs/wazero/internal/wasm/text"
+	"github.com/tetratelabs/wazero/internal/wasmruntime"
 	"github.com/tetratelabs/wazero/sys"
 )
 
@@ -93,6 +105,28 @@ func TestRuntime_CompileModule(t *testing.T) {
 				require.Equal(t, []api.ValueType{api.ValueTypeI32}, f.ParamTypes())
 			},
 		},
+		{
+			name: "FunctionSection exported with local names",
+			wasm: &wasm.Module{
+				TypeSection:     []wasm.FunctionType{{Params: []api.ValueType{api.ValueTypeI32}, Results: []api.ValueType{api.ValueTypeI32}}},
+				FunctionSection: []wasm.Index{0},
+				CodeSection:     []wasm.Code{{Body: []byte{wasm.OpcodeLocalGet, 0, wasm.OpcodeEnd}}},
+				ExportSection: []wasm.Export{{
+					Type:  wasm.ExternTypeFunc,
+					Name:  "function",
+					Index: 0,
+				}},
+				NameSection: &wasm.NameSection{
+					LocalNames: wasm.IndirectNameMap{{Index: 0, NameMap: wasm.NameMap{{Index: 0, Name: "x"}}}},
+				},
+			},
+			expected: func(compiled CompiledModule) {
+				f := compiled.ExportedFunctions()["function"]
+				require.Equal(t, []string{"x"}, f.ParamNames())
+				// The name section has no subsection for result names.
+				require.Nil(t, f.ResultNames())
+			},
+		},
 		{
 			name: "MemorySection, but not exported",
 			wasm: &wasm.Module{
@@ -169,7 +203,30 @@ func TestRuntime_CompileModule_Errors(t *testing.T) {
 		{
 			name:        "memory has too many pages",
 			wasm:        binaryencoding.EncodeModule(&wasm.Module{MemorySection: &wasm.Memory{Min: 2, Cap: 2, Max: 70000, IsMaxEncoded: true}}),
-			expectedErr: "section memory: max 70000 pages (4 Gi) over limit of 65536 pages (4 Gi)",
+			expectedErr: "section memory: memory max pages: 70000 over limit of 65536",
+		},
+		{
+			name: "global.set on an immutable imported global",
+			wasm: binaryencoding.EncodeModule(&wasm.Module{
+				ImportSection: []wasm.Import{{
+					Type: wasm.ExternTypeGlobal, Module: "env", Name: "g",
+					DescGlobal: wasm.GlobalType{ValType: wasm.ValueTypeI32},
+				}},
+				TypeSection:     []wasm.FunctionType{{}},
+				FunctionSection: []wasm.Index{0},
+				CodeSection:     []wasm.Code{{Body: []byte{wasm.OpcodeI32Const, 1, wasm.OpcodeGlobalSet, 0, wasm.OpcodeEnd}}},
+			}),
+			expectedErr: "invalid function[0]: global.set on immutable global[0]",
+		},
+		{
+			name:        "truncated gzip",
+			wasm:        []byte{0x1f, 0x8b},
+			expectedErr: "invalid gzip-compressed module: unexpected EOF",
+		},
+		{
+			name:        "truncated zstd",
+			wasm:        []byte{0x28, 0xb5, 0x2f, 0xfd, 0},
+			expectedErr: "invalid zstd-compressed module: zstd decompression error at 5: unexpected EOF",
 		},
 	}
 
@@ -186,6 +243,310 @@ func TestRuntime_CompileModule_Errors(t *testing.T) {
 	}
 }
 
+func TestRuntime_CompileModule_Gzip(t *testing.T) {
+	bin := binaryencoding.EncodeModule(&wasm.Module{
+		TypeSection:     []wasm.FunctionType{{}},
+		FunctionSection: []wasm.Index{0},
+		CodeSection:     []wasm.Code{{Body: []byte{wasm.OpcodeEnd}}},
+		ExportSection:   []wasm.Export{{Type: wasm.ExternTypeFunc, Name: "function", Index: 0}},
+	})
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	_, err := zw.Write(bin)
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+
+	r := NewRuntime(testCtx)
+	defer r.Close(testCtx)
+
+	compiled, err := r.CompileModule(testCtx, buf.Bytes())
+	require.NoError(t, err)
+	require.NotNil(t, compiled.ExportedFunctions()["function"])
+}
+
+func TestRuntime_CompileModule_Zstd(t *testing.T) {
+	// The module of TestRuntime_CompileModule_Gzip, compressed with "zstd -19".
+	bin := []byte{
+		0x28, 0xb5, 0x2f, 0xfd, 0x24, 0x26, 0x31, 0x01, 0x00, 0x00, 0x61, 0x73,
+		0x6d, 0x01, 0x00, 0x00, 0x00, 0x01, 0x04, 0x01, 0x60, 0x00, 0x00, 0x03,
+		0x02, 0x01, 0x00, 0x07, 0x0c, 0x01, 0x08, 0x66, 0x75, 0x6e, 0x63, 0x74,
+		0x69, 0x6f, 0x6e, 0x00, 0x00, 0x0a, 0x04, 0x01, 0x02, 0x00, 0x0b, 0x46,
+		0x7b, 0xdb, 0x2c,
+	}
+
+	r := NewRuntime(testCtx)
+	defer r.Close(testCtx)
+
+	compiled, err := r.CompileModule(testCtx, bin)
+	require.NoError(t, err)
+	require.NotNil(t, compiled.ExportedFunctions()["function"])
+}
+
+func Test_decompressModule(t *testing.T) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	_, err := zw.Write(make([]byte, 1000))
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+
+	t.Run("within limit", func(t *testing.T) {
+		inflated, err := decompressModule(buf.Bytes(), 1000)
+		require.NoError(t, err)
+		require.Equal(t, 1000, len(inflated))
+	})
+
+	t.Run("exceeds limit", func(t *testing.T) {
+		_, err := decompressModule(buf.Bytes(), 999)
+		require.EqualError(t, err, "gzip-compressed module inflates to more than 999 bytes")
+	})
+
+	t.Run("uncompressed", func(t *testing.T) {
+		bin := binaryencoding.EncodeModule(&wasm.Module{})
+		actual, err := decompressModule(bin, 0)
+		require.NoError(t, err)
+		require.Equal(t, bin, actual)
+	})
+}
+
+func TestRuntime_ExtendedConst(t *testing.T) {
+	// globals returns a module exporting the globals initialized with the given expressions.
+	globals := func(inits ...wasm.ConstantExpression) []byte {
+		m := &wasm.Module{}
+		for i, init := range inits {
+			m.GlobalSection = append(m.GlobalSection, wasm.Global{Type: wasm.GlobalType{ValType: wasm.ValueTypeI32}, Init: init})
+			m.ExportSection = append(m.ExportSection, wasm.Export{Type: wasm.ExternTypeGlobal, Name: strconv.Itoa(i), Index: wasm.Index(i)})
+		}
+		return binaryencoding.EncodeModule(m)
+	}
+	// global.get 0, i32.const 5, i32.add
+	addFive := func(index byte) wasm.ConstantExpression {
+		return wasm.ConstantExpression{Opcode: wasm.OpcodeI32Add, Data: []byte{wasm.OpcodeGlobalGet, index, wasm.OpcodeI32Const, 5}}
+	}
+	// i32.const 2, i32.const 3, i32.mul
+	six := wasm.ConstantExpression{Opcode: wasm.OpcodeI32Mul, Data: []byte{wasm.OpcodeI32Const, 2, wasm.OpcodeI32Const, 3}}
+
+	for _, tc := range []struct {
+		name   string
+		config RuntimeConfig
+	}{
+		{name: "interpreter", config: NewRuntimeConfigInterpreter()},
+		{name: "default", config: NewRuntimeConfig()},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			r := NewRuntimeWithConfig(testCtx, tc.config.WithCoreFeatures(api.CoreFeaturesV2|experimental.CoreFeaturesExtendedConst))
+			defer r.Close(testCtx)
+
+			t.Run("backward reference", func(t *testing.T) {
+				mod, err := r.Instantiate(testCtx, globals(six, addFive(0), addFive(1)))
+				require.NoError(t, err)
+				defer mod.Close(testCtx)
+
+				require.Equal(t, uint64(6), mod.ExportedGlobal("0").Get())
+				require.Equal(t, uint64(11), mod.ExportedGlobal("1").Get())
+				require.Equal(t, uint64(16), mod.ExportedGlobal("2").Get())
+			})
+
+			t.Run("forward reference", func(t *testing.T) {
+				_, err := r.CompileModule(testCtx, globals(addFive(1), six))
+				require.EqualError(t, err, "global index out of range")
+			})
+
+			t.Run("segment offsets", func(t *testing.T) {
+				base, err := r.InstantiateWithConfig(testCtx, globals(wasm.ConstantExpression{Opcode: wasm.OpcodeI32Const, Data: []byte{16}}),
+					NewModuleConfig().WithName("base"))
+				require.NoError(t, err)
+				defer base.Close(testCtx)
+
+				mod, err := r.Instantiate(testCtx, binaryencoding.EncodeModule(&wasm.Module{
+					TypeSection: []wasm.FunctionType{{Results: []wasm.ValueType{wasm.ValueTypeI32}, ResultNumInUint64: 1}},
+					ImportSection: []wasm.Import{{
+						Type: wasm.ExternTypeGlobal, Module: "base", Name: "0",
+						DescGlobal: wasm.GlobalType{ValType: wasm.ValueTypeI32},
+					}},
+					FunctionSection: []wasm.Index{0, 0},
+					CodeSection: []wasm.Code{
+						{Body: []byte{wasm.OpcodeI32Const, 42, wasm.OpcodeEnd}},
+						{Body: []byte{wasm.OpcodeI32Const, 1, wasm.OpcodeCallIndirect, 0, 0, wasm.OpcodeEnd}},
+					},
+					TableSection:  []wasm.Table{{Min: 2, Type: wasm.RefTypeFuncref}},
+					MemorySection: &wasm.Memory{Min: 1, Cap: 1, Max: 1},
+					ElementSection: []wasm.ElementSegment{{
+						// base - 15
+						OffsetExpr: wasm.ConstantExpression{Opcode: wasm.OpcodeI32Sub, Data: []byte{wasm.OpcodeGlobalGet, 0, wasm.OpcodeI32Const, 15}},
+						Init:       []wasm.Index{0},
+						Type:       wasm.RefTypeFuncref,
+						Mode:       wasm.ElementModeActive,
+					}},
+					DataSection: []wasm.DataSegment{
+						{
+							// base + 4*3
+							OffsetExpression: wasm.ConstantExpression{Opcode: wasm.OpcodeI32Add, Data: []byte{
+								wasm.OpcodeGlobalGet, 0, wasm.OpcodeI32Const, 4, wasm.OpcodeI32Const, 3, wasm.OpcodeI32Mul,
+							}},
+							Init: []byte{1, 2, 3, 4},
+						},
+						{
+							// 8 + 4*1, which is folded while decoding.
+							OffsetExpression: wasm.ConstantExpression{Opcode: wasm.OpcodeI32Add, Data: []byte{
+								wasm.OpcodeI32Const, 8, wasm.OpcodeI32Const, 4, wasm.OpcodeI32Const, 1, wasm.OpcodeI32Mul,
+							}},
+							Init: []byte{5, 6},
+						},
+					},
+					ExportSection: []wasm.Export{
+						{Type: wasm.ExternTypeMemory, Name: "memory"},
+						{Type: wasm.ExternTypeFunc, Name: "call", Index: 1},
+					},
+				}))
+				require.NoError(t, err)
+				defer mod.Close(testCtx)
+
+				b, ok := mod.Memory().Read(28, 4)
+				require.True(t, ok)
+				require.Equal(t, []byte{1, 2, 3, 4}, b)
+				b, ok = mod.Memory().Read(12, 2)
+				require.True(t, ok)
+				require.Equal(t, []byte{5, 6}, b)
+
+				results, err := mod.ExportedFunction("call").Call(testCtx)
+				require.NoError(t, err)
+				require.Equal(t, []uint64{42}, results)
+			})
+
+			t.Run("non-constant instruction", func(t *testing.T) {
+				// i32.const 6, i32.const 3, i32.div_s
+				_, err := r.CompileModule(testCtx, globals(wasm.ConstantExpression{
+					Opcode: wasm.OpcodeI32DivS, Data: []byte{wasm.OpcodeI32Const, 6, wasm.OpcodeI32Const, 3},
+				}))
+				require.EqualError(t, err, "global[0]: invalid byte for const expression opt code: 0x6d")
+			})
+
+			t.Run("disabled", func(t *testing.T) {
+				r := NewRuntimeWithConfig(testCtx, tc.config)
+				defer r.Close(testCtx)
+
+				_, err := r.CompileModule(testCtx, globals(six))
+				require.EqualError(t, err, "global[0]: constant expression has been not terminated")
+			})
+		})
+	}
+}
+
+func TestRuntime_CompileModule_DisabledFeature(t *testing.T) {
+	// local.get 0, i32x4.splat, i32x4.extract_lane 3
+	simd := binaryencoding.EncodeModule(&wasm.Module{
+		TypeSection:     []wasm.FunctionType{{Params: []wasm.ValueType{wasm.ValueTypeI32}, Results: []wasm.ValueType{wasm.ValueTypeI32}}},
+		FunctionSection: []wasm.Index{0},
+		CodeSection: []wasm.Code{{Body: []byte{
+			wasm.OpcodeLocalGet, 0,
+			wasm.OpcodeVecPrefix, wasm.OpcodeVecI32x4Splat,
+			wasm.OpcodeVecPrefix, wasm.OpcodeVecI32x4ExtractLane, 3,
+			wasm.OpcodeEnd,
+		}}},
+		ExportSection: []wasm.Export{{Name: "splat", Type: wasm.ExternTypeFunc, Index: 0}},
+	})
+	// Even without any SIMD instruction, the v128 type requires the feature.
+	v128 := binaryencoding.EncodeModule(&wasm.Module{TypeSection: []wasm.FunctionType{{Params: []wasm.ValueType{wasm.ValueTypeV128}}}})
+
+	for _, tc := range []struct {
+		name         string
+		features     api.CoreFeatures
+		expectedErrs []string
+	}{
+		{name: "enabled", features: api.CoreFeaturesV2},
+		{
+			name:     "disabled",
+			features: api.CoreFeaturesV2.SetEnabled(api.CoreFeatureSIMD, false),
+			expectedErrs: []string{
+				`invalid function[0] export["splat"]: i32x4.splat invalid as feature "simd" is disabled`,
+				`type[0]: v128 invalid as feature "simd" is disabled`,
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r := NewRuntimeWithConfig(testCtx, NewRuntimeConfig().WithCoreFeatures(tc.features))
+			defer r.Close(testCtx)
+
+			for i, bin := range [][]byte{simd, v128} {
+				_, err := r.CompileModule(testCtx, bin)
+				if tc.expectedErrs == nil {
+					require.NoError(t, err)
+				} else {
+					require.EqualError(t, err, tc.expectedErrs[i])
+				}
+			}
+		})
+	}
+}
+
+func TestRuntime_CompileModule_MaxBlockNestingDepth(t *testing.T) {
+	// nestedBlocks returns a module whose only function nests depth blocks.
+	nestedBlocks := func(depth int) []byte {
+		body := bytes.Repeat([]byte{wasm.OpcodeBlock, 0x40}, depth) // 0x40 is the empty block type.
+		body = append(body, bytes.Repeat([]byte{wasm.OpcodeEnd}, depth+1)...)
+		return binaryencoding.EncodeModule(&wasm.Module{
+			TypeSection:     []wasm.FunctionType{{}},
+			FunctionSection: []wasm.Index{0},
+			CodeSection:     []wasm.Code{{Body: body}},
+		})
+	}
+
+	t.Run("default", func(t *testing.T) {
+		r := NewRuntime(testCtx)
+		defer r.Close(testCtx)
+
+		_, err := r.CompileModule(testCtx, nestedBlocks(1_000_000))
+		require.EqualError(t, err, "invalid function[0]: block at depth 65537 exceeds block nesting limit 65536")
+	})
+
+	t.Run("WithMaxBlockNestingDepth", func(t *testing.T) {
+		r := NewRuntimeWithConfig(testCtx, NewRuntimeConfig().WithMaxBlockNestingDepth(3))
+		defer r.Close(testCtx)
+
+		_, err := r.CompileModule(testCtx, nestedBlocks(3))
+		require.NoError(t, err)
+
+		_, err = r.CompileModule(testCtx, nestedBlocks(4))
+		require.EqualError(t, err, "invalid function[0]: block at depth 4 exceeds block nesting limit 3")
+	})
+}
+
+func TestRuntime_CompileModule_DecodeLimits(t *testing.T) {
+	// functions returns a module with count functions of type[0].
+	functions := func(count int) []byte {
+		m := &wasm.Module{TypeSection: []wasm.FunctionType{{}}, FunctionSection: make([]wasm.Index, count)}
+		for i := 0; i < count; i++ {
+			m.CodeSection = append(m.CodeSection, wasm.Code{Body: []byte{wasm.OpcodeEnd}})
+		}
+		return binaryencoding.EncodeModule(m)
+	}
+
+	r := NewRuntimeWithConfig(testCtx, NewRuntimeConfig().WithDecodeLimits(2, 1, 0, 0))
+	defer r.Close(testCtx)
+
+	_, err := r.CompileModule(testCtx, functions(2))
+	require.NoError(t, err)
+
+	_, err = r.CompileModule(testCtx, functions(3))
+	require.EqualError(t, err, "section function: too many functions in a module: 3 given with limit 2")
+
+	// Imported functions count toward the limit.
+	_, err = r.CompileModule(testCtx, binaryencoding.EncodeModule(&wasm.Module{
+		TypeSection:     []wasm.FunctionType{{}},
+		ImportSection:   []wasm.Import{{Type: wasm.ExternTypeFunc, Module: "env", Name: "f", DescFunc: 0}},
+		FunctionSection: []wasm.Index{0, 0},
+		CodeSection:     []wasm.Code{{Body: []byte{wasm.OpcodeEnd}}, {Body: []byte{wasm.OpcodeEnd}}},
+	}))
+	require.EqualError(t, err, "section function: too many functions in a module: 3 given with limit 2")
+
+	_, err = r.CompileModule(testCtx, binaryencoding.EncodeModule(&wasm.Module{
+		TypeSection: []wasm.FunctionType{{}, {}},
+	}))
+	require.EqualError(t, err, "section type: too many types in a module: 2 given with limit 1")
+}
+
 // TestModule_Memory only covers a couple cases to avoid duplication of internal/wasm/runtime_test.go
 func TestModule_Memory(t *testing.T) {
 	tests := []struct {
@@ -209,262 +570,1612 @@ func TestModule_Memory(t *testing.T) {
 		},
 	}
 
-	for _, tt := range tests {
-		tc := tt
+	for _, tt := range tests {
+		tc := tt
+
+		t.Run(tc.name, func(t *testing.T) {
+			r := NewRuntime(testCtx)
+			defer r.Close(testCtx)
+
+			// Instantiate the module and get the export of the above memory
+			module, err := r.Instantiate(testCtx, tc.wasm)
+			require.NoError(t, err)
+
+			mem := module.ExportedMemory("memory")
+			if tc.expected {
+				require.Equal(t, tc.expectedLen, mem.Size())
+				defs := module.ExportedMemoryDefinitions()
+				require.Equal(t, 1, len(defs))
+				def := defs["memory"]
+				require.Equal(t, tc.expectedLen>>16, def.Min())
+			} else {
+				require.Nil(t, mem)
+				require.Zero(t, len(module.ExportedMemoryDefinitions()))
+			}
+		})
+	}
+}
+
+// TestModule_Global only covers a couple cases to avoid duplication of internal/wasm/global_test.go
+func TestModule_Global(t *testing.T) {
+	globalVal := int64(100) // intentionally a value that differs in signed vs unsigned encoding
+
+	tests := []struct {
+		name                      string
+		module                    *wasm.Module // module as wat doesn't yet support globals
+		expected, expectedMutable bool
+	}{
+		{
+			name:   "no global",
+			module: &wasm.Module{},
+		},
+		{
+			name: "global not exported",
+			module: &wasm.Module{
+				GlobalSection: []wasm.Global{
+					{
+						Type: wasm.GlobalType{ValType: wasm.ValueTypeI64, Mutable: true},
+						Init: wasm.ConstantExpression{Opcode: wasm.OpcodeI64Const, Data: leb128.EncodeInt64(globalVal)},
+					},
+				},
+			},
+		},
+		{
+			name: "global exported",
+			module: &wasm.Module{
+				GlobalSection: []wasm.Global{
+					{
+						Type: wasm.GlobalType{ValType: wasm.ValueTypeI64},
+						Init: wasm.ConstantExpression{Opcode: wasm.OpcodeI64Const, Data: leb128.EncodeInt64(globalVal)},
+					},
+				},
+				Exports: map[string]*wasm.Export{
+					"global": {Type: wasm.ExternTypeGlobal, Name: "global"},
+				},
+			},
+			expected: true,
+		},
+		{
+			name: "global exported and mutable",
+			module: &wasm.Module{
+				GlobalSection: []wasm.Global{
+					{
+						Type: wasm.GlobalType{ValType: wasm.ValueTypeI64, Mutable: true},
+						Init: wasm.ConstantExpression{Opcode: wasm.OpcodeI64Const, Data: leb128.EncodeInt64(globalVal)},
+					},
+				},
+				Exports: map[string]*wasm.Export{
+					"global": {Type: wasm.ExternTypeGlobal, Name: "global"},
+				},
+			},
+			expected:        true,
+			expectedMutable: true,
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+
+		t.Run(tc.name, func(t *testing.T) {
+			r := NewRuntime(testCtx).(*runtime)
+			defer r.Close(testCtx)
+
+			code := &compiledModule{module: tc.module, compiledEngine: r.store.Engine}
+
+			err := r.store.Engine.CompileModule(testCtx, code.module, nil, false)
+			require.NoError(t, err)
+
+			// Instantiate the module and get the export of the above global
+			module, err := r.InstantiateModule(testCtx, code, NewModuleConfig())
+			require.NoError(t, err)
+
+			global := module.ExportedGlobal("global")
+			if !tc.expected {
+				require.Nil(t, global)
+				return
+			}
+			require.Equal(t, uint64(globalVal), global.Get())
+
+			mutable, ok := global.(api.MutableGlobal)
+			require.Equal(t, tc.expectedMutable, ok)
+			if ok {
+				mutable.Set(2)
+				require.Equal(t, uint64(2), global.Get())
+			}
+		})
+	}
+}
+
+func TestRuntime_InstantiateModule_UsesContext(t *testing.T) {
+	r := NewRuntime(testCtx)
+	defer r.Close(testCtx)
+
+	// Define a function that will be set as the start function
+	var calledStart bool
+	start := func(ctx context.Context) {
+		calledStart = true
+		require.Equal(t, testCtx, ctx)
+	}
+
+	_, err := r.NewHostModuleBuilder("env").
+		NewFunctionBuilder().WithFunc(start).Export("start").
+		Instantiate(testCtx)
+	require.NoError(t, err)
+
+	one := uint32(1)
+	binary := binaryencoding.EncodeModule(&wasm.Module{
+		TypeSection:     []wasm.FunctionType{{}},
+		ImportSection:   []wasm.Import{{Module: "env", Name: "start", Type: wasm.ExternTypeFunc, DescFunc: 0}},
+		FunctionSection: []wasm.Index{0},
+		CodeSection: []wasm.Code{
+			{Body: []byte{wasm.OpcodeCall, 0, wasm.OpcodeEnd}}, // Call the imported env.start.
+		},
+		StartSection: &one,
+	})
+
+	code, err := r.CompileModule(testCtx, binary)
+	require.NoError(t, err)
+
+	// Instantiate the module, which calls the start function. This will fail if the context wasn't as intended.
+	mod, err := r.InstantiateModule(testCtx, code, NewModuleConfig())
+	require.NoError(t, err)
+
+	require.True(t, calledStart)
+
+	// Closing the module shouldn't remove the compiler cache
+	require.NoError(t, mod.Close(testCtx))
+	require.Equal(t, uint32(2), r.(*runtime).store.Engine.CompiledModuleCount())
+}
+
+// TestRuntime_Instantiate_DoesntEnforce_Start ensures wapc-go work when modules import WASI, but don't
+// export "_start".
+func TestRuntime_Instantiate_DoesntEnforce_Start(t *testing.T) {
+	r := NewRuntime(testCtx)
+	defer r.Close(testCtx)
+
+	binary := binaryencoding.EncodeModule(&wasm.Module{
+		MemorySection: &wasm.Memory{Min: 1},
+		ExportSection: []wasm.Export{{Name: "memory", Type: wasm.ExternTypeMemory, Index: 0}},
+	})
+
+	mod, err := r.Instantiate(testCtx, binary)
+	require.NoError(t, err)
+	require.NoError(t, mod.Close(testCtx))
+}
+
+func TestRuntime_InstantiateModule_WithStartSection(t *testing.T) {
+	r := NewRuntime(testCtx)
+	defer r.Close(testCtx)
+
+	var startCount int
+	_, err := r.NewHostModuleBuilder("env").
+		NewFunctionBuilder().WithFunc(func() { startCount++ }).Export("start").
+		Instantiate(testCtx)
+	require.NoError(t, err)
+
+	one := uint32(1)
+	binary := binaryencoding.EncodeModule(&wasm.Module{
+		TypeSection:     []wasm.FunctionType{{}},
+		ImportSection:   []wasm.Import{{Module: "env", Name: "start", Type: wasm.ExternTypeFunc, DescFunc: 0}},
+		FunctionSection: []wasm.Index{0},
+		CodeSection:     []wasm.Code{{Body: []byte{wasm.OpcodeCall, 0, wasm.OpcodeEnd}}},
+		StartSection:    &one,
+	})
+
+	mod, err := r.InstantiateWithConfig(testCtx, binary, NewModuleConfig().WithStartSection(false))
+	require.NoError(t, err)
+	require.Equal(t, 0, startCount)
+
+	// The start function is called once, regardless of how many times RunStart is.
+	require.NoError(t, mod.RunStart(testCtx))
+	require.Equal(t, 1, startCount)
+	require.NoError(t, mod.RunStart(testCtx))
+	require.Equal(t, 1, startCount)
+
+	// RunStart is a no-op when instantiation already called the start function.
+	mod, err = r.InstantiateWithConfig(testCtx, binary, NewModuleConfig().WithName("started"))
+	require.NoError(t, err)
+	require.Equal(t, 2, startCount)
+	require.NoError(t, mod.RunStart(testCtx))
+	require.Equal(t, 2, startCount)
+
+	t.Run("invalid start function", func(t *testing.T) {
+		binary := binaryencoding.EncodeModule(&wasm.Module{
+			TypeSection:     []wasm.FunctionType{{Params: []wasm.ValueType{wasm.ValueTypeI32}}},
+			FunctionSection: []wasm.Index{0},
+			CodeSection:     []wasm.Code{{Body: []byte{wasm.OpcodeEnd}}},
+			StartSection:    new(uint32),
+		})
+
+		_, err := r.InstantiateWithConfig(testCtx, binary, NewModuleConfig().WithStartSection(false))
+		require.EqualError(t, err, "invalid start function: func[0] must have an empty (nullary) signature: i32_v")
+	})
+}
+
+func TestRuntime_Instantiate_ErrorOnStart(t *testing.T) {
+	tests := []struct {
+		name, wasm string
+	}{
+		{
+			name: "_start function",
+			wasm: `(module
+	(import "" "start" (func $start))
+	(export "_start" (func $start))
+)`,
+		},
+		{
+			name: ".start function",
+			wasm: `(module
+	(import "" "start" (func $start))
+	(start $start)
+)`,
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+
+		t.Run(tc.name, func(t *testing.T) {
+			r := NewRuntime(testCtx)
+			defer r.Close(testCtx)
+
+			start := func() {
+				panic(errors.New("ice cream"))
+			}
+
+			host, err := r.NewHostModuleBuilder("host").
+				NewFunctionBuilder().WithFunc(start).Export("start").
+				Instantiate(testCtx)
+			require.NoError(t, err)
+
+			// Start the module as a WASI command. We expect it to fail.
+			_, err = r.Instantiate(testCtx, []byte(tc.wasm))
+			require.Error(t, err)
+
+			// Close the imported module, which should remove its compiler cache.
+			require.NoError(t, host.Close(testCtx))
+
+			// The compiler cache of the importing module should be removed on error.
+			require.Zero(t, r.(*runtime).store.Engine.CompiledModuleCount())
+		})
+	}
+}
+
+func TestRuntime_Instantiate_ErrorTypes(t *testing.T) {
+	decode := func(t *testing.T, wat string) []byte {
+		m, err := text.DecodeModule([]byte(wat))
+		require.NoError(t, err)
+		return binaryencoding.EncodeModule(m)
+	}
+
+	t.Run("missing import", func(t *testing.T) {
+		r := NewRuntime(testCtx)
+		defer r.Close(testCtx)
+
+		bin := decode(t, `(module (import "env" "f" (func)))`)
+		_, err := r.Instantiate(testCtx, bin)
+		var missing *api.MissingImportError
+		require.True(t, errors.As(err, &missing))
+		require.Equal(t, &api.MissingImportError{Module: "env", Name: "f", Type: api.ExternTypeFunc, ModuleMissing: true}, missing)
+		require.EqualError(t, err, "module[env] not instantiated")
+
+		_, err = r.NewHostModuleBuilder("env").Instantiate(testCtx)
+		require.NoError(t, err)
+		_, err = r.Instantiate(testCtx, bin)
+		require.True(t, errors.As(err, &missing))
+		require.Equal(t, &api.MissingImportError{Module: "env", Name: "f", Type: api.ExternTypeFunc}, missing)
+		require.EqualError(t, err, `"f" is not exported in module "env"`)
+	})
+
+	t.Run("start function trap", func(t *testing.T) {
+		for _, tc := range []struct {
+			name, wat, function string
+		}{
+			{
+				name:     "start section",
+				wat:      `(module $m (func $start unreachable) (start $start))`,
+				function: "start function[0]",
+			},
+			{
+				name:     "_start",
+				wat:      `(module $m (func (export "_start") unreachable))`,
+				function: "function[_start]",
+			},
+		} {
+			tc := tc
+			t.Run(tc.name, func(t *testing.T) {
+				r := NewRuntime(testCtx)
+				defer r.Close(testCtx)
+
+				_, err := r.Instantiate(testCtx, decode(t, tc.wat))
+				var trap *api.StartFunctionTrapError
+				require.True(t, errors.As(err, &trap))
+				require.Equal(t, "m", trap.Module)
+				require.Equal(t, tc.function, trap.Function)
+				require.ErrorIs(t, err, wasmruntime.ErrRuntimeUnreachable)
+			})
+		}
+	})
+
+	t.Run("limit exceeded", func(t *testing.T) {
+		r := NewRuntimeWithConfig(testCtx, NewRuntimeConfig().WithMemoryLimitPages(1))
+		defer r.Close(testCtx)
+
+		_, err := r.Instantiate(testCtx, decode(t, `(module (memory 2))`))
+		var limit *api.LimitExceededError
+		require.True(t, errors.As(err, &limit))
+		require.Equal(t, &api.LimitExceededError{Resource: "memory min pages", Requested: 2, Limit: 1}, limit)
+		require.EqualError(t, err, "section memory: memory min pages: 2 over limit of 1")
+	})
+}
+
+// TestRuntime_InstantiateModule_WithName tests that we can pre-validate (cache) a module and instantiate it under
+// different names. This pattern is used in wapc-go.
+func TestRuntime_InstantiateModule_WithName(t *testing.T) {
+	r := NewRuntime(testCtx)
+	defer r.Close(testCtx)
+
+	base, err := r.CompileModule(testCtx, binaryNamedZero)
+	require.NoError(t, err)
+
+	require.Equal(t, "0", base.(*compiledModule).module.NameSection.ModuleName)
+
+	// Use the same runtime to instantiate multiple modules
+	internal := r.(*runtime)
+	m1, err := r.InstantiateModule(testCtx, base, NewModuleConfig().WithName("1"))
+	require.NoError(t, err)
+	require.Equal(t, "1", m1.Name())
+
+	require.Nil(t, internal.Module("0"))
+	require.Equal(t, internal.Module("1"), m1)
+
+	m2, err := r.InstantiateModule(testCtx, base, NewModuleConfig().WithName("2"))
+	require.NoError(t, err)
+	require.Equal(t, "2", m2.Name())
+
+	require.Nil(t, internal.Module("0"))
+	require.Equal(t, internal.Module("2"), m2)
+
+	// Empty name module shouldn't be returned via Module() for future optimization.
+	m3, err := r.InstantiateModule(testCtx, base, NewModuleConfig().WithName(""))
+	require.NoError(t, err)
+	require.Equal(t, "", m3.Name())
+
+	ret := internal.Module("")
+	require.Nil(t, ret)
+}
+
+func TestRuntime_InstantiateModule_WithImportRename(t *testing.T) {
+	r := NewRuntime(testCtx)
+	defer r.Close(testCtx)
+
+	_, err := r.NewHostModuleBuilder("host").
+		NewFunctionBuilder().WithFunc(func() uint32 { return 42 }).Export("answer").
+		Instantiate(testCtx)
+	require.NoError(t, err)
+
+	m, err := text.DecodeModule([]byte(`(module
+  (import "env" "answer" (func $answer (result i32)))
+  (func (export "call") (result i32) call $answer)
+)`))
+	require.NoError(t, err)
+	compiled, err := r.CompileModule(testCtx, binaryencoding.EncodeModule(m))
+	require.NoError(t, err)
+
+	_, err = r.InstantiateModule(testCtx, compiled, NewModuleConfig())
+	require.EqualError(t, err, "module[env] not instantiated")
+
+	mod, err := r.InstantiateModule(testCtx, compiled, NewModuleConfig().WithImportRename("env", "host"))
+	require.NoError(t, err)
+	results, err := mod.ExportedFunction("call").Call(testCtx)
+	require.NoError(t, err)
+	require.Equal(t, []uint64{42}, results)
+
+	// Renaming imports from an instantiated module errs, as it would be shadowed.
+	_, err = r.NewHostModuleBuilder("env").Instantiate(testCtx)
+	require.NoError(t, err)
+	_, err = r.InstantiateModule(testCtx, compiled, NewModuleConfig().WithName("other").WithImportRename("env", "host"))
+	require.EqualError(t, err, "import rename from module[env] to module[host] collides with instantiated module[env]")
+}
+
+func TestRuntime_InstantiateModule_WithImportStub(t *testing.T) {
+	r := NewRuntime(testCtx)
+	defer r.Close(testCtx)
+
+	m, err := text.DecodeModule([]byte(`(module
+  (import "env" "answer" (func $answer (param i32) (result i32)))
+  (import "env" "answer" (func $again (param i32) (result i32)))
+  (func (export "call") (result i32) (i32.const 1) (call $answer))
+)`))
+	require.NoError(t, err)
+	compiled, err := r.CompileModule(testCtx, binaryencoding.EncodeModule(m))
+	require.NoError(t, err)
+
+	// The stubbed module doesn't need to be instantiated.
+	mod, err := r.InstantiateModule(testCtx, compiled, NewModuleConfig().
+		WithImportStub("env", "answer", []uint64{api.EncodeI32(42)}))
+	require.NoError(t, err)
+	results, err := mod.ExportedFunction("call").Call(testCtx)
+	require.NoError(t, err)
+	require.Equal(t, []uint64{42}, results)
+	require.Nil(t, r.Module("env"))
+
+	_, err = mod.(*wasm.ModuleInstance).Clone(testCtx, "clone")
+	require.EqualError(t, err, "module[] cannot be cloned as it imports stubs")
+	require.NoError(t, mod.Close(testCtx))
+
+	// The stub takes precedence over an instantiated module.
+	_, err = r.NewHostModuleBuilder("env").
+		NewFunctionBuilder().WithFunc(func(uint32) uint32 { return 1 }).Export("answer").
+		Instantiate(testCtx)
+	require.NoError(t, err)
+	mod, err = r.InstantiateModule(testCtx, compiled, NewModuleConfig().
+		WithImportStub("env", "answer", []uint64{api.EncodeI32(42)}))
+	require.NoError(t, err)
+	results, err = mod.ExportedFunction("call").Call(testCtx)
+	require.NoError(t, err)
+	require.Equal(t, []uint64{42}, results)
+	require.NoError(t, mod.Close(testCtx))
+
+	_, err = r.InstantiateModule(testCtx, compiled, NewModuleConfig().
+		WithImportStub("env", "answer", nil))
+	require.EqualError(t, err, "import stub env.answer has 0 results, but the import returns 1")
+
+	_, err = r.InstantiateModule(testCtx, compiled, NewModuleConfig().
+		WithImportStub("env", "answer", []uint64{0}).
+		WithImportStub("env", "question", nil))
+	require.EqualError(t, err, "import stub env.question doesn't match a function import")
+}
+
+func TestRuntime_InstantiateModule_WithMinMemoryPages(t *testing.T) {
+	r := NewRuntimeWithConfig(testCtx, NewRuntimeConfig().WithMemoryLimitPages(8))
+	defer r.Close(testCtx)
+
+	m, err := text.DecodeModule([]byte(`(module
+  (memory (export "memory") 1 4)
+  (func (export "size") (result i32) memory.size)
+)`))
+	require.NoError(t, err)
+	compiled, err := r.CompileModule(testCtx, binaryencoding.EncodeModule(m))
+	require.NoError(t, err)
+
+	for _, tc := range []struct {
+		minPages, expected uint32
+	}{
+		{minPages: 0, expected: 1},
+		{minPages: 1, expected: 1},
+		{minPages: 3, expected: 3},
+		{minPages: 4, expected: 4},
+	} {
+		mod, err := r.InstantiateModule(testCtx, compiled, NewModuleConfig().WithName("").WithMinMemoryPages(tc.minPages))
+		require.NoError(t, err)
+		results, err := mod.ExportedFunction("size").Call(testCtx)
+		require.NoError(t, err)
+		require.Equal(t, []uint64{uint64(tc.expected)}, results)
+		mem := mod.ExportedMemory("memory")
+		require.Equal(t, tc.expected*65536, mem.Size())
+		require.Equal(t, uint32(1), mem.Definition().Min())
+		require.NoError(t, mod.Close(testCtx))
+	}
+
+	_, err = r.InstantiateModule(testCtx, compiled, NewModuleConfig().WithMinMemoryPages(5))
+	var limitErr *api.LimitExceededError
+	require.True(t, errors.As(err, &limitErr))
+	require.EqualError(t, err, "memory min pages: 5 over limit of 4")
+
+	// Without a declared maximum, the memory limit applies.
+	m, err = text.DecodeModule([]byte(`(module (memory (export "memory") 1))`))
+	require.NoError(t, err)
+	compiled, err = r.CompileModule(testCtx, binaryencoding.EncodeModule(m))
+	require.NoError(t, err)
+	mod, err := r.InstantiateModule(testCtx, compiled, NewModuleConfig().WithMinMemoryPages(8))
+	require.NoError(t, err)
+	require.Equal(t, uint32(8*65536), mod.ExportedMemory("memory").Size())
+	require.NoError(t, mod.Close(testCtx))
+	_, err = r.InstantiateModule(testCtx, compiled, NewModuleConfig().WithMinMemoryPages(9))
+	require.EqualError(t, err, "memory min pages: 9 over limit of 8")
+}
+
+func TestRuntime_InstantiateModule_WithMemoryBuffer(t *testing.T) {
+	r := NewRuntime(testCtx)
+	defer r.Close(testCtx)
+
+	m, err := text.DecodeModule([]byte(`(module
+  (memory (export "memory") 1 8)
+  (func (export "grow") (param i32) (result i32) local.get 0 memory.grow)
+)`))
+	require.NoError(t, err)
+	compiled, err := r.CompileModule(testCtx, binaryencoding.EncodeModule(m))
+	require.NoError(t, err)
+
+	buf := make([]byte, 3*65536)
+	for i := 0; i < 2; i++ { // The buffer is reused after close.
+		buf[0] = 0xff // The memory is zeroed during instantiation.
+		mod, err := r.InstantiateModule(testCtx, compiled, NewModuleConfig().WithName("").WithMemoryBuffer(buf))
+		require.NoError(t, err)
+		mem := mod.ExportedMemory("memory")
+		require.Equal(t, uint32(65536), mem.Size())
+		b, ok := mem.ReadByte(0)
+		require.True(t, ok)
+		require.Equal(t, byte(0), b)
+		require.True(t, mem.WriteByte(65536-1, 1))
+		require.Equal(t, byte(1), buf[65536-1])
+
+		grow := mod.ExportedFunction("grow")
+		results, err := grow.Call(testCtx, 3) // beyond the buffer
+		require.NoError(t, err)
+		require.Equal(t, uint32(0xffffffff), api.DecodeU32(results[0]))
+		results, err = grow.Call(testCtx, 2)
+		require.NoError(t, err)
+		require.Equal(t, uint32(1), api.DecodeU32(results[0]))
+		require.True(t, mem.WriteByte(3*65536-1, 2))
+		require.Equal(t, byte(2), buf[3*65536-1])
+
+		require.NoError(t, mod.Close(testCtx))
+		require.Equal(t, uint32(0), mem.Size())
+	}
+
+	_, err = r.InstantiateModule(testCtx, compiled, NewModuleConfig().WithMemoryBuffer(make([]byte, 65536+1)))
+	require.EqualError(t, err, "memory buffer size 65537 isn't a multiple of the page size")
+	_, err = r.InstantiateModule(testCtx, compiled, NewModuleConfig().WithMinMemoryPages(2).WithMemoryBuffer(buf[:65536]))
+	require.EqualError(t, err, "memory buffer of 1 pages is less than the minimum of 2 pages")
+
+	// Reusing the config while a module backed by the buffer isn't closed fails, without zeroing its memory.
+	config := NewModuleConfig().WithName("").WithMemoryBuffer(buf)
+	mod, err := r.InstantiateModule(testCtx, compiled, config)
+	require.NoError(t, err)
+	require.True(t, mod.ExportedMemory("memory").WriteByte(0, 1))
+	_, err = r.InstantiateModule(testCtx, compiled, config)
+	require.EqualError(t, err, "memory buffer is in use by another module")
+	require.Equal(t, byte(1), buf[0])
+	require.NoError(t, mod.Close(testCtx))
+	mod, err = r.InstantiateModule(testCtx, compiled, config)
+	require.NoError(t, err)
+	require.NoError(t, mod.Close(testCtx))
+}
+
+func TestRuntime_InstantiateModule_WithFuel(t *testing.T) {
+	// loop is a function that never returns.
+	bin := binaryencoding.EncodeModule(&wasm.Module{
+		TypeSection:     []wasm.FunctionType{{}},
+		FunctionSection: []wasm.Index{0},
+		CodeSection: []wasm.Code{
+			{Body: []byte{wasm.OpcodeLoop, 0x40, wasm.OpcodeBr, 0, wasm.OpcodeEnd, wasm.OpcodeEnd}},
+		},
+		ExportSection: []wasm.Export{{Name: "loop", Type: wasm.ExternTypeFunc, Index: 0}},
+	})
+
+	t.Run("not supported by the compiler", func(t *testing.T) {
+		if !platform.CompilerSupported() {
+			t.Skip()
+		}
+		r := NewRuntimeWithConfig(testCtx, NewRuntimeConfigCompiler())
+		defer r.Close(testCtx)
+
+		_, err := r.InstantiateWithConfig(testCtx, bin, NewModuleConfig().WithFuel(100))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "WithFuel is not supported by the compiler: use wazero.NewRuntimeConfigInterpreter")
+	})
+
+	t.Run("fuel exhausted", func(t *testing.T) {
+		r := NewRuntimeWithConfig(testCtx, NewRuntimeConfigInterpreter())
+		defer r.Close(testCtx)
+
+		m, err := r.InstantiateWithConfig(testCtx, bin, NewModuleConfig().WithFuel(100))
+		require.NoError(t, err)
+
+		_, err = m.ExportedFunction("loop").Call(testCtx)
+		require.Equal(t, sys.NewExitError(sys.ExitCodeFuelExhausted), err)
+		require.True(t, m.IsClosed())
+	})
+}
+
+func TestRuntime_InstantiateModule_WithMemoryGrowHook(t *testing.T) {
+	m, err := text.DecodeModule([]byte(`(module
+  (memory 1 10)
+  (func (export "grow") (param i32) (result i32) local.get 0 memory.grow)
+  (func $start i32.const 1 memory.grow drop)
+  (start $start)
+)`))
+	require.NoError(t, err)
+	bin := binaryencoding.EncodeModule(m)
+
+	for _, tc := range []struct {
+		name   string
+		config RuntimeConfig
+	}{
+		{name: "interpreter", config: NewRuntimeConfigInterpreter()},
+		{name: "default", config: NewRuntimeConfig()},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r := NewRuntimeWithConfig(testCtx, tc.config)
+			defer r.Close(testCtx)
+
+			var calls [][2]uint32
+			var hookMod api.Module
+			mod, err := r.InstantiateWithConfig(testCtx, bin, NewModuleConfig().
+				WithMemoryGrowHook(func(mod api.Module, previousPages, newPages uint32) bool {
+					hookMod = mod
+					calls = append(calls, [2]uint32{previousPages, newPages})
+					return newPages <= 4
+				}))
+			require.NoError(t, err)
+			require.Equal(t, mod, hookMod)
+
+			grow := mod.ExportedFunction("grow")
+			results, err := grow.Call(testCtx, 2)
+			require.NoError(t, err)
+			require.Equal(t, uint64(2), results[0])
+
+			// A vetoed grow returns -1.
+			results, err = grow.Call(testCtx, 1)
+			require.NoError(t, err)
+			require.Equal(t, uint64(math.MaxUint32), results[0])
+			require.Equal(t, uint32(4), mod.Memory().Size()/65536)
+
+			// The start function grows memory, so the hook applies to it as well.
+			require.Equal(t, [][2]uint32{{1, 2}, {2, 4}, {4, 5}}, calls)
+		})
+	}
+}
+
+func TestRuntime_InstantiateModule_WithHostPanicRecovery(t *testing.T) {
+	m, err := text.DecodeModule([]byte(`(module
+  (import "env" "host" (func $host (param i32)))
+  (func (export "call") (param i32) local.get 0 call $host)
+)`))
+	require.NoError(t, err)
+	bin := binaryencoding.EncodeModule(m)
+
+	errHost := errors.New("host failed")
+	for _, tc := range []struct {
+		name   string
+		config RuntimeConfig
+	}{
+		{name: "interpreter", config: NewRuntimeConfigInterpreter()},
+		{name: "default", config: NewRuntimeConfig()},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r := NewRuntimeWithConfig(testCtx, tc.config)
+			defer r.Close(testCtx)
+
+			// host panics with "whoops", or exits with the code, when it isn't zero.
+			_, err := r.NewHostModuleBuilder("env").NewFunctionBuilder().
+				WithFunc(func(ctx context.Context, m api.Module, exitCode uint32) {
+					if exitCode != 0 {
+						panic(sys.NewExitError(exitCode))
+					}
+					panic("whoops")
+				}).Export("host").
+				Instantiate(testCtx)
+			require.NoError(t, err)
+
+			t.Run("default", func(t *testing.T) {
+				mod, err := r.InstantiateWithConfig(testCtx, bin, NewModuleConfig().WithName(""))
+				require.NoError(t, err)
+				defer mod.Close(testCtx)
+
+				_, err = mod.ExportedFunction("call").Call(testCtx, 0)
+				require.Contains(t, err.Error(), "whoops (recovered by wazero)")
+			})
+
+			var recovered []interface{}
+			mod, err := r.InstantiateWithConfig(testCtx, bin, NewModuleConfig().WithName("").
+				WithHostPanicRecovery(func(r interface{}) error {
+					recovered = append(recovered, r)
+					return fmt.Errorf("%w: %v", errHost, r)
+				}))
+			require.NoError(t, err)
+			defer mod.Close(testCtx)
+
+			call := mod.ExportedFunction("call")
+			_, err = call.Call(testCtx, 0)
+			require.ErrorIs(t, err, errHost)
+			require.Contains(t, err.Error(), "host failed: whoops")
+			require.Equal(t, []interface{}{"whoops"}, recovered)
+
+			// The module can still be called after the trap.
+			_, err = call.Call(testCtx, 0)
+			require.ErrorIs(t, err, errHost)
+			require.Equal(t, 2, len(recovered))
+
+			// An exit isn't a panic to recover.
+			_, err = call.Call(testCtx, 3)
+			var exitErr *sys.ExitError
+			require.True(t, errors.As(err, &exitErr))
+			require.Equal(t, uint32(3), exitErr.ExitCode())
+			require.Equal(t, 2, len(recovered))
+		})
+	}
+}
+
+func TestRuntime_InstantiateModule_WithHostCallLogger(t *testing.T) {
+	m, err := text.DecodeModule([]byte(`(module
+  (import "env" "add" (func $add (param i32 i32) (result i32)))
+  (import "env" "exit" (func $exit (param i32)))
+  (func $start (drop (call $add (i32.const 1) (i32.const 2))))
+  (func (export "add") (param i32 i32) (result i32) local.get 0 local.get 1 call $add)
+  (func (export "exit") (param i32) local.get 0 call $exit)
+  (start $start)
+)`))
+	require.NoError(t, err)
+	bin := binaryencoding.EncodeModule(m)
+
+	type call struct {
+		module, name    string
+		params, results []uint64
+	}
+	for _, tc := range []struct {
+		name   string
+		config RuntimeConfig
+	}{
+		{name: "interpreter", config: NewRuntimeConfigInterpreter()},
+		{name: "default", config: NewRuntimeConfig()},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r := NewRuntimeWithConfig(testCtx, tc.config)
+			defer r.Close(testCtx)
+
+			add := func(x, y uint32) uint32 { return x + y }
+			exit := func(ctx context.Context, m api.Module, exitCode uint32) { panic(sys.NewExitError(exitCode)) }
+			_, err := r.NewHostModuleBuilder("env").
+				NewFunctionBuilder().WithFunc(add).Export("add").
+				NewFunctionBuilder().WithFunc(exit).Export("exit").
+				Instantiate(testCtx)
+			require.NoError(t, err)
+
+			var calls []call
+			mod, err := r.InstantiateWithConfig(testCtx, bin, NewModuleConfig().WithName("").
+				WithHostCallLogger(func(module, name string, params, results []uint64) {
+					calls = append(calls, call{module, name, params, append([]uint64(nil), results...)})
+				}))
+			require.NoError(t, err)
+			defer mod.Close(testCtx)
+
+			// The start function calls the host as well.
+			require.Equal(t, []call{{"env", "add", []uint64{1, 2}, []uint64{3}}}, calls)
+
+			// The results overwrite the params on the stack, but not the logged params.
+			results, err := mod.ExportedFunction("add").Call(testCtx, 5, 7)
+			require.NoError(t, err)
+			require.Equal(t, []uint64{12}, results)
+			require.Equal(t, call{"env", "add", []uint64{5, 7}, []uint64{12}}, calls[1])
+
+			// A call which doesn't return is logged without results, and still exits.
+			_, err = mod.ExportedFunction("exit").Call(testCtx, 3)
+			var exitErr *sys.ExitError
+			require.True(t, errors.As(err, &exitErr))
+			require.Equal(t, uint32(3), exitErr.ExitCode())
+			require.Equal(t, call{"env", "exit", []uint64{3}, nil}, calls[2])
+		})
+	}
+}
+
+func TestRuntime_WithMemorySanitizer(t *testing.T) {
+	m, err := text.DecodeModule([]byte(`(module
+  (memory (export "memory") 1 2)
+  (data (i32.const 16) "\01\02\03\04")
+  (global (export "__heap_base") i32 (i32.const 8))
+  (func (export "load32") (param i32) (result i32) local.get 0 i32.load)
+  (func (export "load8") (param i32) (result i32) local.get 0 i32.load8_u)
+  (func (export "store8") (param i32 i32) local.get 0 local.get 1 i32.store8)
+  (func (export "copy") (param i32 i32 i32) local.get 0 local.get 1 local.get 2 memory.copy)
+  (func (export "fill") (param i32 i32) local.get 0 i32.const 0 local.get 1 memory.fill)
+  (func (export "grow") (result i32) i32.const 1 memory.grow)
+)`))
+	require.NoError(t, err)
+	bin := binaryencoding.EncodeModule(m)
+
+	r := NewRuntimeWithConfig(testCtx, NewRuntimeConfigInterpreter().WithMemorySanitizer(true))
+	defer r.Close(testCtx)
+
+	mod, err := r.Instantiate(testCtx, bin)
+	require.NoError(t, err)
+
+	call := func(name string, params ...uint64) error {
+		_, err := mod.ExportedFunction(name).Call(testCtx, params...)
+		return err
+	}
+	requireUninitialized := func(err error) {
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "uninitialized memory read")
+	}
+
+	// Below __heap_base and the data segment are initialized.
+	require.NoError(t, call("load32", 0))
+	require.NoError(t, call("load32", 16))
+
+	// A load of any byte which wasn't written traps.
+	requireUninitialized(call("load32", 100))
+	requireUninitialized(call("load32", 18))
+
+	require.NoError(t, call("store8", 100, 1))
+	require.NoError(t, call("load8", 100))
+	requireUninitialized(call("load32", 100))
+
+	// memory.copy copies whether bytes are initialized.
+	require.NoError(t, call("copy", 200, 16, 4))
+	require.NoError(t, call("load32", 200))
+	require.NoError(t, call("copy", 300, 98, 4))
+	requireUninitialized(call("load32", 300))
+
+	require.NoError(t, call("fill", 400, 4))
+	require.NoError(t, call("load32", 400))
+
+	// Writes by the host initialize bytes.
+	require.True(t, mod.Memory().WriteUint32Le(500, 1))
+	require.NoError(t, call("load32", 500))
+
+	// Grown pages are uninitialized, while out of bounds loads trap as usual.
+	require.NoError(t, call("grow"))
+	requireUninitialized(call("load8", 65536))
+	err = call("load8", 2*65536)
+	require.Contains(t, err.Error(), "out of bounds memory access")
+
+	t.Run("compiler", func(t *testing.T) {
+		if !platform.CompilerSupported() {
+			t.Skip()
+		}
+		r := NewRuntimeWithConfig(testCtx, NewRuntimeConfigCompiler().WithMemorySanitizer(true))
+		defer r.Close(testCtx)
+
+		_, err := r.CompileModule(testCtx, bin)
+		require.EqualError(t, err, "WithMemorySanitizer is not supported by the compiler: use wazero.NewRuntimeConfigInterpreter")
+
+		// Modules selected by WithFallbackInterpreter are interpreted, so they are sanitized.
+		r = NewRuntimeWithConfig(testCtx, NewRuntimeConfigCompiler().WithMemorySanitizer(true).
+			WithFallbackInterpreter(func([]byte) bool { return true }))
+		defer r.Close(testCtx)
+
+		mod, err := r.Instantiate(testCtx, bin)
+		require.NoError(t, err)
+		_, err = mod.ExportedFunction("load32").Call(testCtx, 100)
+		require.Contains(t, err.Error(), "uninitialized memory read")
+	})
+}
+
+func TestModule_StackRemaining(t *testing.T) {
+	m, err := text.DecodeModule([]byte(`(module
+  (import "env" "host" (func $host))
+  (func $recurse (export "recurse") (param i32)
+    (if (i32.eqz (local.get 0))
+      (then (call $host))
+      (else (call $recurse (i32.sub (local.get 0) (i32.const 1)))))
+  )
+)`))
+	require.NoError(t, err)
+	bin := binaryencoding.EncodeModule(m)
+
+	for _, tc := range []struct {
+		name    string
+		config  RuntimeConfig
+		tracked bool
+	}{
+		{name: "interpreter", config: NewRuntimeConfigInterpreter()},
+		{name: "default", config: NewRuntimeConfig(), tracked: platform.CompilerSupported()},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r := NewRuntimeWithConfig(testCtx, tc.config)
+			defer r.Close(testCtx)
+
+			var remaining int
+			_, err := r.NewHostModuleBuilder("env").NewFunctionBuilder().
+				WithFunc(func(ctx context.Context, m api.Module) {
+					remaining = m.StackRemaining()
+				}).Export("host").
+				Instantiate(testCtx)
+			require.NoError(t, err)
+
+			mod, err := r.Instantiate(testCtx, bin)
+			require.NoError(t, err)
+			require.Equal(t, -1, mod.StackRemaining())
+
+			recurse := mod.ExportedFunction("recurse")
+			_, err = recurse.Call(testCtx, 0)
+			require.NoError(t, err)
+			shallow := remaining
+
+			_, err = recurse.Call(testCtx, 100)
+			require.NoError(t, err)
+			deep := remaining
+
+			if tc.tracked {
+				require.True(t, shallow > 0)
+				require.True(t, deep < shallow)
+			} else {
+				require.Equal(t, -1, shallow)
+				require.Equal(t, -1, deep)
+			}
+			require.Equal(t, -1, mod.StackRemaining())
+		})
+	}
+}
+
+func TestRuntime_WithPprofLabels(t *testing.T) {
+	m, err := text.DecodeModule([]byte(`(module $guest
+  (import "env" "host" (func $host))
+  (func $run (export "run") (call $host))
+)`))
+	require.NoError(t, err)
+	bin := binaryencoding.EncodeModule(m)
+
+	for _, tc := range []struct {
+		name   string
+		config RuntimeConfig
+	}{
+		{name: "interpreter", config: NewRuntimeConfigInterpreter()},
+		{name: "default", config: NewRuntimeConfig()},
+	} {
+		for _, enabled := range []bool{false, true} {
+			t.Run(fmt.Sprintf("%s enabled=%v", tc.name, enabled), func(t *testing.T) {
+				r := NewRuntimeWithConfig(testCtx, tc.config.WithPprofLabels(enabled))
+				defer r.Close(testCtx)
+
+				var label string
+				var ok bool
+				_, err := r.NewHostModuleBuilder("env").NewFunctionBuilder().
+					WithFunc(func(ctx context.Context) {
+						label, ok = pprof.Label(ctx, "wasm_function")
+					}).Export("host").
+					Instantiate(testCtx)
+				require.NoError(t, err)
+
+				mod, err := r.Instantiate(testCtx, bin)
+				require.NoError(t, err)
+
+				_, err = mod.ExportedFunction("run").Call(testCtx)
+				require.NoError(t, err)
+				require.Equal(t, enabled, ok)
+				if enabled {
+					require.Equal(t, "guest.run", label)
+				}
+
+				// CallWithStack is labelled, too.
+				label, ok = "", false
+				err = mod.ExportedFunction("run").CallWithStack(testCtx, nil)
+				require.NoError(t, err)
+				require.Equal(t, enabled, ok)
+				if enabled {
+					require.Equal(t, "guest.run", label)
+				}
+			})
+		}
+	}
+}
+
+func TestRuntime_Clone(t *testing.T) {
+	m, err := text.DecodeModule([]byte(`(module
+  (type $load_t (func (result i32)))
+  (memory (export "memory") 1)
+  (global $g (mut i32) (i32.const 0))
+  (table 1 funcref)
+  (elem declare func $load)
+  (func $load (result i32) i32.const 0 i32.load)
+  (func (export "init") (param i32)
+    i32.const 0 local.get 0 i32.store
+    local.get 0 global.set $g
+    i32.const 0 ref.func $load table.set 0)
+  (func (export "global") (result i32) global.get $g)
+  (func (export "call") (result i32) i32.const 0 call_indirect (type $load_t))
+)`))
+	require.NoError(t, err)
+	bin := binaryencoding.EncodeModule(m)
+
+	for _, tc := range []struct {
+		name   string
+		config RuntimeConfig
+	}{
+		{name: "interpreter", config: NewRuntimeConfigInterpreter()},
+		{name: "default", config: NewRuntimeConfig()},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r := NewRuntimeWithConfig(testCtx, tc.config)
+			defer r.Close(testCtx)
+
+			compiled, err := r.CompileModule(testCtx, bin)
+			require.NoError(t, err)
+
+			mod, err := r.InstantiateModule(testCtx, compiled, NewModuleConfig().WithName("original"))
+			require.NoError(t, err)
+			_, err = mod.ExportedFunction("init").Call(testCtx, 42)
+			require.NoError(t, err)
+
+			clone, err := mod.Clone(testCtx, "clone")
+			require.NoError(t, err)
+			require.Equal(t, "clone", clone.Name())
+			require.Equal(t, clone, r.Module("clone"))
+
+			// Changes to the original after cloning aren't visible in the clone.
+			_, err = mod.ExportedFunction("init").Call(testCtx, 7)
+			require.NoError(t, err)
+
+			requireCall := func(mod api.Module, name string, expected uint64) {
+				results, err := mod.ExportedFunction(name).Call(testCtx)
+				require.NoError(t, err)
+				require.Equal(t, []uint64{expected}, results)
+			}
+			requireCall(mod, "global", 7)
+			requireCall(clone, "global", 42)
+			// The table of the clone references its own function, which loads from its own memory.
+			requireCall(mod, "call", 7)
+			requireCall(clone, "call", 42)
+
+			v, ok := clone.Memory().ReadUint32Le(0)
+			require.True(t, ok)
+			require.Equal(t, uint32(42), v)
+
+			// Closing the clone leaves the original as is.
+			require.NoError(t, clone.Close(testCtx))
+			requireCall(mod, "call", 7)
+
+			_, err = mod.Clone(testCtx, "original")
+			require.EqualError(t, err, "module[original] has already been instantiated")
+
+			t.Run("closes compiled code", func(t *testing.T) {
+				mod, err := r.InstantiateWithConfig(testCtx, bin, NewModuleConfig().WithName("closer"))
+				require.NoError(t, err)
+				defer mod.Close(testCtx)
+
+				_, err = mod.Clone(testCtx, "clone")
+				require.EqualError(t, err, "module[closer] cannot be cloned as it closes its compiled code")
+			})
+		})
+	}
+}
+
+func TestRuntime_InstantiateModule_WithMaxCallDepth(t *testing.T) {
+	// recurse is a function that calls itself forever.
+	bin := binaryencoding.EncodeModule(&wasm.Module{
+		TypeSection:     []wasm.FunctionType{{}},
+		FunctionSection: []wasm.Index{0},
+		CodeSection:     []wasm.Code{{Body: []byte{wasm.OpcodeCall, 0, wasm.OpcodeEnd}}},
+		ExportSection:   []wasm.Export{{Name: "recurse", Type: wasm.ExternTypeFunc, Index: 0}},
+	})
+
+	t.Run("not supported by the compiler", func(t *testing.T) {
+		if !platform.CompilerSupported() {
+			t.Skip()
+		}
+		r := NewRuntimeWithConfig(testCtx, NewRuntimeConfigCompiler())
+		defer r.Close(testCtx)
+
+		_, err := r.InstantiateWithConfig(testCtx, bin, NewModuleConfig().WithMaxCallDepth(100))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "WithMaxCallDepth is not supported by the compiler: use wazero.NewRuntimeConfigInterpreter")
+	})
+
+	t.Run("call stack exhausted", func(t *testing.T) {
+		r := NewRuntimeWithConfig(testCtx, NewRuntimeConfigInterpreter())
+		defer r.Close(testCtx)
+
+		m, err := r.InstantiateWithConfig(testCtx, bin, NewModuleConfig().WithMaxCallDepth(100))
+		require.NoError(t, err)
+
+		_, err = m.ExportedFunction("recurse").Call(testCtx)
+		require.ErrorIs(t, err, wasmruntime.ErrRuntimeCallStackExhausted)
+		require.False(t, m.IsClosed())
+	})
+}
+
+func TestRuntime_InstantiateModule_WithBeforeStart(t *testing.T) {
+	zero := uint32(0)
+	// The start function copies the i32 at offset 8 of memory into the exported global "seen".
+	bin := binaryencoding.EncodeModule(&wasm.Module{
+		TypeSection:     []wasm.FunctionType{{}},
+		FunctionSection: []wasm.Index{0},
+		CodeSection: []wasm.Code{{Body: []byte{
+			wasm.OpcodeI32Const, 0,
+			wasm.OpcodeI32Load, 2, 8,
+			wasm.OpcodeGlobalSet, 0,
+			wasm.OpcodeEnd,
+		}}},
+		MemorySection: &wasm.Memory{Min: 1, Cap: 1, Max: 1},
+		GlobalSection: []wasm.Global{{
+			Type: wasm.GlobalType{ValType: wasm.ValueTypeI32, Mutable: true},
+			Init: wasm.ConstantExpression{Opcode: wasm.OpcodeI32Const, Data: leb128.EncodeInt32(0)},
+		}},
+		DataSection: []wasm.DataSegment{{
+			OffsetExpression: wasm.ConstantExpression{Opcode: wasm.OpcodeI32Const, Data: leb128.EncodeInt32(0)},
+			Init:             []byte("data"),
+		}},
+		ExportSection: []wasm.Export{{Name: "seen", Type: wasm.ExternTypeGlobal, Index: 0}},
+		StartSection:  &zero,
+	})
+
+	t.Run("prepares memory for start", func(t *testing.T) {
+		r := NewRuntime(testCtx)
+		defer r.Close(testCtx)
+
+		var calls int
+		config := NewModuleConfig().WithBeforeStart(func(ctx context.Context, mod api.Module) error {
+			calls++
+			require.Equal(t, testCtx, ctx)
+			// Data segments were already applied.
+			buf, ok := mod.Memory().Read(0, 4)
+			require.True(t, ok)
+			require.Equal(t, "data", string(buf))
+			// The start function hasn't run yet.
+			require.Equal(t, uint64(0), mod.ExportedGlobal("seen").Get())
+			require.True(t, mod.Memory().WriteUint32Le(8, 42))
+			return nil
+		})
+		mod, err := r.InstantiateWithConfig(testCtx, bin, config)
+		require.NoError(t, err)
+
+		require.Equal(t, 1, calls)
+		require.Equal(t, uint64(42), mod.ExportedGlobal("seen").Get())
+	})
+
+	t.Run("error fails instantiation", func(t *testing.T) {
+		r := NewRuntime(testCtx)
+		defer r.Close(testCtx)
+
+		config := NewModuleConfig().WithName("guest").WithBeforeStart(func(context.Context, api.Module) error {
+			return errors.New("ice cream")
+		})
+		_, err := r.InstantiateWithConfig(testCtx, bin, config)
+		require.EqualError(t, err, "module[guest] before start failed: ice cream")
+		require.Nil(t, r.Module("guest"))
+	})
+}
+
+func TestRuntime_WithFallbackInterpreter(t *testing.T) {
+	if !platform.CompilerSupported() {
+		t.Skip("the fallback is only used when compiling")
+	}
+
+	decode := func(source string) []byte {
+		m, err := text.DecodeModule([]byte(source))
+		require.NoError(t, err)
+		return binaryencoding.EncodeModule(m)
+	}
+	lib := decode(`(module
+	(memory (export "memory") 1)
+	(table (export "table") 1 funcref)
+	(func (export "double") (param i32) (result i32) (i32.mul (local.get 0) (i32.const 2))))`)
+	app := decode(`(module $app
+	(import "env" "add" (func $add (param i32 i32) (result i32)))
+	(import "lib" "double" (func $double (param i32) (result i32)))
+	(import "lib" "memory" (memory 1))
+	(func (export "run") (param i32) (result i32)
+		(call $add (call $double (local.get 0)) (i32.load (i32.const 0)))))`)
+
+	r := NewRuntimeWithConfig(testCtx, NewRuntimeConfigCompiler().WithFallbackInterpreter(func(binary []byte) bool {
+		return bytes.Equal(binary, app)
+	}))
+	defer r.Close(testCtx)
+	internal := r.(*runtime)
+
+	// The host function reads the memory of its caller, which is imported by app.
+	_, err := r.NewHostModuleBuilder("env").NewFunctionBuilder().
+		WithFunc(func(ctx context.Context, mod api.Module, x, y uint32) uint32 {
+			v, _ := mod.Memory().ReadUint32Le(4)
+			return x + y + v
+		}).Export("add").Instantiate(testCtx)
+	require.NoError(t, err)
+
+	libCode, err := r.CompileModule(testCtx, lib)
+	require.NoError(t, err)
+	require.Equal(t, internal.store.Engine, libCode.(*compiledModule).compiledEngine)
+	libMod, err := r.InstantiateModule(testCtx, libCode, NewModuleConfig().WithName("lib"))
+	require.NoError(t, err)
+	require.True(t, libMod.Memory().WriteUint32Le(0, 10))
+	require.True(t, libMod.Memory().WriteUint32Le(4, 100))
+
+	appCode, err := r.CompileModule(testCtx, app)
+	require.NoError(t, err)
+	require.Equal(t, internal.interpreterEngine, appCode.(*compiledModule).compiledEngine)
+
+	// Each instance of the module is interpreted.
+	for _, name := range []string{"app", "app2"} {
+		appMod, err := r.InstantiateModule(testCtx, appCode, NewModuleConfig().WithName(name))
+		require.NoError(t, err)
+		res, err := appMod.ExportedFunction("run").Call(testCtx, 3)
+		require.NoError(t, err)
+		require.Equal(t, []uint64{3*2 + 10 + 100}, res)
+	}
+
+	t.Run("compiled can't import interpreted", func(t *testing.T) {
+		_, err := r.Instantiate(testCtx, decode(`(module (import "app" "run" (func (param i32) (result i32))))`))
+		require.EqualError(t, err, "import func[app.run]: imported from a module compiled by a different engine")
+	})
+
+	t.Run("tables can't be shared", func(t *testing.T) {
+		table := decode(`(module (import "lib" "table" (table 1 funcref)))`)
+		r := NewRuntimeWithConfig(testCtx, NewRuntimeConfigCompiler().WithFallbackInterpreter(func(binary []byte) bool {
+			return bytes.Equal(binary, table)
+		}))
+		defer r.Close(testCtx)
+
+		_, err := r.InstantiateWithConfig(testCtx, lib, NewModuleConfig().WithName("lib"))
+		require.NoError(t, err)
+		_, err = r.Instantiate(testCtx, table)
+		require.EqualError(t, err, "import table[lib.table]: imported from a module compiled by a different engine")
+	})
+
+	t.Run("called with the binary before inflating", func(t *testing.T) {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		_, err := zw.Write(lib)
+		require.NoError(t, err)
+		require.NoError(t, zw.Close())
+		compressed := buf.Bytes()
+
+		r := NewRuntimeWithConfig(testCtx, NewRuntimeConfigCompiler().WithFallbackInterpreter(func(binary []byte) bool {
+			return bytes.Equal(binary, compressed)
+		}))
+		defer r.Close(testCtx)
+
+		code, err := r.CompileModule(testCtx, compressed)
+		require.NoError(t, err)
+		require.Equal(t, r.(*runtime).interpreterEngine, code.(*compiledModule).compiledEngine)
+	})
+}
+
+func TestRuntime_WithDifferentialCheck(t *testing.T) {
+	if !platform.CompilerSupported() {
+		t.Skip("the interpreter is only compared with the compiler")
+	}
+
+	decode := func(source string) []byte {
+		m, err := text.DecodeModule([]byte(source))
+		require.NoError(t, err)
+		return binaryencoding.EncodeModule(m)
+	}
+	app := decode(`(module
+	(import "env" "count" (func $count))
+	(memory 1)
+	(func (export "run") (param i32) (result i32)
+		call $count
+		(i32.store (local.get 0) (i32.mul (local.get 0) (i32.const 3)))
+		(i32.load (local.get 0)))
+	(func (export "trap") unreachable))`)
+
+	r := NewRuntimeWithConfig(testCtx, NewRuntimeConfigCompiler().WithDifferentialCheck(true))
+	defer r.Close(testCtx)
+	internal := r.(*runtime)
+
+	var count int
+	_, err := r.NewHostModuleBuilder("env").NewFunctionBuilder().
+		WithFunc(func() { count++ }).Export("count").Instantiate(testCtx)
+	require.NoError(t, err)
+
+	code, err := r.CompileModule(testCtx, app)
+	require.NoError(t, err)
+	require.Equal(t, internal.store.Engine, code.(*compiledModule).compiledEngine)
+	require.Equal(t, internal.interpreterEngine, code.(*compiledModule).differentialEngine)
+
+	mod, err := r.InstantiateModule(testCtx, code, NewModuleConfig().WithName("app"))
+	require.NoError(t, err)
+	require.NotNil(t, mod.(*wasm.ModuleInstance).Differential)
+
+	res, err := mod.ExportedFunction("run").Call(testCtx, 8)
+	require.NoError(t, err)
+	require.Equal(t, []uint64{24}, res)
+	require.Equal(t, 2, count) // Each call runs on both engines.
+
+	// The same trap on both engines isn't a difference.
+	_, err = mod.ExportedFunction("trap").Call(testCtx)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "wasm error: unreachable")
+	require.False(t, strings.Contains(err.Error(), "differential check"))
+
+	// Closing the module closes the differential one, without unregistering others.
+	require.NoError(t, mod.Close(testCtx))
+	require.Nil(t, mod.(*wasm.ModuleInstance).Differential)
+	require.NotNil(t, r.Module("env"))
+
+	t.Run("tables can't be shared", func(t *testing.T) {
+		_, err := r.InstantiateWithConfig(testCtx, decode(`(module (table (export "table") 1 funcref))`),
+			NewModuleConfig().WithName("lib"))
+		require.NoError(t, err)
+		_, err = r.Instantiate(testCtx, decode(`(module (import "lib" "table" (table 1 funcref)))`))
+		require.EqualError(t, err, "module[] differential instantiation failed: import table[lib.table]: imported from a module compiled by a different engine")
+	})
+}
+
+func TestModule_SnapshotMemory(t *testing.T) {
+	m, err := text.DecodeModule([]byte(`(module
+	(memory 1 3)
+	(func (export "incr") (result i32)
+		(i32.store (i32.const 0) (i32.add (i32.load (i32.const 0)) (i32.const 1)))
+		(i32.load (i32.const 0)))
+	(func (export "grow") (result i32) (memory.grow (i32.const 1))))`))
+	require.NoError(t, err)
+	bin := binaryencoding.EncodeModule(m)
+
+	for _, tc := range []struct {
+		name   string
+		config RuntimeConfig
+	}{
+		{name: "interpreter", config: NewRuntimeConfigInterpreter()},
+		{name: "default", config: NewRuntimeConfig()},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r := NewRuntimeWithConfig(testCtx, tc.config)
+			defer r.Close(testCtx)
+
+			mod, err := r.Instantiate(testCtx, bin)
+			require.NoError(t, err)
+			incr, grow := mod.ExportedFunction("incr"), mod.ExportedFunction("grow")
+
+			snapshot := mod.SnapshotMemory()
+			require.Equal(t, int(wasm.MemoryPageSize), len(snapshot))
+
+			for i := 0; i < 2; i++ {
+				res, err := incr.Call(testCtx)
+				require.NoError(t, err)
+				require.Equal(t, []uint64{1}, res)
+				res, err = grow.Call(testCtx)
+				require.NoError(t, err)
+				require.Equal(t, []uint64{uint64(i + 1)}, res) // the previous size in pages
+				require.True(t, mod.Memory().WriteByte(uint32(i+1)*wasm.MemoryPageSize, 1))
+
+				// Rewind, which keeps the grown pages, but zeroes them.
+				require.NoError(t, mod.RestoreMemory(snapshot))
+				require.Equal(t, uint32(i+2)*wasm.MemoryPageSize, mod.Memory().Size())
+				b, ok := mod.Memory().ReadByte(uint32(i+1) * wasm.MemoryPageSize)
+				require.True(t, ok)
+				require.Equal(t, byte(0), b)
+			}
+
+			err = mod.RestoreMemory(make([]byte, 4*wasm.MemoryPageSize))
+			require.EqualError(t, err, "snapshot of 4 pages exceeds the maximum of 3 pages")
+		})
+	}
+}
+
+func TestRuntime_WithMemoryZeroOnClose(t *testing.T) {
+	m, err := text.DecodeModule([]byte(`(module (memory (export "memory") 1) (data (i32.const 0) "secret"))`))
+	require.NoError(t, err)
+	bin := binaryencoding.EncodeModule(m)
+	m, err = text.DecodeModule([]byte(`(module (import "defining" "memory" (memory 1)))`))
+	require.NoError(t, err)
+	importingBin := binaryencoding.EncodeModule(m)
 
+	for _, tc := range []struct {
+		name     string
+		zero     bool
+		expected []byte
+	}{
+		{name: "disabled", expected: []byte("secret")},
+		{name: "enabled", zero: true, expected: make([]byte, 6)},
+	} {
 		t.Run(tc.name, func(t *testing.T) {
-			r := NewRuntime(testCtx)
+			r := NewRuntimeWithConfig(testCtx, NewRuntimeConfig().WithMemoryZeroOnClose(tc.zero))
 			defer r.Close(testCtx)
 
-			// Instantiate the module and get the export of the above memory
-			module, err := r.Instantiate(testCtx, tc.wasm)
+			mod, err := r.InstantiateWithConfig(testCtx, bin, NewModuleConfig().WithName("defining"))
 			require.NoError(t, err)
+			// Read returns a view of the memory, which outlives the module.
+			buf, ok := mod.Memory().Read(0, 6)
+			require.True(t, ok)
+			require.Equal(t, []byte("secret"), buf)
 
-			mem := module.ExportedMemory("memory")
-			if tc.expected {
-				require.Equal(t, tc.expectedLen, mem.Size())
-				defs := module.ExportedMemoryDefinitions()
-				require.Equal(t, 1, len(defs))
-				def := defs["memory"]
-				require.Equal(t, tc.expectedLen>>16, def.Min())
-			} else {
-				require.Nil(t, mem)
-				require.Zero(t, len(module.ExportedMemoryDefinitions()))
-			}
+			// Closing a module which imports the memory leaves it as is.
+			importing, err := r.Instantiate(testCtx, importingBin)
+			require.NoError(t, err)
+			require.NoError(t, importing.Close(testCtx))
+			require.Equal(t, []byte("secret"), buf)
+
+			require.NoError(t, mod.Close(testCtx))
+			require.Equal(t, tc.expected, buf)
 		})
 	}
 }
 
-// TestModule_Global only covers a couple cases to avoid duplication of internal/wasm/global_test.go
-func TestModule_Global(t *testing.T) {
-	globalVal := int64(100) // intentionally a value that differs in signed vs unsigned encoding
+func TestRuntime_WithMemoryPrefault(t *testing.T) {
+	m, err := text.DecodeModule([]byte(`(module
+  (memory (export "memory") 1)
+  (data (i32.const 0) "data")
+  (global $g (export "g") (mut i32) (i32.const 0))
+  (func $start i32.const 0 i32.load8_u global.set $g)
+  (start $start)
+)`))
+	require.NoError(t, err)
+	bin := binaryencoding.EncodeModule(m)
 
-	tests := []struct {
-		name                      string
-		module                    *wasm.Module // module as wat doesn't yet support globals
-		expected, expectedMutable bool
-	}{
-		{
-			name:   "no global",
-			module: &wasm.Module{},
-		},
-		{
-			name: "global not exported",
-			module: &wasm.Module{
-				GlobalSection: []wasm.Global{
-					{
-						Type: wasm.GlobalType{ValType: wasm.ValueTypeI64, Mutable: true},
-						Init: wasm.ConstantExpression{Opcode: wasm.OpcodeI64Const, Data: leb128.EncodeInt64(globalVal)},
-					},
-				},
-			},
-		},
-		{
-			name: "global exported",
-			module: &wasm.Module{
-				GlobalSection: []wasm.Global{
-					{
-						Type: wasm.GlobalType{ValType: wasm.ValueTypeI64},
-						Init: wasm.ConstantExpression{Opcode: wasm.OpcodeI64Const, Data: leb128.EncodeInt64(globalVal)},
-					},
-				},
-				Exports: map[string]*wasm.Export{
-					"global": {Type: wasm.ExternTypeGlobal, Name: "global"},
-				},
-			},
-			expected: true,
-		},
-		{
-			name: "global exported and mutable",
-			module: &wasm.Module{
-				GlobalSection: []wasm.Global{
-					{
-						Type: wasm.GlobalType{ValType: wasm.ValueTypeI64, Mutable: true},
-						Init: wasm.ConstantExpression{Opcode: wasm.OpcodeI64Const, Data: leb128.EncodeInt64(globalVal)},
-					},
-				},
-				Exports: map[string]*wasm.Export{
-					"global": {Type: wasm.ExternTypeGlobal, Name: "global"},
-				},
-			},
-			expected:        true,
-			expectedMutable: true,
-		},
-	}
+	r := NewRuntimeWithConfig(testCtx, NewRuntimeConfig().WithMemoryPrefault(true))
+	defer r.Close(testCtx)
 
-	for _, tt := range tests {
-		tc := tt
+	mod, err := r.Instantiate(testCtx, bin)
+	require.NoError(t, err)
+
+	// Pre-faulting leaves the data applied before the start function as is.
+	require.Equal(t, uint64('d'), mod.ExportedGlobal("g").Get())
+	buf, ok := mod.Memory().Read(0, 4)
+	require.True(t, ok)
+	require.Equal(t, []byte("data"), buf)
+	require.NoError(t, mod.Close(testCtx))
+}
+
+func TestRuntime_MemoryPinned(t *testing.T) {
+	m, err := text.DecodeModule([]byte(`(module
+  (memory (export "memory") 1 2)
+  (func (export "grow") (result i32) i32.const 1 memory.grow)
+)`))
+	require.NoError(t, err)
+	bin := binaryencoding.EncodeModule(m)
 
+	for _, tc := range []struct {
+		name   string
+		config RuntimeConfig
+	}{
+		{name: "interpreter", config: NewRuntimeConfigInterpreter()},
+		{name: "default", config: NewRuntimeConfig()},
+	} {
+		tc := tc
 		t.Run(tc.name, func(t *testing.T) {
-			r := NewRuntime(testCtx).(*runtime)
+			r := NewRuntimeWithConfig(testCtx, tc.config)
 			defer r.Close(testCtx)
 
-			code := &compiledModule{module: tc.module}
+			mod, err := r.Instantiate(testCtx, bin)
+			require.NoError(t, err)
 
-			err := r.store.Engine.CompileModule(testCtx, code.module, nil, false)
+			buf, release, err := mod.Memory().Pinned()
 			require.NoError(t, err)
+			require.Equal(t, int(wasm.MemoryPageSize), len(buf))
 
-			// Instantiate the module and get the export of the above global
-			module, err := r.InstantiateModule(testCtx, code, NewModuleConfig())
+			// memory.grow returns -1 while the memory is pinned.
+			grow := mod.ExportedFunction("grow")
+			results, err := grow.Call(testCtx)
 			require.NoError(t, err)
+			require.Equal(t, int32(-1), api.DecodeI32(results[0]))
 
-			global := module.ExportedGlobal("global")
-			if !tc.expected {
-				require.Nil(t, global)
-				return
-			}
-			require.Equal(t, uint64(globalVal), global.Get())
+			// Writes by the host are visible through the memory.
+			buf[0] = 'a'
+			b, _ := mod.Memory().ReadByte(0)
+			require.Equal(t, byte('a'), b)
 
-			mutable, ok := global.(api.MutableGlobal)
-			require.Equal(t, tc.expectedMutable, ok)
-			if ok {
-				mutable.Set(2)
-				require.Equal(t, uint64(2), global.Get())
-			}
+			release()
+			results, err = grow.Call(testCtx)
+			require.NoError(t, err)
+			require.Equal(t, int32(1), api.DecodeI32(results[0]))
 		})
 	}
 }
 
-func TestRuntime_InstantiateModule_UsesContext(t *testing.T) {
-	r := NewRuntime(testCtx)
-	defer r.Close(testCtx)
-
-	// Define a function that will be set as the start function
-	var calledStart bool
-	start := func(ctx context.Context) {
-		calledStart = true
-		require.Equal(t, testCtx, ctx)
-	}
-
-	_, err := r.NewHostModuleBuilder("env").
-		NewFunctionBuilder().WithFunc(start).Export("start").
-		Instantiate(testCtx)
+func TestRuntime_WithStrictFloat(t *testing.T) {
+	m, err := text.DecodeModule([]byte(`(module
+  (func (export "mul") (param f64 f64) (result f64) local.get 0 local.get 1 f64.mul)
+)`))
 	require.NoError(t, err)
+	bin := binaryencoding.EncodeModule(m)
 
-	one := uint32(1)
-	binary := binaryencoding.EncodeModule(&wasm.Module{
-		TypeSection:     []wasm.FunctionType{{}},
-		ImportSection:   []wasm.Import{{Module: "env", Name: "start", Type: wasm.ExternTypeFunc, DescFunc: 0}},
-		FunctionSection: []wasm.Index{0},
-		CodeSection: []wasm.Code{
-			{Body: []byte{wasm.OpcodeCall, 0, wasm.OpcodeEnd}}, // Call the imported env.start.
-		},
-		StartSection: &one,
-	})
+	r := NewRuntimeWithConfig(testCtx, NewRuntimeConfig().WithStrictFloat())
+	defer r.Close(testCtx)
 
-	code, err := r.CompileModule(testCtx, binary)
+	mod, err := r.Instantiate(testCtx, bin)
+	if !platform.StrictFloatSupported {
+		require.EqualError(t, err, "WithStrictFloat is not supported on "+goruntime.GOARCH)
+		return
+	}
 	require.NoError(t, err)
+	require.True(t, mod.(*wasm.ModuleInstance).StrictFloat)
 
-	// Instantiate the module, which calls the start function. This will fail if the context wasn't as intended.
-	mod, err := r.InstantiateModule(testCtx, code, NewModuleConfig())
+	results, err := mod.ExportedFunction("mul").Call(testCtx, api.EncodeF64(1e-300), api.EncodeF64(1e-10))
 	require.NoError(t, err)
-
-	require.True(t, calledStart)
-
-	// Closing the module shouldn't remove the compiler cache
-	require.NoError(t, mod.Close(testCtx))
-	require.Equal(t, uint32(2), r.(*runtime).store.Engine.CompiledModuleCount())
+	require.Equal(t, 1e-310, api.DecodeF64(results[0]))
 }
 
-// TestRuntime_Instantiate_DoesntEnforce_Start ensures wapc-go work when modules import WASI, but don't
-// export "_start".
-func TestRuntime_Instantiate_DoesntEnforce_Start(t *testing.T) {
-	r := NewRuntime(testCtx)
-	defer r.Close(testCtx)
-
-	binary := binaryencoding.EncodeModule(&wasm.Module{
-		MemorySection: &wasm.Memory{Min: 1},
-		ExportSection: []wasm.Export{{Name: "memory", Type: wasm.ExternTypeMemory, Index: 0}},
-	})
-
-	mod, err := r.Instantiate(testCtx, binary)
+func TestErrStackTrace(t *testing.T) {
+	m, err := text.DecodeModule([]byte(`(module $m
+  (func $trap unreachable)
+  (func (export "call") call $trap)
+)`))
 	require.NoError(t, err)
-	require.NoError(t, mod.Close(testCtx))
-}
+	bin := binaryencoding.EncodeModule(m)
 
-func TestRuntime_Instantiate_ErrorOnStart(t *testing.T) {
-	tests := []struct {
-		name, wasm string
+	for _, tc := range []struct {
+		name   string
+		config RuntimeConfig
 	}{
-		{
-			name: "_start function",
-			wasm: `(module
-	(import "" "start" (func $start))
-	(export "_start" (func $start))
-)`,
-		},
-		{
-			name: ".start function",
-			wasm: `(module
-	(import "" "start" (func $start))
-	(start $start)
-)`,
-		},
-	}
-
-	for _, tt := range tests {
-		tc := tt
-
+		{name: "interpreter", config: NewRuntimeConfigInterpreter()},
+		{name: "default", config: NewRuntimeConfig()},
+	} {
 		t.Run(tc.name, func(t *testing.T) {
-			r := NewRuntime(testCtx)
+			r := NewRuntimeWithConfig(testCtx, tc.config)
 			defer r.Close(testCtx)
 
-			start := func() {
-				panic(errors.New("ice cream"))
-			}
-
-			host, err := r.NewHostModuleBuilder("host").
-				NewFunctionBuilder().WithFunc(start).Export("start").
-				Instantiate(testCtx)
+			mod, err := r.Instantiate(testCtx, bin)
 			require.NoError(t, err)
 
-			// Start the module as a WASI command. We expect it to fail.
-			_, err = r.Instantiate(testCtx, []byte(tc.wasm))
-			require.Error(t, err)
-
-			// Close the imported module, which should remove its compiler cache.
-			require.NoError(t, host.Close(testCtx))
-
-			// The compiler cache of the importing module should be removed on error.
-			require.Zero(t, r.(*runtime).store.Engine.CompiledModuleCount())
+			_, err = mod.ExportedFunction("call").Call(testCtx)
+			require.Equal(t, []Frame{
+				{ModuleName: "m", FunctionIndex: 0, FunctionName: "trap"},
+				{ModuleName: "m", FunctionIndex: 1},
+			}, ErrStackTrace(err))
 		})
 	}
+
+	require.Nil(t, ErrStackTrace(sys.NewExitError(0)))
 }
 
-// TestRuntime_InstantiateModule_WithName tests that we can pre-validate (cache) a module and instantiate it under
-// different names. This pattern is used in wapc-go.
-func TestRuntime_InstantiateModule_WithName(t *testing.T) {
+func TestDWARFLines(t *testing.T) {
 	r := NewRuntime(testCtx)
 	defer r.Close(testCtx)
 
-	base, err := r.CompileModule(testCtx, binaryNamedZero)
-	require.NoError(t, err)
+	t.Run("no DWARF", func(t *testing.T) {
+		compiled, err := r.CompileModule(testCtx, binaryencoding.EncodeModule(&wasm.Module{}))
+		require.NoError(t, err)
 
-	require.Equal(t, "0", base.(*compiledModule).module.NameSection.ModuleName)
+		lines := DWARFLines(compiled)
+		require.Nil(t, lines)
+		_, _, ok := lines.SourceLine(0, 0)
+		require.False(t, ok)
+	})
 
-	// Use the same runtime to instantiate multiple modules
-	internal := r.(*runtime)
-	m1, err := r.InstantiateModule(testCtx, base, NewModuleConfig().WithName("1"))
-	require.NoError(t, err)
-	require.Equal(t, "1", m1.Name())
+	t.Run("zig", func(t *testing.T) {
+		compiled, err := r.CompileModule(testCtx, dwarftestdata.ZigWasm)
+		require.NoError(t, err)
 
-	require.Nil(t, internal.Module("0"))
-	require.Equal(t, internal.Module("1"), m1)
+		lines := DWARFLines(compiled)
+		require.NotNil(t, lines)
 
-	m2, err := r.InstantiateModule(testCtx, base, NewModuleConfig().WithName("2"))
-	require.NoError(t, err)
-	require.Equal(t, "2", m2.Name())
+		// 0x63 is in builtin.default_panic, see internal/integration_test/engine/dwarf_test.go.
+		const offset = 0x63
+		m := compiled.(*compiledModule).module
+		var funcIdx uint32
+		for i := range m.CodeSection {
+			if c := &m.CodeSection[i]; offset >= c.BodyOffsetInCodeSection && offset < c.BodyOffsetInCodeSection+uint64(len(c.Body)) {
+				funcIdx = m.ImportFunctionCount + uint32(i)
+			}
+		}
 
-	require.Nil(t, internal.Module("0"))
-	require.Equal(t, internal.Module("2"), m2)
+		file, line, ok := lines.SourceLine(funcIdx, offset)
+		require.True(t, ok)
+		require.True(t, strings.HasSuffix(file, "builtin.zig"), file)
+		require.Equal(t, 889, line)
 
-	// Empty name module shouldn't be returned via Module() for future optimization.
-	m3, err := r.InstantiateModule(testCtx, base, NewModuleConfig().WithName(""))
+		// The offset isn't within the body of any other function.
+		_, _, ok = lines.SourceLine(funcIdx+1, offset)
+		require.False(t, ok)
+	})
+}
+
+func TestRuntime_WithCallTracer(t *testing.T) {
+	m, err := text.DecodeModule([]byte(`(module
+	(import "env" "host" (func $host))
+	(func $leaf call $host)
+	(func $run (export "run") (param i32)
+		call $leaf
+		(if (local.get 0) (then unreachable))))`))
 	require.NoError(t, err)
-	require.Equal(t, "", m3.Name())
+	bin := binaryencoding.EncodeModule(m)
 
-	ret := internal.Module("")
-	require.Nil(t, ret)
+	for _, tc := range []struct {
+		name   string
+		config RuntimeConfig
+	}{
+		{name: "interpreter", config: NewRuntimeConfigInterpreter()},
+		{name: "default", config: NewRuntimeConfig()},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var trace []string
+			r := NewRuntimeWithConfig(testCtx, tc.config.WithCallTracer(
+				func(funcIdx uint32) { trace = append(trace, fmt.Sprintf("enter %d", funcIdx)) },
+				func(funcIdx uint32) { trace = append(trace, fmt.Sprintf("exit %d", funcIdx)) },
+			))
+			defer r.Close(testCtx)
+
+			_, err := r.NewHostModuleBuilder("env").NewFunctionBuilder().
+				WithFunc(func() {}).Export("host").Instantiate(testCtx)
+			require.NoError(t, err)
+			mod, err := r.Instantiate(testCtx, bin)
+			require.NoError(t, err)
+
+			// Indices are in the function index space of the module, and the host function isn't traced.
+			_, err = mod.ExportedFunction("run").Call(testCtx, 0)
+			require.NoError(t, err)
+			require.Equal(t, []string{"enter 2", "enter 1", "exit 1", "exit 2"}, trace)
+
+			// Functions which trap are exited, too.
+			trace = nil
+			_, err = mod.ExportedFunction("run").Call(testCtx, 1)
+			require.Error(t, err)
+			require.Equal(t, []string{"enter 2", "enter 1", "exit 1", "exit 2"}, trace)
+		})
+	}
 }
 
 func TestRuntime_InstantiateModule_ExitError(t *testing.T) {
@@ -831,3 +2542,22 @@ func TestNewRuntime_concurrent(t *testing.T) {
 	}
 	wg.Wait()
 }
+
+func Test_toRegAllocInfo(t *testing.T) {
+	require.Equal(t, RegAllocInfo{VirtualRegisters: 3}, toRegAllocInfo(&compilation.RegAllocInfo{VirtualRegisters: 3}))
+	require.Equal(t, RegAllocInfo{
+		VirtualRegisters: 50,
+		Spills:           2,
+		SpilledRanges: []LiveRange{
+			{VirtualRegister: 130, Block: 0, Begin: 2, End: 10},
+			{VirtualRegister: 131, Block: 1, Begin: 0, End: -1, LiveOut: true},
+		},
+	}, toRegAllocInfo(&compilation.RegAllocInfo{
+		VirtualRegisters: 50,
+		Spills:           2,
+		SpilledRanges: []compilation.LiveRange{
+			{VirtualRegister: 130, Block: 0, Begin: 2, End: 10},
+			{VirtualRegister: 131, Block: 1, Begin: 0, End: -1, LiveOut: true},
+		},
+	}))
+}
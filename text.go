@@ -0,0 +1,36 @@
+package wazero
+
+import (
+	"io"
+
+	"github.com/tetratelabs/wazero/api"
+	experimentalapi "github.com/tetratelabs/wazero/experimental"
+	"github.com/tetratelabs/wazero/internal/wasm"
+	binaryformat "github.com/tetratelabs/wazero/internal/wasm/binary"
+	"github.com/tetratelabs/wazero/internal/wasm/text"
+)
+
+// PrintModuleText writes the WebAssembly Text Format (%.wat) of a binary
+// module (%.wasm) to the writer. This is intended for debugging, such as
+// inspecting what a toolchain emitted, instead of using `wasm2wat`.
+//
+// Functions are written with their type index and signature. When the
+// binary includes a name section, functions and locals are written with
+// their names as identifiers, such as `call $main`.
+//
+// # Notes
+//
+//   - The binary is decoded with all supported features enabled, but it
+//     isn't validated.
+//   - The output is valid WebAssembly Text Format, but custom sections
+//     besides the name section are not written.
+func PrintModuleText(w io.Writer, binary []byte) error {
+	features := api.CoreFeaturesV2 | experimentalapi.CoreFeaturesTailCall | experimentalapi.CoreFeaturesMultiMemory |
+		experimentalapi.CoreFeaturesRelaxedSIMD | experimentalapi.CoreFeaturesThreads |
+		experimentalapi.CoreFeaturesMemory64 | experimentalapi.CoreFeaturesExtendedConst
+	m, err := binaryformat.DecodeModule(binary, features, wasm.MemoryLimitPages, false, false, false, wasm.DefaultDecodeLimits)
+	if err != nil {
+		return err
+	}
+	return text.EncodeModule(w, m)
+}
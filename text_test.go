@@ -0,0 +1,53 @@
+package wazero
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/testing/binaryencoding"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasm"
+	"github.com/tetratelabs/wazero/internal/wasm/text"
+)
+
+func TestPrintModuleText(t *testing.T) {
+	bin := binaryencoding.EncodeModule(&wasm.Module{
+		TypeSection:     []wasm.FunctionType{{Params: []wasm.ValueType{wasm.ValueTypeI32}, Results: []wasm.ValueType{wasm.ValueTypeI32}}},
+		FunctionSection: []wasm.Index{0},
+		CodeSection: []wasm.Code{{Body: []byte{
+			wasm.OpcodeLocalGet, 0, wasm.OpcodeI32Const, 1, wasm.OpcodeI32Add, wasm.OpcodeEnd,
+		}}},
+		ExportSection: []wasm.Export{{Name: "inc", Type: wasm.ExternTypeFunc, Index: 0}},
+		NameSection: &wasm.NameSection{
+			ModuleName:    "math",
+			FunctionNames: wasm.NameMap{{Index: 0, Name: "inc"}},
+			LocalNames:    wasm.IndirectNameMap{{Index: 0, NameMap: wasm.NameMap{{Index: 0, Name: "x"}}}},
+		},
+	})
+
+	var buf bytes.Buffer
+	require.NoError(t, PrintModuleText(&buf, bin))
+	require.Equal(t, `(module $math
+  (type (;0;) (func (param i32) (result i32)))
+  (func $inc (;0;) (type 0) (param $x i32) (result i32)
+    local.get $x
+    i32.const 1
+    i32.add)
+  (export "inc" (func $inc)))
+`, buf.String())
+}
+
+// TestPrintModuleText_compiled ensures the text of modules compiled by toolchains can be decoded.
+func TestPrintModuleText_compiled(t *testing.T) {
+	for _, bin := range [][]byte{facWasm, memGrowWasm} {
+		var buf bytes.Buffer
+		require.NoError(t, PrintModuleText(&buf, bin))
+		_, err := text.DecodeModule(buf.Bytes())
+		require.NoError(t, err)
+	}
+}
+
+func TestPrintModuleText_invalid(t *testing.T) {
+	err := PrintModuleText(&bytes.Buffer{}, []byte("not wasm"))
+	require.EqualError(t, err, "invalid magic number")
+}
@@ -0,0 +1,46 @@
+package wazero
+
+import (
+	"context"
+
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
+)
+
+// callTracer implements experimental.FunctionListenerFactory for
+// RuntimeConfig.WithCallTracer.
+type callTracer struct {
+	enter, exit func(funcIdx uint32)
+}
+
+// NewFunctionListener implements experimental.FunctionListenerFactory.
+func (t *callTracer) NewFunctionListener(def api.FunctionDefinition) experimental.FunctionListener {
+	return &callTracerListener{t: t, funcIdx: def.Index()}
+}
+
+// callTracerListener calls the callTracer for a function.
+type callTracerListener struct {
+	t       *callTracer
+	funcIdx uint32
+}
+
+// Before implements experimental.FunctionListener.
+func (l *callTracerListener) Before(context.Context, api.Module, api.FunctionDefinition, []uint64, experimental.StackIterator) {
+	if enter := l.t.enter; enter != nil {
+		enter(l.funcIdx)
+	}
+}
+
+// After implements experimental.FunctionListener.
+func (l *callTracerListener) After(context.Context, api.Module, api.FunctionDefinition, []uint64) {
+	if exit := l.t.exit; exit != nil {
+		exit(l.funcIdx)
+	}
+}
+
+// Abort implements experimental.FunctionListener.
+func (l *callTracerListener) Abort(context.Context, api.Module, api.FunctionDefinition, error) {
+	if exit := l.t.exit; exit != nil {
+		exit(l.funcIdx)
+	}
+}
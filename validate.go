@@ -0,0 +1,107 @@
+package wazero
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/internal/leb128"
+	"github.com/tetratelabs/wazero/internal/wasm"
+	binaryformat "github.com/tetratelabs/wazero/internal/wasm/binary"
+)
+
+// ValidateModule decodes and validates a binary module (%.wasm), as
+// Runtime.CompileModule does, but without compiling it. This returns the
+// first decoding or validation error, such as a function which doesn't type
+// check, or nil if the module is valid.
+//
+// This is intended to cheaply reject untrusted modules, such as uploads,
+// before spending resources on compiling them. The features are the ones
+// allowed, as set by RuntimeConfig.WithCoreFeatures.
+//
+// # Notes
+//
+//   - The limits are the defaults of RuntimeConfig, so a module may still
+//     fail to compile with a runtime which lowers them, such as with
+//     RuntimeConfig.WithMemoryLimitPages.
+//   - Imports are validated on their own, but aren't resolved, as this
+//     only happens on instantiation.
+//   - ctx is checked for cancellation before decoding and validating.
+func ValidateModule(ctx context.Context, binary []byte, features api.CoreFeatures) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	binary, err := decompressModule(binary, maxInflatedModuleSize)
+	if err != nil {
+		return err
+	}
+
+	m, err := binaryformat.DecodeModule(binary, features, wasm.MemoryLimitPages, false, false, false, wasm.DefaultDecodeLimits)
+	if err != nil {
+		return err
+	}
+	if err = ctx.Err(); err != nil {
+		return err
+	}
+	return m.Validate(features, wasm.MaximumBlockNestingDepth)
+}
+
+// ValidateModuleAll is like ValidateModule, but returns all the validation
+// errors found instead of the first, or nil if the module is valid.
+//
+// This is intended for toolchains which report every error of a module in
+// one pass. For example:
+//
+//	for _, err := range wazero.ValidateModuleAll(ctx, wasm, api.CoreFeaturesV2) {
+//		log.Println(err)
+//	}
+//
+// # Notes
+//
+//   - Validation continues past the errors of an import or a function, as
+//     they don't affect validating the rest of the module. Other errors,
+//     such as a decoding error, are returned alone or last.
+//   - Errors in a function body end with the offset of the instruction in
+//     the binary, e.g. "(at offset 0x2c)". When the binary is compressed,
+//     this is the offset in the decompressed binary.
+//   - The notes of ValidateModule apply.
+func ValidateModuleAll(ctx context.Context, binary []byte, features api.CoreFeatures) []error {
+	if err := ctx.Err(); err != nil {
+		return []error{err}
+	}
+	binary, err := decompressModule(binary, maxInflatedModuleSize)
+	if err != nil {
+		return []error{err}
+	}
+
+	m, err := binaryformat.DecodeModule(binary, features, wasm.MemoryLimitPages, false, false, false, wasm.DefaultDecodeLimits)
+	if err != nil {
+		return []error{err}
+	}
+	if err = ctx.Err(); err != nil {
+		return []error{err}
+	}
+
+	errs := m.ValidateAll(features, wasm.MaximumBlockNestingDepth)
+	for i, err := range errs {
+		var instructionErr *wasm.InstructionError
+		if errors.As(err, &instructionErr) {
+			offset := codeSectionContentStart(binary, m) + m.CodeSection[instructionErr.Index].BodyOffsetInCodeSection + instructionErr.Offset
+			errs[i] = fmt.Errorf("%w (at offset 0x%x)", err, offset)
+		}
+	}
+	return errs
+}
+
+// codeSectionContentStart returns the offset in binary of the contents of the
+// code section, following its ID and size.
+func codeSectionContentStart(binary []byte, m *wasm.Module) uint64 {
+	for _, r := range m.SectionRanges {
+		if r.ID == wasm.SectionIDCode {
+			_, sizeLen, _ := leb128.LoadUint32(binary[r.Start+1:]) // already decoded.
+			return uint64(r.Start+1) + sizeLen
+		}
+	}
+	return 0 // unreachable as functions are defined.
+}
@@ -0,0 +1,102 @@
+package wazero
+
+import (
+	"context"
+	"testing"
+
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/internal/testing/binaryencoding"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+func TestValidateModule(t *testing.T) {
+	// i32.const 1, i64.const 1, i32.add, which doesn't type check.
+	invalidBody := binaryencoding.EncodeModule(&wasm.Module{
+		TypeSection:     []wasm.FunctionType{{Results: []wasm.ValueType{wasm.ValueTypeI32}, ResultNumInUint64: 1}},
+		FunctionSection: []wasm.Index{0},
+		CodeSection: []wasm.Code{{Body: []byte{
+			wasm.OpcodeI32Const, 1, wasm.OpcodeI64Const, 1, wasm.OpcodeI32Add, wasm.OpcodeEnd,
+		}}},
+	})
+	// i32.const 1, i32.extend8_s, which requires api.CoreFeatureSignExtensionOps.
+	signExtension := binaryencoding.EncodeModule(&wasm.Module{
+		TypeSection:     []wasm.FunctionType{{Results: []wasm.ValueType{wasm.ValueTypeI32}, ResultNumInUint64: 1}},
+		FunctionSection: []wasm.Index{0},
+		CodeSection: []wasm.Code{{Body: []byte{
+			wasm.OpcodeI32Const, 1, wasm.OpcodeI32Extend8S, wasm.OpcodeEnd,
+		}}},
+	})
+
+	tests := []struct {
+		name        string
+		binary      []byte
+		features    api.CoreFeatures
+		expectedErr string
+	}{
+		{name: "valid", binary: facWasm, features: api.CoreFeaturesV2},
+		{name: "valid with features", binary: signExtension, features: api.CoreFeaturesV2},
+		{
+			name:        "feature disabled",
+			binary:      signExtension,
+			features:    api.CoreFeaturesV1,
+			expectedErr: "invalid function[0]: i32.extend8_s invalid as feature \"sign-extension-ops\" is disabled",
+		},
+		{
+			name:        "type mismatch",
+			binary:      invalidBody,
+			features:    api.CoreFeaturesV2,
+			expectedErr: "invalid function[0]: cannot pop the 1st operand for i32.add: type mismatch: expected i32, but was i64",
+		},
+		{
+			name:        "invalid magic",
+			binary:      []byte{1, 2, 3, 4},
+			features:    api.CoreFeaturesV2,
+			expectedErr: "invalid magic number",
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateModule(testCtx, tc.binary, tc.features)
+			if tc.expectedErr == "" {
+				require.NoError(t, err)
+			} else {
+				require.EqualError(t, err, tc.expectedErr)
+			}
+		})
+	}
+
+	t.Run("canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(testCtx)
+		cancel()
+		require.ErrorIs(t, ValidateModule(ctx, facWasm, api.CoreFeaturesV2), context.Canceled)
+	})
+}
+
+func TestValidateModuleAll(t *testing.T) {
+	bin := binaryencoding.EncodeModule(&wasm.Module{
+		TypeSection:     []wasm.FunctionType{{}},
+		ImportSection:   []wasm.Import{{Module: "", Name: "f", Type: wasm.ExternTypeFunc}},
+		FunctionSection: []wasm.Index{0, 0, 0},
+		CodeSection: []wasm.Code{
+			{Body: []byte{wasm.OpcodeI32Const, 1, wasm.OpcodeI64Const, 1, wasm.OpcodeI32Add, wasm.OpcodeEnd}},
+			{Body: []byte{wasm.OpcodeEnd}},
+			{Body: []byte{wasm.OpcodeNop, 0xff, wasm.OpcodeEnd}},
+		},
+	})
+
+	errs := ValidateModuleAll(testCtx, bin, api.CoreFeaturesV2)
+	require.Equal(t, 3, len(errs))
+	require.EqualError(t, errs[0], "import[0] has an empty module name")
+	require.EqualError(t, errs[1], "invalid function[0]: cannot pop the 1st operand for i32.add: type mismatch: expected i32, but was i64 (at offset 0x25)")
+	require.EqualError(t, errs[2], "invalid function[2]: invalid instruction 0xff (at offset 0x2d)")
+	require.Equal(t, wasm.OpcodeI32Add, bin[0x25])
+	require.Equal(t, byte(0xff), bin[0x2d])
+
+	require.Nil(t, ValidateModuleAll(testCtx, facWasm, api.CoreFeaturesV2))
+	errs = ValidateModuleAll(testCtx, []byte{1, 2, 3, 4}, api.CoreFeaturesV2)
+	require.Equal(t, 1, len(errs))
+	require.EqualError(t, errs[0], "invalid magic number")
+}